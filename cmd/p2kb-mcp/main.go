@@ -3,8 +3,13 @@ package main
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/ironsheep/p2kb-mcp/internal/browse"
+	"github.com/ironsheep/p2kb-mcp/internal/logging"
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
 	"github.com/ironsheep/p2kb-mcp/internal/server"
 )
 
@@ -31,30 +36,72 @@ func main() {
 			fmt.Println()
 			fmt.Println("Options:")
 			fmt.Println("  --version, -v    Print version information")
+			fmt.Println("  --browse         Browse the OBEX interactively in the terminal")
+			fmt.Println("  --transport T    Serve MCP over stdio (default) or http")
 			fmt.Println("  --help, -h       Print this help message")
 			fmt.Println()
 			fmt.Println("Environment variables:")
 			fmt.Println("  P2KB_CACHE_DIR     Cache directory (default: ~/.p2kb-mcp)")
 			fmt.Println("  P2KB_INDEX_TTL     Index TTL in seconds (default: 86400)")
 			fmt.Println("  P2KB_LOG_LEVEL     Log level: debug, info, warn, error (default: info)")
+			fmt.Println("  P2KB_LOG_FORMAT    Log format: text or json (default: text)")
+			fmt.Println("  P2KB_HTTP_ADDR     HTTP transport listen address (default: 127.0.0.1:8080)")
+			fmt.Println("  P2KB_HTTP_TOKEN    Bearer token HTTP transport clients must send; required")
+			fmt.Println("                     to listen on a non-loopback address (default: none)")
 			fmt.Println()
-			fmt.Println("This server communicates via MCP protocol over stdin/stdout.")
+			fmt.Println("By default this server communicates via MCP protocol over stdin/stdout.")
 			fmt.Println("Configure it in your MCP client (e.g., Claude Desktop).")
+			fmt.Println("With --transport http it accepts JSON-RPC on POST /mcp and streams")
+			fmt.Println("each session's notifications as Server-Sent Events on GET /mcp/events.")
+			return
+		case "--browse":
+			// The browser draws on stdout; keep log lines out of its way
+			log.SetOutput(os.Stderr)
+			if err := browse.New(obex.NewManager(), os.Stdin, os.Stdout).Run(); err != nil {
+				fmt.Fprintf(os.Stderr, "p2kb-mcp: %v\n", err)
+				os.Exit(1)
+			}
 			return
 		}
 	}
 
 	// Configure logging to stderr (stdout is for MCP protocol)
-	log.SetOutput(os.Stderr)
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+	logging.Setup(os.Getenv("P2KB_LOG_LEVEL"), os.Getenv("P2KB_LOG_FORMAT"))
+	slog.Debug("P2KB MCP Server starting", "version", Version, "build_time", BuildTime, "commit", GitCommit)
 
-	logLevel := os.Getenv("P2KB_LOG_LEVEL")
-	if logLevel == "debug" {
-		log.Printf("P2KB MCP Server v%s (built %s, commit %s)", Version, BuildTime, GitCommit)
+	transport, err := transportFlag(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "p2kb-mcp: %v\n", err)
+		os.Exit(2)
 	}
 
 	srv := server.New(Version)
+	if err := srv.SetTransport(transport); err != nil {
+		fmt.Fprintf(os.Stderr, "p2kb-mcp: %v\n", err)
+		os.Exit(2)
+	}
 	if err := srv.Run(); err != nil {
-		log.Fatalf("Server error: %v", err)
+		slog.Error("server error", "error", err)
+		os.Exit(1)
+	}
+}
+
+// transportFlag returns the value of --transport ("--transport http" or
+// "--transport=http") in args, or server.TransportStdio if it is not given.
+// Other arguments are ignored, as they always have been.
+func transportFlag(args []string) (string, error) {
+	transport := server.TransportStdio
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--transport":
+			if i+1 == len(args) {
+				return "", fmt.Errorf("--transport needs a value: %s or %s", server.TransportStdio, server.TransportHTTP)
+			}
+			i++
+			transport = args[i]
+		case strings.HasPrefix(args[i], "--transport="):
+			transport = strings.TrimPrefix(args[i], "--transport=")
+		}
 	}
+	return transport, nil
 }
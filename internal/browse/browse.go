@@ -0,0 +1,269 @@
+// Package browse implements p2kb-mcp --browse, an interactive terminal
+// browser for the OBEX that runs in place of the MCP server.
+package browse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+)
+
+// ANSI escape sequences. Columns are placed with cursor-to-column rather than
+// padding, so titles with wide or multibyte characters still line up.
+const (
+	clearScreen = "\033[H\033[2J"
+	clearLine   = "\033[2K\r"
+	bold        = "\033[1m"
+	reset       = "\033[0m"
+)
+
+// column returns the escape sequence that moves the cursor to column n (1-based).
+func column(n int) string {
+	return fmt.Sprintf("\033[%dG", n)
+}
+
+// searchLimit caps the objects a /term search lists.
+const searchLimit = 50
+
+// OBEX is the part of the OBEX store the browser uses. *obex.Manager is the
+// production implementation.
+type OBEX interface {
+	GetCategories() (map[string]int, error)
+	BrowseCategory(category string) ([]obex.SearchResult, error)
+	Search(term, category, language, microcontroller string, limit int) ([]obex.SearchResult, error)
+	GetObject(objectID string) (*obex.OBEXObject, error)
+	GetDownloadURL(objectID string) string
+}
+
+var _ OBEX = (*obex.Manager)(nil)
+
+// Browser is an interactive OBEX browser reading commands from in and
+// drawing to out: numbers select, /term searches, q goes back (or quits from
+// the category list).
+type Browser struct {
+	obex   OBEX
+	in     *bufio.Scanner
+	out    io.Writer
+	status string // Shown above the next prompt, after the screen is redrawn
+}
+
+// New returns a Browser over store, reading commands from in and writing to out.
+func New(store OBEX, in io.Reader, out io.Writer) *Browser {
+	return &Browser{obex: store, in: bufio.NewScanner(in), out: out}
+}
+
+// Run shows the category list and handles commands until the user quits or
+// input ends.
+func (b *Browser) Run() error {
+	for {
+		counts, err := b.obex.GetCategories()
+		if err != nil {
+			return fmt.Errorf("failed to load OBEX categories: %w", err)
+		}
+		names := sortedCategories(counts)
+		b.showCategories(names, counts)
+
+		cmd, ok := b.prompt("Category number, /term to search, q to quit")
+		if !ok || cmd == "q" {
+			return nil
+		}
+
+		var title string
+		var objects []obex.SearchResult
+		if term, isSearch := strings.CutPrefix(cmd, "/"); isSearch {
+			title = fmt.Sprintf("Search: %s", term)
+			objects, err = b.search(term)
+		} else if n, ok := choice(cmd, len(names)); ok {
+			title = names[n]
+			objects, err = b.obex.BrowseCategory(names[n])
+		} else {
+			b.unknown(cmd)
+			continue
+		}
+		if err != nil {
+			b.showError(err)
+			continue
+		}
+		if !b.browseObjects(title, objects) {
+			return nil
+		}
+	}
+}
+
+// browseObjects lists objects until the user goes back. It returns false if
+// input ended.
+func (b *Browser) browseObjects(title string, objects []obex.SearchResult) bool {
+	pending := "" // A command typed on the object detail screen
+	for {
+		b.showObjects(title, objects)
+
+		cmd := pending
+		pending = ""
+		if cmd == "" {
+			var ok bool
+			if cmd, ok = b.prompt("Object number, /term to search, q to go back"); !ok {
+				return false
+			}
+		}
+		if cmd == "q" {
+			return true
+		}
+
+		if term, isSearch := strings.CutPrefix(cmd, "/"); isSearch {
+			results, err := b.search(term)
+			if err != nil {
+				b.showError(err)
+				continue
+			}
+			title, objects = fmt.Sprintf("Search: %s", term), results
+			continue
+		}
+
+		n, ok := choice(cmd, len(objects))
+		if !ok {
+			b.unknown(cmd)
+			continue
+		}
+		obj, err := b.obex.GetObject(objects[n].ObjectID)
+		if err != nil {
+			b.showError(err)
+			continue
+		}
+		b.showObject(obj)
+
+		cmd, ok = b.prompt("/term to search, q to go back")
+		if !ok {
+			return false
+		}
+		if strings.HasPrefix(cmd, "/") {
+			pending = cmd
+		}
+	}
+}
+
+func (b *Browser) search(term string) ([]obex.SearchResult, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return nil, fmt.Errorf("search term required")
+	}
+	return b.obex.Search(term, "", "", "", searchLimit)
+}
+
+func (b *Browser) showCategories(names []string, counts map[string]int) {
+	fmt.Fprintf(b.out, "%s%sOBEX Categories%s\n\n", clearScreen, bold, reset)
+	if len(names) == 0 {
+		fmt.Fprintln(b.out, "  (no categories)")
+	}
+	for i, name := range names {
+		fmt.Fprintf(b.out, "%4d  %s%s%d\n", i+1, name, column(30), counts[name])
+	}
+	fmt.Fprintln(b.out)
+}
+
+func (b *Browser) showObjects(title string, objects []obex.SearchResult) {
+	fmt.Fprintf(b.out, "%s%s%s%s (%d)\n\n", clearScreen, bold, title, reset, len(objects))
+	if len(objects) == 0 {
+		fmt.Fprintln(b.out, "  (no objects)")
+	}
+	for i, o := range objects {
+		fmt.Fprintf(b.out, "%4d  %s%s%s%s%s\n", i+1, o.ObjectID, column(14), o.Title, column(60), o.Author)
+	}
+	fmt.Fprintln(b.out)
+}
+
+func (b *Browser) showObject(obj *obex.OBEXObject) {
+	meta := obj.ObjectMetadata
+	fmt.Fprintf(b.out, "%s%s%s%s\n\n", clearScreen, bold, meta.Title, reset)
+
+	category := meta.Functionality.Category
+	if meta.Functionality.Subcategory != "" {
+		category += " / " + meta.Functionality.Subcategory
+	}
+	quality := ""
+	if meta.Metadata.QualityScore > 0 {
+		quality = strconv.Itoa(meta.Metadata.QualityScore)
+	}
+	fields := []struct {
+		label, value string
+	}{
+		{"Object ID", meta.ObjectID},
+		{"Author", meta.Author},
+		{"Category", category},
+		{"Languages", strings.Join(meta.TechnicalDetails.Languages, ", ")},
+		{"Microcontroller", strings.Join(meta.TechnicalDetails.Microcontroller, ", ")},
+		{"Version", meta.TechnicalDetails.Version},
+		{"Quality", quality},
+		{"Tags", strings.Join(meta.Functionality.Tags, ", ")},
+		{"Created", meta.Metadata.CreatedDate},
+		{"OBEX page", meta.URLs.OBEXPage},
+		{"Download", b.obex.GetDownloadURL(meta.ObjectID)},
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			fmt.Fprintf(b.out, "  %s:%s%s\n", f.label, column(22), f.value)
+		}
+	}
+
+	description := meta.Functionality.DescriptionFull
+	if description == "" {
+		description = meta.Functionality.DescriptionShort
+	}
+	if description = strings.TrimSpace(description); description != "" {
+		fmt.Fprintf(b.out, "\n%s\n", description)
+	}
+	fmt.Fprintln(b.out)
+}
+
+func (b *Browser) showError(err error) {
+	b.status = fmt.Sprintf("Error: %v", err)
+}
+
+func (b *Browser) unknown(cmd string) {
+	if cmd != "" {
+		b.status = fmt.Sprintf("Unknown command %q", cmd)
+	}
+}
+
+// prompt shows any pending status and text, and reads one trimmed command.
+// It returns false when input ends.
+func (b *Browser) prompt(text string) (string, bool) {
+	if b.status != "" {
+		fmt.Fprintf(b.out, "%s%s\n", clearLine, b.status)
+		b.status = ""
+	}
+	fmt.Fprintf(b.out, "%s%s> ", clearLine, text)
+	if !b.in.Scan() {
+		fmt.Fprintln(b.out)
+		return "", false
+	}
+	return strings.TrimSpace(b.in.Text()), true
+}
+
+// choice parses cmd as a 1-based selection from n items, returning its index.
+func choice(cmd string, n int) (int, bool) {
+	i, err := strconv.Atoi(cmd)
+	if err != nil || i < 1 || i > n {
+		return 0, false
+	}
+	return i - 1, true
+}
+
+// sortedCategories returns the category names, most populated first.
+func sortedCategories(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
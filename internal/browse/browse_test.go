@@ -0,0 +1,161 @@
+package browse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+)
+
+// fakeOBEX is a two-category OBEX: two drivers and one sensor.
+type fakeOBEX struct {
+	categoriesErr error
+}
+
+var fakeObjects = []obex.SearchResult{
+	{ObjectID: "2811", Title: "WS2812 LED Driver", Author: "Jon McPhalen", Category: "drivers"},
+	{ObjectID: "2813", Title: "I2C Driver", Author: "Chip Gracey", Category: "drivers"},
+	{ObjectID: "2815", Title: "VL53L1X Sensor", Author: "Stephen Moraco", Category: "sensors"},
+}
+
+func (f *fakeOBEX) GetCategories() (map[string]int, error) {
+	if f.categoriesErr != nil {
+		return nil, f.categoriesErr
+	}
+	return map[string]int{"drivers": 2, "sensors": 1}, nil
+}
+
+func (f *fakeOBEX) BrowseCategory(category string) ([]obex.SearchResult, error) {
+	var results []obex.SearchResult
+	for _, o := range fakeObjects {
+		if o.Category == category {
+			results = append(results, o)
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeOBEX) Search(term, category, language, microcontroller string, limit int) ([]obex.SearchResult, error) {
+	var results []obex.SearchResult
+	for _, o := range fakeObjects {
+		if strings.Contains(strings.ToLower(o.Title), strings.ToLower(term)) {
+			results = append(results, o)
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeOBEX) GetObject(objectID string) (*obex.OBEXObject, error) {
+	for _, o := range fakeObjects {
+		if o.ObjectID == objectID {
+			var obj obex.OBEXObject
+			obj.ObjectMetadata.ObjectID = o.ObjectID
+			obj.ObjectMetadata.Title = o.Title
+			obj.ObjectMetadata.Author = o.Author
+			obj.ObjectMetadata.Functionality.Category = o.Category
+			obj.ObjectMetadata.Functionality.DescriptionShort = "Drives " + o.Title
+			obj.ObjectMetadata.TechnicalDetails.Languages = []string{"SPIN2", "PASM2"}
+			obj.ObjectMetadata.Metadata.QualityScore = 8
+			return &obj, nil
+		}
+	}
+	return nil, fmt.Errorf("object %s not found", objectID)
+}
+
+func (f *fakeOBEX) GetDownloadURL(objectID string) string {
+	return "https://obex.parallax.com/wp-admin/admin-ajax.php?action=download&id=" + objectID
+}
+
+// browse runs a Browser over fakeOBEX with input and returns what it drew.
+func browse(t *testing.T, input string) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := New(&fakeOBEX{}, strings.NewReader(input), &out).Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return out.String()
+}
+
+func TestBrowseCategories(t *testing.T) {
+	out := browse(t, "q\n")
+
+	want := clearScreen + bold + "OBEX Categories" + reset + "\n\n" +
+		"   1  drivers\033[30G2\n" +
+		"   2  sensors\033[30G1\n\n" +
+		clearLine + "Category number, /term to search, q to quit> "
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestBrowseCategoryToObject(t *testing.T) {
+	// drivers, then the I2C driver, then back out to the categories and quit
+	out := browse(t, "1\n2\nq\nq\nq\n")
+
+	for _, want := range []string{
+		bold + "drivers" + reset + " (2)\n",
+		"   1  2811\033[14GWS2812 LED Driver\033[60GJon McPhalen\n",
+		"   2  2813\033[14GI2C Driver\033[60GChip Gracey\n",
+		bold + "I2C Driver" + reset + "\n\n",
+		"  Object ID:\033[22G2813\n",
+		"  Author:\033[22GChip Gracey\n",
+		"  Languages:\033[22GSPIN2, PASM2\n",
+		"  Quality:\033[22G8\n",
+		"  Download:\033[22Ghttps://obex.parallax.com/wp-admin/admin-ajax.php?action=download&id=2813\n",
+		"\nDrives I2C Driver\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+	if strings.Contains(out, "  Version:") {
+		t.Error("empty fields should be left out of the object detail")
+	}
+	if n := strings.Count(out, "OBEX Categories"); n != 2 {
+		t.Errorf("category list drawn %d times, want 2 (start and after going back)", n)
+	}
+}
+
+func TestBrowseSearch(t *testing.T) {
+	out := browse(t, "/led\nq\nq\n")
+	if !strings.Contains(out, bold+"Search: led"+reset+" (1)\n") || !strings.Contains(out, "WS2812 LED Driver") {
+		t.Errorf("search results missing from output:\n%q", out)
+	}
+
+	// A search from the object detail screen lists its results
+	out = browse(t, "2\n1\n/driver\nq\nq\n")
+	if !strings.Contains(out, bold+"Search: driver"+reset+" (2)\n") {
+		t.Errorf("search from the detail screen missing from output:\n%q", out)
+	}
+}
+
+func TestBrowseUnknownCommand(t *testing.T) {
+	out := browse(t, "9\nq\n")
+	if !strings.Contains(out, clearLine+"Unknown command \"9\"\n") {
+		t.Errorf("output lacks the unknown command message:\n%q", out)
+	}
+
+	out = browse(t, "/ \nq\n")
+	if !strings.Contains(out, "Error: search term required\n") {
+		t.Errorf("output lacks the empty search error:\n%q", out)
+	}
+}
+
+func TestBrowseEndOfInput(t *testing.T) {
+	// Input ending mid-browse quits without an error
+	for _, input := range []string{"", "1\n", "1\n1\n"} {
+		browse(t, input)
+	}
+}
+
+func TestBrowseCategoriesError(t *testing.T) {
+	var out bytes.Buffer
+	store := &fakeOBEX{categoriesErr: errors.New("no network")}
+	err := New(store, strings.NewReader("q\n"), &out).Run()
+	if err == nil || !strings.Contains(err.Error(), "no network") {
+		t.Errorf("Run() error = %v, want the categories error", err)
+	}
+}
@@ -2,16 +2,26 @@
 package cache
 
 import (
+	"archive/zip"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
+	"github.com/ironsheep/p2kb-mcp/internal/fetch"
 	"github.com/ironsheep/p2kb-mcp/internal/filter"
 	"github.com/ironsheep/p2kb-mcp/internal/paths"
 )
@@ -20,24 +30,55 @@ import (
 // const) so tests can point the remote tier at a local httptest server.
 var BaseContentURL = "https://raw.githubusercontent.com/ironsheep/P2-Knowledge-Base/main/"
 
+// MaxPinnedKeys caps how many keys may be pinned resident in the memory cache.
+const MaxPinnedKeys = 50
+
 // Manager handles caching of P2KB content.
 type Manager struct {
-	mu       sync.RWMutex
-	cacheDir string
-	memory   map[string]cacheEntry
+	mu            sync.RWMutex
+	cacheDir      string
+	memory        map[string]cacheEntry
+	pinnedKeys    map[string]bool // Keys pre-warmed at startup and exempt from eviction
+	accessClock   uint64          // Monotonic counter stamped on entries for LRU order
+	skippedWrites atomic.Int64    // Refetches whose content matched the cached copy, so the disk write was skipped
+	etagHits      atomic.Int64    // Conditional fetches answered 304 Not Modified
+	etagMisses    atomic.Int64    // Conditional fetches answered with new content
+	maxEntries    atomic.Int64    // Memory entries kept before LRU eviction; 0 means unbounded
+
+	// Disk writes are queued and coalesced by a writer goroutine (see
+	// writes.go). A key's pending content counts as on disk until written.
+	pendingWrites chan writeRequest // Nil when no writer runs; saveToDisk then writes directly
+	pendingMu     sync.Mutex
+	pending       map[string]writeRequest // Latest queued write per key
+	writeSeq      uint64
+	writerClosed  bool
+	writerStop    chan struct{}
+	writerDone    chan struct{}
+	writeMu       sync.Mutex // Serializes disk writes
 }
 
 type cacheEntry struct {
-	content string
-	mtime   int64
+	content     string
+	contentHash string // sha256Hex(content) when known; computed lazily otherwise
+	mtime       int64
+	lastAccess  uint64 // accessClock value at the last store or memory hit
+	etag        string // ETag the content was served with; "" when unknown
+	etagSHA256  string // Index digest the content was verified against when etag was stored
 }
 
-// NewManager creates a new cache manager.
+// NewManager creates a new cache manager, loading any persisted pinned keys,
+// and starts its disk writer. Close stops the writer.
 func NewManager() *Manager {
-	return &Manager{
-		cacheDir: paths.GetCacheDirOrDefault(),
-		memory:   make(map[string]cacheEntry),
+	m := &Manager{
+		cacheDir:   paths.GetCacheDirOrDefault(),
+		memory:     make(map[string]cacheEntry),
+		pinnedKeys: make(map[string]bool),
 	}
+	m.maxEntries.Store(int64(getCacheMaxEntries()))
+	m.removeLeftoverTemps()
+	m.loadPinned()
+	m.startWriter()
+	return m
 }
 
 // GetOrFetch resolves content for a key using an mtime-aware three-tier lookup.
@@ -57,12 +98,19 @@ func NewManager() *Manager {
 // CLAUDE.md — read locks are released before any disk or network I/O, and no
 // data lock is held across a fetch.
 func (m *Manager) GetOrFetch(key, path, expectedSHA256 string, indexMtime int64) (string, error) {
+	return m.GetOrFetchFrom(BaseContentURL, key, path, expectedSHA256, indexMtime)
+}
+
+// GetOrFetchFrom is GetOrFetch with an explicit content base URL for the
+// remote tier, used for keys provided by a P2KB_EXTRA_INDEX_URLS index.
+func (m *Manager) GetOrFetchFrom(baseURL, key, path, expectedSHA256 string, indexMtime int64) (string, error) {
 	// Tier 1: memory — only serve a fresh entry whose disk file still exists.
 	m.mu.RLock()
 	entry, ok := m.memory[key]
 	m.mu.RUnlock() // release BEFORE disk I/O
 
 	if ok && entry.mtime >= indexMtime && m.diskFileExists(key) {
+		m.touch(key)
 		return entry.content, nil
 	}
 
@@ -72,13 +120,118 @@ func (m *Manager) GetOrFetch(key, path, expectedSHA256 string, indexMtime int64)
 	}
 
 	// Tier 3: remote.
-	return m.fetchAndStore(key, path, expectedSHA256, indexMtime)
+	return m.fetchAndStore(baseURL, key, path, expectedSHA256, indexMtime)
+}
+
+// touch marks a memory entry as just used, for LRU eviction. Only the access
+// stamp is updated, so a concurrent store of newer content is never undone.
+func (m *Manager) touch(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, ok := m.memory[key]; ok {
+		entry.lastAccess = m.nextAccessLocked()
+		m.memory[key] = entry
+	}
+}
+
+// nextAccessLocked advances the access clock. Caller holds m.mu.
+func (m *Manager) nextAccessLocked() uint64 {
+	m.accessClock++
+	return m.accessClock
+}
+
+// EvictMemory drops least recently used memory entries until at most target
+// remain, never evicting pinned keys (so more than target may remain when
+// many keys are pinned). Disk entries are untouched, so evicted keys are
+// re-hydrated from disk on their next read. Returns how many entries were
+// evicted and the total length of their content.
+func (m *Manager) EvictMemory(target int) (evicted int, freedBytes int64) {
+	if target < 0 {
+		target = 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.evictLocked(target)
+}
+
+// DropMemory drops every memory entry, pinned or not, so each key is read
+// from disk again on its next lookup. Used after files in the cache directory
+// change underneath the manager.
+func (m *Manager) DropMemory() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.memory = make(map[string]cacheEntry)
+}
+
+// evictLocked is EvictMemory for a caller holding m.mu.
+func (m *Manager) evictLocked(target int) (evicted int, freedBytes int64) {
+	candidates := make([]string, 0, len(m.memory))
+	for key := range m.memory {
+		if !m.pinnedKeys[key] {
+			candidates = append(candidates, key)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return m.memory[candidates[i]].lastAccess < m.memory[candidates[j]].lastAccess
+	})
+
+	for _, key := range candidates {
+		if len(m.memory) <= target {
+			break
+		}
+		freedBytes += int64(len(m.memory[key].content))
+		delete(m.memory, key)
+		evicted++
+	}
+	return evicted, freedBytes
+}
+
+// SetMaxEntries caps the memory cache at n entries, evicting the least
+// recently used down to n now and whenever a store goes over. Pinned keys
+// are never evicted, so they may exceed the cap. n <= 0 removes the cap.
+func (m *Manager) SetMaxEntries(n int) {
+	if n < 0 {
+		n = 0
+	}
+	m.maxEntries.Store(int64(n))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enforceMaxEntriesLocked()
+}
+
+// MaxEntries returns the memory cache cap, or 0 if it is unbounded.
+func (m *Manager) MaxEntries() int {
+	return int(m.maxEntries.Load())
+}
+
+// enforceMaxEntriesLocked evicts down to the memory cache cap, if there is
+// one. Caller holds m.mu.
+func (m *Manager) enforceMaxEntriesLocked() {
+	if limit := int(m.maxEntries.Load()); limit > 0 && len(m.memory) > limit {
+		m.evictLocked(limit)
+	}
+}
+
+// getCacheMaxEntries returns P2KB_CACHE_MAX_ENTRIES, or 0 (unbounded) if it
+// is unset or invalid.
+func getCacheMaxEntries() int {
+	if v := os.Getenv("P2KB_CACHE_MAX_ENTRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 0
 }
 
 // diskFileExists reports whether the cached file for key is present on disk.
 // Statted on every memory-tier read; negligible cost, and it enforces the
 // "removed disk file => not served from memory" invariant.
 func (m *Manager) diskFileExists(key string) bool {
+	if _, ok := m.pendingWrite(key); ok {
+		return true
+	}
 	_, err := os.Stat(m.cachePath(key))
 	return err == nil
 }
@@ -88,6 +241,14 @@ func (m *Manager) diskFileExists(key string) bool {
 // Returns (content, true) on a fresh hit, ("", false) when absent or stale.
 // It reuses the os.Stat result for hydration so the disk tier stats once.
 func (m *Manager) loadFromDiskIfFresh(key string, indexMtime int64) (string, bool) {
+	if req, ok := m.pendingWrite(key); ok {
+		if req.mtime < indexMtime {
+			return "", false
+		}
+		m.hydrate(key, req.content, req.mtime)
+		return req.content, true
+	}
+
 	info, err := os.Stat(m.cachePath(key))
 	if err != nil || info.ModTime().Unix() < indexMtime {
 		return "", false
@@ -131,14 +292,61 @@ func (e *VerificationError) Error() string {
 // index, graceful degrade) — the content is filtered and cached. On a
 // persistent mismatch nothing is cached and a *VerificationError is returned;
 // the slot stays empty so the next natural request retries.
-func (m *Manager) fetchAndStore(key, path, expectedSHA256 string, indexMtime int64) (string, error) {
+//
+// When the cached copy has an ETag (see revalidationETag) the request is
+// conditional, and a 304 Not Modified keeps the cached copy, restamped with
+// indexMtime, without transferring or writing the content again.
+func (m *Manager) fetchAndStore(baseURL, key, path, expectedSHA256 string, indexMtime int64) (string, error) {
+	cached, etag := m.revalidationETag(key, expectedSHA256)
+	resp, err := m.fetchVerified(baseURL, key, path, expectedSHA256, etag)
+	if err != nil {
+		return "", err
+	}
+	if resp.notModified {
+		m.etagHits.Add(1)
+		m.keepCached(key, cached, etag, expectedSHA256, indexMtime)
+		return cached, nil
+	}
+	if etag != "" {
+		m.etagMisses.Add(1)
+	}
+	return m.filterAndCache(key, resp.body, resp.etag, expectedSHA256, indexMtime), nil
+}
+
+// Refetch is GetOrFetchFrom without the memory and disk tiers: it always
+// fetches key's content, unconditionally, verifies it like the remote tier,
+// and replaces the cached copy with it.
+func (m *Manager) Refetch(baseURL, key, path, expectedSHA256 string, indexMtime int64) (string, error) {
+	resp, err := m.fetchVerified(baseURL, key, path, expectedSHA256, "")
+	if err != nil {
+		return "", err
+	}
+	return m.filterAndCache(key, resp.body, resp.etag, expectedSHA256, indexMtime), nil
+}
+
+// FetchRaw downloads key's content and returns it unfiltered, for inspecting
+// the metadata fields FilterMetadata strips. It always goes to the network,
+// since only filtered content is cached, and verifies the download like the
+// remote tier of GetOrFetch. The filtered form is cached as usual; the raw
+// form never is.
+func (m *Manager) FetchRaw(baseURL, key, path, expectedSHA256 string, indexMtime int64) (string, error) {
+	resp, err := m.fetchVerified(baseURL, key, path, expectedSHA256, "")
+	if err != nil {
+		return "", err
+	}
+	m.filterAndCache(key, resp.body, resp.etag, expectedSHA256, indexMtime)
+	return resp.body, nil
+}
+
+// fetchVerified fetches the raw content at baseURL+path. With expectedSHA256
+// set it retries with cache busting until the download matches, returning a
+// *VerificationError if it never does. A non-empty etag makes the first
+// request conditional; a 304 answer is returned as is, since the content it
+// refers to was verified when it was cached.
+func (m *Manager) fetchVerified(baseURL, key, path, expectedSHA256, etag string) (contentResponse, error) {
 	// Legacy / unverifiable path: a single non-busted fetch, no verification.
 	if expectedSHA256 == "" {
-		content, err := m.fetchContent(path, false)
-		if err != nil {
-			return "", err
-		}
-		return m.filterAndCache(key, content, indexMtime), nil
+		return m.fetchContent(baseURL, path, etag, false)
 	}
 
 	// Verified path. Attempt 0 rides the CDN edge; later attempts cache-bust
@@ -150,36 +358,80 @@ func (m *Manager) fetchAndStore(key, path, expectedSHA256 string, indexMtime int
 			time.Sleep(contentRetryBackoff)
 		}
 
-		content, err := m.fetchContent(path, bust)
+		resp, err := m.fetchContent(baseURL, path, etag, bust)
 		if err != nil {
-			return "", err
+			return contentResponse{}, err
+		}
+		if resp.notModified {
+			return resp, nil
 		}
+		etag = "" // Cache-busting retries are never conditional
 
-		actual = sha256Hex(content)
+		actual = sha256Hex(resp.body)
 		if actual == expectedSHA256 {
-			return m.filterAndCache(key, content, indexMtime), nil
+			return resp, nil
 		}
 	}
 
 	// Persistent mismatch: do NOT cache. Leave the slot empty for a later retry.
-	return "", &VerificationError{Key: key, Expected: expectedSHA256, Actual: actual}
+	return contentResponse{}, &VerificationError{Key: key, Expected: expectedSHA256, Actual: actual}
 }
 
 // filterAndCache filters fetched content and stores it in memory and on disk,
-// stamped with indexMtime. Shared by the verified and legacy fetch paths.
-func (m *Manager) filterAndCache(key, rawContent string, indexMtime int64) string {
+// stamped with indexMtime, along with the ETag it was served with and the
+// index digest it was verified against. Shared by the verified and legacy
+// fetch paths. When the content matches the copy already cached (an index
+// bump that did not change this entry), the disk file is only restamped, not
+// rewritten.
+func (m *Manager) filterAndCache(key, rawContent, etag, etagSHA256 string, indexMtime int64) string {
 	filtered := filter.FilterMetadata(rawContent)
+	hash := sha256Hex(filtered)
 
 	m.mu.Lock()
-	m.memory[key] = cacheEntry{content: filtered, mtime: indexMtime}
+	prev, had := m.memory[key]
+	m.memory[key] = cacheEntry{
+		content:     filtered,
+		contentHash: hash,
+		mtime:       indexMtime,
+		lastAccess:  m.nextAccessLocked(),
+		etag:        etag,
+		etagSHA256:  etagSHA256,
+	}
+	m.enforceMaxEntriesLocked()
 	m.mu.Unlock()
 
+	if had {
+		prevHash := prev.contentHash
+		if prevHash == "" {
+			prevHash = sha256Hex(prev.content)
+		}
+		if prevHash == hash && m.diskFileExists(key) && m.stampDiskMtime(key, etag, etagSHA256, indexMtime) == nil {
+			m.skippedWrites.Add(1)
+			slog.Debug("content unchanged, skipping disk write", "key", key)
+			return filtered
+		}
+	}
+
 	// Save to disk (best effort), stamping the file mtime to match indexMtime.
-	_ = m.saveToDisk(key, filtered, indexMtime)
+	_ = m.saveToDiskWithETag(key, filtered, etag, etagSHA256, indexMtime)
 
 	return filtered
 }
 
+// stampDiskMtime sets the cache file's mtime for key, and records the ETag it
+// was served with, without rewriting it. A write still pending is queued
+// again with the new mtime and ETag.
+func (m *Manager) stampDiskMtime(key, etag, etagSHA256 string, mtime int64) error {
+	if req, ok := m.pendingWrite(key); ok {
+		return m.saveToDiskWithETag(key, req.content, etag, etagSHA256, mtime)
+	}
+	t := time.Unix(mtime, 0)
+	if err := os.Chtimes(m.cachePath(key), t, t); err != nil {
+		return err
+	}
+	return m.writeETag(key, etag, etagSHA256)
+}
+
 // sha256Hex returns the lowercase hex sha256 digest of s, matching the digest
 // format the index generator emits for the raw content blob.
 func sha256Hex(s string) string {
@@ -195,7 +447,8 @@ func (m *Manager) Clear() {
 	// Clear memory cache
 	m.memory = make(map[string]cacheEntry)
 
-	// Clear disk cache
+	// Clear disk cache, including writes not yet made
+	m.dropPending(nil)
 	cacheDir := filepath.Join(m.cacheDir, "cache")
 	_ = os.RemoveAll(cacheDir)
 }
@@ -206,16 +459,29 @@ func (m *Manager) Invalidate(key string) {
 	defer m.mu.Unlock()
 
 	delete(m.memory, key)
+	m.dropPending([]string{key})
 	cachePath := m.cachePath(key)
 	_ = os.Remove(cachePath)
+	_ = os.Remove(m.etagPath(key))
+}
+
+// contentResponse is one fetch of a content file: its raw body and the ETag it
+// was served with, or notModified when a conditional request was answered
+// 304 Not Modified and there is no body.
+type contentResponse struct {
+	body        string
+	etag        string
+	notModified bool
 }
 
 // fetchContent fetches content from the remote URL. When bust is true it adds a
 // cache-busting query parameter and no-cache headers to bypass the GitHub CDN
 // (Fastly), mirroring the index fetch — used only to re-fetch after a sha256
-// mismatch, never on the normal path.
-func (m *Manager) fetchContent(path string, bust bool) (string, error) {
-	url := BaseContentURL + path
+// mismatch, never on the normal path. A non-empty etag is sent as
+// If-None-Match, making the request conditional. GitHub requests carry a
+// token from fetch.SharedTokenPool.
+func (m *Manager) fetchContent(baseURL, path, etag string, bust bool) (contentResponse, error) {
+	url := baseURL + path
 	if bust {
 		// Fastly keys on the query string but ignores client Cache-Control;
 		// the unique ?t= is what actually forces a fresh origin fetch.
@@ -224,29 +490,73 @@ func (m *Manager) fetchContent(path string, bust bool) (string, error) {
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create content request: %w", err)
+		return contentResponse{}, fmt.Errorf("failed to create content request: %w", err)
 	}
 	if bust {
 		req.Header.Set("Cache-Control", "no-cache, no-store, must-revalidate")
 		req.Header.Set("Pragma", "no-cache")
 	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := fetch.NewClient().HTTPClient().Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch content: %w", err)
+		return contentResponse{}, fmt.Errorf("failed to fetch content: %w", errs.Transport(err))
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && etag != "" {
+		return contentResponse{etag: etag, notModified: true}, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch content: HTTP %d", resp.StatusCode)
+		return contentResponse{}, fmt.Errorf("failed to fetch content: %w", errs.HTTPStatus(resp, url))
 	}
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read content: %w", err)
+		return contentResponse{}, fmt.Errorf("failed to read content: %w", err)
 	}
 
-	return string(data), nil
+	if err := checkContentResponse(url, resp.Header.Get("Content-Type"), data); err != nil {
+		return contentResponse{}, err
+	}
+
+	return contentResponse{body: string(data), etag: resp.Header.Get("ETag")}, nil
+}
+
+// Limits for checkContentResponse: how much of the body is sniffed for HTML,
+// searched for a YAML key, and logged when the response is rejected.
+const (
+	htmlSniffBytes  = 512
+	yamlSniffBytes  = 200
+	logPreviewBytes = 100
+)
+
+// checkContentResponse rejects a 200 response that is not a YAML file: an
+// HTML page (an error page from GitHub, a captive portal, a misconfigured
+// mirror) or a body with no "key: value" pair near the start.
+func checkContentResponse(url, contentType string, data []byte) error {
+	sniff := strings.ToLower(strings.TrimSpace(string(data[:min(len(data), htmlSniffBytes)])))
+	if strings.Contains(strings.ToLower(contentType), "text/html") ||
+		strings.HasPrefix(sniff, "<!doctype") || strings.HasPrefix(sniff, "<html") {
+		logUnexpectedResponse(url, contentType, data)
+		return fmt.Errorf("unexpected HTML response from %s - possible network interception or redirect", url)
+	}
+
+	head := string(data[:min(len(data), yamlSniffBytes)])
+	if !strings.Contains(head, ": ") && !strings.Contains(head, ":\n") {
+		logUnexpectedResponse(url, contentType, data)
+		return fmt.Errorf("unexpected response from %s: content does not look like YAML", url)
+	}
+	return nil
+}
+
+func logUnexpectedResponse(url, contentType string, data []byte) {
+	slog.Debug("unexpected content response",
+		"url", url,
+		"content_type", contentType,
+		"body", string(data[:min(len(data), logPreviewBytes)]))
 }
 
 // cachePath returns the on-disk path for a cached key's content file.
@@ -274,23 +584,52 @@ func (m *Manager) readAndHydrate(key string, info os.FileInfo) (string, error) {
 	}
 
 	// Also store in memory cache for faster access next time, preserving mtime
-	m.mu.Lock()
-	m.memory[key] = cacheEntry{content: string(data), mtime: info.ModTime().Unix()}
-	m.mu.Unlock()
+	m.hydrate(key, string(data), info.ModTime().Unix())
 
 	return string(data), nil
 }
 
-// saveToDisk saves content to disk cache and stamps the file mtime to match
-// the YAML mtime so that staleness detection survives process restarts.
+// hydrate stores content read back from the disk tier in the memory cache.
+func (m *Manager) hydrate(key, content string, mtime int64) {
+	m.mu.Lock()
+	m.memory[key] = cacheEntry{content: content, mtime: mtime, lastAccess: m.nextAccessLocked()}
+	m.enforceMaxEntriesLocked()
+	m.mu.Unlock()
+}
+
+// saveToDisk saves content to disk cache, stamped with the YAML mtime. With
+// the disk writer running the write is queued, coalescing with other writes
+// of the key, and errors are logged rather than returned.
 func (m *Manager) saveToDisk(key, content string, mtime int64) error {
+	return m.saveToDiskWithETag(key, content, "", "", mtime)
+}
+
+// saveToDiskWithETag is saveToDisk for content served with an ETag, which is
+// written to the key's sidecar file once the content is on disk.
+func (m *Manager) saveToDiskWithETag(key, content, etag, etagSHA256 string, mtime int64) error {
+	if m.queueWrite(key, content, etag, etagSHA256, mtime) {
+		return nil
+	}
+	if err := m.writeToDisk(key, content, mtime); err != nil {
+		return err
+	}
+	return m.writeETag(key, etag, etagSHA256)
+}
+
+// writeToDisk writes content to the disk cache now and stamps the file mtime
+// to match the YAML mtime so that staleness detection survives process restarts.
+// The key's ETag sidecar is removed first, as it described the old content.
+func (m *Manager) writeToDisk(key, content string, mtime int64) error {
 	cacheDir := filepath.Join(m.cacheDir, "cache")
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		return err
 	}
+	if err := os.Remove(m.etagPath(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 
 	cachePath := m.cachePath(key)
-	if err := os.WriteFile(cachePath, []byte(content), 0644); err != nil {
+	if err := writeFileAtomic(cachePath, []byte(content), 0644); err != nil {
 		return err
 	}
 
@@ -300,12 +639,124 @@ func (m *Manager) saveToDisk(key, content string, mtime int64) error {
 	return os.Chtimes(cachePath, t, t)
 }
 
+// tempSuffix marks a cache file still being written by writeFileAtomic.
+const tempSuffix = ".tmp"
+
+// writeTemp writes data to a temp file. It is a var so tests can cut a write
+// short the way a crash would.
+var writeTemp = func(f *os.File, data []byte) error {
+	_, err := f.Write(data)
+	return err
+}
+
+// writeFileAtomic writes data to path+".tmp", syncs it and renames it over
+// path, so a crash part way through leaves path either as it was or complete,
+// never partially written. The rename is atomic on POSIX file systems.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + tempSuffix
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if err := writeTemp(f, data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// removeLeftoverTemps deletes the .yaml.tmp and .etag.tmp files of writes a
+// crash cut short.
+func (m *Manager) removeLeftoverTemps() {
+	matches, err := filepath.Glob(filepath.Join(m.cacheDir, "cache", "*"+tempSuffix))
+	if err != nil {
+		return
+	}
+	for _, path := range matches {
+		if err := os.Remove(path); err != nil {
+			slog.Warn("failed to remove leftover cache temp file", "path", path, "error", err)
+			continue
+		}
+		slog.Info("removed leftover cache temp file", "path", path)
+	}
+}
+
 // CacheStats contains statistics about the cache.
 type CacheStats struct {
-	MemoryEntries int   `json:"memory_entries"`
-	DiskEntries   int   `json:"disk_entries"`
-	DiskSizeBytes int64 `json:"disk_size_bytes"`
+	MemoryEntries int    `json:"memory_entries"`
+	DiskEntries   int    `json:"disk_entries"`
+	DiskSizeBytes int64  `json:"disk_size_bytes"`
+	PinnedEntries int    `json:"pinned_entry_count"`
 	CacheDir      string `json:"cache_dir"`
+	SkippedWrites int64  `json:"skipped_disk_writes"` // Refetches that matched the cached content
+	ETagHits      int64  `json:"etag_hits"`           // Conditional fetches answered 304 Not Modified
+	ETagMisses    int64  `json:"etag_misses"`         // Conditional fetches answered with new content
+
+	DuplicateContentGroups int `json:"duplicate_content_groups"` // See FindDuplicates
+}
+
+// CacheDir returns the root directory the cache stores its files under.
+func (m *Manager) CacheDir() string {
+	return m.cacheDir
+}
+
+// MemoryEntry describes one entry in the memory cache.
+type MemoryEntry struct {
+	Key           string `json:"key"`
+	Mtime         int64  `json:"mtime"`
+	ContentLength int    `json:"content_length"`
+	ContentHash   string `json:"content_hash"`
+	FirstChars    string `json:"first_100_chars"`
+}
+
+// memoryEntryPreviewChars is how much content MemoryEntries shows per entry.
+const memoryEntryPreviewChars = 100
+
+// MemoryEntries lists the memory cache by key, with a hash and the first 100
+// characters of each entry's content. Hashing happens after the lock is released.
+func (m *Manager) MemoryEntries() []MemoryEntry {
+	m.mu.RLock()
+	snapshot := make(map[string]cacheEntry, len(m.memory))
+	for key, entry := range m.memory {
+		snapshot[key] = entry
+	}
+	m.mu.RUnlock()
+
+	entries := make([]MemoryEntry, 0, len(snapshot))
+	for key, entry := range snapshot {
+		hash := entry.contentHash
+		if hash == "" {
+			hash = sha256Hex(entry.content)
+		}
+		// 4 bytes per rune at most, so this slice holds every rune we keep
+		head := entry.content
+		if len(head) > utf8.UTFMax*memoryEntryPreviewChars {
+			head = head[:utf8.UTFMax*memoryEntryPreviewChars]
+		}
+		first := []rune(head)
+		if len(first) > memoryEntryPreviewChars {
+			first = first[:memoryEntryPreviewChars]
+		}
+		entries = append(entries, MemoryEntry{
+			Key:           key,
+			Mtime:         entry.mtime,
+			ContentLength: len(entry.content),
+			ContentHash:   hash,
+			FirstChars:    string(first),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
 }
 
 // GetCachedKeys returns a list of all cached keys (memory + disk).
@@ -321,6 +772,10 @@ func (m *Manager) GetCachedKeys() []string {
 	}
 	m.mu.RUnlock() // Release read lock BEFORE disk I/O
 
+	for _, key := range m.pendingKeys() {
+		keySet[key] = struct{}{}
+	}
+
 	// Add disk keys - no lock needed for reading directory
 	cacheDir := filepath.Join(m.cacheDir, "cache")
 	entries, err := os.ReadDir(cacheDir)
@@ -346,8 +801,12 @@ func (m *Manager) GetCachedKeys() []string {
 
 // GetStats returns cache statistics.
 func (m *Manager) GetStats() CacheStats {
+	// Write queued entries so the disk counts include them
+	m.Flush()
+
 	m.mu.RLock()
 	memoryCount := len(m.memory)
+	pinnedCount := len(m.pinnedKeys)
 	m.mu.RUnlock()
 
 	var diskCount int
@@ -373,10 +832,47 @@ func (m *Manager) GetStats() CacheStats {
 		MemoryEntries: memoryCount,
 		DiskEntries:   diskCount,
 		DiskSizeBytes: diskSize,
+		PinnedEntries: pinnedCount,
 		CacheDir:      m.cacheDir,
+		SkippedWrites: m.skippedWrites.Load(),
+		ETagHits:      m.etagHits.Load(),
+		ETagMisses:    m.etagMisses.Load(),
+
+		DuplicateContentGroups: len(m.FindDuplicates()),
 	}
 }
 
+// FindDuplicates groups memory-cached keys whose content is identical. It
+// returns content SHA-256 -> keys (sorted) for every hash shared by two or
+// more keys. Only the memory cache is examined; nothing is read from disk or
+// the network, and nothing is deduplicated.
+func (m *Manager) FindDuplicates() map[string][]string {
+	m.mu.RLock()
+	snapshot := make(map[string]cacheEntry, len(m.memory))
+	for key, entry := range m.memory {
+		snapshot[key] = entry
+	}
+	m.mu.RUnlock()
+
+	byHash := make(map[string][]string)
+	for key, entry := range snapshot {
+		hash := entry.contentHash
+		if hash == "" {
+			hash = sha256Hex(entry.content)
+		}
+		byHash[hash] = append(byHash[hash], key)
+	}
+
+	duplicates := make(map[string][]string)
+	for hash, keys := range byHash {
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			duplicates[hash] = keys
+		}
+	}
+	return duplicates
+}
+
 // GetMtime returns the cached mtime for a key, or 0 if not cached.
 // When the key is absent from the memory map it falls back to os.Stat on the
 // cache file so that disk-only entries (after a process restart) still report
@@ -393,6 +889,10 @@ func (m *Manager) GetMtime(key string) int64 {
 	}
 	m.mu.RUnlock() // release BEFORE disk I/O
 
+	if req, ok := m.pendingWrite(key); ok {
+		return req.mtime
+	}
+
 	// Slow path: fall back to the stamped filesystem mtime
 	cachePath := m.cachePath(key)
 	if info, err := os.Stat(cachePath); err == nil {
@@ -412,11 +912,239 @@ func (m *Manager) InvalidateKeys(keys []string) int {
 			delete(m.memory, key)
 			count++
 		}
+		// A write not yet made counts as a disk entry removed
+		dropped := m.dropPending([]string{key}) > 0
 		cachePath := m.cachePath(key)
-		if err := os.Remove(cachePath); err == nil {
+		if err := os.Remove(cachePath); err == nil || dropped {
 			count++
 		}
+		_ = os.Remove(m.etagPath(key))
 	}
 	return count
 }
 
+// Pin adds keys to the pinned set and persists it to pinned.json. Pinned keys
+// are pre-warmed at startup and are never evicted from the memory cache.
+// Returns an error, without pinning anything, if the set would exceed
+// MaxPinnedKeys.
+func (m *Manager) Pin(keys []string) error {
+	m.mu.Lock()
+	if m.pinnedKeys == nil {
+		m.pinnedKeys = make(map[string]bool)
+	}
+
+	// A key listed twice is pinned once
+	added := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if !m.pinnedKeys[key] {
+			added[key] = true
+		}
+	}
+	if len(m.pinnedKeys)+len(added) > MaxPinnedKeys {
+		count := len(m.pinnedKeys)
+		m.mu.Unlock()
+		return fmt.Errorf("cannot pin %d more keys: %d already pinned, limit is %d", len(added), count, MaxPinnedKeys)
+	}
+
+	for _, key := range keys {
+		m.pinnedKeys[key] = true
+	}
+	pinned := m.pinnedListLocked()
+	m.mu.Unlock() // release BEFORE disk I/O
+
+	return m.savePinned(pinned)
+}
+
+// Unpin removes keys from the pinned set and persists the result.
+// Returns the number of keys that were actually pinned.
+func (m *Manager) Unpin(keys []string) (int, error) {
+	m.mu.Lock()
+	removed := 0
+	for _, key := range keys {
+		if m.pinnedKeys[key] {
+			delete(m.pinnedKeys, key)
+			removed++
+		}
+	}
+	pinned := m.pinnedListLocked()
+	m.mu.Unlock() // release BEFORE disk I/O
+
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, m.savePinned(pinned)
+}
+
+// IsPinned reports whether key is in the pinned set.
+func (m *Manager) IsPinned(key string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pinnedKeys[key]
+}
+
+// PinnedKeys returns the pinned keys, sorted.
+func (m *Manager) PinnedKeys() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pinnedListLocked()
+}
+
+// Prewarm loads every pinned key into the memory cache. fetch resolves and
+// fetches one key's content (the server's getContent, which routes through
+// GetOrFetch or GetOrFetchFrom). Keys that fail are skipped; returns how many
+// were warmed.
+func (m *Manager) Prewarm(fetch func(key string) (string, error)) int {
+	warmed := 0
+	for _, key := range m.PinnedKeys() {
+		if _, err := fetch(key); err == nil {
+			warmed++
+		}
+	}
+	return warmed
+}
+
+// pinnedListLocked returns the pinned set as a sorted slice. Caller holds m.mu.
+func (m *Manager) pinnedListLocked() []string {
+	keys := make([]string, 0, len(m.pinnedKeys))
+	for key := range m.pinnedKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pinnedPath returns the location of the persisted pinned-key list.
+func (m *Manager) pinnedPath() string {
+	return filepath.Join(m.cacheDir, "pinned.json")
+}
+
+// loadPinned restores the pinned set from pinned.json (best effort).
+func (m *Manager) loadPinned() {
+	data, err := os.ReadFile(m.pinnedPath())
+	if err != nil {
+		return
+	}
+
+	var keys []string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		m.pinnedKeys[key] = true
+	}
+}
+
+// savePinned writes the pinned set to pinned.json.
+func (m *Manager) savePinned(keys []string) error {
+	if err := os.MkdirAll(m.cacheDir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.pinnedPath(), data, 0644)
+}
+
+// SeedArchiveName is the seed archive picked up from the cache directory when
+// P2KB_SEED_ARCHIVE is not set.
+const SeedArchiveName = "p2kb-cache.zip"
+
+// seedIndexEntry is the archive path of the index snapshot in a seed archive.
+const seedIndexEntry = "index/p2kb-index.json"
+
+// LoadFromSeedArchive pre-populates the cache from a ZIP archive for offline
+// installations. Entries named cache/{key}.yaml are stored in memory and on
+// disk stamped with mtime 0, so any index mtime still supersedes them once the
+// network (or a local content mirror) is reachable. index/p2kb-index.json, if
+// present, seeds the index cache. Keys already cached on disk and an existing
+// index cache are left untouched. Returns how many content entries were loaded.
+func (m *Manager) LoadFromSeedArchive(archivePath string) (int, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open seed archive: %w", err)
+	}
+	defer zr.Close()
+
+	loaded := 0
+	for _, f := range zr.File {
+		if f.Name == seedIndexEntry {
+			if err := m.seedIndex(f); err != nil {
+				return loaded, err
+			}
+			continue
+		}
+
+		key, ok := seedEntryKey(f.Name)
+		if !ok || m.diskFileExists(key) {
+			continue
+		}
+
+		content, err := readZipFile(f)
+		if err != nil {
+			return loaded, fmt.Errorf("failed to read %s from seed archive: %w", f.Name, err)
+		}
+
+		m.mu.Lock()
+		if _, cached := m.memory[key]; !cached {
+			m.memory[key] = cacheEntry{content: content, mtime: 0, lastAccess: m.nextAccessLocked()}
+		}
+		m.mu.Unlock()
+
+		if err := m.writeToDisk(key, content, 0); err != nil {
+			return loaded, fmt.Errorf("failed to cache %s: %w", key, err)
+		}
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// seedEntryKey extracts the key from a cache/{key}.yaml archive entry name,
+// rejecting nested paths so an archive cannot write outside the cache.
+func seedEntryKey(name string) (string, bool) {
+	if !strings.HasPrefix(name, "cache/") || !strings.HasSuffix(name, ".yaml") {
+		return "", false
+	}
+	key := strings.TrimSuffix(strings.TrimPrefix(name, "cache/"), ".yaml")
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.Contains(key, "..") {
+		return "", false
+	}
+	return key, true
+}
+
+// seedIndex writes the archived index to the index cache unless one exists.
+func (m *Manager) seedIndex(f *zip.File) error {
+	indexPath := filepath.Join(m.cacheDir, "index", "p2kb-index.json")
+	if _, err := os.Stat(indexPath); err == nil {
+		return nil
+	}
+
+	data, err := readZipFile(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s from seed archive: %w", f.Name, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(indexPath, []byte(data), 0644)
+}
+
+// readZipFile returns the full contents of one archive entry.
+func readZipFile(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -1,17 +1,21 @@
 package cache
 
 import (
+	"archive/zip"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
 	"github.com/ironsheep/p2kb-mcp/internal/filter"
 	"github.com/ironsheep/p2kb-mcp/internal/paths"
 )
@@ -65,6 +69,29 @@ func TestGetOrFetchMemoryTier(t *testing.T) {
 	}
 }
 
+// TestDropMemory verifies that after DropMemory a key is served from the file
+// on disk, not the memory entry it replaced.
+func TestDropMemory(t *testing.T) {
+	m := &Manager{
+		cacheDir:   t.TempDir(),
+		memory:     make(map[string]cacheEntry),
+		pinnedKeys: map[string]bool{"test-key": true},
+	}
+	primeCache(t, m, "test-key", "old content", knownMtime)
+	if err := m.saveToDisk("test-key", "migrated content", knownMtime); err != nil {
+		t.Fatalf("saveToDisk failed: %v", err)
+	}
+
+	m.DropMemory()
+	content, err := m.GetOrFetch("test-key", "bogus/should-not-fetch.yaml", "", knownMtime)
+	if err != nil {
+		t.Fatalf("GetOrFetch should serve from disk: %v", err)
+	}
+	if content != "migrated content" {
+		t.Errorf("content = %q, want the disk copy, even for a pinned key", content)
+	}
+}
+
 func TestManagerClear(t *testing.T) {
 	tmpDir := t.TempDir()
 	m := &Manager{
@@ -414,7 +441,7 @@ func primeCache(t *testing.T, m *Manager, key, content string, mtime int64) {
 // TestGetOrFetchRefetchesOnNewerIndex (invariant a): when the index mtime
 // advances past the cached entry, GetOrFetch must re-fetch from remote.
 func TestGetOrFetchRefetchesOnNewerIndex(t *testing.T) {
-	hits := stubRemote(t, "fresh remote content")
+	hits := stubRemote(t, "description: fresh remote content\n")
 	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
 	const key = "k"
 
@@ -427,16 +454,79 @@ func TestGetOrFetchRefetchesOnNewerIndex(t *testing.T) {
 	if got := atomic.LoadInt32(hits); got != 1 {
 		t.Errorf("remote fetches = %d, want 1 (newer index must refetch)", got)
 	}
-	if want := filter.FilterMetadata("fresh remote content"); content != want {
+	if want := filter.FilterMetadata("description: fresh remote content\n"); content != want {
 		t.Errorf("content = %q, want %q", content, want)
 	}
 }
 
+// TestGetOrFetchSkipsWriteForUnchangedContent: an index bump that refetches
+// byte-identical content must restamp the disk file, not rewrite it.
+func TestGetOrFetchSkipsWriteForUnchangedContent(t *testing.T) {
+	hits := stubRemote(t, "description: same remote content\n")
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+	const key = "k"
+
+	if _, err := m.GetOrFetch(key, "any/path.yaml", "", knownMtime); err != nil {
+		t.Fatalf("first GetOrFetch: %v", err)
+	}
+	if got := m.GetStats().SkippedWrites; got != 0 {
+		t.Fatalf("SkippedWrites after first fetch = %d, want 0", got)
+	}
+
+	if _, err := m.GetOrFetch(key, "any/path.yaml", "", knownMtime+100); err != nil {
+		t.Fatalf("second GetOrFetch: %v", err)
+	}
+	if got := atomic.LoadInt32(hits); got != 2 {
+		t.Fatalf("remote fetches = %d, want 2 (newer index must refetch)", got)
+	}
+	if got := m.GetStats().SkippedWrites; got != 1 {
+		t.Errorf("SkippedWrites = %d, want 1 for identical content", got)
+	}
+
+	// The restamped file must count as fresh for the new index mtime
+	info, err := os.Stat(m.cachePath(key))
+	if err != nil {
+		t.Fatalf("stat cache file: %v", err)
+	}
+	if info.ModTime().Unix() != knownMtime+100 {
+		t.Errorf("disk mtime = %d, want %d", info.ModTime().Unix(), knownMtime+100)
+	}
+	if _, err := m.GetOrFetch(key, "any/path.yaml", "", knownMtime+100); err != nil {
+		t.Fatalf("third GetOrFetch: %v", err)
+	}
+	if got := atomic.LoadInt32(hits); got != 2 {
+		t.Errorf("remote fetches = %d, want 2 (restamped entry is fresh)", got)
+	}
+}
+
+// TestGetOrFetchWritesChangedContent: changed content is still written.
+func TestGetOrFetchWritesChangedContent(t *testing.T) {
+	stubRemote(t, "description: new remote content\n")
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+	const key = "k"
+
+	primeCache(t, m, key, "old content", knownMtime)
+
+	if _, err := m.GetOrFetch(key, "any/path.yaml", "", knownMtime+100); err != nil {
+		t.Fatalf("GetOrFetch: %v", err)
+	}
+	if got := m.GetStats().SkippedWrites; got != 0 {
+		t.Errorf("SkippedWrites = %d, want 0 for changed content", got)
+	}
+	data, err := os.ReadFile(m.cachePath(key))
+	if err != nil {
+		t.Fatalf("read cache file: %v", err)
+	}
+	if want := filter.FilterMetadata("description: new remote content\n"); string(data) != want {
+		t.Errorf("disk content = %q, want %q", data, want)
+	}
+}
+
 // TestGetOrFetchRefetchesWhenDiskFileDeleted (invariant b): a fresh memory
 // entry must NOT be served once its backing disk file is gone — disk presence
 // is authoritative, so a deleted file forces a re-fetch.
 func TestGetOrFetchRefetchesWhenDiskFileDeleted(t *testing.T) {
-	hits := stubRemote(t, "remote after delete")
+	hits := stubRemote(t, "description: remote after delete\n")
 	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
 	const key = "k"
 
@@ -454,7 +544,7 @@ func TestGetOrFetchRefetchesWhenDiskFileDeleted(t *testing.T) {
 	if got := atomic.LoadInt32(hits); got != 1 {
 		t.Errorf("remote fetches = %d, want 1 (deleted disk file must force refetch)", got)
 	}
-	if want := filter.FilterMetadata("remote after delete"); content != want {
+	if want := filter.FilterMetadata("description: remote after delete\n"); content != want {
 		t.Errorf("content = %q, want %q", content, want)
 	}
 }
@@ -525,7 +615,7 @@ func TestSHA256HexFormat(t *testing.T) {
 // TestGetOrFetchVerifiedMatchCachesAndServes: a download whose sha256 matches
 // the index digest is fetched exactly once, then filtered, cached, and served.
 func TestGetOrFetchVerifiedMatchCachesAndServes(t *testing.T) {
-	const body = "verified content"
+	const body = "description: verified content\n"
 	hits := stubRemote(t, body)
 	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
 	const key = "k"
@@ -557,12 +647,12 @@ func TestGetOrFetchVerifiedMatchCachesAndServes(t *testing.T) {
 // *VerificationError carrying expected/actual, caching nothing.
 func TestGetOrFetchVerifiedMismatchReturnsUnavailable(t *testing.T) {
 	shrinkBackoff(t)
-	const served = "tampered or stale bytes"
+	const served = "description: tampered or stale bytes\n"
 	hits := stubRemote(t, served)
 	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
 	const key = "k"
 
-	expected := sha256Hex("the real content")
+	expected := sha256Hex("description: the real content\n")
 	_, err := m.GetOrFetch(key, "any/path.yaml", expected, knownMtime)
 	if err == nil {
 		t.Fatal("expected a verification error, got nil")
@@ -601,8 +691,8 @@ func TestGetOrFetchVerifiedMismatchReturnsUnavailable(t *testing.T) {
 // served, proving bust-on-mismatch recovers from CDN propagation lag.
 func TestGetOrFetchVerifiedBustRecovers(t *testing.T) {
 	shrinkBackoff(t)
-	const good = "the real content"
-	hits := stubRemoteSeq(t, "stale edge bytes", good)
+	const good = "description: the real content\n"
+	hits := stubRemoteSeq(t, "description: stale edge bytes\n", good)
 	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
 	const key = "k"
 
@@ -653,7 +743,7 @@ func TestGetOrFetchCacheHitSkipsVerification(t *testing.T) {
 // the content is fetched once and served without any verification, even though
 // it would not match an arbitrary hash.
 func TestGetOrFetchEmptySHASkipsVerification(t *testing.T) {
-	hits := stubRemote(t, "unverified legacy content")
+	hits := stubRemote(t, "description: unverified legacy content\n")
 	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
 	const key = "k"
 
@@ -664,7 +754,718 @@ func TestGetOrFetchEmptySHASkipsVerification(t *testing.T) {
 	if got := atomic.LoadInt32(hits); got != 1 {
 		t.Errorf("remote fetches = %d, want 1 (legacy path: single non-busted fetch)", got)
 	}
-	if want := filter.FilterMetadata("unverified legacy content"); content != want {
+	if want := filter.FilterMetadata("description: unverified legacy content\n"); content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
+
+// Pinned key tests
+
+// TestGetOrFetchRejectsNonYAML: an HTML page or other non-YAML body served
+// with status 200 is an error, and nothing is cached.
+func TestGetOrFetchRejectsNonYAML(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     string
+	}{
+		{"html content type", "text/html; charset=utf-8", "description: looks fine\n", "unexpected HTML response"},
+		{"doctype", "text/plain", "\n<!DOCTYPE html>\n<title>503 Service Unavailable</title>", "unexpected HTML response"},
+		{"html tag", "text/plain", "<HTML><body>Sign in to continue</body></HTML>", "unexpected HTML response"},
+		{"not yaml", "text/plain", "Service temporarily unavailable", "does not look like YAML"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+			prev := BaseContentURL
+			BaseContentURL = srv.URL + "/"
+			defer func() { BaseContentURL = prev }()
+
+			m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+			_, err := m.GetOrFetch("k", "any/path.yaml", "", knownMtime)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("GetOrFetch error = %v, want %q", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), srv.URL+"/any/path.yaml") {
+				t.Errorf("error %q does not name the URL", err)
+			}
+			if m.diskFileExists("k") {
+				t.Error("rejected response was written to disk")
+			}
+		})
+	}
+}
+
+func TestPinPersistsAndReloads(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := &Manager{cacheDir: tmpDir, memory: make(map[string]cacheEntry)}
+
+	if err := m.Pin([]string{"p2kbPasm2Mov", "p2kbPasm2Add"}); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if !m.IsPinned("p2kbPasm2Mov") {
+		t.Error("IsPinned(p2kbPasm2Mov) = false after Pin")
+	}
+	if got := m.GetStats().PinnedEntries; got != 2 {
+		t.Errorf("PinnedEntries = %d, want 2", got)
+	}
+
+	// A fresh manager on the same dir must restore the pinned set.
+	reloaded := &Manager{cacheDir: tmpDir, memory: make(map[string]cacheEntry), pinnedKeys: make(map[string]bool)}
+	reloaded.loadPinned()
+	if got := reloaded.PinnedKeys(); len(got) != 2 || got[0] != "p2kbPasm2Add" {
+		t.Errorf("reloaded PinnedKeys = %v, want [p2kbPasm2Add p2kbPasm2Mov]", got)
+	}
+
+	removed, err := reloaded.Unpin([]string{"p2kbPasm2Add", "notPinned"})
+	if err != nil {
+		t.Fatalf("Unpin failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Unpin removed %d, want 1", removed)
+	}
+	if reloaded.IsPinned("p2kbPasm2Add") {
+		t.Error("p2kbPasm2Add still pinned after Unpin")
+	}
+}
+
+func TestPinEnforcesLimit(t *testing.T) {
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+
+	keys := make([]string, MaxPinnedKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%02d", i)
+	}
+	if err := m.Pin(keys); err != nil {
+		t.Fatalf("pinning exactly %d keys should succeed: %v", MaxPinnedKeys, err)
+	}
+
+	// Re-pinning an existing key does not count against the limit.
+	if err := m.Pin([]string{"key00"}); err != nil {
+		t.Errorf("re-pinning an existing key should succeed: %v", err)
+	}
+
+	if err := m.Pin([]string{"oneTooMany"}); err == nil {
+		t.Error("Pin should fail once the limit is reached")
+	}
+	if m.IsPinned("oneTooMany") {
+		t.Error("a rejected Pin must not add the key")
+	}
+}
+
+func TestPinCountsDuplicateKeysOnce(t *testing.T) {
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+
+	keys := make([]string, MaxPinnedKeys-1)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%02d", i)
+	}
+	if err := m.Pin(keys); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+
+	// One slot is left, and a key listed twice needs only one
+	if err := m.Pin([]string{"last", "last"}); err != nil {
+		t.Errorf("pinning one key listed twice into the last slot failed: %v", err)
+	}
+	if got := len(m.PinnedKeys()); got != MaxPinnedKeys {
+		t.Errorf("%d keys pinned, want %d", got, MaxPinnedKeys)
+	}
+}
+
+func TestPrewarmLoadsPinnedKeys(t *testing.T) {
+	hits := stubRemote(t, "description: pinned content\n")
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+	if err := m.Pin([]string{"p2kbPasm2Mov", "missingKey"}); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+
+	fetch := func(key string) (string, error) {
+		if key == "missingKey" {
+			return "", fmt.Errorf("key not found: %s", key)
+		}
+		return m.GetOrFetch(key, "pasm2/mov.yaml", "", knownMtime)
+	}
+
+	if warmed := m.Prewarm(fetch); warmed != 1 {
+		t.Errorf("Prewarm warmed %d keys, want 1", warmed)
+	}
+	if atomic.LoadInt32(hits) != 1 {
+		t.Errorf("remote hits = %d, want 1", atomic.LoadInt32(hits))
+	}
+	if got := m.GetStats().MemoryEntries; got != 1 {
+		t.Errorf("MemoryEntries = %d, want 1 after Prewarm", got)
+	}
+}
+
+// writeSeedArchive writes a ZIP with the given entries and returns its path.
+func writeSeedArchive(t *testing.T, entries map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), SeedArchiveName)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create archive: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create %s: %v", name, err)
+		}
+		if _, err := io.WriteString(w, body); err != nil {
+			t.Fatalf("zip write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("file close: %v", err)
+	}
+	return path
+}
+
+func TestLoadFromSeedArchive(t *testing.T) {
+	hits := stubRemote(t, "description: remote content\n")
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+	primeCache(t, m, "p2kbPasm2Add", "newer add content", knownMtime)
+
+	archive := writeSeedArchive(t, map[string]string{
+		"cache/p2kbPasm2Mov.yaml": "seeded mov content",
+		"cache/p2kbPasm2Add.yaml": "seeded add content",
+		"cache/../escape.yaml":    "must be ignored",
+		"cache/nested/p2kbX.yaml": "must be ignored",
+		"README.txt":              "must be ignored",
+		"index/p2kb-index.json":   `{"system": {"version": "seed"}}`,
+	})
+
+	loaded, err := m.LoadFromSeedArchive(archive)
+	if err != nil {
+		t.Fatalf("LoadFromSeedArchive failed: %v", err)
+	}
+	if loaded != 1 {
+		t.Errorf("loaded = %d, want 1 (p2kbPasm2Add was already cached)", loaded)
+	}
+
+	// Seeded entries carry mtime 0, so they serve a request with no index mtime.
+	got, err := m.GetOrFetch("p2kbPasm2Mov", "pasm2/mov.yaml", "", 0)
+	if err != nil {
+		t.Fatalf("GetOrFetch failed: %v", err)
+	}
+	if got != "seeded mov content" {
+		t.Errorf("GetOrFetch = %q, want seeded content", got)
+	}
+	if atomic.LoadInt32(hits) != 0 {
+		t.Errorf("remote hits = %d, want 0 for a seeded key", atomic.LoadInt32(hits))
+	}
+
+	// The already-cached, newer entry is untouched.
+	got, err = m.GetOrFetch("p2kbPasm2Add", "pasm2/add.yaml", "", knownMtime)
+	if err != nil || got != "newer add content" {
+		t.Errorf("GetOrFetch(p2kbPasm2Add) = %q, %v; want the existing cached content", got, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(m.cacheDir), "escape.yaml")); err == nil {
+		t.Error("archive entry escaped the cache directory")
+	}
+
+	data, err := os.ReadFile(filepath.Join(m.cacheDir, "index", "p2kb-index.json"))
+	if err != nil {
+		t.Fatalf("seeded index missing: %v", err)
+	}
+	if !strings.Contains(string(data), `"seed"`) {
+		t.Errorf("seeded index = %s, want archive copy", data)
+	}
+}
+
+func TestLoadFromSeedArchiveKeepsExistingIndex(t *testing.T) {
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+	indexPath := filepath.Join(m.cacheDir, "index", "p2kb-index.json")
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(indexPath, []byte(`{"system": {"version": "live"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := writeSeedArchive(t, map[string]string{
+		"index/p2kb-index.json": `{"system": {"version": "seed"}}`,
+	})
+	if _, err := m.LoadFromSeedArchive(archive); err != nil {
+		t.Fatalf("LoadFromSeedArchive failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(indexPath)
+	if !strings.Contains(string(data), `"live"`) {
+		t.Errorf("existing index overwritten: %s", data)
+	}
+}
+
+func TestLoadFromSeedArchiveMissingFile(t *testing.T) {
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+	if _, err := m.LoadFromSeedArchive(filepath.Join(t.TempDir(), "absent.zip")); err == nil {
+		t.Error("LoadFromSeedArchive should fail for a missing archive")
+	}
+}
+
+func TestEvictMemoryLRUKeepsPinned(t *testing.T) {
+	m := &Manager{
+		cacheDir:   t.TempDir(),
+		memory:     make(map[string]cacheEntry),
+		pinnedKeys: map[string]bool{"pinned": true},
+	}
+	for _, key := range []string{"pinned", "oldest", "middle", "newest"} {
+		primeCache(t, m, key, "content-"+key, knownMtime)
+		m.touch(key)
+	}
+	// A memory hit makes "oldest" the most recently used entry.
+	if _, err := m.GetOrFetch("oldest", "x.yaml", "", knownMtime); err != nil {
+		t.Fatalf("GetOrFetch failed: %v", err)
+	}
+
+	evicted, freed := m.EvictMemory(2)
+	if evicted != 2 {
+		t.Fatalf("evicted = %d, want 2", evicted)
+	}
+	if want := int64(len("content-middle") + len("content-newest")); freed != want {
+		t.Errorf("freed = %d, want %d", freed, want)
+	}
+	for _, key := range []string{"pinned", "oldest"} {
+		if _, ok := m.memory[key]; !ok {
+			t.Errorf("%s should remain in memory", key)
+		}
+	}
+
+	// Target 0 clears everything except pinned keys; disk stays intact.
+	if evicted, _ := m.EvictMemory(0); evicted != 1 {
+		t.Errorf("second eviction = %d, want 1", evicted)
+	}
+	if _, ok := m.memory["pinned"]; !ok || len(m.memory) != 1 {
+		t.Errorf("memory after EvictMemory(0) = %d entries, want only the pinned key", len(m.memory))
+	}
+	if got := m.GetStats().DiskEntries; got != 4 {
+		t.Errorf("DiskEntries = %d, want 4 (eviction must not touch disk)", got)
+	}
+}
+
+func TestSetMaxEntries(t *testing.T) {
+	m := &Manager{
+		cacheDir:   t.TempDir(),
+		memory:     make(map[string]cacheEntry),
+		pinnedKeys: make(map[string]bool),
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		primeCache(t, m, key, "content-"+key, knownMtime)
+		m.touch(key)
+	}
+
+	m.SetMaxEntries(2)
+	if _, ok := m.memory["a"]; ok || len(m.memory) != 2 {
+		t.Errorf("memory = %d entries with a present = %v, want b and c", len(m.memory), ok)
+	}
+
+	// Reading an evicted key from disk stays within the cap
+	if _, err := m.GetOrFetch("a", "x.yaml", "", knownMtime); err != nil {
+		t.Fatalf("GetOrFetch failed: %v", err)
+	}
+	if _, ok := m.memory["b"]; ok || len(m.memory) != 2 {
+		t.Errorf("after re-hydrating a, memory = %d entries with b present = %v, want a and c", len(m.memory), ok)
+	}
+
+	m.SetMaxEntries(0)
+	if m.MaxEntries() != 0 {
+		t.Errorf("MaxEntries() = %d, want 0 after removing the cap", m.MaxEntries())
+	}
+	if _, err := m.GetOrFetch("b", "x.yaml", "", knownMtime); err != nil {
+		t.Fatalf("GetOrFetch failed: %v", err)
+	}
+	if len(m.memory) != 3 {
+		t.Errorf("memory = %d entries, want 3 with no cap", len(m.memory))
+	}
+}
+
+func TestMemoryEntries(t *testing.T) {
+	long := strings.Repeat("é", 150)
+	m := &Manager{memory: map[string]cacheEntry{
+		"b": {content: long, mtime: 20},
+		"a": {content: "short", contentHash: "precomputed", mtime: 10},
+	}}
+
+	entries := m.MemoryEntries()
+	if len(entries) != 2 || entries[0].Key != "a" || entries[1].Key != "b" {
+		t.Fatalf("entries = %+v, want a then b", entries)
+	}
+	if e := entries[0]; e.Mtime != 10 || e.ContentLength != 5 || e.ContentHash != "precomputed" || e.FirstChars != "short" {
+		t.Errorf("entry a = %+v", e)
+	}
+	e := entries[1]
+	if e.ContentHash != sha256Hex(long) {
+		t.Errorf("hash = %s, want computed sha256 of content", e.ContentHash)
+	}
+	if e.ContentLength != len(long) || e.FirstChars != strings.Repeat("é", 100) {
+		t.Errorf("entry b: length %d, first %d runes; want %d bytes and 100 runes", e.ContentLength, len([]rune(e.FirstChars)), len(long))
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	m := &Manager{memory: map[string]cacheEntry{
+		"spin2Add":     {content: "mnemonic: ADD\n"},
+		"p2kbPasm2Add": {content: "mnemonic: ADD\n", contentHash: sha256Hex("mnemonic: ADD\n")},
+		"aliasAdd":     {content: "mnemonic: ADD\n"},
+		"p2kbPasm2Mov": {content: "mnemonic: MOV\n"},
+		"p2kbPasm2Sub": {content: "mnemonic: SUB\n"},
+	}}
+
+	want := map[string][]string{
+		sha256Hex("mnemonic: ADD\n"): {"aliasAdd", "p2kbPasm2Add", "spin2Add"},
+	}
+	if got := m.FindDuplicates(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindDuplicates() = %v, want %v", got, want)
+	}
+	if got := m.GetStats().DuplicateContentGroups; got != 1 {
+		t.Errorf("DuplicateContentGroups = %d, want 1", got)
+	}
+
+	empty := &Manager{memory: map[string]cacheEntry{"a": {content: "x"}}}
+	if got := empty.FindDuplicates(); len(got) != 0 {
+		t.Errorf("FindDuplicates() with unique content = %v, want none", got)
+	}
+}
+
+func TestGetOrFetchHTTPStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	prev := BaseContentURL
+	BaseContentURL = srv.URL + "/"
+	defer func() { BaseContentURL = prev }()
+
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+	_, err := m.GetOrFetch("k", "any/path.yaml", "", knownMtime)
+	var status *errs.ErrHTTPStatus
+	if !errors.As(err, &status) || status.Code != http.StatusNotFound {
+		t.Fatalf("GetOrFetch error = %v, want *errs.ErrHTTPStatus 404", err)
+	}
+	if status.URL != srv.URL+"/any/path.yaml" {
+		t.Errorf("status.URL = %q, want the content URL", status.URL)
+	}
+}
+
+// interruptWrites cuts every temp file write off halfway, as a crash or
+// interrupt would, until the returned function restores normal writes.
+func interruptWrites(t *testing.T) (restore func()) {
+	t.Helper()
+	prev := writeTemp
+	writeTemp = func(f *os.File, data []byte) error {
+		_, _ = f.Write(data[:len(data)/2])
+		return errors.New("write interrupted")
+	}
+	t.Cleanup(func() { writeTemp = prev })
+	return func() { writeTemp = prev }
+}
+
+func TestWriteToDiskInterruptedLeavesNoPartialFile(t *testing.T) {
+	t.Setenv("P2KB_CACHE_DIR", t.TempDir())
+	m := NewManager()
+	content := strings.Repeat("complete content line\n", 100)
+
+	// First write of a key: nothing is left behind
+	restore := interruptWrites(t)
+	if err := m.writeToDisk("p2kbNew", content, knownMtime); err == nil {
+		t.Fatal("writeToDisk succeeded despite the interrupted write")
+	}
+	if _, err := os.Stat(m.cachePath("p2kbNew")); !os.IsNotExist(err) {
+		t.Errorf("stat p2kbNew.yaml = %v, want absent after an interrupted first write", err)
+	}
+	restore()
+
+	// Rewrite of a key: the previous complete file survives
+	if err := m.writeToDisk("p2kbOld", content, knownMtime); err != nil {
+		t.Fatalf("writeToDisk: %v", err)
+	}
+	interruptWrites(t)
+	_ = m.writeToDisk("p2kbOld", "replacement "+content, knownMtime)
+
+	got, err := os.ReadFile(m.cachePath("p2kbOld"))
+	if err != nil || string(got) != content {
+		t.Errorf("p2kbOld.yaml = %d bytes (err %v), want the previous complete %d bytes", len(got), err, len(content))
+	}
+	for _, key := range []string{"p2kbNew", "p2kbOld"} {
+		if _, err := os.Stat(m.cachePath(key) + tempSuffix); !os.IsNotExist(err) {
+			t.Errorf("%s temp file left behind: %v", key, err)
+		}
+	}
+}
+
+func TestNewManagerRemovesLeftoverTempFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("P2KB_CACHE_DIR", tmpDir)
+	cacheDir := filepath.Join(tmpDir, "cache")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A crash mid-write leaves the temp file but never renames it
+	leftovers := []string{filepath.Join(cacheDir, "p2kbCrashed.yaml.tmp"), filepath.Join(cacheDir, "p2kbCrashed.etag.tmp")}
+	kept := filepath.Join(cacheDir, "p2kbKept.yaml")
+	for _, leftover := range leftovers {
+		_ = os.WriteFile(leftover, []byte("partial con"), 0644)
+	}
+	_ = os.WriteFile(kept, []byte("complete"), 0644)
+
+	NewManager()
+
+	for _, leftover := range leftovers {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("leftover temp file %s not removed: %v", filepath.Base(leftover), err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "p2kbCrashed.yaml")); !os.IsNotExist(err) {
+		t.Errorf("p2kbCrashed.yaml = %v, want absent", err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("complete cache file removed: %v", err)
+	}
+}
+
+func TestSaveToDiskCoalescesWrites(t *testing.T) {
+	t.Setenv("P2KB_CACHE_DIR", t.TempDir())
+	m := NewManager()
+	defer m.Close()
+
+	var writes atomic.Int32
+	prev := writeTemp
+	writeTemp = func(f *os.File, data []byte) error {
+		writes.Add(1)
+		return prev(f, data)
+	}
+	t.Cleanup(func() { writeTemp = prev })
+
+	for i := 1; i <= 20; i++ {
+		if err := m.saveToDisk("p2kbBusy", fmt.Sprintf("version %d\n", i), knownMtime); err != nil {
+			t.Fatalf("saveToDisk: %v", err)
+		}
+	}
+	// Queued, the content already reads back as cached
+	if got := m.GetMtime("p2kbBusy"); got != knownMtime {
+		t.Errorf("GetMtime before the write = %d, want %d", got, knownMtime)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := m.pendingWrite("p2kbBusy"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("queued write never reached disk")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := writes.Load(); n != 1 {
+		t.Errorf("20 rapid writes of one key made %d disk writes, want 1", n)
+	}
+	files, _ := filepath.Glob(filepath.Join(m.CacheDir(), "cache", "*"))
+	if len(files) != 1 {
+		t.Fatalf("cache files = %v, want just p2kbBusy.yaml", files)
+	}
+	got, err := os.ReadFile(m.cachePath("p2kbBusy"))
+	if err != nil || string(got) != "version 20\n" {
+		t.Errorf("p2kbBusy.yaml = %q (err %v), want the last write", got, err)
+	}
+	if info, err := os.Stat(m.cachePath("p2kbBusy")); err != nil || info.ModTime().Unix() != knownMtime {
+		t.Errorf("p2kbBusy.yaml mtime = %v (err %v), want %d", info, err, knownMtime)
+	}
+}
+
+func TestCloseWritesPendingAndInvalidateDropsThem(t *testing.T) {
+	t.Setenv("P2KB_CACHE_DIR", t.TempDir())
+	m := NewManager()
+
+	_ = m.saveToDisk("p2kbKept", "kept\n", knownMtime)
+	_ = m.saveToDisk("p2kbDropped", "dropped\n", knownMtime)
+	m.Invalidate("p2kbDropped")
+	m.Close()
+
+	if got, err := os.ReadFile(m.cachePath("p2kbKept")); err != nil || string(got) != "kept\n" {
+		t.Errorf("p2kbKept.yaml = %q (err %v), want it written by Close", got, err)
+	}
+	if _, err := os.Stat(m.cachePath("p2kbDropped")); !os.IsNotExist(err) {
+		t.Errorf("invalidated p2kbDropped.yaml = %v, want absent", err)
+	}
+
+	// After Close, writes go straight to disk
+	if err := m.saveToDisk("p2kbLate", "late\n", knownMtime); err != nil {
+		t.Fatalf("saveToDisk after Close: %v", err)
+	}
+	if _, err := os.Stat(m.cachePath("p2kbLate")); err != nil {
+		t.Errorf("p2kbLate.yaml after Close: %v", err)
+	}
+}
+
+// etagRemote is a content server that serves body with an ETag, answering a
+// request whose If-None-Match carries that ETag with 304 Not Modified.
+type etagRemote struct {
+	body         atomic.Value // string
+	etag         atomic.Value // string
+	notModified  atomic.Int32
+	conditionals atomic.Int32
+}
+
+// stubRemoteETag points BaseContentURL at an etagRemote serving body as etag.
+func stubRemoteETag(t *testing.T, body, etag string) *etagRemote {
+	t.Helper()
+	remote := &etagRemote{}
+	remote.body.Store(body)
+	remote.etag.Store(etag)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := remote.etag.Load().(string)
+		if inm := r.Header.Get("If-None-Match"); inm != "" {
+			remote.conditionals.Add(1)
+			if inm == etag {
+				remote.notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = io.WriteString(w, remote.body.Load().(string))
+	}))
+	prev := BaseContentURL
+	BaseContentURL = srv.URL + "/"
+	t.Cleanup(func() {
+		BaseContentURL = prev
+		srv.Close()
+	})
+	return remote
+}
+
+// countWrites counts the files written through writeFileAtomic.
+func countWrites(t *testing.T) *atomic.Int32 {
+	t.Helper()
+	var writes atomic.Int32
+	prev := writeTemp
+	writeTemp = func(f *os.File, data []byte) error {
+		writes.Add(1)
+		return prev(f, data)
+	}
+	t.Cleanup(func() { writeTemp = prev })
+	return &writes
+}
+
+func TestGetOrFetchRevalidatesWithETag(t *testing.T) {
+	const body = "description: etag content\n"
+	remote := stubRemoteETag(t, body, `"v1"`)
+	dir := t.TempDir()
+	m := &Manager{cacheDir: dir, memory: make(map[string]cacheEntry)}
+	const key = "k"
+
+	first, err := m.GetOrFetch(key, "any/path.yaml", sha256Hex(body), knownMtime)
+	if err != nil {
+		t.Fatalf("first GetOrFetch: %v", err)
+	}
+	if etag, sha := m.readETag(key); etag != `"v1"` || sha != sha256Hex(body) {
+		t.Fatalf("sidecar = (%q, %q), want the ETag and digest", etag, sha)
+	}
+
+	writes := countWrites(t)
+	second, err := m.GetOrFetch(key, "any/path.yaml", sha256Hex(body), knownMtime+100)
+	if err != nil {
+		t.Fatalf("second GetOrFetch: %v", err)
+	}
+	if second != first {
+		t.Errorf("content after 304 = %q, want %q", second, first)
+	}
+	if got := remote.notModified.Load(); got != 1 {
+		t.Errorf("304 responses = %d, want 1", got)
+	}
+	if got := writes.Load(); got != 0 {
+		t.Errorf("files written after 304 = %d, want 0", got)
+	}
+	info, err := os.Stat(m.cachePath(key))
+	if err != nil {
+		t.Fatalf("stat cache file: %v", err)
+	}
+	if info.ModTime().Unix() != knownMtime+100 {
+		t.Errorf("disk mtime = %d, want %d", info.ModTime().Unix(), knownMtime+100)
+	}
+
+	// After a restart the ETag comes from the sidecar
+	restarted := &Manager{cacheDir: dir, memory: make(map[string]cacheEntry)}
+	third, err := restarted.GetOrFetch(key, "any/path.yaml", sha256Hex(body), knownMtime+200)
+	if err != nil {
+		t.Fatalf("GetOrFetch after restart: %v", err)
+	}
+	if third != first || remote.notModified.Load() != 2 || writes.Load() != 0 {
+		t.Errorf("after restart: content %q, 304s %d, writes %d; want the cached content from a 304 with no writes",
+			third, remote.notModified.Load(), writes.Load())
+	}
+
+	stats := m.GetStats()
+	if stats.ETagHits != 1 || stats.ETagMisses != 0 {
+		t.Errorf("ETagHits, ETagMisses = %d, %d; want 1, 0", stats.ETagHits, stats.ETagMisses)
+	}
+}
+
+func TestGetOrFetchETagMissStoresNewContent(t *testing.T) {
+	remote := stubRemoteETag(t, "description: old\n", `"v1"`)
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+	const key = "k"
+
+	if _, err := m.GetOrFetch(key, "any/path.yaml", "", knownMtime); err != nil {
+		t.Fatalf("first GetOrFetch: %v", err)
+	}
+	remote.body.Store("description: new\n")
+	remote.etag.Store(`"v2"`)
+
+	content, err := m.GetOrFetch(key, "any/path.yaml", "", knownMtime+100)
+	if err != nil {
+		t.Fatalf("second GetOrFetch: %v", err)
+	}
+	if want := filter.FilterMetadata("description: new\n"); content != want {
 		t.Errorf("content = %q, want %q", content, want)
 	}
+	if etag, _ := m.readETag(key); etag != `"v2"` {
+		t.Errorf("sidecar ETag = %q, want \"v2\"", etag)
+	}
+	stats := m.GetStats()
+	if stats.ETagHits != 0 || stats.ETagMisses != 1 {
+		t.Errorf("ETagHits, ETagMisses = %d, %d; want 0, 1", stats.ETagHits, stats.ETagMisses)
+	}
+}
+
+// TestGetOrFetchETagSkippedForNewDigest: when the index digest changed, the
+// cached copy is known to be out of date, so the fetch is unconditional and a
+// CDN still serving the old ETag cannot keep it.
+func TestGetOrFetchETagSkippedForNewDigest(t *testing.T) {
+	const body = "description: etag content\n"
+	remote := stubRemoteETag(t, body, `"v1"`)
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+	const key = "k"
+
+	if _, err := m.GetOrFetch(key, "any/path.yaml", "", knownMtime); err != nil {
+		t.Fatalf("first GetOrFetch: %v", err)
+	}
+	if _, err := m.GetOrFetch(key, "any/path.yaml", sha256Hex(body), knownMtime+100); err != nil {
+		t.Fatalf("second GetOrFetch: %v", err)
+	}
+	if got := remote.conditionals.Load(); got != 0 {
+		t.Errorf("conditional requests = %d, want 0", got)
+	}
+}
+
+func TestInvalidateRemovesETag(t *testing.T) {
+	stubRemoteETag(t, "description: etag content\n", `"v1"`)
+	m := &Manager{cacheDir: t.TempDir(), memory: make(map[string]cacheEntry)}
+
+	if _, err := m.GetOrFetch("k", "any/path.yaml", "", knownMtime); err != nil {
+		t.Fatalf("GetOrFetch: %v", err)
+	}
+	m.Invalidate("k")
+	if _, err := os.Stat(m.etagPath("k")); !os.IsNotExist(err) {
+		t.Errorf("ETag sidecar after Invalidate: %v, want it removed", err)
+	}
 }
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// etagPath returns the on-disk path of the sidecar file holding the ETag a
+// cached key's content was served with. Its first line is the ETag and its
+// second the index digest the content was verified against, if any.
+func (m *Manager) etagPath(key string) string {
+	return filepath.Join(m.cacheDir, "cache", key+".etag")
+}
+
+// writeETag records the ETag key's cached content was served with, or removes
+// the sidecar when there is none. A sidecar that already records both is
+// left as is.
+func (m *Manager) writeETag(key, etag, etagSHA256 string) error {
+	if etag == "" {
+		if err := os.Remove(m.etagPath(key)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if oldETag, oldSHA256 := m.readETag(key); oldETag == etag && oldSHA256 == etagSHA256 {
+		return nil
+	}
+	return writeFileAtomic(m.etagPath(key), []byte(etag+"\n"+etagSHA256+"\n"), 0644)
+}
+
+// readETag returns the ETag and index digest recorded for key's cached
+// content, or "" when none is.
+func (m *Manager) readETag(key string) (etag, etagSHA256 string) {
+	data, err := os.ReadFile(m.etagPath(key))
+	if err != nil {
+		return "", ""
+	}
+	lines := strings.SplitN(string(data), "\n", 3)
+	etag = strings.TrimSpace(lines[0])
+	if len(lines) > 1 {
+		etagSHA256 = strings.TrimSpace(lines[1])
+	}
+	return etag, etagSHA256
+}
+
+// revalidationETag returns key's cached content and the ETag to revalidate
+// it with, or two empty strings when it cannot be revalidated: nothing is
+// cached, the content was served without an ETag, or it was verified against
+// a different index digest, in which case the index says it has changed and
+// a 304 from a lagging CDN must not keep it.
+func (m *Manager) revalidationETag(key, expectedSHA256 string) (content, etag string) {
+	if req, ok := m.pendingWrite(key); ok {
+		if req.etag == "" || req.etagSHA256 != expectedSHA256 {
+			return "", ""
+		}
+		return req.content, req.etag
+	}
+
+	m.mu.RLock()
+	entry, ok := m.memory[key]
+	m.mu.RUnlock() // release BEFORE disk I/O
+
+	if ok && entry.etag != "" && m.diskFileExists(key) {
+		if entry.etagSHA256 != expectedSHA256 {
+			return "", ""
+		}
+		return entry.content, entry.etag
+	}
+
+	etag, etagSHA256 := m.readETag(key)
+	if etag == "" || etagSHA256 != expectedSHA256 {
+		return "", ""
+	}
+	data, err := os.ReadFile(m.cachePath(key))
+	if err != nil {
+		return "", ""
+	}
+	return string(data), etag
+}
+
+// keepCached restamps key's cached content with indexMtime after a 304 Not
+// Modified confirmed it is current. The disk file is rewritten only if it has
+// gone missing since revalidationETag read it.
+func (m *Manager) keepCached(key, content, etag, etagSHA256 string, indexMtime int64) {
+	m.mu.Lock()
+	m.memory[key] = cacheEntry{
+		content:    content,
+		mtime:      indexMtime,
+		lastAccess: m.nextAccessLocked(),
+		etag:       etag,
+		etagSHA256: etagSHA256,
+	}
+	m.enforceMaxEntriesLocked()
+	m.mu.Unlock()
+
+	if err := m.stampDiskMtime(key, etag, etagSHA256, indexMtime); err != nil {
+		_ = m.saveToDiskWithETag(key, content, etag, etagSHA256, indexMtime)
+	}
+}
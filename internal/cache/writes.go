@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+// writeCoalesceWindow is how long the disk writer waits for another write
+// before flushing its batch. Writes arriving within the window of each other
+// go to disk together, and repeated writes of one key become a single write.
+const writeCoalesceWindow = 50 * time.Millisecond
+
+// pendingWriteBuffer is the capacity of Manager.pendingWrites, and the batch
+// size at which the writer flushes without waiting out the window.
+const pendingWriteBuffer = 100
+
+// writeRequest is a disk write queued by saveToDisk.
+type writeRequest struct {
+	key        string
+	content    string
+	etag       string // Written to the ETag sidecar after the content; "" for none
+	etagSHA256 string
+	mtime      int64
+	seq        uint64 // Distinguishes successive writes of one key
+}
+
+// startWriter starts the goroutine that drains pendingWrites. Until it is
+// started, and after Close, saveToDisk writes synchronously.
+func (m *Manager) startWriter() {
+	m.pending = make(map[string]writeRequest)
+	m.pendingWrites = make(chan writeRequest, pendingWriteBuffer)
+	m.writerStop = make(chan struct{})
+	m.writerDone = make(chan struct{})
+	go m.runWriter()
+}
+
+// runWriter batches queued writes until none has arrived for
+// writeCoalesceWindow, then writes the batch, once per key.
+func (m *Manager) runWriter() {
+	defer close(m.writerDone)
+
+	batch := make(map[string]writeRequest)
+	var flush <-chan time.Time
+	for {
+		select {
+		case req := <-m.pendingWrites:
+			batch[req.key] = req // Last write wins
+			flush = time.After(writeCoalesceWindow)
+			if len(batch) < pendingWriteBuffer {
+				continue
+			}
+		case <-flush:
+		case <-m.writerStop:
+			return // Close writes whatever is still pending
+		}
+
+		for key := range batch {
+			m.writePending(key)
+		}
+		batch = make(map[string]writeRequest)
+		flush = nil
+	}
+}
+
+// queueWrite records a write as pending and hands it to the writer. It
+// reports false, queuing nothing, when no writer is running.
+func (m *Manager) queueWrite(key, content, etag, etagSHA256 string, mtime int64) bool {
+	m.pendingMu.Lock()
+	if m.pendingWrites == nil || m.writerClosed {
+		m.pendingMu.Unlock()
+		return false
+	}
+	m.writeSeq++
+	req := writeRequest{key: key, content: content, etag: etag, etagSHA256: etagSHA256, mtime: mtime, seq: m.writeSeq}
+	m.pending[key] = req
+	m.pendingMu.Unlock()
+
+	select {
+	case m.pendingWrites <- req:
+	case <-m.writerStop:
+		// Closing; Close writes it from pending
+	}
+	return true
+}
+
+// writePending writes the latest pending content for key to disk. A key
+// invalidated while its write was under way has its file removed again.
+func (m *Manager) writePending(key string) {
+	// One write at a time, as writes of a key share its temp file
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+
+	req, ok := m.pendingWrite(key)
+	if !ok {
+		return // Written already, or invalidated
+	}
+	if err := m.writeToDisk(req.key, req.content, req.mtime); err != nil {
+		slog.Warn("failed to write cache file", "key", key, "error", err)
+	} else if err := m.writeETag(req.key, req.etag, req.etagSHA256); err != nil {
+		slog.Warn("failed to write cache ETag file", "key", key, "error", err)
+	}
+
+	m.pendingMu.Lock()
+	cur, still := m.pending[key]
+	if still && cur.seq == req.seq {
+		delete(m.pending, key)
+	}
+	m.pendingMu.Unlock()
+
+	if !still {
+		_ = os.Remove(m.cachePath(key))
+		_ = os.Remove(m.etagPath(key))
+	}
+}
+
+// pendingWrite returns the write queued for key and not yet on disk, if any.
+func (m *Manager) pendingWrite(key string) (writeRequest, bool) {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	req, ok := m.pending[key]
+	return req, ok
+}
+
+// dropPending discards the queued writes for keys, or for every key when
+// keys is nil, reporting how many it discarded.
+func (m *Manager) dropPending(keys []string) int {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	if keys == nil {
+		n := len(m.pending)
+		for key := range m.pending {
+			delete(m.pending, key)
+		}
+		return n
+	}
+	n := 0
+	for _, key := range keys {
+		if _, ok := m.pending[key]; ok {
+			delete(m.pending, key)
+			n++
+		}
+	}
+	return n
+}
+
+// pendingKeys returns the keys with writes not yet on disk.
+func (m *Manager) pendingKeys() []string {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	keys := make([]string, 0, len(m.pending))
+	for key := range m.pending {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Flush writes every queued disk write now, without waiting for the writer.
+func (m *Manager) Flush() {
+	for _, key := range m.pendingKeys() {
+		m.writePending(key)
+	}
+}
+
+// Close stops the disk writer and writes anything still queued. Writes made
+// after Close go straight to disk.
+func (m *Manager) Close() {
+	m.pendingMu.Lock()
+	if m.pendingWrites == nil || m.writerClosed {
+		m.pendingMu.Unlock()
+		return
+	}
+	m.writerClosed = true
+	m.pendingMu.Unlock()
+
+	close(m.writerStop)
+	<-m.writerDone
+	m.Flush()
+}
@@ -0,0 +1,132 @@
+// Package errs defines the typed errors returned by the index, cache and OBEX
+// managers, so callers can tell failures apart with errors.As instead of
+// matching message text.
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrKeyNotFound is returned when a P2KB key or OBEX object ID is not in the index.
+type ErrKeyNotFound struct {
+	Key string
+}
+
+func (e *ErrKeyNotFound) Error() string {
+	return fmt.Sprintf("key not found: %s", e.Key)
+}
+
+// ErrCategoryNotFound is returned when a category name (or alias) names no category.
+type ErrCategoryNotFound struct {
+	Category string
+}
+
+func (e *ErrCategoryNotFound) Error() string {
+	return fmt.Sprintf("category not found: %s", e.Category)
+}
+
+// ErrCacheExpired is returned when a disk cache entry exists but is older than
+// its TTL, so the caller should fetch a fresh copy.
+type ErrCacheExpired struct {
+	Key string
+}
+
+func (e *ErrCacheExpired) Error() string {
+	return fmt.Sprintf("cache expired: %s", e.Key)
+}
+
+// ErrHTTPStatus is returned when a server answers with an unexpected status.
+type ErrHTTPStatus struct {
+	Code int
+	URL  string
+}
+
+func (e *ErrHTTPStatus) Error() string {
+	return fmt.Sprintf("HTTP %d from %s", e.Code, e.URL)
+}
+
+// ErrRateLimited is returned when a server refuses a request for exceeding its
+// rate limit. RetryAfter is zero when the server did not say when to retry;
+// Reset is GitHub's X-RateLimit-Reset, zero when the response had none.
+type ErrRateLimited struct {
+	RetryAfter time.Duration
+	Reset      time.Time
+}
+
+func (e *ErrRateLimited) Error() string {
+	msg := "rate limited"
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(", retry after %s", e.RetryAfter)
+	}
+	if !e.Reset.IsZero() {
+		msg += fmt.Sprintf(" (limit resets at Unix time %d)", e.Reset.Unix())
+	}
+	return msg
+}
+
+// ErrOffline is returned when a server cannot be reached at all: DNS failure,
+// refused connection or timeout.
+type ErrOffline struct{}
+
+func (e *ErrOffline) Error() string {
+	return "network unavailable"
+}
+
+// Transport classifies an error from http.Client.Do, which fails only when no
+// response arrived: the result matches *ErrOffline and still wraps err.
+// Cancellation is the caller's doing, not the network's, and is returned as is.
+func Transport(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", &ErrOffline{}, err)
+}
+
+// HTTPStatus returns the error for a response with an unexpected status: an
+// *ErrHTTPStatus, which also matches *ErrRateLimited for HTTP 429 and for
+// GitHub's 403 with no requests remaining.
+func HTTPStatus(resp *http.Response, url string) error {
+	status := &ErrHTTPStatus{Code: resp.StatusCode, URL: url}
+	if limited := rateLimit(resp); limited != nil {
+		return fmt.Errorf("%w: %w", limited, status)
+	}
+	return status
+}
+
+// rateLimit reads a rate limit refusal from resp, or returns nil if it is not one.
+func rateLimit(resp *http.Response) *ErrRateLimited {
+	exhausted := resp.Header.Get("X-RateLimit-Remaining") == "0"
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		limited := &ErrRateLimited{}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			limited.RetryAfter = time.Duration(secs) * time.Second
+		}
+		if exhausted {
+			limited.Reset = rateLimitReset(resp)
+		}
+		return limited
+	case resp.StatusCode == http.StatusForbidden && exhausted:
+		limited := &ErrRateLimited{Reset: rateLimitReset(resp)}
+		if wait := time.Until(limited.Reset); !limited.Reset.IsZero() && wait > 0 {
+			limited.RetryAfter = wait.Round(time.Second)
+		}
+		return limited
+	}
+	return nil
+}
+
+// rateLimitReset returns the time in resp's X-RateLimit-Reset header, or the
+// zero time if it has none.
+func rateLimitReset(resp *http.Response) time.Time {
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(reset, 0)
+}
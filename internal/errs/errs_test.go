@@ -0,0 +1,116 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}}
+	err := fmt.Errorf("fetching: %w", HTTPStatus(resp, "https://example.com/a.yaml"))
+
+	var status *ErrHTTPStatus
+	if !errors.As(err, &status) || status.Code != 404 || status.URL != "https://example.com/a.yaml" {
+		t.Fatalf("errors.As(*ErrHTTPStatus) = %v, want 404 for the URL", status)
+	}
+	var limited *ErrRateLimited
+	if errors.As(err, &limited) {
+		t.Error("a 404 should not be rate limited")
+	}
+}
+
+func TestHTTPStatusRateLimited(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   int
+		header http.Header
+		want   time.Duration
+	}{
+		{"429 with Retry-After", 429, http.Header{"Retry-After": {"30"}}, 30 * time.Second},
+		{"429 without Retry-After", 429, http.Header{}, 0},
+		{"GitHub 403", 403, http.Header{
+			"X-Ratelimit-Remaining": {"0"},
+			"X-Ratelimit-Reset":     {strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)},
+		}, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := HTTPStatus(&http.Response{StatusCode: tt.code, Header: tt.header}, "u")
+
+			var limited *ErrRateLimited
+			if !errors.As(err, &limited) {
+				t.Fatalf("%v does not match *ErrRateLimited", err)
+			}
+			if diff := limited.RetryAfter - tt.want; diff < -time.Second || diff > time.Second {
+				t.Errorf("RetryAfter = %v, want about %v", limited.RetryAfter, tt.want)
+			}
+			var status *ErrHTTPStatus
+			if !errors.As(err, &status) || status.Code != tt.code {
+				t.Errorf("%v does not also match *ErrHTTPStatus %d", err, tt.code)
+			}
+		})
+	}
+
+	// GitHub's reset time is kept, for 429s as well as 403s
+	for _, code := range []int{403, 429} {
+		resp := &http.Response{StatusCode: code, Header: http.Header{
+			"X-Ratelimit-Remaining": {"0"},
+			"X-Ratelimit-Reset":     {"1700000000"},
+		}}
+		var limited *ErrRateLimited
+		if !errors.As(HTTPStatus(resp, "u"), &limited) || limited.Reset.Unix() != 1700000000 {
+			t.Errorf("%d: Reset = %v, want Unix time 1700000000", code, limited)
+		}
+	}
+
+	// A 403 with requests left is a plain refusal
+	forbidden := &http.Response{StatusCode: 403, Header: http.Header{"X-Ratelimit-Remaining": {"12"}}}
+	var limited *ErrRateLimited
+	if errors.As(HTTPStatus(forbidden, "u"), &limited) {
+		t.Error("403 with requests remaining should not be rate limited")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	cause := errors.New("dial tcp: connection refused")
+	err := Transport(cause)
+
+	var offline *ErrOffline
+	if !errors.As(err, &offline) {
+		t.Errorf("%v does not match *ErrOffline", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("%v does not wrap the transport error", err)
+	}
+
+	canceled := fmt.Errorf("get: %w", context.Canceled)
+	if err := Transport(canceled); errors.As(err, &offline) {
+		t.Error("cancellation should not be reported as offline")
+	}
+}
+
+func TestErrorMessages(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&ErrKeyNotFound{Key: "p2kbPasm2Nop"}, "key not found: p2kbPasm2Nop"},
+		{&ErrCategoryNotFound{Category: "maths"}, "category not found: maths"},
+		{&ErrCacheExpired{Key: "2811"}, "cache expired: 2811"},
+		{&ErrHTTPStatus{Code: 503, URL: "u"}, "HTTP 503 from u"},
+		{&ErrRateLimited{RetryAfter: 5 * time.Second}, "rate limited, retry after 5s"},
+		{&ErrRateLimited{}, "rate limited"},
+		{&ErrRateLimited{Reset: time.Unix(1700000000, 0)}, "rate limited (limit resets at Unix time 1700000000)"},
+		{&ErrOffline{}, "network unavailable"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
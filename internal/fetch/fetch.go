@@ -3,16 +3,28 @@ package fetch
 
 import (
 	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
 )
 
+// ErrTooManyRedirects is returned by FetchURLWithRedirectControl when a
+// request is redirected more than the allowed number of times.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
 // Client provides HTTP fetching with configurable timeouts.
 type Client struct {
-	httpClient *http.Client
-	baseURL    string
+	httpClient   *http.Client
+	baseURL      string
+	httpCacheTTL time.Duration // How long FetchCached trusts a stored ETag
+	tokens       *TokenPool    // GitHub tokens requests are authenticated with
 }
 
 // Option configures the Client.
@@ -32,22 +44,51 @@ func WithBaseURL(url string) Option {
 	}
 }
 
+// WithTransport sets the transport requests are sent with. GitHub requests
+// still get their token from the pool on the way to it.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = rt
+	}
+}
+
 // NewClient creates a new HTTP client with default settings.
 func NewClient(opts ...Option) *Client {
 	c := &Client{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		baseURL: "https://raw.githubusercontent.com/ironsheep/P2-Knowledge-Base/main/",
+		baseURL:      "https://raw.githubusercontent.com/ironsheep/P2-Knowledge-Base/main/",
+		httpCacheTTL: getHTTPCacheTTL(),
+		tokens:       SharedTokenPool(),
 	}
 
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	if c.tokens.Size() > 0 {
+		base := c.httpClient.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		c.httpClient.Transport = &tokenTransport{pool: c.tokens, base: base}
+	}
+
 	return c
 }
 
+// HTTPClient returns the client's underlying *http.Client, for callers that
+// build their own requests but want its timeout and GitHub authentication.
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// Authenticated reports whether the client has GitHub tokens to send.
+func (c *Client) Authenticated() bool {
+	return c.tokens.Size() > 0
+}
+
 // Fetch retrieves content from a URL.
 func (c *Client) Fetch(path string) ([]byte, error) {
 	url := c.baseURL + path
@@ -58,12 +99,106 @@ func (c *Client) Fetch(path string) ([]byte, error) {
 func (c *Client) FetchURL(url string) ([]byte, error) {
 	resp, err := c.httpClient.Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, fmt.Errorf("HTTP request failed: %w", errs.Transport(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		return nil, errs.HTTPStatus(resp, url)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return data, nil
+}
+
+// FetchURLWithRedirectControl retrieves content from an absolute URL like
+// FetchURL, but follows at most maxRedirects redirects and logs each hop.
+// It returns the redirect chain, starting with url and ending with the URL
+// the content came from; on error the chain shows how far the request got.
+func (c *Client) FetchURLWithRedirectControl(ctx context.Context, url string, maxRedirects int) ([]byte, []string, error) {
+	chain := []string{url}
+
+	client := *c.httpClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		from := via[len(via)-1].URL.String()
+		to := req.URL.String()
+		if len(via) > maxRedirects {
+			return fmt.Errorf("%w: limit %d reached at %s -> %s", ErrTooManyRedirects, maxRedirects, strings.Join(chain, " -> "), to)
+		}
+		slog.Info("following redirect", "from", from, "to", to, "hop", len(via))
+		chain = append(chain, to)
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, chain, fmt.Errorf("invalid request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if errors.Is(err, ErrTooManyRedirects) {
+		return nil, chain, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	if err != nil {
+		return nil, chain, fmt.Errorf("HTTP request failed: %w", errs.Transport(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, chain, errs.HTTPStatus(resp, url)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, chain, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return data, chain, nil
+}
+
+// FetchFirstAvailable tries each URL in turn, one at a time so a failing
+// source does not multiply the load on the others, and returns the content of
+// the first that answers 200 OK along with that URL. If none does, the error
+// is the last URL's.
+func (c *Client) FetchFirstAvailable(ctx context.Context, urls []string) ([]byte, string, error) {
+	if len(urls) == 0 {
+		return nil, "", errors.New("no URLs to fetch")
+	}
+
+	var lastErr error
+	for _, url := range urls {
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+		data, err := c.fetchWithContext(ctx, url)
+		if err == nil {
+			return data, url, nil
+		}
+		slog.Debug("fetch failed, trying next URL", "url", url, "error", err)
+		lastErr = err
+	}
+	return nil, "", fmt.Errorf("all %d URLs failed: %w", len(urls), lastErr)
+}
+
+// fetchWithContext is FetchURL with a request context.
+func (c *Client) fetchWithContext(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", errs.Transport(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.HTTPStatus(resp, url)
 	}
 
 	data, err := io.ReadAll(resp.Body)
@@ -84,12 +219,12 @@ func (c *Client) FetchGzip(path string) ([]byte, error) {
 func (c *Client) FetchGzipURL(url string) ([]byte, error) {
 	resp, err := c.httpClient.Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, fmt.Errorf("HTTP request failed: %w", errs.Transport(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		return nil, errs.HTTPStatus(resp, url)
 	}
 
 	gr, err := gzip.NewReader(resp.Body)
@@ -114,11 +249,21 @@ func (c *Client) Head(path string) (bool, error) {
 
 // HeadURL performs a HEAD request to an absolute URL.
 func (c *Client) HeadURL(url string) (bool, error) {
+	status, err := c.HeadStatus(url)
+	if err != nil {
+		return false, err
+	}
+	return status == http.StatusOK, nil
+}
+
+// HeadStatus performs a HEAD request to an absolute URL and returns the HTTP
+// status code of the response, after any redirects.
+func (c *Client) HeadStatus(url string) (int, error) {
 	resp, err := c.httpClient.Head(url)
 	if err != nil {
-		return false, fmt.Errorf("HTTP HEAD failed: %w", err)
+		return 0, fmt.Errorf("HTTP HEAD failed: %w", errs.Transport(err))
 	}
 	defer resp.Body.Close()
 
-	return resp.StatusCode == http.StatusOK, nil
+	return resp.StatusCode, nil
 }
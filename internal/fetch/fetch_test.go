@@ -1,8 +1,14 @@
 package fetch
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -121,6 +127,23 @@ func TestHeadURL(t *testing.T) {
 	}
 }
 
+func TestHeadStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient()
+	for path, want := range map[string]int{"/ok": http.StatusOK, "/gone": http.StatusGone} {
+		status, err := c.HeadStatus(ts.URL + path)
+		if err != nil || status != want {
+			t.Errorf("HeadStatus(%s) = %d, %v; want %d", path, status, err, want)
+		}
+	}
+}
+
 func TestHead(t *testing.T) {
 	// Create test server
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -179,3 +202,118 @@ func TestClientOptions(t *testing.T) {
 		t.Errorf("baseURL = %q, want https://custom.example.com/", c.baseURL)
 	}
 }
+
+// newRedirectServer serves /hop/N, which redirects to /hop/N-1, down to
+// /hop/0, which returns the content.
+func newRedirectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if n == 0 {
+			_, _ = w.Write([]byte("final content"))
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestFetchURLWithRedirectControl(t *testing.T) {
+	ts := newRedirectServer(t)
+
+	c := NewClient()
+	data, chain, err := c.FetchURLWithRedirectControl(context.Background(), ts.URL+"/hop/2", 5)
+	if err != nil {
+		t.Fatalf("FetchURLWithRedirectControl failed: %v", err)
+	}
+	if string(data) != "final content" {
+		t.Errorf("data = %q, want 'final content'", string(data))
+	}
+	want := []string{ts.URL + "/hop/2", ts.URL + "/hop/1", ts.URL + "/hop/0"}
+	if !reflect.DeepEqual(chain, want) {
+		t.Errorf("chain = %v, want %v", chain, want)
+	}
+}
+
+func TestFetchURLWithRedirectControlNoRedirect(t *testing.T) {
+	ts := newRedirectServer(t)
+
+	c := NewClient()
+	_, chain, err := c.FetchURLWithRedirectControl(context.Background(), ts.URL+"/hop/0", 0)
+	if err != nil {
+		t.Fatalf("FetchURLWithRedirectControl failed: %v", err)
+	}
+	if len(chain) != 1 || chain[0] != ts.URL+"/hop/0" {
+		t.Errorf("chain = %v, want only the requested URL", chain)
+	}
+}
+
+func TestFetchURLWithRedirectControlTooMany(t *testing.T) {
+	ts := newRedirectServer(t)
+
+	c := NewClient()
+	data, chain, err := c.FetchURLWithRedirectControl(context.Background(), ts.URL+"/hop/3", 2)
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("err = %v, want ErrTooManyRedirects", err)
+	}
+	if data != nil {
+		t.Errorf("data = %q, want nil", data)
+	}
+	if len(chain) != 3 {
+		t.Errorf("chain = %v, want the 3 URLs reached before the limit", chain)
+	}
+	if !strings.Contains(err.Error(), ts.URL+"/hop/0") {
+		t.Errorf("error %q should name the refused redirect target", err)
+	}
+}
+
+func TestFetchFirstAvailable(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/up":
+			w.Write([]byte("mirrored content"))
+		default:
+			t.Errorf("unexpected request for %s after a URL succeeded", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient()
+	data, used, err := c.FetchFirstAvailable(context.Background(), []string{ts.URL + "/down", ts.URL + "/up", ts.URL + "/unused"})
+	if err != nil {
+		t.Fatalf("FetchFirstAvailable failed: %v", err)
+	}
+	if string(data) != "mirrored content" {
+		t.Errorf("data = %q, want 'mirrored content'", data)
+	}
+	if used != ts.URL+"/up" {
+		t.Errorf("used = %q, want the second URL", used)
+	}
+	if !reflect.DeepEqual(requests, []string{"/down", "/up"}) {
+		t.Errorf("requests = %v, want /down then /up", requests)
+	}
+}
+
+func TestFetchFirstAvailableAllFail(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := NewClient()
+	if _, _, err := c.FetchFirstAvailable(context.Background(), []string{ts.URL + "/a", ts.URL + "/b"}); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("err = %v, want the last URL's 503", err)
+	}
+	if _, _, err := c.FetchFirstAvailable(context.Background(), nil); err == nil {
+		t.Error("expected an error with no URLs")
+	}
+}
@@ -0,0 +1,156 @@
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
+)
+
+// DefaultHTTPCacheTTL is how long FetchCached revalidates a stored ETag before
+// treating the entry as expired. Override with P2KB_HTTP_CACHE_TTL_SECS.
+const DefaultHTTPCacheTTL = 1 * time.Hour
+
+// WithHTTPCacheTTL sets how long FetchCached trusts a stored ETag.
+func WithHTTPCacheTTL(d time.Duration) Option {
+	return func(c *Client) {
+		c.httpCacheTTL = d
+	}
+}
+
+// FetchCached retrieves url like FetchURL, keeping the response body and its
+// ETag in cacheDir as {sha256}.body and {sha256}.etag, where the hash is of
+// cacheKey, or of url when cacheKey is empty. The body is stored verbatim.
+//
+// While a stored ETag is younger than the client's HTTP cache TTL, the request
+// carries If-None-Match and a 304 returns the stored body, restarting the TTL.
+// Expired entries are fetched unconditionally and replaced. Responses without
+// an ETag are returned but not cached.
+func (c *Client) FetchCached(ctx context.Context, url, cacheKey, cacheDir string) ([]byte, error) {
+	if cacheKey == "" {
+		cacheKey = url
+	}
+	sum := sha256.Sum256([]byte(cacheKey))
+	base := filepath.Join(cacheDir, hex.EncodeToString(sum[:]))
+	bodyPath, etagPath := base+".body", base+".etag"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	etag := c.storedETag(bodyPath, etagPath)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", errs.Transport(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && etag != "" {
+		data, err := os.ReadFile(bodyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cached response: %w", err)
+		}
+		now := time.Now()
+		_ = os.Chtimes(etagPath, now, now)
+		return data, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.HTTPStatus(resp, url)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if err := storeCached(cacheDir, bodyPath, etagPath, data, resp.Header.Get("ETag")); err != nil {
+		slog.Debug("failed to store HTTP cache entry", "url", url, "error", err)
+	}
+	return data, nil
+}
+
+// storedETag returns the ETag to revalidate with, or "" if there is no usable
+// entry: no ETag file, an ETag older than the TTL, or a missing body.
+func (c *Client) storedETag(bodyPath, etagPath string) string {
+	info, err := os.Stat(etagPath)
+	if err != nil || time.Since(info.ModTime()) > c.httpCacheTTL {
+		return ""
+	}
+	if _, err := os.Stat(bodyPath); err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(etagPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// storeCached replaces the entry's body and then its ETag, each written to a
+// temporary file and renamed into place. The old ETag is removed first, so an
+// ETag never refers to a body that is missing, half written or not its own.
+// Without an ETag any previous entry is removed instead.
+func storeCached(cacheDir, bodyPath, etagPath string, body []byte, etag string) error {
+	if err := os.Remove(etagPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if etag == "" {
+		_ = os.Remove(bodyPath)
+		return nil
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(bodyPath, body); err != nil {
+		return err
+	}
+	return writeFileAtomic(etagPath, []byte(etag))
+}
+
+// writeFileAtomic writes data to a temporary file beside path and renames it
+// over path, so a crash or a concurrent FetchCached never leaves path torn.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// getHTTPCacheTTL returns the FetchCached ETag TTL from environment or default.
+func getHTTPCacheTTL() time.Duration {
+	if v := os.Getenv("P2KB_HTTP_CACHE_TTL_SECS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return DefaultHTTPCacheTTL
+}
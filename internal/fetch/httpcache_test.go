@@ -0,0 +1,196 @@
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// etagServer serves body with ETag "v1", answering a matching If-None-Match
+// with 304. It counts requests and the 304s among them.
+func etagServer(t *testing.T, body string) (url string, requests, notModified *int32) {
+	t.Helper()
+	requests, notModified = new(int32), new(int32)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL + "/arch/cog.yaml", requests, notModified
+}
+
+func TestFetchCachedRevalidates(t *testing.T) {
+	url, requests, notModified := etagServer(t, "mnemonic: COG\n")
+	dir := filepath.Join(t.TempDir(), "http")
+	c := NewClient()
+
+	for i := 0; i < 3; i++ {
+		data, err := c.FetchCached(context.Background(), url, "", dir)
+		if err != nil {
+			t.Fatalf("FetchCached #%d failed: %v", i+1, err)
+		}
+		if string(data) != "mnemonic: COG\n" {
+			t.Errorf("FetchCached #%d = %q, want the served body", i+1, data)
+		}
+	}
+	if *requests != 3 || *notModified != 2 {
+		t.Errorf("requests = %d with %d 304s, want 3 with 2", *requests, *notModified)
+	}
+
+	// The entry is named by the URL's sha256
+	entries, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(entries) != 2 {
+		t.Fatalf("cache dir holds %v, want a .body and a .etag", entries)
+	}
+	sum := sha256.Sum256([]byte(url))
+	etag, err := os.ReadFile(filepath.Join(dir, hex.EncodeToString(sum[:])+".etag"))
+	if err != nil || string(etag) != `"v1"` {
+		t.Errorf("stored ETag = %q, %v; want \"v1\"", etag, err)
+	}
+}
+
+func TestFetchCachedKeyAndExpiry(t *testing.T) {
+	url, requests, notModified := etagServer(t, "mnemonic: COG\n")
+	dir := t.TempDir()
+	c := NewClient(WithHTTPCacheTTL(time.Minute))
+
+	// Entries are keyed by cacheKey, so a cache-busted URL shares the entry
+	if _, err := c.FetchCached(context.Background(), url, "p2kbArchCog", dir); err != nil {
+		t.Fatalf("FetchCached failed: %v", err)
+	}
+	if _, err := c.FetchCached(context.Background(), url+"?t=1", "p2kbArchCog", dir); err != nil {
+		t.Fatalf("FetchCached failed: %v", err)
+	}
+	if *notModified != 1 {
+		t.Errorf("304s = %d, want 1 for the shared cache key", *notModified)
+	}
+
+	// An ETag older than the TTL is not sent
+	etags, _ := filepath.Glob(filepath.Join(dir, "*.etag"))
+	if len(etags) != 1 {
+		t.Fatalf("got ETag files %v, want one", etags)
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(etags[0], old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.FetchCached(context.Background(), url, "p2kbArchCog", dir); err != nil {
+		t.Fatalf("FetchCached failed: %v", err)
+	}
+	if *requests != 3 || *notModified != 1 {
+		t.Errorf("after expiry: requests = %d with %d 304s, want 3 with 1", *requests, *notModified)
+	}
+
+	// A missing body means the ETag cannot be used
+	bodies, _ := filepath.Glob(filepath.Join(dir, "*.body"))
+	for _, b := range bodies {
+		_ = os.Remove(b)
+	}
+	data, err := c.FetchCached(context.Background(), url, "p2kbArchCog", dir)
+	if err != nil || string(data) != "mnemonic: COG\n" {
+		t.Errorf("after losing the body: FetchCached = %q, %v; want a full fetch", data, err)
+	}
+	if *notModified != 1 {
+		t.Errorf("304s = %d after losing the body, want still 1", *notModified)
+	}
+}
+
+func TestFetchCachedWithoutETag(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Error("If-None-Match sent without a stored ETag")
+		}
+		_, _ = w.Write([]byte("plain"))
+	}))
+	defer ts.Close()
+	dir := t.TempDir()
+
+	c := NewClient()
+	for i := 0; i < 2; i++ {
+		if data, err := c.FetchCached(context.Background(), ts.URL, "", dir); err != nil || string(data) != "plain" {
+			t.Fatalf("FetchCached = %q, %v", data, err)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("cache dir holds %d files, want none for responses without an ETag", len(entries))
+	}
+}
+
+func TestFetchCachedError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	if _, err := NewClient().FetchCached(context.Background(), ts.URL, "", t.TempDir()); err == nil {
+		t.Error("FetchCached of a 404 should fail")
+	}
+}
+
+func TestGetHTTPCacheTTL(t *testing.T) {
+	tests := []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", DefaultHTTPCacheTTL},
+		{"120", 2 * time.Minute},
+		{"0", DefaultHTTPCacheTTL},
+		{"soon", DefaultHTTPCacheTTL},
+	}
+	for _, tt := range tests {
+		t.Setenv("P2KB_HTTP_CACHE_TTL_SECS", tt.env)
+		if got := getHTTPCacheTTL(); got != tt.want {
+			t.Errorf("P2KB_HTTP_CACHE_TTL_SECS=%q: got %v, want %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestFetchCachedReplacesEntry(t *testing.T) {
+	var version atomic.Int32
+	version.Store(1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"v` + string(rune('0'+version.Load())) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte("body " + etag))
+	}))
+	defer ts.Close()
+	dir := t.TempDir()
+	c := NewClient()
+
+	if _, err := c.FetchCached(context.Background(), ts.URL, "", dir); err != nil {
+		t.Fatalf("FetchCached failed: %v", err)
+	}
+	version.Store(2)
+	data, err := c.FetchCached(context.Background(), ts.URL, "", dir)
+	if err != nil || string(data) != `body "v2"` {
+		t.Fatalf("FetchCached = %q, %v; want the new body", data, err)
+	}
+
+	// The entry is replaced in place, with no temporary files left behind
+	sum := sha256.Sum256([]byte(ts.URL))
+	base := filepath.Join(dir, hex.EncodeToString(sum[:]))
+	if etag, _ := os.ReadFile(base + ".etag"); string(etag) != `"v2"` {
+		t.Errorf("stored ETag = %q, want \"v2\"", etag)
+	}
+	if body, _ := os.ReadFile(base + ".body"); string(body) != `body "v2"` {
+		t.Errorf("stored body = %q, want the new body", body)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("cache dir holds %d files, want a .body and a .etag", len(entries))
+	}
+}
@@ -0,0 +1,163 @@
+package fetch
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenPool hands out GitHub tokens round-robin, so instances sharing a
+// token set spread their requests over every token's rate limit. A token
+// whose X-RateLimit-Remaining reaches 0 is skipped until its
+// X-RateLimit-Reset time.
+type TokenPool struct {
+	mu           sync.Mutex
+	tokens       []string
+	limitedUntil []time.Time // Per token; zero when not rate limited
+	next         int         // Index of the token to try first
+	now          func() time.Time
+}
+
+// NewTokenPool returns a pool of the non-empty tokens, in order, without
+// duplicates.
+func NewTokenPool(tokens []string) *TokenPool {
+	p := &TokenPool{now: time.Now}
+	seen := make(map[string]bool)
+	for _, t := range tokens {
+		if t = strings.TrimSpace(t); t != "" && !seen[t] {
+			seen[t] = true
+			p.tokens = append(p.tokens, t)
+		}
+	}
+	p.limitedUntil = make([]time.Time, len(p.tokens))
+	return p
+}
+
+// Size returns how many tokens the pool holds.
+func (p *TokenPool) Size() int {
+	return len(p.tokens)
+}
+
+// Active returns how many tokens are not currently rate limited.
+func (p *TokenPool) Active() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	active := 0
+	for _, until := range p.limitedUntil {
+		if !now.Before(until) {
+			active++
+		}
+	}
+	return active
+}
+
+// acquire returns the next token that is not rate limited and its position,
+// or -1 if every token is.
+func (p *TokenPool) acquire() (string, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	for i := 0; i < len(p.tokens); i++ {
+		idx := (p.next + i) % len(p.tokens)
+		if now.Before(p.limitedUntil[idx]) {
+			continue
+		}
+		p.next = (idx + 1) % len(p.tokens)
+		return p.tokens[idx], idx
+	}
+	return "", -1
+}
+
+// observe records the rate limit headers of a response made with the token
+// at idx.
+func (p *TokenPool) observe(idx int, header http.Header) {
+	remaining := header.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if remaining != "0" {
+		p.limitedUntil[idx] = time.Time{}
+		return
+	}
+	// Without a usable reset time, GitHub's limits roll over within the hour
+	until := p.now().Add(time.Hour)
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		until = time.Unix(reset, 0)
+	}
+	p.limitedUntil[idx] = until
+}
+
+// EnvTokens returns the GitHub tokens configured in P2KB_GITHUB_TOKENS
+// (comma-separated) followed by GITHUB_TOKEN.
+func EnvTokens() []string {
+	tokens := strings.Split(os.Getenv("P2KB_GITHUB_TOKENS"), ",")
+	return append(tokens, os.Getenv("GITHUB_TOKEN"))
+}
+
+// SharedTokenPool returns the pool of EnvTokens that clients use unless given
+// WithTokenPool. It is built on first use and shared, so rate limits seen by
+// one client are respected by all.
+var SharedTokenPool = sync.OnceValue(func() *TokenPool {
+	return NewTokenPool(EnvTokens())
+})
+
+// WithTokenPool authenticates the client's GitHub requests with tokens from a
+// pool of its own instead of SharedTokenPool.
+func WithTokenPool(tokens []string) Option {
+	return func(c *Client) {
+		c.tokens = NewTokenPool(tokens)
+	}
+}
+
+// WithAuthToken authenticates the client's GitHub requests with token alone,
+// sent as "Authorization: Bearer <token>", instead of SharedTokenPool. An
+// empty token leaves the client on SharedTokenPool.
+func WithAuthToken(token string) Option {
+	return func(c *Client) {
+		if strings.TrimSpace(token) != "" {
+			c.tokens = NewTokenPool([]string{token})
+		}
+	}
+}
+
+// authenticatesHost reports whether requests to host carry a pool token. Only
+// GitHub hosts do, so a token never leaks to OBEX or a mirror. A var so tests
+// can authenticate requests to a local server.
+var authenticatesHost = func(host string) bool {
+	host = strings.ToLower(host)
+	return host == "github.com" || strings.HasSuffix(host, ".github.com") ||
+		host == "githubusercontent.com" || strings.HasSuffix(host, ".githubusercontent.com")
+}
+
+// tokenTransport sets each GitHub request's Authorization header from a token
+// pool, chosen at request time, and feeds the response's rate limit headers
+// back to the pool.
+type tokenTransport struct {
+	pool *TokenPool
+	base http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" || !authenticatesHost(req.URL.Hostname()) {
+		return t.base.RoundTrip(req)
+	}
+	token, idx := t.pool.acquire()
+	if idx < 0 {
+		// Every token is rate limited; fall back to an anonymous request
+		return t.base.RoundTrip(req)
+	}
+
+	authed := req.Clone(req.Context())
+	authed.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.base.RoundTrip(authed)
+	if err == nil {
+		t.pool.observe(idx, resp.Header)
+	}
+	return resp, err
+}
@@ -0,0 +1,205 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// authenticateLocal lets pool tokens reach httptest servers for the test.
+func authenticateLocal(t *testing.T) {
+	t.Helper()
+	prev := authenticatesHost
+	authenticatesHost = func(string) bool { return true }
+	t.Cleanup(func() { authenticatesHost = prev })
+}
+
+// tokenServer records the Authorization header of each request. A token in
+// limited answers with X-RateLimit-Remaining 0 and the given reset time.
+func tokenServer(t *testing.T, limited map[string]int64) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		mu.Lock()
+		seen = append(seen, auth)
+		mu.Unlock()
+		if reset, ok := limited[auth]; ok {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		} else {
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func TestTokenPoolRotation(t *testing.T) {
+	authenticateLocal(t)
+	srv, seen := tokenServer(t, nil)
+
+	c := NewClient(WithTokenPool([]string{"a", " b ", "", "c", "a"}))
+	if c.tokens.Size() != 3 {
+		t.Fatalf("Size() = %d, want 3 distinct non-empty tokens", c.tokens.Size())
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := c.FetchURL(srv.URL); err != nil {
+			t.Fatalf("FetchURL: %v", err)
+		}
+	}
+	want := []string{"Bearer a", "Bearer b", "Bearer c", "Bearer a"}
+	if got := seen(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Authorization headers = %v, want %v", got, want)
+	}
+}
+
+func TestTokenPoolSkipsRateLimitedToken(t *testing.T) {
+	authenticateLocal(t)
+	reset := time.Now().Add(time.Hour)
+	srv, seen := tokenServer(t, map[string]int64{"Bearer a": reset.Unix()})
+
+	c := NewClient(WithTokenPool([]string{"a", "b"}))
+	for i := 0; i < 4; i++ {
+		if _, err := c.FetchURL(srv.URL); err != nil {
+			t.Fatalf("FetchURL: %v", err)
+		}
+	}
+	want := []string{"Bearer a", "Bearer b", "Bearer b", "Bearer b"}
+	if got := seen(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Authorization headers = %v, want a skipped once it is rate limited", got)
+	}
+	if active := c.tokens.Active(); active != 1 {
+		t.Errorf("Active() = %d, want 1", active)
+	}
+
+	// Past the reset time the token is used again
+	c.tokens.now = func() time.Time { return reset.Add(time.Second) }
+	if active := c.tokens.Active(); active != 2 {
+		t.Errorf("Active() after reset = %d, want 2", active)
+	}
+	if _, err := c.FetchURL(srv.URL); err != nil {
+		t.Fatalf("FetchURL: %v", err)
+	}
+	if got := seen(); got[len(got)-1] != "Bearer a" {
+		t.Errorf("after reset used %q, want Bearer a", got[len(got)-1])
+	}
+}
+
+func TestTokenPoolAllLimitedFallsBackToAnonymous(t *testing.T) {
+	authenticateLocal(t)
+	reset := time.Now().Add(time.Hour).Unix()
+	srv, seen := tokenServer(t, map[string]int64{"Bearer a": reset})
+
+	c := NewClient(WithTokenPool([]string{"a"}))
+	for i := 0; i < 2; i++ {
+		if _, err := c.FetchURL(srv.URL); err != nil {
+			t.Fatalf("FetchURL: %v", err)
+		}
+	}
+	if got := seen(); !reflect.DeepEqual(got, []string{"Bearer a", ""}) {
+		t.Errorf("Authorization headers = %q, want no token once the only one is limited", got)
+	}
+}
+
+func TestTokensOnlySentToGitHub(t *testing.T) {
+	srv, seen := tokenServer(t, nil)
+
+	c := NewClient(WithTokenPool([]string{"a"}))
+	if _, err := c.FetchURL(srv.URL); err != nil {
+		t.Fatalf("FetchURL: %v", err)
+	}
+	if got := seen(); got[0] != "" {
+		t.Errorf("Authorization = %q sent to a non-GitHub host", got[0])
+	}
+
+	for host, want := range map[string]bool{
+		"raw.githubusercontent.com": true,
+		"api.github.com":            true,
+		"github.com":                true,
+		"obex.parallax.com":         false,
+		"evilgithub.com":            false,
+	} {
+		if got := authenticatesHost(host); got != want {
+			t.Errorf("authenticatesHost(%s) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+// countingTransport counts the requests sent through it.
+type countingTransport struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.n++
+	t.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestTokenPoolKeepsTransport(t *testing.T) {
+	authenticateLocal(t)
+	srv, seen := tokenServer(t, nil)
+
+	transport := &countingTransport{}
+	c := NewClient(WithTransport(transport), WithTokenPool([]string{"a"}))
+	if _, err := c.FetchURL(srv.URL); err != nil {
+		t.Fatalf("FetchURL: %v", err)
+	}
+	if transport.n != 1 {
+		t.Errorf("given transport sent %d requests, want 1", transport.n)
+	}
+	if got := seen(); !reflect.DeepEqual(got, []string{"Bearer a"}) {
+		t.Errorf("Authorization headers = %v, want [Bearer a]", got)
+	}
+}
+
+func TestEnvTokens(t *testing.T) {
+	t.Setenv("P2KB_GITHUB_TOKENS", "one,two")
+	t.Setenv("GITHUB_TOKEN", "three")
+	if p := NewTokenPool(EnvTokens()); !reflect.DeepEqual(p.tokens, []string{"one", "two", "three"}) {
+		t.Errorf("tokens = %v, want [one two three]", p.tokens)
+	}
+
+	t.Setenv("P2KB_GITHUB_TOKENS", "")
+	t.Setenv("GITHUB_TOKEN", "")
+	if p := NewTokenPool(EnvTokens()); p.Size() != 0 {
+		t.Errorf("Size() = %d with no tokens set, want 0", p.Size())
+	}
+}
+
+func TestWithAuthToken(t *testing.T) {
+	authenticateLocal(t)
+	srv, seen := tokenServer(t, nil)
+
+	c := NewClient(WithAuthToken("ghp_one"))
+	if !c.Authenticated() {
+		t.Fatal("Authenticated() = false with a token")
+	}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := c.HTTPClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+	if got := seen(); !reflect.DeepEqual(got, []string{"Bearer ghp_one"}) {
+		t.Errorf("Authorization headers = %v, want [Bearer ghp_one]", got)
+	}
+
+	// No token keeps the shared pool
+	if c := NewClient(WithAuthToken("")); c.tokens != SharedTokenPool() {
+		t.Error("WithAuthToken(\"\") replaced the shared pool")
+	}
+}
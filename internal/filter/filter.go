@@ -11,8 +11,10 @@ import (
 var metadataPattern = regexp.MustCompile(
 	`(?m)^\s*(last_updated|enhancement_source|documentation_source|documentation_level|manual_extraction_date):.*\n?`)
 
-// FilterMetadata removes internal metadata lines from YAML content.
+// FilterMetadata removes internal metadata fields from YAML content.
 // This saves tokens by removing tracking data that's not useful for AI consumption.
+// A field's whole value is removed, including the continuation lines of a
+// block scalar (| or >), a multi-line flow value, or a nested sequence.
 //
 // Filtered fields:
 //   - last_updated
@@ -21,24 +23,71 @@ var metadataPattern = regexp.MustCompile(
 //   - documentation_level
 //   - manual_extraction_date
 func FilterMetadata(content string) string {
-	return metadataPattern.ReplaceAllString(content, "")
+	if !metadataPattern.MatchString(content) {
+		return content
+	}
+	kept, _ := filterLines(strings.SplitAfter(content, "\n"))
+	return strings.Join(kept, "")
 }
 
 // FilterMetadataLines removes metadata lines and returns the result.
 // This is an alternative implementation that processes line by line.
 func FilterMetadataLines(content string) string {
-	lines := strings.Split(content, "\n")
-	var filtered []string
+	kept, _ := filterLines(strings.Split(content, "\n"))
+	return strings.Join(kept, "\n")
+}
+
+// filterLines drops each metadata line, and the continuation lines of its
+// value, from lines. Lines may or may not carry their line endings. A value
+// continues on every following line indented deeper than its key, and, when
+// the key has nothing after the colon, on "- " items at the key's own
+// indentation (a compact sequence). Blank lines are dropped only when the
+// value continues after them.
+func filterLines(lines []string) (kept []string, dropped int) {
+	skipIndent := -1     // Indentation of the key whose value is being skipped; -1 when not skipping
+	skipCompact := false // That key's value may be a compact sequence
+	var blanks []string  // Blank lines seen while skipping, not yet known to be part of the value
 
 	for _, line := range lines {
 		trimmed := strings.TrimSpace(line)
+		if skipIndent >= 0 {
+			if trimmed == "" {
+				blanks = append(blanks, line)
+				continue
+			}
+			indent := indentation(line)
+			if indent > skipIndent || (skipCompact && indent == skipIndent && isSequenceItem(trimmed)) {
+				dropped += len(blanks) + 1
+				blanks = nil
+				continue
+			}
+			kept = append(kept, blanks...)
+			blanks = nil
+			skipIndent = -1
+		}
+
 		if shouldFilterLine(trimmed) {
+			dropped++
+			skipIndent = indentation(line)
+			_, value, _ := strings.Cut(trimmed, ":")
+			value = strings.TrimSpace(value)
+			skipCompact = value == "" || strings.HasPrefix(value, "#")
 			continue
 		}
-		filtered = append(filtered, line)
+		kept = append(kept, line)
 	}
 
-	return strings.Join(filtered, "\n")
+	return append(kept, blanks...), dropped
+}
+
+// indentation returns the number of leading spaces and tabs in line.
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// isSequenceItem reports whether a trimmed line is a YAML sequence entry.
+func isSequenceItem(trimmed string) bool {
+	return trimmed == "-" || strings.HasPrefix(trimmed, "- ")
 }
 
 // shouldFilterLine returns true if the line should be filtered out.
@@ -60,16 +109,9 @@ func shouldFilterLine(line string) bool {
 	return false
 }
 
-// CountFilteredLines counts how many lines would be filtered.
+// CountFilteredLines counts how many lines would be filtered, including the
+// continuation lines of multi-line metadata values.
 func CountFilteredLines(content string) int {
-	lines := strings.Split(content, "\n")
-	count := 0
-
-	for _, line := range lines {
-		if shouldFilterLine(strings.TrimSpace(line)) {
-			count++
-		}
-	}
-
-	return count
+	_, dropped := filterLines(strings.Split(content, "\n"))
+	return dropped
 }
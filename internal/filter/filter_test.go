@@ -92,6 +92,67 @@ related_instructions:
 	}
 }
 
+func TestFilterMetadataMultiLineValues(t *testing.T) {
+	fields := []string{
+		"last_updated",
+		"enhancement_source",
+		"documentation_source",
+		"documentation_level",
+		"manual_extraction_date",
+	}
+	values := []struct {
+		name  string
+		value string // Everything after "<field>:", through the last line of the value
+	}{
+		{"single-line", ` "2025-01-01"` + "\n"},
+		{"literal block scalar", " |\n  Extracted by hand.\n\n  Reviewed twice.\n"},
+		{"folded block scalar", " >-\n  Extracted by hand\n  and reviewed.\n"},
+		{"multi-line flow scalar", ` "Extracted by hand` + "\n" + `  and reviewed"` + "\n"},
+		{"multi-line flow sequence", " [manual,\n  forum]\n"},
+		{"block sequence", "\n  - manual\n  - forum\n"},
+		{"compact sequence", "\n- manual\n- forum\n"},
+	}
+
+	for _, field := range fields {
+		for _, v := range values {
+			t.Run(field+"/"+v.name, func(t *testing.T) {
+				input := "mnemonic: MOV\n" + field + ":" + v.value + "\ndescription: Move data\n"
+				want := "mnemonic: MOV\n\ndescription: Move data\n"
+				if got := FilterMetadata(input); got != want {
+					t.Errorf("FilterMetadata() =\n%q\nwant\n%q", got, want)
+				}
+				if got := FilterMetadataLines(input); got != want {
+					t.Errorf("FilterMetadataLines() =\n%q\nwant\n%q", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestFilterMetadataNestedField(t *testing.T) {
+	// A nested metadata field's value ends at the next line no deeper than its key
+	input := `mnemonic: MOV
+meta:
+  enhancement_source: |
+    Enhanced in review pass 3
+  reviewed: true
+syntax:
+  - "MOV D,S"
+`
+	want := `mnemonic: MOV
+meta:
+  reviewed: true
+syntax:
+  - "MOV D,S"
+`
+	if got := FilterMetadata(input); got != want {
+		t.Errorf("FilterMetadata() =\n%q\nwant\n%q", got, want)
+	}
+	if got := CountFilteredLines(input); got != 2 {
+		t.Errorf("CountFilteredLines() = %d, want 2", got)
+	}
+}
+
 func TestFilterMetadataLines(t *testing.T) {
 	input := `mnemonic: MOV
 last_updated: "2025-01-01"
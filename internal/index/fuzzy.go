@@ -0,0 +1,95 @@
+package index
+
+import "strings"
+
+const (
+	// maxFuzzyDistance is the most edits a query token may be from a key
+	// token and still match in the fuzzy pass.
+	maxFuzzyDistance = 2
+
+	// minFuzzyTokenLen is the shortest query token the fuzzy pass considers;
+	// a one- or two-letter token is within two edits of almost everything.
+	minFuzzyTokenLen = 3
+
+	// fuzzyWeight scales fuzzy scores below those of the token pass, so an
+	// approximate match never outranks a real one when results are merged.
+	fuzzyWeight = 0.5
+)
+
+// levenshtein returns the edit distance between a and b: the fewest
+// single-rune insertions, deletions and substitutions turning one into the
+// other. A transposition ("mvo" for "mov") counts as two.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// fuzzyDistanceLimit is the edit distance allowed for a query token: one
+// edit for three-letter tokens, maxFuzzyDistance for longer ones.
+func fuzzyDistanceLimit(qt string) int {
+	return min(maxFuzzyDistance, len(qt)-2)
+}
+
+// fuzzyScore scores how closely query tokens resemble key tokens by edit
+// distance, for queries the token pass found nothing for. Each query token
+// is compared with every key token and with the whole key run together, so
+// a misspelled key ("p2kbpasm2mvo") still finds its entry. Returns 0 if no
+// query token is within its distance limit, otherwise a score below
+// fuzzyWeight.
+func fuzzyScore(queryTokens, keyTokens []string) float64 {
+	if len(queryTokens) == 0 || len(keyTokens) == 0 {
+		return 0
+	}
+
+	candidates := append([]string{}, keyTokens...)
+	if keyTokens[0] == "p2kb" {
+		candidates = candidates[1:]
+		candidates = append(candidates, strings.Join(keyTokens[1:], ""))
+	}
+	candidates = append(candidates, strings.Join(keyTokens, ""))
+
+	total := 0.0
+	for _, qt := range queryTokens {
+		if len(qt) < minFuzzyTokenLen {
+			continue
+		}
+		limit := fuzzyDistanceLimit(qt)
+		best := 0.0
+		for _, kt := range candidates {
+			if abs(len(kt)-len(qt)) > limit {
+				continue
+			}
+			d := levenshtein(qt, kt)
+			if d > limit {
+				continue
+			}
+			best = max(best, 1-float64(d)/float64(max(len(qt), len(kt))))
+		}
+		total += best
+	}
+	return total / float64(len(queryTokens)) * fuzzyWeight
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
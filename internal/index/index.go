@@ -3,17 +3,24 @@ package index
 
 import (
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
+	"github.com/ironsheep/p2kb-mcp/internal/fetch"
 	"github.com/ironsheep/p2kb-mcp/internal/paths"
 )
 
@@ -21,6 +28,16 @@ import (
 // so tests can point the remote tier at a local httptest server.
 var IndexURL = "https://raw.githubusercontent.com/ironsheep/P2-Knowledge-Base/main/deliverables/ai/p2kb-index.json.gz"
 
+// CommitsURL is the GitHub API listing of the latest commit to touch the index
+// file, polled by WatchForChanges. A var so tests can point it at a local
+// httptest server.
+var CommitsURL = "https://api.github.com/repos/ironsheep/P2-Knowledge-Base/commits?path=deliverables/ai/p2kb-index.json.gz&per_page=1"
+
+// minWatchInterval is the shortest polling interval WatchForChanges accepts,
+// keeping unauthenticated polling well inside GitHub's rate limit. A var so
+// tests can shorten it.
+var minWatchInterval = 5 * time.Minute
+
 const (
 	// DefaultIndexTTL is the default time-to-live for the cached index.
 	// A short 5-minute window lets EnsureIndex ride the Fastly CDN edge on the
@@ -82,76 +99,171 @@ type IndexStatus struct {
 // Manager handles index operations.
 type Manager struct {
 	mu               sync.RWMutex
-	fetchMu          sync.Mutex // Prevents concurrent fetches, separate from data lock
+	fetchMu          sync.Mutex    // Prevents concurrent fetches, separate from data lock
+	fetching         chan struct{} // Closed when the EnsureIndex load in progress finishes; nil when none is
+	fetchErr         error         // Outcome of the last EnsureIndex load, for the callers that waited on it
 	index            *Index
 	indexPath        string
 	metaPath         string
 	lastRefresh      time.Time
 	ttl              time.Duration
-	lastErrorRefresh time.Time // Tracks last refresh-on-error attempt to prevent refresh storms
+	lastErrorRefresh time.Time           // Tracks last refresh-on-error attempt to prevent refresh storms
+	timedRefresh     bool                // Refresh on a TTL timer instead of on first use after expiry
+	refreshTimer     *time.Timer         // Pending background refresh; nil when none is armed
+	closed           bool                // Set by Close; no further timers are armed
+	extraURLs        []string            // Supplementary index URLs from P2KB_EXTRA_INDEX_URLS
+	keySources       map[string]string   // key -> extra index URL that provided it; public keys absent
+	idf              map[string]float64  // key token -> inverse document frequency, rebuilt on load
+	categoryAliases  map[string][]string // lowercased category suffix ("math") -> categories, rebuilt on load
 }
 
 // NewManager creates a new index manager.
 func NewManager() *Manager {
 	cacheDir := paths.GetCacheDirOrDefault()
 	return &Manager{
-		indexPath: filepath.Join(cacheDir, "index", "p2kb-index.json"),
-		metaPath:  filepath.Join(cacheDir, "index", "p2kb-index.meta"),
-		ttl:       getIndexTTL(),
+		indexPath:    filepath.Join(cacheDir, "index", "p2kb-index.json"),
+		metaPath:     filepath.Join(cacheDir, "index", "p2kb-index.meta"),
+		ttl:          getIndexTTL(),
+		extraURLs:    getExtraIndexURLs(),
+		timedRefresh: os.Getenv("P2KB_BACKGROUND_REFRESH") != "false",
 	}
 }
 
-// EnsureIndex ensures the index is loaded and fresh.
-// This method is safe for concurrent access and does NOT hold locks during network I/O.
-func (m *Manager) EnsureIndex() error {
-	// Fast path: check with read lock if we have a fresh index
-	m.mu.RLock()
-	if m.index != nil && time.Since(m.lastRefresh) < m.ttl {
-		m.mu.RUnlock()
-		return nil
+// Close cancels any pending background refresh. The manager remains usable;
+// it just stops refreshing on its own.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	if m.refreshTimer != nil {
+		m.refreshTimer.Stop()
+		m.refreshTimer = nil
 	}
-	m.mu.RUnlock()
+}
 
-	// Slow path: need to load or refresh
-	// Use fetchMu to prevent concurrent fetches (separate from data lock)
-	m.fetchMu.Lock()
-	defer m.fetchMu.Unlock()
+// isFreshLocked reports whether the loaded index can be served without
+// fetching. With background refresh on, any loaded index is served and the
+// timer keeps it current, so only the very first load blocks a caller.
+func (m *Manager) isFreshLocked() bool {
+	if m.index == nil {
+		return false
+	}
+	return m.timedRefresh || time.Since(m.lastRefresh) < m.ttl
+}
+
+// armRefreshLocked schedules the next background refresh for when the current
+// index reaches its TTL. Caller must hold m.mu for writing.
+func (m *Manager) armRefreshLocked() {
+	if !m.timedRefresh || m.closed {
+		return
+	}
+	if m.refreshTimer != nil {
+		m.refreshTimer.Stop()
+	}
+	m.refreshTimer = time.AfterFunc(time.Until(m.lastRefresh.Add(m.ttl)), m.scheduleRefresh)
+}
 
-	// Double-check after acquiring fetch lock (another goroutine may have loaded it)
+// scheduleRefresh runs when the refresh timer fires. The fetch happens in its
+// own goroutine; on success fetchAndInstall arms the next timer, on failure
+// the refresh is retried a full TTL later.
+func (m *Manager) scheduleRefresh() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.refreshTimer = nil
+	m.mu.Unlock()
+
+	go func() {
+		m.fetchMu.Lock()
+		defer m.fetchMu.Unlock()
+
+		m.mu.RLock()
+		closed := m.closed
+		m.mu.RUnlock()
+		if closed {
+			return
+		}
+
+		// bust=false: a timed refresh is the lazy TTL path, so ride the CDN edge
+		if err := m.fetchAndInstall(false); err != nil {
+			slog.Warn("background index refresh failed", "error", err)
+			m.mu.Lock()
+			if !m.closed && m.refreshTimer == nil {
+				m.refreshTimer = time.AfterFunc(m.ttl, m.scheduleRefresh)
+			}
+			m.mu.Unlock()
+		}
+	}()
+}
+
+// EnsureIndex ensures the index is loaded and fresh.
+// This method is safe for concurrent access and holds no lock during disk or
+// network I/O. Concurrent callers share a single load: the first loads from
+// the cache or fetches, the rest wait for it and return its result.
+func (m *Manager) EnsureIndex() error {
+	// Fast path: check with read lock if we have a fresh index
 	m.mu.RLock()
-	if m.index != nil && time.Since(m.lastRefresh) < m.ttl {
+	if m.isFreshLocked() {
 		m.mu.RUnlock()
 		return nil
 	}
 	m.mu.RUnlock()
 
-	// Try to load from cache (quick file I/O, safe to hold write lock briefly)
+	// Slow path: join the load in progress, or start one
 	m.mu.Lock()
-	if m.loadFromCache() {
+	if m.isFreshLocked() {
 		m.mu.Unlock()
 		return nil
 	}
-	m.mu.Unlock()
+	if done := m.fetching; done != nil {
+		m.mu.Unlock()
+		<-done
 
-	// Fetch from remote WITHOUT holding the data lock
-	// This is the critical fix: network I/O happens outside the lock
-	// bust=false: ride the Fastly CDN edge on the lazy TTL-expiry path.
-	idx, data, err := m.fetchIndexData(false)
-	if err != nil {
-		return fmt.Errorf("index fetch failed: %w", err)
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return m.fetchErr
 	}
+	done := make(chan struct{})
+	m.fetching = done
+	m.mu.Unlock()
+
+	err := m.loadOrFetch()
 
-	// Update the index under write lock (quick operation)
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.fetching = nil
+	m.fetchErr = err
+	m.mu.Unlock()
+	close(done)
+	return err
+}
 
-	// Save to cache
-	if err := m.saveToCache(data); err != nil {
-		fmt.Fprintf(os.Stderr, "p2kb-mcp: warning: failed to cache index: %v\n", err)
+// loadOrFetch installs the cached index if it is fresh, and fetches it
+// otherwise. EnsureIndex calls it with no lock held.
+func (m *Manager) loadOrFetch() error {
+	if idx, sources, modTime, ok := m.loadFromCache(m.indexPath); ok {
+		m.mu.Lock()
+		m.installLocked(idx, sources, modTime)
+		m.mu.Unlock()
+		return nil
 	}
 
-	m.index = idx
-	m.lastRefresh = time.Now()
+	m.fetchMu.Lock()
+	defer m.fetchMu.Unlock()
+
+	// A Refresh or timed refresh may have installed an index while we waited
+	m.mu.RLock()
+	fresh := m.isFreshLocked()
+	m.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	// bust=false: ride the Fastly CDN edge on the lazy TTL-expiry path.
+	if err := m.fetchAndInstall(false); err != nil {
+		return fmt.Errorf("index fetch failed: %w", err)
+	}
 	return nil
 }
 
@@ -162,26 +274,153 @@ func (m *Manager) Refresh() error {
 	m.fetchMu.Lock()
 	defer m.fetchMu.Unlock()
 
-	// Fetch from remote WITHOUT holding the data lock
 	// bust=true: bypass CDN cache on explicit user-triggered refresh.
-	idx, data, err := m.fetchIndexData(true)
-	if err != nil {
+	if err := m.fetchAndInstall(true); err != nil {
 		return fmt.Errorf("index refresh failed: %w", err)
 	}
+	return nil
+}
+
+// WatchForChanges polls CommitsURL every interval (at least five minutes) and
+// calls onChange with the new commit SHA whenever the latest commit to the
+// index file differs from the last one seen. The last SHA is kept in the
+// index .meta file, so a change made while the server was down is reported
+// by the first poll; with no SHA on record the first poll only records it.
+// Failed polls are logged and retried at the next interval. onChange is
+// expected to refresh the index. WatchForChanges blocks until ctx is done and
+// returns ctx.Err().
+func (m *Manager) WatchForChanges(ctx context.Context, interval time.Duration, onChange func(newVersion string)) error {
+	interval = max(interval, minWatchInterval)
+	client := newGitHubClient()
+	lastSHA := m.loadCommitSHA()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		sha, err := fetchLatestCommitSHA(ctx, client)
+		switch {
+		case err != nil:
+			if ctx.Err() == nil {
+				slog.Warn("index change check failed", "error", err)
+			}
+		case sha != lastSHA:
+			if err := m.saveCommitSHA(sha); err != nil {
+				slog.Warn("failed to record index commit", "commit", sha, "error", err)
+			}
+			previous := lastSHA
+			lastSHA = sha
+			if previous != "" {
+				onChange(sha)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// newGitHubClient returns the client index and commit fetches are made with,
+// its GitHub requests authenticated from fetch.SharedTokenPool. A var so
+// tests can route it to a local server.
+var newGitHubClient = func() *http.Client {
+	return fetch.NewClient().HTTPClient()
+}
+
+// fetchLatestCommitSHA returns the SHA of the newest commit listed at
+// CommitsURL.
+func fetchLatestCommitSHA(ctx context.Context, client *http.Client) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", CommitsURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", "p2kb-mcp")
 
-	// Update the index under write lock
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("network error listing index commits: %w", errs.Transport(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("listing index commits: %w", errs.HTTPStatus(resp, CommitsURL))
+	}
+
+	var commits []struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
+		return "", fmt.Errorf("failed to parse index commits: %w", err)
+	}
+	if len(commits) == 0 || commits[0].SHA == "" {
+		return "", fmt.Errorf("no commits listed for the index file")
+	}
+	return commits[0].SHA, nil
+}
+
+// commitSHAHeader names the line of the index .meta file holding the SHA of
+// the last index commit WatchForChanges saw.
+const commitSHAHeader = "X-Commit-SHA"
+
+// loadCommitSHA returns the commit SHA recorded in the .meta file, or "" if
+// there is none.
+func (m *Manager) loadCommitSHA() string {
+	data, err := os.ReadFile(m.metaPath)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(name) == commitSHAHeader {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// saveCommitSHA records sha in the .meta file.
+func (m *Manager) saveCommitSHA(sha string) error {
+	if err := os.MkdirAll(filepath.Dir(m.metaPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.metaPath, []byte(fmt.Sprintf("%s: %s\n", commitSHAHeader, sha)), 0644)
+}
+
+// fetchAndInstall fetches the index (and any extras) from remote, caches it
+// and swaps it in. Caller must hold fetchMu but not mu: network I/O happens
+// outside the data lock, which is only held for the quick swap.
+func (m *Manager) fetchAndInstall(bust bool) error {
+	idx, data, err := m.fetchIndexData(bust)
+	if err != nil {
+		return err
+	}
+	extras := m.fetchExtraIndexes(bust)
+
+	// Update the index under write lock (quick operation)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if err := m.saveToCache(data); err != nil {
-		fmt.Fprintf(os.Stderr, "p2kb-mcp: warning: failed to cache index: %v\n", err)
+		slog.Warn("failed to cache index", "error", err)
 	}
+	m.saveExtrasToCache(extras)
 
-	m.index = idx
-	m.lastRefresh = time.Now()
+	merged, sources := mergeIndexes(idx, extras)
+	m.installLocked(merged, sources, time.Now())
 	return nil
 }
 
+// installLocked swaps in idx, with the extra index each key came from,
+// refreshed at the given time, and arms the next background refresh. Caller
+// must hold m.mu for writing.
+func (m *Manager) installLocked(idx *Index, sources map[string]string, refreshed time.Time) {
+	m.setIndexLocked(idx, sources)
+	m.lastRefresh = refreshed
+	m.armRefreshLocked()
+}
+
 // KeyResolution contains the result of resolving a key through aliases.
 type KeyResolution struct {
 	CanonicalKey string // The resolved canonical key (e.g., "p2kbPasm2Add")
@@ -312,13 +551,34 @@ func (m *Manager) GetKeyPath(key string) (path string, mtime int64, sha256 strin
 
 	resolution := m.resolveKeyLocked(key)
 	if !resolution.Found {
-		return "", 0, "", fmt.Errorf("key not found: %s", key)
+		return "", 0, "", &errs.ErrKeyNotFound{Key: key}
 	}
 
 	entry := m.index.Files[resolution.CanonicalKey]
 	return entry.Path, entry.Mtime, entry.SHA256, nil
 }
 
+// GetKeySource returns the URL of the index that provided a key: IndexURL
+// for public keys, or one of the P2KB_EXTRA_INDEX_URLS for keys that only an
+// extra index carries. Supports aliases. Returns "" if the key is not found.
+func (m *Manager) GetKeySource(key string) string {
+	if err := m.EnsureIndex(); err != nil {
+		return ""
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	resolution := m.resolveKeyLocked(key)
+	if !resolution.Found {
+		return ""
+	}
+	if source, ok := m.keySources[resolution.CanonicalKey]; ok {
+		return source
+	}
+	return IndexURL
+}
+
 // KeyExists checks if a key exists in the index.
 // Supports both canonical keys and aliases.
 func (m *Manager) KeyExists(key string) bool {
@@ -337,7 +597,8 @@ func (m *Manager) KeyExists(key string) bool {
 // It substring-matches both canonical file keys and alias names (case-insensitive).
 // When an alias name matches, all of its target canonical keys are included,
 // provided they exist in m.index.Files (dangling aliases are skipped).
-// Results are deduplicated, sorted, and truncated to limit (when limit > 0).
+// Results are deduplicated, sorted, and truncated to limit; a limit of 0 means
+// no limit.
 func (m *Manager) Search(term string, limit int) []string {
 	if term == "" {
 		return nil
@@ -388,6 +649,123 @@ func (m *Manager) Search(term string, limit int) []string {
 	return matches
 }
 
+// RankedResult is a Search hit with its TF-IDF relevance score.
+type RankedResult struct {
+	Key   string  `json:"key"`
+	Score float64 `json:"score"`
+}
+
+// partialTokenWeight is the term-frequency weight of a key token that only
+// contains the query token ("mov" in "movbyts"), relative to an exact match.
+const partialTokenWeight = 0.5
+
+// SearchRanked returns the same keys as Search, ordered by TF-IDF relevance
+// instead of alphabetically. For each query token, term frequency is the
+// fraction of the key's tokens that match it (partial matches weighted by
+// partialTokenWeight) and inverse document frequency is
+// log(totalKeys / keysContainingToken), taken from the table built when the
+// index is loaded. Ties are broken alphabetically. At most limit results are
+// returned; a limit of 0 means no limit.
+func (m *Manager) SearchRanked(term string, limit int) []RankedResult {
+	keys := m.Search(term, 0)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	m.mu.RLock()
+	idf := m.idf
+	total := 0
+	if m.index != nil {
+		total = len(m.index.Files)
+	}
+	if idf == nil && m.index != nil {
+		idf = buildIDF(m.index)
+	}
+	m.mu.RUnlock()
+
+	queryTokens := tokenizeQuery(term)
+	results := make([]RankedResult, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, RankedResult{
+			Key:   key,
+			Score: tfidfScore(queryTokens, KeyTokens(key), idf, total),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Key < results[j].Key
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// tfidfScore sums TF-IDF over the query tokens for one key. A query token that
+// is not itself a key token is treated as maximally rare (document frequency 1).
+func tfidfScore(queryTokens, keyTokens []string, idf map[string]float64, totalKeys int) float64 {
+	if len(keyTokens) == 0 || totalKeys == 0 {
+		return 0
+	}
+
+	score := 0.0
+	for _, qt := range queryTokens {
+		matched := 0.0
+		for _, kt := range keyTokens {
+			switch {
+			case kt == qt:
+				matched++
+			case len(qt) >= 3 && strings.Contains(kt, qt):
+				matched += partialTokenWeight
+			}
+		}
+		if matched == 0 {
+			continue
+		}
+
+		weight, ok := idf[qt]
+		if !ok {
+			weight = math.Log(float64(totalKeys))
+		}
+		score += matched / float64(len(keyTokens)) * weight
+	}
+	return score
+}
+
+// buildIDF computes log(totalKeys / keysContainingToken) for every key token.
+func buildIDF(idx *Index) map[string]float64 {
+	docFreq := make(map[string]int)
+	for key := range idx.Files {
+		seen := make(map[string]bool)
+		for _, token := range KeyTokens(key) {
+			if !seen[token] {
+				seen[token] = true
+				docFreq[token]++
+			}
+		}
+	}
+
+	total := float64(len(idx.Files))
+	idf := make(map[string]float64, len(docFreq))
+	for token, n := range docFreq {
+		idf[token] = math.Log(total / float64(n))
+	}
+	return idf
+}
+
+// setIndexLocked installs a freshly loaded index and its derived tables.
+// Caller holds the write lock.
+func (m *Manager) setIndexLocked(idx *Index, sources map[string]string) {
+	m.index = idx
+	m.keySources = sources
+	m.idf = buildIDF(idx)
+	m.categoryAliases = buildCategoryAliases(idx)
+}
+
 // FindSimilarKeys finds keys similar to the given key.
 func (m *Manager) FindSimilarKeys(key string, limit int) []string {
 	if err := m.EnsureIndex(); err != nil {
@@ -425,18 +803,37 @@ func (m *Manager) FindSimilarKeys(key string, limit int) []string {
 	return matches
 }
 
-// QueryMatch represents a key that matches a natural language query.
-type QueryMatch struct {
+// MatchResult represents a key that matches a natural language query.
+type MatchResult struct {
 	Key      string  `json:"key"`
 	Score    float64 `json:"score"`
 	Category string  `json:"category,omitempty"`
+	Fuzzy    bool    `json:"fuzzy,omitempty"` // Found by edit distance, not by token match
 }
 
+// QueryMatch is the previous name for MatchResult.
+//
+// Deprecated: use MatchResult.
+type QueryMatch = MatchResult
+
+// Category boosts added to a key's score in MatchQuery. A query token that
+// starts a category name ("pasm2" for pasm2_math) says more about intent than
+// one matching a later part ("math"), so it earns the larger boost.
+const (
+	categoryPrefixBoost = 0.15
+	categoryPartBoost   = 0.1
+)
+
+// maxQueryMatches caps the number of results MatchQuery returns.
+const maxQueryMatches = 20
+
 // MatchQuery finds keys matching a natural language query.
 // Returns exact match if query is a valid key or alias, otherwise finds best matches.
+// When no key shares a token with the query, keys within maxFuzzyDistance
+// edits of a query token are returned instead, marked Fuzzy.
 // Query examples: "mov instruction", "pasm2 add", "spin2 pinwrite", "cog architecture"
 // Also supports aliases: "ADD", "WAITMS", "motor_controller"
-func (m *Manager) MatchQuery(query string) ([]QueryMatch, error) {
+func (m *Manager) MatchQuery(query string) ([]MatchResult, error) {
 	if err := m.EnsureIndex(); err != nil {
 		return nil, err
 	}
@@ -448,7 +845,7 @@ func (m *Manager) MatchQuery(query string) ([]QueryMatch, error) {
 	resolution := m.resolveKeyLocked(query)
 	if resolution.Found {
 		cat := m.getKeyCategory(resolution.CanonicalKey)
-		return []QueryMatch{{Key: resolution.CanonicalKey, Score: 1.0, Category: cat}}, nil
+		return []MatchResult{{Key: resolution.CanonicalKey, Score: 1.0, Category: cat}}, nil
 	}
 
 	// Tokenize the query
@@ -456,31 +853,106 @@ func (m *Manager) MatchQuery(query string) ([]QueryMatch, error) {
 	if len(queryTokens) == 0 {
 		return nil, fmt.Errorf("empty query")
 	}
+	queryTokenSet := make(map[string]bool, len(queryTokens))
+	for _, qt := range queryTokens {
+		queryTokenSet[qt] = true
+	}
+
+	// One pass over the categories instead of a scan per matching key
+	keyCategories := m.keyCategoriesLocked()
 
 	// Score each key
-	var matches []QueryMatch
+	var matches []MatchResult
 	for key := range m.index.Files {
 		keyTokens := tokenizeKey(key)
 		score := scoreMatch(queryTokens, keyTokens)
-		if score > 0 {
-			cat := m.getKeyCategory(key)
-			matches = append(matches, QueryMatch{Key: key, Score: score, Category: cat})
+		if score <= 0 {
+			continue
 		}
+
+		boost, cat := categoryBoost(keyCategories[key], queryTokens, queryTokenSet)
+		matches = append(matches, MatchResult{Key: key, Score: score + boost, Category: cat})
 	}
 
-	// Sort by score descending
+	// Nothing matched by token: try the keys within a few typos of the query
+	if len(matches) == 0 {
+		for key := range m.index.Files {
+			score := fuzzyScore(queryTokens, tokenizeKey(key))
+			if score <= 0 {
+				continue
+			}
+			_, cat := categoryBoost(keyCategories[key], queryTokens, queryTokenSet)
+			matches = append(matches, MatchResult{Key: key, Score: score, Category: cat, Fuzzy: true})
+		}
+	}
+
+	// Sort by score descending, then by key for stable output
 	sort.Slice(matches, func(i, j int) bool {
-		return matches[i].Score > matches[j].Score
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Key < matches[j].Key
 	})
 
 	// Limit results
-	if len(matches) > 20 {
-		matches = matches[:20]
+	if len(matches) > maxQueryMatches {
+		matches = matches[:maxQueryMatches]
 	}
 
 	return matches, nil
 }
 
+// keyCategoriesLocked maps each key to its categories, sorted by name
+// (internal, caller holds the lock).
+func (m *Manager) keyCategoriesLocked() map[string][]string {
+	names := make([]string, 0, len(m.index.Categories))
+	for cat := range m.index.Categories {
+		names = append(names, cat)
+	}
+	sort.Strings(names)
+
+	result := make(map[string][]string, len(m.index.Files))
+	for _, cat := range names {
+		for _, k := range m.index.Categories[cat] {
+			result[k] = append(result[k], cat)
+		}
+	}
+	return result
+}
+
+// categoryBoost returns the score boost earned by a key's categories and the
+// category to report for it: the first with a query token as a prefix of its
+// name, else the first sharing a part with the query, else the first category.
+func categoryBoost(cats, queryTokens []string, queryTokenSet map[string]bool) (float64, string) {
+	for _, c := range cats {
+		for _, qt := range queryTokens {
+			if len(qt) >= 3 && strings.HasPrefix(c, qt) {
+				return categoryPrefixBoost, c
+			}
+		}
+	}
+	for _, c := range cats {
+		if categoryMatchesQuery(c, queryTokenSet) {
+			return categoryPartBoost, c
+		}
+	}
+	if len(cats) > 0 {
+		return 0, cats[0]
+	}
+	return 0, ""
+}
+
+// categoryMatchesQuery reports whether a category name, or one of its
+// underscore-separated parts, appears among the query tokens.
+func categoryMatchesQuery(category string, queryTokens map[string]bool) bool {
+	for _, part := range tokenizeQuery(category) {
+		if queryTokens[part] {
+			return true
+		}
+	}
+	return false
+}
+
 // getKeyCategory returns the first category a key belongs to (internal, no lock).
 func (m *Manager) getKeyCategory(key string) string {
 	for cat, keys := range m.index.Categories {
@@ -521,6 +993,16 @@ func tokenizeKey(key string) []string {
 	return tokens
 }
 
+// KeyTokens returns the lowercase words of a key without the "p2kb" prefix.
+// "p2kbPasm2Mov" -> ["pasm2", "mov"]
+func KeyTokens(key string) []string {
+	tokens := tokenizeKey(key)
+	if len(tokens) > 0 && tokens[0] == "p2kb" {
+		tokens = tokens[1:]
+	}
+	return tokens
+}
+
 // tokenizeQuery splits a natural language query into lowercase tokens.
 // "MOV instruction" -> ["mov", "instruction"]
 // "spin2 pinwrite method" -> ["spin2", "pinwrite", "method"]
@@ -547,8 +1029,69 @@ func tokenizeQuery(query string) []string {
 	return tokens
 }
 
+// expandedTokenWeight is the share of a match a query token earns through
+// one of its domainExpansion tokens rather than directly.
+const expandedTokenWeight = 0.5
+
+// domainExpansion maps P2 domain terms to key tokens they imply, so a query
+// for "cordic" also finds the math and Q-instruction entries. Terms of two
+// words are keyed by their query tokens joined with a space and apply to both
+// words. Read-only.
+var domainExpansion = map[string][]string{
+	"cordic":      {"math", "fixed", "point", "qmul", "qdiv", "qfrac", "qsqrt", "qrotate", "qvector", "qlog", "qexp"},
+	"smart pin":   {"mode", "configuration", "wrpin", "wxpin", "wypin", "rdpin", "rqpin", "akpin", "pinstart"},
+	"smartpin":    {"mode", "configuration", "wrpin", "wxpin", "wypin", "rdpin", "rqpin", "akpin", "pinstart"},
+	"streamer":    {"xinit", "xcont", "xzero", "xstop", "video", "dac"},
+	"interrupt":   {"int1", "int2", "int3", "setint1", "reti1", "event"},
+	"event":       {"pollct1", "waitct1", "setse1", "interrupt"},
+	"hub":         {"memory", "rdlong", "wrlong", "fifo", "rdfast", "wrfast"},
+	"lut":         {"rdlut", "wrlut", "memory", "sharing"},
+	"random":      {"getrnd", "xoro32", "hubset"},
+	"timing":      {"waitx", "getct", "waitct", "addct1"},
+	"fixed point": {"math", "cordic", "muls", "qfrac"},
+}
+
+// expandQueryTokens returns, for each query token, the domainExpansion tokens
+// it implies that are not already query tokens.
+func expandQueryTokens(queryTokens []string) [][]string {
+	inQuery := make(map[string]bool, len(queryTokens))
+	for _, qt := range queryTokens {
+		inQuery[qt] = true
+	}
+
+	expansions := make([][]string, len(queryTokens))
+	add := func(i int, terms []string) {
+		for _, term := range terms {
+			if !inQuery[term] {
+				expansions[i] = append(expansions[i], term)
+			}
+		}
+	}
+	for i, qt := range queryTokens {
+		add(i, domainExpansion[qt])
+		if i+1 < len(queryTokens) {
+			if terms, ok := domainExpansion[qt+" "+queryTokens[i+1]]; ok {
+				add(i, terms)
+				add(i+1, terms)
+			}
+		}
+	}
+	return expansions
+}
+
+// tokenMatches reports whether query token qt matches key token kt exactly,
+// as a substring or as a prefix (for partial words like "mov" in "move").
+func tokenMatches(qt, kt string) bool {
+	if qt == kt {
+		return true
+	}
+	return len(qt) >= 3 && (strings.Contains(kt, qt) || strings.HasPrefix(kt, qt))
+}
+
 // scoreMatch calculates how well query tokens match key tokens.
-// Returns a score from 0 to 1, where 1 is a perfect match.
+// Returns a score from 0 to 1, where 1 is a perfect match. A query token that
+// misses the key but implies one of its tokens through domainExpansion counts
+// for expandedTokenWeight of a match.
 func scoreMatch(queryTokens, keyTokens []string) float64 {
 	if len(queryTokens) == 0 || len(keyTokens) == 0 {
 		return 0
@@ -560,23 +1103,19 @@ func scoreMatch(queryTokens, keyTokens []string) float64 {
 		filteredKeyTokens = keyTokens[1:]
 	}
 
-	// Count matching tokens
-	matches := 0
-	for _, qt := range queryTokens {
-		for _, kt := range filteredKeyTokens {
-			// Exact match
-			if qt == kt {
-				matches++
-				break
-			}
-			// Substring match (for partial words like "mov" matching "move")
-			if len(qt) >= 3 && strings.Contains(kt, qt) {
-				matches++
-				break
-			}
-			// Prefix match
-			if len(qt) >= 3 && strings.HasPrefix(kt, qt) {
-				matches++
+	// Count matching tokens, directly or through an expansion
+	expansions := expandQueryTokens(queryTokens)
+	direct := 0
+	matches := 0.0
+	for i, qt := range queryTokens {
+		if matchesAnyToken(qt, filteredKeyTokens) {
+			direct++
+			matches++
+			continue
+		}
+		for _, term := range expansions[i] {
+			if matchesAnyToken(term, filteredKeyTokens) {
+				matches += expandedTokenWeight
 				break
 			}
 		}
@@ -587,10 +1126,10 @@ func scoreMatch(queryTokens, keyTokens []string) float64 {
 	}
 
 	// Score: ratio of matched query tokens, with bonus for more specific matches
-	score := float64(matches) / float64(len(queryTokens))
+	score := matches / float64(len(queryTokens))
 
 	// Bonus for matching more key tokens
-	if matches == len(queryTokens) && len(queryTokens) > 1 {
+	if direct == len(queryTokens) && len(queryTokens) > 1 {
 		score += 0.1
 	}
 
@@ -602,6 +1141,16 @@ func scoreMatch(queryTokens, keyTokens []string) float64 {
 	return score
 }
 
+// matchesAnyToken reports whether query token qt matches any of keyTokens.
+func matchesAnyToken(qt string, keyTokens []string) bool {
+	for _, kt := range keyTokens {
+		if tokenMatches(qt, kt) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetAllKeys returns all keys in the index.
 func (m *Manager) GetAllKeys() []string {
 	if err := m.EnsureIndex(); err != nil {
@@ -619,6 +1168,122 @@ func (m *Manager) GetAllKeys() []string {
 	return keys
 }
 
+// KeyInfo is a key's file entry joined with the categories it belongs to.
+type KeyInfo struct {
+	Key        string   `json:"-"`
+	Path       string   `json:"path"`
+	Categories []string `json:"categories"`
+	Mtime      int64    `json:"mtime"`
+}
+
+// ListKeys returns the keys that start with prefix and belong to category
+// (exact name; empty means any), sorted, along with the total number of keys
+// in the index before filtering.
+func (m *Manager) ListKeys(prefix, category string) (keys []KeyInfo, total int, err error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keyCategories := m.keyCategoriesLocked()
+	keys = make([]KeyInfo, 0)
+	for key, entry := range m.index.Files {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		cats := keyCategories[key]
+		if category != "" && !containsCategory(cats, category) {
+			continue
+		}
+		if cats == nil {
+			cats = []string{}
+		}
+		keys = append(keys, KeyInfo{Key: key, Path: entry.Path, Categories: cats, Mtime: entry.Mtime})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+
+	return keys, len(m.index.Files), nil
+}
+
+// containsCategory reports whether cats includes category.
+func containsCategory(cats []string, category string) bool {
+	for _, c := range cats {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyMtimeResult is a key with its upstream modification time, both as the
+// index's Unix timestamp and as RFC3339 for display.
+type KeyMtimeResult struct {
+	Key     string `json:"key"`
+	Mtime   int64  `json:"mtime"`
+	Updated string `json:"updated"`
+}
+
+// GetKeysByMtime returns up to limit keys from the whole index (all of them
+// if limit <= 0), most recently modified first.
+func (m *Manager) GetKeysByMtime(limit int) []KeyMtimeResult {
+	if err := m.EnsureIndex(); err != nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]string, 0, len(m.index.Files))
+	for key := range m.index.Files {
+		keys = append(keys, key)
+	}
+	return m.sortKeysByMtimeLocked(keys, limit)
+}
+
+// SortKeysByMtime returns up to limit of keys (all of them if limit <= 0),
+// most recently modified first. Keys not in the index are dropped.
+func (m *Manager) SortKeysByMtime(keys []string, limit int) []KeyMtimeResult {
+	if err := m.EnsureIndex(); err != nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortKeysByMtimeLocked(keys, limit)
+}
+
+// sortKeysByMtimeLocked orders keys newest first, breaking mtime ties by key
+// so the order is stable. Caller must hold m.mu.
+func (m *Manager) sortKeysByMtimeLocked(keys []string, limit int) []KeyMtimeResult {
+	results := make([]KeyMtimeResult, 0, len(keys))
+	for _, key := range keys {
+		entry, ok := m.index.Files[key]
+		if !ok {
+			continue
+		}
+		results = append(results, KeyMtimeResult{
+			Key:     key,
+			Mtime:   entry.Mtime,
+			Updated: time.Unix(entry.Mtime, 0).UTC().Format(time.RFC3339),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Mtime != results[j].Mtime {
+			return results[i].Mtime > results[j].Mtime
+		}
+		return results[i].Key < results[j].Key
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
 // GetFileMtime returns the modification time for a key.
 // Supports both canonical keys and aliases.
 func (m *Manager) GetFileMtime(key string) (int64, error) {
@@ -631,7 +1296,7 @@ func (m *Manager) GetFileMtime(key string) (int64, error) {
 
 	resolution := m.resolveKeyLocked(key)
 	if !resolution.Found {
-		return 0, fmt.Errorf("key not found: %s", key)
+		return 0, &errs.ErrKeyNotFound{Key: key}
 	}
 
 	entry := m.index.Files[resolution.CanonicalKey]
@@ -671,8 +1336,8 @@ func (m *Manager) GetCategoriesWithCounts() map[string]int {
 	return counts
 }
 
-// GetCategoryKeys returns all keys in a category.
-// Category lookup is case-insensitive.
+// GetCategoryKeys returns all keys in a category, with no limit; callers page
+// the result themselves. Category lookup is case-insensitive.
 func (m *Manager) GetCategoryKeys(category string) ([]string, error) {
 	if err := m.EnsureIndex(); err != nil {
 		return nil, err
@@ -695,7 +1360,7 @@ func (m *Manager) GetCategoryKeys(category string) ([]string, error) {
 		}
 	}
 	if !ok {
-		return nil, fmt.Errorf("category not found: %s", category)
+		return nil, &errs.ErrCategoryNotFound{Category: category}
 	}
 
 	result := make([]string, len(keys))
@@ -704,6 +1369,53 @@ func (m *Manager) GetCategoryKeys(category string) ([]string, error) {
 	return result, nil
 }
 
+// GetCategoriesByAlias returns the categories whose suffix after the last
+// underscore matches alias, case-insensitively ("math" -> pasm2_math,
+// spin2_math), sorted by name. It returns nil if none match.
+func (m *Manager) GetCategoriesByAlias(alias string) []string {
+	if err := m.EnsureIndex(); err != nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	aliases := m.categoryAliases
+	if aliases == nil {
+		aliases = buildCategoryAliases(m.index)
+	}
+
+	matches := aliases[strings.ToLower(alias)]
+	if len(matches) == 0 {
+		return nil
+	}
+	result := make([]string, len(matches))
+	copy(result, matches)
+	return result
+}
+
+// buildCategoryAliases maps the lowercased suffix after the last underscore
+// of each category name to the categories sharing it, sorted by name.
+// Categories without an underscore get no alias.
+func buildCategoryAliases(idx *Index) map[string][]string {
+	aliases := make(map[string][]string)
+	if idx == nil {
+		return aliases
+	}
+	for cat := range idx.Categories {
+		i := strings.LastIndex(cat, "_")
+		if i < 0 || i == len(cat)-1 {
+			continue
+		}
+		alias := strings.ToLower(cat[i+1:])
+		aliases[alias] = append(aliases[alias], cat)
+	}
+	for _, cats := range aliases {
+		sort.Strings(cats)
+	}
+	return aliases
+}
+
 // GetKeyCategories returns the categories a key belongs to.
 func (m *Manager) GetKeyCategories(key string) []string {
 	if err := m.EnsureIndex(); err != nil {
@@ -743,6 +1455,13 @@ func (m *Manager) GetStats() Stats {
 	}
 }
 
+// IsLoaded reports whether an index is in memory, without loading one.
+func (m *Manager) IsLoaded() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.index != nil
+}
+
 // GetIndexStatus returns index freshness information.
 // This method releases the read lock before disk I/O for better concurrency.
 func (m *Manager) GetIndexStatus() IndexStatus {
@@ -777,33 +1496,211 @@ func (m *Manager) GetIndexStatus() IndexStatus {
 	return status
 }
 
-// loadFromCache attempts to load the index from the local cache.
-func (m *Manager) loadFromCache() bool {
+// loadFromCache reads the index cached at path, merged with the cached extra
+// indexes, and returns it with the extra index each key came from and the
+// cache file's modification time. ok is false when the cache is missing,
+// expired or unreadable. It takes no lock: the caller installs the result.
+func (m *Manager) loadFromCache(path string) (idx *Index, sources map[string]string, modTime time.Time, ok bool) {
+	return m.loadFromCacheWith(path, decodeIndex)
+}
+
+// loadFromCacheWith is loadFromCache parsing the file with decode, so the
+// benchmarks can compare decoders doing otherwise the same work.
+func (m *Manager) loadFromCacheWith(path string, decode func(io.Reader) (*Index, error)) (idx *Index, sources map[string]string, modTime time.Time, ok bool) {
 	// Check if cache exists and is fresh
-	info, err := os.Stat(m.indexPath)
+	info, err := os.Stat(path)
 	if err != nil {
-		return false
+		return nil, nil, time.Time{}, false
 	}
 
 	// Check TTL
 	if time.Since(info.ModTime()) > m.ttl {
-		return false
+		return nil, nil, time.Time{}, false
 	}
 
-	// Load the index
-	data, err := os.ReadFile(m.indexPath)
+	// decode reads from the file, so decodeIndex never holds the raw bytes in
+	// memory alongside the parsed structure.
+	f, err := os.Open(path)
 	if err != nil {
-		return false
+		return nil, nil, time.Time{}, false
 	}
+	defer f.Close()
 
-	var idx Index
-	if err := json.Unmarshal(data, &idx); err != nil {
-		return false
+	idx, err = decode(f)
+	if err == nil {
+		err = validateIndex(idx)
+	}
+	if err != nil {
+		// Unusable as is; remove it so the fetch that follows replaces it
+		slog.Warn("discarding cached index", "path", path, "error", err)
+		f.Close()
+		os.Remove(path)
+		return nil, nil, time.Time{}, false
 	}
 
-	m.index = &idx
-	m.lastRefresh = info.ModTime()
-	return true
+	// Every configured extra index must be cached too, otherwise fall through
+	// to the remote path so the merged view is complete.
+	extras, ok := m.loadExtrasFromCache()
+	if !ok {
+		return nil, nil, time.Time{}, false
+	}
+
+	idx, sources = mergeIndexes(idx, extras)
+	return idx, sources, info.ModTime(), true
+}
+
+// maxIndexSizeHint bounds the map sizes decodeIndex takes from the system
+// section, so a corrupt count cannot make it allocate a huge map up front.
+const maxIndexSizeHint = 1 << 16
+
+// decodeIndex parses an index JSON document from r one entry at a time.
+// Unlike json.Unmarshal it never buffers the whole document: "categories",
+// "files" and "aliases" are walked token by token and each entry is decoded
+// individually, so peak memory is roughly the parsed Index alone. Top-level
+// sections may appear in any order; unknown sections are skipped. When
+// "system" comes first, as the generator writes it, its totals size the maps
+// up front, sparing the copies a growing map leaves behind.
+func decodeIndex(r io.Reader) (*Index, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	idx := &Index{}
+	sizeHint := func(total int) int {
+		return max(0, min(total, maxIndexSizeHint))
+	}
+	// Key lists are decoded into one reused slice and stored as exact-size
+	// copies, rather than each growing its own
+	var scratch []string
+	decodeKeys := func() ([]string, error) {
+		if err := dec.Decode(&scratch); err != nil {
+			return nil, err
+		}
+		return slices.Clone(scratch), nil
+	}
+
+	for dec.More() {
+		section, err := objectKey(dec)
+		if err != nil {
+			return nil, err
+		}
+
+		switch section {
+		case "system":
+			err = dec.Decode(&idx.System)
+		case "categories":
+			if idx.Categories == nil {
+				idx.Categories = make(map[string][]string, sizeHint(idx.System.TotalCategories))
+			}
+			err = decodeEntries(dec, func(name string) error {
+				keys, err := decodeKeys()
+				if err != nil {
+					return err
+				}
+				idx.Categories[name] = keys
+				return nil
+			})
+		case "files":
+			if idx.Files == nil {
+				idx.Files = make(map[string]FileEntry, sizeHint(idx.System.TotalEntries))
+			}
+			err = decodeEntries(dec, func(key string) error {
+				var entry FileEntry
+				if err := dec.Decode(&entry); err != nil {
+					return err
+				}
+				idx.Files[key] = entry
+				return nil
+			})
+		case "aliases":
+			if idx.Aliases == nil {
+				idx.Aliases = make(map[string][]string, sizeHint(idx.System.TotalAliases))
+			}
+			err = decodeEntries(dec, func(alias string) error {
+				targets, err := decodeKeys()
+				if err != nil {
+					return err
+				}
+				idx.Aliases[alias] = targets
+				return nil
+			})
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode index section %q: %w", section, err)
+		}
+	}
+
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+	if idx.Categories == nil {
+		idx.Categories = make(map[string][]string)
+	}
+	if idx.Files == nil {
+		idx.Files = make(map[string]FileEntry)
+	}
+	if idx.Aliases == nil {
+		idx.Aliases = make(map[string][]string)
+	}
+	return idx, nil
+}
+
+// decodeEntries walks a JSON object whose values are decoded by decodeValue.
+// A JSON null is accepted and treated as an empty object.
+func decodeEntries(dec *json.Decoder, decodeValue func(key string) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected object, got %v", tok)
+	}
+
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return err
+		}
+		if err := decodeValue(key); err != nil {
+			return fmt.Errorf("entry %q: %w", key, err)
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// objectKey reads the next object key from dec.
+func objectKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key, got %v", tok)
+	}
+	return key, nil
+}
+
+// expectDelim reads the next token from dec and checks it is delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %q, got %v", delim, tok)
+	}
+	return nil
 }
 
 // fetchIndexData fetches the index from the remote URL and returns the parsed index and raw data.
@@ -818,14 +1715,52 @@ func (m *Manager) loadFromCache() bool {
 // params or headers, allowing the Fastly CDN edge to serve a cached response.
 // Use bust=false for the routine lazy TTL-expiry path (EnsureIndex).
 func (m *Manager) fetchIndexData(bust bool) (*Index, []byte, error) {
-	client := &http.Client{Timeout: 30 * time.Second}
+	idx, data, err := fetchIndexFrom(IndexURL, bust)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A download cut short between top-level keys still parses; don't let it
+	// replace a good index or reach the cache
+	if err := validateIndex(idx); err != nil {
+		slog.Warn("rejected index download", "url", IndexURL, "error", err)
+		return nil, nil, err
+	}
+	return idx, data, nil
+}
+
+// minIndexFileShare is the share of its declared total_entries an index must
+// actually carry, in percent, to pass validateIndex.
+const minIndexFileShare = 80
+
+// validateIndex rejects an index that parsed but is visibly incomplete, as a
+// truncated download can be: no entries, no files or no categories, or fewer
+// than minIndexFileShare percent of the files its system section declares.
+func validateIndex(idx *Index) error {
+	declared, files := idx.System.TotalEntries, len(idx.Files)
+	switch {
+	case declared <= 0:
+		return fmt.Errorf("corrupt index: system.total_entries is %d", declared)
+	case files == 0:
+		return fmt.Errorf("corrupt index: no files (declares %d entries)", declared)
+	case len(idx.Categories) == 0:
+		return fmt.Errorf("corrupt index: no categories (%d files)", files)
+	case files < declared*minIndexFileShare/100:
+		return fmt.Errorf("corrupt index: %d files but declares %d entries, below the %d%% minimum", files, declared, minIndexFileShare)
+	}
+	return nil
+}
 
-	fetchURL := IndexURL
+// fetchIndexFrom fetches and parses the gzipped index at indexURL.
+// Shared by the public index and the P2KB_EXTRA_INDEX_URLS indexes.
+func fetchIndexFrom(indexURL string, bust bool) (*Index, []byte, error) {
+	client := newGitHubClient()
+
+	fetchURL := indexURL
 	if bust {
 		// Cache-busting query parameter to bypass GitHub CDN cache.
 		// GitHub's CDN (Fastly) ignores client-side Cache-Control headers,
 		// but treats different query strings as different resources.
-		fetchURL = fmt.Sprintf("%s?t=%d", IndexURL, time.Now().UnixNano())
+		fetchURL = fmt.Sprintf("%s?t=%d", indexURL, time.Now().UnixNano())
 	}
 
 	req, err := http.NewRequest("GET", fetchURL, nil)
@@ -840,12 +1775,12 @@ func (m *Manager) fetchIndexData(bust bool) (*Index, []byte, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, nil, fmt.Errorf("network error fetching index from %s: %w", IndexURL, err)
+		return nil, nil, fmt.Errorf("network error fetching index from %s: %w", indexURL, errs.Transport(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, nil, fmt.Errorf("HTTP %d fetching index from %s", resp.StatusCode, IndexURL)
+		return nil, nil, fmt.Errorf("fetching index: %w", errs.HTTPStatus(resp, indexURL))
 	}
 
 	// Decompress gzip
@@ -907,6 +1842,137 @@ func (m *Manager) saveToCache(data []byte) error {
 	return os.WriteFile(m.indexPath, data, 0644)
 }
 
+// extraIndex is a supplementary index listed in P2KB_EXTRA_INDEX_URLS.
+type extraIndex struct {
+	url  string
+	idx  *Index
+	data []byte
+}
+
+// fetchExtraIndexes fetches every configured extra index. A failing extra is
+// logged and skipped so a private index outage never hides the public one.
+func (m *Manager) fetchExtraIndexes(bust bool) []extraIndex {
+	var extras []extraIndex
+	for _, url := range m.extraURLs {
+		idx, data, err := fetchIndexFrom(url, bust)
+		if err != nil {
+			slog.Warn("skipping extra index", "url", url, "error", err)
+			continue
+		}
+		extras = append(extras, extraIndex{url: url, idx: idx, data: data})
+	}
+	return extras
+}
+
+// extraIndexPath returns the cache file for an extra index, named by a hash
+// of its URL so reordering P2KB_EXTRA_INDEX_URLS keeps the cache valid.
+func (m *Manager) extraIndexPath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	name := fmt.Sprintf("p2kb-index-extra-%x.json", sum[:6])
+	return filepath.Join(filepath.Dir(m.indexPath), name)
+}
+
+// saveExtrasToCache writes each fetched extra index next to the public one.
+func (m *Manager) saveExtrasToCache(extras []extraIndex) {
+	for _, extra := range extras {
+		if err := os.WriteFile(m.extraIndexPath(extra.url), extra.data, 0644); err != nil {
+			slog.Warn("failed to cache extra index", "url", extra.url, "error", err)
+		}
+	}
+}
+
+// loadExtrasFromCache loads every configured extra index from the cache.
+// Returns false if any of them is missing or unreadable.
+func (m *Manager) loadExtrasFromCache() ([]extraIndex, bool) {
+	var extras []extraIndex
+	for _, url := range m.extraURLs {
+		f, err := os.Open(m.extraIndexPath(url))
+		if err != nil {
+			return nil, false
+		}
+		idx, err := decodeIndex(f)
+		f.Close()
+		if err != nil {
+			return nil, false
+		}
+		extras = append(extras, extraIndex{url: url, idx: idx})
+	}
+	return extras, true
+}
+
+// mergeIndexes folds extra indexes into the public one, in the order given.
+// On a key or alias collision the earlier index wins (public first). Category
+// membership is unioned. The public system version is kept; the totals are
+// recomputed to describe the merged view. The returned map records which extra
+// URL provided each non-public key.
+func mergeIndexes(public *Index, extras []extraIndex) (*Index, map[string]string) {
+	if len(extras) == 0 {
+		return public, nil
+	}
+	if public.Files == nil {
+		public.Files = make(map[string]FileEntry)
+	}
+	if public.Categories == nil {
+		public.Categories = make(map[string][]string)
+	}
+	if public.Aliases == nil {
+		public.Aliases = make(map[string][]string)
+	}
+
+	sources := make(map[string]string)
+	for _, extra := range extras {
+		for key, entry := range extra.idx.Files {
+			if _, exists := public.Files[key]; exists {
+				continue
+			}
+			public.Files[key] = entry
+			sources[key] = extra.url
+		}
+		for cat, keys := range extra.idx.Categories {
+			public.Categories[cat] = unionKeys(public.Categories[cat], keys)
+		}
+		for alias, keys := range extra.idx.Aliases {
+			if _, exists := public.Aliases[alias]; !exists {
+				public.Aliases[alias] = keys
+			}
+		}
+	}
+
+	public.System.TotalEntries = len(public.Files)
+	public.System.TotalCategories = len(public.Categories)
+	public.System.TotalAliases = len(public.Aliases)
+	return public, sources
+}
+
+// unionKeys appends the keys from add that are not already in base.
+func unionKeys(base, add []string) []string {
+	seen := make(map[string]bool, len(base))
+	for _, k := range base {
+		seen[k] = true
+	}
+	for _, k := range add {
+		if !seen[k] {
+			seen[k] = true
+			base = append(base, k)
+		}
+	}
+	return base
+}
+
+// ContentBaseURL derives the content base URL for an index URL. Index paths
+// are repo-relative, so for the standard layout
+// ".../deliverables/ai/p2kb-index.json.gz" the base is the repo root;
+// otherwise it is the directory holding the index file.
+func ContentBaseURL(indexURL string) string {
+	if i := strings.IndexByte(indexURL, '?'); i >= 0 {
+		indexURL = indexURL[:i]
+	}
+	const standardSuffix = "deliverables/ai/p2kb-index.json.gz"
+	if strings.HasSuffix(indexURL, standardSuffix) {
+		return strings.TrimSuffix(indexURL, standardSuffix)
+	}
+	return indexURL[:strings.LastIndexByte(indexURL, '/')+1]
+}
 
 // getIndexTTL returns the index TTL from environment or default.
 func getIndexTTL() time.Duration {
@@ -917,3 +1983,14 @@ func getIndexTTL() time.Duration {
 	}
 	return DefaultIndexTTL
 }
+
+// getExtraIndexURLs returns the comma-separated P2KB_EXTRA_INDEX_URLS list.
+func getExtraIndexURLs() []string {
+	var urls []string
+	for _, url := range strings.Split(os.Getenv("P2KB_EXTRA_INDEX_URLS"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
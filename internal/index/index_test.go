@@ -3,17 +3,29 @@ package index
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
+	"github.com/ironsheep/p2kb-mcp/internal/fetch"
 	"github.com/ironsheep/p2kb-mcp/internal/paths"
+	"github.com/ironsheep/p2kb-mcp/internal/testdata"
 )
 
 func TestNewManager(t *testing.T) {
@@ -157,8 +169,9 @@ func TestManagerGetCategoryKeys(t *testing.T) {
 
 	// Test non-existent category
 	_, err = m.GetCategoryKeys("nonexistent")
-	if err == nil {
-		t.Error("expected error for non-existent category")
+	var notFound *errs.ErrCategoryNotFound
+	if !errors.As(err, &notFound) || notFound.Category != "nonexistent" {
+		t.Errorf("err = %v, want *errs.ErrCategoryNotFound for nonexistent", err)
 	}
 }
 
@@ -267,7 +280,7 @@ func TestSaveAndLoadFromCache(t *testing.T) {
 		ttl:       DefaultIndexTTL,
 	}
 
-	testData := []byte(`{"system":{"version":"1.0.0","total_entries":1,"total_categories":1},"categories":{},"files":{}}`)
+	testData := []byte(`{"system":{"version":"1.0.0","total_entries":1,"total_categories":1},"categories":{"pasm2_data":["p2kbPasm2Mov"]},"files":{"p2kbPasm2Mov":{"path":"mov.yaml","mtime":1}}}`)
 
 	// Save to cache
 	err := m.saveToCache(testData)
@@ -281,12 +294,39 @@ func TestSaveAndLoadFromCache(t *testing.T) {
 	}
 
 	// Load from cache
-	if !m.loadFromCache() {
+	if _, _, _, ok := m.loadFromCache(m.indexPath); !ok {
 		t.Error("loadFromCache returned false")
 	}
+	if m.index != nil {
+		t.Error("loadFromCache installed the index itself")
+	}
+
+	// Concurrent callers share one load from the cache and see the same index
+	const callers = 50
+	var wg sync.WaitGroup
+	loaded := make([]*Index, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := m.EnsureIndex(); err != nil {
+				t.Errorf("EnsureIndex: %v", err)
+				return
+			}
+			m.mu.RLock()
+			loaded[i] = m.index
+			m.mu.RUnlock()
+		}(i)
+	}
+	wg.Wait()
 
 	if m.index == nil {
-		t.Error("index is nil after loadFromCache")
+		t.Fatal("index is nil after EnsureIndex")
+	}
+	for i, idx := range loaded {
+		if idx != m.index {
+			t.Errorf("caller %d saw a different index", i)
+		}
 	}
 }
 
@@ -316,14 +356,16 @@ func TestGetIndexStatus(t *testing.T) {
 	}
 
 	// Create a cached index
-	testData := []byte(`{"system":{"version":"2.0.0","total_entries":100,"total_categories":10},"categories":{},"files":{}}`)
+	testData := []byte(`{"system":{"version":"2.0.0","total_entries":1,"total_categories":1},"categories":{"pasm2_data":["p2kbPasm2Mov"]},"files":{"p2kbPasm2Mov":{"path":"mov.yaml","mtime":1}}}`)
 	err := m.saveToCache(testData)
 	if err != nil {
 		t.Fatalf("saveToCache failed: %v", err)
 	}
 
 	// Load the index to get version
-	m.loadFromCache()
+	if err := m.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex failed: %v", err)
+	}
 
 	// Test with cached index
 	status = m.GetIndexStatus()
@@ -352,7 +394,7 @@ func TestGetIndexStatusExpired(t *testing.T) {
 	}
 
 	// Create a cached index
-	testData := []byte(`{"system":{"version":"1.0.0","total_entries":1,"total_categories":1},"categories":{},"files":{}}`)
+	testData := []byte(`{"system":{"version":"1.0.0","total_entries":1,"total_categories":1},"categories":{"pasm2_data":["p2kbPasm2Mov"]},"files":{"p2kbPasm2Mov":{"path":"mov.yaml","mtime":1}}}`)
 	err := m.saveToCache(testData)
 	if err != nil {
 		t.Fatalf("saveToCache failed: %v", err)
@@ -459,6 +501,53 @@ func TestScoreMatch(t *testing.T) {
 	}
 }
 
+func TestScoreMatchDomainExpansion(t *testing.T) {
+	direct := scoreMatch([]string{"cordic"}, []string{"p2kb", "arch", "cordic"})
+	expanded := scoreMatch([]string{"cordic"}, []string{"p2kb", "pasm2", "math"})
+	if direct != 1.0 || expanded != expandedTokenWeight {
+		t.Errorf("cordic scores %v against a cordic key and %v against a math key, want 1 and %v", direct, expanded, expandedTokenWeight)
+	}
+
+	// A two-word term expands both of its words
+	if got := scoreMatch([]string{"smart", "pin"}, []string{"p2kb", "pasm2", "wrpin"}); got != 0.75 {
+		t.Errorf("smart pin vs wrpin = %v, want 0.75 (pin direct, smart through the expansion)", got)
+	}
+	if got := scoreMatch([]string{"smart", "pin"}, []string{"p2kb", "arch", "mode"}); got != expandedTokenWeight {
+		t.Errorf("smart pin vs mode = %v, want %v", got, expandedTokenWeight)
+	}
+}
+
+func TestMatchQueryDomainExpansion(t *testing.T) {
+	m := &Manager{
+		index: &Index{
+			Files: map[string]FileEntry{
+				"p2kbArchCordic":   {Path: "arch/cordic.yaml"},
+				"p2kbPasm2Math":    {Path: "pasm2/math.yaml"},
+				"p2kbPasm2Qmul":    {Path: "pasm2/qmul.yaml"},
+				"p2kbPasm2Mov":     {Path: "pasm2/mov.yaml"},
+				"p2kbSpin2Pinread": {Path: "spin2/pinread.yaml"},
+			},
+		},
+		lastRefresh: time.Now(),
+		ttl:         DefaultIndexTTL,
+	}
+
+	matches, err := m.MatchQuery("cordic")
+	if err != nil {
+		t.Fatalf("MatchQuery failed: %v", err)
+	}
+	var keys []string
+	for _, match := range matches {
+		keys = append(keys, match.Key)
+	}
+	if want := []string{"p2kbArchCordic", "p2kbPasm2Math", "p2kbPasm2Qmul"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("MatchQuery(cordic) = %v, want %v", keys, want)
+	}
+	if matches[0].Score <= matches[1].Score {
+		t.Errorf("direct match scored %v, not above expanded match %v", matches[0].Score, matches[1].Score)
+	}
+}
+
 func TestMatchQuery(t *testing.T) {
 	m := &Manager{
 		index: &Index{
@@ -521,6 +610,333 @@ func TestMatchQuery(t *testing.T) {
 	}
 }
 
+func TestMatchQueryCategoryBoost(t *testing.T) {
+	m := &Manager{
+		index: &Index{
+			Files: map[string]FileEntry{
+				"p2kbPasm2Add":     {Path: "pasm2/add.yaml"},
+				"p2kbSpin2Add":     {Path: "spin2/add.yaml"},
+				"p2kbPasm2Addpix":  {Path: "pasm2/addpix.yaml"},
+				"p2kbArchAddress":  {Path: "arch/address.yaml"},
+				"p2kbSpin2Unknown": {Path: "spin2/unknown.yaml"},
+			},
+			Categories: map[string][]string{
+				"pasm2_math":  {"p2kbPasm2Add", "p2kbPasm2Addpix"},
+				"spin2_ops":   {"p2kbSpin2Add"},
+				"arch_memory": {"p2kbArchAddress"},
+			},
+		},
+		lastRefresh: time.Now(),
+		ttl:         DefaultIndexTTL,
+	}
+
+	matches, err := m.MatchQuery("add math")
+	if err != nil {
+		t.Fatalf("MatchQuery error: %v", err)
+	}
+	if len(matches) == 0 {
+		t.Fatal("MatchQuery returned no matches")
+	}
+	if matches[0].Category != "pasm2_math" {
+		t.Errorf("top match category = %q, want pasm2_math", matches[0].Category)
+	}
+
+	scores := make(map[string]float64)
+	for _, match := range matches {
+		scores[match.Key] = match.Score
+	}
+	if scores["p2kbPasm2Add"] <= scores["p2kbSpin2Add"] {
+		t.Errorf("boosted p2kbPasm2Add (%.2f) should outrank p2kbSpin2Add (%.2f)",
+			scores["p2kbPasm2Add"], scores["p2kbSpin2Add"])
+	}
+	if _, ok := scores["p2kbSpin2Unknown"]; ok {
+		t.Error("zero-score keys should not be returned")
+	}
+
+	for i := 1; i < len(matches); i++ {
+		if matches[i].Score > matches[i-1].Score {
+			t.Fatalf("matches not sorted by score: %v", matches)
+		}
+	}
+}
+
+func TestMatchQueryCategoryPrefixBoost(t *testing.T) {
+	// All three keys match only "mov", so their token scores tie; the
+	// categories alone must decide the order.
+	m := &Manager{
+		index: &Index{
+			Files: map[string]FileEntry{
+				"p2kbMovByte": {Path: "spin2/movbyte.yaml"},
+				"p2kbMovLong": {Path: "misc/movlong.yaml"},
+				"p2kbMovWord": {Path: "pasm2/movword.yaml"},
+			},
+			Categories: map[string][]string{
+				"spin2_ops":  {"p2kbMovByte"},
+				"misc_pasm2": {"p2kbMovLong"},
+				"pasm2_data": {"p2kbMovWord"},
+			},
+		},
+		lastRefresh: time.Now(),
+		ttl:         DefaultIndexTTL,
+	}
+
+	matches, err := m.MatchQuery("pasm2 mov")
+	if err != nil {
+		t.Fatalf("MatchQuery error: %v", err)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("got %d matches, want 3: %v", len(matches), matches)
+	}
+
+	want := []string{"p2kbMovWord", "p2kbMovLong", "p2kbMovByte"}
+	for i, key := range want {
+		if matches[i].Key != key {
+			t.Fatalf("match order = %v, want %v", matches, want)
+		}
+	}
+
+	base := matches[2].Score
+	if got := matches[0].Score - base; math.Abs(got-categoryPrefixBoost) > 1e-9 {
+		t.Errorf("prefix boost = %.3f, want %.2f", got, categoryPrefixBoost)
+	}
+	if got := matches[1].Score - base; math.Abs(got-categoryPartBoost) > 1e-9 {
+		t.Errorf("part boost = %.3f, want %.2f", got, categoryPartBoost)
+	}
+	if matches[0].Category != "pasm2_data" {
+		t.Errorf("top match category = %q, want pasm2_data", matches[0].Category)
+	}
+}
+
+func TestGetCategoriesByAlias(t *testing.T) {
+	m := &Manager{
+		index: &Index{
+			Files: map[string]FileEntry{"p2kbPasm2Add": {Path: "pasm2/add.yaml"}},
+			Categories: map[string][]string{
+				"pasm2_math":  {"p2kbPasm2Add"},
+				"spin2_math":  {},
+				"arch_memory": {},
+				"misc":        {},
+			},
+		},
+		lastRefresh: time.Now(),
+		ttl:         DefaultIndexTTL,
+	}
+
+	tests := []struct {
+		alias string
+		want  []string
+	}{
+		{"memory", []string{"arch_memory"}},
+		{"MATH", []string{"pasm2_math", "spin2_math"}},
+		{"misc", nil},
+		{"motors", nil},
+	}
+
+	for _, tt := range tests {
+		got := m.GetCategoriesByAlias(tt.alias)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("GetCategoriesByAlias(%q) = %v, want %v", tt.alias, got, tt.want)
+		}
+	}
+}
+
+func TestGetKeysByMtime(t *testing.T) {
+	m := &Manager{
+		index: &Index{
+			Files: map[string]FileEntry{
+				"p2kbPasm2Add": {Path: "pasm2/add.yaml", Mtime: 100},
+				"p2kbPasm2Mov": {Path: "pasm2/mov.yaml", Mtime: 300},
+				"p2kbSpin2Abs": {Path: "spin2/abs.yaml", Mtime: 300},
+				"p2kbArchCog":  {Path: "arch/cog.yaml", Mtime: 1700000000},
+			},
+		},
+		lastRefresh: time.Now(),
+		ttl:         DefaultIndexTTL,
+	}
+
+	got := m.GetKeysByMtime(3)
+	want := []KeyMtimeResult{
+		{Key: "p2kbArchCog", Mtime: 1700000000, Updated: "2023-11-14T22:13:20Z"},
+		{Key: "p2kbPasm2Mov", Mtime: 300, Updated: "1970-01-01T00:05:00Z"},
+		{Key: "p2kbSpin2Abs", Mtime: 300, Updated: "1970-01-01T00:05:00Z"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetKeysByMtime(3) = %v, want %v", got, want)
+	}
+
+	if all := m.GetKeysByMtime(0); len(all) != 4 || all[3].Key != "p2kbPasm2Add" {
+		t.Errorf("GetKeysByMtime(0) = %v, want all 4 keys ending with the oldest", all)
+	}
+}
+
+func TestSortKeysByMtime(t *testing.T) {
+	m := &Manager{
+		index: &Index{
+			Files: map[string]FileEntry{
+				"p2kbPasm2Add": {Path: "pasm2/add.yaml", Mtime: 100},
+				"p2kbPasm2Mov": {Path: "pasm2/mov.yaml", Mtime: 200},
+				"p2kbArchCog":  {Path: "arch/cog.yaml", Mtime: 400},
+			},
+		},
+		lastRefresh: time.Now(),
+		ttl:         DefaultIndexTTL,
+	}
+
+	got := m.SortKeysByMtime([]string{"p2kbPasm2Add", "p2kbGhost", "p2kbPasm2Mov"}, 0)
+	if len(got) != 2 || got[0].Key != "p2kbPasm2Mov" || got[1].Key != "p2kbPasm2Add" {
+		t.Errorf("SortKeysByMtime = %v, want p2kbPasm2Mov, p2kbPasm2Add (unknown key dropped)", got)
+	}
+	if got := m.SortKeysByMtime([]string{"p2kbPasm2Add", "p2kbPasm2Mov"}, 1); len(got) != 1 || got[0].Key != "p2kbPasm2Mov" {
+		t.Errorf("SortKeysByMtime limit 1 = %v, want only p2kbPasm2Mov", got)
+	}
+}
+
+func TestListKeys(t *testing.T) {
+	m := &Manager{
+		index: &Index{
+			Files: map[string]FileEntry{
+				"p2kbPasm2Add": {Path: "pasm2/add.yaml", Mtime: 100},
+				"p2kbPasm2Mov": {Path: "pasm2/mov.yaml", Mtime: 200},
+				"p2kbSpin2Abs": {Path: "spin2/abs.yaml", Mtime: 300},
+				"p2kbArchCog":  {Path: "arch/cog.yaml", Mtime: 400},
+			},
+			Categories: map[string][]string{
+				"pasm2_math": {"p2kbPasm2Add"},
+				"pasm2_data": {"p2kbPasm2Mov", "p2kbPasm2Add"},
+				"spin2_math": {"p2kbSpin2Abs"},
+			},
+		},
+		lastRefresh: time.Now(),
+		ttl:         DefaultIndexTTL,
+	}
+
+	keys, total, err := m.ListKeys("p2kbPasm2", "")
+	if err != nil {
+		t.Fatalf("ListKeys error: %v", err)
+	}
+	if total != 4 {
+		t.Errorf("total = %d, want 4 (unfiltered)", total)
+	}
+	if len(keys) != 2 || keys[0].Key != "p2kbPasm2Add" || keys[1].Key != "p2kbPasm2Mov" {
+		t.Fatalf("keys = %v, want p2kbPasm2Add, p2kbPasm2Mov", keys)
+	}
+	if keys[0].Path != "pasm2/add.yaml" || keys[0].Mtime != 100 ||
+		strings.Join(keys[0].Categories, ",") != "pasm2_data,pasm2_math" {
+		t.Errorf("keys[0] = %+v, want joined file entry and categories", keys[0])
+	}
+
+	keys, _, _ = m.ListKeys("", "spin2_math")
+	if len(keys) != 1 || keys[0].Key != "p2kbSpin2Abs" {
+		t.Errorf("category filter = %v, want only p2kbSpin2Abs", keys)
+	}
+
+	keys, _, _ = m.ListKeys("p2kbArch", "")
+	if len(keys) != 1 || keys[0].Categories == nil {
+		t.Errorf("uncategorized key = %+v, want non-nil empty categories", keys)
+	}
+
+	if keys, _, _ = m.ListKeys("", "math"); len(keys) != 0 {
+		t.Errorf("category must match exactly, got %v", keys)
+	}
+}
+
+func TestMatchQueryCapsResults(t *testing.T) {
+	files := make(map[string]FileEntry)
+	for i := 0; i < 50; i++ {
+		files[fmt.Sprintf("p2kbPasm2Mov%02d", i)] = FileEntry{Path: "pasm2/mov.yaml"}
+	}
+	m := &Manager{
+		index:       &Index{Files: files},
+		lastRefresh: time.Now(),
+		ttl:         DefaultIndexTTL,
+	}
+
+	matches, err := m.MatchQuery("mov")
+	if err != nil {
+		t.Fatalf("MatchQuery error: %v", err)
+	}
+	if len(matches) != maxQueryMatches {
+		t.Errorf("MatchQuery returned %d matches, want %d", len(matches), maxQueryMatches)
+	}
+}
+
+func TestSearchRanked(t *testing.T) {
+	m := &Manager{
+		index: &Index{
+			Files: map[string]FileEntry{
+				"p2kbPasm2Movbyts":  {Path: "pasm2/movbyts.yaml"},
+				"p2kbPasm2Mov":      {Path: "pasm2/mov.yaml"},
+				"p2kbPasm2Add":      {Path: "pasm2/add.yaml"},
+				"p2kbSpin2Pinwrite": {Path: "spin2/pinwrite.yaml"},
+				"p2kbArchCogMemory": {Path: "arch/cog-memory.yaml"},
+				"p2kbGuideMovGuide": {Path: "guide/mov-guide.yaml"},
+				"p2kbSpin2Pinread":  {Path: "spin2/pinread.yaml"},
+				"p2kbSpin2Pinfloat": {Path: "spin2/pinfloat.yaml"},
+				"p2kbSpin2Pinlow":   {Path: "spin2/pinlow.yaml"},
+				"p2kbSpin2Pinhigh":  {Path: "spin2/pinhigh.yaml"},
+				"p2kbArchHubMemory": {Path: "arch/hub-memory.yaml"},
+				"p2kbArchLutMemory": {Path: "arch/lut-memory.yaml"},
+				"p2kbPasm2Rdlong":   {Path: "pasm2/rdlong.yaml"},
+				"p2kbPasm2Wrlong":   {Path: "pasm2/wrlong.yaml"},
+				"p2kbPasm2Sub":      {Path: "pasm2/sub.yaml"},
+				"p2kbPasm2Cmp":      {Path: "pasm2/cmp.yaml"},
+			},
+			Aliases: map[string][]string{
+				"MOVE": {"p2kbPasm2Mov"},
+			},
+		},
+		lastRefresh: time.Now(),
+		ttl:         DefaultIndexTTL,
+	}
+
+	results := m.SearchRanked("mov", 0)
+	var keys []string
+	for _, r := range results {
+		keys = append(keys, r.Key)
+	}
+	// Exact token matches outrank the partial "movbyts"; the shorter key wins
+	// between the exact matches because "mov" is a larger fraction of it.
+	want := []string{"p2kbPasm2Mov", "p2kbGuideMovGuide", "p2kbPasm2Movbyts"}
+	if len(keys) != len(want) {
+		t.Fatalf("SearchRanked(mov) = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("SearchRanked(mov)[%d] = %q, want %q", i, keys[i], want[i])
+		}
+	}
+	if results[0].Score <= results[2].Score {
+		t.Errorf("exact match score %.3f should exceed partial %.3f", results[0].Score, results[2].Score)
+	}
+
+	if got := m.SearchRanked("mov", 1); len(got) != 1 || got[0].Key != "p2kbPasm2Mov" {
+		t.Errorf("SearchRanked(mov, 1) = %v, want only p2kbPasm2Mov", got)
+	}
+	if got := m.SearchRanked("zzz", 0); len(got) != 0 {
+		t.Errorf("SearchRanked(zzz) = %v, want none", got)
+	}
+}
+
+func TestBuildIDF(t *testing.T) {
+	idx := &Index{Files: map[string]FileEntry{
+		"p2kbPasm2Mov": {},
+		"p2kbPasm2Add": {},
+		"p2kbSpin2Add": {},
+		"p2kbArchCog":  {},
+	}}
+	idf := buildIDF(idx)
+
+	if _, ok := idf["p2kb"]; ok {
+		t.Error("the p2kb prefix should be excluded from the IDF table")
+	}
+	if idf["mov"] <= idf["add"] {
+		t.Errorf("rare token idf %.3f should exceed common token idf %.3f", idf["mov"], idf["add"])
+	}
+	if want := math.Log(4.0 / 2.0); math.Abs(idf["add"]-want) > 1e-9 {
+		t.Errorf("idf[add] = %.4f, want %.4f", idf["add"], want)
+	}
+}
+
 func TestGetAllKeys(t *testing.T) {
 	m := &Manager{
 		index: &Index{
@@ -856,6 +1272,13 @@ func TestGetKeyPathWithAlias(t *testing.T) {
 	if path != "pasm2/add.yaml" {
 		t.Errorf("path = %q, want pasm2/add.yaml", path)
 	}
+
+	// Neither a key nor an alias
+	_, _, _, err = m.GetKeyPath("p2kbPasm2Nope")
+	var notFound *errs.ErrKeyNotFound
+	if !errors.As(err, &notFound) || notFound.Key != "p2kbPasm2Nope" {
+		t.Errorf("err = %v, want *errs.ErrKeyNotFound for p2kbPasm2Nope", err)
+	}
 }
 
 func TestGetFileMtimeWithAlias(t *testing.T) {
@@ -1289,13 +1712,13 @@ func TestResolveKeyDoesNotTriggerRefreshInCooldown(t *testing.T) {
 
 // minimalGzipIndex returns a gzip-compressed minimal valid Index JSON blob
 // suitable for serving from an httptest server so that fetchIndexData's
-// gzip.NewReader + json.Unmarshal succeed.
+// gzip.NewReader + json.Unmarshal and validateIndex succeed.
 func minimalGzipIndex(t *testing.T) []byte {
 	t.Helper()
 	idx := Index{
-		System:     SystemInfo{Version: "test-1.0"},
-		Categories: map[string][]string{},
-		Files:      map[string]FileEntry{},
+		System:     SystemInfo{Version: "test-1.0", TotalEntries: 1},
+		Categories: map[string][]string{"pasm2_data": {"p2kbPasm2Mov"}},
+		Files:      map[string]FileEntry{"p2kbPasm2Mov": {Path: "mov.yaml", Mtime: 1}},
 		Aliases:    map[string][]string{},
 	}
 	raw, err := json.Marshal(idx)
@@ -1501,3 +1924,768 @@ func TestRefreshIsBusted(t *testing.T) {
 		t.Errorf("manual Refresh must send no-cache Cache-Control; got %q", cc)
 	}
 }
+
+// Streaming index decode tests
+
+func TestDecodeIndexAnySectionOrder(t *testing.T) {
+	// Sections deliberately out of the generator's order, with an unknown
+	// section and a null aliases map mixed in.
+	doc := `{
+		"files": {"p2kbPasm2Mov": {"path": "pasm2/mov.yaml", "mtime": 1700000000, "sha256": "abc"}},
+		"extra": {"ignored": [1, 2, {"nested": true}]},
+		"aliases": null,
+		"categories": {"pasm2_data": ["p2kbPasm2Mov"]},
+		"system": {"version": "3.5.0", "total_entries": 1}
+	}`
+
+	idx, err := decodeIndex(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("decodeIndex failed: %v", err)
+	}
+	if idx.System.Version != "3.5.0" || idx.System.TotalEntries != 1 {
+		t.Errorf("System = %+v, want version 3.5.0 with 1 entry", idx.System)
+	}
+	entry, ok := idx.Files["p2kbPasm2Mov"]
+	if !ok || entry.Path != "pasm2/mov.yaml" || entry.Mtime != 1700000000 || entry.SHA256 != "abc" {
+		t.Errorf("Files[p2kbPasm2Mov] = %+v, want full entry", entry)
+	}
+	if keys := idx.Categories["pasm2_data"]; len(keys) != 1 || keys[0] != "p2kbPasm2Mov" {
+		t.Errorf("Categories[pasm2_data] = %v", keys)
+	}
+	if len(idx.Aliases) != 0 {
+		t.Errorf("Aliases = %v, want empty", idx.Aliases)
+	}
+}
+
+func TestDecodeIndexMatchesUnmarshal(t *testing.T) {
+	data := testdata.MustGetFixture("p2kb-index.json")
+
+	var want Index
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	got, err := decodeIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decodeIndex failed: %v", err)
+	}
+
+	if got.System != want.System {
+		t.Errorf("System = %+v, want %+v", got.System, want.System)
+	}
+	if len(got.Files) != len(want.Files) || len(got.Categories) != len(want.Categories) {
+		t.Errorf("decoded %d files / %d categories, want %d / %d",
+			len(got.Files), len(got.Categories), len(want.Files), len(want.Categories))
+	}
+	for key, entry := range want.Files {
+		if got.Files[key] != entry {
+			t.Errorf("Files[%s] = %+v, want %+v", key, got.Files[key], entry)
+		}
+	}
+}
+
+func TestDecodeIndexRejectsMalformed(t *testing.T) {
+	for _, doc := range []string{
+		``,
+		`[]`,
+		`{"files": []}`,
+		`{"files": {"k": {"path": 1}}}`,
+		`{"system": {}`,
+	} {
+		if _, err := decodeIndex(strings.NewReader(doc)); err == nil {
+			t.Errorf("decodeIndex(%q) should fail", doc)
+		}
+	}
+}
+
+// writeBenchIndex writes a synthetic index of roughly 500KB, the size of the
+// production index, and returns its path.
+func writeBenchIndex(b *testing.B) string {
+	b.Helper()
+	idx := Index{
+		System:     SystemInfo{Version: "bench", TotalEntries: 4000, TotalCategories: 40, TotalAliases: 4000},
+		Categories: make(map[string][]string),
+		Files:      make(map[string]FileEntry),
+		Aliases:    make(map[string][]string),
+	}
+	for i := 0; i < 4000; i++ {
+		key := fmt.Sprintf("p2kbBenchEntry%04d", i)
+		idx.Files[key] = FileEntry{
+			Path:   fmt.Sprintf("deliverables/ai/P2/bench/entry%04d.yaml", i),
+			Mtime:  1700000000 + int64(i),
+			SHA256: fmt.Sprintf("%064x", i),
+		}
+		cat := fmt.Sprintf("bench_cat%02d", i%40)
+		idx.Categories[cat] = append(idx.Categories[cat], key)
+		idx.Aliases[fmt.Sprintf("ALIAS%04d", i)] = []string{key}
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		b.Fatalf("marshal: %v", err)
+	}
+	path := filepath.Join(b.TempDir(), "p2kb-index.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		b.Fatalf("write: %v", err)
+	}
+	return path
+}
+
+// decodeIndexReadAll is the previous decoder: the whole file read into memory,
+// then json.Unmarshal. The benchmarks compare decodeIndex against it.
+func decodeIndexReadAll(r io.Reader) (*Index, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, err
+	}
+	return &idx, nil
+}
+
+// benchDecoders are the decoders the loadFromCache benchmarks compare, each
+// run through the same loadFromCacheWith: stat, validation and extra index
+// merging included.
+var benchDecoders = []struct {
+	name   string
+	decode func(io.Reader) (*Index, error)
+}{
+	{"stream", decodeIndex},
+	{"readall", decodeIndexReadAll},
+}
+
+// BenchmarkLoadFromCache measures the time and allocation of loading the
+// cached index with each decoder.
+func BenchmarkLoadFromCache(b *testing.B) {
+	m := &Manager{indexPath: writeBenchIndex(b), ttl: time.Hour}
+	for _, d := range benchDecoders {
+		b.Run(d.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, _, ok := m.loadFromCacheWith(m.indexPath, d.decode); !ok {
+					b.Fatal("loadFromCache failed")
+				}
+			}
+		})
+	}
+}
+
+// heapSampler passes reads through to r, recording the highest HeapInuse seen
+// before each one.
+type heapSampler struct {
+	r    io.Reader
+	high uint64
+}
+
+func (h *heapSampler) sample() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	h.high = max(h.high, ms.HeapInuse)
+}
+
+func (h *heapSampler) Read(p []byte) (int, error) {
+	h.sample()
+	return h.r.Read(p)
+}
+
+// BenchmarkLoadFromCachePeakHeap reports, for each decoder, the peak heap of
+// a load as peak-heap-B: the highest HeapInuse seen at each read of the file
+// and once the decoder returns, above the level a GC leaves before the load.
+// ReadMemStats stops the world, so compare ns/op from BenchmarkLoadFromCache.
+func BenchmarkLoadFromCachePeakHeap(b *testing.B) {
+	m := &Manager{indexPath: writeBenchIndex(b), ttl: time.Hour}
+	for _, d := range benchDecoders {
+		b.Run(d.name, func(b *testing.B) {
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				var before runtime.MemStats
+				runtime.ReadMemStats(&before)
+				sampled := func(r io.Reader) (*Index, error) {
+					sampler := &heapSampler{r: r, high: before.HeapInuse}
+					idx, err := d.decode(sampler)
+					sampler.sample()
+					peak = max(peak, sampler.high-before.HeapInuse)
+					return idx, err
+				}
+				if _, _, _, ok := m.loadFromCacheWith(m.indexPath, sampled); !ok {
+					b.Fatal("loadFromCache failed")
+				}
+			}
+			b.ReportMetric(float64(peak), "peak-heap-B")
+		})
+	}
+}
+
+// BenchmarkMatchQuery scores a query against an index the size of the
+// production knowledge base (~970 keys); it should stay well under 5ms/op.
+func BenchmarkMatchQuery(b *testing.B) {
+	idx := &Index{
+		Files:      make(map[string]FileEntry),
+		Categories: make(map[string][]string),
+	}
+	prefixes := []string{"Pasm2", "Spin2", "Arch", "Hw", "Guide"}
+	for i := 0; i < 970; i++ {
+		key := fmt.Sprintf("p2kb%sEntry%03d", prefixes[i%len(prefixes)], i)
+		idx.Files[key] = FileEntry{Path: fmt.Sprintf("bench/entry%03d.yaml", i)}
+		cat := fmt.Sprintf("%s_group%d", strings.ToLower(prefixes[i%len(prefixes)]), i%12)
+		idx.Categories[cat] = append(idx.Categories[cat], key)
+	}
+	m := &Manager{index: idx, lastRefresh: time.Now(), ttl: DefaultIndexTTL}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.MatchQuery("pasm2 entry group3"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// gzipFixture returns the named fixture gzip-compressed, as served remotely.
+func gzipFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(testdata.MustGetFixture(name)); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// stubMergedIndexes serves the public fixture index at IndexURL and the
+// extra fixture index at the returned URL.
+func stubMergedIndexes(t *testing.T) string {
+	t.Helper()
+	public := gzipFixture(t, "p2kb-index.json")
+	extra := gzipFixture(t, "p2kb-index-extra.json")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/public/deliverables/ai/p2kb-index.json.gz":
+			_, _ = w.Write(public)
+		case "/private/kb/index.json.gz":
+			_, _ = w.Write(extra)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	prev := IndexURL
+	IndexURL = srv.URL + "/public/deliverables/ai/p2kb-index.json.gz"
+	t.Cleanup(func() {
+		IndexURL = prev
+		srv.Close()
+	})
+	return srv.URL + "/private/kb/index.json.gz"
+}
+
+func TestMergeExtraIndexes(t *testing.T) {
+	extraURL := stubMergedIndexes(t)
+	dir := t.TempDir()
+	m := &Manager{
+		indexPath: filepath.Join(dir, "index", "p2kb-index.json"),
+		ttl:       time.Hour,
+		extraURLs: []string{extraURL},
+	}
+
+	if err := m.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex failed: %v", err)
+	}
+
+	// Overlapping key: the public (first-listed) index wins.
+	path, mtime, _, err := m.GetKeyPath("p2kbPasm2Mov")
+	if err != nil {
+		t.Fatalf("GetKeyPath(p2kbPasm2Mov) error: %v", err)
+	}
+	if path != "deliverables/ai/P2/pasm2/instructions/mov.yaml" || mtime != 1700000000 {
+		t.Errorf("colliding key took the extra entry: path=%q mtime=%d", path, mtime)
+	}
+	if got := m.GetKeySource("p2kbPasm2Mov"); got != IndexURL {
+		t.Errorf("GetKeySource(p2kbPasm2Mov) = %q, want public %q", got, IndexURL)
+	}
+
+	// Disjoint keys come from the extra index, aliases included.
+	if got := m.GetKeySource("p2kbProjMulDiv"); got != extraURL {
+		t.Errorf("GetKeySource(p2kbProjMulDiv) = %q, want %q", got, extraURL)
+	}
+	if got := m.GetKeySource("MOTOR"); got != extraURL {
+		t.Errorf("GetKeySource(MOTOR) = %q, want %q", got, extraURL)
+	}
+	if got := m.GetKeySource("p2kbNoSuchKey"); got != "" {
+		t.Errorf("GetKeySource(unknown) = %q, want empty", got)
+	}
+
+	// Categories are unioned without duplicates.
+	math, err := m.GetCategoryKeys("pasm2_math")
+	if err != nil {
+		t.Fatalf("GetCategory error: %v", err)
+	}
+	if len(math) != 3 {
+		t.Errorf("pasm2_math = %v, want Mov, Add and ProjMulDiv", math)
+	}
+	if _, err := m.GetCategoryKeys("project_drivers"); err != nil {
+		t.Errorf("extra-only category missing: %v", err)
+	}
+
+	// Stats keep the public version but count the merged view.
+	stats := m.GetStats()
+	if stats.Version != "test-1.0.0" {
+		t.Errorf("Version = %q, want public test-1.0.0", stats.Version)
+	}
+	if stats.TotalEntries != 7 {
+		t.Errorf("TotalEntries = %d, want 7", stats.TotalEntries)
+	}
+
+	// A fresh manager rebuilds the same merged view from the disk cache.
+	reloaded := &Manager{indexPath: m.indexPath, ttl: time.Hour, extraURLs: []string{extraURL}}
+	if err := reloaded.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex failed for merged index: %v", err)
+	}
+	if got := reloaded.keySources["p2kbProjMotorDriver"]; got != extraURL {
+		t.Errorf("reloaded source = %q, want %q", got, extraURL)
+	}
+}
+
+func TestMergeExtraIndexesSkipsUnreachable(t *testing.T) {
+	extraURL := stubMergedIndexes(t)
+	m := &Manager{
+		indexPath: filepath.Join(t.TempDir(), "index", "p2kb-index.json"),
+		ttl:       time.Hour,
+		extraURLs: []string{extraURL + ".missing", extraURL},
+	}
+
+	if err := m.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex should succeed despite a dead extra index: %v", err)
+	}
+	if got := m.GetKeySource("p2kbProjMulDiv"); got != extraURL {
+		t.Errorf("GetKeySource(p2kbProjMulDiv) = %q, want %q", got, extraURL)
+	}
+}
+
+func TestContentBaseURL(t *testing.T) {
+	tests := []struct {
+		indexURL string
+		want     string
+	}{
+		{"https://raw.githubusercontent.com/me/kb/main/deliverables/ai/p2kb-index.json.gz", "https://raw.githubusercontent.com/me/kb/main/"},
+		{"https://example.com/private/index.json.gz", "https://example.com/private/"},
+		{"https://example.com/private/index.json.gz?token=abc", "https://example.com/private/"},
+	}
+	for _, tt := range tests {
+		if got := ContentBaseURL(tt.indexURL); got != tt.want {
+			t.Errorf("ContentBaseURL(%q) = %q, want %q", tt.indexURL, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkSearchRanked ranks a common term against ~970 keys; it should stay
+// well under 1ms/op.
+func BenchmarkSearchRanked(b *testing.B) {
+	idx := &Index{Files: make(map[string]FileEntry)}
+	prefixes := []string{"Pasm2", "Spin2", "Arch", "Hw", "Guide"}
+	for i := 0; i < 970; i++ {
+		key := fmt.Sprintf("p2kb%sEntry%03d", prefixes[i%len(prefixes)], i)
+		idx.Files[key] = FileEntry{Path: fmt.Sprintf("bench/entry%03d.yaml", i)}
+	}
+	m := &Manager{index: idx, lastRefresh: time.Now(), ttl: DefaultIndexTTL}
+	m.idf = buildIDF(idx)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.SearchRanked("pasm2", 50)
+	}
+}
+
+func TestBackgroundRefreshTimer(t *testing.T) {
+	_, hits := stubIndexServer(t)
+	dir := t.TempDir()
+	m := &Manager{
+		indexPath:    filepath.Join(dir, "index", "p2kb-index.json"),
+		ttl:          50 * time.Millisecond,
+		timedRefresh: true,
+	}
+	defer m.Close()
+
+	if err := m.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex failed: %v", err)
+	}
+	if hits() != 1 {
+		t.Fatalf("hits after first EnsureIndex = %d, want 1", hits())
+	}
+
+	// The timer refreshes without any caller asking
+	deadline := time.Now().Add(2 * time.Second)
+	for hits() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hits() < 3 {
+		t.Fatalf("hits = %d, want at least 3 from background refreshes", hits())
+	}
+
+	// A stale-looking index is still served without blocking on a fetch
+	m.mu.Lock()
+	m.lastRefresh = time.Now().Add(-time.Hour)
+	m.mu.Unlock()
+	before := hits()
+	if err := m.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex failed: %v", err)
+	}
+	if hits() > before+1 {
+		t.Errorf("EnsureIndex fetched synchronously: hits %d -> %d", before, hits())
+	}
+}
+
+func TestBackgroundRefreshClose(t *testing.T) {
+	_, hits := stubIndexServer(t)
+	dir := t.TempDir()
+	m := &Manager{
+		indexPath:    filepath.Join(dir, "index", "p2kb-index.json"),
+		ttl:          30 * time.Millisecond,
+		timedRefresh: true,
+	}
+
+	if err := m.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex failed: %v", err)
+	}
+	m.Close()
+	// Let any refresh already in flight finish before sampling
+	m.fetchMu.Lock()
+	m.fetchMu.Unlock()
+	after := hits()
+
+	time.Sleep(150 * time.Millisecond)
+	if hits() != after {
+		t.Errorf("hits grew from %d to %d after Close", after, hits())
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.refreshTimer != nil {
+		t.Error("refresh timer still armed after Close")
+	}
+}
+
+func TestCheckOnUseRefreshWithoutTimer(t *testing.T) {
+	_, hits := stubIndexServer(t)
+	dir := t.TempDir()
+	m := &Manager{
+		indexPath: filepath.Join(dir, "index", "p2kb-index.json"),
+		ttl:       time.Hour,
+	}
+	defer m.Close()
+
+	if err := m.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex failed: %v", err)
+	}
+	if m.refreshTimer != nil {
+		t.Error("timer armed with background refresh disabled")
+	}
+
+	// With the timer model off, an expired index is re-fetched on use
+	m.mu.Lock()
+	m.lastRefresh = time.Now().Add(-2 * time.Hour)
+	m.mu.Unlock()
+	if err := os.Chtimes(m.indexPath, time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex failed: %v", err)
+	}
+	if hits() != 2 {
+		t.Errorf("hits = %d, want 2 (re-fetch on use after expiry)", hits())
+	}
+}
+
+func TestNewManagerBackgroundRefreshEnv(t *testing.T) {
+	t.Setenv("P2KB_BACKGROUND_REFRESH", "")
+	if m := NewManager(); !m.timedRefresh {
+		t.Error("background refresh should default to on")
+	}
+	t.Setenv("P2KB_BACKGROUND_REFRESH", "false")
+	if m := NewManager(); m.timedRefresh {
+		t.Error("P2KB_BACKGROUND_REFRESH=false should disable the timer")
+	}
+}
+
+// stubCommitsServer serves the commits listing with the given SHAs in turn,
+// repeating the last, to a client whose token pool holds "secret", and
+// shortens the watch interval for the test.
+func stubCommitsServer(t *testing.T, shas ...string) (polls func() int, auth func() string) {
+	t.Helper()
+	var n atomic.Int32
+	var lastAuth atomic.Value
+	lastAuth.Store("")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastAuth.Store(r.Header.Get("Authorization"))
+		i := int(n.Add(1)) - 1
+		fmt.Fprintf(w, `[{"sha": %q}]`, shas[min(i, len(shas)-1)])
+	}))
+	prevClient, prevInterval := newGitHubClient, minWatchInterval
+	newGitHubClient = func() *http.Client {
+		return fetch.NewClient(fetch.WithTokenPool([]string{"secret"}), fetch.WithTransport(toServer{srv})).HTTPClient()
+	}
+	minWatchInterval = time.Millisecond
+	t.Cleanup(func() {
+		newGitHubClient, minWatchInterval = prevClient, prevInterval
+		srv.Close()
+	})
+	return func() int { return int(n.Load()) }, func() string { return lastAuth.Load().(string) }
+}
+
+// toServer sends every request to srv, so a request to a GitHub URL reaches
+// the stub after the token pool has authenticated it.
+type toServer struct{ srv *httptest.Server }
+
+func (t toServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	local := req.Clone(req.Context())
+	local.URL.Scheme = "http"
+	local.URL.Host = t.srv.Listener.Addr().String()
+	return http.DefaultTransport.RoundTrip(local)
+}
+
+// watchUntil runs WatchForChanges until the stub has been polled at least
+// polls times, returning the versions passed to onChange.
+func watchUntil(t *testing.T, m *Manager, polls func() int, want int) []string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	var changes []string
+	done := make(chan error, 1)
+	go func() {
+		done <- m.WatchForChanges(ctx, 0, func(v string) { changes = append(changes, v) })
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for polls() < want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("WatchForChanges returned %v, want context.Canceled", err)
+	}
+	return changes
+}
+
+func TestWatchForChanges(t *testing.T) {
+	polls, auth := stubCommitsServer(t, "aaa", "bbb")
+	m := &Manager{metaPath: filepath.Join(t.TempDir(), "index", "p2kb-index.meta")}
+
+	changes := watchUntil(t, m, polls, 5)
+	if !reflect.DeepEqual(changes, []string{"bbb"}) {
+		t.Errorf("onChange calls = %v, want [bbb]", changes)
+	}
+	if got := m.loadCommitSHA(); got != "bbb" {
+		t.Errorf("recorded SHA = %q, want bbb", got)
+	}
+	if got := auth(); got != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", got)
+	}
+}
+
+func TestWatchForChangesReportsChangeSinceLastRun(t *testing.T) {
+	polls, _ := stubCommitsServer(t, "bbb")
+	m := &Manager{metaPath: filepath.Join(t.TempDir(), "p2kb-index.meta")}
+	if err := m.saveCommitSHA("aaa"); err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := watchUntil(t, m, polls, 3); !reflect.DeepEqual(changes, []string{"bbb"}) {
+		t.Errorf("onChange calls = %v, want [bbb]", changes)
+	}
+}
+
+func TestValidateIndex(t *testing.T) {
+	parse := func(name string) *Index {
+		t.Helper()
+		idx, err := decodeIndex(bytes.NewReader(testdata.MustGetFixture(name)))
+		if err != nil {
+			t.Fatalf("decode %s: %v", name, err)
+		}
+		return idx
+	}
+	if err := validateIndex(parse("p2kb-index.json")); err != nil {
+		t.Errorf("complete index rejected: %v", err)
+	}
+	err := validateIndex(parse("p2kb-index-truncated.json"))
+	if err == nil || !strings.Contains(err.Error(), "5 files but declares 12 entries") {
+		t.Errorf("truncated index: err = %v, want the file count mismatch", err)
+	}
+
+	files := func(n int) map[string]FileEntry {
+		m := make(map[string]FileEntry, n)
+		for i := 0; i < n; i++ {
+			m[fmt.Sprintf("p2kbKey%d", i)] = FileEntry{Path: "k.yaml"}
+		}
+		return m
+	}
+	categories := map[string][]string{"pasm2_math": {"p2kbKey0"}}
+	tests := []struct {
+		name string
+		idx  Index
+		ok   bool
+	}{
+		{"exactly 80%", Index{System: SystemInfo{TotalEntries: 10}, Files: files(8), Categories: categories}, true},
+		{"below 80%", Index{System: SystemInfo{TotalEntries: 10}, Files: files(7), Categories: categories}, false},
+		{"no total", Index{Files: files(3), Categories: categories}, false},
+		{"no files", Index{System: SystemInfo{TotalEntries: 3}, Categories: categories}, false},
+		{"no categories", Index{System: SystemInfo{TotalEntries: 3}, Files: files(3)}, false},
+	}
+	for _, tt := range tests {
+		if err := validateIndex(&tt.idx); (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestCorruptIndexRejected(t *testing.T) {
+	truncated := gzipFixture(t, "p2kb-index-truncated.json")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(truncated)
+	}))
+	prev := IndexURL
+	IndexURL = srv.URL + "/index.json.gz"
+	t.Cleanup(func() {
+		IndexURL = prev
+		srv.Close()
+	})
+
+	// The warnings go through slog, so JSON logging stays one record per line
+	var logs bytes.Buffer
+	prevLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prevLogger) })
+
+	m := &Manager{indexPath: filepath.Join(t.TempDir(), "index", "p2kb-index.json"), ttl: time.Hour}
+	if err := m.EnsureIndex(); err == nil || !strings.Contains(err.Error(), "corrupt index") {
+		t.Fatalf("EnsureIndex err = %v, want a corrupt index error", err)
+	}
+	if m.index != nil {
+		t.Error("corrupt download was installed")
+	}
+	if _, err := os.Stat(m.indexPath); !os.IsNotExist(err) {
+		t.Errorf("corrupt download was cached: %v", err)
+	}
+
+	// A corrupt cache file is deleted rather than served
+	if err := m.saveToCache(testdata.MustGetFixture("p2kb-index-truncated.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, ok := m.loadFromCache(m.indexPath); ok {
+		t.Error("loadFromCache accepted a corrupt cache file")
+	}
+	if _, err := os.Stat(m.indexPath); !os.IsNotExist(err) {
+		t.Errorf("corrupt cache file kept: %v", err)
+	}
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var record struct {
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		if record.Level == "WARN" {
+			messages = append(messages, record.Msg)
+		}
+	}
+	if want := []string{"rejected index download", "discarding cached index"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("warnings = %q, want %q", messages, want)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"mov", "mov", 0},
+		{"pinwrte", "pinwrite", 1}, // deletion
+		{"pinwritee", "pinwrite", 1},
+		{"mvo", "mov", 2}, // transposition
+		{"add", "adc", 1},
+		{"", "cog", 3},
+		{"cog", "", 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	key := []string{"p2kb", "spin2", "pinwrite"}
+	if score := fuzzyScore([]string{"pinwrte"}, key); score <= 0 || score >= fuzzyWeight {
+		t.Errorf("fuzzyScore(pinwrte) = %f, want between 0 and %f", score, fuzzyWeight)
+	}
+	// The whole key run together is a candidate too
+	if score := fuzzyScore([]string{"p2kbspin2pinwirte"}, key); score <= 0 {
+		t.Errorf("fuzzyScore(p2kbspin2pinwirte) = %f, want > 0", score)
+	}
+	// Too many edits, or too short to compare
+	for _, qt := range []string{"pinread", "xyz", "pw"} {
+		if score := fuzzyScore([]string{qt}, key); score != 0 {
+			t.Errorf("fuzzyScore(%s) = %f, want 0", qt, score)
+		}
+	}
+}
+
+func TestMatchQueryFuzzy(t *testing.T) {
+	m := &Manager{
+		index: &Index{
+			Files: map[string]FileEntry{
+				"p2kbPasm2Mov":      {Path: "pasm2/mov.yaml"},
+				"p2kbPasm2Add":      {Path: "pasm2/add.yaml"},
+				"p2kbPasm2Movbyts":  {Path: "pasm2/movbyts.yaml"},
+				"p2kbSpin2Pinwrite": {Path: "spin2/pinwrite.yaml"},
+			},
+			Categories: map[string][]string{
+				"pasm2_data": {"p2kbPasm2Mov", "p2kbPasm2Movbyts"},
+				"pasm2_math": {"p2kbPasm2Add"},
+				"spin2_pin":  {"p2kbSpin2Pinwrite"},
+			},
+		},
+		lastRefresh: time.Now(),
+		ttl:         DefaultIndexTTL,
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"pinwrte", "p2kbSpin2Pinwrite"},       // single-character deletion
+		{"spin2 pnwrite", "p2kbSpin2Pinwrite"}, // token pass finds spin2 first
+		{"p2kbPasm2Mvo", "p2kbPasm2Mov"},       // transposition in a key
+	}
+	for _, tt := range tests {
+		matches, err := m.MatchQuery(tt.query)
+		if err != nil {
+			t.Fatalf("MatchQuery(%q) error: %v", tt.query, err)
+		}
+		if len(matches) == 0 || matches[0].Key != tt.want {
+			t.Errorf("MatchQuery(%q) = %v, want %s first", tt.query, matches, tt.want)
+		}
+	}
+
+	// Fuzzy results are marked and only appear when the token pass is empty
+	matches, _ := m.MatchQuery("pinwrte")
+	if !matches[0].Fuzzy || matches[0].Category != "spin2_pin" {
+		t.Errorf("MatchQuery(pinwrte)[0] = %+v, want fuzzy in spin2_pin", matches[0])
+	}
+	matches, _ = m.MatchQuery("spin2 pnwrite")
+	if matches[0].Fuzzy {
+		t.Errorf("MatchQuery(spin2 pnwrite)[0] = %+v, want a token match", matches[0])
+	}
+
+	// Nothing within two edits either
+	for _, query := range []string{"qqqqzzzz", "xyz nonexistent"} {
+		if matches, err := m.MatchQuery(query); err != nil || len(matches) != 0 {
+			t.Errorf("MatchQuery(%q) = %v, %v; want no matches", query, matches, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package logging configures the server's log output: plain text lines, or
+// JSON records with P2KB_LOG_FORMAT=json, at the P2KB_LOG_LEVEL threshold.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Log formats accepted by Setup.
+const (
+	FormatText = "text" // log package lines: date, time, source file, message
+	FormatJSON = "json" // One JSON object per record: time, level, msg and attributes
+)
+
+var (
+	// level is the threshold of the JSON handler Setup installs, so SetLevel
+	// takes effect without rebuilding the logger.
+	level slog.LevelVar
+
+	// textLogger is slog's original default, which writes through the log
+	// package; Setup restores it for FormatText.
+	textLogger = slog.Default()
+
+	// jsonFormat reports whether Setup last installed the JSON handler.
+	jsonFormat atomic.Bool
+)
+
+// ParseLevel maps a P2KB_LOG_LEVEL value (debug, info, warn or error, in any
+// case) to its slog level. An empty value is info.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("log level must be debug, info, warn or error, not %q", name)
+}
+
+// Setup makes the logger for levelName and format the default for slog, and
+// so for every package logging through it, writing to stderr (stdout carries
+// the MCP protocol). An unknown level is info and an unknown format text. With
+// FormatJSON, lines written with the log package become INFO records too.
+func Setup(levelName, format string) *slog.Logger {
+	return setup(os.Stderr, levelName, format)
+}
+
+// setup is Setup writing to w.
+func setup(w io.Writer, levelName, format string) *slog.Logger {
+	lvl, _ := ParseLevel(levelName)
+	level.Set(lvl)
+
+	log.SetOutput(w)
+	if strings.EqualFold(strings.TrimSpace(format), FormatJSON) {
+		jsonFormat.Store(true)
+		logger := slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: &level}))
+		slog.SetDefault(logger)
+		return logger
+	}
+
+	jsonFormat.Store(false)
+	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+	slog.SetDefault(textLogger)
+	slog.SetLogLoggerLevel(lvl)
+	return textLogger
+}
+
+// SetLevel changes the threshold of the logger Setup installed, as
+// p2kb_settings does for P2KB_LOG_LEVEL. An unknown level is an error and
+// leaves the threshold as it was.
+func SetLevel(levelName string) error {
+	lvl, err := ParseLevel(levelName)
+	if err != nil {
+		return err
+	}
+	level.Set(lvl)
+	if !jsonFormat.Load() {
+		slog.SetLogLoggerLevel(lvl)
+	}
+	return nil
+}
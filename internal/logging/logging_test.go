@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+// restoreDefault puts the text logger back after a test replaces it.
+func restoreDefault(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() { setup(os.Stderr, "info", FormatText) })
+}
+
+// records decodes one JSON record per line of buf.
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var out []map[string]interface{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var rec map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func TestSetupJSON(t *testing.T) {
+	restoreDefault(t)
+	var buf bytes.Buffer
+	logger := setup(&buf, "info", "JSON")
+	if slog.Default() != logger {
+		t.Fatal("Setup did not make its logger the default")
+	}
+
+	slog.Info("index preloaded", "entries", 970)
+	slog.Debug("below the threshold")
+	log.Printf("from the log package")
+
+	recs := records(t, &buf)
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2: %v", len(recs), recs)
+	}
+	rec := recs[0]
+	for _, field := range []string{"time", "level", "msg"} {
+		if _, ok := rec[field]; !ok {
+			t.Errorf("record %v has no %q", rec, field)
+		}
+	}
+	if rec["level"] != "INFO" || rec["msg"] != "index preloaded" || rec["entries"] != float64(970) {
+		t.Errorf("record = %v, want INFO \"index preloaded\" with entries 970", rec)
+	}
+	if recs[1]["msg"] != "from the log package" {
+		t.Errorf("log.Printf record = %v", recs[1])
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	restoreDefault(t)
+	var buf bytes.Buffer
+	setup(&buf, "debug", FormatJSON)
+
+	if err := SetLevel("warn"); err != nil {
+		t.Fatalf("SetLevel(warn): %v", err)
+	}
+	slog.Info("dropped")
+	slog.Warn("kept")
+	if recs := records(t, &buf); len(recs) != 1 || recs[0]["msg"] != "kept" {
+		t.Errorf("records = %v, want only the warning", recs)
+	}
+
+	if err := SetLevel("loud"); err == nil {
+		t.Error("SetLevel accepted an unknown level")
+	}
+}
+
+func TestSetupText(t *testing.T) {
+	restoreDefault(t)
+	var buf bytes.Buffer
+	setup(&buf, "warn", "")
+
+	slog.Info("dropped")
+	slog.Warn("disk nearly full", "free_mb", 12)
+	out := buf.String()
+	if strings.Contains(out, "dropped") || !strings.Contains(out, "WARN disk nearly full free_mb=12") {
+		t.Errorf("text output = %q, want only the warning as a plain line", out)
+	}
+	if strings.HasPrefix(strings.TrimSpace(out), "{") {
+		t.Errorf("text output = %q is JSON", out)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		want slog.Level
+		ok   bool
+	}{
+		{"debug", slog.LevelDebug, true},
+		{"", slog.LevelInfo, true},
+		{"INFO", slog.LevelInfo, true},
+		{"warn", slog.LevelWarn, true},
+		{"error", slog.LevelError, true},
+		{"verbose", slog.LevelInfo, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.name)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v, ok %v", tt.name, got, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package obex
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blockElements end a line in the text of a page description.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Div: true, atom.Li: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Pre: true, atom.Blockquote: true,
+}
+
+// ExtractPageDescription finds the description on an OBEX page: the first
+// <div> whose id or class names a description. It returns the div's inner
+// HTML and its text, one line per paragraph with runs of whitespace collapsed.
+// ok is false when the page has no such div or it is empty.
+func ExtractPageDescription(page io.Reader) (innerHTML, text string, ok bool) {
+	z := html.NewTokenizer(page)
+
+	// Skip to the description div
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return "", "", false
+		}
+		if tt == html.StartTagToken && isDescriptionDiv(z.Token()) {
+			break
+		}
+	}
+
+	var raw, body, line strings.Builder
+	endLine := func() {
+		if words := strings.Fields(line.String()); len(words) > 0 {
+			body.WriteString(strings.Join(words, " "))
+			body.WriteByte('\n')
+		}
+		line.Reset()
+	}
+
+	depth := 1 // Open <div> elements, counting the description's own
+	skip := 0  // Open <script> and <style> elements, whose text is not prose
+	for depth > 0 {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break // Unclosed at the end of the page: keep what was read
+		}
+		tokRaw := string(z.Raw()) // Token rewrites the buffer Raw points into
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if tt == html.StartTagToken {
+				switch tok.DataAtom {
+				case atom.Div:
+					depth++
+				case atom.Script, atom.Style:
+					skip++
+				}
+			}
+			if blockElements[tok.DataAtom] {
+				endLine()
+			}
+		case html.EndTagToken:
+			switch tok.DataAtom {
+			case atom.Div:
+				depth--
+			case atom.Script, atom.Style:
+				skip = max(skip-1, 0)
+			}
+			if blockElements[tok.DataAtom] {
+				endLine()
+			}
+		case html.TextToken:
+			if skip == 0 {
+				line.WriteString(tok.Data)
+			}
+		}
+		if depth > 0 {
+			raw.WriteString(tokRaw)
+		}
+	}
+	endLine()
+
+	innerHTML = strings.TrimSpace(raw.String())
+	text = strings.TrimSpace(body.String())
+	return innerHTML, text, text != ""
+}
+
+// isDescriptionDiv reports whether tok opens a <div> whose id or one of whose
+// classes contains "description", as in class="obex-description".
+func isDescriptionDiv(tok html.Token) bool {
+	if tok.DataAtom != atom.Div {
+		return false
+	}
+	for _, attr := range tok.Attr {
+		if attr.Key != "id" && attr.Key != "class" {
+			continue
+		}
+		for _, name := range strings.Fields(attr.Val) {
+			if strings.Contains(strings.ToLower(name), "description") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package obex
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DependencyGraphLimit caps the objects GetDependencyGraph analyzes; every
+// description is checked against every other object, so the work grows with
+// the square of the count.
+const DependencyGraphLimit = 200
+
+// minGraphTitleLen is the shortest title matched by name. Shorter titles
+// ("LED", "I2C") are too generic to mean the object itself.
+const minGraphTitleLen = 8
+
+// Match types of a GraphEdge, strongest first.
+const (
+	MatchObjectID = "object_id" // "OB2811" or "OBEX 2811"
+	MatchPageURL  = "page_url"  // The object's OBEX page URL
+	MatchTitle    = "title"     // The object's title, as whole words
+)
+
+// objectIDMention matches an object ID written the way descriptions cite
+// them: OB2811, OB-2811, OBEX 2811, OBEX #2811.
+var objectIDMention = regexp.MustCompile(`(?i)\bOB(?:EX)?[ #-]*(\d{3,6})\b`)
+
+// pageURLMention matches OBEX page URLs, scheme optional.
+var pageURLMention = regexp.MustCompile(`(?i)(?:https?://)?(?:www\.)?obex\.parallax\.com/obex/[^\s"'<>)\]]+`)
+
+// GraphNode is one object in a DependencyGraph.
+type GraphNode struct {
+	ObjectID  string `json:"object_id"`
+	Title     string `json:"title"`
+	Category  string `json:"category"`
+	InDegree  int    `json:"in_degree"`
+	OutDegree int    `json:"out_degree"`
+}
+
+// GraphEdge records that the source object's description mentions the
+// target object.
+type GraphEdge struct {
+	SourceID  string `json:"source_id"`
+	TargetID  string `json:"target_id"`
+	MatchType string `json:"match_type"`
+}
+
+// DependencyGraph links OBEX objects whose descriptions mention each other.
+type DependencyGraph struct {
+	Nodes                       []GraphNode `json:"nodes"`
+	Edges                       []GraphEdge `json:"edges"`
+	StronglyConnectedComponents [][]string  `json:"strongly_connected_components"` // Groups of two or more mutually reachable objects
+	IsolatedObjects             []string    `json:"isolated_objects"`              // Objects with no edges either way
+	AnalyzedObjects             int         `json:"analyzed_objects"`
+	TotalObjects                int         `json:"total_objects"`
+	Truncated                   bool        `json:"truncated"` // Objects past the first DependencyGraphLimit were left out
+}
+
+// GetDependencyGraph builds the mention graph over the first
+// DependencyGraphLimit valid objects in index order: an edge A->B means A's
+// description_full (or, lacking one, its short description) names B by
+// object ID, OBEX page URL or title. The result is reused until the object
+// list changes.
+func (m *Manager) GetDependencyGraph() (*DependencyGraph, error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	graph, generation := m.dependencyGraph, m.indexGeneration
+	fresh := graph != nil && m.dependencyGraphGeneration == generation
+	objectIDs := make([]string, len(m.objectIDs))
+	copy(objectIDs, m.objectIDs)
+	m.mu.RUnlock()
+	if fresh {
+		return graph, nil
+	}
+
+	// Fetch objects WITHOUT holding the data lock
+	graph, complete := m.computeDependencyGraph(objectIDs)
+
+	// Keep the result only if every object loaded
+	if complete {
+		m.mu.Lock()
+		m.dependencyGraph = graph
+		m.dependencyGraphGeneration = generation
+		m.mu.Unlock()
+	}
+	return graph, nil
+}
+
+// computeDependencyGraph loads objects from objectIDs until
+// DependencyGraphLimit are valid and links them. complete is false if any
+// object failed to load.
+func (m *Manager) computeDependencyGraph(objectIDs []string) (graph *DependencyGraph, complete bool) {
+	complete = true
+	truncated := false
+	var objects []*OBEXObject
+	for _, objID := range objectIDs {
+		if len(objects) == DependencyGraphLimit {
+			truncated = true
+			break
+		}
+		obj, err := m.GetObject(objID)
+		if err != nil {
+			complete = false
+			continue
+		}
+		if ValidateObject(obj) != nil {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+
+	graph = buildDependencyGraph(objects)
+	graph.TotalObjects = len(objectIDs)
+	graph.Truncated = truncated
+	return graph, complete
+}
+
+// buildDependencyGraph links objects by the mentions in their descriptions.
+func buildDependencyGraph(objects []*OBEXObject) *DependencyGraph {
+	graph := &DependencyGraph{
+		Nodes:                       make([]GraphNode, len(objects)),
+		Edges:                       []GraphEdge{},
+		StronglyConnectedComponents: [][]string{},
+		IsolatedObjects:             []string{},
+		AnalyzedObjects:             len(objects),
+	}
+
+	byID := make(map[string]int, len(objects))
+	bySlug := make(map[string]int)
+	for i, obj := range objects {
+		meta := obj.ObjectMetadata
+		graph.Nodes[i] = GraphNode{ObjectID: meta.ObjectID, Title: meta.Title, Category: meta.Functionality.Category}
+		byID[meta.ObjectID] = i
+		if slug, ok := OBEXPageSlug(meta.URLs.OBEXPage); ok {
+			bySlug[slug] = i
+		}
+	}
+
+	adjacent := make([][]int, len(objects))
+	for i, obj := range objects {
+		text := obj.ObjectMetadata.Functionality.DescriptionFull
+		if text == "" {
+			text = obj.ObjectMetadata.Functionality.DescriptionShort
+		}
+		for _, ref := range findMentions(text, i, objects, byID, bySlug) {
+			graph.Edges = append(graph.Edges, GraphEdge{
+				SourceID:  graph.Nodes[i].ObjectID,
+				TargetID:  graph.Nodes[ref.target].ObjectID,
+				MatchType: ref.matchType,
+			})
+			adjacent[i] = append(adjacent[i], ref.target)
+			graph.Nodes[i].OutDegree++
+			graph.Nodes[ref.target].InDegree++
+		}
+	}
+
+	for _, node := range graph.Nodes {
+		if node.InDegree == 0 && node.OutDegree == 0 {
+			graph.IsolatedObjects = append(graph.IsolatedObjects, node.ObjectID)
+		}
+	}
+	for _, component := range stronglyConnected(adjacent) {
+		if len(component) < 2 {
+			continue
+		}
+		ids := make([]string, len(component))
+		for j, node := range component {
+			ids[j] = graph.Nodes[node].ObjectID
+		}
+		sort.Strings(ids)
+		graph.StronglyConnectedComponents = append(graph.StronglyConnectedComponents, ids)
+	}
+	sort.Slice(graph.StronglyConnectedComponents, func(i, j int) bool {
+		return graph.StronglyConnectedComponents[i][0] < graph.StronglyConnectedComponents[j][0]
+	})
+	return graph
+}
+
+// mention is one object a description refers to, by index into objects.
+type mention struct {
+	target    int
+	matchType string
+}
+
+// findMentions returns the objects other than objects[self] that text
+// mentions, each once under its strongest match type, in objects order.
+func findMentions(text string, self int, objects []*OBEXObject, byID, bySlug map[string]int) []mention {
+	found := make(map[int]string)
+	note := func(target int, matchType string) {
+		if _, seen := found[target]; !seen && target != self {
+			found[target] = matchType
+		}
+	}
+
+	for _, match := range objectIDMention.FindAllStringSubmatch(text, -1) {
+		if target, ok := byID[match[1]]; ok {
+			note(target, MatchObjectID)
+		}
+	}
+	for _, raw := range pageURLMention.FindAllString(text, -1) {
+		if slug, ok := OBEXPageSlug(strings.TrimRight(raw, ".,;:")); ok {
+			if target, ok := bySlug[slug]; ok {
+				note(target, MatchPageURL)
+			}
+		}
+	}
+	lower := strings.ToLower(text)
+	for target, obj := range objects {
+		title := strings.ToLower(strings.TrimSpace(obj.ObjectMetadata.Title))
+		if len(title) >= minGraphTitleLen && containsWords(lower, title) {
+			note(target, MatchTitle)
+		}
+	}
+
+	mentions := make([]mention, 0, len(found))
+	for target, matchType := range found {
+		mentions = append(mentions, mention{target: target, matchType: matchType})
+	}
+	sort.Slice(mentions, func(i, j int) bool { return mentions[i].target < mentions[j].target })
+	return mentions
+}
+
+// containsWords reports whether phrase occurs in text with no letter or digit
+// directly on either side, so "servo driver" is not found in "servo drivers".
+func containsWords(text, phrase string) bool {
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for from := 0; ; {
+		i := strings.Index(text[from:], phrase)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(phrase)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+			return true
+		}
+		from = start + 1
+	}
+}
+
+// stronglyConnected returns the strongly connected components of the graph
+// whose edges run from node i to each node in adjacent[i], using Tarjan's
+// algorithm.
+func stronglyConnected(adjacent [][]int) [][]int {
+	index := make([]int, len(adjacent))
+	low := make([]int, len(adjacent))
+	onStack := make([]bool, len(adjacent))
+	for i := range index {
+		index[i] = -1
+	}
+
+	var stack []int
+	var components [][]int
+	next := 0
+	var visit func(v int)
+	visit = func(v int) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range adjacent[v] {
+			if index[w] < 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+
+		if low[v] == index[v] {
+			var component []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+	for v := range adjacent {
+		if index[v] < 0 {
+			visit(v)
+		}
+	}
+	return components
+}
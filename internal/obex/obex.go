@@ -4,17 +4,28 @@ package obex
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
+	"github.com/ironsheep/p2kb-mcp/internal/fetch"
 	"github.com/ironsheep/p2kb-mcp/internal/paths"
 	"gopkg.in/yaml.v3"
 )
@@ -29,17 +40,66 @@ const (
 	// OBEXPath is the path to OBEX objects in the repository.
 	OBEXPath = "deliverables/ai/P2/community/obex/objects"
 
-	// OBEXDownloadBase is the base URL for OBEX downloads.
-	OBEXDownloadBase = "https://obex.parallax.com/wp-admin/admin-ajax.php?action=download_obex_zip&popcorn=salty&obuid=OB"
-
 	// DefaultOBEXTTL is the default time-to-live for the OBEX index.
 	DefaultOBEXTTL = 24 * time.Hour
 
+	// HighQualityScore and LowQualityScore (on the 1-10 quality_score scale)
+	// bound the objects whose disk cache TTL is fixed: well-established
+	// objects at or above HighQualityScore rarely change and keep
+	// MaxObjectTTL, drafts below LowQualityScore keep LowQualityTTL.
+	HighQualityScore = 8
+	LowQualityScore  = 4
+
+	// MaxObjectTTL caps how long a cached OBEX object stays fresh.
+	MaxObjectTTL = 7 * 24 * time.Hour
+
+	// LowQualityTTL is how long a cached low-quality OBEX object stays fresh.
+	LowQualityTTL = 6 * time.Hour
+
 	// ErrorRefreshCooldown is the minimum time between refresh-on-error attempts.
 	// This prevents excessive refresh attempts when objects are genuinely not found.
 	ErrorRefreshCooldown = 5 * time.Minute
+
+	// DefaultOBEXConcurrency is the default number of concurrent remote object
+	// fetches, overridable via P2KB_OBEX_CONCURRENCY.
+	DefaultOBEXConcurrency = 3
+
+	// LoadAllBatchSize and LoadAllBatchDelay pace LoadAllObjects so a full
+	// load does not trip GitHub's rate limits.
+	LoadAllBatchSize  = 10
+	LoadAllBatchDelay = 100 * time.Millisecond
+
+	// OBEXPageHost is the host serving OBEX object pages,
+	// obex.parallax.com/obex/{slug}.
+	OBEXPageHost = "obex.parallax.com"
 )
 
+// ObjectsURL is the base URL of the OBEX object YAML files. It is a var (not a
+// const) so tests can point the remote tier at a local httptest server.
+var ObjectsURL = GitHubRawBase + "/" + OBEXPath
+
+// IndexContentsURL is the GitHub contents API listing of the OBEX object
+// files, from which the object IDs are read. A var for tests, like ObjectsURL.
+var IndexContentsURL = GitHubAPIBase + "/" + OBEXPath
+
+// OBEXDownloadBase is the base URL for OBEX downloads. Like ObjectsURL it is a
+// var so tests can serve ZIPs from a local httptest server.
+var OBEXDownloadBase = "https://obex.parallax.com/wp-admin/admin-ajax.php?action=download_obex_zip&popcorn=salty&obuid=OB"
+
+// KnownCategories lists the functionality categories an OBEX object may declare.
+// Objects outside this list are treated as malformed by ValidateObject.
+var KnownCategories = []string{
+	"audio", "communication", "demos", "display", "drivers",
+	"misc", "motors", "sensors", "tools",
+}
+
+// MicrocontrollerAny is the microcontroller filter value that disables
+// filtering, the same as leaving the filter empty.
+const MicrocontrollerAny = "any"
+
+// objectIDPattern matches a well-formed (numeric) OBEX object ID.
+var objectIDPattern = regexp.MustCompile(`^\d+$`)
+
 // ObjectMetadata represents the object_metadata section of an OBEX YAML file.
 type ObjectMetadata struct {
 	ObjectID       string `yaml:"object_id"`
@@ -85,16 +145,74 @@ type ObjectMetadata struct {
 // OBEXObject represents a complete OBEX object from a YAML file.
 type OBEXObject struct {
 	ObjectMetadata ObjectMetadata `yaml:"object_metadata"`
+
+	// normalizedTags holds normalizeTags(Functionality.Tags), computed once
+	// when the object is loaded so search does not re-normalize per query.
+	normalizedTags []string
+
+	// warnings holds the structural problems found when the object was
+	// decoded; see validateOBEXObject.
+	warnings []string
+
+	// local marks an object read from P2KB_OBEX_LOCAL_DIR.
+	local bool
+}
+
+// IsLocal reports whether the object came from P2KB_OBEX_LOCAL_DIR rather
+// than the public OBEX.
+func (o *OBEXObject) IsLocal() bool {
+	return o.local
+}
+
+// ValidationWarnings returns the structural problems found when the object
+// was decoded. The object is still usable; the warnings describe fields that
+// were missing or malformed in the upstream YAML.
+func (o *OBEXObject) ValidationWarnings() []string {
+	return o.warnings
 }
 
 // SearchResult represents a search match.
 type SearchResult struct {
-	ObjectID         string `json:"object_id"`
-	Title            string `json:"title"`
-	Author           string `json:"author"`
-	Category         string `json:"category"`
-	DescriptionShort string `json:"description_short"`
-	MatchType        string `json:"match_type"`
+	ObjectID         string   `json:"object_id"`
+	Title            string   `json:"title"`
+	Author           string   `json:"author"`
+	Category         string   `json:"category"`
+	DescriptionShort string   `json:"description_short"`
+	MatchType        string   `json:"match_type"`
+	MatchedInFull    bool     `json:"matched_in_full,omitempty"` // Term found only in description_full
+	Microcontroller  []string `json:"microcontroller,omitempty"`
+	Subcategory      string   `json:"subcategory,omitempty"`
+	Source           string   `json:"source,omitempty"` // "local" for P2KB_OBEX_LOCAL_DIR objects
+	FileSizeBytes    int64    `json:"-"`                // From file_size; -1 when missing or unreadable
+
+	// Set by BrowseByAuthor and BrowseByAuthors
+	MatchScore        float64  `json:"match_score,omitempty"`
+	MatchedAuthorName string   `json:"matched_author_name,omitempty"`
+	MatchedQueries    []string `json:"matched_queries,omitempty"` // The author queries that matched, in the order given
+}
+
+// ValidationError reports an OBEX object whose YAML parsed cleanly but is
+// missing fields that search and browse depend on.
+type ValidationError struct {
+	ObjectID string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid OBEX object %s: %s", e.ObjectID, strings.Join(e.Problems, "; "))
+}
+
+// InvalidObject describes an object that failed validation, for debugging.
+type InvalidObject struct {
+	ObjectID string   `json:"object_id"`
+	Title    string   `json:"title,omitempty"`
+	Problems []string `json:"problems"`
+}
+
+// RefreshStats reports what Refresh did with the memory cache.
+type RefreshStats struct {
+	Retained int // Objects kept: still indexed, with a disk cache entry within its TTL
+	Evicted  int // Objects dropped: no longer indexed, or their disk cache entry is stale
 }
 
 // AuthorStats tracks objects per author.
@@ -103,6 +221,34 @@ type AuthorStats struct {
 	ObjectCount int    `json:"object_count"`
 }
 
+// AuthorDetail is an author's complete OBEX portfolio.
+type AuthorDetail struct {
+	Name            string         `json:"name"`
+	ObjectCount     int            `json:"object_count"`
+	Categories      map[string]int `json:"categories"`
+	Tags            []TagCount     `json:"tags"`
+	AverageQuality  float64        `json:"average_quality_score"` // Over objects with a quality score
+	EarliestCreated string         `json:"earliest_created_date,omitempty"`
+	LatestCreated   string         `json:"latest_created_date,omitempty"`
+	Languages       []string       `json:"languages"`
+	GitHubUsernames []string       `json:"github_usernames"`
+	Objects         []AuthorObject `json:"objects"`
+}
+
+// TagCount is how many objects carry a tag.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// AuthorObject summarizes one object in an author's portfolio.
+type AuthorObject struct {
+	ObjectID     string `json:"object_id"`
+	Title        string `json:"title"`
+	Category     string `json:"category"`
+	QualityScore int    `json:"quality_score"`
+}
+
 // DownloadResult contains the result of downloading and extracting an OBEX object.
 type DownloadResult struct {
 	ObjectID       string   `json:"object_id"`
@@ -122,16 +268,107 @@ type Manager struct {
 	lastRefresh      time.Time
 	ttl              time.Duration
 	httpClient       *http.Client
-	lastErrorRefresh time.Time // Tracks last refresh-on-error attempt to prevent refresh storms
+	authConfigured   bool                           // httpClient sends GitHub requests with a token
+	lastErrorRefresh time.Time                      // Tracks last refresh-on-error attempt to prevent refresh storms
+	fetchSem         chan struct{}                  // Bounds concurrent remote object fetches; nil means unbounded
+	pendingFetches   atomic.Int64                   // Slots of fetchSem currently held
+	workers          int                            // Objects forEachObject loads at once; 0 means DefaultOBEXWorkers
+	corruptEvictions atomic.Int64                   // Unreadable disk cache entries deleted and re-fetched
+	objectAccess     map[string]uint64              // objectID -> accessClock at last store or memory hit
+	objectLoadedAt   map[string]time.Time           // objectID -> when it entered the memory cache
+	accessClock      uint64                         // Monotonic counter for LRU eviction, guarded by mu
+	categoryMu       sync.Mutex                     // Serializes category index builds, separate from data lock
+	categoryIndex    map[string][]string            // Lowercased category -> valid object IDs; nil until built
+	subCategoryIndex map[string]map[string][]string // Lowercased category -> lowercased subcategory -> valid object IDs; built with categoryIndex
+	indexGeneration  uint64                         // Bumped whenever objectIDs is replaced, guarded by mu
+	pageSlugs        map[string]string              // OBEX page slug -> object ID for objects seen so far; reset with objectIDs
+	tagMu            sync.Mutex                     // Serializes tag index builds, separate from data lock
+	tagIndex         map[string][]string            // Normalized tag -> valid object IDs; nil until built
+	sortedTags       []string                       // Keys of tagIndex in order, for prefix lookups; built with tagIndex
+	localDir         string                         // P2KB_OBEX_LOCAL_DIR; "" for none
+	localObjects     map[string]*OBEXObject         // Objects read from localDir by ID, never evicted; reloaded with the index
+	downloadURLs     map[string]string              // Object ID -> replacement download URL set by SetDownloadURL
+	mirrorURLs       []string                       // P2KB_OBEX_MIRROR_URLS bases, tried in order when ObjectsURL fails
+	lastMirrorUsed   string                         // URL of the last object fetched from a mirror, guarded by mu
+
+	corpusStats           *CorpusStats // Last GetCorpusStats result; nil until computed
+	corpusStatsGeneration uint64       // indexGeneration corpusStats was computed from
+
+	tagCloud           *TagCloud // Last complete GetTagCloud result; nil until computed
+	tagCloudGeneration uint64    // indexGeneration tagCloud was computed from
+
+	dependencyGraph           *DependencyGraph // Last complete GetDependencyGraph result; nil until computed
+	dependencyGraphGeneration uint64           // indexGeneration dependencyGraph was computed from
 }
 
-// NewManager creates a new OBEX manager.
+// NewManager creates a new OBEX manager. Its GitHub requests carry the token
+// from GitHubToken, or else the P2KB_GITHUB_TOKENS pool, so they are not held
+// to the anonymous rate limit.
 func NewManager() *Manager {
+	client := fetch.NewClient(fetch.WithTimeout(30*time.Second), fetch.WithAuthToken(GitHubToken()))
 	return &Manager{
-		cacheDir:   paths.GetCacheDirOrDefault(),
-		objects:    make(map[string]*OBEXObject),
-		ttl:        DefaultOBEXTTL,
-		httpClient: &http.Client{Timeout: 30 * time.Second},
+		cacheDir:       paths.GetCacheDirOrDefault(),
+		objects:        make(map[string]*OBEXObject),
+		ttl:            DefaultOBEXTTL,
+		httpClient:     client.HTTPClient(),
+		authConfigured: client.Authenticated(),
+		fetchSem:       make(chan struct{}, getOBEXConcurrency()),
+		workers:        getOBEXWorkers(),
+		localDir:       os.Getenv("P2KB_OBEX_LOCAL_DIR"),
+		mirrorURLs:     getMirrorURLs(),
+	}
+}
+
+// AuthConfigured reports whether the manager's GitHub requests are
+// authenticated with a token.
+func (m *Manager) AuthConfigured() bool {
+	return m.authConfigured
+}
+
+// ConcurrencyLimit returns the maximum number of concurrent remote object
+// fetches, or 0 if fetches are unbounded.
+func (m *Manager) ConcurrencyLimit() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return cap(m.fetchSem)
+}
+
+// SetConcurrencyLimit changes the maximum number of concurrent remote object
+// fetches; n <= 0 removes the limit. Fetches already holding a slot finish
+// under the old limit.
+func (m *Manager) SetConcurrencyLimit(n int) {
+	var sem chan struct{}
+	if n > 0 {
+		sem = make(chan struct{}, n)
+	}
+	m.mu.Lock()
+	m.fetchSem = sem
+	m.mu.Unlock()
+}
+
+// PendingFetches returns how many remote object fetches currently hold a
+// concurrency slot.
+func (m *Manager) PendingFetches() int64 {
+	return m.pendingFetches.Load()
+}
+
+// acquireFetchSlot blocks until a remote fetch slot is free and returns the
+// function that releases it.
+func (m *Manager) acquireFetchSlot() func() {
+	// Release to the semaphore acquired from, even if the limit changes meanwhile
+	m.mu.RLock()
+	sem := m.fetchSem
+	m.mu.RUnlock()
+
+	if sem != nil {
+		sem <- struct{}{}
+	}
+	m.pendingFetches.Add(1)
+	return func() {
+		m.pendingFetches.Add(-1)
+		if sem != nil {
+			<-sem
+		}
 	}
 }
 
@@ -159,8 +396,12 @@ func (m *Manager) EnsureIndex() error {
 	}
 	m.mu.RUnlock()
 
+	// Local objects are reread with every index load
+	local := m.loadLocalObjects()
+
 	// Try to load from cache (quick file I/O, safe to hold write lock briefly)
 	m.mu.Lock()
+	m.localObjects = local
 	if m.loadIndexFromCache() {
 		m.mu.Unlock()
 		return nil
@@ -181,11 +422,57 @@ func (m *Manager) EnsureIndex() error {
 	// Save to cache
 	m.saveIndexToCache(objectIDs)
 
-	m.objectIDs = objectIDs
+	m.setObjectIDsLocked(objectIDs)
 	m.lastRefresh = time.Now()
 	return nil
 }
 
+// loadLocalObjects reads every object file in the local directory, keyed by
+// file name like the public OBEX. Unreadable files are logged and skipped.
+func (m *Manager) loadLocalObjects() map[string]*OBEXObject {
+	if m.localDir == "" {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(m.localDir, "*.yaml"))
+	if err != nil || len(files) == 0 {
+		return nil
+	}
+
+	local := make(map[string]*OBEXObject, len(files))
+	for _, file := range files {
+		objectID := strings.TrimSuffix(filepath.Base(file), ".yaml")
+		if objectID == "_template" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			slog.Warn("skipping unreadable local OBEX object", "file", file, "error", err)
+			continue
+		}
+		obj, err := decodeObject(data)
+		if err != nil {
+			slog.Warn("skipping malformed local OBEX object", "file", file, "error", err)
+			continue
+		}
+		if obj.ObjectMetadata.ObjectID == "" {
+			obj.ObjectMetadata.ObjectID = objectID
+		}
+		obj.local = true
+		local[objectID] = obj
+	}
+	return local
+}
+
+// LocalObjectCount returns how many objects were read from P2KB_OBEX_LOCAL_DIR.
+func (m *Manager) LocalObjectCount() int {
+	if err := m.EnsureIndex(); err != nil {
+		return 0
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.localObjects)
+}
+
 // GetObjectIDs returns all OBEX object IDs.
 func (m *Manager) GetObjectIDs() []string {
 	if err := m.EnsureIndex(); err != nil {
@@ -211,8 +498,13 @@ func (m *Manager) GetObject(objectID string) (*OBEXObject, error) {
 	objectID = normalizeObjectID(objectID)
 
 	m.mu.RLock()
+	if obj, ok := m.localObjects[objectID]; ok {
+		m.mu.RUnlock()
+		return obj, nil
+	}
 	if obj, ok := m.objects[objectID]; ok {
 		m.mu.RUnlock()
+		m.touch(objectID)
 		return obj, nil
 	}
 	m.mu.RUnlock()
@@ -226,7 +518,7 @@ func (m *Manager) GetObject(objectID string) (*OBEXObject, error) {
 				return m.fetchObject(objectID)
 			}
 		}
-		return nil, fmt.Errorf("OBEX object not found: %s", objectID)
+		return nil, &errs.ErrKeyNotFound{Key: objectID}
 	}
 
 	// Fetch from remote or cache
@@ -246,7 +538,7 @@ func (m *Manager) tryErrorRefresh() bool {
 	}
 
 	// Attempt refresh
-	if err := m.Refresh(); err != nil {
+	if _, err := m.Refresh(); err != nil {
 		// Refresh failed, but still update timestamp to prevent retry storm
 		m.mu.Lock()
 		m.lastErrorRefresh = time.Now()
@@ -261,14 +553,40 @@ func (m *Manager) tryErrorRefresh() bool {
 	return true
 }
 
-// Search searches OBEX objects by term.
-func (m *Manager) Search(term string, category string, language string, limit int) ([]SearchResult, error) {
+// Search searches OBEX objects by term. A non-empty microcontroller other
+// than MicrocontrollerAny keeps only objects built for it; see
+// MatchesMicrocontroller.
+func (m *Manager) Search(term string, category string, language string, microcontroller string, limit int) ([]SearchResult, error) {
+	filters := SearchFilters{Category: category, Language: language, Microcontroller: microcontroller}
+	results, _, err := m.SearchWithPhases(term, filters, limit)
+	return results, err
+}
+
+// SearchFilters narrows SearchWithPhases. Objects are filtered before the
+// limit is counted, so limit results are returned whenever that many objects
+// match and pass. Zero values filter nothing.
+type SearchFilters struct {
+	Category        string
+	Subcategory     string // Compared within Category
+	Language        string
+	Microcontroller string // See MatchesMicrocontroller
+	MinFileSizeKB   int    // See MatchesFileSize
+	MaxFileSizeKB   int
+}
+
+// SearchWithPhases is Search, also reporting how many phases it took. Phase 1
+// matches the titles, tags and short descriptions of the objects already in
+// memory, without fetching anything. Only if that finds fewer than limit
+// results does phase 2 fetch the other objects and search everything,
+// including description_full; results matched only there have MatchedInFull
+// set. Results are in index order either way.
+func (m *Manager) SearchWithPhases(term string, filters SearchFilters, limit int) (results []SearchResult, phases int, err error) {
 	if err := m.EnsureIndex(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if term == "" {
-		return nil, fmt.Errorf("search term required")
+		return nil, 0, fmt.Errorf("search term required")
 	}
 
 	if limit <= 0 {
@@ -278,165 +596,1506 @@ func (m *Manager) Search(term string, category string, language string, limit in
 	// Expand search terms
 	searchTerms := expandSearchTerms(strings.ToLower(term))
 
-	var results []SearchResult
 	objectIDs := m.GetObjectIDs()
+	matched := make(map[string]bool)
 
+	// Phase 1: objects already in memory, summary fields only
+	m.mu.RLock()
 	for _, objID := range objectIDs {
-		obj, err := m.GetObject(objID)
-		if err != nil {
+		obj, ok := m.objects[objID]
+		if !ok || ValidateObject(obj) != nil || !filters.matches(obj) {
 			continue
 		}
+		if matchType := matchSummary(obj, searchTerms); matchType != "" {
+			results = append(results, newSearchResult(obj, matchType))
+			matched[objID] = true
+			if len(results) >= limit {
+				break
+			}
+		}
+	}
+	m.mu.RUnlock()
 
-		// Apply filters
-		if category != "" && !strings.EqualFold(obj.ObjectMetadata.Functionality.Category, category) {
-			continue
+	if len(results) >= limit {
+		return results, 1, nil
+	}
+
+	// Phase 2: every other object, fetching as needed, full description too
+	var rest []string
+	for _, objID := range objectIDs {
+		if !matched[objID] {
+			rest = append(rest, objID)
+		}
+	}
+	m.forEachObject(rest, func(_ string, obj *OBEXObject, err error) bool {
+		if err != nil || ValidateObject(obj) != nil || !filters.matches(obj) {
+			return true
 		}
 
-		if language != "" {
-			hasLanguage := false
-			for _, lang := range obj.ObjectMetadata.TechnicalDetails.Languages {
-				if strings.EqualFold(lang, language) {
-					hasLanguage = true
-					break
-				}
-			}
-			if !hasLanguage {
-				continue
-			}
+		var result SearchResult
+		if matchType := matchSummary(obj, searchTerms); matchType != "" {
+			result = newSearchResult(obj, matchType)
+		} else if matchesFullDescription(obj, searchTerms) {
+			result = newSearchResult(obj, "description")
+			result.MatchedInFull = true
+		} else {
+			return true
 		}
+		results = append(results, result)
+		return len(results) < limit
+	})
 
-		// Check for matches
-		matchType := m.matchObject(obj, searchTerms)
-		if matchType != "" {
-			results = append(results, SearchResult{
-				ObjectID:         obj.ObjectMetadata.ObjectID,
-				Title:            obj.ObjectMetadata.Title,
-				Author:           obj.ObjectMetadata.Author,
-				Category:         obj.ObjectMetadata.Functionality.Category,
-				DescriptionShort: obj.ObjectMetadata.Functionality.DescriptionShort,
-				MatchType:        matchType,
-			})
+	// Phase 1 results were found out of turn; restore index order
+	position := make(map[string]int, len(objectIDs))
+	for i, objID := range objectIDs {
+		position[objID] = i
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return position[results[i].ObjectID] < position[results[j].ObjectID]
+	})
 
-			if len(results) >= limit {
+	return results, 2, nil
+}
+
+// matches reports whether obj passes every filter of f.
+func (f SearchFilters) matches(obj *OBEXObject) bool {
+	if f.Category != "" && !strings.EqualFold(obj.ObjectMetadata.Functionality.Category, f.Category) {
+		return false
+	}
+	if f.Subcategory != "" && !strings.EqualFold(obj.ObjectMetadata.Functionality.Subcategory, f.Subcategory) {
+		return false
+	}
+
+	if f.Language != "" {
+		hasLanguage := false
+		for _, lang := range obj.ObjectMetadata.TechnicalDetails.Languages {
+			if strings.EqualFold(lang, f.Language) {
+				hasLanguage = true
 				break
 			}
 		}
+		if !hasLanguage {
+			return false
+		}
 	}
 
-	return results, nil
+	if !MatchesMicrocontroller(obj.ObjectMetadata.TechnicalDetails.Microcontroller, f.Microcontroller) {
+		return false
+	}
+	return MatchesFileSize(parseFileSize(obj.ObjectMetadata.TechnicalDetails.FileSize), f.MinFileSizeKB, f.MaxFileSizeKB)
 }
 
-// GetCategories returns OBEX categories with counts.
-func (m *Manager) GetCategories() (map[string]int, error) {
+// newSearchResult returns the search result for obj.
+func newSearchResult(obj *OBEXObject, matchType string) SearchResult {
+	return SearchResult{
+		ObjectID:         obj.ObjectMetadata.ObjectID,
+		Title:            obj.ObjectMetadata.Title,
+		Author:           obj.ObjectMetadata.Author,
+		Category:         obj.ObjectMetadata.Functionality.Category,
+		DescriptionShort: obj.ObjectMetadata.Functionality.DescriptionShort,
+		MatchType:        matchType,
+		Microcontroller:  obj.ObjectMetadata.TechnicalDetails.Microcontroller,
+		Subcategory:      obj.ObjectMetadata.Functionality.Subcategory,
+		Source:           obj.source(),
+		FileSizeBytes:    parseFileSize(obj.ObjectMetadata.TechnicalDetails.FileSize),
+	}
+}
+
+// source is the SearchResult.Source of the object: "local" or "".
+func (o *OBEXObject) source() string {
+	if o.local {
+		return "local"
+	}
+	return ""
+}
+
+// SearchByOBEXPageURL returns the object whose OBEX page is pageURL, as
+// copied from a browser. URLs are compared by slug (see OBEXPageSlug), so the
+// scheme, a trailing slash and percent-encoding do not matter. Objects whose
+// page has already been seen are found directly; otherwise every object is
+// scanned.
+func (m *Manager) SearchByOBEXPageURL(pageURL string) (*OBEXObject, error) {
+	slug, ok := OBEXPageSlug(pageURL)
+	if !ok {
+		return nil, fmt.Errorf("not an OBEX page URL: %s", pageURL)
+	}
+
 	if err := m.EnsureIndex(); err != nil {
 		return nil, err
 	}
 
-	categories := make(map[string]int)
-	objectIDs := m.GetObjectIDs()
+	m.mu.RLock()
+	objectID, seen := m.pageSlugs[slug]
+	m.mu.RUnlock()
+	if seen {
+		if obj, err := m.GetObject(objectID); err == nil {
+			return obj, nil
+		}
+	}
 
-	for _, objID := range objectIDs {
+	for _, objID := range m.GetObjectIDs() {
 		obj, err := m.GetObject(objID)
 		if err != nil {
 			continue
 		}
 
-		cat := obj.ObjectMetadata.Functionality.Category
-		if cat == "" {
-			cat = "uncategorized"
+		m.mu.Lock()
+		m.notePageSlugLocked(objID, obj)
+		m.mu.Unlock()
+
+		if objSlug, ok := OBEXPageSlug(obj.ObjectMetadata.URLs.OBEXPage); ok && objSlug == slug {
+			return obj, nil
 		}
-		categories[cat]++
 	}
 
+	return nil, fmt.Errorf("no OBEX object has page URL: %s", pageURL)
+}
+
+// OBEXPageSlug returns the lowercased slug of an OBEX page URL of the form
+// obex.parallax.com/obex/{slug}. The scheme may be http, https or missing, and
+// a www. prefix, trailing slash, query string and percent-encoding are
+// ignored. ok is false if raw is not an OBEX page URL.
+func OBEXPageSlug(raw string) (slug string, ok bool) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	if strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.") != OBEXPageHost {
+		return "", false
+	}
+
+	// u.Path is already percent-decoded
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) != 2 || !strings.EqualFold(segments[0], "obex") || segments[1] == "" {
+		return "", false
+	}
+	return strings.ToLower(segments[1]), true
+}
+
+// GetCategories returns OBEX categories with counts of the objects
+// BrowseCategory would list for each.
+func (m *Manager) GetCategories() (map[string]int, error) {
+	categoryIndex, err := m.getCategoryIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make(map[string]int, len(categoryIndex))
+	for cat, ids := range categoryIndex {
+		categories[cat] = len(ids)
+	}
 	return categories, nil
 }
 
-// BrowseCategory returns objects in a category.
+// GetCategoryObjectCount returns how many valid objects are in category
+// (case-insensitive), or 0 if the OBEX index is unavailable.
+func (m *Manager) GetCategoryObjectCount(category string) int {
+	categoryIndex, err := m.getCategoryIndex()
+	if err != nil {
+		return 0
+	}
+	return len(categoryIndex[strings.ToLower(category)])
+}
+
+// BrowseCategory returns objects in a category, or every valid object when
+// category is empty.
 func (m *Manager) BrowseCategory(category string) ([]SearchResult, error) {
 	if err := m.EnsureIndex(); err != nil {
 		return nil, err
 	}
 
-	var results []SearchResult
+	// A named category only needs the objects listed for it in the category index
 	objectIDs := m.GetObjectIDs()
-
-	for _, objID := range objectIDs {
-		obj, err := m.GetObject(objID)
+	if category != "" {
+		categoryIndex, err := m.getCategoryIndex()
 		if err != nil {
-			continue
+			return nil, err
+		}
+		objectIDs = categoryIndex[strings.ToLower(category)]
+	}
+
+	var results []SearchResult
+	m.forEachObject(objectIDs, func(_ string, obj *OBEXObject, err error) bool {
+		if err != nil || ValidateObject(obj) != nil {
+			return true
 		}
 
 		if category != "" && !strings.EqualFold(obj.ObjectMetadata.Functionality.Category, category) {
-			continue
+			return true
 		}
 
-		results = append(results, SearchResult{
-			ObjectID:         obj.ObjectMetadata.ObjectID,
-			Title:            obj.ObjectMetadata.Title,
-			Author:           obj.ObjectMetadata.Author,
-			Category:         obj.ObjectMetadata.Functionality.Category,
-			DescriptionShort: obj.ObjectMetadata.Functionality.DescriptionShort,
-		})
+		results = append(results, newSearchResult(obj, ""))
+		return true
+	})
+
+	return results, nil
+}
+
+// GetSubcategories returns the subcategories of category (case-insensitive)
+// with counts of their valid objects, keyed by lowercased name. Objects with
+// no subcategory are not counted. Returns nil if the OBEX index is unavailable.
+func (m *Manager) GetSubcategories(category string) map[string]int {
+	_, subCategoryIndex, err := m.getCategoryIndexes()
+	if err != nil {
+		return nil
+	}
+
+	subcategories := make(map[string]int)
+	for sub, ids := range subCategoryIndex[strings.ToLower(category)] {
+		subcategories[sub] = len(ids)
+	}
+	return subcategories
+}
+
+// BrowseSubcategory returns the objects in category whose subcategory is
+// subcategory, both compared case-insensitively.
+func (m *Manager) BrowseSubcategory(category, subcategory string) ([]SearchResult, error) {
+	_, subCategoryIndex, err := m.getCategoryIndexes()
+	if err != nil {
+		return nil, err
 	}
 
+	var results []SearchResult
+	objectIDs := subCategoryIndex[strings.ToLower(category)][strings.ToLower(subcategory)]
+	m.forEachObject(objectIDs, func(_ string, obj *OBEXObject, err error) bool {
+		if err != nil || ValidateObject(obj) != nil {
+			return true
+		}
+
+		results = append(results, newSearchResult(obj, ""))
+		return true
+	})
+
 	return results, nil
 }
 
-// GetAuthors returns authors sorted by object count.
-func (m *Manager) GetAuthors() ([]AuthorStats, error) {
-	if err := m.EnsureIndex(); err != nil {
+// GetMicrocontrollerDistribution returns how many valid objects list each
+// microcontroller value, as written upstream. Objects listing none are
+// counted under "unspecified"; an object listing several counts once for each.
+func (m *Manager) GetMicrocontrollerDistribution() (map[string]int, error) {
+	stats, err := m.GetOverviewStats()
+	if err != nil {
 		return nil, err
 	}
+	return stats.MicrocontrollerDistribution, nil
+}
 
-	authorCounts := make(map[string]int)
-	objectIDs := m.GetObjectIDs()
+// MatchesMicrocontroller reports whether an object listing values is built
+// for the microcontroller filter. An empty filter or MicrocontrollerAny
+// matches everything; otherwise names are compared after
+// normalizeMicrocontroller, and objects listing no microcontroller never match.
+func MatchesMicrocontroller(values []string, filter string) bool {
+	filter = strings.TrimSpace(filter)
+	if filter == "" || strings.EqualFold(filter, MicrocontrollerAny) {
+		return true
+	}
 
-	for _, objID := range objectIDs {
-		obj, err := m.GetObject(objID)
-		if err != nil {
-			continue
+	want := normalizeMicrocontroller(filter)
+	for _, v := range values {
+		if normalizeMicrocontroller(v) == want {
+			return true
 		}
+	}
+	return false
+}
 
-		author := obj.ObjectMetadata.Author
-		if author == "" {
-			author = "Unknown"
-		}
-		authorCounts[author]++
+// normalizeMicrocontroller reduces a microcontroller name to a comparable
+// form, so "P2", "Propeller 2" and "P2X8C4M64P" all become "p2".
+func normalizeMicrocontroller(name string) string {
+	n := strings.ToLower(strings.TrimSpace(name))
+	n = strings.NewReplacer(" ", "", "-", "", "_", "").Replace(n)
+	if rest := strings.TrimPrefix(n, "propeller"); rest != n && rest != "" {
+		n = "p" + rest
 	}
 
-	// Convert to slice and sort
-	authors := make([]AuthorStats, 0, len(authorCounts))
-	for name, count := range authorCounts {
-		authors = append(authors, AuthorStats{
-			Name:        name,
-			ObjectCount: count,
-		})
+	switch {
+	case strings.HasPrefix(n, "p2"):
+		return "p2"
+	case strings.HasPrefix(n, "p1"), strings.HasPrefix(n, "p8x32"):
+		return "p1"
 	}
+	return n
+}
 
-	sort.Slice(authors, func(i, j int) bool {
-		return authors[i].ObjectCount > authors[j].ObjectCount
-	})
+// FileSizeStats summarizes the file sizes of the valid OBEX objects, in KB
+// rounded to one decimal place. Objects whose file_size is missing or
+// unreadable are counted in Unknown and left out of the rest.
+type FileSizeStats struct {
+	MinKB   float64 `json:"min_kb"`
+	MaxKB   float64 `json:"max_kb"`
+	AvgKB   float64 `json:"avg_kb"`
+	Known   int     `json:"known"`
+	Unknown int     `json:"unknown"`
+}
 
-	return authors, nil
+// GetFileSizeStats returns the smallest, largest and average file size of the
+// valid objects.
+func (m *Manager) GetFileSizeStats() (*FileSizeStats, error) {
+	stats, err := m.GetOverviewStats()
+	if err != nil {
+		return nil, err
+	}
+	return stats.FileSizes, nil
 }
 
-// GetTotalObjects returns the total number of OBEX objects.
-func (m *Manager) GetTotalObjects() int {
+// OverviewStats is what the p2kb_obex_find overview reports about the valid
+// objects: GetMicrocontrollerDistribution's counts and GetFileSizeStats'
+// summary.
+type OverviewStats struct {
+	MicrocontrollerDistribution map[string]int
+	FileSizes                   *FileSizeStats
+}
+
+// GetOverviewStats gathers the OverviewStats in a single pass over the
+// objects, loaded through forEachObject.
+func (m *Manager) GetOverviewStats() (*OverviewStats, error) {
 	if err := m.EnsureIndex(); err != nil {
-		return 0
+		return nil, err
 	}
 
+	distribution := make(map[string]int)
+	var sizes []int64
+	unknown := 0
+	m.forEachObject(m.GetObjectIDs(), func(_ string, obj *OBEXObject, err error) bool {
+		if err != nil || ValidateObject(obj) != nil {
+			return true
+		}
+		details := obj.ObjectMetadata.TechnicalDetails
+
+		counted := false
+		for _, mc := range details.Microcontroller {
+			if mc = strings.TrimSpace(mc); mc != "" {
+				distribution[mc]++
+				counted = true
+			}
+		}
+		if !counted {
+			distribution["unspecified"]++
+		}
+
+		if size := parseFileSize(details.FileSize); size >= 0 {
+			sizes = append(sizes, size)
+		} else {
+			unknown++
+		}
+		return true
+	})
+
+	return &OverviewStats{
+		MicrocontrollerDistribution: distribution,
+		FileSizes:                   SummarizeFileSizes(sizes, unknown),
+	}, nil
+}
+
+// SummarizeFileSizes builds the FileSizeStats of known sizes, in bytes, and a
+// count of objects whose size is unknown.
+func SummarizeFileSizes(sizes []int64, unknown int) *FileSizeStats {
+	stats := &FileSizeStats{Known: len(sizes), Unknown: unknown}
+	if len(sizes) == 0 {
+		return stats
+	}
+	lo, hi, total := sizes[0], sizes[0], int64(0)
+	for _, size := range sizes {
+		lo = min(lo, size)
+		hi = max(hi, size)
+		total += size
+	}
+	stats.MinKB = SizeKB(lo)
+	stats.MaxKB = SizeKB(hi)
+	stats.AvgKB = SizeKB(total / int64(len(sizes)))
+	return stats
+}
+
+// SizeKB converts a size in bytes to KB, rounded to one decimal place.
+func SizeKB(bytes int64) float64 {
+	return math.Round(float64(bytes)/1024*10) / 10
+}
+
+// MatchesFileSize reports whether an object of sizeBytes passes the file size
+// filters, given in KB; a zero bound is no bound, and both are inclusive.
+// Objects of unknown size (negative) always pass, since they may well be small.
+func MatchesFileSize(sizeBytes int64, minKB, maxKB int) bool {
+	if sizeBytes < 0 {
+		return true
+	}
+	if minKB > 0 && sizeBytes < int64(minKB)*1024 {
+		return false
+	}
+	return maxKB <= 0 || sizeBytes <= int64(maxKB)*1024
+}
+
+// fileSizePattern matches an OBEX file_size: a number, which may have
+// thousands separators and a fraction, then an optional unit.
+var fileSizePattern = regexp.MustCompile(`(?i)^([0-9][0-9,]*(?:\.[0-9]+)?)\s*(b|bytes?|k|kb|kib|m|mb|mib)?$`)
+
+// parseFileSize converts an OBEX file_size ("45.2 KB", "1.2 MB", "512 bytes",
+// "2048") to bytes, taking a KB as 1024 bytes. It returns -1 for a missing or
+// unreadable size.
+func parseFileSize(s string) int64 {
+	match := fileSizePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return -1
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", ""), 64)
+	if err != nil {
+		return -1
+	}
+	switch strings.ToLower(match[2]) {
+	case "k", "kb", "kib":
+		n *= 1024
+	case "m", "mb", "mib":
+		n *= 1024 * 1024
+	}
+	return int64(math.Round(n))
+}
+
+// getCategoryIndex returns the category index, building it on first use after
+// each index load. Building loads every object once; afterwards category
+// lookups and counts need no object fetches.
+func (m *Manager) getCategoryIndex() (map[string][]string, error) {
+	categoryIndex, _, err := m.getCategoryIndexes()
+	return categoryIndex, err
+}
+
+// getCategoryIndexes returns the category index and the subcategory index
+// built alongside it; see getCategoryIndex.
+func (m *Manager) getCategoryIndexes() (map[string][]string, map[string]map[string][]string, error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, nil, err
+	}
+
+	m.mu.RLock()
+	categoryIndex, subCategoryIndex := m.categoryIndex, m.subCategoryIndex
+	m.mu.RUnlock()
+	if categoryIndex != nil {
+		return categoryIndex, subCategoryIndex, nil
+	}
+
+	// One build at a time; later callers pick up the finished index
+	m.categoryMu.Lock()
+	defer m.categoryMu.Unlock()
+
+	m.mu.RLock()
+	categoryIndex, subCategoryIndex = m.categoryIndex, m.subCategoryIndex
+	generation := m.indexGeneration
+	objectIDs := make([]string, len(m.objectIDs))
+	copy(objectIDs, m.objectIDs)
+	m.mu.RUnlock()
+	if categoryIndex != nil {
+		return categoryIndex, subCategoryIndex, nil
+	}
+
+	// Fetch objects WITHOUT holding the data lock
+	categoryIndex, subCategoryIndex, complete := m.buildCategoryIndex(objectIDs)
+
+	// Keep the index only if every object loaded and the ID list is unchanged
+	if complete {
+		m.mu.Lock()
+		if m.indexGeneration == generation {
+			m.categoryIndex = categoryIndex
+			m.subCategoryIndex = subCategoryIndex
+		}
+		m.mu.Unlock()
+	}
+	return categoryIndex, subCategoryIndex, nil
+}
+
+// buildCategoryIndex maps each lowercased category to the IDs of its valid
+// objects, and each lowercased category and subcategory pair to the IDs of
+// those with that subcategory, in objectIDs order. complete is false if any
+// object failed to load.
+func (m *Manager) buildCategoryIndex(objectIDs []string) (categoryIndex map[string][]string, subCategoryIndex map[string]map[string][]string, complete bool) {
+	categoryIndex = make(map[string][]string)
+	subCategoryIndex = make(map[string]map[string][]string)
+	complete = true
+	m.forEachObject(objectIDs, func(objID string, obj *OBEXObject, err error) bool {
+		if err != nil {
+			complete = false
+			return true
+		}
+		if ValidateObject(obj) != nil {
+			return true
+		}
+		cat := strings.ToLower(obj.ObjectMetadata.Functionality.Category)
+		categoryIndex[cat] = append(categoryIndex[cat], objID)
+
+		sub := strings.ToLower(strings.TrimSpace(obj.ObjectMetadata.Functionality.Subcategory))
+		if sub == "" {
+			return true
+		}
+		if subCategoryIndex[cat] == nil {
+			subCategoryIndex[cat] = make(map[string][]string)
+		}
+		subCategoryIndex[cat][sub] = append(subCategoryIndex[cat][sub], objID)
+		return true
+	})
+	return categoryIndex, subCategoryIndex, complete
+}
+
+// setObjectIDsLocked replaces the object ID list and drops the category and
+// tag indexes built from the previous list (caller holds the write lock).
+func (m *Manager) setObjectIDsLocked(objectIDs []string) {
+	if len(m.localObjects) > 0 {
+		merged := make([]string, 0, len(objectIDs)+len(m.localObjects))
+		for _, id := range objectIDs {
+			if m.localObjects[id] == nil {
+				merged = append(merged, id)
+			}
+		}
+		for id := range m.localObjects {
+			merged = append(merged, id)
+		}
+		sort.Strings(merged)
+		objectIDs = merged
+	}
+	m.objectIDs = objectIDs
+	m.categoryIndex = nil
+	m.subCategoryIndex = nil
+	m.pageSlugs = nil
+	m.tagIndex = nil
+	m.sortedTags = nil
+	m.indexGeneration++
+}
+
+// GetInvalidObjects returns every indexed object that fails ValidateObject.
+// Search and BrowseCategory silently skip these; this exposes them for debugging.
+func (m *Manager) GetInvalidObjects() ([]InvalidObject, error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, err
+	}
+
+	var invalid []InvalidObject
+	for _, objID := range m.GetObjectIDs() {
+		obj, err := m.GetObject(objID)
+		if err != nil {
+			continue
+		}
+
+		var verr *ValidationError
+		if errors.As(ValidateObject(obj), &verr) {
+			invalid = append(invalid, InvalidObject{
+				ObjectID: objID,
+				Title:    obj.ObjectMetadata.Title,
+				Problems: verr.Problems,
+			})
+		}
+	}
+
+	return invalid, nil
+}
+
+// FindByTags returns already-loaded objects whose normalized tags overlap the
+// given tokens, most overlapping first. Only the in-memory object cache and
+// local objects are consulted, so it never triggers a fetch; objects not yet
+// loaded by a search or get are simply not considered.
+func (m *Manager) FindByTags(tokens []string, limit int) []SearchResult {
+	wanted := make(map[string]bool)
+	for _, tag := range normalizeTags(tokens) {
+		wanted[tag] = true
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		result  SearchResult
+		overlap int
+	}
+	var matches []scored
+
+	m.mu.RLock()
+	candidates := make([]*OBEXObject, 0, len(m.objects)+len(m.localObjects))
+	for id, obj := range m.objects {
+		if m.localObjects[id] == nil {
+			candidates = append(candidates, obj)
+		}
+	}
+	for _, obj := range m.localObjects {
+		candidates = append(candidates, obj)
+	}
+	for _, obj := range candidates {
+		if ValidateObject(obj) != nil {
+			continue
+		}
+		tags := obj.normalizedTags
+		if tags == nil {
+			tags = normalizeTags(obj.ObjectMetadata.Functionality.Tags)
+		}
+		overlap := 0
+		for _, tag := range tags {
+			if wanted[tag] {
+				overlap++
+			}
+		}
+		if overlap == 0 {
+			continue
+		}
+		matches = append(matches, scored{
+			result: SearchResult{
+				ObjectID:         obj.ObjectMetadata.ObjectID,
+				Title:            obj.ObjectMetadata.Title,
+				Author:           obj.ObjectMetadata.Author,
+				Category:         obj.ObjectMetadata.Functionality.Category,
+				DescriptionShort: obj.ObjectMetadata.Functionality.DescriptionShort,
+				MatchType:        "tag",
+				Source:           obj.source(),
+			},
+			overlap: overlap,
+		})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].overlap != matches[j].overlap {
+			return matches[i].overlap > matches[j].overlap
+		}
+		return matches[i].result.ObjectID < matches[j].result.ObjectID
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	results := make([]SearchResult, len(matches))
+	for i, match := range matches {
+		results[i] = match.result
+	}
+	return results
+}
+
+// SearchByTag returns the valid objects carrying tag, in index order, looked
+// up in the inverted tag index. tag is normalized the way object tags are
+// (see normalizeTags), so "Sensors" finds objects tagged "sensor".
+func (m *Manager) SearchByTag(tag string, limit int) ([]SearchResult, error) {
+	tagIndex, _, err := m.getTagIndex()
+	if err != nil {
+		return nil, err
+	}
+	return m.tagResults(tagIndex[normalizeTag(tag)], limit), nil
+}
+
+// SearchByTagPrefix returns the valid objects carrying any tag that starts with
+// prefix (normalized like a tag), and the matching tags in order. Each object
+// is listed once, in index order.
+func (m *Manager) SearchByTagPrefix(prefix string, limit int) ([]SearchResult, []string, error) {
+	tagIndex, sortedTags, err := m.getTagIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A plural prefix ("sensors") still finds its singular tags
+	prefix = normalizeTag(prefix)
+	var tags []string
+	for i := sort.SearchStrings(sortedTags, prefix); i < len(sortedTags) && strings.HasPrefix(sortedTags[i], prefix); i++ {
+		tags = append(tags, sortedTags[i])
+	}
+
+	matched := make(map[string]bool)
+	for _, tag := range tags {
+		for _, objID := range tagIndex[tag] {
+			matched[objID] = true
+		}
+	}
+	var objectIDs []string
+	for _, objID := range m.GetObjectIDs() {
+		if matched[objID] {
+			objectIDs = append(objectIDs, objID)
+		}
+	}
+	return m.tagResults(objectIDs, limit), tags, nil
+}
+
+// tagResults turns tag index object IDs into search results, up to limit.
+func (m *Manager) tagResults(objectIDs []string, limit int) []SearchResult {
+	results := []SearchResult{}
+	for _, objID := range objectIDs {
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+		obj, err := m.GetObject(objID)
+		if err != nil {
+			continue
+		}
+		results = append(results, newSearchResult(obj, "tag"))
+	}
+	return results
+}
+
+// getTagIndex returns the inverted tag index and its sorted tags, building
+// them on first use after each index load. Like the category index, building
+// loads every object once; after p2kb_obex_build_index that is all from memory.
+func (m *Manager) getTagIndex() (map[string][]string, []string, error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, nil, err
+	}
+
+	m.mu.RLock()
+	tagIndex, sortedTags := m.tagIndex, m.sortedTags
+	m.mu.RUnlock()
+	if tagIndex != nil {
+		return tagIndex, sortedTags, nil
+	}
+
+	// One build at a time; later callers pick up the finished index
+	m.tagMu.Lock()
+	defer m.tagMu.Unlock()
+
+	m.mu.RLock()
+	tagIndex, sortedTags = m.tagIndex, m.sortedTags
+	generation := m.indexGeneration
+	objectIDs := make([]string, len(m.objectIDs))
+	copy(objectIDs, m.objectIDs)
+	m.mu.RUnlock()
+	if tagIndex != nil {
+		return tagIndex, sortedTags, nil
+	}
+
+	// Fetch objects WITHOUT holding the data lock
+	tagIndex, complete := m.buildTagIndex(objectIDs)
+	sortedTags = make([]string, 0, len(tagIndex))
+	for tag := range tagIndex {
+		sortedTags = append(sortedTags, tag)
+	}
+	sort.Strings(sortedTags)
+
+	// Keep the index only if every object loaded and the ID list is unchanged
+	if complete {
+		m.mu.Lock()
+		if m.indexGeneration == generation {
+			m.tagIndex = tagIndex
+			m.sortedTags = sortedTags
+		}
+		m.mu.Unlock()
+	}
+	return tagIndex, sortedTags, nil
+}
+
+// buildTagIndex maps each normalized tag to the IDs of the valid objects
+// carrying it, in objectIDs order. complete is false if any object failed to
+// load.
+func (m *Manager) buildTagIndex(objectIDs []string) (tagIndex map[string][]string, complete bool) {
+	tagIndex = make(map[string][]string)
+	complete = true
+	for _, objID := range objectIDs {
+		obj, err := m.GetObject(objID)
+		if err != nil {
+			complete = false
+			continue
+		}
+		if ValidateObject(obj) != nil {
+			continue
+		}
+		tags := obj.normalizedTags
+		if tags == nil {
+			tags = normalizeTags(obj.ObjectMetadata.Functionality.Tags)
+		}
+		for _, tag := range tags {
+			tagIndex[tag] = append(tagIndex[tag], objID)
+		}
+	}
+	return tagIndex, complete
+}
+
+// GetAuthors returns the authors of the valid objects sorted by object
+// count.
+func (m *Manager) GetAuthors() ([]AuthorStats, error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, err
+	}
+
+	authorCounts := make(map[string]int)
+	objectIDs := m.GetObjectIDs()
+
+	m.forEachObject(objectIDs, func(_ string, obj *OBEXObject, err error) bool {
+		// ValidateObject requires an author, so every counted object has one
+		if err != nil || ValidateObject(obj) != nil {
+			return true
+		}
+
+		authorCounts[obj.ObjectMetadata.Author]++
+		return true
+	})
+
+	// Convert to slice and sort
+	authors := make([]AuthorStats, 0, len(authorCounts))
+	for name, count := range authorCounts {
+		authors = append(authors, AuthorStats{
+			Name:        name,
+			ObjectCount: count,
+		})
+	}
+
+	sort.Slice(authors, func(i, j int) bool {
+		return authors[i].ObjectCount > authors[j].ObjectCount
+	})
+
+	return authors, nil
+}
+
+// MatchAuthors returns the distinct author names equal to name
+// (case-insensitive) or, if none are, containing it, with their valid object
+// counts, most prolific first.
+func (m *Manager) MatchAuthors(name string) ([]AuthorStats, error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, err
+	}
+
+	nameLower := strings.ToLower(strings.TrimSpace(name))
+	exact := make(map[string]int)
+	partial := make(map[string]int)
+
+	for _, objID := range m.GetObjectIDs() {
+		obj, err := m.GetObject(objID)
+		if err != nil || ValidateObject(obj) != nil {
+			continue
+		}
+
+		author := obj.ObjectMetadata.Author
+		authorLower := strings.ToLower(author)
+		if authorLower == nameLower {
+			exact[author]++
+		} else if strings.Contains(authorLower, nameLower) {
+			partial[author]++
+		}
+	}
+
+	counts := exact
+	if len(counts) == 0 {
+		counts = partial
+	}
+	authors := make([]AuthorStats, 0, len(counts))
+	for author, count := range counts {
+		authors = append(authors, AuthorStats{Name: author, ObjectCount: count})
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].ObjectCount != authors[j].ObjectCount {
+			return authors[i].ObjectCount > authors[j].ObjectCount
+		}
+		return authors[i].Name < authors[j].Name
+	})
+
+	return authors, nil
+}
+
+// authorMatchThreshold is the AuthorMatchScore above which BrowseByAuthors
+// includes an object.
+const authorMatchThreshold = 0.5
+
+// BrowseByAuthor returns the valid objects whose author matches authorQuery
+// by AuthorMatchScore, best match first and in index order within a score.
+// Each result carries its MatchScore and MatchedAuthorName. A limit <= 0
+// returns every match.
+func (m *Manager) BrowseByAuthor(authorQuery string, limit int) ([]SearchResult, error) {
+	return m.BrowseByAuthors([]string{authorQuery}, limit)
+}
+
+// BrowseByAuthors is BrowseByAuthor for several author queries at once, for
+// objects by any of them. An object matching more than one query is returned
+// once, scored by its best match, with every query it matched in
+// MatchedQueries.
+func (m *Manager) BrowseByAuthors(authorQueries []string, limit int) ([]SearchResult, error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for _, objID := range m.GetObjectIDs() {
+		obj, err := m.GetObject(objID)
+		if err != nil || ValidateObject(obj) != nil {
+			continue
+		}
+		var best float64
+		var matched []string
+		for _, query := range authorQueries {
+			score := AuthorMatchScore(query, obj.ObjectMetadata.Author)
+			if score <= authorMatchThreshold {
+				continue
+			}
+			matched = append(matched, query)
+			best = math.Max(best, score)
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		result := newSearchResult(obj, "author")
+		result.MatchScore = best
+		result.MatchedAuthorName = obj.ObjectMetadata.Author
+		result.MatchedQueries = matched
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].MatchScore > results[j].MatchScore
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// AuthorMatchScore scores how well query names author, from 0 to 1, the way
+// the KB search scores keys: both are split into lowercase words on anything
+// that is not a letter or digit, so "Jon_McPhalen" and "Jon McPhalen
+// (ElectricAye)" are both "jon mcphalen ...". The score is the fraction of
+// query words that equal, or (from three letters) occur within, an author
+// word, plus 0.1 when a multi-word query matches entirely, capped at 1.
+func AuthorMatchScore(query, author string) float64 {
+	queryWords, authorWords := nameWords(query), nameWords(author)
+	if len(queryWords) == 0 || len(authorWords) == 0 {
+		return 0
+	}
+
+	matches := 0
+	for _, qw := range queryWords {
+		for _, aw := range authorWords {
+			if qw == aw || (len(qw) >= 3 && strings.Contains(aw, qw)) {
+				matches++
+				break
+			}
+		}
+	}
+
+	score := float64(matches) / float64(len(queryWords))
+	if matches == len(queryWords) && len(queryWords) > 1 {
+		score += 0.1
+	}
+	if score > 1.0 {
+		score = 1.0
+	}
+	return score
+}
+
+// nameWords splits a name into lowercase runs of letters and digits.
+func nameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// GetAuthorDetail builds the portfolio of the author named exactly author,
+// over the objects search and browse would show. It returns an error if the
+// author has no such objects.
+func (m *Manager) GetAuthorDetail(author string) (*AuthorDetail, error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, err
+	}
+
+	detail := &AuthorDetail{
+		Name:            author,
+		Categories:      make(map[string]int),
+		Tags:            []TagCount{},
+		Languages:       []string{},
+		GitHubUsernames: []string{},
+		Objects:         []AuthorObject{},
+	}
+	tagCounts := make(map[string]int)
+	languages := make(map[string]bool)
+	usernames := make(map[string]bool)
+	qualityTotal, qualityCount := 0, 0
+
+	for _, objID := range m.GetObjectIDs() {
+		obj, err := m.GetObject(objID)
+		if err != nil || ValidateObject(obj) != nil || obj.ObjectMetadata.Author != author {
+			continue
+		}
+		meta := obj.ObjectMetadata
+
+		detail.Objects = append(detail.Objects, AuthorObject{
+			ObjectID:     meta.ObjectID,
+			Title:        meta.Title,
+			Category:     meta.Functionality.Category,
+			QualityScore: meta.Metadata.QualityScore,
+		})
+		detail.Categories[meta.Functionality.Category]++
+
+		for _, tag := range normalizeTags(meta.Functionality.Tags) {
+			tagCounts[tag]++
+		}
+		for _, lang := range meta.TechnicalDetails.Languages {
+			if lang = strings.ToUpper(strings.TrimSpace(lang)); lang != "" {
+				languages[lang] = true
+			}
+		}
+		if username := strings.TrimSpace(meta.AuthorUsername); username != "" {
+			usernames[username] = true
+		}
+		if meta.Metadata.QualityScore > 0 {
+			qualityTotal += meta.Metadata.QualityScore
+			qualityCount++
+		}
+
+		// created_date is "YYYY-MM-DD hh:mm:ss", so string order is date order
+		if created := meta.Metadata.CreatedDate; created != "" {
+			if detail.EarliestCreated == "" || created < detail.EarliestCreated {
+				detail.EarliestCreated = created
+			}
+			if created > detail.LatestCreated {
+				detail.LatestCreated = created
+			}
+		}
+	}
+
+	if len(detail.Objects) == 0 {
+		return nil, fmt.Errorf("no OBEX objects by author: %s", author)
+	}
+
+	detail.ObjectCount = len(detail.Objects)
+	if qualityCount > 0 {
+		detail.AverageQuality = float64(qualityTotal) / float64(qualityCount)
+	}
+	for tag, count := range tagCounts {
+		detail.Tags = append(detail.Tags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(detail.Tags, func(i, j int) bool {
+		if detail.Tags[i].Count != detail.Tags[j].Count {
+			return detail.Tags[i].Count > detail.Tags[j].Count
+		}
+		return detail.Tags[i].Tag < detail.Tags[j].Tag
+	})
+	for lang := range languages {
+		detail.Languages = append(detail.Languages, lang)
+	}
+	sort.Strings(detail.Languages)
+	for username := range usernames {
+		detail.GitHubUsernames = append(detail.GitHubUsernames, username)
+	}
+	sort.Strings(detail.GitHubUsernames)
+
+	return detail, nil
+}
+
+// TagCloudSize and TagPairCloudSize cap the tags and tag pairs GetTagCloud returns.
+const (
+	TagCloudSize     = 50
+	TagPairCloudSize = 20
+)
+
+// TagCloud is the most common normalized tags across every valid OBEX object,
+// and the tag pairs that most often appear on the same object.
+type TagCloud struct {
+	Tags         []TagCount     `json:"tags"`
+	TagPairs     []TagPairCount `json:"tag_pairs"`
+	TotalObjects int            `json:"total_objects"`
+}
+
+// TagPairCount is how many objects carry both tags of a pair. Pair is the two
+// tags in alphabetical order joined by "+", e.g. "motor+servo".
+type TagPairCount struct {
+	Pair  string `json:"pair"`
+	Count int    `json:"count"`
+}
+
+// GetTagCloud returns the TagCloudSize most common tags and TagPairCloudSize
+// most common tag pairs, by count then alphabetically. Tags are normalized
+// (see normalizeTags). The result is reused until the object list changes.
+func (m *Manager) GetTagCloud() (*TagCloud, error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	cloud, generation := m.tagCloud, m.indexGeneration
+	fresh := cloud != nil && m.tagCloudGeneration == generation
+	objectIDs := make([]string, len(m.objectIDs))
+	copy(objectIDs, m.objectIDs)
+	m.mu.RUnlock()
+	if fresh {
+		return cloud, nil
+	}
+
+	// Fetch objects WITHOUT holding the data lock
+	cloud, complete := m.computeTagCloud(objectIDs)
+
+	// Keep the result only if every object loaded
+	if complete {
+		m.mu.Lock()
+		m.tagCloud = cloud
+		m.tagCloudGeneration = generation
+		m.mu.Unlock()
+	}
+	return cloud, nil
+}
+
+// computeTagCloud counts tags and tag pairs over objectIDs. complete is false
+// if any object failed to load.
+func (m *Manager) computeTagCloud(objectIDs []string) (cloud *TagCloud, complete bool) {
+	cloud = &TagCloud{}
+	complete = true
+
+	tagCounts := make(map[string]int)
+	pairCounts := make(map[string]int)
+	for _, objID := range objectIDs {
+		obj, err := m.GetObject(objID)
+		if err != nil {
+			complete = false
+			continue
+		}
+		if ValidateObject(obj) != nil {
+			continue
+		}
+		cloud.TotalObjects++
+
+		tags := normalizeTags(obj.ObjectMetadata.Functionality.Tags)
+		sort.Strings(tags)
+		for i, tag := range tags {
+			tagCounts[tag]++
+			for _, other := range tags[i+1:] {
+				pairCounts[tag+"+"+other]++
+			}
+		}
+	}
+
+	cloud.Tags = make([]TagCount, 0, len(tagCounts))
+	for tag, count := range tagCounts {
+		cloud.Tags = append(cloud.Tags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(cloud.Tags, func(i, j int) bool {
+		if cloud.Tags[i].Count != cloud.Tags[j].Count {
+			return cloud.Tags[i].Count > cloud.Tags[j].Count
+		}
+		return cloud.Tags[i].Tag < cloud.Tags[j].Tag
+	})
+	if len(cloud.Tags) > TagCloudSize {
+		cloud.Tags = cloud.Tags[:TagCloudSize]
+	}
+
+	cloud.TagPairs = make([]TagPairCount, 0, len(pairCounts))
+	for pair, count := range pairCounts {
+		cloud.TagPairs = append(cloud.TagPairs, TagPairCount{Pair: pair, Count: count})
+	}
+	sort.Slice(cloud.TagPairs, func(i, j int) bool {
+		if cloud.TagPairs[i].Count != cloud.TagPairs[j].Count {
+			return cloud.TagPairs[i].Count > cloud.TagPairs[j].Count
+		}
+		return cloud.TagPairs[i].Pair < cloud.TagPairs[j].Pair
+	})
+	if len(cloud.TagPairs) > TagPairCloudSize {
+		cloud.TagPairs = cloud.TagPairs[:TagPairCloudSize]
+	}
+
+	return cloud, complete
+}
+
+// CorpusStatsTTL is how long GetCorpusStats reuses a computed result.
+const CorpusStatsTTL = 5 * time.Minute
+
+// CorpusStats aggregates every valid OBEX object.
+type CorpusStats struct {
+	TotalObjects       int            `json:"total_objects"`
+	ByLanguage         map[string]int `json:"by_language"`
+	ByCategory         map[string]int `json:"by_category"`
+	QualityStats       QualityStats   `json:"quality_stats"`
+	WithGitHubRepoPct  float64        `json:"with_github_repo_pct"`
+	WithForumLinkPct   float64        `json:"with_forum_link_pct"`
+	CompletenessAvgPct float64        `json:"completeness_avg_pct"`
+	ExtractionStatuses map[string]int `json:"extraction_status_breakdown"`
+	ComputedAt         time.Time      `json:"computed_at"`
+}
+
+// QualityStats summarizes quality scores over the objects that have one.
+type QualityStats struct {
+	ScoredObjects int     `json:"scored_objects"`
+	Min           int     `json:"min"`
+	Max           int     `json:"max"`
+	Avg           float64 `json:"avg"`
+	Median        float64 `json:"median"`
+	P90           int     `json:"p90"`
+}
+
+// GetCorpusStats returns statistics over every valid OBEX object, computed in
+// one pass and reused for CorpusStatsTTL while the object list is unchanged.
+func (m *Manager) GetCorpusStats() (*CorpusStats, error) {
+	if err := m.EnsureIndex(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	stats, generation := m.corpusStats, m.indexGeneration
+	fresh := stats != nil && m.corpusStatsGeneration == generation && time.Since(stats.ComputedAt) < CorpusStatsTTL
+	objectIDs := make([]string, len(m.objectIDs))
+	copy(objectIDs, m.objectIDs)
+	m.mu.RUnlock()
+	if fresh {
+		return stats, nil
+	}
+
+	// Fetch objects WITHOUT holding the data lock
+	stats, complete := m.computeCorpusStats(objectIDs)
+
+	// Keep the result only if every object loaded
+	if complete {
+		m.mu.Lock()
+		m.corpusStats = stats
+		m.corpusStatsGeneration = generation
+		m.mu.Unlock()
+	}
+	return stats, nil
+}
+
+// computeCorpusStats makes a single pass over objectIDs. complete is false if
+// any object failed to load.
+func (m *Manager) computeCorpusStats(objectIDs []string) (stats *CorpusStats, complete bool) {
+	stats = &CorpusStats{
+		ByLanguage:         make(map[string]int),
+		ByCategory:         make(map[string]int),
+		ExtractionStatuses: make(map[string]int),
+		ComputedAt:         time.Now(),
+	}
+	complete = true
+
+	var scores []int
+	var withGitHub, withForum int
+	var completenessTotal float64
+	for _, objID := range objectIDs {
+		obj, err := m.GetObject(objID)
+		if err != nil {
+			complete = false
+			continue
+		}
+		if ValidateObject(obj) != nil {
+			continue
+		}
+		meta := obj.ObjectMetadata
+		stats.TotalObjects++
+
+		for _, lang := range meta.TechnicalDetails.Languages {
+			if lang = strings.ToUpper(strings.TrimSpace(lang)); lang != "" {
+				stats.ByLanguage[lang]++
+			}
+		}
+		stats.ByCategory[strings.ToLower(meta.Functionality.Category)]++
+
+		status := strings.TrimSpace(meta.Metadata.ExtractionStatus)
+		if status == "" {
+			status = "unspecified"
+		}
+		stats.ExtractionStatuses[status]++
+
+		if meta.Metadata.QualityScore > 0 {
+			scores = append(scores, meta.Metadata.QualityScore)
+		}
+		if meta.URLs.GithubRepo != "" {
+			withGitHub++
+		}
+		if meta.URLs.ForumDiscussion != "" {
+			withForum++
+		}
+		completenessTotal += objectCompleteness(&meta)
+	}
+
+	if stats.TotalObjects > 0 {
+		total := float64(stats.TotalObjects)
+		stats.WithGitHubRepoPct = roundPct(float64(withGitHub) / total)
+		stats.WithForumLinkPct = roundPct(float64(withForum) / total)
+		stats.CompletenessAvgPct = roundPct(completenessTotal / total)
+	}
+	stats.QualityStats = summarizeQuality(scores)
+
+	return stats, complete
+}
+
+// objectCompleteness returns the fraction of meta's optional fields that are
+// populated. The fields ValidateObject requires are not counted.
+func objectCompleteness(meta *ObjectMetadata) float64 {
+	optional := []bool{
+		meta.AuthorUsername != "",
+		meta.URLs.OBEXPage != "",
+		meta.URLs.DownloadDirect != "",
+		meta.URLs.ForumDiscussion != "",
+		meta.URLs.GithubRepo != "",
+		meta.URLs.Documentation != "",
+		len(meta.TechnicalDetails.Languages) > 0,
+		len(meta.TechnicalDetails.Microcontroller) > 0,
+		meta.TechnicalDetails.Version != "",
+		meta.TechnicalDetails.FileFormat != "",
+		meta.TechnicalDetails.FileSize != "",
+		meta.Functionality.Subcategory != "",
+		meta.Functionality.DescriptionShort != "",
+		meta.Functionality.DescriptionFull != "",
+		len(meta.Functionality.Tags) > 0,
+		len(meta.Functionality.HardwareSupport) > 0,
+		len(meta.Functionality.Peripherals) > 0,
+		meta.Metadata.DiscoveryDate != "",
+		meta.Metadata.LastVerified != "",
+		meta.Metadata.ExtractionStatus != "",
+		meta.Metadata.QualityScore > 0,
+		meta.Metadata.CreatedDate != "",
+	}
+
+	populated := 0
+	for _, ok := range optional {
+		if ok {
+			populated++
+		}
+	}
+	return float64(populated) / float64(len(optional))
+}
+
+// summarizeQuality computes min, max, mean, median and 90th percentile
+// (nearest rank) of scores.
+func summarizeQuality(scores []int) QualityStats {
+	q := QualityStats{ScoredObjects: len(scores)}
+	if len(scores) == 0 {
+		return q
+	}
+
+	sorted := append([]int(nil), scores...)
+	sort.Ints(sorted)
+	n := len(sorted)
+
+	total := 0
+	for _, s := range sorted {
+		total += s
+	}
+	q.Min = sorted[0]
+	q.Max = sorted[n-1]
+	q.Avg = float64(total) / float64(n)
+	if n%2 == 1 {
+		q.Median = float64(sorted[n/2])
+	} else {
+		q.Median = float64(sorted[n/2-1]+sorted[n/2]) / 2
+	}
+	q.P90 = sorted[(9*n+9)/10-1]
+	return q
+}
+
+// roundPct converts a fraction to a percentage rounded to one decimal place.
+func roundPct(fraction float64) float64 {
+	return math.Round(fraction*1000) / 10
+}
+
+// IsIndexLoaded reports whether the OBEX object list is in memory, without
+// loading it.
+func (m *Manager) IsIndexLoaded() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.objectIDs != nil
+}
+
+// GetTotalObjects returns the total number of OBEX objects.
+func (m *Manager) GetTotalObjects() int {
+	if err := m.EnsureIndex(); err != nil {
+		return 0
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.objectIDs)
+}
+
+// LoadAllObjects loads every indexed object into memory so search and browse
+// never wait on a fetch. Objects are loaded batchSize at a time, each fetch
+// taking a P2KB_OBEX_CONCURRENCY slot, with pause between batches. progress,
+// if non-nil, is called after each batch with the running counts. Objects that
+// fail to load are counted and skipped. Cancelling ctx stops the load after
+// the current batch and returns ctx.Err().
+func (m *Manager) LoadAllObjects(ctx context.Context, batchSize int, pause time.Duration, progress func(loaded, failed int)) error {
+	if err := m.EnsureIndex(); err != nil {
+		return err
+	}
+	if batchSize <= 0 {
+		batchSize = LoadAllBatchSize
+	}
+
+	objectIDs := m.GetObjectIDs()
+	var loaded, failed atomic.Int64
+
+	for start := 0; start < len(objectIDs); start += batchSize {
+		if start > 0 && pause > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pause):
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := min(start+batchSize, len(objectIDs))
+		var wg sync.WaitGroup
+		for _, objID := range objectIDs[start:end] {
+			wg.Add(1)
+			go func(objID string) {
+				defer wg.Done()
+				if _, err := m.GetObject(objID); err != nil {
+					failed.Add(1)
+					return
+				}
+				loaded.Add(1)
+			}(objID)
+		}
+		wg.Wait()
+
+		if progress != nil {
+			progress(int(loaded.Load()), int(failed.Load()))
+		}
+	}
+
+	// Every object is in memory now, so the tag index builds without fetching
+	if failed.Load() == 0 {
+		_, _, _ = m.getTagIndex()
+	}
+	return nil
+}
+
+// IndexCoverage returns how many indexed objects are held in memory, out of
+// the total in the index.
+func (m *Manager) IndexCoverage() (loaded, total int) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return len(m.objectIDs)
+
+	for _, objID := range m.objectIDs {
+		if _, ok := m.objects[objID]; ok || m.localObjects[objID] != nil {
+			loaded++
+		}
+	}
+	return loaded, len(m.objectIDs)
 }
 
 // GetDownloadURL returns the download URL for an object.
 func (m *Manager) GetDownloadURL(objectID string) string {
 	objectID = normalizeObjectID(objectID)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if url, ok := m.downloadURLs[objectID]; ok {
+		return url
+	}
 	return OBEXDownloadBase + objectID
 }
 
+// SetDownloadURL replaces the download URL GetDownloadURL returns for an
+// object, for a link found to have moved, and records it as the object's
+// download_direct URL. The replacement is kept in memory only. Callers may
+// still be reading the cached object, so it is replaced by an updated copy
+// rather than changed in place.
+func (m *Manager) SetDownloadURL(objectID, downloadURL string) error {
+	obj, err := m.GetObject(objectID)
+	if err != nil {
+		return err
+	}
+	objectID = normalizeObjectID(objectID)
+
+	updated := *obj
+	updated.ObjectMetadata.URLs.DownloadDirect = downloadURL
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.downloadURLs == nil {
+		m.downloadURLs = make(map[string]string)
+	}
+	m.downloadURLs[objectID] = downloadURL
+	if m.localObjects[objectID] == obj {
+		m.localObjects[objectID] = &updated
+	} else if m.objects[objectID] == obj {
+		m.objects[objectID] = &updated
+	}
+	return nil
+}
+
 // DownloadAndExtract downloads an OBEX object zip and extracts it to the target directory.
 // If targetDir is empty, it defaults to "./OBX/{object-slug}/".
 // Returns information about the extracted files.
@@ -509,12 +2168,12 @@ func (m *Manager) downloadZip(url string) ([]byte, error) {
 
 	resp, err := m.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, errs.Transport(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+		return nil, fmt.Errorf("download failed: %w", errs.HTTPStatus(resp, url))
 	}
 
 	return io.ReadAll(resp.Body)
@@ -562,6 +2221,59 @@ func extractZip(zipData []byte, targetDir string) ([]string, int64, error) {
 	return files, totalSize, nil
 }
 
+// ZipEntry is one file listed in an OBEX ZIP.
+type ZipEntry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// ZipPreview lists a ZIP's files and holds the start of the first one.
+type ZipPreview struct {
+	Files       []ZipEntry
+	PreviewFile string // Empty when the ZIP holds no files
+	Content     []byte // At most maxBytes of PreviewFile
+	Truncated   bool   // PreviewFile is longer than Content
+}
+
+// PreviewZip lists the files in zipData and reads up to maxBytes of the first
+// one, without extracting anything to disk. Directories are not listed.
+func PreviewZip(zipData []byte, maxBytes int) (*ZipPreview, error) {
+	reader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip file: %w", err)
+	}
+
+	preview := &ZipPreview{Files: []ZipEntry{}}
+	var first *zip.File
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		preview.Files = append(preview.Files, ZipEntry{Name: file.Name, Size: int64(file.UncompressedSize64)})
+		if first == nil {
+			first = file
+		}
+	}
+	if first == nil {
+		return preview, nil
+	}
+
+	rc, err := first.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip entry: %w", err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, int64(maxBytes)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip entry: %w", err)
+	}
+	preview.PreviewFile = first.Name
+	preview.Content = content
+	preview.Truncated = first.UncompressedSize64 > uint64(maxBytes)
+	return preview, nil
+}
+
 // extractFile extracts a single file from a zip archive.
 func extractFile(file *zip.File, destPath string) error {
 	rc, err := file.Open()
@@ -634,9 +2346,11 @@ func generateSlug(title string) string {
 	return strings.Trim(result.String(), "-")
 }
 
-// Refresh forces a refresh of the OBEX index and clears stale objects.
+// Refresh forces a refresh of the OBEX index and clears stale objects: those
+// the new index no longer lists, and those whose disk cache entry has expired.
+// Other objects stay in memory; newly listed ones load lazily on demand.
 // This method fetches fresh data from remote without holding locks during network I/O.
-func (m *Manager) Refresh() error {
+func (m *Manager) Refresh() (RefreshStats, error) {
 	// Use fetchMu to prevent concurrent fetches
 	m.fetchMu.Lock()
 	defer m.fetchMu.Unlock()
@@ -644,22 +2358,65 @@ func (m *Manager) Refresh() error {
 	// Fetch from GitHub API WITHOUT holding the data lock
 	objectIDs, err := m.fetchIndexData()
 	if err != nil {
-		return fmt.Errorf("OBEX index refresh failed: %w", err)
+		return RefreshStats{}, fmt.Errorf("OBEX index refresh failed: %w", err)
+	}
+	local := m.loadLocalObjects()
+	indexed := make(map[string]bool, len(objectIDs))
+	for _, id := range objectIDs {
+		indexed[id] = true
+	}
+
+	// Decide which memory objects to keep, checking their disk entries
+	// without holding the data lock
+	m.mu.RLock()
+	inMemory := make([]string, 0, len(m.objects))
+	for id := range m.objects {
+		inMemory = append(inMemory, id)
+	}
+	m.mu.RUnlock()
+
+	fresh := make(map[string]bool, len(inMemory))
+	for _, id := range inMemory {
+		fresh[id] = indexed[id] && m.diskCacheFresh(id)
 	}
 
 	// Update the index under write lock
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Clear memory cache
-	m.objects = make(map[string]*OBEXObject)
+	var stats RefreshStats
+	for id := range m.objects {
+		// Objects stored since the snapshot were just fetched, so only
+		// their presence in the new index matters
+		keep, checked := fresh[id]
+		if !checked {
+			keep = indexed[id]
+		}
+		if keep {
+			stats.Retained++
+			continue
+		}
+		delete(m.objects, id)
+		delete(m.objectAccess, id)
+		delete(m.objectLoadedAt, id)
+		stats.Evicted++
+	}
 
 	// Save to cache
 	m.saveIndexToCache(objectIDs)
 
-	m.objectIDs = objectIDs
+	m.localObjects = local
+	m.setObjectIDsLocked(objectIDs)
 	m.lastRefresh = time.Now()
-	return nil
+	return stats, nil
+}
+
+// diskCacheFresh reports whether objectID's disk cache entry exists and is
+// within its TTL.
+func (m *Manager) diskCacheFresh(objectID string) bool {
+	cachePath := filepath.Join(m.cacheDir, "obex", "objects", objectID+".yaml")
+	info, err := os.Stat(cachePath)
+	return err == nil && time.Since(info.ModTime()) <= m.cachedObjectTTL(cachePath)
 }
 
 // ClearCache clears all cached OBEX data.
@@ -669,7 +2426,11 @@ func (m *Manager) ClearCache() int {
 	m.mu.Lock()
 	count := len(m.objects)
 	m.objects = make(map[string]*OBEXObject)
-	m.objectIDs = nil
+	m.objectAccess = nil
+	m.objectLoadedAt = nil
+	m.localObjects = nil
+	m.downloadURLs = nil
+	m.setObjectIDsLocked(nil)
 	m.lastRefresh = time.Time{}
 	cacheDir := filepath.Join(m.cacheDir, "obex")
 	m.mu.Unlock() // Release lock BEFORE disk I/O
@@ -680,6 +2441,106 @@ func (m *Manager) ClearCache() int {
 	return count
 }
 
+// EvictMemoryObjects drops least recently used objects from the memory cache
+// until at most target remain; the disk cache is untouched, so evicted objects
+// reload from disk on their next get. Returns how many were evicted.
+func (m *Manager) EvictMemoryObjects(target int) int {
+	if target < 0 {
+		target = 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.objects))
+	for id := range m.objects {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return m.objectAccess[ids[i]] < m.objectAccess[ids[j]]
+	})
+
+	evicted := 0
+	for _, id := range ids {
+		if len(m.objects) <= target {
+			break
+		}
+		delete(m.objects, id)
+		delete(m.objectAccess, id)
+		delete(m.objectLoadedAt, id)
+		evicted++
+	}
+	return evicted
+}
+
+// touch marks a memory-cached object as just used, for LRU eviction.
+func (m *Manager) touch(objectID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[objectID]; ok {
+		m.stampAccessLocked(objectID)
+	}
+}
+
+// storeObjectLocked puts obj in the memory cache. Caller holds m.mu.
+func (m *Manager) storeObjectLocked(objectID string, obj *OBEXObject) {
+	m.objects[objectID] = obj
+	if m.objectLoadedAt == nil {
+		m.objectLoadedAt = make(map[string]time.Time)
+	}
+	m.objectLoadedAt[objectID] = time.Now()
+	m.stampAccessLocked(objectID)
+	m.notePageSlugLocked(objectID, obj)
+}
+
+// notePageSlugLocked remembers obj's OBEX page slug so SearchByOBEXPageURL
+// can skip the scan. Caller holds m.mu.
+func (m *Manager) notePageSlugLocked(objectID string, obj *OBEXObject) {
+	slug, ok := OBEXPageSlug(obj.ObjectMetadata.URLs.OBEXPage)
+	if !ok {
+		return
+	}
+	if m.pageSlugs == nil {
+		m.pageSlugs = make(map[string]string)
+	}
+	m.pageSlugs[slug] = objectID
+}
+
+// MemoryObject describes one object held in the memory cache.
+type MemoryObject struct {
+	ObjectID string    `json:"object_id"`
+	Title    string    `json:"title"`
+	Author   string    `json:"author"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// MemoryObjects lists the objects currently in the memory cache, by object ID.
+func (m *Manager) MemoryObjects() []MemoryObject {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	objects := make([]MemoryObject, 0, len(m.objects))
+	for id, obj := range m.objects {
+		objects = append(objects, MemoryObject{
+			ObjectID: id,
+			Title:    obj.ObjectMetadata.Title,
+			Author:   obj.ObjectMetadata.Author,
+			LoadedAt: m.objectLoadedAt[id],
+		})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].ObjectID < objects[j].ObjectID })
+	return objects
+}
+
+// stampAccessLocked records an access to objectID. Caller holds m.mu.
+func (m *Manager) stampAccessLocked(objectID string) {
+	if m.objectAccess == nil {
+		m.objectAccess = make(map[string]uint64)
+	}
+	m.accessClock++
+	m.objectAccess[objectID] = m.accessClock
+}
+
 // GetCacheStats returns OBEX cache statistics.
 func (m *Manager) GetCacheStats() (memoryCount, diskCount int, staleCount int) {
 	m.mu.RLock()
@@ -695,7 +2556,7 @@ func (m *Manager) GetCacheStats() (memoryCount, diskCount int, staleCount int) {
 
 				// Check if stale
 				info, err := entry.Info()
-				if err == nil && time.Since(info.ModTime()) > m.ttl {
+				if err == nil && time.Since(info.ModTime()) > m.cachedObjectTTL(filepath.Join(objectsDir, entry.Name())) {
 					staleCount++
 				}
 			}
@@ -720,15 +2581,29 @@ func (m *Manager) objectExists(objectID string) bool {
 	return false
 }
 
+// matchObject matches searchTerms against every searchable field of obj,
+// returning "title", "tag" or "description", or "" if nothing matches.
 func (m *Manager) matchObject(obj *OBEXObject, searchTerms []string) string {
+	if matchType := matchSummary(obj, searchTerms); matchType != "" {
+		return matchType
+	}
+	if matchesFullDescription(obj, searchTerms) {
+		return "description"
+	}
+	return ""
+}
+
+// matchSummary matches searchTerms against obj's title, tags and short
+// description, returning "title", "tag" or "description" for the first that
+// matches, or "" if none does.
+func matchSummary(obj *OBEXObject, searchTerms []string) string {
 	titleLower := strings.ToLower(obj.ObjectMetadata.Title)
 	descShortLower := strings.ToLower(obj.ObjectMetadata.Functionality.DescriptionShort)
-	descFullLower := strings.ToLower(obj.ObjectMetadata.Functionality.DescriptionFull)
 
-	// Check tags
-	tagsLower := make([]string, len(obj.ObjectMetadata.Functionality.Tags))
-	for i, tag := range obj.ObjectMetadata.Functionality.Tags {
-		tagsLower[i] = strings.ToLower(tag)
+	// Check tags, normalized so "Motors", "motor" and "MOTOR" compare equal
+	tagsLower := obj.normalizedTags
+	if tagsLower == nil {
+		tagsLower = normalizeTags(obj.ObjectMetadata.Functionality.Tags)
 	}
 
 	for _, term := range searchTerms {
@@ -749,7 +2624,7 @@ func (m *Manager) matchObject(obj *OBEXObject, searchTerms []string) string {
 
 	for _, term := range searchTerms {
 		// Description match
-		if strings.Contains(descShortLower, term) || strings.Contains(descFullLower, term) {
+		if strings.Contains(descShortLower, term) {
 			return "description"
 		}
 	}
@@ -757,6 +2632,18 @@ func (m *Manager) matchObject(obj *OBEXObject, searchTerms []string) string {
 	return ""
 }
 
+// matchesFullDescription reports whether any of searchTerms occurs in obj's
+// description_full.
+func matchesFullDescription(obj *OBEXObject, searchTerms []string) bool {
+	descFullLower := strings.ToLower(obj.ObjectMetadata.Functionality.DescriptionFull)
+	for _, term := range searchTerms {
+		if strings.Contains(descFullLower, term) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *Manager) loadIndexFromCache() bool {
 	indexPath := filepath.Join(m.cacheDir, "obex", "index.json")
 
@@ -779,7 +2666,7 @@ func (m *Manager) loadIndexFromCache() bool {
 		return false
 	}
 
-	m.objectIDs = objectIDs
+	m.setObjectIDsLocked(objectIDs)
 	m.lastRefresh = info.ModTime()
 	return true
 }
@@ -788,7 +2675,7 @@ func (m *Manager) loadIndexFromCache() bool {
 // This method does NOT modify any state - it only performs network I/O and parsing.
 // Caller is responsible for updating the index under appropriate locks.
 func (m *Manager) fetchIndexData() ([]string, error) {
-	url := fmt.Sprintf("%s/%s", GitHubAPIBase, OBEXPath)
+	url := IndexContentsURL
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -799,12 +2686,12 @@ func (m *Manager) fetchIndexData() ([]string, error) {
 
 	resp, err := m.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch OBEX index: %w", err)
+		return nil, fmt.Errorf("failed to fetch OBEX index: %w", errs.Transport(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch OBEX index: HTTP %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to fetch OBEX index: %w", errs.HTTPStatus(resp, url))
 	}
 
 	var entries []struct {
@@ -852,45 +2739,101 @@ func (m *Manager) fetchObject(objectID string) (*OBEXObject, error) {
 	// Try to load from disk cache first
 	obj, err := m.loadObjectFromCache(objectID)
 	if err == nil {
+		warnIfInvalid(objectID, obj)
 		m.mu.Lock()
-		m.objects[objectID] = obj
+		m.storeObjectLocked(objectID, obj)
 		m.mu.Unlock()
 		return obj, nil
 	}
-
-	// Fetch from GitHub
-	url := fmt.Sprintf("%s/%s/%s.yaml", GitHubRawBase, OBEXPath, objectID)
-
-	resp, err := m.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch OBEX object: %w", err)
+	if errors.Is(err, errCorruptedCache) {
+		m.evictCorruptedObject(objectID, err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OBEX object not found: %s (HTTP %d)", objectID, resp.StatusCode)
-	}
+	// Fetch from GitHub, bounded so bursts of gets cannot flood the remote
+	release := m.acquireFetchSlot()
+	defer release()
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := m.fetchObjectData(objectID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read OBEX object: %w", err)
+		return nil, err
 	}
 
-	obj = &OBEXObject{}
-	if err := yaml.Unmarshal(data, obj); err != nil {
+	obj, err = decodeObject(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse OBEX object: %w", err)
 	}
+	warnIfInvalid(objectID, obj)
 
 	// Cache to memory and disk
 	m.mu.Lock()
-	m.objects[objectID] = obj
+	m.storeObjectLocked(objectID, obj)
 	m.mu.Unlock()
 
-	m.saveObjectToCache(objectID, data)
+	m.saveObjectToCache(objectID, data, obj.ObjectMetadata.Metadata.QualityScore)
 
 	return obj, nil
 }
 
+// fetchObjectData downloads an object's YAML from ObjectsURL or, if that
+// fails for any reason but the object being gone, from the first mirror in
+// P2KB_OBEX_MIRROR_URLS that has it.
+func (m *Manager) fetchObjectData(objectID string) ([]byte, error) {
+	data, err := m.fetchObjectFrom(ObjectsURL, objectID)
+	var notFound *errs.ErrKeyNotFound
+	if err == nil || len(m.mirrorURLs) == 0 || errors.As(err, &notFound) {
+		return data, err
+	}
+
+	urls := make([]string, len(m.mirrorURLs))
+	for i, base := range m.mirrorURLs {
+		urls[i] = fmt.Sprintf("%s/%s.yaml", base, objectID)
+	}
+	mirrored, used, mirrorErr := fetch.NewClient(fetch.WithTimeout(m.httpClient.Timeout)).FetchFirstAvailable(context.Background(), urls)
+	if mirrorErr != nil {
+		slog.Warn("OBEX mirrors failed", "object_id", objectID, "error", mirrorErr)
+		return nil, err
+	}
+	slog.Info("OBEX object fetched from mirror", "object_id", objectID, "url", used)
+
+	m.mu.Lock()
+	m.lastMirrorUsed = used
+	m.mu.Unlock()
+	return mirrored, nil
+}
+
+// fetchObjectFrom downloads an object's YAML from under base.
+func (m *Manager) fetchObjectFrom(base, objectID string) ([]byte, error) {
+	url := fmt.Sprintf("%s/%s.yaml", base, objectID)
+
+	resp, err := m.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OBEX object: %w", errs.Transport(err))
+	}
+	defer resp.Body.Close()
+
+	// A 404 means the object is listed in the index but its file is gone
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %w", &errs.ErrKeyNotFound{Key: objectID}, errs.HTTPStatus(resp, url))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch OBEX object %s: %w", objectID, errs.HTTPStatus(resp, url))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OBEX object: %w", err)
+	}
+	return data, nil
+}
+
+// LastMirrorUsed returns the URL the most recent mirrored object fetch came
+// from, or "" if no object has needed a mirror.
+func (m *Manager) LastMirrorUsed() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastMirrorUsed
+}
+
 func (m *Manager) loadObjectFromCache(objectID string) (*OBEXObject, error) {
 	cachePath := filepath.Join(m.cacheDir, "obex", "objects", objectID+".yaml")
 
@@ -900,9 +2843,9 @@ func (m *Manager) loadObjectFromCache(objectID string) (*OBEXObject, error) {
 		return nil, err
 	}
 
-	// If file is older than TTL, treat as cache miss
-	if time.Since(info.ModTime()) > m.ttl {
-		return nil, fmt.Errorf("cache expired")
+	// If file is older than its TTL, treat as cache miss
+	if time.Since(info.ModTime()) > m.cachedObjectTTL(cachePath) {
+		return nil, &errs.ErrCacheExpired{Key: objectID}
 	}
 
 	data, err := os.ReadFile(cachePath)
@@ -910,22 +2853,194 @@ func (m *Manager) loadObjectFromCache(objectID string) (*OBEXObject, error) {
 		return nil, err
 	}
 
+	// An empty file is a write that never completed
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty file", errCorruptedCache)
+	}
+	obj, err := decodeObject(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorruptedCache, err)
+	}
+	return obj, nil
+}
+
+// errCorruptedCache marks a disk cache entry that exists but cannot be parsed,
+// as opposed to one that is missing or expired.
+var errCorruptedCache = errors.New("corrupted cache entry")
+
+// evictCorruptedObject deletes objectID's unreadable disk cache entry so the
+// re-fetched copy replaces it.
+func (m *Manager) evictCorruptedObject(objectID string, cause error) {
+	slog.Warn("corrupted cache entry, deleting and re-fetching", "key", objectID, "error", cause)
+	cachePath := filepath.Join(m.cacheDir, "obex", "objects", objectID+".yaml")
+	_ = os.Remove(cachePath)
+	_ = os.Remove(metaPath(cachePath))
+	m.corruptEvictions.Add(1)
+}
+
+// CorruptedCacheEvictions returns how many unreadable disk cache entries have
+// been deleted and re-fetched since startup.
+func (m *Manager) CorruptedCacheEvictions() int64 {
+	return m.corruptEvictions.Load()
+}
+
+// decodeObject parses an OBEX YAML document. Type mismatches in individual
+// fields (e.g. languages given as a scalar) are not fatal: yaml.v3 still fills
+// every other field, so the mismatches are kept as validation warnings.
+func decodeObject(data []byte) (*OBEXObject, error) {
 	obj := &OBEXObject{}
 	if err := yaml.Unmarshal(data, obj); err != nil {
-		return nil, err
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, err
+		}
+		obj.warnings = append(obj.warnings, typeErr.Errors...)
 	}
-
+	obj.normalizedTags = normalizeTags(obj.ObjectMetadata.Functionality.Tags)
+	obj.warnings = append(obj.warnings, validateOBEXObject(obj)...)
 	return obj, nil
 }
 
-func (m *Manager) saveObjectToCache(objectID string, data []byte) {
+// saveObjectToCache writes an object's YAML to the disk cache, with a .meta
+// sidecar recording the TTL its quality score earns, so the freshness check
+// need not parse the YAML.
+func (m *Manager) saveObjectToCache(objectID string, data []byte, qualityScore int) {
 	cacheDir := filepath.Join(m.cacheDir, "obex", "objects")
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		return
 	}
 
 	cachePath := filepath.Join(cacheDir, objectID+".yaml")
-	_ = os.WriteFile(cachePath, data, 0644)
+	if err := os.WriteFile(cachePath, data, 0644); err != nil {
+		return
+	}
+
+	ttl := objectTTL(m.ttl, qualityScore)
+	meta := fmt.Sprintf("%s: %d\n", cacheTTLHeader, int64(ttl/time.Second))
+	_ = os.WriteFile(metaPath(cachePath), []byte(meta), 0644)
+}
+
+// cacheTTLHeader names the TTL line, in seconds, of an object's .meta sidecar.
+const cacheTTLHeader = "X-Cache-TTL"
+
+// metaPath returns the .meta sidecar path for a cached object's YAML path.
+func metaPath(cachePath string) string {
+	return strings.TrimSuffix(cachePath, ".yaml") + ".meta"
+}
+
+// objectTTL returns how long a cached object with the given quality score
+// stays fresh: base scaled up by the score as a percentage (a 6 adds 60%),
+// with the high and low quality bands fixed. Unscored objects keep base.
+func objectTTL(base time.Duration, qualityScore int) time.Duration {
+	switch {
+	case qualityScore <= 0:
+		return base
+	case qualityScore >= HighQualityScore:
+		return MaxObjectTTL
+	case qualityScore < LowQualityScore:
+		return min(base, LowQualityTTL)
+	}
+	return min(time.Duration(float64(base)*(1+float64(qualityScore)/10)), MaxObjectTTL)
+}
+
+// cachedObjectTTL returns the TTL of the cached object at cachePath, from its
+// .meta sidecar. Objects cached before sidecars were written fall back to the
+// quality score in the YAML itself.
+func (m *Manager) cachedObjectTTL(cachePath string) time.Duration {
+	if meta, err := os.ReadFile(metaPath(cachePath)); err == nil {
+		name, value, ok := strings.Cut(strings.TrimSpace(string(meta)), ":")
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); ok && name == cacheTTLHeader && err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return m.ttl
+	}
+	obj, err := decodeObject(data)
+	if err != nil {
+		return m.ttl
+	}
+	return objectTTL(m.ttl, obj.ObjectMetadata.Metadata.QualityScore)
+}
+
+// ValidateObject checks the fields search and browse rely on: a numeric
+// object_id, a non-empty title and author, and a category from
+// KnownCategories. It returns a *ValidationError listing every problem found.
+func ValidateObject(obj *OBEXObject) error {
+	meta := obj.ObjectMetadata
+	var problems []string
+
+	if meta.ObjectID == "" {
+		problems = append(problems, "missing object_id")
+	} else if !objectIDPattern.MatchString(meta.ObjectID) {
+		problems = append(problems, fmt.Sprintf("object_id %q is not numeric", meta.ObjectID))
+	}
+	if strings.TrimSpace(meta.Title) == "" {
+		problems = append(problems, "missing title")
+	}
+	if strings.TrimSpace(meta.Author) == "" {
+		problems = append(problems, "missing author")
+	}
+	if !isKnownCategory(meta.Functionality.Category) {
+		problems = append(problems, fmt.Sprintf("unknown category %q", meta.Functionality.Category))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{ObjectID: meta.ObjectID, Problems: problems}
+}
+
+// isKnownCategory reports whether category appears in KnownCategories (case-insensitive).
+func isKnownCategory(category string) bool {
+	for _, known := range KnownCategories {
+		if strings.EqualFold(category, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// warnIfInvalid logs a malformed object once, when it is loaded into memory.
+func warnIfInvalid(objectID string, obj *OBEXObject) {
+	if err := ValidateObject(obj); err != nil {
+		slog.Warn("skipping malformed OBEX object", "object_id", objectID, "error", err)
+	}
+	if len(obj.warnings) > 0 {
+		slog.Warn("OBEX object has validation warnings", "object_id", objectID, "warnings", obj.warnings)
+	}
+}
+
+// validateOBEXObject checks the fields that get and search responses surface
+// but ValidateObject does not require: languages, quality score and the OBEX
+// page URL. Problems are returned as warnings rather than an error so the
+// object is still served.
+func validateOBEXObject(obj *OBEXObject) []string {
+	meta := obj.ObjectMetadata
+	var warnings []string
+
+	if len(meta.TechnicalDetails.Languages) == 0 {
+		warnings = append(warnings, "technical_details.languages is empty")
+	}
+	if meta.Metadata.QualityScore <= 0 {
+		warnings = append(warnings, fmt.Sprintf("metadata.quality_score %d is not positive", meta.Metadata.QualityScore))
+	}
+	if !isWebURL(meta.URLs.OBEXPage) {
+		warnings = append(warnings, fmt.Sprintf("urls.obex_page %q is not a valid URL", meta.URLs.OBEXPage))
+	}
+
+	return warnings
+}
+
+// isWebURL reports whether raw is an absolute http or https URL with a host.
+func isWebURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
 }
 
 func normalizeObjectID(objectID string) string {
@@ -938,7 +3053,73 @@ func normalizeObjectID(objectID string) string {
 	return strings.TrimSpace(objectID)
 }
 
+// tagAbbreviations maps tags to the equivalent names they should also match.
+var tagAbbreviations = map[string][]string{
+	"ws2812":  {"neopixel"},
+	"ws2812b": {"neopixel"},
+	"sk6812":  {"neopixel"},
+	"iic":     {"i2c"},
+	"twi":     {"i2c"},
+}
+
+// normalizeTags returns the deduplicated, normalized form of tags: each tag is
+// lowercased, singularized (see singularize), and followed by any known
+// equivalents from tagAbbreviations.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+
+	add := func(tag string) {
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		add(tag)
+		for _, alt := range tagAbbreviations[tag] {
+			add(alt)
+		}
+	}
+
+	return normalized
+}
+
+// normalizeTag lowercases, trims and singularizes one tag, without the
+// abbreviation expansion normalizeTags adds.
+func normalizeTag(tag string) string {
+	return singularize(strings.ToLower(strings.TrimSpace(tag)))
+}
+
+// singularize applies simple English plural rules to a lowercase word:
+// "ies" -> "y", "ves" -> "f", and a trailing "s" is dropped from words longer
+// than three letters (but not from "ss" endings like "class"). It is applied
+// to both tags and search terms, so imperfect stems still match each other.
+func singularize(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 4 && strings.HasSuffix(word, "ves"):
+		return word[:len(word)-3] + "f"
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// singularizeTerm singularizes each word of a (possibly multi-word) search term.
+func singularizeTerm(term string) string {
+	words := strings.Fields(term)
+	for i, w := range words {
+		words[i] = singularize(w)
+	}
+	return strings.Join(words, " ")
+}
+
 // expandSearchTerms expands a search term to include related terms.
+// The term is also normalized the same way as tags, so "motors" searches "motor".
 func expandSearchTerms(term string) []string {
 	expansions := map[string][]string{
 		"i2c":     {"i2c", "iic", "twi", "two-wire"},
@@ -953,9 +3134,16 @@ func expandSearchTerms(term string) []string {
 		"usb":     {"usb", "hid", "cdc"},
 		"sd":      {"sd", "sdcard", "fat", "filesystem"},
 		"wifi":    {"wifi", "wireless", "esp", "network"},
+
+		// Terms are matched lowercased, so "P2" and "Propeller 2" appear here lowercased
+		"propeller2": {"p2", "propeller 2"},
+		"p2":         {"p2", "propeller 2"},
 	}
 
 	terms := []string{term}
+	if normalized := singularizeTerm(term); normalized != term {
+		terms = append(terms, normalized)
+	}
 
 	// Check if term matches any expansion key
 	for key, expanded := range expansions {
@@ -987,3 +3175,34 @@ func expandSearchTerms(term string) []string {
 
 	return unique
 }
+
+// GitHubToken returns the GitHub token in P2KB_GITHUB_TOKEN, falling back to
+// GITHUB_TOKEN, or "" if neither is set.
+func GitHubToken() string {
+	if token := strings.TrimSpace(os.Getenv("P2KB_GITHUB_TOKEN")); token != "" {
+		return token
+	}
+	return strings.TrimSpace(os.Getenv("GITHUB_TOKEN"))
+}
+
+// getMirrorURLs returns the comma-separated base URLs in
+// P2KB_OBEX_MIRROR_URLS, without trailing slashes, or nil if it is unset.
+func getMirrorURLs() []string {
+	var urls []string
+	for _, u := range strings.Split(os.Getenv("P2KB_OBEX_MIRROR_URLS"), ",") {
+		if u = strings.TrimRight(strings.TrimSpace(u), "/"); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// getOBEXConcurrency returns the remote fetch concurrency from environment or default.
+func getOBEXConcurrency() int {
+	if v := os.Getenv("P2KB_OBEX_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultOBEXConcurrency
+}
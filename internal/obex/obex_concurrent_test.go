@@ -1,9 +1,20 @@
 package obex
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/testdata"
 )
 
 // TestConcurrentEnsureIndex tests the fix for the lock-during-network-I/O issue.
@@ -145,3 +156,331 @@ func TestConcurrentClearCache(t *testing.T) {
 	wg.Wait()
 	t.Log("Concurrent ClearCache calls completed without deadlock")
 }
+
+// TestConcurrentSetDownloadURL verifies that SetDownloadURL leaves an object
+// a caller already holds untouched while that caller reads it.
+func TestConcurrentSetDownloadURL(t *testing.T) {
+	m := newCategoryTestManager(t)
+	held, err := m.GetObject("2811")
+	if err != nil {
+		t.Fatalf("GetObject failed: %v", err)
+	}
+	before := held.ObjectMetadata.URLs.DownloadDirect
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = m.SetDownloadURL("2811", fmt.Sprintf("https://obex.example/files/%d.zip", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = held.ObjectMetadata.URLs.DownloadDirect
+		}
+	}()
+	wg.Wait()
+
+	if got := held.ObjectMetadata.URLs.DownloadDirect; got != before {
+		t.Errorf("held object's download_direct = %q, want it unchanged (%q)", got, before)
+	}
+	current, _ := m.GetObject("2811")
+	if got := current.ObjectMetadata.URLs.DownloadDirect; got != "https://obex.example/files/99.zip" {
+		t.Errorf("cached download_direct = %q, want the last URL set", got)
+	}
+}
+
+// stubObjectServer points ObjectsURL at a server that answers every object
+// request after delay, recording the peak number of requests in flight.
+func stubObjectServer(t *testing.T, delay time.Duration) (peak func() int32) {
+	t.Helper()
+	body := testdata.MustGetFixture("obexObjectValid.yaml")
+	var inFlight, maxInFlight int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			old := atomic.LoadInt32(&maxInFlight)
+			if n <= old || atomic.CompareAndSwapInt32(&maxInFlight, old, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		atomic.AddInt32(&inFlight, -1)
+		_, _ = w.Write(body)
+	}))
+
+	prev := ObjectsURL
+	ObjectsURL = srv.URL
+	t.Cleanup(func() {
+		ObjectsURL = prev
+		srv.Close()
+	})
+	return func() int32 { return atomic.LoadInt32(&maxInFlight) }
+}
+
+// TestFetchSemaphoreSerializesFetches verifies that concurrent GetObject calls
+// for uncached objects are limited to the semaphore size.
+func TestFetchSemaphoreSerializesFetches(t *testing.T) {
+	peak := stubObjectServer(t, 100*time.Millisecond)
+
+	ids := make([]string, 10)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%d", 1000+i)
+	}
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objects:     make(map[string]*OBEXObject),
+		objectIDs:   ids,
+		ttl:         1 * time.Hour,
+		lastRefresh: time.Now(),
+		httpClient:  &http.Client{Timeout: 5 * time.Second},
+		fetchSem:    make(chan struct{}, 3),
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if _, err := m.GetObject(id); err != nil {
+				t.Errorf("GetObject(%s) failed: %v", id, err)
+			}
+		}(id)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if elapsed < 300*time.Millisecond {
+		t.Errorf("10 fetches with a limit of 3 took %v, want >= 300ms", elapsed)
+	}
+	if got := peak(); got > 3 {
+		t.Errorf("peak concurrent fetches = %d, want <= 3", got)
+	}
+	if got := m.PendingFetches(); got != 0 {
+		t.Errorf("PendingFetches = %d after all fetches, want 0", got)
+	}
+}
+
+// TestFetchSemaphoreSkipsMemoryHits verifies that a memory-cached object is
+// served even while every fetch slot is taken.
+func TestFetchSemaphoreSkipsMemoryHits(t *testing.T) {
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objects:     map[string]*OBEXObject{"1234": {}},
+		objectIDs:   []string{"1234"},
+		ttl:         1 * time.Hour,
+		lastRefresh: time.Now(),
+		fetchSem:    make(chan struct{}, 1),
+	}
+	m.fetchSem <- struct{}{} // occupy the only slot
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := m.GetObject("1234"); err != nil {
+			t.Errorf("GetObject failed: %v", err)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("GetObject blocked on the fetch semaphore for a memory hit")
+	}
+	if m.ConcurrencyLimit() != 1 {
+		t.Errorf("ConcurrencyLimit = %d, want 1", m.ConcurrencyLimit())
+	}
+}
+
+// TestSetConcurrencyLimit verifies a new limit applies to the next fetch
+// while a slot held under the old one is still released to it.
+func TestSetConcurrencyLimit(t *testing.T) {
+	m := &Manager{fetchSem: make(chan struct{}, 1)}
+	releaseOld := m.acquireFetchSlot() // the only slot of the old limit
+
+	m.SetConcurrencyLimit(2)
+	if m.ConcurrencyLimit() != 2 {
+		t.Fatalf("ConcurrencyLimit = %d, want 2", m.ConcurrencyLimit())
+	}
+
+	acquired := make(chan func())
+	go func() { acquired <- m.acquireFetchSlot() }()
+	select {
+	case release := <-acquired:
+		release()
+	case <-time.After(time.Second):
+		t.Fatal("fetch blocked on the old limit after SetConcurrencyLimit")
+	}
+	releaseOld()
+	if got := m.PendingFetches(); got != 0 {
+		t.Errorf("PendingFetches = %d, want 0", got)
+	}
+
+	m.SetConcurrencyLimit(0)
+	if m.ConcurrencyLimit() != 0 {
+		t.Errorf("ConcurrencyLimit = %d, want 0 (unbounded)", m.ConcurrencyLimit())
+	}
+}
+
+// TestEvictMemoryObjectsLRU verifies eviction drops the least recently used
+// objects and leaves recently read ones in memory.
+func TestEvictMemoryObjectsLRU(t *testing.T) {
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objects:     make(map[string]*OBEXObject),
+		objectIDs:   []string{"1", "2", "3"},
+		ttl:         1 * time.Hour,
+		lastRefresh: time.Now(),
+	}
+	m.mu.Lock()
+	for _, id := range []string{"1", "2", "3"} {
+		m.objects[id] = &OBEXObject{}
+		m.stampAccessLocked(id)
+	}
+	m.mu.Unlock()
+
+	// Reading "1" makes it the most recently used.
+	if _, err := m.GetObject("1"); err != nil {
+		t.Fatalf("GetObject failed: %v", err)
+	}
+
+	if evicted := m.EvictMemoryObjects(1); evicted != 2 {
+		t.Fatalf("evicted = %d, want 2", evicted)
+	}
+	if _, ok := m.objects["1"]; !ok || len(m.objects) != 1 {
+		t.Errorf("objects after eviction = %v, want only 1", m.objects)
+	}
+	if evicted := m.EvictMemoryObjects(0); evicted != 1 || len(m.objects) != 0 {
+		t.Errorf("EvictMemoryObjects(0) evicted %d, left %d", evicted, len(m.objects))
+	}
+}
+
+// TestLoadAllObjects verifies that a bulk load fetches every object in
+// batches, stays within the fetch semaphore and reports full coverage.
+func TestLoadAllObjects(t *testing.T) {
+	peak := stubObjectServer(t, 20*time.Millisecond)
+
+	ids := make([]string, 25)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%d", 1000+i)
+	}
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objects:     make(map[string]*OBEXObject),
+		objectIDs:   ids,
+		ttl:         1 * time.Hour,
+		lastRefresh: time.Now(),
+		httpClient:  &http.Client{Timeout: 5 * time.Second},
+		fetchSem:    make(chan struct{}, 2),
+	}
+
+	if loaded, total := m.IndexCoverage(); loaded != 0 || total != 25 {
+		t.Fatalf("IndexCoverage() before = %d/%d, want 0/25", loaded, total)
+	}
+
+	var calls []int
+	err := m.LoadAllObjects(context.Background(), 10, time.Millisecond, func(loaded, failed int) {
+		calls = append(calls, loaded)
+		if failed != 0 {
+			t.Errorf("%d objects failed to load", failed)
+		}
+	})
+	if err != nil {
+		t.Fatalf("LoadAllObjects failed: %v", err)
+	}
+
+	if want := []int{10, 20, 25}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("progress calls = %v, want %v", calls, want)
+	}
+	if p := peak(); p > 2 {
+		t.Errorf("peak in-flight fetches = %d, want <= 2", p)
+	}
+	if loaded, total := m.IndexCoverage(); loaded != 25 || total != 25 {
+		t.Errorf("IndexCoverage() after = %d/%d, want 25/25", loaded, total)
+	}
+}
+
+// TestLoadAllObjectsCancel verifies that a cancelled load stops between batches.
+func TestLoadAllObjectsCancel(t *testing.T) {
+	stubObjectServer(t, 0)
+
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objects:     make(map[string]*OBEXObject),
+		objectIDs:   []string{"1000", "1001", "1002", "1003"},
+		ttl:         1 * time.Hour,
+		lastRefresh: time.Now(),
+		httpClient:  &http.Client{Timeout: 5 * time.Second},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := m.LoadAllObjects(ctx, 2, time.Hour, func(loaded, failed int) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("LoadAllObjects error = %v, want context.Canceled", err)
+	}
+	if loaded, _ := m.IndexCoverage(); loaded != 2 {
+		t.Errorf("loaded %d objects, want only the first batch of 2", loaded)
+	}
+}
+
+// TestCorruptedCacheEntryRefetched verifies that an unparseable disk cache
+// file is deleted and the object fetched again, while a missing file is a
+// plain cache miss.
+func TestCorruptedCacheEntryRefetched(t *testing.T) {
+	peak := stubObjectServer(t, 0)
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objects:     make(map[string]*OBEXObject),
+		objectIDs:   []string{"2811", "2812", "2813"},
+		ttl:         1 * time.Hour,
+		lastRefresh: time.Now(),
+		httpClient:  &http.Client{Timeout: 5 * time.Second},
+		fetchSem:    make(chan struct{}, 3),
+	}
+	objectsDir := filepath.Join(m.cacheDir, "obex", "objects")
+	if err := os.MkdirAll(objectsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for id, data := range map[string]string{
+		"2811": "object_metadata:\n  title: [unterminated\n", // bad YAML
+		"2812": "",                                           // partial write
+	} {
+		if err := os.WriteFile(filepath.Join(objectsDir, id+".yaml"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, id := range []string{"2811", "2812"} {
+		obj, err := m.GetObject(id)
+		if err != nil {
+			t.Fatalf("GetObject(%s) failed: %v", id, err)
+		}
+		if obj.ObjectMetadata.Title != "WS2812 LED Driver" {
+			t.Errorf("GetObject(%s) title = %q, want the re-fetched object", id, obj.ObjectMetadata.Title)
+		}
+	}
+	if peak() == 0 {
+		t.Error("corrupted entries were not re-fetched")
+	}
+	if got := m.CorruptedCacheEvictions(); got != 2 {
+		t.Errorf("CorruptedCacheEvictions() = %d, want 2", got)
+	}
+
+	// The re-fetched copy replaced the corrupted file
+	m.objects = make(map[string]*OBEXObject)
+	if _, err := m.loadObjectFromCache("2811"); err != nil {
+		t.Errorf("cache entry still unreadable after re-fetch: %v", err)
+	}
+
+	// A missing file is fetched without counting as corrupted
+	if _, err := m.GetObject("2813"); err != nil {
+		t.Fatalf("GetObject(2813) failed: %v", err)
+	}
+	if got := m.CorruptedCacheEvictions(); got != 2 {
+		t.Errorf("CorruptedCacheEvictions() = %d after a plain miss, want 2", got)
+	}
+}
@@ -3,12 +3,25 @@ package obex
 import (
 	"archive/zip"
 	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
+	"github.com/ironsheep/p2kb-mcp/internal/testdata"
+	"gopkg.in/yaml.v3"
 )
 
 func TestNewManager(t *testing.T) {
@@ -24,6 +37,54 @@ func TestNewManager(t *testing.T) {
 	}
 }
 
+// authRecorder stands in for http.DefaultTransport, answering every request
+// with an empty JSON list and recording the Authorization header it carried.
+type authRecorder struct {
+	got []string
+}
+
+func (r *authRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.got = append(r.got, req.Header.Get("Authorization"))
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader("[]")),
+		Request:    req,
+	}, nil
+}
+
+func TestNewManagerAuthorization(t *testing.T) {
+	// NewManager's client sends through the default transport, so replacing
+	// it sees the index listing request as it leaves for api.github.com
+	recorder := &authRecorder{}
+	saved := http.DefaultTransport
+	http.DefaultTransport = recorder
+	t.Cleanup(func() { http.DefaultTransport = saved })
+
+	tests := []struct {
+		p2kbToken, githubToken string
+		want                   string
+	}{
+		{" ghp_p2kb ", "ghp_generic", "Bearer ghp_p2kb"},
+		{"", "ghp_generic", "Bearer ghp_generic"},
+	}
+	for _, tt := range tests {
+		t.Setenv("P2KB_GITHUB_TOKEN", tt.p2kbToken)
+		t.Setenv("GITHUB_TOKEN", tt.githubToken)
+		m := NewManager()
+		if !m.AuthConfigured() {
+			t.Errorf("P2KB_GITHUB_TOKEN=%q: AuthConfigured() = false", tt.p2kbToken)
+		}
+		recorder.got = nil
+		if _, err := m.fetchIndexData(); err != nil {
+			t.Fatalf("fetchIndexData failed: %v", err)
+		}
+		if len(recorder.got) != 1 || recorder.got[0] != tt.want {
+			t.Errorf("P2KB_GITHUB_TOKEN=%q, GITHUB_TOKEN=%q: Authorization = %q, want %q", tt.p2kbToken, tt.githubToken, recorder.got, tt.want)
+		}
+	}
+}
+
 func TestNormalizeObjectID(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -126,6 +187,29 @@ func TestGetDownloadURL(t *testing.T) {
 	}
 }
 
+func TestSetDownloadURL(t *testing.T) {
+	m := newCategoryTestManager(t)
+	const moved = "https://obex.example/files/OB2811.zip"
+
+	if err := m.SetDownloadURL("OB2811", moved); err != nil {
+		t.Fatalf("SetDownloadURL failed: %v", err)
+	}
+	if got := m.GetDownloadURL("2811"); got != moved {
+		t.Errorf("GetDownloadURL(2811) = %q, want %q", got, moved)
+	}
+	if got := m.objects["2811"].ObjectMetadata.URLs.DownloadDirect; got != moved {
+		t.Errorf("download_direct = %q, want %q", got, moved)
+	}
+	if got := m.GetDownloadURL("2812"); got != OBEXDownloadBase+"2812" {
+		t.Errorf("GetDownloadURL(2812) = %q, want the default", got)
+	}
+
+	m.ClearCache()
+	if got := m.GetDownloadURL("2811"); got != OBEXDownloadBase+"2811" {
+		t.Errorf("GetDownloadURL(2811) after ClearCache = %q, want the default", got)
+	}
+}
+
 func TestSaveAndLoadIndexFromCache(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -156,6 +240,94 @@ func TestSaveAndLoadIndexFromCache(t *testing.T) {
 	}
 }
 
+func TestObjectTTL(t *testing.T) {
+	tests := []struct {
+		score int
+		want  time.Duration
+	}{
+		{0, DefaultOBEXTTL}, // unscored
+		{2, LowQualityTTL},  // draft
+		{3, LowQualityTTL},  // just below LowQualityScore
+		{4, 33*time.Hour + 36*time.Minute},
+		{7, 40*time.Hour + 48*time.Minute},
+		{8, MaxObjectTTL},
+		{10, MaxObjectTTL},
+	}
+	for _, tt := range tests {
+		if got := objectTTL(DefaultOBEXTTL, tt.score); got != tt.want {
+			t.Errorf("objectTTL(24h, %d) = %v, want %v", tt.score, got, tt.want)
+		}
+	}
+
+	// A long base TTL is still capped
+	if got := objectTTL(6*24*time.Hour, 7); got != MaxObjectTTL {
+		t.Errorf("objectTTL(6d, 7) = %v, want %v", got, MaxObjectTTL)
+	}
+}
+
+func TestObjectCacheTTLByQuality(t *testing.T) {
+	m := &Manager{
+		cacheDir: t.TempDir(),
+		objects:  make(map[string]*OBEXObject),
+		ttl:      DefaultOBEXTTL,
+	}
+	objectYAML := func(id string, score int) []byte {
+		return []byte(fmt.Sprintf("object_metadata:\n  object_id: %q\n  title: Test\n  author: Test\n  metadata:\n    quality_score: %d\n", id, score))
+	}
+	objectsDir := filepath.Join(m.cacheDir, "obex", "objects")
+	age := func(id string, d time.Duration) {
+		t.Helper()
+		when := time.Now().Add(-d)
+		if err := os.Chtimes(filepath.Join(objectsDir, id+".yaml"), when, when); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.saveObjectToCache("100", objectYAML("100", 9), 9) // high quality
+	m.saveObjectToCache("200", objectYAML("200", 2), 2) // draft
+	m.saveObjectToCache("300", objectYAML("300", 0), 0) // unscored
+
+	meta, err := os.ReadFile(filepath.Join(objectsDir, "100.meta"))
+	if err != nil || string(meta) != "X-Cache-TTL: 604800\n" {
+		t.Errorf("100.meta = %q, %v; want X-Cache-TTL: 604800", meta, err)
+	}
+
+	tests := []struct {
+		id        string
+		age       time.Duration
+		wantFresh bool
+	}{
+		{"100", 3 * 24 * time.Hour, true},
+		{"100", 8 * 24 * time.Hour, false},
+		{"200", 5 * time.Hour, true},
+		{"200", 12 * time.Hour, false},
+		{"300", 12 * time.Hour, true},
+		{"300", 3 * 24 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		age(tt.id, tt.age)
+		_, err := m.loadObjectFromCache(tt.id)
+		if fresh := err == nil; fresh != tt.wantFresh {
+			t.Errorf("object %s cached %v ago: fresh = %v, want %v (err %v)", tt.id, tt.age, fresh, tt.wantFresh, err)
+		}
+	}
+
+	// Objects cached without a sidecar take the TTL from their YAML
+	if err := os.Remove(filepath.Join(objectsDir, "100.meta")); err != nil {
+		t.Fatal(err)
+	}
+	age("100", 3*24*time.Hour)
+	if _, err := m.loadObjectFromCache("100"); err != nil {
+		t.Errorf("high quality object without sidecar expired after 3 days: %v", err)
+	}
+
+	age("200", 12*time.Hour)
+	age("300", time.Hour)
+	if _, _, stale := m.GetCacheStats(); stale != 1 {
+		t.Errorf("stale count = %d, want 1 (the draft)", stale)
+	}
+}
+
 func TestSaveAndLoadObjectFromCache(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -182,7 +354,7 @@ func TestSaveAndLoadObjectFromCache(t *testing.T) {
 `)
 
 	// Save to cache
-	m.saveObjectToCache("2811", testYAML)
+	m.saveObjectToCache("2811", testYAML, 0)
 
 	// Verify file exists
 	cachePath := filepath.Join(tmpDir, "obex", "objects", "2811.yaml")
@@ -695,3 +867,1986 @@ func TestGetObjectDoesNotTriggerRefreshInCooldown(t *testing.T) {
 		t.Error("GetObject should NOT trigger error refresh when in cooldown")
 	}
 }
+
+// Tests for OBEX object validation
+
+// loadFixtureObject parses one of the obexObject*.yaml fixtures.
+func loadFixtureObject(t *testing.T, name string) *OBEXObject {
+	t.Helper()
+	obj := &OBEXObject{}
+	if err := yaml.Unmarshal(testdata.MustGetFixture(name), obj); err != nil {
+		t.Fatalf("failed to parse fixture %s: %v", name, err)
+	}
+	return obj
+}
+
+func TestValidateObject(t *testing.T) {
+	tests := []struct {
+		fixture string
+		problem string // empty means the object must validate
+	}{
+		{"obexObjectValid.yaml", ""},
+		{"obexObjectNoID.yaml", "missing object_id"},
+		{"obexObjectNoTitle.yaml", "missing title"},
+		{"obexObjectNoAuthor.yaml", "missing author"},
+		{"obexObjectNoCategory.yaml", `unknown category ""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			err := ValidateObject(loadFixtureObject(t, tt.fixture))
+			if tt.problem == "" {
+				if err != nil {
+					t.Fatalf("ValidateObject() = %v, want nil", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateObject() = %v, want *ValidationError", err)
+			}
+			if len(verr.Problems) != 1 || verr.Problems[0] != tt.problem {
+				t.Errorf("Problems = %v, want [%s]", verr.Problems, tt.problem)
+			}
+		})
+	}
+}
+
+func TestValidateObjectNonNumericID(t *testing.T) {
+	obj := loadFixtureObject(t, "obexObjectValid.yaml")
+	obj.ObjectMetadata.ObjectID = "OB2811"
+
+	if err := ValidateObject(obj); err == nil {
+		t.Error("ValidateObject() should reject a non-numeric object_id")
+	}
+}
+
+func TestValidateOBEXObject(t *testing.T) {
+	complete := func() *OBEXObject {
+		obj := loadFixtureObject(t, "obexObjectValid.yaml")
+		obj.ObjectMetadata.Metadata.QualityScore = 8
+		obj.ObjectMetadata.URLs.OBEXPage = "https://obex.parallax.com/obex/ws2812/"
+		return obj
+	}
+
+	if warnings := validateOBEXObject(complete()); len(warnings) != 0 {
+		t.Fatalf("validateOBEXObject(complete) = %v, want none", warnings)
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(meta *ObjectMetadata)
+		warning string
+	}{
+		{"no languages", func(meta *ObjectMetadata) { meta.TechnicalDetails.Languages = nil }, "technical_details.languages is empty"},
+		{"zero quality", func(meta *ObjectMetadata) { meta.Metadata.QualityScore = 0 }, "metadata.quality_score 0 is not positive"},
+		{"relative page", func(meta *ObjectMetadata) { meta.URLs.OBEXPage = "obex/ws2812" }, `urls.obex_page "obex/ws2812" is not a valid URL`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := complete()
+			tt.mutate(&obj.ObjectMetadata)
+			warnings := validateOBEXObject(obj)
+			if len(warnings) != 1 || warnings[0] != tt.warning {
+				t.Errorf("warnings = %v, want [%s]", warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestWarnIfInvalidLogsObjectIDOnce(t *testing.T) {
+	var logs bytes.Buffer
+	prevLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prevLogger) })
+
+	data := bytes.Replace(testdata.MustGetFixture("obexObjectValid.yaml"), []byte(`"2811"`), []byte(`"OB2811"`), 1)
+	obj, err := decodeObject(data)
+	if err != nil {
+		t.Fatalf("decodeObject() error = %v", err)
+	}
+	if obj.ObjectMetadata.ObjectID != "OB2811" {
+		t.Fatalf("ObjectID = %q, want the fixture's id prefixed", obj.ObjectMetadata.ObjectID)
+	}
+
+	warnIfInvalid("OB2811", obj)
+	if n := strings.Count(logs.String(), "is not numeric"); n != 1 {
+		t.Errorf("non-numeric object_id logged %d times, want once:\n%s", n, logs.String())
+	}
+}
+
+func TestDecodeObjectKeepsTypeMismatchesAsWarnings(t *testing.T) {
+	obj, err := decodeObject(testdata.MustGetFixture("obexObjectLanguagesScalar.yaml"))
+	if err != nil {
+		t.Fatalf("decodeObject() error = %v, want the partial object", err)
+	}
+
+	if obj.ObjectMetadata.Title != "WS2812 LED Driver" {
+		t.Errorf("Title = %q, want fields after the mismatch to decode", obj.ObjectMetadata.Title)
+	}
+	if len(obj.normalizedTags) == 0 {
+		t.Error("normalizedTags not populated")
+	}
+
+	warnings := obj.ValidationWarnings()
+	joined := strings.Join(warnings, "\n")
+	for _, want := range []string{"cannot unmarshal", "technical_details.languages is empty", "is not a valid URL"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("warnings %v missing %q", warnings, want)
+		}
+	}
+}
+
+func TestDecodeObjectRejectsInvalidYAML(t *testing.T) {
+	if _, err := decodeObject([]byte("object_metadata: [unclosed")); err == nil {
+		t.Error("decodeObject() should fail on a syntax error")
+	}
+}
+
+func TestSearchAndBrowseSkipInvalidObjects(t *testing.T) {
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2811", "2812"},
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	m.objects["2811"] = loadFixtureObject(t, "obexObjectValid.yaml")
+	m.objects["2812"] = loadFixtureObject(t, "obexObjectNoAuthor.yaml")
+	m.objects["2812"].ObjectMetadata.ObjectID = "2812"
+
+	results, err := m.Search("ws2812", "", "", "", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ObjectID != "2811" {
+		t.Errorf("Search returned %v, want only 2811", results)
+	}
+
+	browsed, err := m.BrowseCategory("drivers")
+	if err != nil {
+		t.Fatalf("BrowseCategory failed: %v", err)
+	}
+	if len(browsed) != 1 || browsed[0].ObjectID != "2811" {
+		t.Errorf("BrowseCategory returned %v, want only 2811", browsed)
+	}
+
+	invalid, err := m.GetInvalidObjects()
+	if err != nil {
+		t.Fatalf("GetInvalidObjects failed: %v", err)
+	}
+	if len(invalid) != 1 || invalid[0].ObjectID != "2812" {
+		t.Errorf("GetInvalidObjects returned %v, want only 2812", invalid)
+	}
+}
+
+// Tests for the category index
+
+// newCategoryTestManager returns a manager with four in-memory objects: two
+// drivers, one sensor (mixed-case category) and one invalid driver.
+func newCategoryTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2811", "2812", "2813", "2814"},
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	for _, id := range m.objectIDs {
+		obj := loadFixtureObject(t, "obexObjectValid.yaml")
+		obj.ObjectMetadata.ObjectID = id
+		m.objects[id] = obj
+	}
+	m.objects["2812"].ObjectMetadata.Author = ""
+	m.objects["2813"].ObjectMetadata.Functionality.Category = "Sensors"
+	return m
+}
+
+func TestCategoryIndex(t *testing.T) {
+	m := newCategoryTestManager(t)
+
+	categoryIndex, err := m.getCategoryIndex()
+	if err != nil {
+		t.Fatalf("getCategoryIndex failed: %v", err)
+	}
+	want := map[string][]string{
+		"drivers": {"2811", "2814"},
+		"sensors": {"2813"},
+	}
+	if len(categoryIndex) != len(want) {
+		t.Fatalf("categoryIndex = %v, want %v", categoryIndex, want)
+	}
+	for cat, ids := range want {
+		if strings.Join(categoryIndex[cat], ",") != strings.Join(ids, ",") {
+			t.Errorf("categoryIndex[%q] = %v, want %v", cat, categoryIndex[cat], ids)
+		}
+	}
+	if m.categoryIndex == nil {
+		t.Error("complete category index was not kept")
+	}
+
+	if got := m.GetCategoryObjectCount("DRIVERS"); got != 2 {
+		t.Errorf("GetCategoryObjectCount(DRIVERS) = %d, want 2", got)
+	}
+	if got := m.GetCategoryObjectCount("motors"); got != 0 {
+		t.Errorf("GetCategoryObjectCount(motors) = %d, want 0", got)
+	}
+
+	counts, err := m.GetCategories()
+	if err != nil {
+		t.Fatalf("GetCategories failed: %v", err)
+	}
+	if counts["drivers"] != 2 || counts["sensors"] != 1 || len(counts) != 2 {
+		t.Errorf("GetCategories() = %v, want drivers:2 sensors:1", counts)
+	}
+
+	browsed, err := m.BrowseCategory("sensors")
+	if err != nil {
+		t.Fatalf("BrowseCategory failed: %v", err)
+	}
+	if len(browsed) != 1 || browsed[0].ObjectID != "2813" {
+		t.Errorf("BrowseCategory(sensors) = %v, want only 2813", browsed)
+	}
+}
+
+func TestCategoryIndexRebuiltWhenObjectIDsChange(t *testing.T) {
+	m := newCategoryTestManager(t)
+	if got := m.GetCategoryObjectCount("drivers"); got != 2 {
+		t.Fatalf("GetCategoryObjectCount(drivers) = %d, want 2", got)
+	}
+
+	m.mu.Lock()
+	m.setObjectIDsLocked([]string{"2811"})
+	m.mu.Unlock()
+	if m.categoryIndex != nil {
+		t.Fatal("replacing the object IDs should drop the category index")
+	}
+
+	if got := m.GetCategoryObjectCount("drivers"); got != 1 {
+		t.Errorf("GetCategoryObjectCount(drivers) after refresh = %d, want 1", got)
+	}
+}
+
+func TestCategoryIndexNotKeptWhenIncomplete(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	prev := ObjectsURL
+	ObjectsURL = srv.URL
+	defer func() { ObjectsURL = prev }()
+
+	m := newCategoryTestManager(t)
+	m.httpClient = srv.Client()
+	m.objectIDs = append(m.objectIDs, "9999")
+
+	if got := m.GetCategoryObjectCount("drivers"); got != 2 {
+		t.Errorf("GetCategoryObjectCount(drivers) = %d, want 2", got)
+	}
+	if m.categoryIndex != nil {
+		t.Error("category index kept although object 9999 failed to load")
+	}
+}
+
+// newSubcategoryTestManager returns a manager holding two "led" drivers
+// (2811, 2812), an "I2C" driver (2814) and a demo with no subcategory (2813).
+func newSubcategoryTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m := newMixedMicrocontrollerManager(t)
+	m.objectIDs = append(m.objectIDs, "2814")
+	m.objects["2814"] = loadFixtureObject(t, "obexObjectI2CDriver.yaml")
+	return m
+}
+
+func TestGetSubcategories(t *testing.T) {
+	m := newSubcategoryTestManager(t)
+
+	// Count the fixtures by hand to compare against the index
+	want := make(map[string]map[string]int)
+	for _, obj := range m.objects {
+		fn := obj.ObjectMetadata.Functionality
+		if fn.Subcategory == "" {
+			continue
+		}
+		cat := strings.ToLower(fn.Category)
+		if want[cat] == nil {
+			want[cat] = make(map[string]int)
+		}
+		want[cat][strings.ToLower(fn.Subcategory)]++
+	}
+	if !reflect.DeepEqual(want["drivers"], map[string]int{"led": 2, "i2c": 1}) {
+		t.Fatalf("fixture counts = %v, want led: 2, i2c: 1", want["drivers"])
+	}
+
+	for _, category := range []string{"drivers", "Drivers"} {
+		if got := m.GetSubcategories(category); !reflect.DeepEqual(got, want["drivers"]) {
+			t.Errorf("GetSubcategories(%q) = %v, want %v", category, got, want["drivers"])
+		}
+	}
+	if got := m.GetSubcategories("demos"); len(got) != 0 {
+		t.Errorf("GetSubcategories(demos) = %v, want none", got)
+	}
+	if m.subCategoryIndex == nil {
+		t.Error("subCategoryIndex should be kept once built")
+	}
+}
+
+func TestBrowseSubcategory(t *testing.T) {
+	m := newSubcategoryTestManager(t)
+
+	results, err := m.BrowseSubcategory("drivers", "i2c")
+	if err != nil {
+		t.Fatalf("BrowseSubcategory failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ObjectID != "2814" || results[0].Subcategory != "I2C" {
+		t.Errorf("BrowseSubcategory(drivers, i2c) = %+v, want only 2814", results)
+	}
+
+	results, err = m.BrowseSubcategory("DRIVERS", "LED")
+	if err != nil {
+		t.Fatalf("BrowseSubcategory failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ObjectID != "2811" || results[1].ObjectID != "2812" {
+		t.Errorf("BrowseSubcategory(DRIVERS, LED) = %+v, want 2811, 2812", results)
+	}
+
+	// A subcategory only exists within its own category
+	results, err = m.BrowseSubcategory("demos", "led")
+	if err != nil {
+		t.Fatalf("BrowseSubcategory failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("BrowseSubcategory(demos, led) = %+v, want none", results)
+	}
+}
+
+// Tests for corpus statistics
+
+// newCorpusStatsTestManager returns a manager holding three sparse drivers
+// (2811, 2812, 2814), a fully populated sensor (2815) and an invalid object
+// (2899) that statistics must ignore.
+func newCorpusStatsTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2811", "2812", "2814", "2815", "2899"},
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	m.objects["2811"] = loadFixtureObject(t, "obexObjectValid.yaml")
+	m.objects["2812"] = loadFixtureObject(t, "obexObjectP1.yaml")
+	m.objects["2814"] = loadFixtureObject(t, "obexObjectI2CDriver.yaml")
+	m.objects["2815"] = loadFixtureObject(t, "obexObjectComplete.yaml")
+	m.objects["2899"] = loadFixtureObject(t, "obexObjectNoAuthor.yaml")
+	return m
+}
+
+func TestGetCorpusStats(t *testing.T) {
+	m := newCorpusStatsTestManager(t)
+
+	stats, err := m.GetCorpusStats()
+	if err != nil {
+		t.Fatalf("GetCorpusStats failed: %v", err)
+	}
+
+	if stats.TotalObjects != 4 {
+		t.Errorf("TotalObjects = %d, want 4 (invalid object excluded)", stats.TotalObjects)
+	}
+	if want := map[string]int{"SPIN2": 3, "PASM2": 2, "SPIN": 1, "PASM": 1}; !reflect.DeepEqual(stats.ByLanguage, want) {
+		t.Errorf("ByLanguage = %v, want %v", stats.ByLanguage, want)
+	}
+	if want := map[string]int{"drivers": 3, "sensors": 1}; !reflect.DeepEqual(stats.ByCategory, want) {
+		t.Errorf("ByCategory = %v, want %v", stats.ByCategory, want)
+	}
+	if want := (QualityStats{ScoredObjects: 2, Min: 4, Max: 8, Avg: 6, Median: 6, P90: 8}); stats.QualityStats != want {
+		t.Errorf("QualityStats = %+v, want %+v", stats.QualityStats, want)
+	}
+	if stats.WithGitHubRepoPct != 25 || stats.WithForumLinkPct != 25 {
+		t.Errorf("github/forum pct = %v/%v, want 25/25", stats.WithGitHubRepoPct, stats.WithForumLinkPct)
+	}
+	// 5, 5, 6 and 22 of 22 optional fields: 38/88
+	if stats.CompletenessAvgPct != 43.2 {
+		t.Errorf("CompletenessAvgPct = %v, want 43.2", stats.CompletenessAvgPct)
+	}
+	if want := map[string]int{"complete": 1, "unspecified": 3}; !reflect.DeepEqual(stats.ExtractionStatuses, want) {
+		t.Errorf("ExtractionStatuses = %v, want %v", stats.ExtractionStatuses, want)
+	}
+	if stats.ComputedAt.IsZero() {
+		t.Error("ComputedAt not set")
+	}
+}
+
+func TestGetCorpusStatsCaching(t *testing.T) {
+	m := newCorpusStatsTestManager(t)
+
+	first, err := m.GetCorpusStats()
+	if err != nil {
+		t.Fatalf("GetCorpusStats failed: %v", err)
+	}
+	if again, _ := m.GetCorpusStats(); again != first {
+		t.Error("second call within the TTL should reuse the cached result")
+	}
+
+	// An expired result is recomputed
+	m.mu.Lock()
+	m.corpusStats.ComputedAt = time.Now().Add(-CorpusStatsTTL)
+	m.mu.Unlock()
+	expired, _ := m.GetCorpusStats()
+	if expired == first {
+		t.Error("expired result should be recomputed")
+	}
+
+	// So is one computed from a replaced object list
+	m.mu.Lock()
+	m.setObjectIDsLocked([]string{"2811"})
+	m.mu.Unlock()
+	replaced, _ := m.GetCorpusStats()
+	if replaced == expired || replaced.TotalObjects != 1 {
+		t.Errorf("after replacing the object list, TotalObjects = %d, want a fresh count of 1", replaced.TotalObjects)
+	}
+}
+
+func TestSummarizeQuality(t *testing.T) {
+	tests := []struct {
+		scores []int
+		want   QualityStats
+	}{
+		{nil, QualityStats{}},
+		{[]int{7}, QualityStats{ScoredObjects: 1, Min: 7, Max: 7, Avg: 7, Median: 7, P90: 7}},
+		{[]int{9, 1, 5}, QualityStats{ScoredObjects: 3, Min: 1, Max: 9, Avg: 5, Median: 5, P90: 9}},
+		{[]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, QualityStats{ScoredObjects: 10, Min: 1, Max: 10, Avg: 5.5, Median: 5.5, P90: 9}},
+	}
+
+	for _, tt := range tests {
+		if got := summarizeQuality(tt.scores); got != tt.want {
+			t.Errorf("summarizeQuality(%v) = %+v, want %+v", tt.scores, got, tt.want)
+		}
+	}
+}
+
+// Tests for author portfolios
+
+// newAuthorTestManager returns a manager whose objects are by two authors
+// sharing the surname "McPhalen", plus one invalid object by the first.
+func newAuthorTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2811", "2812", "2813", "2814"},
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	for _, id := range m.objectIDs {
+		obj := loadFixtureObject(t, "obexObjectValid.yaml")
+		obj.ObjectMetadata.ObjectID = id
+		m.objects[id] = obj
+	}
+
+	jon := &m.objects["2811"].ObjectMetadata
+	jon.AuthorUsername = "JonnyMac"
+	jon.Metadata.QualityScore = 8
+	jon.Metadata.CreatedDate = "2021-03-01 10:00:00"
+
+	jon = &m.objects["2812"].ObjectMetadata
+	jon.Title = "MAX7219 Display"
+	jon.Functionality.Category = "display"
+	jon.Functionality.Tags = []string{"LEDs", "max7219"}
+	jon.TechnicalDetails.Languages = []string{"spin2"}
+	jon.Metadata.QualityScore = 6
+	jon.Metadata.CreatedDate = "2020-07-15 09:30:00"
+
+	m.objects["2813"].ObjectMetadata.Author = "Jane McPhalen"
+	m.objects["2814"].ObjectMetadata.Functionality.Category = ""
+	return m
+}
+
+func TestMatchAuthors(t *testing.T) {
+	m := newAuthorTestManager(t)
+
+	tests := []struct {
+		name string
+		want []AuthorStats
+	}{
+		{"jon mcphalen", []AuthorStats{{Name: "Jon McPhalen", ObjectCount: 2}}},
+		{"jon", []AuthorStats{{Name: "Jon McPhalen", ObjectCount: 2}}},
+		{"McPhalen", []AuthorStats{{Name: "Jon McPhalen", ObjectCount: 2}, {Name: "Jane McPhalen", ObjectCount: 1}}},
+		{"nobody", []AuthorStats{}},
+	}
+
+	for _, tt := range tests {
+		got, err := m.MatchAuthors(tt.name)
+		if err != nil {
+			t.Fatalf("MatchAuthors(%q) failed: %v", tt.name, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("MatchAuthors(%q) = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("MatchAuthors(%q) = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestAuthorMatchScore(t *testing.T) {
+	tests := []struct {
+		query, author string
+		want          float64
+	}{
+		{"McPhalen", "Jon McPhalen", 1},
+		{"McPhalen", "Jon McPhalen (ElectricAye)", 1},
+		{"jon mcphalen", "Jon_McPhalen", 1},
+		{"phal", "Jon McPhalen", 1},
+		{"Jon Smith", "Jon McPhalen", 0.5},
+		{"ElectricAye", "Jon McPhalen", 0},
+		{"jo", "Jon McPhalen", 0},
+		{"", "Jon McPhalen", 0},
+	}
+	for _, tt := range tests {
+		if got := AuthorMatchScore(tt.query, tt.author); got != tt.want {
+			t.Errorf("AuthorMatchScore(%q, %q) = %v, want %v", tt.query, tt.author, got, tt.want)
+		}
+	}
+}
+
+func TestBrowseByAuthor(t *testing.T) {
+	m := newAuthorTestManager(t)
+	m.objects["2812"].ObjectMetadata.Author = "Jon McPhalen (ElectricAye)"
+	m.objects["2813"].ObjectMetadata.Author = "Jon_McPhalen"
+
+	results, err := m.BrowseByAuthor("McPhalen", 0)
+	if err != nil {
+		t.Fatalf("BrowseByAuthor failed: %v", err)
+	}
+	// 2814 is by Jon McPhalen too, but invalid
+	if got := resultIDs(results); !reflect.DeepEqual(got, []string{"2811", "2812", "2813"}) {
+		t.Fatalf("BrowseByAuthor(McPhalen) = %v, want [2811 2812 2813]", got)
+	}
+	for _, r := range results {
+		if r.MatchScore != 1 || r.MatchedAuthorName != m.objects[r.ObjectID].ObjectMetadata.Author {
+			t.Errorf("%s: score %v, author %q", r.ObjectID, r.MatchScore, r.MatchedAuthorName)
+		}
+	}
+
+	// Half a two-word name is not enough, and the limit applies
+	if results, _ := m.BrowseByAuthor("Jane McPhalen", 0); len(results) != 0 {
+		t.Errorf("BrowseByAuthor(Jane McPhalen) = %v, want none", resultIDs(results))
+	}
+	if results, _ := m.BrowseByAuthor("jon", 2); len(results) != 2 {
+		t.Errorf("BrowseByAuthor(jon, 2) returned %d results, want 2", len(results))
+	}
+}
+
+func TestBrowseByAuthorsDeduplicates(t *testing.T) {
+	m := newAuthorTestManager(t)
+	m.objects["2812"].ObjectMetadata.Author = "Jon McPhalen & Chip Gracey"
+	m.objects["2813"].ObjectMetadata.Author = "Chip Gracey"
+
+	results, err := m.BrowseByAuthors([]string{"Jon McPhalen", "Chip Gracey"}, 0)
+	if err != nil {
+		t.Fatalf("BrowseByAuthors failed: %v", err)
+	}
+	// 2812 matches both authors but is listed once; 2814 is invalid
+	if got := resultIDs(results); !reflect.DeepEqual(got, []string{"2811", "2812", "2813"}) {
+		t.Fatalf("BrowseByAuthors = %v, want [2811 2812 2813]", got)
+	}
+	matched := make(map[string][]string)
+	for _, r := range results {
+		matched[r.ObjectID] = r.MatchedQueries
+	}
+	want := map[string][]string{
+		"2811": {"Jon McPhalen"},
+		"2812": {"Jon McPhalen", "Chip Gracey"},
+		"2813": {"Chip Gracey"},
+	}
+	if !reflect.DeepEqual(matched, want) {
+		t.Errorf("MatchedQueries = %v, want %v", matched, want)
+	}
+
+	if results, _ := m.BrowseByAuthors([]string{"Jon McPhalen", "Chip Gracey"}, 2); len(results) != 2 {
+		t.Errorf("BrowseByAuthors with limit 2 returned %d results, want 2", len(results))
+	}
+}
+
+func TestGetAuthorDetail(t *testing.T) {
+	m := newAuthorTestManager(t)
+
+	detail, err := m.GetAuthorDetail("Jon McPhalen")
+	if err != nil {
+		t.Fatalf("GetAuthorDetail failed: %v", err)
+	}
+
+	if detail.ObjectCount != 2 || len(detail.Objects) != 2 {
+		t.Fatalf("ObjectCount = %d, objects = %v, want 2 valid objects", detail.ObjectCount, detail.Objects)
+	}
+	if detail.Categories["drivers"] != 1 || detail.Categories["display"] != 1 {
+		t.Errorf("Categories = %v, want drivers:1 display:1", detail.Categories)
+	}
+	if len(detail.Tags) == 0 || detail.Tags[0] != (TagCount{Tag: "led", Count: 2}) {
+		t.Errorf("Tags = %v, want led (2) first", detail.Tags)
+	}
+	if detail.AverageQuality != 7 {
+		t.Errorf("AverageQuality = %v, want 7", detail.AverageQuality)
+	}
+	if detail.EarliestCreated != "2020-07-15 09:30:00" || detail.LatestCreated != "2021-03-01 10:00:00" {
+		t.Errorf("created range = %q..%q", detail.EarliestCreated, detail.LatestCreated)
+	}
+	if strings.Join(detail.Languages, ",") != "PASM2,SPIN2" {
+		t.Errorf("Languages = %v, want [PASM2 SPIN2]", detail.Languages)
+	}
+	if strings.Join(detail.GitHubUsernames, ",") != "JonnyMac" {
+		t.Errorf("GitHubUsernames = %v, want [JonnyMac]", detail.GitHubUsernames)
+	}
+	if detail.Objects[1] != (AuthorObject{ObjectID: "2812", Title: "MAX7219 Display", Category: "display", QualityScore: 6}) {
+		t.Errorf("Objects[1] = %+v", detail.Objects[1])
+	}
+
+	if _, err := m.GetAuthorDetail("jon mcphalen"); err == nil {
+		t.Error("GetAuthorDetail should require the exact author name")
+	}
+}
+
+// Tests for tag normalization
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		tags     []string
+		expected []string
+	}{
+		{[]string{"motor", "Motor", "motors", "MOTOR"}, []string{"motor"}},
+		{[]string{"Batteries", "shelves"}, []string{"battery", "shelf"}},
+		{[]string{"GPS", "bus", "class"}, []string{"gps", "bus", "class"}},
+		{[]string{"WS2812", "neopixels"}, []string{"ws2812", "neopixel"}},
+		{[]string{" ", ""}, []string{}},
+	}
+
+	for _, tt := range tests {
+		result := normalizeTags(tt.tags)
+		if len(result) != len(tt.expected) {
+			t.Errorf("normalizeTags(%v) = %v, want %v", tt.tags, result, tt.expected)
+			continue
+		}
+		for i := range result {
+			if result[i] != tt.expected[i] {
+				t.Errorf("normalizeTags(%v) = %v, want %v", tt.tags, result, tt.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestExpandSearchTermsNormalizesInput(t *testing.T) {
+	result := expandSearchTerms("motors")
+
+	found := false
+	for _, term := range result {
+		if term == "motor" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expandSearchTerms(motors) = %v, should contain motor", result)
+	}
+}
+
+// newMixedMicrocontrollerManager returns a manager holding a P2 object
+// (2811), a Propeller 1 object (2812) and one listing no microcontroller (2813).
+func newMixedMicrocontrollerManager(t *testing.T) *Manager {
+	t.Helper()
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2811", "2812", "2813"},
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	m.objects["2811"] = loadFixtureObject(t, "obexObjectValid.yaml")
+	m.objects["2812"] = loadFixtureObject(t, "obexObjectP1.yaml")
+	m.objects["2813"] = loadFixtureObject(t, "obexObjectNoMicrocontroller.yaml")
+	return m
+}
+
+func TestGetAuthorsSkipsInvalidObjects(t *testing.T) {
+	m := newMixedMicrocontrollerManager(t)
+	m.objects["2812"].ObjectMetadata.Title = ""
+
+	authors, err := m.GetAuthors()
+	if err != nil {
+		t.Fatalf("GetAuthors failed: %v", err)
+	}
+	total := 0
+	for _, a := range authors {
+		total += a.ObjectCount
+	}
+	if total != 2 {
+		t.Errorf("authors = %+v count %d objects, want the 2 valid ones", authors, total)
+	}
+}
+
+func TestSearchFiltersByMicrocontroller(t *testing.T) {
+	m := newMixedMicrocontrollerManager(t)
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"2811", "2812", "2813"}},
+		{"any", []string{"2811", "2812", "2813"}},
+		{"ANY", []string{"2811", "2812", "2813"}},
+		{"P2", []string{"2811"}},
+		{"propeller 2", []string{"2811"}},
+		{"P1", []string{"2812"}},
+		{"P3", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			results, err := m.Search("ws2812", "", "", tt.filter, 10)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.ObjectID)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(microcontroller=%q) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+// newPhasedSearchManager holds 2811 (the WS2812 driver) and 2900 (which
+// mentions lanterns and the WS2812 only in description_full) in memory; 2901,
+// another lantern object, has to be fetched. fetches counts requests for it.
+func newPhasedSearchManager(t *testing.T) (m *Manager, fetches func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	count := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+		if r.URL.Path != "/2901.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`object_metadata:
+  object_id: "2901"
+  title: "Pixel Matrix"
+  author: "Test Author"
+  functionality:
+    category: "display"
+    description_short: "8x8 LED matrix"
+    description_full: "Lantern effects on an 8x8 matrix."
+`))
+	}))
+	prev := ObjectsURL
+	ObjectsURL = srv.URL
+	t.Cleanup(func() {
+		ObjectsURL = prev
+		srv.Close()
+	})
+
+	hidden := &OBEXObject{}
+	hidden.ObjectMetadata.ObjectID = "2900"
+	hidden.ObjectMetadata.Title = "Light Show"
+	hidden.ObjectMetadata.Author = "Test Author"
+	hidden.ObjectMetadata.Functionality.Category = "display"
+	hidden.ObjectMetadata.Functionality.DescriptionShort = "Animated light patterns"
+	hidden.ObjectMetadata.Functionality.DescriptionFull = "Flickers like a lantern; built on the WS2812 driver."
+
+	m = &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2811", "2900", "2901"},
+		objects:     map[string]*OBEXObject{"2811": loadFixtureObject(t, "obexObjectValid.yaml"), "2900": hidden},
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+		httpClient:  &http.Client{Timeout: 5 * time.Second},
+		fetchSem:    make(chan struct{}, 3),
+	}
+	return m, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+}
+
+func TestSearchWithPhases(t *testing.T) {
+	type hit struct {
+		ID     string
+		InFull bool
+	}
+	tests := []struct {
+		name        string
+		term        string
+		limit       int
+		want        []hit
+		wantPhases  int
+		wantFetches bool
+	}{
+		// Phase 1 fills the limit from memory: nothing is fetched
+		{"phase 1 only", "ws2812", 1, []hit{{"2811", false}}, 1, false},
+		// Too few in memory: phase 2 fetches 2901 and reads full descriptions
+		{"phase 2 full description", "lantern", 10, []hit{{"2900", true}, {"2901", true}}, 2, true},
+		{"both phases", "ws2812", 10, []hit{{"2811", false}, {"2900", true}}, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, fetches := newPhasedSearchManager(t)
+
+			results, phases, err := m.SearchWithPhases(tt.term, SearchFilters{}, tt.limit)
+			if err != nil {
+				t.Fatalf("SearchWithPhases failed: %v", err)
+			}
+			var got []hit
+			for _, r := range results {
+				got = append(got, hit{r.ObjectID, r.MatchedInFull})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+			if phases != tt.wantPhases {
+				t.Errorf("phases = %d, want %d", phases, tt.wantPhases)
+			}
+			if fetched := fetches() > 0; fetched != tt.wantFetches {
+				t.Errorf("fetched = %v, want %v", fetched, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestSearchWithPhasesFiltersBeforeLimit(t *testing.T) {
+	m := newSubcategoryTestManager(t)
+	for _, id := range []string{"2811", "2812", "2813"} {
+		m.objects[id].ObjectMetadata.TechnicalDetails.FileSize = "1 MB"
+	}
+	m.objects["2814"].ObjectMetadata.TechnicalDetails.FileSize = "10 KB"
+
+	// 2811 is the first "driver" match; each filter must skip it rather
+	// than spend the limit of 1 on it
+	for name, filters := range map[string]SearchFilters{
+		"subcategory": {Category: "drivers", Subcategory: "i2c"},
+		"file size":   {MaxFileSizeKB: 100},
+	} {
+		results, _, err := m.SearchWithPhases("driver", filters, 1)
+		if err != nil {
+			t.Fatalf("%s: SearchWithPhases failed: %v", name, err)
+		}
+		if len(results) != 1 || results[0].ObjectID != "2814" {
+			t.Errorf("%s: results = %+v, want only 2814", name, results)
+		}
+	}
+}
+
+func TestSearchResultsCarryMicrocontroller(t *testing.T) {
+	m := newMixedMicrocontrollerManager(t)
+
+	results, err := m.Search("ws2812", "", "", "P1", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || !reflect.DeepEqual(results[0].Microcontroller, []string{"Propeller 1"}) {
+		t.Errorf("results = %+v, want 2812 listing Propeller 1", results)
+	}
+}
+
+func TestMatchesMicrocontroller(t *testing.T) {
+	tests := []struct {
+		values []string
+		filter string
+		want   bool
+	}{
+		{nil, "", true},
+		{nil, "any", true},
+		{nil, "P2", false},
+		{[]string{"P2"}, "p2", true},
+		{[]string{"Propeller 2"}, "P2", true},
+		{[]string{"P2X8C4M64P"}, "propeller2", true},
+		{[]string{"Propeller 1"}, "P2", false},
+		{[]string{"P8X32A"}, "P1", true},
+		{[]string{"P1", "P2"}, "P1", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesMicrocontroller(tt.values, tt.filter); got != tt.want {
+			t.Errorf("MatchesMicrocontroller(%v, %q) = %v, want %v", tt.values, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestGetMicrocontrollerDistribution(t *testing.T) {
+	m := newMixedMicrocontrollerManager(t)
+
+	got, err := m.GetMicrocontrollerDistribution()
+	if err != nil {
+		t.Fatalf("GetMicrocontrollerDistribution failed: %v", err)
+	}
+	want := map[string]int{"P2": 1, "Propeller 1": 1, "unspecified": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("distribution = %v, want %v", got, want)
+	}
+}
+
+func TestParseFileSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"45.2 KB", 46285},
+		{"24 KB", 24576},
+		{"24KB", 24576},
+		{"24 kb", 24576},
+		{"24 KiB", 24576},
+		{"24K", 24576},
+		{"1.2 MB", 1258291},
+		{"3 mb", 3145728},
+		{"2048", 2048},
+		{"512 bytes", 512},
+		{"1 byte", 1},
+		{"512 B", 512},
+		{"1,536 bytes", 1536},
+		{"  7 KB ", 7168},
+		{"0 KB", 0},
+		{"", -1},
+		{"unknown", -1},
+		{"1.2 GB", -1},
+		{"KB", -1},
+		{"-5 KB", -1},
+		{"about 40 KB", -1},
+	}
+
+	for _, tt := range tests {
+		if got := parseFileSize(tt.in); got != tt.want {
+			t.Errorf("parseFileSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesFileSize(t *testing.T) {
+	tests := []struct {
+		size         int64
+		minKB, maxKB int
+		want         bool
+	}{
+		{10240, 0, 0, true},
+		{10240, 10, 0, true}, // Bounds are inclusive
+		{10239, 10, 0, false},
+		{10240, 0, 10, true},
+		{10241, 0, 10, false},
+		{10240, 5, 20, true},
+		{30720, 5, 20, false},
+		{-1, 5, 20, true}, // Unknown sizes pass
+		{0, 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesFileSize(tt.size, tt.minKB, tt.maxKB); got != tt.want {
+			t.Errorf("MatchesFileSize(%d, %d, %d) = %v, want %v", tt.size, tt.minKB, tt.maxKB, got, tt.want)
+		}
+	}
+}
+
+func TestGetFileSizeStats(t *testing.T) {
+	m := newMixedMicrocontrollerManager(t)
+	m.objects["2811"].ObjectMetadata.TechnicalDetails.FileSize = "10 KB"
+	m.objects["2812"].ObjectMetadata.TechnicalDetails.FileSize = "1 MB"
+	m.objects["2813"].ObjectMetadata.TechnicalDetails.FileSize = "big"
+
+	got, err := m.GetFileSizeStats()
+	if err != nil {
+		t.Fatalf("GetFileSizeStats failed: %v", err)
+	}
+	want := &FileSizeStats{MinKB: 10, MaxKB: 1024, AvgKB: 517, Known: 2, Unknown: 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+
+	if got := SummarizeFileSizes(nil, 3); !reflect.DeepEqual(got, &FileSizeStats{Unknown: 3}) {
+		t.Errorf("stats with no known sizes = %+v", got)
+	}
+}
+
+func TestGetOverviewStats(t *testing.T) {
+	m := newMixedMicrocontrollerManager(t)
+	m.objects["2811"].ObjectMetadata.TechnicalDetails.FileSize = "10 KB"
+
+	got, err := m.GetOverviewStats()
+	if err != nil {
+		t.Fatalf("GetOverviewStats failed: %v", err)
+	}
+	want := &OverviewStats{
+		MicrocontrollerDistribution: map[string]int{"P2": 1, "Propeller 1": 1, "unspecified": 1},
+		FileSizes:                   &FileSizeStats{MinKB: 10, MaxKB: 10, AvgKB: 10, Known: 1, Unknown: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestExpandSearchTermsPropeller2(t *testing.T) {
+	for _, term := range []string{"propeller2", "p2"} {
+		result := expandSearchTerms(term)
+		for _, want := range []string{"p2", "propeller 2"} {
+			found := false
+			for _, got := range result {
+				if got == want {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expandSearchTerms(%q) = %v, should contain %q", term, result, want)
+			}
+		}
+	}
+}
+
+func TestSearchMatchesTagVariants(t *testing.T) {
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"1", "2", "3", "4"},
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	for id, tag := range map[string]string{"1": "motor", "2": "motors", "3": "Motor", "4": "sensor"} {
+		obj := &OBEXObject{}
+		obj.ObjectMetadata.ObjectID = id
+		obj.ObjectMetadata.Title = "Object " + id
+		obj.ObjectMetadata.Author = "Test Author"
+		obj.ObjectMetadata.Functionality.Category = "drivers"
+		obj.ObjectMetadata.Functionality.Tags = []string{tag}
+		m.objects[id] = obj
+	}
+
+	results, err := m.Search("motors", "", "", "", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, r := range results {
+		got[r.ObjectID] = r.MatchType
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if got[id] != "tag" {
+			t.Errorf("object %s match_type = %q, want tag", id, got[id])
+		}
+	}
+	if _, ok := got["4"]; ok {
+		t.Error("object 4 (tagged sensor) should not match motors")
+	}
+}
+
+func TestFindByTagsUsesLoadedObjects(t *testing.T) {
+	valid := loadFixtureObject(t, "obexObjectValid.yaml")
+	valid.normalizedTags = normalizeTags(valid.ObjectMetadata.Functionality.Tags)
+	noCategory := loadFixtureObject(t, "obexObjectNoCategory.yaml")
+
+	m := &Manager{objects: map[string]*OBEXObject{
+		valid.ObjectMetadata.ObjectID: valid,
+		"bad":                         noCategory,
+	}}
+
+	// "neopixel" matches ws2812 through tag normalization.
+	results := m.FindByTags([]string{"neopixel", "mov"}, 5)
+	if len(results) != 1 || results[0].ObjectID != valid.ObjectMetadata.ObjectID {
+		t.Fatalf("FindByTags = %v, want only object %s", results, valid.ObjectMetadata.ObjectID)
+	}
+	if results[0].MatchType != "tag" {
+		t.Errorf("MatchType = %q, want tag", results[0].MatchType)
+	}
+
+	if results := m.FindByTags([]string{"pasm2", "mov"}, 5); len(results) != 0 {
+		t.Errorf("FindByTags with no overlapping tags = %v, want none", results)
+	}
+}
+
+func TestPreviewZip(t *testing.T) {
+	zipBuf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuf)
+	if _, err := zipWriter.Create("driver/"); err != nil {
+		t.Fatalf("failed to create zip directory: %v", err)
+	}
+	for _, f := range []struct{ name, content string }{
+		{"driver/main.spin2", "CON _clkfreq = 200_000_000\n"},
+		{"driver/readme.txt", "read me"},
+	} {
+		w, err := zipWriter.Create(f.name)
+		if err != nil {
+			t.Fatalf("failed to create zip entry: %v", err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			t.Fatalf("failed to write zip entry: %v", err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatalf("failed to close zip writer: %v", err)
+	}
+
+	preview, err := PreviewZip(zipBuf.Bytes(), 3)
+	if err != nil {
+		t.Fatalf("PreviewZip failed: %v", err)
+	}
+	wantFiles := []ZipEntry{{"driver/main.spin2", 27}, {"driver/readme.txt", 7}}
+	if !reflect.DeepEqual(preview.Files, wantFiles) {
+		t.Errorf("Files = %v, want %v", preview.Files, wantFiles)
+	}
+	if preview.PreviewFile != "driver/main.spin2" || string(preview.Content) != "CON" || !preview.Truncated {
+		t.Errorf("preview = %q %q truncated=%v, want first file's first 3 bytes, truncated", preview.PreviewFile, preview.Content, preview.Truncated)
+	}
+
+	preview, err = PreviewZip(zipBuf.Bytes(), 4096)
+	if err != nil {
+		t.Fatalf("PreviewZip failed: %v", err)
+	}
+	if preview.Truncated || len(preview.Content) != 27 {
+		t.Errorf("whole file: got %d bytes truncated=%v, want 27 bytes untruncated", len(preview.Content), preview.Truncated)
+	}
+
+	if _, err := PreviewZip([]byte("<html>not a zip</html>"), 10); err == nil {
+		t.Error("PreviewZip accepted non-zip data")
+	}
+}
+
+func TestMemoryObjects(t *testing.T) {
+	m := NewManager()
+	before := time.Now()
+	m.mu.Lock()
+	m.storeObjectLocked("2812", loadFixtureObject(t, "obexObjectP1.yaml"))
+	m.storeObjectLocked("2811", loadFixtureObject(t, "obexObjectValid.yaml"))
+	m.mu.Unlock()
+
+	objects := m.MemoryObjects()
+	if len(objects) != 2 || objects[0].ObjectID != "2811" || objects[1].ObjectID != "2812" {
+		t.Fatalf("MemoryObjects() = %+v, want 2811 then 2812", objects)
+	}
+	if objects[0].Title != "WS2812 LED Driver" || objects[0].LoadedAt.Before(before) {
+		t.Errorf("object 2811 = %+v, want fixture title and a load time", objects[0])
+	}
+
+	m.EvictMemoryObjects(0)
+	if got := m.MemoryObjects(); len(got) != 0 {
+		t.Errorf("after eviction MemoryObjects() = %+v, want none", got)
+	}
+	if len(m.objectLoadedAt) != 0 {
+		t.Errorf("load times kept for evicted objects: %v", m.objectLoadedAt)
+	}
+}
+
+func TestOBEXPageSlug(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{"https://obex.parallax.com/obex/park-transformation/", "park-transformation", true},
+		{"http://obex.parallax.com/obex/park-transformation", "park-transformation", true},
+		{"obex.parallax.com/obex/park-transformation", "park-transformation", true},
+		{"https://www.obex.parallax.com/obex/park-transformation/?tab=files#top", "park-transformation", true},
+		{"https://OBEX.Parallax.com/obex/Park-Transformation/", "park-transformation", true},
+		{"https://obex.parallax.com/obex/park%2Dtransformation/", "park-transformation", true},
+		{"  https://obex.parallax.com/obex/vl53l1x  ", "vl53l1x", true},
+		{"https://obex.parallax.com/obex/", "", false},
+		{"https://obex.parallax.com/", "", false},
+		{"https://obex.parallax.com/obex/a/b", "", false},
+		{"https://example.com/obex/park-transformation", "", false},
+		{"ftp://obex.parallax.com/obex/park-transformation", "", false},
+		{"park transformation", "", false},
+		{"2811", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := OBEXPageSlug(tt.raw)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("OBEXPageSlug(%q) = %q, %v, want %q, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// newPageURLTestManager returns a manager holding objects whose obex_page is
+// written three ways: https with a trailing slash (2815), without a scheme
+// (2811) and plain http (2905).
+func newPageURLTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2811", "2815", "2905"},
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	scalar, err := decodeObject(testdata.MustGetFixture("obexObjectLanguagesScalar.yaml"))
+	if err != nil {
+		t.Fatalf("decode fixture: %v", err)
+	}
+	m.objects["2811"] = scalar
+	m.objects["2815"] = loadFixtureObject(t, "obexObjectComplete.yaml")
+	m.objects["2905"] = loadFixtureObject(t, "obexObjectPageURL.yaml")
+	return m
+}
+
+func TestSearchByOBEXPageURL(t *testing.T) {
+	m := newPageURLTestManager(t)
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://obex.parallax.com/obex/park-transformation/", "2905"},
+		{"http://obex.parallax.com/obex/park-transformation", "2905"},
+		{"https://obex.parallax.com/obex/park%2Dtransformation", "2905"},
+		{"http://obex.parallax.com/obex/vl53l1x", "2815"},
+		{"obex.parallax.com/obex/vl53l1x/", "2815"},
+		{"https://obex.parallax.com/obex/ws2812/", "2811"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			obj, err := m.SearchByOBEXPageURL(tt.url)
+			if err != nil {
+				t.Fatalf("SearchByOBEXPageURL(%q) failed: %v", tt.url, err)
+			}
+			if obj.ObjectMetadata.ObjectID != tt.want {
+				t.Errorf("SearchByOBEXPageURL(%q) = %s, want %s", tt.url, obj.ObjectMetadata.ObjectID, tt.want)
+			}
+		})
+	}
+
+	if _, err := m.SearchByOBEXPageURL("https://obex.parallax.com/obex/no-such-object/"); err == nil {
+		t.Error("expected error for unknown page URL")
+	}
+	if _, err := m.SearchByOBEXPageURL("park transformation"); err == nil {
+		t.Error("expected error for a query that is not a page URL")
+	}
+}
+
+func TestSearchByOBEXPageURLRemembersSlugs(t *testing.T) {
+	m := newPageURLTestManager(t)
+
+	if _, err := m.SearchByOBEXPageURL("https://obex.parallax.com/obex/no-such-object/"); err == nil {
+		t.Fatal("expected error for unknown page URL")
+	}
+
+	// The failed scan saw every object, so later lookups skip it
+	want := map[string]string{"ws2812": "2811", "vl53l1x": "2815", "park-transformation": "2905"}
+	if !reflect.DeepEqual(m.pageSlugs, want) {
+		t.Errorf("pageSlugs = %v, want %v", m.pageSlugs, want)
+	}
+
+	m.mu.Lock()
+	m.setObjectIDsLocked([]string{"2811"})
+	m.mu.Unlock()
+	if m.pageSlugs != nil {
+		t.Errorf("pageSlugs = %v after the index was replaced, want nil", m.pageSlugs)
+	}
+}
+
+func TestGetTagCloud(t *testing.T) {
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2811", "2812", "2813", "2814", "2815", "2905"},
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	m.objects["2811"] = loadFixtureObject(t, "obexObjectValid.yaml")     // led, ws2812
+	m.objects["2812"] = loadFixtureObject(t, "obexObjectP1.yaml")        // led, ws2812
+	m.objects["2813"] = loadFixtureObject(t, "obexObjectI2CDriver.yaml") // i2c, sensor
+	m.objects["2814"] = loadFixtureObject(t, "obexObjectNoAuthor.yaml")  // invalid, not counted
+	m.objects["2815"] = loadFixtureObject(t, "obexObjectComplete.yaml")  // distance, i2c
+	m.objects["2905"] = loadFixtureObject(t, "obexObjectPageURL.yaml")   // motor, cordic
+
+	cloud, err := m.GetTagCloud()
+	if err != nil {
+		t.Fatalf("GetTagCloud failed: %v", err)
+	}
+	if cloud.TotalObjects != 5 {
+		t.Errorf("TotalObjects = %d, want 5", cloud.TotalObjects)
+	}
+
+	// ws2812 also counts as neopixel (see tagAbbreviations)
+	wantTags := []TagCount{
+		{"i2c", 2}, {"led", 2}, {"neopixel", 2}, {"ws2812", 2},
+		{"cordic", 1}, {"distance", 1}, {"motor", 1}, {"sensor", 1},
+	}
+	if !reflect.DeepEqual(cloud.Tags, wantTags) {
+		t.Errorf("Tags = %v, want %v", cloud.Tags, wantTags)
+	}
+	wantPairs := []TagPairCount{
+		{"led+neopixel", 2}, {"led+ws2812", 2}, {"neopixel+ws2812", 2},
+		{"cordic+motor", 1}, {"distance+i2c", 1}, {"i2c+sensor", 1},
+	}
+	if !reflect.DeepEqual(cloud.TagPairs, wantPairs) {
+		t.Errorf("TagPairs = %v, want %v", cloud.TagPairs, wantPairs)
+	}
+
+	// Reused until the object list changes
+	if again, _ := m.GetTagCloud(); again != cloud {
+		t.Error("second GetTagCloud recomputed the cloud")
+	}
+	m.mu.Lock()
+	m.setObjectIDsLocked([]string{"2813"})
+	m.mu.Unlock()
+	rebuilt, _ := m.GetTagCloud()
+	if rebuilt.TotalObjects != 1 || len(rebuilt.TagPairs) != 1 {
+		t.Errorf("after index change: %d objects, pairs %v; want 1 object, 1 pair", rebuilt.TotalObjects, rebuilt.TagPairs)
+	}
+}
+
+func TestGetTagCloudCaps(t *testing.T) {
+	obj := loadFixtureObject(t, "obexObjectValid.yaml")
+	obj.ObjectMetadata.Functionality.Tags = nil
+	for i := 0; i < TagCloudSize+10; i++ {
+		obj.ObjectMetadata.Functionality.Tags = append(obj.ObjectMetadata.Functionality.Tags, fmt.Sprintf("tag%02d", i))
+	}
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2811"},
+		objects:     map[string]*OBEXObject{"2811": obj},
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+
+	cloud, err := m.GetTagCloud()
+	if err != nil {
+		t.Fatalf("GetTagCloud failed: %v", err)
+	}
+	if len(cloud.Tags) != TagCloudSize || cloud.Tags[0].Tag != "tag00" {
+		t.Errorf("got %d tags starting %v, want %d starting tag00", len(cloud.Tags), cloud.Tags[0], TagCloudSize)
+	}
+	if len(cloud.TagPairs) != TagPairCloudSize || cloud.TagPairs[0].Pair != "tag00+tag01" {
+		t.Errorf("got %d pairs starting %v, want %d starting tag00+tag01", len(cloud.TagPairs), cloud.TagPairs[0], TagPairCloudSize)
+	}
+}
+
+// newTagCorpusManager holds the GetTagCloud fixture corpus: five valid objects
+// and one invalid one, all in memory.
+func newTagCorpusManager(t *testing.T) *Manager {
+	t.Helper()
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2811", "2812", "2813", "2814", "2815", "2905"},
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	m.objects["2811"] = loadFixtureObject(t, "obexObjectValid.yaml")
+	m.objects["2812"] = loadFixtureObject(t, "obexObjectP1.yaml")
+	m.objects["2813"] = loadFixtureObject(t, "obexObjectI2CDriver.yaml")
+	m.objects["2814"] = loadFixtureObject(t, "obexObjectNoAuthor.yaml")
+	m.objects["2815"] = loadFixtureObject(t, "obexObjectComplete.yaml")
+	m.objects["2905"] = loadFixtureObject(t, "obexObjectPageURL.yaml")
+	return m
+}
+
+// scanTag is the linear scan the tag index replaces: the IDs of every valid
+// object whose normalized tags include tag, in index order.
+func scanTag(m *Manager, tag string) []string {
+	var ids []string
+	for _, objID := range m.GetObjectIDs() {
+		obj := m.objects[objID]
+		if ValidateObject(obj) != nil {
+			continue
+		}
+		for _, t := range normalizeTags(obj.ObjectMetadata.Functionality.Tags) {
+			if t == normalizeTag(tag) {
+				ids = append(ids, obj.ObjectMetadata.ObjectID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+func resultIDs(results []SearchResult) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.ObjectID)
+	}
+	return ids
+}
+
+func TestSearchByTagMatchesLinearScan(t *testing.T) {
+	m := newTagCorpusManager(t)
+
+	queries := []string{"Sensors", " I2C ", "NeoPixel", "unknown"}
+	for _, obj := range m.objects {
+		queries = append(queries, obj.ObjectMetadata.Functionality.Tags...)
+	}
+	for _, tag := range queries {
+		results, err := m.SearchByTag(tag, 0)
+		if err != nil {
+			t.Fatalf("SearchByTag(%q) failed: %v", tag, err)
+		}
+		if got, want := resultIDs(results), scanTag(m, tag); !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchByTag(%q) = %v, linear scan = %v", tag, got, want)
+		}
+	}
+
+	if results, _ := m.SearchByTag("i2c", 1); len(results) != 1 || results[0].MatchType != "tag" {
+		t.Errorf("SearchByTag(i2c, 1) = %v, want one tag match", results)
+	}
+
+	// Rebuilt when the object list changes
+	m.mu.Lock()
+	m.setObjectIDsLocked([]string{"2813"})
+	m.mu.Unlock()
+	if results, _ := m.SearchByTag("i2c", 0); !reflect.DeepEqual(resultIDs(results), []string{"2814"}) {
+		t.Errorf("after index change: SearchByTag(i2c) = %v, want [2814]", resultIDs(results))
+	}
+}
+
+func TestSearchByTagPrefix(t *testing.T) {
+	m := newTagCorpusManager(t)
+
+	tests := []struct {
+		prefix   string
+		wantTags []string
+		wantIDs  []string
+	}{
+		{"i", []string{"i2c"}, []string{"2814", "2815"}},
+		{"Sensors", []string{"sensor"}, []string{"2814"}},
+		{"d", []string{"distance"}, []string{"2815"}},
+		{"", []string{"cordic", "distance", "i2c", "led", "motor", "neopixel", "sensor", "ws2812"}, []string{"2811", "2812", "2814", "2815", "2905"}},
+		{"x", nil, nil},
+	}
+	for _, tt := range tests {
+		results, tags, err := m.SearchByTagPrefix(tt.prefix, 0)
+		if err != nil {
+			t.Fatalf("SearchByTagPrefix(%q) failed: %v", tt.prefix, err)
+		}
+		if !reflect.DeepEqual(tags, tt.wantTags) {
+			t.Errorf("SearchByTagPrefix(%q) tags = %v, want %v", tt.prefix, tags, tt.wantTags)
+		}
+		if got := resultIDs(results); !reflect.DeepEqual(got, tt.wantIDs) {
+			t.Errorf("SearchByTagPrefix(%q) objects = %v, want %v", tt.prefix, got, tt.wantIDs)
+		}
+	}
+}
+
+func TestGetObjectTypedErrors(t *testing.T) {
+	newManager := func() *Manager {
+		return &Manager{
+			cacheDir:    t.TempDir(),
+			objects:     make(map[string]*OBEXObject),
+			objectIDs:   []string{"2811"},
+			ttl:         1 * time.Hour,
+			lastRefresh: time.Now(),
+			httpClient:  &http.Client{Timeout: 5 * time.Second},
+		}
+	}
+
+	// Not in the index: no request is made
+	_, err := newManager().GetObject("9999")
+	var notFound *errs.ErrKeyNotFound
+	if !errors.As(err, &notFound) || notFound.Key != "9999" {
+		t.Errorf("GetObject(9999) = %v, want *errs.ErrKeyNotFound", err)
+	}
+
+	tests := []struct {
+		name         string
+		status       int
+		header       http.Header
+		wantNotFound bool
+		wantLimited  bool
+	}{
+		{"404", http.StatusNotFound, nil, true, false},
+		{"500", http.StatusInternalServerError, nil, false, false},
+		{"429", http.StatusTooManyRequests, http.Header{"Retry-After": {"30"}}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				for k, v := range tt.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			prev := ObjectsURL
+			ObjectsURL = srv.URL
+			defer func() { ObjectsURL = prev }()
+
+			_, err := newManager().GetObject("2811")
+			var status *errs.ErrHTTPStatus
+			if !errors.As(err, &status) || status.Code != tt.status {
+				t.Fatalf("GetObject = %v, want *errs.ErrHTTPStatus %d", err, tt.status)
+			}
+			var notFound *errs.ErrKeyNotFound
+			if got := errors.As(err, &notFound); got != tt.wantNotFound {
+				t.Errorf("matches *errs.ErrKeyNotFound = %v, want %v", got, tt.wantNotFound)
+			}
+			var limited *errs.ErrRateLimited
+			if got := errors.As(err, &limited); got != tt.wantLimited {
+				t.Errorf("matches *errs.ErrRateLimited = %v, want %v", got, tt.wantLimited)
+			}
+		})
+	}
+}
+
+// stubIndexServer points IndexContentsURL at a server listing the object IDs
+// in *listing, read on every request so tests can change the index.
+func stubIndexServer(t *testing.T, listing *[]string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var b strings.Builder
+		b.WriteString(`[{"name": "_template.yaml", "type": "file"}`)
+		for _, id := range *listing {
+			fmt.Fprintf(&b, `, {"name": "%s.yaml", "type": "file"}`, id)
+		}
+		b.WriteString("]")
+		_, _ = w.Write([]byte(b.String()))
+	}))
+	prev := IndexContentsURL
+	IndexContentsURL = srv.URL
+	t.Cleanup(func() {
+		IndexContentsURL = prev
+		srv.Close()
+	})
+}
+
+func TestRefreshRetainsUnchangedObjects(t *testing.T) {
+	listing := []string{"2811", "2812", "2813"}
+	stubIndexServer(t, &listing)
+
+	m := &Manager{
+		cacheDir:   t.TempDir(),
+		objects:    make(map[string]*OBEXObject),
+		ttl:        DefaultOBEXTTL,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+	if _, err := m.Refresh(); err != nil {
+		t.Fatalf("first Refresh failed: %v", err)
+	}
+	data := testdata.MustGetFixture("obexObjectValid.yaml")
+	for _, id := range listing {
+		m.saveObjectToCache(id, data, 0)
+		obj := loadFixtureObject(t, "obexObjectValid.yaml")
+		obj.ObjectMetadata.ObjectID = id
+		m.mu.Lock()
+		m.storeObjectLocked(id, obj)
+		m.mu.Unlock()
+	}
+
+	// 2813 is replaced by 2814; 2811 and 2812 are unchanged
+	listing = []string{"2811", "2812", "2814"}
+	stats, err := m.Refresh()
+	if err != nil {
+		t.Fatalf("second Refresh failed: %v", err)
+	}
+	if stats != (RefreshStats{Retained: 2, Evicted: 1}) {
+		t.Errorf("Refresh stats = %+v, want 2 retained and 1 evicted", stats)
+	}
+	m.mu.RLock()
+	_, has2811 := m.objects["2811"]
+	_, has2812 := m.objects["2812"]
+	_, has2813 := m.objects["2813"]
+	m.mu.RUnlock()
+	if !has2811 || !has2812 || has2813 {
+		t.Errorf("after refresh: 2811 %v, 2812 %v, 2813 %v; want 2811 and 2812 only", has2811, has2812, has2813)
+	}
+	if got := m.GetObjectIDs(); !reflect.DeepEqual(got, listing) {
+		t.Errorf("GetObjectIDs = %v, want %v", got, listing)
+	}
+
+	// An unchanged object whose disk entry has expired is evicted too
+	old := time.Now().Add(-2 * objectTTL(DefaultOBEXTTL, 10))
+	if err := os.Chtimes(filepath.Join(m.cacheDir, "obex", "objects", "2812.yaml"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := m.Refresh(); stats != (RefreshStats{Retained: 1, Evicted: 1}) {
+		t.Errorf("Refresh stats with a stale entry = %+v, want 1 retained and 1 evicted", stats)
+	}
+}
+
+func TestLocalObjectsOverrideAndExtendIndex(t *testing.T) {
+	listing := []string{"2811", "2812"}
+	stubIndexServer(t, &listing)
+
+	localDir := t.TempDir()
+	t.Setenv("P2KB_OBEX_LOCAL_DIR", localDir)
+	fixture := string(testdata.MustGetFixture("obexObjectValid.yaml"))
+	override := strings.Replace(fixture, "WS2812 LED Driver", "In-house LED Driver", 1)
+	private := strings.Replace(strings.Replace(fixture, `"2811"`, `"9001"`, 1), "WS2812 LED Driver", "In-house Motor Driver", 1)
+	for name, content := range map[string]string{"2811.yaml": override, "9001.yaml": private} {
+		if err := os.WriteFile(filepath.Join(localDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := NewManager()
+	m.cacheDir = t.TempDir()
+
+	if got, want := m.GetObjectIDs(), []string{"2811", "2812", "9001"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetObjectIDs = %v, want %v", got, want)
+	}
+	if got := m.LocalObjectCount(); got != 2 {
+		t.Errorf("LocalObjectCount = %d, want 2", got)
+	}
+
+	obj, err := m.GetObject("2811")
+	if err != nil {
+		t.Fatalf("GetObject(2811) failed: %v", err)
+	}
+	if !obj.IsLocal() || obj.ObjectMetadata.Title != "In-house LED Driver" {
+		t.Errorf("GetObject(2811) = %q, local %v; want the local override", obj.ObjectMetadata.Title, obj.IsLocal())
+	}
+
+	results, err := m.Search("in-house", "", "", "", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if got := resultIDs(results); !reflect.DeepEqual(got, []string{"2811", "9001"}) {
+		t.Fatalf("Search(in-house) = %v, want [2811 9001]", got)
+	}
+	for _, r := range results {
+		if r.Source != "local" {
+			t.Errorf("result %s Source = %q, want local", r.ObjectID, r.Source)
+		}
+	}
+
+	// Local objects are never evicted from memory
+	m.EvictMemoryObjects(0)
+	if obj, err := m.GetObject("9001"); err != nil || !obj.IsLocal() {
+		t.Errorf("GetObject(9001) after eviction = %v, %v; want the local object", obj, err)
+	}
+}
+
+func TestFetchObjectFallsBackToMirror(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/obex/2811.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(testdata.MustGetFixture("obexObjectValid.yaml"))
+	}))
+	defer mirror.Close()
+
+	prev := ObjectsURL
+	ObjectsURL = primary.URL
+	defer func() { ObjectsURL = prev }()
+	t.Setenv("P2KB_CACHE_DIR", t.TempDir())
+	t.Setenv("P2KB_OBEX_MIRROR_URLS", " "+mirror.URL+"/obex/ ")
+
+	m := NewManager()
+	if m.LastMirrorUsed() != "" {
+		t.Fatalf("LastMirrorUsed() = %q before any fetch, want empty", m.LastMirrorUsed())
+	}
+	obj, err := m.fetchObject("2811")
+	if err != nil {
+		t.Fatalf("fetchObject failed: %v", err)
+	}
+	want := loadFixtureObject(t, "obexObjectValid.yaml")
+	if obj.ObjectMetadata.Title != want.ObjectMetadata.Title {
+		t.Errorf("title = %q, want %q from the mirror", obj.ObjectMetadata.Title, want.ObjectMetadata.Title)
+	}
+	if got := m.LastMirrorUsed(); got != mirror.URL+"/obex/2811.yaml" {
+		t.Errorf("LastMirrorUsed() = %q, want the mirror URL", got)
+	}
+}
+
+func TestFetchObjectNotFoundSkipsMirrors(t *testing.T) {
+	primary := httptest.NewServer(http.NotFoundHandler())
+	defer primary.Close()
+	mirrorHits := 0
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorHits++
+	}))
+	defer mirror.Close()
+
+	prev := ObjectsURL
+	ObjectsURL = primary.URL
+	defer func() { ObjectsURL = prev }()
+	t.Setenv("P2KB_CACHE_DIR", t.TempDir())
+	t.Setenv("P2KB_OBEX_MIRROR_URLS", mirror.URL)
+
+	m := NewManager()
+	_, err := m.fetchObject("2811")
+	var notFound *errs.ErrKeyNotFound
+	if !errors.As(err, &notFound) {
+		t.Errorf("err = %v, want ErrKeyNotFound", err)
+	}
+	if mirrorHits != 0 {
+		t.Errorf("mirror hit %d times, want 0 for an object that is gone", mirrorHits)
+	}
+}
+
+func TestExtractPageDescription(t *testing.T) {
+	html, text, ok := ExtractPageDescription(bytes.NewReader(testdata.MustGetFixture("obexPage.html")))
+	if !ok {
+		t.Fatal("no description found in the fixture page")
+	}
+	wantText := "Smart-pin driver for WS2812 RGB LED strips.\n" +
+		"Supports up to 1024 pixels & runs in its own cog.\n" +
+		"Tested on the P2 Edge."
+	if text != wantText {
+		t.Errorf("text = %q, want %q", text, wantText)
+	}
+	if !strings.HasPrefix(html, "<p>Smart-pin driver for <strong>WS2812</strong>") || !strings.HasSuffix(html, "</script>") {
+		t.Errorf("html = %q, want the description div's inner HTML", html)
+	}
+	if strings.Contains(html, "Download") || strings.Contains(html, "Jon McPhalen") {
+		t.Errorf("html = %q runs past the description div", html)
+	}
+
+	for _, page := range []string{
+		`<html><body><div class="content"><p>No description here</p></div></body></html>`,
+		`<div id="description">   </div>`,
+		``,
+	} {
+		if _, _, ok := ExtractPageDescription(strings.NewReader(page)); ok {
+			t.Errorf("description found in %q", page)
+		}
+	}
+}
+
+func newDependencyGraphTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objectIDs:   []string{"2901", "2902", "2903", "2904", "2899"},
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	m.objects["2901"] = loadFixtureObject(t, "obexGraphServo.yaml")
+	m.objects["2902"] = loadFixtureObject(t, "obexGraphMotor.yaml")
+	m.objects["2903"] = loadFixtureObject(t, "obexGraphPID.yaml")
+	m.objects["2904"] = loadFixtureObject(t, "obexGraphBlinky.yaml")
+	m.objects["2899"] = loadFixtureObject(t, "obexObjectNoAuthor.yaml")
+	return m
+}
+
+func TestGetDependencyGraph(t *testing.T) {
+	m := newDependencyGraphTestManager(t)
+
+	graph, err := m.GetDependencyGraph()
+	if err != nil {
+		t.Fatalf("GetDependencyGraph failed: %v", err)
+	}
+
+	wantEdges := []GraphEdge{
+		{SourceID: "2901", TargetID: "2902", MatchType: MatchObjectID},
+		{SourceID: "2902", TargetID: "2901", MatchType: MatchTitle},
+		{SourceID: "2903", TargetID: "2902", MatchType: MatchPageURL},
+	}
+	if !reflect.DeepEqual(graph.Edges, wantEdges) {
+		t.Errorf("Edges = %+v, want %+v", graph.Edges, wantEdges)
+	}
+
+	degrees := make(map[string][2]int)
+	for _, node := range graph.Nodes {
+		degrees[node.ObjectID] = [2]int{node.InDegree, node.OutDegree}
+	}
+	wantDegrees := map[string][2]int{"2901": {1, 1}, "2902": {2, 1}, "2903": {0, 1}, "2904": {0, 0}}
+	if !reflect.DeepEqual(degrees, wantDegrees) {
+		t.Errorf("in/out degrees = %v, want %v (invalid object excluded)", degrees, wantDegrees)
+	}
+
+	if want := [][]string{{"2901", "2902"}}; !reflect.DeepEqual(graph.StronglyConnectedComponents, want) {
+		t.Errorf("StronglyConnectedComponents = %v, want %v", graph.StronglyConnectedComponents, want)
+	}
+	// "servo controllers" is not the title "Servo Controller"
+	if want := []string{"2904"}; !reflect.DeepEqual(graph.IsolatedObjects, want) {
+		t.Errorf("IsolatedObjects = %v, want %v", graph.IsolatedObjects, want)
+	}
+	if graph.AnalyzedObjects != 4 || graph.TotalObjects != 5 || graph.Truncated {
+		t.Errorf("analyzed %d of %d, truncated %v; want 4 of 5, not truncated", graph.AnalyzedObjects, graph.TotalObjects, graph.Truncated)
+	}
+
+	if again, _ := m.GetDependencyGraph(); again != graph {
+		t.Error("second call should reuse the cached graph")
+	}
+}
+
+func TestGetDependencyGraphLimit(t *testing.T) {
+	m := &Manager{
+		cacheDir:    t.TempDir(),
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+	}
+	for i := 0; i < DependencyGraphLimit+5; i++ {
+		obj := loadFixtureObject(t, "obexGraphBlinky.yaml")
+		obj.ObjectMetadata.ObjectID = strconv.Itoa(3000 + i)
+		m.objectIDs = append(m.objectIDs, obj.ObjectMetadata.ObjectID)
+		m.objects[obj.ObjectMetadata.ObjectID] = obj
+	}
+
+	graph, err := m.GetDependencyGraph()
+	if err != nil {
+		t.Fatalf("GetDependencyGraph failed: %v", err)
+	}
+	if len(graph.Nodes) != DependencyGraphLimit || !graph.Truncated || graph.TotalObjects != DependencyGraphLimit+5 {
+		t.Errorf("nodes = %d, truncated = %v, total = %d; want %d, true, %d",
+			len(graph.Nodes), graph.Truncated, graph.TotalObjects, DependencyGraphLimit, DependencyGraphLimit+5)
+	}
+}
+
+// newSlowRemoteManager indexes count objects, 4000 onwards, served by a
+// remote that takes delay per object; the first inMemory are held in memory.
+// fetches counts remote requests.
+func newSlowRemoteManager(t *testing.T, count, inMemory int, delay time.Duration) (m *Manager, fetches func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	fetched := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetched++
+		mu.Unlock()
+		time.Sleep(delay)
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".yaml")
+		fmt.Fprintf(w, `object_metadata:
+  object_id: "%s"
+  title: "Pooled Object %s"
+  author: "Author %s"
+  functionality:
+    category: "drivers"
+    description_short: "Pooled test object"
+`, id, id, id)
+	}))
+	prev := ObjectsURL
+	ObjectsURL = srv.URL
+	t.Cleanup(func() {
+		ObjectsURL = prev
+		srv.Close()
+	})
+
+	m = &Manager{
+		cacheDir:    t.TempDir(),
+		objects:     make(map[string]*OBEXObject),
+		ttl:         DefaultOBEXTTL,
+		lastRefresh: time.Now(),
+		httpClient:  &http.Client{Timeout: 5 * time.Second},
+	}
+	for i := 0; i < count; i++ {
+		id := strconv.Itoa(4000 + i)
+		m.objectIDs = append(m.objectIDs, id)
+		if i < inMemory {
+			obj := &OBEXObject{}
+			obj.ObjectMetadata.ObjectID = id
+			obj.ObjectMetadata.Title = "Pooled Object " + id
+			obj.ObjectMetadata.Author = "Author " + id
+			obj.ObjectMetadata.Functionality.Category = "drivers"
+			m.objects[id] = obj
+		}
+	}
+	return m, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetched
+	}
+}
+
+func TestBrowseCategoryFetchesInParallel(t *testing.T) {
+	m, fetches := newSlowRemoteManager(t, 50, 0, 100*time.Millisecond)
+
+	start := time.Now()
+	results, err := m.BrowseCategory("")
+	if err != nil {
+		t.Fatalf("BrowseCategory failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 2*time.Second {
+		t.Errorf("50 objects took %v, want under 2s", elapsed)
+	}
+	if len(results) != 50 || fetches() != 50 {
+		t.Fatalf("got %d results from %d fetches, want 50 of each", len(results), fetches())
+	}
+	for i, r := range results {
+		if want := strconv.Itoa(4000 + i); r.ObjectID != want {
+			t.Fatalf("results[%d] = %s, want %s (index order)", i, r.ObjectID, want)
+		}
+	}
+}
+
+func TestCorpusScansFetchInParallel(t *testing.T) {
+	scans := map[string]func(m *Manager) error{
+		"GetMicrocontrollerDistribution": func(m *Manager) error {
+			_, err := m.GetMicrocontrollerDistribution()
+			return err
+		},
+		"GetFileSizeStats": func(m *Manager) error {
+			_, err := m.GetFileSizeStats()
+			return err
+		},
+		"GetOverviewStats": func(m *Manager) error {
+			_, err := m.GetOverviewStats()
+			return err
+		},
+	}
+	for name, scan := range scans {
+		m, fetches := newSlowRemoteManager(t, 50, 0, 100*time.Millisecond)
+		start := time.Now()
+		if err := scan(m); err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		if elapsed := time.Since(start); elapsed >= 2*time.Second || fetches() != 50 {
+			t.Errorf("%s: 50 objects took %v and %d fetches, want under 2s and 50", name, elapsed, fetches())
+		}
+	}
+}
+
+func TestForEachObjectSkipsPoolForMemoryObjects(t *testing.T) {
+	m, fetches := newSlowRemoteManager(t, 10, 6, 0)
+
+	authors, err := m.GetAuthors()
+	if err != nil {
+		t.Fatalf("GetAuthors failed: %v", err)
+	}
+	if len(authors) != 10 {
+		t.Errorf("got %d authors, want 10", len(authors))
+	}
+	if got := fetches(); got != 4 {
+		t.Errorf("remote fetches = %d, want 4 (memory objects bypass the pool)", got)
+	}
+}
+
+func TestForEachObjectStopsEarly(t *testing.T) {
+	m, fetches := newSlowRemoteManager(t, 50, 0, 10*time.Millisecond)
+	m.workers = 2
+
+	results, err := m.Search("pooled", "", "", "", 3)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("got %d results, want 3", len(results))
+	}
+	// At most the workers' objects in flight are loaded past the limit
+	if got := fetches(); got > 3+2+2 {
+		t.Errorf("remote fetches = %d, want the search to stop loading near its limit", got)
+	}
+}
+
+func TestGetOBEXWorkers(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", DefaultOBEXWorkers},
+		{"16", 16},
+		{"0", DefaultOBEXWorkers},
+		{"many", DefaultOBEXWorkers},
+	}
+	for _, tt := range tests {
+		t.Setenv("P2KB_OBEX_WORKERS", tt.value)
+		if got := getOBEXWorkers(); got != tt.want {
+			t.Errorf("P2KB_OBEX_WORKERS=%q: got %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
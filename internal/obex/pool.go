@@ -0,0 +1,158 @@
+package obex
+
+import (
+	"os"
+	"strconv"
+	"sync"
+)
+
+// DefaultOBEXWorkers is the default number of objects a search or browse
+// loads at once, overridable via P2KB_OBEX_WORKERS. Remote fetches among them
+// still take a P2KB_OBEX_CONCURRENCY slot.
+const DefaultOBEXWorkers = 8
+
+// objectResult is one job's outcome from an objectPool.
+type objectResult struct {
+	objectID string
+	obj      *OBEXObject
+	err      error
+}
+
+// objectPool loads OBEX objects on a fixed number of worker goroutines.
+// Object IDs sent on jobs are loaded with GetObject and their outcomes
+// delivered on results, in completion order. Closing jobs lets the workers
+// finish; results is closed once they have.
+type objectPool struct {
+	jobs    chan string
+	results chan objectResult
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+}
+
+// newObjectPool starts workers goroutines loading objects for m.
+func (m *Manager) newObjectPool(workers int) *objectPool {
+	p := &objectPool{
+		jobs:    make(chan string),
+		results: make(chan objectResult, workers),
+		done:    make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for objectID := range p.jobs {
+				obj, err := m.GetObject(objectID)
+				select {
+				case p.results <- objectResult{objectID: objectID, obj: obj, err: err}:
+				case <-p.done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		p.wg.Wait()
+		close(p.results)
+	}()
+	return p
+}
+
+// stop abandons the pool, dropping results not yet received, and returns
+// once the workers have finished their current objects and exited.
+func (p *objectPool) stop() {
+	p.once.Do(func() { close(p.done) })
+	p.wg.Wait()
+}
+
+// forEachObject calls fn for each of objectIDs, in order, with its object or
+// the error loading it, until fn returns false. Objects already in memory are
+// handed over directly; the rest are loaded P2KB_OBEX_WORKERS at a time, ahead
+// of fn, so a pass over the whole index is not one round trip at a time.
+func (m *Manager) forEachObject(objectIDs []string, fn func(objectID string, obj *OBEXObject, err error) bool) {
+	var missing []string
+	pooled := make(map[string]bool)
+	m.mu.RLock()
+	for _, objID := range objectIDs {
+		if !m.inMemoryLocked(objID) && !pooled[objID] {
+			missing = append(missing, objID)
+			pooled[objID] = true
+		}
+	}
+	m.mu.RUnlock()
+
+	pending := make(map[string]objectResult)
+	var pool *objectPool
+	if len(missing) > 0 {
+		pool = m.newObjectPool(min(m.workerCount(), len(missing)))
+		defer pool.stop()
+		go func() {
+			defer close(pool.jobs)
+			for _, objID := range missing {
+				select {
+				case pool.jobs <- objID:
+				case <-pool.done:
+					return
+				}
+			}
+		}()
+	}
+
+	for _, objID := range objectIDs {
+		if !pooled[objID] {
+			obj, err := m.GetObject(objID)
+			if !fn(objID, obj, err) {
+				return
+			}
+			continue
+		}
+
+		result, ok := pending[objID]
+		for !ok {
+			r, open := <-pool.results
+			if !open {
+				break
+			}
+			pending[r.objectID] = r
+			result, ok = pending[objID]
+		}
+		delete(pending, objID)
+		delete(pooled, objID) // A repeated ID is loaded from memory
+		if !ok {
+			result.obj, result.err = m.GetObject(objID)
+		}
+		if !fn(objID, result.obj, result.err) {
+			return
+		}
+	}
+}
+
+// inMemoryLocked reports whether objectID is held in memory, so GetObject
+// returns it without a load (caller holds the read lock).
+func (m *Manager) inMemoryLocked(objectID string) bool {
+	objectID = normalizeObjectID(objectID)
+	if _, ok := m.localObjects[objectID]; ok {
+		return true
+	}
+	_, ok := m.objects[objectID]
+	return ok
+}
+
+// workerCount returns how many objects forEachObject loads at once.
+func (m *Manager) workerCount() int {
+	if m.workers > 0 {
+		return m.workers
+	}
+	return DefaultOBEXWorkers
+}
+
+// getOBEXWorkers returns the object loading worker count from environment or default.
+func getOBEXWorkers() int {
+	if v := os.Getenv("P2KB_OBEX_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultOBEXWorkers
+}
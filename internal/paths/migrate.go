@@ -0,0 +1,161 @@
+package paths
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MigrateResult summarizes a MigrateCache run.
+type MigrateResult struct {
+	Files   int   // Files copied to the destination
+	Bytes   int64 // Their total size
+	Skipped int   // Files the destination already had, unchanged
+}
+
+// ErrDestinationNewer is returned by MigrateCache when the destination holds
+// newer copies of files it would overwrite, and force was not given.
+type ErrDestinationNewer struct {
+	Files []string // Relative to the cache directory
+}
+
+func (e *ErrDestinationNewer) Error() string {
+	return fmt.Sprintf("destination has newer copies of %d file(s), e.g. %s", len(e.Files), e.Files[0])
+}
+
+// migrateFile is one file MigrateCache will copy.
+type migrateFile struct {
+	rel  string
+	info fs.FileInfo
+}
+
+// MigrateCache copies the cache at source into dest, for when an install moves
+// and its old cache would otherwise be orphaned. Files keep their modification
+// times, which the content cache relies on. A file the destination already
+// has is replaced when the source copy is newer (this is how a newer
+// index/p2kb-index.json wins), skipped when both copies match, and, when the
+// destination copy is newer, replaced only with force: otherwise nothing is
+// copied and the error is an *ErrDestinationNewer. The source is left as is.
+func MigrateCache(source, dest string, force bool) (MigrateResult, error) {
+	var result MigrateResult
+
+	source, err := filepath.Abs(ExpandCacheDir(source))
+	if err != nil {
+		return result, err
+	}
+	dest, err = filepath.Abs(ExpandCacheDir(dest))
+	if err != nil {
+		return result, err
+	}
+	if info, err := os.Stat(source); err != nil {
+		return result, err
+	} else if !info.IsDir() {
+		return result, fmt.Errorf("source %s is not a directory", source)
+	}
+	if source == dest {
+		return result, fmt.Errorf("source and destination are both %s", source)
+	}
+	if Within(source, dest) {
+		return result, fmt.Errorf("destination %s is inside source %s", dest, source)
+	}
+
+	// Plan every copy first, so a conflict leaves the destination untouched
+	var copies []migrateFile
+	var newer []string
+	err = filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || isScratchFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+
+		existing, err := os.Stat(filepath.Join(dest, rel))
+		switch {
+		case err != nil:
+			// Not there yet
+		case existing.ModTime().Equal(info.ModTime()) && existing.Size() == info.Size():
+			result.Skipped++
+			return nil
+		case existing.ModTime().After(info.ModTime()) && !force:
+			newer = append(newer, filepath.ToSlash(rel))
+		}
+		copies = append(copies, migrateFile{rel: rel, info: info})
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	if len(newer) > 0 {
+		return MigrateResult{}, &ErrDestinationNewer{Files: newer}
+	}
+
+	for _, f := range copies {
+		if err := copyFile(filepath.Join(source, f.rel), filepath.Join(dest, f.rel), f.info); err != nil {
+			return result, fmt.Errorf("failed to copy %s: %w", f.rel, err)
+		}
+		result.Files++
+		result.Bytes += f.info.Size()
+	}
+	return result, nil
+}
+
+// Within reports whether path is root or lies beneath it. Both are cleaned
+// but not resolved, so they should be absolute.
+func Within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isScratchFile reports whether name is a half-written temp file or a
+// writability probe, neither of which belongs in a migrated cache.
+func isScratchFile(name string) bool {
+	return strings.HasSuffix(name, ".tmp") || name == ".write-test"
+}
+
+// copyFile copies src to dst through a temp file renamed into place, then
+// gives dst src's modification time.
+func copyFile(src, dst string, info fs.FileInfo) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
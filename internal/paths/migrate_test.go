@@ -0,0 +1,120 @@
+package paths
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCacheFile writes content to dir/rel with the given modification time.
+func writeCacheFile(t *testing.T, dir, rel, content string, mtime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateCache(t *testing.T) {
+	source, dest := t.TempDir(), filepath.Join(t.TempDir(), "new-cache")
+	old := time.Unix(1700000000, 0)
+	writeCacheFile(t, source, "index/p2kb-index.json", `{"v":2}`, old.Add(time.Hour))
+	writeCacheFile(t, source, "cache/p2kbPasm2Mov.yaml", "mnemonic: MOV\n", old)
+	writeCacheFile(t, source, "obex/2811.json", "{}", old)
+	writeCacheFile(t, source, "cache/p2kbPasm2Add.yaml.tmp", "partial", old)
+
+	// An older index at the destination gives way; a matching file is skipped
+	writeCacheFile(t, dest, "index/p2kb-index.json", `{"v":1}`, old)
+	writeCacheFile(t, dest, "obex/2811.json", "{}", old)
+
+	result, err := MigrateCache(source, dest, false)
+	if err != nil {
+		t.Fatalf("MigrateCache: %v", err)
+	}
+	if result.Files != 2 || result.Bytes != int64(len(`{"v":2}`)+len("mnemonic: MOV\n")) || result.Skipped != 1 {
+		t.Errorf("result = %+v, want 2 files, 21 bytes, 1 skipped", result)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, "index", "p2kb-index.json"))
+	if err != nil || string(data) != `{"v":2}` {
+		t.Errorf("index = %q, %v; want the source's", data, err)
+	}
+	info, err := os.Stat(filepath.Join(dest, "cache", "p2kbPasm2Mov.yaml"))
+	if err != nil {
+		t.Fatalf("migrated entry: %v", err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("migrated mtime = %v, want %v", info.ModTime(), old)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "cache", "p2kbPasm2Add.yaml.tmp")); !os.IsNotExist(err) {
+		t.Errorf("temp file migrated: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(source, "cache", "p2kbPasm2Mov.yaml")); err != nil {
+		t.Errorf("source entry gone: %v", err)
+	}
+}
+
+func TestMigrateCacheNewerDestination(t *testing.T) {
+	source, dest := t.TempDir(), t.TempDir()
+	old := time.Unix(1700000000, 0)
+	writeCacheFile(t, source, "index/p2kb-index.json", `{"v":1}`, old)
+	writeCacheFile(t, source, "cache/p2kbPasm2Mov.yaml", "mnemonic: MOV\n", old)
+	writeCacheFile(t, dest, "index/p2kb-index.json", `{"v":2}`, old.Add(time.Hour))
+
+	_, err := MigrateCache(source, dest, false)
+	var newer *ErrDestinationNewer
+	if !errors.As(err, &newer) || len(newer.Files) != 1 || newer.Files[0] != "index/p2kb-index.json" {
+		t.Fatalf("err = %v, want ErrDestinationNewer for the index", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "cache", "p2kbPasm2Mov.yaml")); !os.IsNotExist(err) {
+		t.Errorf("files copied despite the conflict: %v", err)
+	}
+
+	result, err := MigrateCache(source, dest, true)
+	if err != nil || result.Files != 2 {
+		t.Fatalf("forced MigrateCache = %+v, %v; want 2 files", result, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "index", "p2kb-index.json")); string(data) != `{"v":1}` {
+		t.Errorf("forced index = %q, want the source's", data)
+	}
+}
+
+func TestMigrateCacheRejectsOverlap(t *testing.T) {
+	source := t.TempDir()
+	for _, dest := range []string{source, filepath.Join(source, "inner")} {
+		if _, err := MigrateCache(source, dest, false); err == nil {
+			t.Errorf("MigrateCache(%s, %s) succeeded", source, dest)
+		}
+	}
+	if _, err := MigrateCache(filepath.Join(source, "missing"), t.TempDir(), false); err == nil {
+		t.Error("MigrateCache from a missing source succeeded")
+	}
+}
+
+func TestWithin(t *testing.T) {
+	root := filepath.FromSlash("/cache")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/cache", true},
+		{"/cache/index", true},
+		{"/cache/..index", true},
+		{"/", false},
+		{"/cache-old", false},
+		{"/other/cache", false},
+	}
+	for _, tt := range tests {
+		if got := Within(root, filepath.FromSlash(tt.path)); got != tt.want {
+			t.Errorf("Within(%s, %s) = %v, want %v", root, tt.path, got, tt.want)
+		}
+	}
+}
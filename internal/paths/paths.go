@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strings"
 )
 
 const (
@@ -30,7 +32,8 @@ const (
 // or is not writable, rather than silently falling back to another location.
 func GetCacheDir() (string, error) {
 	// Priority 1: Explicit environment variable override
-	if dir := os.Getenv("P2KB_CACHE_DIR"); dir != "" {
+	if raw := os.Getenv("P2KB_CACHE_DIR"); raw != "" {
+		dir := ExpandCacheDir(raw)
 		if err := ensureWritableDir(dir); err != nil {
 			return "", fmt.Errorf("P2KB_CACHE_DIR is set but not writable: %w", err)
 		}
@@ -64,6 +67,35 @@ func GetCacheDir() (string, error) {
 	return cacheDir, nil
 }
 
+// percentVarPattern matches Windows-style %VAR% references.
+var percentVarPattern = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_]*)%`)
+
+// ExpandCacheDir expands a P2KB_CACHE_DIR value: a leading "~" becomes the
+// current user's home directory, and $VAR / ${VAR} references are replaced via
+// os.ExpandEnv. On Windows, %VAR% references are expanded as well. Unset
+// variables expand to the empty string, as with os.ExpandEnv.
+func ExpandCacheDir(raw string) string {
+	dir := raw
+	if runtime.GOOS == "windows" {
+		dir = expandPercentVars(dir)
+	}
+	dir = os.ExpandEnv(dir)
+
+	if dir == "~" || strings.HasPrefix(dir, "~/") || strings.HasPrefix(dir, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[1:])
+		}
+	}
+	return dir
+}
+
+// expandPercentVars replaces %VAR% references with their environment values.
+func expandPercentVars(s string) string {
+	return percentVarPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		return os.Getenv(strings.Trim(ref, "%"))
+	})
+}
+
 // cacheDirForExe is a pure layout helper: given an already-resolved executable
 // path it returns the cache directory that should be used, without touching the
 // filesystem or environment variables.
@@ -125,6 +157,19 @@ func isContainerToolsInstall(exePath string) bool {
 	return filepath.Base(filepath.Dir(exePath)) == "platforms"
 }
 
+// IsContainerInstall reports whether the running executable is a
+// container-tools install; see isContainerToolsInstall.
+func IsContainerInstall() bool {
+	exePath, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+	return isContainerToolsInstall(exePath)
+}
+
 // GetCacheDirOrDefault returns the cache directory, falling back to a default on error.
 // This is provided for backward compatibility but logs a warning when falling back.
 func GetCacheDirOrDefault() string {
@@ -22,6 +22,86 @@ func TestGetCacheDirWithEnvVar(t *testing.T) {
 	}
 }
 
+func TestExpandCacheDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+	os.Setenv("P2KB_TEST_ROOT", "/srv/p2kb")
+	defer os.Unsetenv("P2KB_TEST_ROOT")
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"~/my-cache", filepath.Join(home, "my-cache")},
+		{"~", home},
+		{"$P2KB_TEST_ROOT/cache", "/srv/p2kb/cache"},
+		{"${P2KB_TEST_ROOT}/p2kb", "/srv/p2kb/p2kb"},
+		{"/plain/path", "/plain/path"},
+		{"/not/~/expanded", "/not/~/expanded"},
+	}
+
+	for _, tt := range tests {
+		if got := ExpandCacheDir(tt.raw); got != tt.want {
+			t.Errorf("ExpandCacheDir(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestExpandPercentVars(t *testing.T) {
+	os.Setenv("P2KB_TEST_APPDATA", `C:\Users\me\AppData\Roaming`)
+	defer os.Unsetenv("P2KB_TEST_APPDATA")
+
+	got := expandPercentVars(`%P2KB_TEST_APPDATA%\p2kb`)
+	if want := `C:\Users\me\AppData\Roaming\p2kb`; got != want {
+		t.Errorf("expandPercentVars = %q, want %q", got, want)
+	}
+	if got := expandPercentVars("100% sure"); got != "100% sure" {
+		t.Errorf("expandPercentVars changed a lone percent sign: %q", got)
+	}
+}
+
+func TestGetCacheDirExpandsEnvVar(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.Setenv("P2KB_TEST_TMP", tmpDir)
+	os.Setenv("P2KB_CACHE_DIR", "${P2KB_TEST_TMP}/p2kb")
+	defer os.Unsetenv("P2KB_TEST_TMP")
+	defer os.Unsetenv("P2KB_CACHE_DIR")
+
+	dir, err := GetCacheDir()
+	if err != nil {
+		t.Fatalf("GetCacheDir() error: %v", err)
+	}
+	want := filepath.Join(tmpDir, "p2kb")
+	if dir != want {
+		t.Errorf("GetCacheDir() = %q, want %q", dir, want)
+	}
+	if info, err := os.Stat(want); err != nil || !info.IsDir() {
+		t.Errorf("expanded cache directory was not created: %v", err)
+	}
+}
+
+func TestGetCacheDirExpandsTilde(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("HOME does not control os.UserHomeDir on Windows")
+	}
+	home := t.TempDir()
+	prevHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	os.Setenv("P2KB_CACHE_DIR", "~/my-cache")
+	defer os.Setenv("HOME", prevHome)
+	defer os.Unsetenv("P2KB_CACHE_DIR")
+
+	dir, err := GetCacheDir()
+	if err != nil {
+		t.Fatalf("GetCacheDir() error: %v", err)
+	}
+	if want := filepath.Join(home, "my-cache"); dir != want {
+		t.Errorf("GetCacheDir() = %q, want %q", dir, want)
+	}
+}
+
 func TestGetCacheDirOrDefault(t *testing.T) {
 	// This should always return a value, never panic
 	dir := GetCacheDirOrDefault()
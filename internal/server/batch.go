@@ -0,0 +1,46 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// isBatch reports whether a request line is a JSON-RPC batch: a JSON array,
+// so its first non-whitespace character is '['.
+func isBatch(line []byte) bool {
+	trimmed := bytes.TrimLeft(line, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// handleBatch handles the requests of a JSON-RPC batch in parallel and
+// returns their responses in request order. Notifications get no entry, so
+// the result is empty if every request was one. An empty batch is itself an
+// invalid request, answered with a single -32600 error.
+func (s *Server) handleBatch(ctx context.Context, reqs []MCPRequest) []*MCPResponse {
+	if len(reqs) == 0 {
+		return []*MCPResponse{{
+			JSONRPC: "2.0",
+			Error:   &MCPError{Code: -32600, Message: "Invalid Request", Data: "empty batch"},
+		}}
+	}
+
+	results := make([]*MCPResponse, len(reqs))
+	var wg sync.WaitGroup
+	for i := range reqs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = s.handleRequestContext(ctx, &reqs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	responses := make([]*MCPResponse, 0, len(results))
+	for _, resp := range results {
+		if resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	return responses
+}
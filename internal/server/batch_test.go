@@ -0,0 +1,60 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestServeBatchRequest(t *testing.T) {
+	srv := New("1.0.0")
+	in := strings.NewReader(`  [{"jsonrpc":"2.0","id":1,"method":"ping"},` +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"},` +
+		`{"jsonrpc":"2.0","id":"two","method":"no/such"}]` + "\n")
+	var out bytes.Buffer
+
+	if err := srv.serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("serve() = %v, want nil at EOF", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want one batch response: %q", len(lines), out.String())
+	}
+	var resps []MCPResponse
+	if err := json.Unmarshal([]byte(lines[0]), &resps); err != nil {
+		t.Fatalf("batch response %q is not a JSON array: %v", lines[0], err)
+	}
+	if len(resps) != 2 {
+		t.Fatalf("got %d responses, want 2 (the notification gets none)", len(resps))
+	}
+	if resps[0].ID != float64(1) || resps[0].Error != nil {
+		t.Errorf("first response = %+v, want ping result for id 1", resps[0])
+	}
+	if resps[1].ID != "two" || resps[1].Error == nil || resps[1].Error.Code != -32601 {
+		t.Errorf("second response = %+v, want -32601 for id two", resps[1])
+	}
+}
+
+func TestServeBatchOfNotificationsGetsNoResponse(t *testing.T) {
+	srv := New("1.0.0")
+	in := strings.NewReader(`[{"jsonrpc":"2.0","method":"notifications/initialized"}]` + "\n")
+	var out bytes.Buffer
+
+	if err := srv.serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("serve() = %v, want nil at EOF", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("output = %q, want nothing for a batch of notifications", out.String())
+	}
+}
+
+func TestHandleBatchEmpty(t *testing.T) {
+	srv := New("1.0.0")
+	resps := srv.handleBatch(context.Background(), nil)
+	if len(resps) != 1 || resps[0].Error == nil || resps[0].Error.Code != -32600 {
+		t.Errorf("handleBatch(empty) = %+v, want a single -32600 error", resps)
+	}
+}
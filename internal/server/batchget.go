@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxBatchGetKeys is the most keys p2kb_batch_get accepts at once.
+	maxBatchGetKeys = 20
+
+	// batchGetConcurrency caps the fetches p2kb_batch_get runs at once.
+	batchGetConcurrency = 4
+)
+
+// batchGetResult is one key's entry in the p2kb_batch_get response: its
+// content, or why it could not be fetched.
+type batchGetResult struct {
+	Key     string `json:"key"`
+	Content string `json:"content,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handleBatchGet implements p2kb_batch_get - fetch several keys in one call.
+// Results come back in request order, and a key that fails gets an error in
+// its own entry rather than failing the whole request.
+func (s *Server) handleBatchGet(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if len(params.Keys) == 0 {
+		return s.errorResponse(id, -32602, "Missing required parameter", "keys")
+	}
+	if len(params.Keys) > maxBatchGetKeys {
+		return s.errorResponse(id, -32602, "Too many keys", fmt.Sprintf("keys accepts at most %d entries", maxBatchGetKeys))
+	}
+
+	results := make([]batchGetResult, len(params.Keys))
+	sem := make(chan struct{}, batchGetConcurrency)
+	var wg sync.WaitGroup
+	for i, key := range params.Keys {
+		wg.Add(1)
+		go func(result *batchGetResult, key string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			*result = s.batchGetOne(ctx, id, key)
+		}(&results[i], key)
+	}
+	wg.Wait()
+
+	return s.successResponse(id, map[string]interface{}{
+		"results": results,
+	})
+}
+
+// batchGetOne fetches one p2kb_batch_get key, accepting aliases the way
+// p2kb_get does.
+func (s *Server) batchGetOne(ctx context.Context, id interface{}, key string) batchGetResult {
+	if strings.TrimSpace(key) == "" {
+		return batchGetResult{Key: key, Error: "empty key"}
+	}
+
+	canonical, resolvedFrom := key, ""
+	if resolution := s.indexManager.ResolveKey(key); resolution.Found {
+		canonical, resolvedFrom = resolution.CanonicalKey, resolution.ResolvedFrom
+	}
+
+	result, errResp := s.pagedContentResult(ctx, id, canonical, resolvedFrom, contentPage{})
+	if errResp != nil {
+		return batchGetResult{Key: key, Error: errorText(errResp.Error)}
+	}
+	content, _ := result["content"].(string)
+	return batchGetResult{Key: key, Content: content}
+}
+
+// errorText flattens an MCP error to one line: its message, followed by the
+// underlying error when the data carries one.
+func errorText(e *MCPError) string {
+	if data, ok := e.Data.(map[string]interface{}); ok {
+		if detail, ok := data["error"].(string); ok && detail != "" {
+			return e.Message + ": " + detail
+		}
+	}
+	return e.Message
+}
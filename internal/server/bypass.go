@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/cache"
+	"github.com/ironsheep/p2kb-mcp/internal/index"
+)
+
+// DefaultBypassCacheMaxPerMin is how many p2kb_get calls a minute may use
+// bypass_cache. Override with P2KB_BYPASS_CACHE_MAX_PER_MIN.
+const DefaultBypassCacheMaxPerMin = 5
+
+// bypassWindow is how often the bypass count resets. It is a var so tests can
+// shorten it.
+var bypassWindow = time.Minute
+
+// bypassLimiter counts p2kb_get bypass_cache calls, so a client cannot set off
+// a stampede of GitHub fetches. The count resets every bypassWindow, from a
+// goroutine started by the first bypass. The zero value is ready to use.
+type bypassLimiter struct {
+	used      atomic.Int64
+	nextReset atomic.Int64 // Unix nanoseconds of the next reset
+	start     sync.Once
+}
+
+// take claims one bypass of limit per window. It returns how many remain
+// after this one, or false and the time until the next reset when none do.
+func (l *bypassLimiter) take(limit int) (remaining int, wait time.Duration, ok bool) {
+	l.start.Do(func() {
+		l.nextReset.Store(time.Now().Add(bypassWindow).UnixNano())
+		go l.resetEvery(bypassWindow)
+	})
+	for {
+		n := l.used.Load()
+		if n >= int64(limit) {
+			return 0, time.Until(time.Unix(0, l.nextReset.Load())), false
+		}
+		if l.used.CompareAndSwap(n, n+1) {
+			return limit - int(n) - 1, 0, true
+		}
+	}
+}
+
+// resetEvery zeroes the count every interval, for the life of the server.
+func (l *bypassLimiter) resetEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		l.nextReset.Store(now.Add(interval).UnixNano())
+		l.used.Store(0)
+	}
+}
+
+// parseBypassCacheMax returns a P2KB_BYPASS_CACHE_MAX_PER_MIN value, or
+// DefaultBypassCacheMaxPerMin if it is unset or invalid. 0 refuses every bypass.
+func parseBypassCacheMax(v string) int {
+	if v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return DefaultBypassCacheMaxPerMin
+}
+
+// getBypassingCache is getContentWithRelated for p2kb_get with bypass_cache:
+// the content is fetched from GitHub, skipping the memory and disk caches,
+// and replaces the cached copy. Calls beyond the per-minute limit are refused.
+func (s *Server) getBypassingCache(ctx context.Context, id interface{}, key string, resolvedFrom string, page contentPage) *MCPResponse {
+	limit := parseBypassCacheMax(s.getenv("P2KB_BYPASS_CACHE_MAX_PER_MIN"))
+	remaining, wait, ok := s.bypass.take(limit)
+	if !ok {
+		return s.errorResponse(id, -32000, "Cache bypass limit reached", map[string]interface{}{
+			"key":              key,
+			"limit_per_min":    limit,
+			"retry_after_secs": int(wait.Round(time.Second).Seconds()),
+			"hint":             "Call p2kb_get without bypass_cache for the cached content",
+		})
+	}
+
+	if err := s.refetchContent(key); err != nil {
+		return s.contentErrorResponse(id, key, err)
+	}
+	// Responses kept for repeat lookups may hold the content just replaced
+	s.requestCache.clear()
+
+	result, errResp := s.pagedContentResult(ctx, id, key, resolvedFrom, page)
+	if errResp != nil {
+		return errResp
+	}
+	result["bypass_cache_remaining"] = remaining
+	return s.successResponse(id, result)
+}
+
+// refetchContent fetches key's content from its repository, skipping the
+// memory and disk caches, and caches it in place of the old copy.
+func (s *Server) refetchContent(key string) error {
+	path, mtime, sha256, err := s.indexManager.GetKeyPath(key)
+	if err != nil {
+		return err
+	}
+	baseURL := cache.BaseContentURL
+	if source := s.indexManager.GetKeySource(key); source != "" && source != index.IndexURL {
+		baseURL = index.ContentBaseURL(source)
+	}
+	_, err = s.cacheManager.Refetch(baseURL, key, path, sha256, mtime)
+	return err
+}
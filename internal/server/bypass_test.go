@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetBypassCache(t *testing.T) {
+	t.Setenv("P2KB_BYPASS_CACHE_MAX_PER_MIN", "2")
+	var fetches atomic.Int32
+	files := map[string]interface{}{"p2kbPasm2Nop": map[string]interface{}{"path": "nop.yaml", "mtime": 1700000000}}
+	srv, cleanup := newServerWithIndex(t, files, map[string]interface{}{}, func(w http.ResponseWriter, r *http.Request) {
+		n := fetches.Add(1)
+		_, _ = w.Write([]byte("name: NOP\nversion: " + string(rune('0'+n)) + "\n"))
+	})
+	defer cleanup()
+
+	call := func(args map[string]interface{}) *MCPResponse {
+		params, _ := json.Marshal(map[string]interface{}{"name": "p2kb_get", "arguments": args})
+		return srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: params})
+	}
+	plain := map[string]interface{}{"query": "p2kbPasm2Nop"}
+	bypass := map[string]interface{}{"query": "p2kbPasm2Nop", "bypass_cache": true}
+
+	call(plain)
+	call(plain)
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("two cached lookups fetched %d times, want 1", n)
+	}
+
+	for want := 1; want >= 0; want-- {
+		result := extractResultMap(t, call(bypass))
+		if result["bypass_cache_remaining"] != float64(want) {
+			t.Errorf("bypass_cache_remaining = %v, want %d", result["bypass_cache_remaining"], want)
+		}
+	}
+	if n := fetches.Load(); n != 3 {
+		t.Errorf("after two bypasses, %d fetches, want 3", n)
+	}
+
+	// A plain lookup now sees the refetched content, not a stored response
+	content, _ := extractResultMap(t, call(plain))["content"].(string)
+	if content != "name: NOP\nversion: 3\n" {
+		t.Errorf("content after bypass = %q, want the third fetch", content)
+	}
+
+	resp := call(bypass)
+	if resp.Error == nil || resp.Error.Message != "Cache bypass limit reached" {
+		t.Fatalf("third bypass error = %v, want the limit", resp.Error)
+	}
+	if n := fetches.Load(); n != 3 {
+		t.Errorf("refused bypass fetched; %d fetches, want 3", n)
+	}
+
+	resp = srv.handleGet(context.Background(), 1, json.RawMessage(`{"queries": ["p2kbPasm2Nop"], "bypass_cache": true}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("bypass with queries: error = %v, want -32602", resp.Error)
+	}
+}
+
+func TestBypassLimiterResets(t *testing.T) {
+	prev := bypassWindow
+	bypassWindow = 50 * time.Millisecond
+	defer func() { bypassWindow = prev }()
+
+	var l bypassLimiter
+	for i := 2; i >= 0; i-- {
+		if remaining, _, ok := l.take(3); !ok || remaining != i {
+			t.Fatalf("take = %d, %v, want %d remaining", remaining, ok, i)
+		}
+	}
+	_, wait, ok := l.take(3)
+	if ok || wait <= 0 || wait > bypassWindow {
+		t.Fatalf("take past the limit = ok %v, wait %v; want refused with a wait within the window", ok, wait)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if remaining, _, ok := l.take(3); ok {
+			if remaining != 2 {
+				t.Errorf("first take after reset left %d, want 2", remaining)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("count never reset")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseBypassCacheMax(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", DefaultBypassCacheMaxPerMin},
+		{"10", 10},
+		{"0", 0},
+		{"-1", DefaultBypassCacheMaxPerMin},
+		{"lots", DefaultBypassCacheMaxPerMin},
+	}
+	for _, tt := range tests {
+		if got := parseBypassCacheMax(tt.value); got != tt.want {
+			t.Errorf("parseBypassCacheMax(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/cache"
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+)
+
+// cacheDumpFilename is where p2kb_cache_dump writes, inside the cache directory,
+// when no output_path is given.
+const cacheDumpFilename = "debug-dump.json"
+
+// CacheDump is the p2kb_cache_dump document.
+type CacheDump struct {
+	GeneratedAt  time.Time           `json:"generated_at"`
+	IndexSummary CacheDumpIndex      `json:"index_summary"`
+	MemoryCache  []cache.MemoryEntry `json:"memory_cache"`
+	OBEXObjects  []obex.MemoryObject `json:"obex_objects"`
+}
+
+// CacheDumpIndex summarizes the loaded index in a CacheDump.
+type CacheDumpIndex struct {
+	Version      string `json:"version"`
+	TotalEntries int    `json:"total_entries"`
+}
+
+// debugToolsEnabled reports whether P2KB_ENABLE_DEBUG_TOOLS=true, which
+// allows p2kb_cache_dump and registers p2kb_raw_get.
+func debugToolsEnabled() bool {
+	return os.Getenv("P2KB_ENABLE_DEBUG_TOOLS") == "true"
+}
+
+// handleCacheDump implements p2kb_cache_dump - a debugging snapshot of the
+// memory caches. It exposes cached content, so it is off unless
+// P2KB_ENABLE_DEBUG_TOOLS=true.
+func (s *Server) handleCacheDump(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		OutputPath string `json:"output_path"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+	}
+
+	if !debugToolsEnabled() {
+		return s.errorResponse(id, -32000, "Debug tools disabled", map[string]interface{}{
+			"hint": "Set P2KB_ENABLE_DEBUG_TOOLS=true in the server environment to allow p2kb_cache_dump",
+		})
+	}
+
+	stats := s.indexManager.GetStats()
+	dump := CacheDump{
+		GeneratedAt: time.Now().UTC(),
+		IndexSummary: CacheDumpIndex{
+			Version:      stats.Version,
+			TotalEntries: stats.TotalEntries,
+		},
+		MemoryCache: s.cacheManager.MemoryEntries(),
+		OBEXObjects: s.obexManager.MemoryObjects(),
+	}
+
+	// "-" returns the dump in the response instead of writing a file
+	if params.OutputPath == "-" {
+		return s.successResponse(id, dump)
+	}
+
+	outputPath := params.OutputPath
+	if outputPath == "" {
+		outputPath = filepath.Join(s.cacheManager.CacheDir(), cacheDumpFilename)
+	}
+
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return s.errorResponse(id, -32000, "Failed to encode cache dump", err.Error())
+	}
+	// Owner-only: the dump holds cached content
+	if err := os.WriteFile(outputPath, append(data, '\n'), 0600); err != nil {
+		return s.errorResponse(id, -32000, "Failed to write cache dump", err.Error())
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":           "cache_dump_written",
+		"output_path":    outputPath,
+		"memory_entries": len(dump.MemoryCache),
+		"obex_objects":   len(dump.OBEXObjects),
+		"message":        fmt.Sprintf("Wrote %d memory cache entries and %d OBEX objects to %s", len(dump.MemoryCache), len(dump.OBEXObjects), outputPath),
+	})
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newServerWithCachedContent(t *testing.T) (*Server, func()) {
+	t.Helper()
+	files := map[string]interface{}{
+		"p2kbPasm2Add": map[string]interface{}{"path": "pasm2/add.yaml", "mtime": 1700000000},
+	}
+	srv, cleanup := newServerWithFilesAndContent(t, files, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("mnemonic: ADD\n"))
+	})
+	if _, err := srv.getContent("p2kbPasm2Add"); err != nil {
+		cleanup()
+		t.Fatalf("getContent: %v", err)
+	}
+	seedOBEXObject(t, "2811", "obexObjectValid.yaml")
+	if _, err := srv.obexManager.GetObject("2811"); err != nil {
+		cleanup()
+		t.Fatalf("GetObject: %v", err)
+	}
+	return srv, cleanup
+}
+
+func TestHandleCacheDumpDisabled(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+
+	resp := srv.handleCacheDump(1, json.RawMessage(`{"output_path": "-"}`))
+	if resp.Error == nil || resp.Error.Code != -32000 {
+		t.Fatalf("dump without P2KB_ENABLE_DEBUG_TOOLS = %+v, want -32000", resp)
+	}
+}
+
+func TestHandleCacheDumpInline(t *testing.T) {
+	srv, cleanup := newServerWithCachedContent(t)
+	defer cleanup()
+	t.Setenv("P2KB_ENABLE_DEBUG_TOOLS", "true")
+
+	result := extractResultMap(t, srv.handleCacheDump(1, json.RawMessage(`{"output_path": "-"}`)))
+	summary, _ := result["index_summary"].(map[string]interface{})
+	if _, ok := summary["total_entries"]; summary["version"] != "test-1.0" || !ok {
+		t.Errorf("index_summary = %v, want version test-1.0 and total_entries", summary)
+	}
+
+	memory, _ := result["memory_cache"].([]interface{})
+	if len(memory) != 1 {
+		t.Fatalf("memory_cache = %v, want 1 entry", result["memory_cache"])
+	}
+	entry := memory[0].(map[string]interface{})
+	if entry["key"] != "p2kbPasm2Add" || entry["first_100_chars"] == "" || len(entry["content_hash"].(string)) != 64 {
+		t.Errorf("memory entry = %v", entry)
+	}
+
+	objects, _ := result["obex_objects"].([]interface{})
+	if len(objects) != 1 || objects[0].(map[string]interface{})["object_id"] != "2811" {
+		t.Errorf("obex_objects = %v, want 2811", result["obex_objects"])
+	}
+}
+
+func TestHandleCacheDumpToFile(t *testing.T) {
+	srv, cleanup := newServerWithCachedContent(t)
+	defer cleanup()
+	t.Setenv("P2KB_ENABLE_DEBUG_TOOLS", "true")
+
+	result := extractResultMap(t, srv.handleCacheDump(1, nil))
+	wantPath := filepath.Join(srv.cacheManager.CacheDir(), cacheDumpFilename)
+	if result["type"] != "cache_dump_written" || result["output_path"] != wantPath {
+		t.Fatalf("result = %v, want dump written to %s", result, wantPath)
+	}
+
+	data, err := os.ReadFile(wantPath)
+	if err != nil {
+		t.Fatalf("read dump: %v", err)
+	}
+	var dump CacheDump
+	if err := json.Unmarshal(data, &dump); err != nil {
+		t.Fatalf("dump is not valid JSON: %v", err)
+	}
+	if len(dump.MemoryCache) != 1 || len(dump.OBEXObjects) != 1 {
+		t.Errorf("dump has %d memory entries and %d objects, want 1 and 1", len(dump.MemoryCache), len(dump.OBEXObjects))
+	}
+	if info, err := os.Stat(wantPath); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("dump mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	resp := srv.handleCacheDump(1, json.RawMessage(`{"output_path": "`+filepath.Join(t.TempDir(), "missing", "dump.json")+`"}`))
+	if resp.Error == nil || resp.Error.Code != -32000 {
+		t.Errorf("unwritable path = %+v, want -32000", resp)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/ironsheep/p2kb-mcp/internal/yaml"
+)
+
+// Bounds on how many keys p2kb_compare sets side by side.
+const (
+	minCompareKeys = 2
+	maxCompareKeys = 4
+)
+
+// compareFields are the entry fields p2kb_compare reports, in order.
+var compareFields = []string{"mnemonic", "syntax", "description", "flags", "related_instructions"}
+
+// handleCompare implements p2kb_compare - the fields of two to four entries
+// side by side, e.g. ADD, ADDX and ADDSX. Each field maps every key to its
+// text, or to null when the entry has no such field or could not be fetched;
+// errors says why for the latter.
+func (s *Server) handleCompare(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if len(params.Keys) < minCompareKeys || len(params.Keys) > maxCompareKeys {
+		return s.errorResponse(id, -32602, "Invalid keys", fmt.Sprintf("keys needs %d to %d entries, got %d", minCompareKeys, maxCompareKeys, len(params.Keys)))
+	}
+	seen := make(map[string]bool, len(params.Keys))
+	for _, key := range params.Keys {
+		if seen[key] {
+			return s.errorResponse(id, -32602, "Invalid keys", fmt.Sprintf("key %q is listed twice", key))
+		}
+		seen[key] = true
+	}
+
+	entries := make([]batchGetResult, len(params.Keys))
+	var wg sync.WaitGroup
+	for i, key := range params.Keys {
+		wg.Add(1)
+		go func(entry *batchGetResult, key string) {
+			defer wg.Done()
+			*entry = s.batchGetOne(ctx, id, key)
+		}(&entries[i], key)
+	}
+	wg.Wait()
+
+	fields := make(map[string]map[string]interface{}, len(compareFields))
+	for _, field := range compareFields {
+		fields[field] = make(map[string]interface{}, len(params.Keys))
+	}
+	keyErrors := make(map[string]string)
+	for _, entry := range entries {
+		var values map[string]string
+		if entry.Error != "" {
+			keyErrors[entry.Key] = entry.Error
+		} else if extracted, err := yaml.ExtractFields(entry.Content, compareFields...); err != nil {
+			keyErrors[entry.Key] = err.Error()
+		} else {
+			values = extracted
+		}
+
+		for _, field := range compareFields {
+			if value, ok := values[field]; ok {
+				fields[field][entry.Key] = value
+			} else {
+				fields[field][entry.Key] = nil
+			}
+		}
+	}
+
+	result := map[string]interface{}{
+		"type":   "comparison",
+		"keys":   params.Keys,
+		"fields": fields,
+	}
+	if len(keyErrors) > 0 {
+		result["errors"] = keyErrors
+	}
+	return s.successResponse(id, result)
+}
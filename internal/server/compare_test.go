@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ironsheep/p2kb-mcp/internal/testdata"
+)
+
+// fixtureBody is a newBatchGetServer body serving the key's fixture.
+func fixtureBody(key string) []byte {
+	return testdata.MustGetFixture(key + ".yaml")
+}
+
+func TestHandleCompare(t *testing.T) {
+	srv, cleanup := newBatchGetServer(t, fixtureBody)
+	defer cleanup()
+
+	args, _ := json.Marshal(map[string]interface{}{"keys": []string{"p2kbPasm2Add", "p2kbPasm2Mov"}})
+	result := extractResultMap(t, srv.handleCompare(context.Background(), 1, args))
+
+	if result["type"] != "comparison" || !reflect.DeepEqual(result["keys"], []interface{}{"p2kbPasm2Add", "p2kbPasm2Mov"}) {
+		t.Errorf("type = %v, keys = %v", result["type"], result["keys"])
+	}
+	if _, ok := result["errors"]; ok {
+		t.Errorf("errors = %v, want none", result["errors"])
+	}
+
+	fields, _ := result["fields"].(map[string]interface{})
+	want := map[string]map[string]interface{}{
+		"mnemonic": {"p2kbPasm2Add": "ADD", "p2kbPasm2Mov": "MOV"},
+		"syntax":   {"p2kbPasm2Add": "ADD D,S\nADD D,#S", "p2kbPasm2Mov": "MOV D,S\nMOV D,#N"},
+		"flags":    {"p2kbPasm2Add": nil, "p2kbPasm2Mov": "C: Set if S[31] = 1\nZ: Set if D = 0"},
+	}
+	for field, values := range want {
+		got, _ := fields[field].(map[string]interface{})
+		for key, value := range values {
+			if got[key] != value {
+				t.Errorf("fields.%s.%s = %#v, want %#v", field, key, got[key], value)
+			}
+		}
+	}
+	for _, field := range compareFields {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("fields has no %s", field)
+		}
+	}
+}
+
+func TestHandleCompareMissingKey(t *testing.T) {
+	srv, cleanup := newBatchGetServer(t, fixtureBody)
+	defer cleanup()
+
+	args, _ := json.Marshal(map[string]interface{}{"keys": []string{"p2kbPasm2Add", "p2kbPasm2Sub"}})
+	result := extractResultMap(t, srv.handleCompare(context.Background(), 1, args))
+
+	fields, _ := result["fields"].(map[string]interface{})
+	for _, field := range compareFields {
+		values, _ := fields[field].(map[string]interface{})
+		if value, ok := values["p2kbPasm2Sub"]; !ok || value != nil {
+			t.Errorf("fields.%s.p2kbPasm2Sub = %#v, want null", field, value)
+		}
+	}
+	if mnemonic, _ := fields["mnemonic"].(map[string]interface{}); mnemonic["p2kbPasm2Add"] != "ADD" {
+		t.Errorf("fields.mnemonic.p2kbPasm2Add = %v, want ADD", mnemonic["p2kbPasm2Add"])
+	}
+
+	errors, _ := result["errors"].(map[string]interface{})
+	if msg, _ := errors["p2kbPasm2Sub"].(string); !strings.Contains(msg, "p2kbPasm2Sub") {
+		t.Errorf("errors = %v, want an explanation for p2kbPasm2Sub", result["errors"])
+	}
+	if _, ok := errors["p2kbPasm2Add"]; ok {
+		t.Error("errors has an entry for a key that was fetched")
+	}
+}
+
+func TestHandleCompareInvalidKeys(t *testing.T) {
+	srv, cleanup := newBatchGetServer(t, fixtureBody)
+	defer cleanup()
+
+	for _, keys := range [][]string{
+		{"p2kbPasm2Add"},
+		{"a", "b", "c", "d", "e"},
+		{"p2kbPasm2Add", "p2kbPasm2Add"},
+	} {
+		args, _ := json.Marshal(map[string]interface{}{"keys": keys})
+		resp := srv.handleCompare(context.Background(), 1, args)
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("keys %v: error = %v, want -32602", keys, resp.Error)
+		}
+	}
+}
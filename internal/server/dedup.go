@@ -0,0 +1,78 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync/atomic"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// readOnlyTools are the tools whose identical concurrent calls callGroup may
+// coalesce. A tool that changes state (pins, settings, refreshes, downloads,
+// migrations) must run once per call, as must p2kb_quiz, whose every call
+// draws new questions.
+var readOnlyTools = map[string]bool{
+	"p2kb_get":                   true,
+	"p2kb_batch_get":             true,
+	"p2kb_compare":               true,
+	"p2kb_related":               true,
+	"p2kb_find":                  true,
+	"p2kb_category_tree":         true,
+	"p2kb_obex_get":              true,
+	"p2kb_obex_find":             true,
+	"p2kb_discover":              true,
+	"p2kb_obex_author_detail":    true,
+	"p2kb_obex_tag_search":       true,
+	"p2kb_obex_preview":          true,
+	"p2kb_obex_readme":           true,
+	"p2kb_obex_cite":             true,
+	"p2kb_obex_verify":           true,
+	"p2kb_version":               true,
+	"p2kb_suggest":               true,
+	"p2kb_list_keys":             true,
+	"p2kb_healthcheck":           true,
+	"p2kb_raw_get":               true,
+	"p2kb_find_duplicates":       true,
+	"p2kb_obex_stats":            true,
+	"p2kb_obex_dependency_graph": true,
+}
+
+// callGroup coalesces identical concurrent tool calls with a
+// singleflight.Group: the first caller runs the call and callers arriving
+// before it returns get the same response. The zero value is ready to use.
+type callGroup struct {
+	group singleflight.Group
+
+	waiting      atomic.Int64 // Callers waiting for a response
+	deduplicated atomic.Int64 // Calls answered from another caller's run
+}
+
+// do runs fn for key unless a call with the same key is already running, in
+// which case it waits for that call and returns its response with shared set.
+func (g *callGroup) do(key string, fn func() *MCPResponse) (resp *MCPResponse, shared bool) {
+	// ran is only read once the result arrives, after fn has returned
+	ran := false
+	results := g.group.DoChan(key, func() (interface{}, error) {
+		ran = true
+		return fn(), nil
+	})
+
+	g.waiting.Add(1)
+	result := <-results
+	g.waiting.Add(-1)
+
+	if !ran {
+		g.deduplicated.Add(1)
+	}
+	resp, _ = result.Val.(*MCPResponse)
+	return resp, !ran
+}
+
+// toolCallKey identifies a tool call for deduplication: the tool name plus the
+// SHA-256 of its raw arguments.
+func toolCallKey(name string, args json.RawMessage) string {
+	sum := sha256.Sum256(args)
+	return name + ":" + hex.EncodeToString(sum[:])
+}
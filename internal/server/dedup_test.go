@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdenticalConcurrentCallsShareOneFetch(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	files := map[string]interface{}{
+		"p2kbPasm2Add": map[string]interface{}{"path": "pasm2/add.yaml", "mtime": 1700000000},
+	}
+	srv, cleanup := newServerWithFilesAndContent(t, files, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		_, _ = w.Write([]byte("mnemonic: ADD\n"))
+	})
+	defer cleanup()
+	// Load the index first so every call goes straight to the content fetch
+	if err := srv.indexManager.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex: %v", err)
+	}
+
+	const calls = 10
+	params, _ := json.Marshal(map[string]interface{}{
+		"name":      "p2kb_get",
+		"arguments": map[string]interface{}{"query": "p2kbPasm2Add"},
+	})
+	responses := make([]*MCPResponse, calls)
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: i, Method: "tools/call", Params: params})
+		}(i)
+	}
+
+	// Hold the fetch until every call is waiting on it
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.calls.waiting.Load() < calls {
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatalf("only %d calls waiting", srv.calls.waiting.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := hits.Load(); got != 1 {
+		t.Errorf("content fetched %d times, want 1", got)
+	}
+	for i, resp := range responses {
+		if resp.ID != i {
+			t.Errorf("response %d has ID %v", i, resp.ID)
+		}
+		if resp.Error != nil {
+			t.Errorf("response %d: %s", i, resp.Error.Message)
+			continue
+		}
+		if result := extractResultMap(t, resp); result["key"] != "p2kbPasm2Add" {
+			t.Errorf("response %d = %v, want p2kbPasm2Add", i, result)
+		}
+	}
+
+	version := extractResultMap(t, srv.handleVersion(1))
+	if version["deduplicated_calls_total"] != float64(calls-1) {
+		t.Errorf("deduplicated_calls_total = %v, want %d", version["deduplicated_calls_total"], calls-1)
+	}
+}
+
+func TestCallGroupRunsSequentialCallsSeparately(t *testing.T) {
+	var g callGroup
+	runs := 0
+	for i := 0; i < 3; i++ {
+		resp, shared := g.do("p2kb_version:x", func() *MCPResponse {
+			runs++
+			return &MCPResponse{ID: i}
+		})
+		if shared || resp.ID != i {
+			t.Errorf("call %d: shared=%v ID=%v, want its own run", i, shared, resp.ID)
+		}
+	}
+	if runs != 3 || g.deduplicated.Load() != 0 {
+		t.Errorf("runs = %d, deduplicated = %d; want 3 and 0", runs, g.deduplicated.Load())
+	}
+}
+
+func TestReadOnlyTools(t *testing.T) {
+	for _, name := range []string{"p2kb_pin", "p2kb_unpin", "p2kb_refresh", "p2kb_settings", "p2kb_migrate_cache",
+		"p2kb_obex_download", "p2kb_obex_build_index", "p2kb_memory_pressure", "p2kb_cache_dump", "p2kb_quiz"} {
+		if readOnlyTools[name] {
+			t.Errorf("%s would have identical concurrent calls coalesced", name)
+		}
+	}
+
+	defined := make(map[string]bool)
+	for _, tool := range GetToolDefinitions() {
+		defined[tool.Name] = true
+	}
+	for name := range readOnlyTools {
+		if !defined[name] && name != "p2kb_raw_get" {
+			t.Errorf("readOnlyTools names %s, which is not a tool", name)
+		}
+	}
+}
+
+func TestToolCallKey(t *testing.T) {
+	a := toolCallKey("p2kb_get", json.RawMessage(`{"query":"ADD"}`))
+	if a != toolCallKey("p2kb_get", json.RawMessage(`{"query":"ADD"}`)) {
+		t.Error("identical calls have different keys")
+	}
+	if a == toolCallKey("p2kb_get", json.RawMessage(`{"query":"MOV"}`)) {
+		t.Error("different arguments share a key")
+	}
+	if a == toolCallKey("p2kb_find", json.RawMessage(`{"query":"ADD"}`)) {
+		t.Error("different tools share a key")
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// validFieldPath reports whether path is a usable p2kb_get field: dot-separated
+// names with none empty.
+func validFieldPath(path string) bool {
+	for _, name := range strings.Split(path, ".") {
+		if strings.TrimSpace(name) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// selectField replaces result's content with the value of one YAML field,
+// named by a dot path such as "syntax" or "flags.Z", leaving field and value
+// in its place. A number steps into a sequence ("syntax.0"). The content is
+// the filtered form, so metadata fields are never found.
+func (s *Server) selectField(id interface{}, key string, result map[string]interface{}, path string) *MCPResponse {
+	content, _ := result["content"].(string)
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return s.errorResponse(id, -32000, fmt.Sprintf("Content for '%s' is not a YAML mapping", key), err.Error())
+	}
+
+	var value interface{} = doc
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		next, ok := fieldChild(value, name)
+		if !ok {
+			data := map[string]interface{}{
+				"key":              key,
+				"field":            path,
+				"available_fields": sortedKeys(doc),
+			}
+			if i > 0 {
+				data["found"] = strings.Join(names[:i], ".")
+				data["found_type"] = yamlTypeName(value)
+			}
+			return s.errorResponse(id, -32602, fmt.Sprintf("Field '%s' not found in '%s'", path, key), data)
+		}
+		value = next
+	}
+
+	delete(result, "content")
+	result["type"] = "field"
+	result["field"] = path
+	result["value"] = jsonValue(value)
+	return nil
+}
+
+// fieldChild returns the member of a decoded YAML mapping or sequence named
+// by name.
+func fieldChild(value interface{}, name string) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[name]
+		return child, ok
+	case map[interface{}]interface{}:
+		for k, child := range v {
+			if fmt.Sprint(k) == name {
+				return child, true
+			}
+		}
+	case []interface{}:
+		if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(v) {
+			return v[i], true
+		}
+	}
+	return nil, false
+}
+
+// yamlTypeName describes a decoded YAML value for an error message.
+func yamlTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "mapping"
+	case []interface{}:
+		return "sequence"
+	case nil:
+		return "null"
+	}
+	return "scalar"
+}
+
+// jsonValue converts a decoded YAML value to one encoding/json accepts:
+// mappings with non-string keys, which yaml.v3 produces for keys such as
+// 0 or true, get their keys as strings.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			out[k] = jsonValue(child)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			out[fmt.Sprint(k)] = jsonValue(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = jsonValue(child)
+		}
+		return out
+	}
+	return value
+}
+
+// sortedKeys returns doc's keys in order.
+func sortedKeys(doc map[string]interface{}) []string {
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// fieldEntry is the YAML served to the field tests.
+const fieldEntry = `mnemonic: ADD
+last_updated: "2025-01-01"
+syntax:
+  - "ADD D,S"
+  - "ADD D,#S"
+operands:
+  D: "Destination register"
+  S: "Source register or #immediate"
+flags:
+  C: "Set on carry"
+  Z: "Set if result is zero"
+encoding: "EEEE 0001000 CZI DDDDDDDDD SSSSSSSSS"
+`
+
+func newFieldServer(t *testing.T) (*Server, func()) {
+	t.Helper()
+	t.Setenv("P2KB_REQUEST_CACHE_TTL_SECS", "0")
+	files := map[string]interface{}{"p2kbPasm2Add": map[string]interface{}{"path": "add.yaml", "mtime": 1700000000}}
+	return newServerWithIndex(t, files, map[string]interface{}{"pasm2_math": []string{"p2kbPasm2Add"}}, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(fieldEntry))
+	})
+}
+
+func TestHandleGetField(t *testing.T) {
+	srv, cleanup := newFieldServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		field string
+		want  interface{}
+	}{
+		{"encoding", "EEEE 0001000 CZI DDDDDDDDD SSSSSSSSS"},
+		{"operands.D", "Destination register"},
+		{"flags.Z", "Set if result is zero"},
+		{"syntax", []interface{}{"ADD D,S", "ADD D,#S"}},
+		{"syntax.1", "ADD D,#S"},
+		{"flags", map[string]interface{}{"C": "Set on carry", "Z": "Set if result is zero"}},
+	}
+	for _, tt := range tests {
+		args, _ := json.Marshal(map[string]string{"query": "p2kbPasm2Add", "field": tt.field})
+		result := extractResultMap(t, srv.handleGet(context.Background(), 1, args))
+		if result["type"] != "field" || result["field"] != tt.field {
+			t.Errorf("%s: type = %v, field = %v", tt.field, result["type"], result["field"])
+		}
+		if !reflect.DeepEqual(result["value"], tt.want) {
+			t.Errorf("%s: value = %#v, want %#v", tt.field, result["value"], tt.want)
+		}
+		if _, ok := result["content"]; ok {
+			t.Errorf("%s: content returned alongside the field", tt.field)
+		}
+	}
+
+	// Selecting by category position works too
+	result := extractResultMap(t, srv.handleGet(context.Background(), 1, json.RawMessage(`{"category": "pasm2_math", "position": 0, "field": "mnemonic"}`)))
+	if result["value"] != "ADD" {
+		t.Errorf("by position: value = %v, want ADD", result["value"])
+	}
+}
+
+func TestHandleGetFieldErrors(t *testing.T) {
+	srv, cleanup := newFieldServer(t)
+	defer cleanup()
+
+	// A missing field names the fields there are; metadata is filtered out
+	resp := srv.handleGet(context.Background(), 1, json.RawMessage(`{"query": "p2kbPasm2Add", "field": "flags.X"}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Fatalf("missing field: error = %+v, want -32602", resp.Error)
+	}
+	data, _ := resp.Error.Data.(map[string]interface{})
+	want := []string{"encoding", "flags", "mnemonic", "operands", "syntax"}
+	if !reflect.DeepEqual(data["available_fields"], want) {
+		t.Errorf("available_fields = %v, want %v", data["available_fields"], want)
+	}
+	if data["found"] != "flags" || data["found_type"] != "mapping" {
+		t.Errorf("found = %v (%v), want flags (mapping)", data["found"], data["found_type"])
+	}
+
+	for _, args := range []string{
+		`{"query": "p2kbPasm2Add", "field": "last_updated"}`,
+		`{"query": "p2kbPasm2Add", "field": "mnemonic.x"}`,
+		`{"query": "p2kbPasm2Add", "field": "syntax.5"}`,
+		`{"query": "p2kbPasm2Add", "field": "flags..Z"}`,
+		`{"query": "p2kbPasm2Add", "field": "syntax", "max_bytes": 100}`,
+	} {
+		if resp := srv.handleGet(context.Background(), 1, json.RawMessage(args)); resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("%s: error = %+v, want -32602", args, resp.Error)
+		}
+	}
+}
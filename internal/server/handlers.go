@@ -1,15 +1,30 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
-	"os"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
 	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/ironsheep/p2kb-mcp/internal/cache"
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
+	"github.com/ironsheep/p2kb-mcp/internal/fetch"
+	"github.com/ironsheep/p2kb-mcp/internal/index"
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ToolCallParams represents the params for a tools/call request.
@@ -19,56 +34,232 @@ type ToolCallParams struct {
 }
 
 // handleToolsCall dispatches tool calls to their implementations.
-func (s *Server) handleToolsCall(req *MCPRequest) *MCPResponse {
+func (s *Server) handleToolsCall(ctx context.Context, req *MCPRequest) (resp *MCPResponse) {
 	var params ToolCallParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		return s.errorResponse(req.ID, -32602, "Invalid params", err.Error())
 	}
 
-	switch params.Name {
+	ctx, endCall := s.telemetry.startCall(ctx, req.ID, params.Name, params.Arguments)
+	defer func() { endCall(resp) }()
+
+	key := toolCallKey(params.Name, params.Arguments)
+	ttl := parseRequestCacheTTL(s.getenv("P2KB_REQUEST_CACHE_TTL_SECS"))
+	cacheable := cachedTools[params.Name] && ttl > 0 && !wantsFreshContent(params.Arguments)
+
+	// A repeat of a recent lookup is answered from the request cache, and
+	// counts as serving its keys again
+	var served *recentKeys
+	if cacheable {
+		if resp, keys := s.requestCache.get(key, time.Now()); resp != nil {
+			for _, k := range keys {
+				s.history.add(k)
+			}
+			own := *resp
+			own.ID = req.ID
+			return &own
+		}
+		ctx, served = withServedKeys(ctx)
+	}
+
+	if !fastTool[params.Name] {
+		stop := s.startKeepalive(ctx, req.ID, parseKeepaliveInterval(s.getenv("P2KB_KEEPALIVE_INTERVAL_SECS")))
+		defer stop()
+	}
+
+	run := func() *MCPResponse {
+		resp := limitResponseSize(params.Name, s.safeCallTool(ctx, req.ID, params.Name, params.Arguments), getMaxResponseBytes())
+		if cacheable && resp != nil && resp.Error == nil {
+			now := time.Now()
+			s.requestCache.put(key, resp, served.list(), now, now.Add(ttl))
+		}
+		return resp
+	}
+	if !readOnlyTools[params.Name] {
+		return run()
+	}
+
+	// Identical read-only calls arriving together share one run; each
+	// caller still gets a response carrying its own ID
+	resp, shared := s.calls.do(key, run)
+	if shared && resp != nil {
+		own := *resp
+		own.ID = req.ID
+		return &own
+	}
+	return resp
+}
+
+// callTool runs the named tool's handler.
+func (s *Server) callTool(ctx context.Context, id interface{}, name string, args json.RawMessage) *MCPResponse {
+	switch name {
 	case "p2kb_get":
-		return s.handleGet(req.ID, params.Arguments)
+		return s.handleGet(ctx, id, args)
+	case "p2kb_batch_get":
+		return s.handleBatchGet(ctx, id, args)
+	case "p2kb_compare":
+		return s.handleCompare(ctx, id, args)
+	case "p2kb_related":
+		return s.handleRelated(ctx, id, args)
 	case "p2kb_find":
-		return s.handleFind(req.ID, params.Arguments)
+		return s.handleFind(id, args)
+	case "p2kb_category_tree":
+		return s.handleCategoryTree(id, args)
 	case "p2kb_obex_get":
-		return s.handleOBEXGet(req.ID, params.Arguments)
+		return s.handleOBEXGet(ctx, id, args)
 	case "p2kb_obex_find":
-		return s.handleOBEXFind(req.ID, params.Arguments)
+		return s.handleOBEXFind(ctx, id, args)
+	case "p2kb_discover":
+		return s.handleDiscover(ctx, id, args)
+	case "p2kb_obex_author_detail":
+		return s.handleOBEXAuthorDetail(id, args)
+	case "p2kb_obex_tag_search":
+		return s.handleOBEXTagSearch(id, args)
 	case "p2kb_obex_download":
-		return s.handleOBEXDownload(req.ID, params.Arguments)
+		return s.handleOBEXDownload(id, args)
+	case "p2kb_obex_preview":
+		return s.handleOBEXPreview(ctx, id, args)
+	case "p2kb_obex_readme":
+		return s.handleOBEXReadme(ctx, id, args)
+	case "p2kb_obex_cite":
+		return s.handleOBEXCite(ctx, id, args)
+	case "p2kb_obex_build_index":
+		return s.handleOBEXBuildIndex(id, args)
+	case "p2kb_obex_verify":
+		return s.handleOBEXVerify(id, args)
 	case "p2kb_version":
-		return s.handleVersion(req.ID)
+		return s.handleVersion(id)
+	case "p2kb_settings":
+		return s.handleSettings(id, args)
 	case "p2kb_refresh":
-		return s.handleRefresh(req.ID, params.Arguments)
+		return s.handleRefresh(id, args)
+	case "p2kb_migrate_cache":
+		return s.handleMigrateCache(id, args)
+	case "p2kb_pin":
+		return s.handlePin(ctx, id, args)
+	case "p2kb_unpin":
+		return s.handleUnpin(id, args)
+	case "p2kb_suggest":
+		return s.handleSuggest(ctx, id, args)
+	case "p2kb_quiz":
+		return s.handleQuiz(id, args)
+	case "p2kb_memory_pressure":
+		return s.handleMemoryPressure(id, args)
+	case "p2kb_list_keys":
+		return s.handleListKeys(id, args)
+	case "p2kb_healthcheck":
+		return s.handleHealthcheck(id, args)
+	case "p2kb_cache_dump":
+		return s.handleCacheDump(id, args)
+	case "p2kb_raw_get":
+		return s.handleRawGet(id, args)
+	case "p2kb_find_duplicates":
+		return s.handleFindDuplicates(id)
+	case "p2kb_obex_stats":
+		return s.handleOBEXStats(id, args)
+	case "p2kb_obex_dependency_graph":
+		return s.handleOBEXDependencyGraph(id)
 	default:
-		return s.errorResponse(req.ID, -32601, "Unknown tool", params.Name)
+		return s.errorResponse(id, -32601, "Unknown tool", name)
 	}
 }
 
 // handleGet implements p2kb_get - natural language or key-based content retrieval.
 // Supports canonical keys (p2kbPasm2Add), aliases (ADD), and natural language queries.
-func (s *Server) handleGet(id interface{}, args json.RawMessage) *MCPResponse {
+func (s *Server) handleGet(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
 	var params struct {
-		Query string `json:"query"`
+		Query        string   `json:"query"`
+		Queries      []string `json:"queries"`
+		AutoSelect   bool     `json:"auto_select"`
+		MaxBytes     int      `json:"max_bytes"`
+		Offset       int      `json:"offset"`
+		Continuation string   `json:"continuation"`
+		Category     string   `json:"category"`
+		Position     *int     `json:"position"`
+		BypassCache  bool     `json:"bypass_cache"`
+		Field        string   `json:"field"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
 	}
 
+	if (params.Category != "" || params.Position != nil) && (params.Query != "" || len(params.Queries) > 0) {
+		return s.errorResponse(id, -32602, "Invalid arguments", "category and position are an alternative to query and queries")
+	}
+	if params.Query != "" && len(params.Queries) > 0 {
+		return s.errorResponse(id, -32602, "Invalid arguments", "query and queries are mutually exclusive")
+	}
+	if params.BypassCache && (params.Query == "" || params.Continuation != "") {
+		return s.errorResponse(id, -32602, "Invalid arguments", "bypass_cache applies to a single query")
+	}
+	if len(params.Queries) > maxGetQueries {
+		return s.errorResponse(id, -32602, "Too many queries", fmt.Sprintf("queries accepts at most %d entries", maxGetQueries))
+	}
+	for _, q := range params.Queries {
+		if strings.TrimSpace(q) == "" {
+			return s.errorResponse(id, -32602, "Invalid queries", "queries must not contain empty strings")
+		}
+	}
+
+	if params.MaxBytes < 0 || params.MaxBytes > maxContentPageBytes {
+		return s.errorResponse(id, -32602, "Invalid max_bytes", fmt.Sprintf("max_bytes must be between 0 (unlimited) and %d", maxContentPageBytes))
+	}
+	if params.Offset < 0 {
+		return s.errorResponse(id, -32602, "Invalid offset", "offset must be 0 or greater")
+	}
+	if params.Field != "" {
+		if !validFieldPath(params.Field) {
+			return s.errorResponse(id, -32602, "Invalid field", fmt.Sprintf("field %q must be dot-separated names, e.g. \"flags.Z\"", params.Field))
+		}
+		if params.MaxBytes > 0 || params.Offset > 0 || params.Continuation != "" {
+			return s.errorResponse(id, -32602, "Invalid arguments", "field returns a single value and cannot be paged with max_bytes, offset or continuation")
+		}
+	}
+	page := contentPage{maxBytes: params.MaxBytes, offset: params.Offset, field: params.Field}
+
+	// A continuation token names the key and offset, overriding query
+	if params.Continuation != "" {
+		key, offset, err := decodeContinuation(params.Continuation)
+		if err != nil {
+			return s.errorResponse(id, -32602, "Invalid continuation", err.Error())
+		}
+		page.offset = offset
+		return s.getContentWithRelated(ctx, id, key, "", page)
+	}
+
+	if len(params.Queries) > 0 {
+		return s.getByQueries(ctx, id, params.Queries, params.AutoSelect, page)
+	}
+
+	if params.Category != "" || params.Position != nil {
+		if params.Category == "" {
+			return s.errorResponse(id, -32602, "Missing required parameter", "category")
+		}
+		if params.Position == nil {
+			return s.errorResponse(id, -32602, "Missing required parameter", "position")
+		}
+		return s.getByPosition(ctx, id, params.Category, *params.Position, page)
+	}
+
 	if params.Query == "" {
 		return s.errorResponse(id, -32602, "Missing required parameter", "query")
 	}
 
+	getContent := s.getContentWithRelated
+	if params.BypassCache {
+		getContent = s.getBypassingCache
+	}
+
 	// Try exact key or alias match first
 	resolution := s.indexManager.ResolveKey(params.Query)
 	if resolution.Found {
-		return s.getContentWithRelated(id, resolution.CanonicalKey, resolution.ResolvedFrom)
+		return getContent(ctx, id, resolution.CanonicalKey, resolution.ResolvedFrom, page)
 	}
 
 	// Use natural language matching
 	matches, err := s.indexManager.MatchQuery(params.Query)
 	if err != nil {
-		return s.errorResponse(id, -32000, "Query failed", err.Error())
+		return s.errorResponse(id, managerErrorCode(err), "Query failed", err.Error())
 	}
 
 	if len(matches) == 0 {
@@ -90,64 +281,463 @@ func (s *Server) handleGet(id interface{}, args json.RawMessage) *MCPResponse {
 		})
 	}
 
-	// If single high-confidence match, return content
-	if len(matches) == 1 || matches[0].Score > 0.9 {
-		return s.getContentWithRelated(id, matches[0].Key, "")
+	if key, ok := confidentMatch(matches); ok {
+		return getContent(ctx, id, key, "", page)
 	}
 
-	// If top match is significantly better, return it
-	if len(matches) >= 2 && matches[0].Score > matches[1].Score+0.2 {
-		return s.getContentWithRelated(id, matches[0].Key, "")
+	// Caller asked us to pick rather than return suggestions; a lone
+	// approximate match is still offered as a suggestion
+	if params.AutoSelect && len(matches) > 1 {
+		return s.autoSelectMatch(ctx, id, matches[0], matches[1], page)
 	}
 
 	// Multiple matches - return suggestions
 	suggestions := make([]map[string]interface{}, 0, len(matches))
 	for _, m := range matches {
-		suggestions = append(suggestions, map[string]interface{}{
+		suggestion := map[string]interface{}{
 			"key":      m.Key,
 			"score":    m.Score,
 			"category": m.Category,
-		})
+		}
+		if m.Fuzzy {
+			suggestion["fuzzy"] = true
+		}
+		suggestions = append(suggestions, suggestion)
 	}
 
+	message := "Multiple matches found. Please be more specific or use an exact key."
+	if matches[0].Fuzzy {
+		message = fuzzyMatchMessage
+	}
 	return s.successResponse(id, map[string]interface{}{
 		"type":        "suggestions",
 		"query":       params.Query,
-		"message":     "Multiple matches found. Please be more specific or use an exact key.",
+		"message":     message,
 		"suggestions": suggestions,
 	})
 }
 
+// getByPosition serves the key at position (0-based) in category's sorted key
+// list, with the neighbouring keys so a caller can page through the category.
+func (s *Server) getByPosition(ctx context.Context, id interface{}, category string, position int, page contentPage) *MCPResponse {
+	keys, err := s.indexManager.GetCategoryKeys(category)
+	if err != nil {
+		return s.errorResponse(id, managerErrorCode(err), "Category lookup failed", map[string]interface{}{
+			"error":    err.Error(),
+			"category": category,
+			"hint":     "Use p2kb_find with no parameters to list categories",
+		})
+	}
+	if position < 0 || position >= len(keys) {
+		data := map[string]interface{}{
+			"category":          category,
+			"position":          position,
+			"total_in_category": len(keys),
+		}
+		message := fmt.Sprintf("Category '%s' is empty", category)
+		if len(keys) > 0 {
+			data["valid_range"] = fmt.Sprintf("0-%d", len(keys)-1)
+			message = fmt.Sprintf("Position %d is out of range; '%s' has positions 0-%d", position, category, len(keys)-1)
+		}
+		return s.errorResponse(id, -32602, message, data)
+	}
+
+	key := keys[position]
+	result, errResp := s.pagedContentResult(ctx, id, key, "", page)
+	if errResp != nil {
+		return errResp
+	}
+	result["position"] = position
+	result["total_in_category"] = len(keys)
+	if position > 0 {
+		result["prev_key"] = keys[position-1]
+	}
+	if position < len(keys)-1 {
+		result["next_key"] = keys[position+1]
+	}
+	return s.successResponse(id, result)
+}
+
+// confidentMatch returns the key p2kb_get serves without asking: the only
+// match, one scoring above 0.9, or one leading the next by more than 0.2.
+// An approximate (fuzzy) match is never served without asking.
+func confidentMatch(matches []index.MatchResult) (string, bool) {
+	if matches[0].Fuzzy {
+		return "", false
+	}
+
+	// If single high-confidence match, return content
+	if len(matches) == 1 || matches[0].Score > 0.9 {
+		return matches[0].Key, true
+	}
+
+	// If top match is significantly better, return it
+	if len(matches) >= 2 && matches[0].Score > matches[1].Score+0.2 {
+		return matches[0].Key, true
+	}
+	return "", false
+}
+
+// fuzzyMatchMessage introduces p2kb_get suggestions found by edit distance,
+// when nothing matched the query's words.
+const fuzzyMatchMessage = "Did you mean (approximate match)?"
+
+// maxGetQueries is the most alternative queries p2kb_get accepts at once.
+const maxGetQueries = 5
+
+// getByQueries answers p2kb_get for several alternative queries: each is
+// matched in parallel, and the merged matches are ranked as if they came from
+// one query. Results name the query behind each key in matched_by_query.
+func (s *Server) getByQueries(ctx context.Context, id interface{}, queries []string, autoSelect bool, page contentPage) *MCPResponse {
+	matches, matchedBy, err := s.matchQueries(queries)
+	if err != nil {
+		return s.errorResponse(id, managerErrorCode(err), "Query failed", err.Error())
+	}
+
+	if len(matches) == 0 {
+		quoted := make([]string, len(queries))
+		for i, q := range queries {
+			quoted[i] = fmt.Sprintf("%q", q)
+		}
+		return s.successResponse(id, map[string]interface{}{
+			"type":    "no_matches",
+			"queries": queries,
+			"message": fmt.Sprintf("No documentation found matching any of: %s", strings.Join(quoted, ", ")),
+			"hint":    "Try using p2kb_find to explore available documentation",
+		})
+	}
+
+	var result map[string]interface{}
+	var errResp *MCPResponse
+	if key, ok := confidentMatch(matches); ok {
+		result, errResp = s.pagedContentResult(ctx, id, key, "", page)
+	} else if autoSelect && len(matches) > 1 {
+		result, errResp = s.autoSelectResult(ctx, id, matches[0], matches[1], page)
+	} else {
+		suggestions := make([]map[string]interface{}, 0, len(matches))
+		for _, m := range matches {
+			suggestion := map[string]interface{}{
+				"key":              m.Key,
+				"score":            m.Score,
+				"category":         m.Category,
+				"matched_by_query": matchedBy[m.Key],
+			}
+			if m.Fuzzy {
+				suggestion["fuzzy"] = true
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+		message := "Multiple matches found. Please be more specific or use an exact key."
+		if matches[0].Fuzzy {
+			message = fuzzyMatchMessage
+		}
+		return s.successResponse(id, map[string]interface{}{
+			"type":        "suggestions",
+			"queries":     queries,
+			"message":     message,
+			"suggestions": suggestions,
+		})
+	}
+	if errResp != nil {
+		return errResp
+	}
+
+	key, _ := result["key"].(string)
+	result["matched_by_query"] = matchedBy[key]
+	return s.successResponse(id, result)
+}
+
+// matchQueries runs MatchQuery for each query in parallel and merges the
+// results: each key keeps its best score, credited to the query that gave it
+// (the earlier query on a tie), best first. It fails only if every query did.
+func (s *Server) matchQueries(queries []string) ([]index.MatchResult, map[string]string, error) {
+	results := make([][]index.MatchResult, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		go func(i int, q string) {
+			defer wg.Done()
+			results[i], errs[i] = s.indexManager.MatchQuery(q)
+		}(i, q)
+	}
+	wg.Wait()
+
+	best := make(map[string]index.MatchResult)
+	matchedBy := make(map[string]string)
+	failed := 0
+	for i, matches := range results {
+		if errs[i] != nil {
+			failed++
+			continue
+		}
+		for _, m := range matches {
+			if prev, seen := best[m.Key]; !seen || m.Score > prev.Score {
+				best[m.Key] = m
+				matchedBy[m.Key] = queries[i]
+			}
+		}
+	}
+	if failed == len(queries) {
+		return nil, nil, errs[0]
+	}
+
+	merged := make([]index.MatchResult, 0, len(best))
+	for _, m := range best {
+		merged = append(merged, m)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Score != merged[j].Score {
+			return merged[i].Score > merged[j].Score
+		}
+		return merged[i].Key < merged[j].Key
+	})
+	return merged, matchedBy, nil
+}
+
+// Reasons p2kb_get auto_select reports for its pick.
+const (
+	autoSelectHighestScore  = "highest_score"
+	autoSelectHistory       = "history_context"
+	autoSelectRicherDocs    = "richer_docs"
+	autoSelectCloseScoreGap = 0.15
+)
+
+// autoSelectMatch picks between the top two natural-language matches for
+// p2kb_get auto_select. A clear lead wins outright. When the scores are within
+// autoSelectCloseScoreGap, it prefers a key whose category the session has
+// recently read, then the key with more related instructions, and finally the
+// higher score.
+func (s *Server) autoSelectMatch(ctx context.Context, id interface{}, top, runnerUp index.MatchResult, page contentPage) *MCPResponse {
+	result, errResp := s.autoSelectResult(ctx, id, top, runnerUp, page)
+	if errResp != nil {
+		return errResp
+	}
+	return s.successResponse(id, result)
+}
+
+// autoSelectResult is autoSelectMatch's result before it is sent.
+func (s *Server) autoSelectResult(ctx context.Context, id interface{}, top, runnerUp index.MatchResult, page contentPage) (map[string]interface{}, *MCPResponse) {
+	key, reason := top.Key, autoSelectHighestScore
+
+	if top.Score-runnerUp.Score <= autoSelectCloseScoreGap {
+		if pick, ok := s.pickByHistory(top.Key, runnerUp.Key); ok {
+			key, reason = pick, autoSelectHistory
+		} else if pick, ok := s.pickByRelatedCount(top.Key, runnerUp.Key); ok {
+			key, reason = pick, autoSelectRicherDocs
+		}
+	}
+
+	result, errResp := s.pagedContentResult(ctx, id, key, "", page)
+	if errResp != nil {
+		return nil, errResp
+	}
+	result["auto_selected"] = true
+	result["auto_select_reason"] = reason
+	return result, nil
+}
+
+// pickByHistory returns whichever key (top first) shares a category with a key
+// p2kb_get served recently. ok is false when neither or both do.
+func (s *Server) pickByHistory(top, runnerUp string) (key string, ok bool) {
+	recent := make(map[string]bool)
+	for _, k := range s.history.list() {
+		for _, cat := range s.indexManager.GetKeyCategories(k) {
+			recent[cat] = true
+		}
+	}
+
+	inHistory := func(key string) bool {
+		for _, cat := range s.indexManager.GetKeyCategories(key) {
+			if recent[cat] {
+				return true
+			}
+		}
+		return false
+	}
+
+	topSeen, runnerUpSeen := inHistory(top), inHistory(runnerUp)
+	if topSeen == runnerUpSeen {
+		return "", false
+	}
+	if topSeen {
+		return top, true
+	}
+	return runnerUp, true
+}
+
+// pickByRelatedCount returns whichever key's content lists more related
+// instructions. ok is false on a tie; unreadable content counts as none.
+func (s *Server) pickByRelatedCount(top, runnerUp string) (key string, ok bool) {
+	related := func(key string) int {
+		content, err := s.getContent(key)
+		if err != nil {
+			return 0
+		}
+		return len(extractRelatedInstructions(content))
+	}
+
+	topCount, runnerUpCount := related(top), related(runnerUp)
+	switch {
+	case topCount > runnerUpCount:
+		return top, true
+	case runnerUpCount > topCount:
+		return runnerUp, true
+	}
+	return "", false
+}
+
+// maxContentPageBytes is the largest max_bytes p2kb_get accepts.
+const maxContentPageBytes = 32768
+
+// contentPage selects the part of a p2kb_get result's content to return. The
+// zero value returns all of it.
+type contentPage struct {
+	maxBytes int // 0 = unlimited
+	offset   int
+	field    string // Dot path of the one YAML field to return instead; "" = the content
+}
+
+// applyPage cuts result's content down to the requested page, adding the
+// paging fields and a continuation token when more content follows. The cut
+// is moved back to a rune boundary so no page splits a UTF-8 character, but a
+// page always holds at least one whole rune, so a max_bytes smaller than the
+// next rune still makes progress.
+func (s *Server) applyPage(id interface{}, key string, result map[string]interface{}, page contentPage) *MCPResponse {
+	if page.maxBytes == 0 && page.offset == 0 {
+		return nil
+	}
+
+	content, _ := result["content"].(string)
+	total := len(content)
+	if page.offset > total {
+		return s.errorResponse(id, -32602, "Invalid offset",
+			fmt.Sprintf("offset %d is beyond the end of '%s' (%d bytes)", page.offset, key, total))
+	}
+
+	start := page.offset
+	for start < total && !utf8.RuneStart(content[start]) {
+		start++
+	}
+	end := total
+	if page.maxBytes > 0 && start+page.maxBytes < total {
+		end = start + page.maxBytes
+		for end > start && !utf8.RuneStart(content[end]) {
+			end--
+		}
+		if end == start {
+			_, size := utf8.DecodeRuneInString(content[start:])
+			end = start + size
+		}
+	}
+
+	result["content"] = content[start:end]
+	result["offset"] = start
+	result["total_bytes"] = total
+	result["returned_bytes"] = end - start
+	result["truncated"] = end < total
+	if end < total {
+		result["continuation_token"] = encodeContinuation(key, end)
+	}
+	return nil
+}
+
+// encodeContinuation returns the p2kb_get continuation token for reading key
+// from offset.
+func encodeContinuation(key string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%d", key, offset)))
+}
+
+// decodeContinuation reverses encodeContinuation.
+func decodeContinuation(token string) (string, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", 0, fmt.Errorf("malformed continuation token: %w", err)
+	}
+	// Split at the last colon; the offset never contains one
+	i := strings.LastIndex(string(raw), ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("malformed continuation token")
+	}
+	key := string(raw[:i])
+	offset, err := strconv.Atoi(string(raw[i+1:]))
+	if err != nil || offset < 0 {
+		return "", 0, fmt.Errorf("malformed continuation token")
+	}
+	return key, offset, nil
+}
+
 // getContentWithRelated fetches content and extracts related items.
 // If resolvedFrom is non-empty, it indicates the original alias that was resolved.
-func (s *Server) getContentWithRelated(id interface{}, key string, resolvedFrom string) *MCPResponse {
-	content, err := s.getContent(key)
-	if err != nil {
-		// A verification failure is distinct from not-found / network errors:
-		// the file was downloaded but its sha256 did not match the index after
-		// cache-busting retries (likely transient CDN propagation lag). Surface
-		// it as "temporarily unavailable" so the client retries rather than
-		// treating the key as missing.
-		var verr *cache.VerificationError
-		if errors.As(err, &verr) {
-			return s.errorResponse(id, -32001,
-				fmt.Sprintf("Content for '%s' is temporarily unavailable — verification failed", key),
-				map[string]interface{}{
-					"error":           verr.Error(),
-					"key":             key,
-					"expected_sha256": verr.Expected,
-					"actual_sha256":   verr.Actual,
-					"hint":            "The source is likely mid-update; retry shortly. Not a missing key.",
-				})
+func (s *Server) getContentWithRelated(ctx context.Context, id interface{}, key string, resolvedFrom string, page contentPage) *MCPResponse {
+	result, errResp := s.pagedContentResult(ctx, id, key, resolvedFrom, page)
+	if errResp != nil {
+		return errResp
+	}
+	return s.successResponse(id, result)
+}
+
+// pagedContentResult is contentResult cut down to page.
+func (s *Server) pagedContentResult(ctx context.Context, id interface{}, key string, resolvedFrom string, page contentPage) (map[string]interface{}, *MCPResponse) {
+	result, errResp := s.contentResult(ctx, id, key, resolvedFrom)
+	if errResp != nil {
+		return nil, errResp
+	}
+	if page.field != "" {
+		if errResp := s.selectField(id, key, result, page.field); errResp != nil {
+			return nil, errResp
 		}
+		return result, nil
+	}
+	if errResp := s.applyPage(id, key, result, page); errResp != nil {
+		return nil, errResp
+	}
+	return result, nil
+}
 
-		return s.errorResponse(id, -32000, fmt.Sprintf("Failed to fetch content for '%s'", key),
-			map[string]interface{}{
-				"error":       err.Error(),
-				"key":         key,
-				"hint":        "Check network connectivity and try p2kb_refresh to update the index",
-				"report_info": "If this persists, report: key, error message, and any preceding errors from stderr",
-			})
+// managerErrorCode maps an error from the index, cache or OBEX manager to an
+// MCP error code: -32602 when the request named a key or category that does
+// not exist, -32603 when the server's own disk cache failed, and -32000 for
+// upstream failures (offline, rate limited, bad HTTP status) and anything else.
+func managerErrorCode(err error) int {
+	var keyErr *errs.ErrKeyNotFound
+	var categoryErr *errs.ErrCategoryNotFound
+	var offline *errs.ErrOffline
+	var status *errs.ErrHTTPStatus
+	var pathErr *fs.PathError
+	switch {
+	case errors.As(err, &keyErr), errors.As(err, &categoryErr):
+		return -32602
+	case errors.As(err, &offline), errors.As(err, &status):
+		return -32000
+	case errors.As(err, &pathErr):
+		return -32603
+	}
+	return -32000
+}
+
+// addRetryAfter adds retry_after_secs to error data when err is a rate limit
+// refusal that said when to retry, and rate_limit_reset (Unix seconds) when
+// GitHub said when its limit resets.
+func addRetryAfter(data map[string]interface{}, err error) {
+	var limited *errs.ErrRateLimited
+	if !errors.As(err, &limited) {
+		return
+	}
+	if limited.RetryAfter > 0 {
+		data["retry_after_secs"] = int(limited.RetryAfter.Seconds())
+	}
+	if !limited.Reset.IsZero() {
+		data["rate_limit_reset"] = limited.Reset.Unix()
+	}
+}
+
+// contentResult builds the p2kb_get content result for key, or the error
+// response to send if its content cannot be fetched.
+func (s *Server) contentResult(ctx context.Context, id interface{}, key string, resolvedFrom string) (map[string]interface{}, *MCPResponse) {
+	content, err := s.getContentFor(ctx, key)
+	if err != nil {
+		return nil, s.contentErrorResponse(id, key, err)
 	}
 
 	// Extract related instructions
@@ -170,15 +760,62 @@ func (s *Server) getContentWithRelated(id interface{}, key string, resolvedFrom
 		result["related"] = related
 	}
 
-	return s.successResponse(id, result)
+	s.recordServed(ctx, key)
+	return result, nil
+}
+
+// contentErrorResponse is the p2kb_get error response for a failure to fetch
+// key's content.
+func (s *Server) contentErrorResponse(id interface{}, key string, err error) *MCPResponse {
+	// A verification failure is distinct from not-found / network errors:
+	// the file was downloaded but its sha256 did not match the index after
+	// cache-busting retries (likely transient CDN propagation lag). Surface
+	// it as "temporarily unavailable" so the client retries rather than
+	// treating the key as missing.
+	var verr *cache.VerificationError
+	if errors.As(err, &verr) {
+		return s.errorResponse(id, -32001,
+			fmt.Sprintf("Content for '%s' is temporarily unavailable — verification failed", key),
+			map[string]interface{}{
+				"error":           verr.Error(),
+				"key":             key,
+				"expected_sha256": verr.Expected,
+				"actual_sha256":   verr.Actual,
+				"hint":            "The source is likely mid-update; retry shortly. Not a missing key.",
+			})
+	}
+
+	code := managerErrorCode(err)
+	data := map[string]interface{}{
+		"error":       err.Error(),
+		"key":         key,
+		"hint":        "Check network connectivity and try p2kb_refresh to update the index",
+		"report_info": "If this persists, report: key, error message, and any preceding errors from stderr",
+	}
+	if code == -32602 {
+		data["hint"] = "The key is no longer in the index; use p2kb_find to look it up again"
+	}
+	addRetryAfter(data, err)
+	return s.errorResponse(id, code, fmt.Sprintf("Failed to fetch content for '%s'", key), data)
 }
 
+// recentlyUpdatedCount is how many keys the p2kb_find overview lists under
+// most_recently_updated.
+const recentlyUpdatedCount = 5
+
 // handleFind implements p2kb_find - explore/discover documentation.
 func (s *Server) handleFind(id interface{}, args json.RawMessage) *MCPResponse {
 	var params struct {
 		Term     string `json:"term"`
 		Category string `json:"category"`
 		Limit    int    `json:"limit"`
+		SortBy   string `json:"sort_by"`
+		Operator string `json:"operator"`
+		MinCount *int   `json:"min_count"`
+		MaxCount *int   `json:"max_count"`
+		Detailed bool   `json:"detailed"`
+		Offset   int    `json:"offset"`
+		Regex    bool   `json:"regex"`
 	}
 	params.Limit = 50 // default
 
@@ -188,22 +825,96 @@ func (s *Server) handleFind(id interface{}, args json.RawMessage) *MCPResponse {
 		}
 	}
 
+	sortByMtime := params.SortBy == "mtime"
+	if params.SortBy != "" && !sortByMtime {
+		return s.errorResponse(id, -32602, "Invalid sort_by", `sort_by must be "mtime"`)
+	}
+
+	operator := strings.ToUpper(params.Operator)
+	if operator == "" {
+		operator = "AND"
+	}
+	if operator != "AND" && operator != "OR" {
+		return s.errorResponse(id, -32602, "Invalid operator", `operator must be "AND" or "OR"`)
+	}
+
+	if (params.MinCount != nil && *params.MinCount < 0) || (params.MaxCount != nil && *params.MaxCount < 0) {
+		return s.errorResponse(id, -32602, "Invalid count range", "min_count and max_count must not be negative")
+	}
+	if params.MinCount != nil && params.MaxCount != nil && *params.MinCount > *params.MaxCount {
+		return s.errorResponse(id, -32602, "Invalid count range", "min_count must not exceed max_count")
+	}
+	countFiltered := params.MinCount != nil || params.MaxCount != nil
+
+	if params.Offset < 0 {
+		return s.errorResponse(id, -32602, "Invalid offset", "offset must be 0 or greater")
+	}
+
 	// No parameters - list categories
-	if params.Term == "" && params.Category == "" {
-		categories := s.indexManager.GetCategoriesWithCounts()
+	if params.Term == "" && params.Category == "" && !sortByMtime {
+		categories := sortCategoryCounts(s.indexManager.GetCategoriesWithCounts())
 		stats := s.indexManager.GetStats()
 
-		return s.successResponse(id, map[string]interface{}{
-			"type":             "categories",
-			"categories":       categories,
-			"total_categories": stats.TotalCategories,
-			"total_entries":    stats.TotalEntries,
-		})
+		result := map[string]interface{}{
+			"type":                  "categories",
+			"categories":            categories,
+			"total_categories":      stats.TotalCategories,
+			"total_entries":         stats.TotalEntries,
+			"pinned_keys":           s.cacheManager.PinnedKeys(),
+			"most_recently_updated": s.indexManager.GetKeysByMtime(recentlyUpdatedCount),
+		}
+		if countFiltered {
+			categories = filterCategoryCounts(categories, params.MinCount, params.MaxCount)
+			result["categories"] = categories
+			result["filtered_category_count"] = len(categories)
+		}
+		return s.successResponse(id, result)
+	}
+
+	// With a search or listing, categories within the count range are
+	// reported beside the keys
+	withCategoriesInRange := func(result map[string]interface{}) map[string]interface{} {
+		if countFiltered {
+			categories := sortCategoryCounts(s.indexManager.GetCategoriesWithCounts())
+			result["categories_in_range"] = filterCategoryCounts(categories, params.MinCount, params.MaxCount)
+		}
+		return result
+	}
+
+	// sort_by alone - the most recently updated keys across every category
+	if params.Term == "" && params.Category == "" {
+		keys := s.indexManager.GetKeysByMtime(0)
+		start, end := pageBounds(len(keys), params.Offset, params.Limit)
+		result := map[string]interface{}{
+			"type":    "keys",
+			"sort_by": params.SortBy,
+			"keys":    keys[start:end],
+			"count":   end - start,
+		}
+		addPageInfo(result, len(keys), params.Offset, params.Limit)
+		return s.successResponse(id, withCategoriesInRange(result))
 	}
 
 	// Category only - list keys in category
 	if params.Term == "" && params.Category != "" {
-		keys, err := s.indexManager.GetCategoryKeys(params.Category)
+		category := params.Category
+		keys, err := s.indexManager.GetCategoryKeys(category)
+		if err != nil {
+			// Not a category name; try it as an alias ("math" -> pasm2_math)
+			matches := s.indexManager.GetCategoriesByAlias(params.Category)
+			if len(matches) > 1 {
+				return s.successResponse(id, map[string]interface{}{
+					"type":                "category_ambiguous",
+					"category":            params.Category,
+					"message":             fmt.Sprintf("Category '%s' matches several categories; specify one", params.Category),
+					"matching_categories": matches,
+				})
+			}
+			if len(matches) == 1 {
+				category = matches[0]
+				keys, err = s.indexManager.GetCategoryKeys(category)
+			}
+		}
 		if err != nil {
 			categories := s.indexManager.GetCategories()
 			return s.successResponse(id, map[string]interface{}{
@@ -214,24 +925,51 @@ func (s *Server) handleFind(id interface{}, args json.RawMessage) *MCPResponse {
 			})
 		}
 
-		if params.Limit > 0 && len(keys) > params.Limit {
-			keys = keys[:params.Limit]
-		}
-
-		return s.successResponse(id, map[string]interface{}{
+		result := map[string]interface{}{
 			"type":     "keys",
-			"category": params.Category,
-			"keys":     keys,
-			"count":    len(keys),
-		})
+			"category": category,
+		}
+		start, end := pageBounds(len(keys), params.Offset, params.Limit)
+		if sortByMtime {
+			byMtime := s.indexManager.SortKeysByMtime(keys, 0)
+			result["sort_by"] = params.SortBy
+			result["keys"] = byMtime[start:end]
+		} else {
+			result["keys"] = keys[start:end]
+		}
+		result["count"] = end - start
+		addPageInfo(result, len(keys), params.Offset, params.Limit)
+		if category != params.Category {
+			result["resolved_from"] = params.Category
+		}
+		return s.successResponse(id, withCategoriesInRange(result))
 	}
 
-	// Search by term
-	keys := s.indexManager.Search(params.Term, params.Limit)
+	// Search by term, most relevant first; a regex matches whole keys, in
+	// key order
+	tokens := strings.Fields(params.Term)
+	var keys []string
+	if params.Regex {
+		re, err := compileKeyRegex(params.Term, regexLimitsOrDefault(s.getenv("P2KB_MAX_REGEX_COMPLEXITY")))
+		if err != nil {
+			return s.errorResponse(id, -32602, "Invalid regex", err.Error())
+		}
+		tokens = []string{params.Term}
+		keys, err = s.searchRegex(re, 0)
+		if err != nil {
+			return s.errorResponse(id, -32000, "Failed to load index", err.Error())
+		}
+	} else {
+		keys = s.searchTokens(tokens, operator, 0)
+	}
 
 	// If category specified, filter results
 	if params.Category != "" {
 		categoryKeys, err := s.indexManager.GetCategoryKeys(params.Category)
+		if err != nil {
+			// An alias filters by every category it names
+			categoryKeys, err = s.aliasCategoryKeys(params.Category)
+		}
 		if err == nil {
 			categorySet := make(map[string]bool)
 			for _, k := range categoryKeys {
@@ -248,77 +986,435 @@ func (s *Server) handleFind(id interface{}, args json.RawMessage) *MCPResponse {
 		}
 	}
 
-	return s.successResponse(id, map[string]interface{}{
-		"type":  "keys",
-		"term":  params.Term,
-		"keys":  keys,
-		"count": len(keys),
-	})
-}
-
-// handleOBEXGet implements p2kb_obex_get - OBEX object retrieval.
-func (s *Server) handleOBEXGet(id interface{}, args json.RawMessage) *MCPResponse {
-	var params struct {
-		Query string `json:"query"`
+	result := map[string]interface{}{
+		"type":        "keys",
+		"term":        params.Term,
+		"tokens_used": tokens,
 	}
-	if err := json.Unmarshal(args, &params); err != nil {
-		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	if params.Regex {
+		result["regex"] = true
+	} else if len(tokens) > 1 {
+		result["operator"] = operator
 	}
-
+	if params.Detailed {
+		if sortByMtime {
+			byMtime := s.indexManager.SortKeysByMtime(keys, 0)
+			result["sort_by"] = params.SortBy
+			keys = keys[:0]
+			for _, k := range byMtime {
+				keys = append(keys, k.Key)
+			}
+		}
+		s.addKeyDetails(result, keys, params.Offset, params.Limit)
+		return s.successResponse(id, withCategoriesInRange(result))
+	}
+
+	start, end := pageBounds(len(keys), params.Offset, params.Limit)
+	if sortByMtime {
+		result["sort_by"] = params.SortBy
+		result["keys"] = s.indexManager.SortKeysByMtime(keys, 0)[start:end]
+	} else {
+		result["keys"] = keys[start:end]
+	}
+	result["count"] = end - start
+	addPageInfo(result, len(keys), params.Offset, params.Limit)
+	return s.successResponse(id, withCategoriesInRange(result))
+}
+
+// keyDetailPageSize caps the keys p2kb_find describes per call with detailed:
+// each looks up its categories, which walks every category.
+const keyDetailPageSize = 20
+
+// keyDetail describes one key in a detailed p2kb_find result.
+type keyDetail struct {
+	Key           string   `json:"key"`
+	Path          string   `json:"path"`
+	MtimeRFC3339  string   `json:"mtime_rfc3339,omitempty"`
+	Categories    []string `json:"categories"`
+	ContentCached bool     `json:"content_cached"`
+}
+
+// addKeyDetails sets result's keys to details of a page of keys starting at
+// offset, keyDetailPageSize of them or limit if fewer, with total_matches and
+// the paging fields of addPageInfo. keys must hold every match, uncapped, as
+// total_matches counts them.
+func (s *Server) addKeyDetails(result map[string]interface{}, keys []string, offset, limit int) {
+	pageSize := keyDetailPageSize
+	if limit > 0 && limit < pageSize {
+		pageSize = limit
+	}
+	start, end := pageBounds(len(keys), offset, pageSize)
+
+	details := make([]keyDetail, 0, end-start)
+	for _, key := range keys[start:end] {
+		detail := keyDetail{Key: key, Categories: s.indexManager.GetKeyCategories(key)}
+		if path, mtime, _, err := s.indexManager.GetKeyPath(key); err == nil {
+			detail.Path = path
+			if mtime > 0 {
+				detail.MtimeRFC3339 = time.Unix(mtime, 0).UTC().Format(time.RFC3339)
+			}
+		}
+		// GetMtime reads the memory and disk caches without fetching
+		detail.ContentCached = s.cacheManager.GetMtime(key) != 0
+		details = append(details, detail)
+	}
+
+	result["detailed"] = true
+	result["keys"] = details
+	result["count"] = len(details)
+	result["total_matches"] = len(keys)
+	result["page_size"] = pageSize
+	addPageInfo(result, len(keys), offset, pageSize)
+}
+
+// pageBounds returns the bounds of the page of a total-item result that
+// starts at offset and holds at most limit items (all the rest when limit is
+// 0). An offset past the end gives an empty page.
+func pageBounds(total, offset, limit int) (start, end int) {
+	start = offset
+	if start > total {
+		start = total
+	}
+	end = total
+	if limit > 0 && start+limit < total {
+		end = start + limit
+	}
+	return start, end
+}
+
+// addPageInfo adds where a page sits in a result of total items: total_count,
+// offset, limit, and has_more when items follow the page.
+func addPageInfo(result map[string]interface{}, total, offset, limit int) {
+	_, end := pageBounds(total, offset, limit)
+	result["total_count"] = total
+	result["offset"] = offset
+	result["limit"] = limit
+	result["has_more"] = end < total
+}
+
+// regexLimits bounds a p2kb_find regex. Go's regexp runs in linear time, so
+// there is no catastrophic backtracking, but each alternation and quantifier
+// adds to the program every key is run through.
+type regexLimits struct {
+	alternations int
+	quantifiers  int
+}
+
+// defaultRegexLimits applies unless P2KB_MAX_REGEX_COMPLEXITY overrides it.
+var defaultRegexLimits = regexLimits{alternations: 10, quantifiers: 5}
+
+// parseRegexLimits parses a P2KB_MAX_REGEX_COMPLEXITY value: the most
+// alternations and quantifiers a regex may have, as "alternations,quantifiers"
+// (e.g. "10,5"). An empty value gives defaultRegexLimits.
+func parseRegexLimits(v string) (regexLimits, error) {
+	if v == "" {
+		return defaultRegexLimits, nil
+	}
+	alternations, quantifiers, ok := strings.Cut(v, ",")
+	a, errA := strconv.Atoi(strings.TrimSpace(alternations))
+	q, errQ := strconv.Atoi(strings.TrimSpace(quantifiers))
+	if !ok || errA != nil || errQ != nil || a < 0 || q < 0 {
+		return regexLimits{}, fmt.Errorf("value must be two whole numbers, alternations and quantifiers, such as \"10,5\", not %q", v)
+	}
+	return regexLimits{alternations: a, quantifiers: q}, nil
+}
+
+// regexLimitsOrDefault is parseRegexLimits, giving defaultRegexLimits for an
+// invalid value.
+func regexLimitsOrDefault(v string) regexLimits {
+	limits, err := parseRegexLimits(v)
+	if err != nil {
+		return defaultRegexLimits
+	}
+	return limits
+}
+
+// compileKeyRegex compiles a p2kb_find regex term, rejecting one with more
+// alternations or quantifiers than limits allow.
+func compileKeyRegex(term string, limits regexLimits) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(term)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := syntax.Parse(term, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	alternations, quantifiers := countAlternations(term), countQuantifiers(tree)
+	if alternations > limits.alternations {
+		return nil, fmt.Errorf("regex has %d alternations; at most %d are allowed", alternations, limits.alternations)
+	}
+	if quantifiers > limits.quantifiers {
+		return nil, fmt.Errorf("regex has %d quantifiers; at most %d are allowed", quantifiers, limits.quantifiers)
+	}
+	return re, nil
+}
+
+// countAlternations counts the "|" operators in a regex. It reads the source,
+// as the parser folds alternations of single characters into a class.
+func countAlternations(term string) int {
+	n := 0
+	inClass := false
+	for i := 0; i < len(term); i++ {
+		switch c := term[i]; {
+		case c == '\\':
+			i++ // Escaped character
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+			// A ']' first in a class is literal
+			if i+1 < len(term) && term[i+1] == '^' {
+				i++
+			}
+			if i+1 < len(term) && term[i+1] == ']' {
+				i++
+			}
+		case c == '|':
+			n++
+		}
+	}
+	return n
+}
+
+// countQuantifiers counts the quantifiers (*, +, ?, {n,m}) in a parsed regex.
+func countQuantifiers(re *syntax.Regexp) int {
+	n := 0
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		n++
+	}
+	for _, sub := range re.Sub {
+		n += countQuantifiers(sub)
+	}
+	return n
+}
+
+// searchRegex returns up to limit keys (0 for all) that re matches, in key
+// order.
+func (s *Server) searchRegex(re *regexp.Regexp, limit int) ([]string, error) {
+	all, _, err := s.indexManager.ListKeys("", "")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	for _, k := range all {
+		if re.MatchString(k.Key) {
+			keys = append(keys, k.Key)
+			if limit > 0 && len(keys) == limit {
+				break
+			}
+		}
+	}
+	return keys, nil
+}
+
+// searchTokens returns the keys matching the whitespace-separated tokens of a
+// p2kb_find term, most relevant first. A key matches a token the way it would
+// match a single-token term; with operator "AND" it must match every token,
+// with "OR" any of them. Relevance is the sum of the per-token scores.
+func (s *Server) searchTokens(tokens []string, operator string, limit int) []string {
+	var ranked []index.RankedResult
+	if len(tokens) <= 1 {
+		ranked = s.indexManager.SearchRanked(strings.Join(tokens, ""), limit)
+	} else {
+		scores := make(map[string]float64)
+		matched := make(map[string]int)
+		for _, token := range tokens {
+			for _, r := range s.indexManager.SearchRanked(token, 0) {
+				scores[r.Key] += r.Score
+				matched[r.Key]++
+			}
+		}
+		for key, score := range scores {
+			if operator == "AND" && matched[key] < len(tokens) {
+				continue
+			}
+			ranked = append(ranked, index.RankedResult{Key: key, Score: score})
+		}
+		sort.Slice(ranked, func(i, j int) bool {
+			if ranked[i].Score != ranked[j].Score {
+				return ranked[i].Score > ranked[j].Score
+			}
+			return ranked[i].Key < ranked[j].Key
+		})
+		if limit > 0 && len(ranked) > limit {
+			ranked = ranked[:limit]
+		}
+	}
+
+	keys := make([]string, 0, len(ranked))
+	for _, r := range ranked {
+		keys = append(keys, r.Key)
+	}
+	return keys
+}
+
+// aliasCategoryKeys returns the union of the keys in every category alias
+// resolves to, or an error if it is not an alias.
+func (s *Server) aliasCategoryKeys(alias string) ([]string, error) {
+	matches := s.indexManager.GetCategoriesByAlias(alias)
+	if len(matches) == 0 {
+		return nil, &errs.ErrCategoryNotFound{Category: alias}
+	}
+
+	var union []string
+	for _, category := range matches {
+		keys, err := s.indexManager.GetCategoryKeys(category)
+		if err != nil {
+			continue
+		}
+		for _, k := range keys {
+			if !containsString(union, k) {
+				union = append(union, k)
+			}
+		}
+	}
+	return union, nil
+}
+
+// handleCategoryTree implements p2kb_category_tree - the category list grouped
+// by underscore prefix (pasm2_math under pasm2), as ASCII art or nested JSON.
+func (s *Server) handleCategoryTree(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Format string `json:"format"`
+	}
+	params.Format = "ascii" // default
+
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+	}
+	if params.Format != "ascii" && params.Format != "json" {
+		return s.errorResponse(id, -32602, "Invalid format", `format must be "ascii" or "json"`)
+	}
+
+	counts := s.indexManager.GetCategoriesWithCounts()
+	tree := buildCategoryTree(counts)
+
+	result := map[string]interface{}{
+		"type":             "category_tree",
+		"format":           params.Format,
+		"total_categories": len(counts),
+	}
+	if params.Format == "json" {
+		result["tree"] = tree
+	} else {
+		result["tree"] = renderCategoryTree(tree)
+	}
+	return s.successResponse(id, result)
+}
+
+// handleOBEXGet implements p2kb_obex_get - OBEX object retrieval.
+func (s *Server) handleOBEXGet(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Query           string `json:"query"`
+		Microcontroller string `json:"microcontroller"`
+		IncludeSnippet  *bool  `json:"include_snippet"`
+		FullDescription bool   `json:"fetch_full_description"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
 	if params.Query == "" {
 		return s.errorResponse(id, -32602, "Missing required parameter", "query")
 	}
 
+	objectID, resp := s.resolveOBEXQuery(ctx, id, params.Query, params.Microcontroller)
+	if resp != nil {
+		return resp
+	}
+	return s.getOBEXObject(id, objectID, params.IncludeSnippet == nil || *params.IncludeSnippet, params.FullDescription)
+}
+
+// resolveOBEXQuery turns a p2kb_obex_get style query (numeric ID, OBEX page
+// URL or search text) into a single object ID. When the query does not
+// identify exactly one object it returns the response to send instead:
+// no_matches, suggestions or a search error.
+func (s *Server) resolveOBEXQuery(ctx context.Context, id interface{}, query, microcontroller string) (string, *MCPResponse) {
 	// Check if query is a numeric ID
-	if isNumericID(params.Query) {
-		return s.getOBEXObject(id, params.Query)
+	if isNumericID(query) {
+		return query, nil
+	}
+
+	// A page URL pasted from the browser names exactly one object
+	if _, ok := obex.OBEXPageSlug(query); ok {
+		obj, err := s.obexManager.SearchByOBEXPageURL(query)
+		if err != nil {
+			return "", s.successResponse(id, map[string]interface{}{
+				"type":    "no_matches",
+				"query":   query,
+				"message": "No OBEX object has this page URL",
+				"hint":    "Try using p2kb_obex_find to explore available objects",
+			})
+		}
+		return obj.ObjectMetadata.ObjectID, nil
 	}
 
 	// Search for matching objects
-	results, err := s.obexManager.Search(params.Query, "", "", 10)
+	endSearch := s.telemetry.span(ctx, spanOBEXSearch, attribute.String("query", query))
+	results, err := s.obexManager.Search(query, "", "", microcontroller, 10)
+	endSearch(err)
 	if err != nil {
-		return s.errorResponse(id, -32000, "OBEX search failed", err.Error())
+		return "", s.errorResponse(id, managerErrorCode(err), "OBEX search failed", err.Error())
 	}
 
 	if len(results) == 0 {
-		return s.successResponse(id, map[string]interface{}{
+		return "", s.successResponse(id, map[string]interface{}{
 			"type":    "no_matches",
-			"query":   params.Query,
+			"query":   query,
 			"message": "No OBEX objects found matching this query",
 			"hint":    "Try using p2kb_obex_find to explore available objects",
 		})
 	}
 
-	// Single result - return full object info
+	// Single result - the caller handles the object
 	if len(results) == 1 {
-		return s.getOBEXObject(id, results[0].ObjectID)
+		return results[0].ObjectID, nil
 	}
 
 	// Multiple results - return as suggestions
 	suggestions := make([]map[string]interface{}, 0, len(results))
 	for _, r := range results {
 		suggestions = append(suggestions, map[string]interface{}{
-			"object_id":   r.ObjectID,
-			"title":       r.Title,
-			"author":      r.Author,
-			"category":    r.Category,
-			"description": r.DescriptionShort,
-			"match_type":  r.MatchType,
+			"object_id":       r.ObjectID,
+			"title":           r.Title,
+			"author":          r.Author,
+			"category":        r.Category,
+			"description":     r.DescriptionShort,
+			"match_type":      r.MatchType,
+			"microcontroller": r.Microcontroller,
 		})
 	}
 
-	return s.successResponse(id, map[string]interface{}{
+	return "", s.successResponse(id, map[string]interface{}{
 		"type":        "suggestions",
-		"query":       params.Query,
+		"query":       query,
 		"message":     "Multiple OBEX objects found. Specify an object_id or refine your search.",
 		"suggestions": suggestions,
 	})
 }
 
-// getOBEXObject returns full OBEX object info with download instructions.
-func (s *Server) getOBEXObject(id interface{}, objectID string) *MCPResponse {
+// maxDownloadRedirects caps the redirects followed when P2KB_LOG_REDIRECTS
+// traces an OBEX download URL.
+const maxDownloadRedirects = 10
+
+// obexPageTimeout bounds the OBEX page fetch behind fetch_full_description.
+const obexPageTimeout = 10 * time.Second
+
+// obexPageCacheDir is where, under the cache directory, FetchCached keeps the
+// OBEX pages fetch_full_description reads, so an unchanged page is answered
+// with a 304 instead of being downloaded again.
+const obexPageCacheDir = "http"
+
+// getOBEXObject returns full OBEX object info with download instructions,
+// and with includeSnippet the Spin2 code to use a Spin2 object.
+func (s *Server) getOBEXObject(id interface{}, objectID string, includeSnippet, fullDescription bool) *MCPResponse {
 	obj, err := s.obexManager.GetObject(objectID)
-	if err != nil {
+	var notFound *errs.ErrKeyNotFound
+	if errors.As(err, &notFound) {
 		return s.successResponse(id, map[string]interface{}{
 			"type":      "object_not_found",
 			"object_id": objectID,
@@ -326,6 +1422,11 @@ func (s *Server) getOBEXObject(id interface{}, objectID string) *MCPResponse {
 			"hint":      "Use p2kb_obex_find to search for objects",
 		})
 	}
+	if err != nil {
+		data := map[string]interface{}{"error": err.Error(), "object_id": objectID}
+		addRetryAfter(data, err)
+		return s.errorResponse(id, managerErrorCode(err), fmt.Sprintf("Failed to fetch OBEX object '%s'", objectID), data)
+	}
 
 	meta := obj.ObjectMetadata
 	downloadURL := s.obexManager.GetDownloadURL(meta.ObjectID)
@@ -333,17 +1434,18 @@ func (s *Server) getOBEXObject(id interface{}, objectID string) *MCPResponse {
 	// Generate slug for directory naming
 	slug := generateSlug(meta.Title)
 
-	return s.successResponse(id, map[string]interface{}{
-		"type":         "obex_object",
-		"object_id":    meta.ObjectID,
-		"title":        meta.Title,
-		"author":       meta.Author,
-		"category":     meta.Functionality.Category,
-		"description":  meta.Functionality.DescriptionShort,
-		"languages":    meta.TechnicalDetails.Languages,
-		"tags":         meta.Functionality.Tags,
-		"download_url": downloadURL,
-		"obex_page":    meta.URLs.OBEXPage,
+	result := map[string]interface{}{
+		"type":            "obex_object",
+		"object_id":       meta.ObjectID,
+		"title":           meta.Title,
+		"author":          meta.Author,
+		"category":        meta.Functionality.Category,
+		"description":     meta.Functionality.DescriptionShort,
+		"languages":       meta.TechnicalDetails.Languages,
+		"microcontroller": meta.TechnicalDetails.Microcontroller,
+		"tags":            meta.Functionality.Tags,
+		"download_url":    downloadURL,
+		"obex_page":       meta.URLs.OBEXPage,
 		"download_instructions": map[string]interface{}{
 			"suggested_directory": fmt.Sprintf("OBEX/%s", slug),
 			"filename":            fmt.Sprintf("OB%s.zip", meta.ObjectID),
@@ -355,16 +1457,88 @@ func (s *Server) getOBEXObject(id interface{}, objectID string) *MCPResponse {
 			"quality":      meta.Metadata.QualityScore,
 			"created_date": meta.Metadata.CreatedDate,
 		},
-	})
+	}
+
+	// A P2KB_OBEX_LOCAL_DIR object overrides or adds to the public OBEX
+	if obj.IsLocal() {
+		result["source"] = "local"
+	}
+
+	if includeSnippet && hasLanguage(meta.TechnicalDetails.Languages, "SPIN2") {
+		result["code_snippet"] = spin2Snippet(meta)
+	}
+
+	// The YAML description_full is sometimes cut short; the OBEX page has it all
+	if fullDescription {
+		s.addPageDescription(result, meta.URLs.OBEXPage)
+	}
+
+	// Opt-in diagnostics for broken downloads: follow the download URL and
+	// report where it leads
+	if s.getenv("P2KB_LOG_REDIRECTS") == "true" {
+		_, chain, err := fetch.NewClient().FetchURLWithRedirectControl(context.Background(), downloadURL, maxDownloadRedirects)
+		result["redirect_chain"] = chain
+		if err != nil {
+			result["redirect_error"] = err.Error()
+		}
+	}
+
+	// Strict mode surfaces structural problems in the upstream YAML to the caller
+	if s.getenv("P2KB_STRICT_VALIDATION") == "true" {
+		if warnings := obj.ValidationWarnings(); len(warnings) > 0 {
+			result["validation_warnings"] = warnings
+		}
+	}
+
+	return s.successResponse(id, result)
+}
+
+// addPageDescription fetches the OBEX page at pageURL, revalidating the copy
+// kept under obexPageCacheDir by ETag, and adds the description found there
+// to result as full_description_html and full_description_text.
+// When the page is unreachable or has no description, result instead reports
+// full_description_available: false and why; the object is served regardless.
+func (s *Server) addPageDescription(result map[string]interface{}, pageURL string) {
+	fail := func(reason string) {
+		result["full_description_available"] = false
+		result["full_description_error"] = reason
+	}
+	if pageURL == "" {
+		fail("Object has no OBEX page")
+		return
+	}
+
+	client := fetch.NewClient(fetch.WithTimeout(obexPageTimeout))
+	page, err := client.FetchCached(context.Background(), pageURL, "", filepath.Join(s.cacheManager.CacheDir(), obexPageCacheDir))
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	html, text, ok := obex.ExtractPageDescription(bytes.NewReader(page))
+	if !ok {
+		fail("No description found on the OBEX page")
+		return
+	}
+	result["full_description_available"] = true
+	result["full_description_html"] = html
+	result["full_description_text"] = text
 }
 
 // handleOBEXFind implements p2kb_obex_find - explore OBEX objects.
-func (s *Server) handleOBEXFind(id interface{}, args json.RawMessage) *MCPResponse {
+func (s *Server) handleOBEXFind(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
 	var params struct {
-		Term     string `json:"term"`
-		Category string `json:"category"`
-		Author   string `json:"author"`
-		Limit    int    `json:"limit"`
+		Term            string   `json:"term"`
+		Category        string   `json:"category"`
+		Subcategory     string   `json:"subcategory"`
+		Author          string   `json:"author"`
+		Authors         []string `json:"authors"`
+		Microcontroller string   `json:"microcontroller"`
+		Limit           int      `json:"limit"`
+		Offset          int      `json:"offset"`
+		ShowInvalid     bool     `json:"show_invalid"`
+		Mode            string   `json:"mode"`
+		MinFileSizeKB   int      `json:"min_file_size_kb"`
+		MaxFileSizeKB   int      `json:"max_file_size_kb"`
 	}
 	params.Limit = 20 // default
 
@@ -374,8 +1548,77 @@ func (s *Server) handleOBEXFind(id interface{}, args json.RawMessage) *MCPRespon
 		}
 	}
 
+	// Admin/debug: list objects that fail schema validation (hidden from search/browse)
+	if params.ShowInvalid {
+		invalid, err := s.obexManager.GetInvalidObjects()
+		if err != nil {
+			return s.errorResponse(id, -32000, "Failed to validate OBEX objects", err.Error())
+		}
+
+		return s.successResponse(id, map[string]interface{}{
+			"type":    "invalid_objects",
+			"objects": invalid,
+			"count":   len(invalid),
+		})
+	}
+
+	// Tag cloud: the most common tags across the whole corpus; other filters are ignored
+	if params.Mode != "" {
+		if params.Mode != "tags" {
+			return s.errorResponse(id, -32602, "Invalid mode", fmt.Sprintf("unknown mode %q (want \"tags\")", params.Mode))
+		}
+
+		cloud, err := s.obexManager.GetTagCloud()
+		if err != nil {
+			return s.errorResponse(id, -32000, "Failed to build OBEX tag cloud", err.Error())
+		}
+
+		return s.successResponse(id, map[string]interface{}{
+			"type":          "tag_cloud",
+			"tags":          cloud.Tags,
+			"tag_pairs":     cloud.TagPairs,
+			"total_objects": cloud.TotalObjects,
+		})
+	}
+
+	if params.Offset < 0 {
+		return s.errorResponse(id, -32602, "Invalid offset", "offset must be 0 or greater")
+	}
+	inPage := func(i int) bool {
+		return i >= params.Offset && (params.Limit <= 0 || i < params.Offset+params.Limit)
+	}
+
+	// Subcategories are only unique within their parent category
+	if params.Subcategory != "" && params.Category == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "subcategory requires category")
+	}
+
+	// A team's objects may be filed under several names; authors searches them all
+	if params.Author != "" && len(params.Authors) > 0 {
+		return s.errorResponse(id, -32602, "Conflicting parameters", "author and authors are mutually exclusive")
+	}
+	authorQueries := params.Authors
+	if params.Author != "" {
+		authorQueries = []string{params.Author}
+	}
+
+	// "any" is the documented way of asking for no microcontroller filter
+	filterMicrocontroller := !obex.MatchesMicrocontroller(nil, params.Microcontroller)
+
+	// Size bounds are in KB; zero means no bound
+	if params.MinFileSizeKB < 0 || params.MaxFileSizeKB < 0 {
+		return s.errorResponse(id, -32602, "Invalid file size", "min_file_size_kb and max_file_size_kb must not be negative")
+	}
+	if params.MaxFileSizeKB > 0 && params.MinFileSizeKB > params.MaxFileSizeKB {
+		return s.errorResponse(id, -32602, "Invalid file size", "min_file_size_kb is larger than max_file_size_kb")
+	}
+	filterSize := params.MinFileSizeKB > 0 || params.MaxFileSizeKB > 0
+	matchesSize := func(obj obex.SearchResult) bool {
+		return obex.MatchesFileSize(obj.FileSizeBytes, params.MinFileSizeKB, params.MaxFileSizeKB)
+	}
+
 	// No parameters - list categories
-	if params.Term == "" && params.Category == "" && params.Author == "" {
+	if params.Term == "" && params.Category == "" && len(authorQueries) == 0 && !filterMicrocontroller && !filterSize {
 		categories, err := s.obexManager.GetCategories()
 		if err != nil {
 			return s.errorResponse(id, -32000, "Failed to get OBEX categories", err.Error())
@@ -388,140 +1631,618 @@ func (s *Server) handleOBEXFind(id interface{}, args json.RawMessage) *MCPRespon
 			topAuthors = topAuthors[:5]
 		}
 
+		// One pass over the objects for both the distribution and the sizes
+		overview, err := s.obexManager.GetOverviewStats()
+		if err != nil {
+			overview = &obex.OverviewStats{}
+		}
+
+		// Nest each category's subcategories under it
+		sorted := sortCategoryCounts(categories)
+		categoryCounts := make([]obexCategoryCount, 0, len(sorted))
+		for _, c := range sorted {
+			entry := obexCategoryCount{Name: c.Name, Count: c.Count}
+			if subcategories := s.obexManager.GetSubcategories(c.Name); len(subcategories) > 0 {
+				entry.Subcategories = subcategories
+			}
+			categoryCounts = append(categoryCounts, entry)
+		}
+
 		return s.successResponse(id, map[string]interface{}{
-			"type":          "overview",
-			"categories":    categories,
-			"total_objects": s.obexManager.GetTotalObjects(),
-			"top_authors":   topAuthors,
+			"type":                         "overview",
+			"categories":                   categoryCounts,
+			"total_objects":                s.obexManager.GetTotalObjects(),
+			"local_objects":                s.obexManager.LocalObjectCount(),
+			"top_authors":                  topAuthors,
+			"microcontroller_distribution": overview.MicrocontrollerDistribution,
+			"file_size_stats":              overview.FileSizes,
 		})
 	}
 
 	// Author filter
-	if params.Author != "" && params.Term == "" && params.Category == "" {
-		// Get all objects and filter by author
-		objects, err := s.obexManager.BrowseCategory("")
+	if len(authorQueries) > 0 && params.Term == "" && params.Category == "" {
+		// Fuzzy author match; the page is taken after the microcontroller filter
+		var objects []obex.SearchResult
+		var err error
+		if params.Author != "" {
+			objects, err = s.obexManager.BrowseByAuthor(params.Author, 0)
+		} else {
+			objects, err = s.obexManager.BrowseByAuthors(params.Authors, 0)
+		}
 		if err != nil {
 			return s.errorResponse(id, -32000, "Failed to browse OBEX", err.Error())
 		}
 
 		filtered := make([]map[string]interface{}, 0)
+		authorsMatched := make(map[string]int, len(authorQueries))
+		for _, query := range authorQueries {
+			authorsMatched[query] = 0
+		}
+		total := 0
 		for _, obj := range objects {
-			if strings.Contains(strings.ToLower(obj.Author), strings.ToLower(params.Author)) {
-				filtered = append(filtered, map[string]interface{}{
-					"object_id":   obj.ObjectID,
-					"title":       obj.Title,
-					"author":      obj.Author,
-					"category":    obj.Category,
-					"description": obj.DescriptionShort,
-				})
-				if len(filtered) >= params.Limit {
-					break
-				}
+			if !obex.MatchesMicrocontroller(obj.Microcontroller, params.Microcontroller) || !matchesSize(obj) {
+				continue
+			}
+			for _, query := range obj.MatchedQueries {
+				authorsMatched[query]++
+			}
+			total++
+			if !inPage(total - 1) {
+				continue
+			}
+			object := map[string]interface{}{
+				"object_id":           obj.ObjectID,
+				"title":               obj.Title,
+				"author":              obj.Author,
+				"category":            obj.Category,
+				"description":         obj.DescriptionShort,
+				"microcontroller":     obj.Microcontroller,
+				"match_score":         obj.MatchScore,
+				"matched_author_name": obj.MatchedAuthorName,
 			}
+			if obj.Source != "" {
+				object["source"] = obj.Source
+			}
+			if filterSize {
+				addFileSize(object, obj.FileSizeBytes)
+			}
+			filtered = append(filtered, object)
 		}
 
-		return s.successResponse(id, map[string]interface{}{
+		result := map[string]interface{}{
 			"type":    "objects",
-			"author":  params.Author,
 			"objects": filtered,
 			"count":   len(filtered),
-		})
+		}
+		addPageInfo(result, total, params.Offset, params.Limit)
+		if params.Author != "" {
+			result["author"] = params.Author
+		} else {
+			result["authors"] = params.Authors
+			result["authors_matched"] = authorsMatched
+		}
+		return s.successResponse(id, result)
 	}
 
 	// Search or browse
 	if params.Term != "" {
-		results, err := s.obexManager.Search(params.Term, params.Category, "", params.Limit)
+		endSearch := s.telemetry.span(ctx, spanOBEXSearch, attribute.String("query", params.Term))
+		// Every filter is applied by the search, before its limit, so the
+		// page holds only matches that pass them. One match past the page
+		// tells whether another follows; with no limit, every match is
+		// wanted.
+		filters := obex.SearchFilters{
+			Category:        params.Category,
+			Subcategory:     params.Subcategory,
+			Microcontroller: params.Microcontroller,
+			MinFileSizeKB:   params.MinFileSizeKB,
+			MaxFileSizeKB:   params.MaxFileSizeKB,
+		}
+		searchLimit := params.Offset + params.Limit + 1
+		if params.Limit <= 0 {
+			searchLimit = s.obexManager.GetTotalObjects()
+		}
+		results, phases, err := s.obexManager.SearchWithPhases(params.Term, filters, searchLimit)
+		endSearch(err)
 		if err != nil {
 			return s.errorResponse(id, -32000, "OBEX search failed", err.Error())
 		}
 
 		objects := make([]map[string]interface{}, 0, len(results))
+		total := 0
 		for _, r := range results {
-			objects = append(objects, map[string]interface{}{
-				"object_id":   r.ObjectID,
-				"title":       r.Title,
-				"author":      r.Author,
-				"category":    r.Category,
-				"description": r.DescriptionShort,
-				"match_type":  r.MatchType,
-			})
+			total++
+			if !inPage(total - 1) {
+				continue
+			}
+			object := map[string]interface{}{
+				"object_id":       r.ObjectID,
+				"title":           r.Title,
+				"author":          r.Author,
+				"category":        r.Category,
+				"subcategory":     r.Subcategory,
+				"description":     r.DescriptionShort,
+				"match_type":      r.MatchType,
+				"microcontroller": r.Microcontroller,
+			}
+			if r.MatchedInFull {
+				object["matched_in_full"] = true
+			}
+			if r.Source != "" {
+				object["source"] = r.Source
+			}
+			if filterSize {
+				addFileSize(object, r.FileSizeBytes)
+			}
+			objects = append(objects, object)
 		}
 
-		return s.successResponse(id, map[string]interface{}{
-			"type":    "objects",
-			"term":    params.Term,
-			"objects": objects,
-			"count":   len(objects),
-		})
+		result := map[string]interface{}{
+			"type":          "objects",
+			"term":          params.Term,
+			"objects":       objects,
+			"count":         len(objects),
+			"search_phases": phases,
+		}
+		addPageInfo(result, total, params.Offset, params.Limit)
+		return s.successResponse(id, result)
 	}
 
-	// Category browse
-	if params.Category != "" {
-		objects, err := s.obexManager.BrowseCategory(params.Category)
+	// Category browse; a microcontroller or size filter alone browses every category
+	if params.Category != "" || filterMicrocontroller || filterSize {
+		var objects []obex.SearchResult
+		var err error
+		if params.Subcategory != "" {
+			objects, err = s.obexManager.BrowseSubcategory(params.Category, params.Subcategory)
+		} else {
+			objects, err = s.obexManager.BrowseCategory(params.Category)
+		}
 		if err != nil {
 			return s.errorResponse(id, -32000, "Failed to browse category", err.Error())
 		}
 
-		if len(objects) > params.Limit {
-			objects = objects[:params.Limit]
-		}
-
 		result := make([]map[string]interface{}, 0, len(objects))
+		total := 0
 		for _, obj := range objects {
-			result = append(result, map[string]interface{}{
-				"object_id":   obj.ObjectID,
-				"title":       obj.Title,
-				"author":      obj.Author,
-				"description": obj.DescriptionShort,
-			})
+			if !obex.MatchesMicrocontroller(obj.Microcontroller, params.Microcontroller) || !matchesSize(obj) {
+				continue
+			}
+			total++
+			if !inPage(total - 1) {
+				continue
+			}
+			object := map[string]interface{}{
+				"object_id":       obj.ObjectID,
+				"title":           obj.Title,
+				"author":          obj.Author,
+				"subcategory":     obj.Subcategory,
+				"description":     obj.DescriptionShort,
+				"microcontroller": obj.Microcontroller,
+			}
+			if obj.Source != "" {
+				object["source"] = obj.Source
+			}
+			if filterSize {
+				addFileSize(object, obj.FileSizeBytes)
+			}
+			result = append(result, object)
 		}
 
-		return s.successResponse(id, map[string]interface{}{
+		response := map[string]interface{}{
 			"type":     "objects",
 			"category": params.Category,
 			"objects":  result,
 			"count":    len(result),
-		})
+		}
+		addPageInfo(response, total, params.Offset, params.Limit)
+		if params.Subcategory != "" {
+			response["subcategory"] = params.Subcategory
+		}
+		return s.successResponse(id, response)
 	}
 
 	// Shouldn't reach here
 	return s.errorResponse(id, -32602, "Invalid parameters", nil)
 }
 
+// addFileSize adds an object's file size in KB to a p2kb_obex_find result:
+// -1 with size_unknown set when the object gives no readable size.
+func addFileSize(object map[string]interface{}, sizeBytes int64) {
+	if sizeBytes < 0 {
+		object["file_size_kb"] = -1
+		object["size_unknown"] = true
+		return
+	}
+	object["file_size_kb"] = obex.SizeKB(sizeBytes)
+}
+
+// handleDiscover implements p2kb_discover - one query run against the KB and
+// OBEX at the same time, so the caller need not know which one holds the
+// answer. primary_source names the system with more matches.
+func (s *Server) handleDiscover(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	params.Limit = 10 // default, per source
+
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if params.Query == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "query")
+	}
+
+	var kb, obexResult map[string]interface{}
+	var kbCount, obexCount int
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		kb, kbCount = s.discoverKB(params.Query, params.Limit)
+	}()
+	go func() {
+		defer wg.Done()
+		obexResult, obexCount = s.discoverOBEX(ctx, id, params.Query, params.Limit)
+	}()
+	wg.Wait()
+
+	primary := "none"
+	switch {
+	case kbCount > obexCount:
+		primary = "kb"
+	case obexCount > kbCount:
+		primary = "obex"
+	case kbCount > 0:
+		primary = "both"
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":           "discover",
+		"query":          params.Query,
+		"primary_source": primary,
+		"kb":             kb,
+		"obex":           obexResult,
+		"hint":           "Use p2kb_get for KB keys and p2kb_obex_get for OBEX objects",
+	})
+}
+
+// discoverKB returns the KB side of p2kb_discover - the categories whose
+// names contain query and the keys it finds - and how many matches that is.
+func (s *Server) discoverKB(query string, limit int) (map[string]interface{}, int) {
+	categories := matchingCategories(s.indexManager.GetCategoriesWithCounts(), query)
+	keys := s.searchTokens(strings.Fields(query), "AND", limit)
+
+	count := len(categories) + len(keys)
+	return map[string]interface{}{
+		"categories": categories,
+		"keys":       keys,
+		"count":      count,
+	}, count
+}
+
+// discoverOBEX is discoverKB for OBEX categories and objects. A failure to
+// load the OBEX index is reported in the result rather than failing the call.
+func (s *Server) discoverOBEX(ctx context.Context, id interface{}, query string, limit int) (map[string]interface{}, int) {
+	counts, err := s.obexManager.GetCategories()
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "count": 0}, 0
+	}
+	endSearch := s.telemetry.span(ctx, spanOBEXSearch, attribute.String("query", query))
+	objects, err := s.obexManager.Search(query, "", "", "", limit)
+	endSearch(err)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "count": 0}, 0
+	}
+	if objects == nil {
+		objects = []obex.SearchResult{}
+	}
+
+	categories := matchingCategories(counts, query)
+	count := len(categories) + len(objects)
+	return map[string]interface{}{
+		"categories": categories,
+		"objects":    objects,
+		"count":      count,
+	}, count
+}
+
+// matchingCategories returns the categories whose names contain query,
+// ignoring case, largest first.
+func matchingCategories(counts map[string]int, query string) []categoryCount {
+	query = strings.ToLower(query)
+	matches := make([]categoryCount, 0)
+	for _, c := range sortCategoryCounts(counts) {
+		if strings.Contains(strings.ToLower(c.Name), query) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// handleOBEXTagSearch implements p2kb_obex_tag_search - OBEX objects by tag,
+// looked up in the inverted tag index: tag for one exact (normalized) tag,
+// prefix_tag for every tag starting with it.
+func (s *Server) handleOBEXTagSearch(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Tag       string `json:"tag"`
+		PrefixTag string `json:"prefix_tag"`
+		Limit     int    `json:"limit"`
+	}
+	params.Limit = 20 // default
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	tag, prefix := strings.TrimSpace(params.Tag), strings.TrimSpace(params.PrefixTag)
+	if tag != "" && prefix != "" {
+		return s.errorResponse(id, -32602, "Invalid arguments", "tag and prefix_tag are mutually exclusive")
+	}
+	if tag == "" && prefix == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "tag or prefix_tag")
+	}
+
+	if tag != "" {
+		objects, err := s.obexManager.SearchByTag(tag, params.Limit)
+		if err != nil {
+			return s.errorResponse(id, -32000, "Failed to search OBEX tags", err.Error())
+		}
+		return s.successResponse(id, map[string]interface{}{
+			"type":    "tag_results",
+			"tag":     params.Tag,
+			"objects": objects,
+			"count":   len(objects),
+		})
+	}
+
+	objects, tags, err := s.obexManager.SearchByTagPrefix(prefix, params.Limit)
+	if err != nil {
+		return s.errorResponse(id, -32000, "Failed to search OBEX tags", err.Error())
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	return s.successResponse(id, map[string]interface{}{
+		"type":         "tag_results",
+		"prefix_tag":   params.PrefixTag,
+		"matched_tags": tags,
+		"objects":      objects,
+		"count":        len(objects),
+	})
+}
+
+// handleOBEXAuthorDetail implements p2kb_obex_author_detail - an author's
+// complete OBEX portfolio, or the matching names if a partial name is ambiguous.
+func (s *Server) handleOBEXAuthorDetail(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Author string `json:"author"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if strings.TrimSpace(params.Author) == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "author")
+	}
+
+	authors, err := s.obexManager.MatchAuthors(params.Author)
+	if err != nil {
+		return s.errorResponse(id, -32000, "Failed to search OBEX authors", err.Error())
+	}
+
+	if len(authors) == 0 {
+		return s.successResponse(id, map[string]interface{}{
+			"type":    "author_not_found",
+			"author":  params.Author,
+			"message": fmt.Sprintf("No OBEX author matches '%s'", params.Author),
+			"hint":    "Use p2kb_obex_find with no parameters to see top authors",
+		})
+	}
+	if len(authors) > 1 {
+		return s.successResponse(id, map[string]interface{}{
+			"type":             "author_ambiguous",
+			"author":           params.Author,
+			"message":          fmt.Sprintf("'%s' matches several authors; specify one", params.Author),
+			"matching_authors": authors,
+		})
+	}
+
+	detail, err := s.obexManager.GetAuthorDetail(authors[0].Name)
+	if err != nil {
+		return s.errorResponse(id, -32000, "Failed to build author portfolio", err.Error())
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":                  "author_detail",
+		"author":                detail.Name,
+		"object_count":          detail.ObjectCount,
+		"categories":            detail.Categories,
+		"tags":                  detail.Tags,
+		"average_quality_score": detail.AverageQuality,
+		"earliest_created_date": detail.EarliestCreated,
+		"latest_created_date":   detail.LatestCreated,
+		"languages":             detail.Languages,
+		"github_usernames":      detail.GitHubUsernames,
+		"objects":               detail.Objects,
+	})
+}
+
+// handleOBEXStats implements p2kb_obex_stats - aggregate statistics over the
+// whole OBEX corpus, reused for obex.CorpusStatsTTL between calls.
+func (s *Server) handleOBEXStats(id interface{}, _ json.RawMessage) *MCPResponse {
+	stats, err := s.obexManager.GetCorpusStats()
+	if err != nil {
+		return s.errorResponse(id, -32000, "Failed to compute OBEX statistics", err.Error())
+	}
+	return s.successResponse(id, stats)
+}
+
+// handleOBEXDependencyGraph implements p2kb_obex_dependency_graph - which OBEX
+// objects mention each other in their descriptions.
+func (s *Server) handleOBEXDependencyGraph(id interface{}) *MCPResponse {
+	graph, err := s.obexManager.GetDependencyGraph()
+	if err != nil {
+		return s.errorResponse(id, managerErrorCode(err), "Failed to build OBEX dependency graph", err.Error())
+	}
+	return s.successResponse(id, map[string]interface{}{
+		"type":                          "obex_dependency_graph",
+		"nodes":                         graph.Nodes,
+		"edges":                         graph.Edges,
+		"strongly_connected_components": graph.StronglyConnectedComponents,
+		"isolated_objects":              graph.IsolatedObjects,
+		"analyzed_objects":              graph.AnalyzedObjects,
+		"total_objects":                 graph.TotalObjects,
+		"truncated":                     graph.Truncated,
+		"node_limit":                    obex.DependencyGraphLimit,
+	})
+}
+
 // handleOBEXDownload implements p2kb_obex_download - download and extract OBEX objects.
 func (s *Server) handleOBEXDownload(id interface{}, args json.RawMessage) *MCPResponse {
 	var params struct {
 		ObjectID  string `json:"object_id"`
 		TargetDir string `json:"target_dir"`
 	}
-	if err := json.Unmarshal(args, &params); err != nil {
-		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if params.ObjectID == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "object_id")
+	}
+
+	// Perform download and extraction
+	result, err := s.obexManager.DownloadAndExtract(params.ObjectID, params.TargetDir)
+	if err != nil {
+		return s.errorResponse(id, -32000, "Download failed", map[string]interface{}{
+			"object_id": params.ObjectID,
+			"error":     err.Error(),
+			"hint":      "Check that the object ID is valid using p2kb_obex_get first",
+		})
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":            "download_complete",
+		"object_id":       result.ObjectID,
+		"title":           result.Title,
+		"extraction_path": result.ExtractionPath,
+		"files":           result.Files,
+		"total_size":      result.TotalSize,
+		"file_count":      len(result.Files),
+		"message":         fmt.Sprintf("Successfully downloaded and extracted %d files to %s", len(result.Files), result.ExtractionPath),
+	})
+}
+
+// DefaultPreviewBytes is how much of an OBEX file p2kb_obex_preview returns
+// when max_bytes is not given; maxPreviewBytes caps what a caller may ask for.
+const (
+	DefaultPreviewBytes = 4096
+	maxPreviewBytes     = 1 << 20
+)
+
+// handleOBEXPreview implements p2kb_obex_preview - peek at an OBEX object's
+// ZIP without extracting it. Downloading is opt-in via P2KB_ENABLE_DOWNLOADS.
+func (s *Server) handleOBEXPreview(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Query    string `json:"query"`
+		MaxBytes int    `json:"max_bytes"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if params.Query == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "query")
+	}
+	if params.MaxBytes < 0 || params.MaxBytes > maxPreviewBytes {
+		return s.errorResponse(id, -32602, "Invalid max_bytes", fmt.Sprintf("max_bytes must be between 1 and %d", maxPreviewBytes))
+	}
+	if params.MaxBytes == 0 {
+		params.MaxBytes = DefaultPreviewBytes
+	}
+
+	if s.getenv("P2KB_ENABLE_DOWNLOADS") != "true" {
+		return s.errorResponse(id, -32000, "Downloads disabled", map[string]interface{}{
+			"hint": "Set P2KB_ENABLE_DOWNLOADS=true in the server environment to allow p2kb_obex_preview to fetch OBEX ZIPs",
+		})
+	}
+
+	objectID, resp := s.resolveOBEXQuery(ctx, id, params.Query, "")
+	if resp != nil {
+		return resp
+	}
+
+	obj, err := s.obexManager.GetObject(objectID)
+	if err != nil {
+		return s.successResponse(id, map[string]interface{}{
+			"type":      "object_not_found",
+			"object_id": objectID,
+			"message":   fmt.Sprintf("OBEX object '%s' not found", objectID),
+			"hint":      "Use p2kb_obex_find to search for objects",
+		})
+	}
+	meta := obj.ObjectMetadata
+
+	downloadURL := s.obexManager.GetDownloadURL(meta.ObjectID)
+	data, err := fetch.NewClient().FetchURL(downloadURL)
+	if err != nil {
+		return s.errorResponse(id, -32000, "OBEX download unavailable", map[string]interface{}{
+			"object_id":    meta.ObjectID,
+			"download_url": downloadURL,
+			"error":        err.Error(),
+			"hint":         "The OBEX site did not return this object's ZIP. It may be temporarily down, or the object may have been removed; check its OBEX page: " + meta.URLs.OBEXPage,
+		})
+	}
+
+	result := map[string]interface{}{
+		"type":         "obex_preview",
+		"object_id":    meta.ObjectID,
+		"title":        meta.Title,
+		"download_url": downloadURL,
+		"max_bytes":    params.MaxBytes,
 	}
 
-	if params.ObjectID == "" {
-		return s.errorResponse(id, -32602, "Missing required parameter", "object_id")
+	var content []byte
+	preview, err := obex.PreviewZip(data, params.MaxBytes)
+	if err == nil {
+		content = preview.Content
+		result["file_listing"] = preview.Files
+		result["preview_filename"] = preview.PreviewFile
+		result["truncated"] = preview.Truncated
+	} else {
+		// Not a ZIP: preview the raw response body instead
+		content = data
+		if len(content) > params.MaxBytes {
+			content = content[:params.MaxBytes]
+		}
+		result["file_listing"] = []obex.ZipEntry{}
+		result["preview_filename"] = ""
+		result["truncated"] = len(data) > params.MaxBytes
+		result["note"] = "Download is not a ZIP archive; previewing the raw response"
 	}
 
-	// Perform download and extraction
-	result, err := s.obexManager.DownloadAndExtract(params.ObjectID, params.TargetDir)
-	if err != nil {
-		return s.errorResponse(id, -32000, "Download failed", map[string]interface{}{
-			"object_id": params.ObjectID,
-			"error":     err.Error(),
-			"hint":      "Check that the object ID is valid using p2kb_obex_get first",
-		})
+	if text, ok := utf8Prefix(content); ok {
+		result["preview_content"] = text
+		result["preview_encoding"] = "utf-8"
+	} else {
+		result["preview_content"] = base64.StdEncoding.EncodeToString(content)
+		result["preview_encoding"] = "base64"
 	}
 
-	return s.successResponse(id, map[string]interface{}{
-		"type":            "download_complete",
-		"object_id":       result.ObjectID,
-		"title":           result.Title,
-		"extraction_path": result.ExtractionPath,
-		"files":           result.Files,
-		"total_size":      result.TotalSize,
-		"file_count":      len(result.Files),
-		"message":         fmt.Sprintf("Successfully downloaded and extracted %d files to %s", len(result.Files), result.ExtractionPath),
-	})
+	return s.successResponse(id, result)
+}
+
+// utf8Prefix returns b as text if it is valid UTF-8, allowing for one rune
+// cut short by the byte limit at the end.
+func utf8Prefix(b []byte) (string, bool) {
+	for trim := 0; trim < utf8.UTFMax && trim <= len(b); trim++ {
+		head, tail := b[:len(b)-trim], b[len(b)-trim:]
+		if utf8.Valid(head) && (trim == 0 || !utf8.FullRune(tail)) {
+			return string(head), true
+		}
+	}
+	return "", false
 }
 
 // handleVersion implements p2kb_version.
@@ -529,10 +2250,14 @@ func (s *Server) handleVersion(id interface{}) *MCPResponse {
 	stats := s.indexManager.GetStats()
 	indexStatus := s.indexManager.GetIndexStatus()
 	obexMem, obexDisk, obexStale := s.obexManager.GetCacheStats()
+	obexLoaded, obexTotal := s.obexManager.IndexCoverage()
+	cacheStats := s.cacheManager.GetStats()
+	preloaded, prefetchErr := s.prefetchStatus()
 
-	return s.successResponse(id, map[string]interface{}{
-		"mcp_version":   s.version,
-		"index_version": stats.Version,
+	result := map[string]interface{}{
+		"mcp_version":     s.version,
+		"index_version":   stats.Version,
+		"index_preloaded": preloaded,
 		"index": map[string]interface{}{
 			"total_entries":    stats.TotalEntries,
 			"total_categories": stats.TotalCategories,
@@ -542,12 +2267,30 @@ func (s *Server) handleVersion(id interface{}) *MCPResponse {
 			"needs_refresh":    indexStatus.NeedsRefresh,
 		},
 		"obex": map[string]interface{}{
-			"total_objects":       s.obexManager.GetTotalObjects(),
-			"cached_memory":       obexMem,
-			"cached_disk":         obexDisk,
-			"stale_cache_entries": obexStale,
+			"total_objects":             s.obexManager.GetTotalObjects(),
+			"cached_memory":             obexMem,
+			"cached_disk":               obexDisk,
+			"stale_cache_entries":       obexStale,
+			"corrupted_cache_evictions": s.obexManager.CorruptedCacheEvictions(),
+			"last_mirror_used":          s.obexManager.LastMirrorUsed(),
 		},
-	})
+		"settings_overridden":         s.settingsOverridden(),
+		"token_pool_size":             fetch.SharedTokenPool().Size(),
+		"active_tokens":               fetch.SharedTokenPool().Active(),
+		"auth_configured":             s.obexManager.AuthConfigured(),
+		"obex_concurrency_limit":      s.obexManager.ConcurrencyLimit(),
+		"obex_pending_fetches":        s.obexManager.PendingFetches(),
+		"obex_index_coverage_pct":     coveragePct(obexLoaded, obexTotal),
+		"content_skipped_disk_writes": cacheStats.SkippedWrites,
+		"content_etag_hits":           cacheStats.ETagHits,
+		"content_etag_misses":         cacheStats.ETagMisses,
+		"duplicate_content_groups":    cacheStats.DuplicateContentGroups,
+		"deduplicated_calls_total":    s.calls.deduplicated.Load(),
+	}
+	if prefetchErr != nil {
+		result["index_prefetch_error"] = prefetchErr.Error()
+	}
+	return s.successResponse(id, result)
 }
 
 // handleRefresh implements p2kb_refresh - smart cache refresh.
@@ -568,6 +2311,9 @@ func (s *Server) handleRefresh(id interface{}, args json.RawMessage) *MCPRespons
 		return s.errorResponse(id, -32000, "Failed to refresh index", err.Error())
 	}
 
+	// Responses kept for repeat lookups may predate the refresh
+	s.requestCache.clear()
+
 	result := map[string]interface{}{
 		"refreshed": true,
 		"flushed":   params.Flush,
@@ -596,10 +2342,12 @@ func (s *Server) handleRefresh(id interface{}, args json.RawMessage) *MCPRespons
 
 		// Optionally refresh OBEX
 		if params.IncludeOBEX {
-			if err := s.obexManager.Refresh(); err != nil {
+			if refreshStats, err := s.obexManager.Refresh(); err != nil {
 				result["obex_error"] = err.Error()
 			} else {
 				result["obex_refreshed"] = true
+				result["retained_objects"] = refreshStats.Retained
+				result["evicted_objects"] = refreshStats.Evicted
 			}
 		}
 	}
@@ -611,18 +2359,321 @@ func (s *Server) handleRefresh(id interface{}, args json.RawMessage) *MCPRespons
 	return s.successResponse(id, result)
 }
 
+// handlePin implements p2kb_pin - keep keys resident in the memory cache.
+func (s *Server) handlePin(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if len(params.Keys) == 0 {
+		return s.errorResponse(id, -32602, "Missing required parameter", "keys")
+	}
+
+	// Pin canonical keys only, so aliases and case variants share one slot
+	var canonical, unknown []string
+	for _, key := range params.Keys {
+		resolution := s.indexManager.ResolveKey(key)
+		if !resolution.Found {
+			unknown = append(unknown, key)
+			continue
+		}
+		canonical = append(canonical, resolution.CanonicalKey)
+	}
+
+	if err := s.cacheManager.Pin(canonical); err != nil {
+		return s.errorResponse(id, -32602, "Pin limit exceeded", map[string]interface{}{
+			"error": err.Error(),
+			"limit": cache.MaxPinnedKeys,
+			"hint":  "Use p2kb_unpin to release keys you no longer need",
+		})
+	}
+
+	// Pre-warm the newly pinned keys now rather than waiting for a restart
+	warmed := 0
+	for _, key := range canonical {
+		if _, err := s.getContentFor(ctx, key); err == nil {
+			warmed++
+		}
+	}
+
+	result := map[string]interface{}{
+		"type":        "pinned",
+		"pinned":      canonical,
+		"warmed":      warmed,
+		"pinned_keys": s.cacheManager.PinnedKeys(),
+	}
+	if len(unknown) > 0 {
+		result["unknown_keys"] = unknown
+	}
+
+	return s.successResponse(id, result)
+}
+
+// handleUnpin implements p2kb_unpin - release keys pinned by p2kb_pin.
+func (s *Server) handleUnpin(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if len(params.Keys) == 0 {
+		return s.errorResponse(id, -32602, "Missing required parameter", "keys")
+	}
+
+	// Accept aliases too, falling back to the literal key for entries no longer indexed
+	keys := make([]string, 0, len(params.Keys))
+	for _, key := range params.Keys {
+		if resolution := s.indexManager.ResolveKey(key); resolution.Found {
+			key = resolution.CanonicalKey
+		}
+		keys = append(keys, key)
+	}
+
+	removed, err := s.cacheManager.Unpin(keys)
+	if err != nil {
+		return s.errorResponse(id, -32000, "Failed to save pinned keys", err.Error())
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":        "unpinned",
+		"removed":     removed,
+		"pinned_keys": s.cacheManager.PinnedKeys(),
+	})
+}
+
+// handleMemoryPressure implements p2kb_memory_pressure - shrink the in-memory
+// content and OBEX caches to reclaim memory without a restart.
+func (s *Server) handleMemoryPressure(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		TargetEntries int `json:"target_entries"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+	}
+
+	if params.TargetEntries < 0 {
+		return s.errorResponse(id, -32602, "Invalid target_entries", "target_entries must be 0 or greater")
+	}
+
+	evicted, freed := s.cacheManager.EvictMemory(params.TargetEntries)
+	obexEvicted := s.obexManager.EvictMemoryObjects(params.TargetEntries)
+	stats := s.cacheManager.GetStats()
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":                        "memory_pressure",
+		"target_entries":              params.TargetEntries,
+		"evicted_count":               evicted,
+		"obex_evicted_count":          obexEvicted,
+		"remaining_memory_entries":    stats.MemoryEntries,
+		"remaining_disk_entries":      stats.DiskEntries,
+		"memory_freed_estimate_bytes": freed,
+		"pinned_keys":                 s.cacheManager.PinnedKeys(),
+	})
+}
+
+// duplicateGroup is one set of keys with identical content in p2kb_find_duplicates.
+type duplicateGroup struct {
+	ContentHash   string   `json:"content_hash"`
+	ContentLength int      `json:"content_length"`
+	Keys          []string `json:"keys"`
+}
+
+// handleFindDuplicates implements p2kb_find_duplicates - report memory-cached
+// entries whose content is identical, so KB maintainers can spot redundancy.
+// Only what is already in memory is examined.
+func (s *Server) handleFindDuplicates(id interface{}) *MCPResponse {
+	lengths := make(map[string]int)
+	entries := s.cacheManager.MemoryEntries()
+	for _, entry := range entries {
+		lengths[entry.Key] = entry.ContentLength
+	}
+
+	duplicates := s.cacheManager.FindDuplicates()
+	groups := make([]duplicateGroup, 0, len(duplicates))
+	duplicateKeys := 0
+	for hash, keys := range duplicates {
+		group := duplicateGroup{ContentHash: hash, Keys: keys}
+		for _, key := range keys {
+			if length, ok := lengths[key]; ok {
+				group.ContentLength = length
+				break
+			}
+		}
+		groups = append(groups, group)
+		duplicateKeys += len(keys)
+	}
+
+	// Largest groups first
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Keys) != len(groups[j].Keys) {
+			return len(groups[i].Keys) > len(groups[j].Keys)
+		}
+		return groups[i].ContentHash < groups[j].ContentHash
+	})
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":                   "duplicates",
+		"groups":                 groups,
+		"group_count":            len(groups),
+		"duplicate_keys":         duplicateKeys,
+		"memory_entries_scanned": len(entries),
+	})
+}
+
+// maxSuggestions caps the related keys returned by p2kb_suggest.
+const maxSuggestions = 10
+
+// maxSuggestedOBEXObjects caps the OBEX objects returned by p2kb_suggest.
+const maxSuggestedOBEXObjects = 5
+
+// suggestion is one related key proposed by p2kb_suggest.
+type suggestion struct {
+	Key          string   `json:"key"`
+	Reason       string   `json:"reason"`
+	ReferencedBy []string `json:"referenced_by"`
+}
+
+// handleSuggest implements p2kb_suggest - propose related entries the agent has
+// not looked at yet, based on the keys it has already accessed.
+func (s *Server) handleSuggest(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		ContextKeys []string `json:"context_keys"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if len(params.ContextKeys) == 0 {
+		return s.errorResponse(id, -32602, "Missing required parameter", "context_keys")
+	}
+
+	// Resolve the context to canonical keys; everything in it counts as seen
+	seen := make(map[string]bool)
+	var context, unknown []string
+	for _, key := range params.ContextKeys {
+		resolution := s.indexManager.ResolveKey(key)
+		if !resolution.Found {
+			unknown = append(unknown, key)
+			continue
+		}
+		if !seen[resolution.CanonicalKey] {
+			seen[resolution.CanonicalKey] = true
+			context = append(context, resolution.CanonicalKey)
+		}
+	}
+
+	// Union of related_instructions across the context, in first-seen order
+	byKey := make(map[string]*suggestion)
+	var suggestions []*suggestion
+	for _, key := range context {
+		content, err := s.getContentFor(ctx, key)
+		if err != nil {
+			continue
+		}
+		for _, related := range extractRelatedInstructions(content) {
+			resolution := s.indexManager.ResolveKey(related)
+			if !resolution.Found || seen[resolution.CanonicalKey] {
+				continue
+			}
+			sug, ok := byKey[resolution.CanonicalKey]
+			if !ok {
+				sug = &suggestion{Key: resolution.CanonicalKey}
+				byKey[sug.Key] = sug
+				suggestions = append(suggestions, sug)
+			}
+			if !containsString(sug.ReferencedBy, key) {
+				sug.ReferencedBy = append(sug.ReferencedBy, key)
+			}
+		}
+	}
+
+	// Keys referenced from more of the context rank first
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return len(suggestions[i].ReferencedBy) > len(suggestions[j].ReferencedBy)
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	for _, sug := range suggestions {
+		sug.Reason = "referenced by " + strings.Join(sug.ReferencedBy, ", ")
+	}
+
+	// OBEX objects whose tags overlap the words of the context keys
+	var tokens []string
+	for _, key := range context {
+		tokens = append(tokens, index.KeyTokens(key)...)
+	}
+	objects := s.obexManager.FindByTags(tokens, maxSuggestedOBEXObjects)
+
+	result := map[string]interface{}{
+		"type":         "related_suggestions",
+		"context_keys": context,
+		"suggestions":  suggestions,
+		"count":        len(suggestions),
+	}
+	if len(objects) > 0 {
+		result["obex_objects"] = objects
+	}
+	if len(unknown) > 0 {
+		result["unknown_keys"] = unknown
+	}
+
+	return s.successResponse(id, result)
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
 // Helper methods
 
 func (s *Server) getContent(key string) (string, error) {
+	return s.getContentFor(context.Background(), key)
+}
+
+// getContentFor is getContent within a tool call: when tracing, the index
+// lookup and the cache read or fetch are spans of the call ctx carries.
+func (s *Server) getContentFor(ctx context.Context, key string) (content string, err error) {
 	// Resolve the index mtime FIRST so the cache lookup is always mtime-aware:
 	// a newer index must invalidate older cached content on read. GetKeyPath
 	// also refreshes the index when its TTL has expired, and returns the
 	// content sha256 (empty for pre-3.5.0 indexes) for transport verification.
+	endIndex := s.telemetry.span(ctx, spanIndexEnsure, attribute.String("key", key))
 	path, mtime, sha256, err := s.indexManager.GetKeyPath(key)
+	endIndex(err)
 	if err != nil {
 		return "", err
 	}
 
+	// A cached copy older than the index is fetched again
+	if s.telemetry != nil {
+		name := spanCacheGet
+		if cached := s.cacheManager.GetMtime(key); cached == 0 || cached < mtime {
+			name = spanFetchContent
+		}
+		endContent := s.telemetry.span(ctx, name, attribute.String("key", key))
+		defer func() { endContent(err) }()
+	}
+
+	// Keys contributed by a P2KB_EXTRA_INDEX_URLS index live in that index's
+	// repository, not the public one.
+	if source := s.indexManager.GetKeySource(key); source != "" && source != index.IndexURL {
+		return s.cacheManager.GetOrFetchFrom(index.ContentBaseURL(source), key, path, sha256, mtime)
+	}
+
 	// Single mtime-aware entry point: memory -> disk -> remote, with disk
 	// presence authoritative. Never bypasses the index mtime; verifies the
 	// download against sha256 when the index carries one.
@@ -645,11 +2696,9 @@ func (s *Server) successResponse(id interface{}, result interface{}) *MCPRespons
 }
 
 func (s *Server) errorResponse(id interface{}, code int, message string, data interface{}) *MCPResponse {
-	// Log errors to stderr for diagnostics (visible to users checking logs)
-	logLevel := os.Getenv("P2KB_LOG_LEVEL")
-	if logLevel == "debug" || logLevel == "info" {
-		log.Printf("p2kb-mcp error [%d]: %s (data: %v)", code, message, data)
-	}
+	// Log errors to stderr for diagnostics; shown only at P2KB_LOG_LEVEL=debug
+	// (see logging.SetLevel), as errors returned to the client are routine
+	slog.Debug("tool error", "code", code, "message", message, "data", data)
 
 	return &MCPResponse{
 		JSONRPC: "2.0",
@@ -696,6 +2745,106 @@ func extractRelatedInstructions(content string) []string {
 	return related
 }
 
+// categoryCount is one entry of a category overview listing.
+type categoryCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// obexCategoryCount is a categoryCount with the category's OBEX
+// subcategories nested under it.
+type obexCategoryCount struct {
+	Name          string         `json:"name"`
+	Count         int            `json:"count"`
+	Subcategories map[string]int `json:"subcategories,omitempty"`
+}
+
+// categoryTreeNode is one top-level category group in p2kb_category_tree. A
+// group's count is its own entries (if the bare prefix is itself a category)
+// plus all its subcategories'.
+type categoryTreeNode struct {
+	Name          string          `json:"name"`
+	Count         int             `json:"count"`
+	Subcategories []categoryCount `json:"subcategories,omitempty"`
+}
+
+// buildCategoryTree groups categories by the text before their first
+// underscore, most populated groups and subcategories first.
+func buildCategoryTree(counts map[string]int) []categoryTreeNode {
+	totals := make(map[string]int)
+	children := make(map[string]map[string]int)
+	for name, count := range counts {
+		prefix, sub, nested := strings.Cut(name, "_")
+		totals[prefix] += count
+		if !nested {
+			continue
+		}
+		if children[prefix] == nil {
+			children[prefix] = make(map[string]int)
+		}
+		children[prefix][sub] = count
+	}
+
+	tree := make([]categoryTreeNode, 0, len(totals))
+	for _, group := range sortCategoryCounts(totals) {
+		node := categoryTreeNode{Name: group.Name, Count: group.Count}
+		if subs := children[group.Name]; len(subs) > 0 {
+			node.Subcategories = sortCategoryCounts(subs)
+		}
+		tree = append(tree, node)
+	}
+	return tree
+}
+
+// renderCategoryTree draws tree with box-drawing branches, one line per
+// category and no trailing whitespace.
+func renderCategoryTree(tree []categoryTreeNode) string {
+	var b strings.Builder
+	for _, node := range tree {
+		fmt.Fprintf(&b, "%s (%d)\n", node.Name, node.Count)
+		for i, sub := range node.Subcategories {
+			branch := "├──"
+			if i == len(node.Subcategories)-1 {
+				branch = "└──"
+			}
+			fmt.Fprintf(&b, "  %s %s (%d)\n", branch, sub.Name, sub.Count)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// sortCategoryCounts converts a category->count map into a stable list sorted
+// by count descending, ties broken alphabetically, so clients see the most
+// populated categories first regardless of JSON map ordering.
+func sortCategoryCounts(counts map[string]int) []categoryCount {
+	result := make([]categoryCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, categoryCount{Name: name, Count: count})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// filterCategoryCounts returns the categories holding at least minCount and
+// at most maxCount keys, keeping their order. A nil bound is not applied.
+func filterCategoryCounts(categories []categoryCount, minCount, maxCount *int) []categoryCount {
+	filtered := make([]categoryCount, 0, len(categories))
+	for _, c := range categories {
+		if (minCount != nil && c.Count < *minCount) || (maxCount != nil && c.Count > *maxCount) {
+			continue
+		}
+		filtered = append(filtered, c)
+	}
+	return filtered
+}
+
 // isNumericID checks if the query is a numeric object ID.
 func isNumericID(query string) bool {
 	query = strings.TrimSpace(query)
@@ -706,6 +2855,75 @@ func isNumericID(query string) bool {
 	return matched
 }
 
+// hasLanguage reports whether languages includes lang, ignoring case.
+func hasLanguage(languages []string, lang string) bool {
+	for _, l := range languages {
+		if strings.EqualFold(strings.TrimSpace(l), lang) {
+			return true
+		}
+	}
+	return false
+}
+
+// spin2Snippet returns a Spin2 top-level file that uses an OBEX object
+// unzipped into OBEX/{slug}: a comment naming the object, an OBJ declaration
+// and a PUB method calling its start method. Text from the metadata only
+// appears in comments, flattened to one line.
+func spin2Snippet(meta obex.ObjectMetadata) string {
+	slug := generateSlug(meta.Title)
+
+	header := spin2Comment(meta.Title)
+	if author := spin2Comment(meta.Author); author != "" {
+		header += " by " + author
+	}
+	if version := spin2Comment(meta.TechnicalDetails.Version); version != "" {
+		header += ", version " + version
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "' %s\n", header)
+	if page := spin2Comment(meta.URLs.OBEXPage); page != "" {
+		fmt.Fprintf(&b, "' OBEX: %s\n", page)
+	}
+	fmt.Fprintf(&b, "' Unzip OB%s.zip into OBEX/%s\n", meta.ObjectID, slug)
+	b.WriteString("\nCON\n  _clkfreq = 200_000_000\n")
+	fmt.Fprintf(&b, "\nOBJ\n  lib : \"OBEX/%s/%s\"\n", slug, spin2FileName(meta.Title))
+	b.WriteString("\nPUB main()\n")
+	if description := spin2Comment(meta.Functionality.DescriptionShort); description != "" {
+		fmt.Fprintf(&b, "  ' %s\n", description)
+	}
+	b.WriteString("  lib.start()  ' check the object's documentation for its start parameters\n")
+	return b.String()
+}
+
+// spin2Comment flattens s to a single line for a Spin2 ' comment.
+func spin2Comment(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// spin2FileName turns a title into an object file name: its ASCII letters and
+// digits, with each run of other characters between them replaced by an
+// underscore.
+func spin2FileName(title string) string {
+	var b strings.Builder
+	gap := false
+	for _, r := range title {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			if gap && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			gap = false
+		} else {
+			gap = true
+		}
+	}
+	if b.Len() == 0 {
+		return "object"
+	}
+	return b.String()
+}
+
 // generateSlug creates a filesystem-safe slug from a title.
 func generateSlug(title string) string {
 	// Convert to lowercase
@@ -728,3 +2946,77 @@ func generateSlug(title string) string {
 	// Trim leading/trailing hyphens
 	return strings.Trim(result.String(), "-")
 }
+
+// Page sizes for p2kb_list_keys.
+const (
+	defaultListKeysLimit = 200
+	maxListKeysLimit     = 1000
+)
+
+// handleListKeys implements p2kb_list_keys - a paginated dump of index keys
+// for tooling scripts, as a plain list or joined with file entries and categories.
+func (s *Server) handleListKeys(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Prefix   string `json:"prefix"`
+		Category string `json:"category"`
+		Format   string `json:"format"`
+		Offset   int    `json:"offset"`
+		Limit    int    `json:"limit"`
+	}
+	params.Format = "list"
+	params.Limit = defaultListKeysLimit
+
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+	}
+
+	if params.Format != "list" && params.Format != "map" {
+		return s.errorResponse(id, -32602, "Invalid format", `format must be "list" or "map"`)
+	}
+	if params.Offset < 0 {
+		return s.errorResponse(id, -32602, "Invalid offset", "offset must be 0 or greater")
+	}
+	if params.Limit <= 0 {
+		return s.errorResponse(id, -32602, "Invalid limit", "limit must be greater than 0")
+	}
+	if params.Limit > maxListKeysLimit {
+		params.Limit = maxListKeysLimit
+	}
+
+	keys, total, err := s.indexManager.ListKeys(params.Prefix, params.Category)
+	if err != nil {
+		return s.errorResponse(id, -32000, "Failed to load index", err.Error())
+	}
+
+	matched := len(keys)
+	start, end := pageBounds(matched, params.Offset, params.Limit)
+	page := keys[start:end]
+
+	var data interface{}
+	if params.Format == "map" {
+		entries := make(map[string]index.KeyInfo, len(page))
+		for _, k := range page {
+			entries[k.Key] = k
+		}
+		data = entries
+	} else {
+		names := make([]string, 0, len(page))
+		for _, k := range page {
+			names = append(names, k.Key)
+		}
+		data = names
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":           "key_list",
+		"format":         params.Format,
+		"total_count":    total,
+		"matched_count":  matched,
+		"returned_count": len(page),
+		"offset":         params.Offset,
+		"limit":          params.Limit,
+		"keys":           data,
+	})
+}
@@ -1,20 +1,36 @@
 package server
 
 import (
+	"archive/zip"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/ironsheep/p2kb-mcp/internal/cache"
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
 	"github.com/ironsheep/p2kb-mcp/internal/index"
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+	"github.com/ironsheep/p2kb-mcp/internal/testdata"
+	"gopkg.in/yaml.v3"
 )
 
 // Test helper functions
@@ -193,6 +209,19 @@ func TestErrorResponse(t *testing.T) {
 
 // Test p2kb_version
 
+func TestHandleVersionReportsLastMirror(t *testing.T) {
+	srv := New("1.2.3")
+	mock := newMockOBEXManager()
+	mock.MirrorUsed = "https://mirror.example/obex/2811.yaml"
+	srv.obexManager = mock
+
+	result := extractResultMap(t, srv.handleVersion(1))
+	obexSection, _ := result["obex"].(map[string]interface{})
+	if obexSection["last_mirror_used"] != mock.MirrorUsed {
+		t.Errorf("obex.last_mirror_used = %v, want %s", obexSection["last_mirror_used"], mock.MirrorUsed)
+	}
+}
+
 func TestHandleVersion(t *testing.T) {
 	srv := New("1.2.3")
 	resp := srv.handleVersion(1)
@@ -236,6 +265,17 @@ func TestHandleVersion(t *testing.T) {
 	if _, ok := data["obex"]; !ok {
 		t.Error("missing obex field")
 	}
+	if data["obex_concurrency_limit"] != float64(3) {
+		t.Errorf("obex_concurrency_limit = %v, want 3", data["obex_concurrency_limit"])
+	}
+	if _, ok := data["obex_pending_fetches"]; !ok {
+		t.Error("missing obex_pending_fetches field")
+	}
+	for _, field := range []string{"token_pool_size", "active_tokens", "auth_configured"} {
+		if _, ok := data[field]; !ok {
+			t.Errorf("missing %s field", field)
+		}
+	}
 }
 
 // Test p2kb_get
@@ -283,6 +323,268 @@ func TestHandleGetInvalidArgs(t *testing.T) {
 	}
 }
 
+// newServerWithLargeContent serves p2kbArchCog as a file too big to read
+// comfortably in one call, with a metadata line the cache filters out and
+// multibyte characters to land page cuts mid-rune.
+func newServerWithLargeContent(t *testing.T) (*Server, string, func()) {
+	t.Helper()
+	var body strings.Builder
+	body.WriteString("last_updated: 2024-01-01\n")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&body, "line_%03d: cog état ✓\n", i)
+	}
+	raw := body.String()
+	files := map[string]interface{}{
+		"p2kbArchCog": map[string]interface{}{"path": "arch/cog.yaml", "mtime": 1700000000},
+	}
+	srv, cleanup := newServerWithFilesAndContent(t, files, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(raw))
+	})
+	return srv, strings.TrimPrefix(raw, "last_updated: 2024-01-01\n"), cleanup
+}
+
+func TestHandleGetPagedContinuation(t *testing.T) {
+	srv, want, cleanup := newServerWithLargeContent(t)
+	defer cleanup()
+
+	args := `{"query": "p2kbArchCog", "max_bytes": 1000}`
+	var got strings.Builder
+	for pages := 1; ; pages++ {
+		if pages > 20 {
+			t.Fatal("continuation did not reach the end of the content")
+		}
+		resp := srv.handleGet(context.Background(), 1, json.RawMessage(args))
+		if resp.Error != nil {
+			t.Fatalf("page %d: unexpected error: %s", pages, resp.Error.Message)
+		}
+		result := extractResultMap(t, resp)
+		content, _ := result["content"].(string)
+		if !utf8.ValidString(content) {
+			t.Fatalf("page %d splits a UTF-8 character", pages)
+		}
+		if result["offset"] != float64(got.Len()) {
+			t.Errorf("page %d: offset = %v, want %d (pages must not overlap)", pages, result["offset"], got.Len())
+		}
+		if result["total_bytes"] != float64(len(want)) {
+			t.Errorf("page %d: total_bytes = %v, want %d (filtered size)", pages, result["total_bytes"], len(want))
+		}
+		if result["returned_bytes"] != float64(len(content)) || len(content) > 1000 {
+			t.Errorf("page %d: returned_bytes = %v for %d bytes of content", pages, result["returned_bytes"], len(content))
+		}
+		got.WriteString(content)
+
+		if result["truncated"] == false {
+			if _, ok := result["continuation_token"]; ok {
+				t.Error("final page has a continuation_token")
+			}
+			break
+		}
+		token, _ := result["continuation_token"].(string)
+		if token == "" {
+			t.Fatalf("page %d: truncated without a continuation_token", pages)
+		}
+		// The token overrides query
+		args = fmt.Sprintf(`{"query": "ignored", "continuation": %q, "max_bytes": 1000}`, token)
+	}
+
+	if got.String() != want {
+		t.Error("reassembled pages do not match the filtered content")
+	}
+}
+
+func TestApplyPageSmallerThanRune(t *testing.T) {
+	srv := New("1.0.0")
+	const content = "✓é"
+	var got []string
+	for offset := 0; offset < len(content); {
+		if len(got) > len(content) {
+			t.Fatalf("pages %q are not advancing", got)
+		}
+		result := map[string]interface{}{"content": content}
+		if resp := srv.applyPage(1, "p2kbArchCog", result, contentPage{maxBytes: 1, offset: offset}); resp != nil {
+			t.Fatalf("offset %d: %s", offset, resp.Error.Message)
+		}
+		page := result["content"].(string)
+		got = append(got, page)
+		offset += len(page)
+		if token, ok := result["continuation_token"].(string); ok {
+			if _, next, _ := decodeContinuation(token); next != offset {
+				t.Errorf("continuation offset = %d, want %d", next, offset)
+			}
+		}
+	}
+	if !reflect.DeepEqual(got, []string{"✓", "é"}) {
+		t.Errorf("pages = %q, want one whole rune each", got)
+	}
+}
+
+func TestHandleGetOffset(t *testing.T) {
+	srv, want, cleanup := newServerWithLargeContent(t)
+	defer cleanup()
+
+	resp := srv.handleGet(context.Background(), 1, json.RawMessage(`{"query": "p2kbArchCog", "offset": 100}`))
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if result["content"] != want[100:] || result["truncated"] != false {
+		t.Errorf("offset 100 without max_bytes should return the rest: truncated = %v", result["truncated"])
+	}
+
+	// Without paging parameters the result is unchanged
+	result = extractResultMap(t, srv.handleGet(context.Background(), 1, json.RawMessage(`{"query": "p2kbArchCog"}`)))
+	if result["content"] != want {
+		t.Error("unpaged content differs from the filtered content")
+	}
+	if _, ok := result["truncated"]; ok {
+		t.Error("unpaged result should not carry paging fields")
+	}
+}
+
+func TestHandleGetPagingInvalid(t *testing.T) {
+	srv, _, cleanup := newServerWithLargeContent(t)
+	defer cleanup()
+
+	tests := []struct {
+		name string
+		args string
+	}{
+		{"max_bytes too large", `{"query": "p2kbArchCog", "max_bytes": 32769}`},
+		{"negative max_bytes", `{"query": "p2kbArchCog", "max_bytes": -1}`},
+		{"negative offset", `{"query": "p2kbArchCog", "offset": -1}`},
+		{"offset past end", `{"query": "p2kbArchCog", "offset": 1000000}`},
+		{"malformed token", `{"continuation": "not base64!"}`},
+		{"token without offset", fmt.Sprintf(`{"continuation": %q}`, base64.RawURLEncoding.EncodeToString([]byte("p2kbArchCog")))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := srv.handleGet(context.Background(), 1, json.RawMessage(tt.args))
+			if resp.Error == nil || resp.Error.Code != -32602 {
+				t.Errorf("resp.Error = %+v, want -32602", resp.Error)
+			}
+		})
+	}
+}
+
+// newDiscoverServer serves a KB of PASM2 and Spin2 keys alongside the mock
+// OBEX of LED and I2C drivers.
+func newDiscoverServer(t *testing.T) (*Server, *MockOBEXManager, func()) {
+	t.Helper()
+	srv, cleanup := newServerWithMathCategories(t)
+	mock := newMockOBEXManager()
+	srv.obexManager = mock
+	return srv, mock, cleanup
+}
+
+func TestHandleDiscoverPrimarySource(t *testing.T) {
+	srv, _, cleanup := newDiscoverServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		query       string
+		wantPrimary string
+	}{
+		{"ws2812", "obex"},
+		{"pasm2", "kb"},
+		{"math", "kb"},
+		{"driver", "obex"},
+		{"zzzz", "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp := srv.handleDiscover(context.Background(), 1, json.RawMessage(fmt.Sprintf(`{"query": %q}`, tt.query)))
+			if resp.Error != nil {
+				t.Fatalf("unexpected error: %s", resp.Error.Message)
+			}
+			result := extractResultMap(t, resp)
+			if result["type"] != "discover" || result["primary_source"] != tt.wantPrimary {
+				t.Errorf("type = %v, primary_source = %v, want discover, %s", result["type"], result["primary_source"], tt.wantPrimary)
+			}
+		})
+	}
+
+	result := extractResultMap(t, srv.handleDiscover(context.Background(), 1, json.RawMessage(`{"query": "ws2812"}`)))
+	objects, _ := result["obex"].(map[string]interface{})["objects"].([]interface{})
+	if len(objects) != 1 || objects[0].(map[string]interface{})["object_id"] != "2811" {
+		t.Errorf("obex.objects = %v, want object 2811", objects)
+	}
+
+	result = extractResultMap(t, srv.handleDiscover(context.Background(), 1, json.RawMessage(`{"query": "math"}`)))
+	kb := result["kb"].(map[string]interface{})
+	if categories, _ := kb["categories"].([]interface{}); len(categories) != 2 {
+		t.Errorf("kb.categories = %v, want pasm2_math and spin2_math", kb["categories"])
+	}
+}
+
+// gatedIndex is an IndexManager whose SearchRanked first hands a value to
+// gate, so it can only finish while the OBEX search is waiting on the same
+// channel.
+type gatedIndex struct {
+	IndexManager
+	gate chan struct{}
+}
+
+func (g gatedIndex) SearchRanked(term string, limit int) []index.RankedResult {
+	select {
+	case g.gate <- struct{}{}:
+	case <-time.After(2 * time.Second):
+	}
+	return g.IndexManager.SearchRanked(term, limit)
+}
+
+func TestHandleDiscoverRunsInParallel(t *testing.T) {
+	srv, mock, cleanup := newDiscoverServer(t)
+	defer cleanup()
+
+	// Each side blocks until the other is running: a sequential search
+	// would stall the OBEX side until the test gives up
+	gate := make(chan struct{})
+	mock.SearchGate = gate
+	srv.indexManager = gatedIndex{IndexManager: srv.indexManager, gate: gate}
+
+	done := make(chan *MCPResponse, 1)
+	start := time.Now()
+	go func() { done <- srv.handleDiscover(context.Background(), 1, json.RawMessage(`{"query": "led"}`)) }()
+
+	select {
+	case resp := <-done:
+		if resp.Error != nil {
+			t.Fatalf("unexpected error: %s", resp.Error.Message)
+		}
+		if elapsed := time.Since(start); elapsed >= 2*time.Second {
+			t.Errorf("discover took %v; the KB search waited for an OBEX search that was not running", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("p2kb_discover did not search the KB and OBEX in parallel")
+	}
+}
+
+func TestHandleDiscoverOBEXUnavailable(t *testing.T) {
+	srv, mock, cleanup := newDiscoverServer(t)
+	defer cleanup()
+	mock.IndexErr = errors.New("obex index unavailable")
+
+	resp := srv.handleDiscover(context.Background(), 1, json.RawMessage(`{"query": "pasm2"}`))
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if obexResult := result["obex"].(map[string]interface{}); obexResult["error"] != "obex index unavailable" {
+		t.Errorf("obex = %v, want the index error", obexResult)
+	}
+	if result["primary_source"] != "kb" {
+		t.Errorf("primary_source = %v, want kb", result["primary_source"])
+	}
+}
+
+func TestHandleDiscoverMissingQuery(t *testing.T) {
+	srv := New("1.0.0")
+	resp := srv.handleDiscover(context.Background(), 1, json.RawMessage(`{}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("resp.Error = %+v, want -32602", resp.Error)
+	}
+}
+
 // Test p2kb_find
 
 func TestHandleFindNoParams(t *testing.T) {
@@ -358,6 +660,7 @@ func TestHandleOBEXGetMissingQuery(t *testing.T) {
 
 func TestHandleOBEXGetWithNumericID(t *testing.T) {
 	srv := New("1.0.0")
+	srv.obexManager = newMockOBEXManager()
 	params, _ := json.Marshal(map[string]interface{}{
 		"name": "p2kb_obex_get",
 		"arguments": map[string]interface{}{
@@ -373,14 +676,21 @@ func TestHandleOBEXGetWithNumericID(t *testing.T) {
 	}
 
 	resp := srv.handleRequest(req)
-	// May fail due to network, but tests the path
 	if resp.Error != nil {
-		t.Log("handleOBEXGet with ID returned error (expected if no network):", resp.Error.Message)
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if result["type"] != "obex_object" {
+		t.Fatalf("type = %v, want obex_object", result["type"])
+	}
+	if result["title"] != "WS2812 LED Driver" {
+		t.Errorf("title = %v, want WS2812 LED Driver", result["title"])
 	}
 }
 
 func TestHandleOBEXGetWithSearchTerm(t *testing.T) {
 	srv := New("1.0.0")
+	srv.obexManager = newMockOBEXManager()
 	params, _ := json.Marshal(map[string]interface{}{
 		"name": "p2kb_obex_get",
 		"arguments": map[string]interface{}{
@@ -396,9 +706,49 @@ func TestHandleOBEXGetWithSearchTerm(t *testing.T) {
 	}
 
 	resp := srv.handleRequest(req)
-	// May fail due to network, but tests the path
 	if resp.Error != nil {
-		t.Log("handleOBEXGet with search returned error (expected if no network):", resp.Error.Message)
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	// Both LED drivers match, so the caller gets suggestions
+	result := extractResultMap(t, resp)
+	if result["type"] != "suggestions" {
+		t.Fatalf("type = %v, want suggestions", result["type"])
+	}
+	if suggestions, _ := result["suggestions"].([]interface{}); len(suggestions) != 2 {
+		t.Errorf("got %d suggestions, want 2", len(suggestions))
+	}
+}
+
+func TestHandleOBEXGetWithPageURL(t *testing.T) {
+	srv := New("1.0.0")
+	srv.obexManager = newMockOBEXManager()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"https://obex.parallax.com/obex/ws2812-led-driver/", "obex_object"},
+		{"http://obex.parallax.com/obex/ws2812-led-driver", "obex_object"},
+		{"https://obex.parallax.com/obex/no-such-object/", "no_matches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			params, _ := json.Marshal(map[string]interface{}{
+				"name": "p2kb_obex_get",
+				"arguments": map[string]interface{}{
+					"query": tt.query,
+				},
+			})
+			resp := srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: params})
+			if resp.Error != nil {
+				t.Fatalf("unexpected error: %s", resp.Error.Message)
+			}
+			result := extractResultMap(t, resp)
+			if result["type"] != tt.want {
+				t.Errorf("type = %v, want %s", result["type"], tt.want)
+			}
+		})
 	}
 }
 
@@ -406,6 +756,7 @@ func TestHandleOBEXGetWithSearchTerm(t *testing.T) {
 
 func TestHandleOBEXFindNoParams(t *testing.T) {
 	srv := New("1.0.0")
+	srv.obexManager = newMockOBEXManager()
 	params, _ := json.Marshal(map[string]interface{}{
 		"name":      "p2kb_obex_find",
 		"arguments": map[string]interface{}{},
@@ -419,14 +770,33 @@ func TestHandleOBEXFindNoParams(t *testing.T) {
 	}
 
 	resp := srv.handleRequest(req)
-	// Should return overview with categories
 	if resp.Error != nil {
-		t.Log("handleOBEXFind returned error (expected if no network):", resp.Error.Message)
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	// Should return overview with categories
+	result := extractResultMap(t, resp)
+	if result["type"] != "overview" {
+		t.Fatalf("type = %v, want overview", result["type"])
+	}
+	if result["total_objects"] != float64(3) {
+		t.Errorf("total_objects = %v, want 3", result["total_objects"])
+	}
+	categories, _ := result["categories"].([]interface{})
+	if len(categories) != 1 {
+		t.Fatalf("got %d categories, want 1", len(categories))
+	}
+	drivers, _ := categories[0].(map[string]interface{})
+	if drivers["name"] != "drivers" || drivers["count"] != float64(3) {
+		t.Errorf("category = %v, want drivers with 3 objects", drivers)
+	}
+	if authors, _ := result["top_authors"].([]interface{}); len(authors) != 2 {
+		t.Errorf("got %d top authors, want 2", len(authors))
 	}
 }
 
 func TestHandleOBEXFindWithCategory(t *testing.T) {
 	srv := New("1.0.0")
+	srv.obexManager = newMockOBEXManager()
 	params, _ := json.Marshal(map[string]interface{}{
 		"name": "p2kb_obex_find",
 		"arguments": map[string]interface{}{
@@ -443,12 +813,60 @@ func TestHandleOBEXFindWithCategory(t *testing.T) {
 
 	resp := srv.handleRequest(req)
 	if resp.Error != nil {
-		t.Log("handleOBEXFind with category returned error (expected if no network):", resp.Error.Message)
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if result["count"] != float64(3) {
+		t.Errorf("count = %v, want 3", result["count"])
+	}
+}
+
+func TestHandleOBEXFindPagination(t *testing.T) {
+	srv := New("1.0.0")
+	srv.obexManager = newMockOBEXManager()
+
+	var got []string
+	for offset := 0; offset < 4; offset += 2 {
+		raw, _ := json.Marshal(map[string]interface{}{"category": "drivers", "offset": offset, "limit": 2})
+		result := extractResultMap(t, srv.handleOBEXFind(context.Background(), 1, raw))
+		if result["total_count"] != float64(3) || result["has_more"] != (offset == 0) {
+			t.Errorf("offset %d: total_count = %v, has_more = %v", offset, result["total_count"], result["has_more"])
+		}
+		objects, _ := result["objects"].([]interface{})
+		for _, o := range objects {
+			got = append(got, o.(map[string]interface{})["object_id"].(string))
+		}
+	}
+	if len(got) != 3 || got[0] == got[1] || got[1] == got[2] || got[0] == got[2] {
+		t.Errorf("pages = %v, want the 3 drivers once each", got)
+	}
+
+	resp := srv.handleOBEXFind(context.Background(), 2, json.RawMessage(`{"category": "drivers", "offset": -1}`))
+	if resp.Error == nil || resp.Error.Message != "Invalid offset" {
+		t.Errorf("negative offset error = %v, want Invalid offset", resp.Error)
+	}
+}
+
+func TestHandleOBEXFindTermPageAfterFilters(t *testing.T) {
+	srv := New("1.0.0")
+	srv.obexManager = newMockOBEXManager()
+
+	// The only i2c driver is the last match; a search capped at the page
+	// size would stop before reaching it
+	raw, _ := json.Marshal(map[string]interface{}{"term": "Driver", "category": "drivers", "subcategory": "i2c", "limit": 1})
+	result := extractResultMap(t, srv.handleOBEXFind(context.Background(), 1, raw))
+	objects, _ := result["objects"].([]interface{})
+	if len(objects) != 1 || objects[0].(map[string]interface{})["object_id"] != "2813" {
+		t.Fatalf("objects = %v, want 2813 alone", objects)
+	}
+	if result["total_count"] != float64(1) || result["has_more"] != false {
+		t.Errorf("total_count = %v, has_more = %v; want 1 and false", result["total_count"], result["has_more"])
 	}
 }
 
 func TestHandleOBEXFindWithAuthor(t *testing.T) {
 	srv := New("1.0.0")
+	srv.obexManager = newMockOBEXManager()
 	params, _ := json.Marshal(map[string]interface{}{
 		"name": "p2kb_obex_find",
 		"arguments": map[string]interface{}{
@@ -465,14 +883,75 @@ func TestHandleOBEXFindWithAuthor(t *testing.T) {
 
 	resp := srv.handleRequest(req)
 	if resp.Error != nil {
-		t.Log("handleOBEXFind with author returned error (expected if no network):", resp.Error.Message)
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if result["type"] != "objects" {
+		t.Fatalf("type = %v, want objects", result["type"])
+	}
+	if result["count"] != float64(2) {
+		t.Errorf("count = %v, want 2 objects by Jon", result["count"])
+	}
+}
+
+func TestHandleOBEXFindAuthorFuzzy(t *testing.T) {
+	mock := newMockOBEXManager()
+	mock.Objects[1].Author = "Jon McPhalen (ElectricAye)"
+	mock.Objects = append(mock.Objects, obex.SearchResult{ObjectID: "2814", Title: "Servo", Author: "Jon_McPhalen", Category: "motors"})
+	srv := New("1.0.0")
+	srv.obexManager = mock
+
+	args, _ := json.Marshal(map[string]interface{}{"author": "McPhalen"})
+	result := extractResultMap(t, srv.handleOBEXFind(context.Background(), 1, args))
+	objects, _ := result["objects"].([]interface{})
+	if len(objects) != 3 {
+		t.Fatalf("objects = %v, want the three spellings of Jon McPhalen", objects)
+	}
+	for _, o := range objects {
+		obj := o.(map[string]interface{})
+		if obj["match_score"] != float64(1) {
+			t.Errorf("object %v match_score = %v, want 1", obj["object_id"], obj["match_score"])
+		}
+		if obj["matched_author_name"] != obj["author"] {
+			t.Errorf("object %v matched_author_name = %v, want %v", obj["object_id"], obj["matched_author_name"], obj["author"])
+		}
+	}
+	if !mock.Called("BrowseByAuthor(McPhalen)") {
+		t.Error("author filter did not use BrowseByAuthor")
+	}
+}
+
+func TestHandleOBEXFindAuthors(t *testing.T) {
+	mock := newMockOBEXManager()
+	mock.Objects[1].Author = "Jon McPhalen & Chip Gracey"
+	srv := New("1.0.0")
+	srv.obexManager = mock
+
+	args, _ := json.Marshal(map[string]interface{}{"authors": []string{"Jon McPhalen", "Chip Gracey", "Nobody Here"}})
+	result := extractResultMap(t, srv.handleOBEXFind(context.Background(), 1, args))
+	objects, _ := result["objects"].([]interface{})
+	if len(objects) != 3 {
+		t.Fatalf("objects = %v, want 2811, 2812 and 2813 once each", objects)
+	}
+	want := map[string]interface{}{"Jon McPhalen": float64(2), "Chip Gracey": float64(2), "Nobody Here": float64(0)}
+	if !reflect.DeepEqual(result["authors_matched"], want) {
+		t.Errorf("authors_matched = %v, want %v", result["authors_matched"], want)
+	}
+	if _, ok := result["author"]; ok {
+		t.Error("author should not be set for an authors search")
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"author": "McPhalen", "authors": []string{"Gracey"}})
+	if resp := srv.handleOBEXFind(context.Background(), 1, args); resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("expected -32602 for author with authors, got %+v", resp.Error)
 	}
 }
 
 // Test p2kb_refresh
 
 func TestHandleRefresh(t *testing.T) {
-	srv := New("1.0.0")
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
 	params, _ := json.Marshal(map[string]interface{}{
 		"name":      "p2kb_refresh",
 		"arguments": map[string]interface{}{},
@@ -486,14 +965,23 @@ func TestHandleRefresh(t *testing.T) {
 	}
 
 	resp := srv.handleRequest(req)
-	// May fail due to network
 	if resp.Error != nil {
-		t.Log("handleRefresh returned error (expected if no network):", resp.Error.Message)
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if result["refreshed"] != true {
+		t.Errorf("refreshed = %v, want true", result["refreshed"])
+	}
+	if _, ok := result["obex_refreshed"]; ok {
+		t.Error("OBEX should not be refreshed without include_obex")
 	}
 }
 
 func TestHandleRefreshWithOBEX(t *testing.T) {
-	srv := New("1.0.0")
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	mock := newMockOBEXManager()
+	srv.obexManager = mock
 	params, _ := json.Marshal(map[string]interface{}{
 		"name": "p2kb_refresh",
 		"arguments": map[string]interface{}{
@@ -510,7 +998,29 @@ func TestHandleRefreshWithOBEX(t *testing.T) {
 
 	resp := srv.handleRequest(req)
 	if resp.Error != nil {
-		t.Log("handleRefresh with OBEX returned error (expected if no network):", resp.Error.Message)
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if result["obex_refreshed"] != true {
+		t.Errorf("obex_refreshed = %v, want true", result["obex_refreshed"])
+	}
+	if !mock.Called("Refresh") {
+		t.Error("OBEX manager was not refreshed")
+	}
+	if result["retained_objects"] != float64(len(mock.Details)) || result["evicted_objects"] != float64(0) {
+		t.Errorf("retained_objects = %v, evicted_objects = %v; want %d and 0",
+			result["retained_objects"], result["evicted_objects"], len(mock.Details))
+	}
+
+	// A failed OBEX refresh is reported alongside the index refresh
+	mock.RefreshErr = errors.New("obex unreachable")
+	resp = srv.handleRequest(req)
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result = extractResultMap(t, resp)
+	if result["obex_error"] != "obex unreachable" {
+		t.Errorf("obex_error = %v, want obex unreachable", result["obex_error"])
 	}
 }
 
@@ -565,10 +1075,8 @@ func TestRemovedToolsReturnError(t *testing.T) {
 		"p2kb_search",
 		"p2kb_browse",
 		"p2kb_categories",
-		"p2kb_batch_get",
 		"p2kb_info",
 		"p2kb_stats",
-		"p2kb_related",
 		"p2kb_help",
 		"p2kb_cached",
 		"p2kb_index_status",
@@ -675,6 +1183,15 @@ func TestHandleOBEXDownloadInvalidArgs(t *testing.T) {
 
 func TestHandleOBEXDownloadWithObjectID(t *testing.T) {
 	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	mock.DownloadResult = &obex.DownloadResult{
+		ObjectID:       "2811",
+		Title:          "WS2812 LED Driver",
+		ExtractionPath: "OBEX/ws2812-led-driver",
+		Files:          []string{"jm_ws2812.spin2", "README.txt"},
+		TotalSize:      2048,
+	}
+	srv.obexManager = mock
 	params, _ := json.Marshal(map[string]interface{}{
 		"name": "p2kb_obex_download",
 		"arguments": map[string]interface{}{
@@ -690,18 +1207,26 @@ func TestHandleOBEXDownloadWithObjectID(t *testing.T) {
 	}
 
 	resp := srv.handleRequest(req)
-	// Will fail due to network, but tests the path
 	if resp.Error != nil {
-		t.Log("handleOBEXDownload returned error (expected if no network):", resp.Error.Message)
-		// Verify it's a -32000 error (operation failed) not -32602 (invalid params)
-		if resp.Error.Code == -32602 {
-			t.Error("should not be invalid params error")
-		}
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if result["type"] != "download_complete" {
+		t.Fatalf("type = %v, want download_complete", result["type"])
+	}
+	if result["file_count"] != float64(2) {
+		t.Errorf("file_count = %v, want 2", result["file_count"])
+	}
+	if !mock.Called("DownloadAndExtract(2811, )") {
+		t.Errorf("calls = %v, want DownloadAndExtract with the default directory", mock.Calls)
 	}
 }
 
 func TestHandleOBEXDownloadWithTargetDir(t *testing.T) {
 	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	mock.DownloadResult = &obex.DownloadResult{ObjectID: "2811", ExtractionPath: "custom/output/path"}
+	srv.obexManager = mock
 	params, _ := json.Marshal(map[string]interface{}{
 		"name": "p2kb_obex_download",
 		"arguments": map[string]interface{}{
@@ -718,14 +1243,19 @@ func TestHandleOBEXDownloadWithTargetDir(t *testing.T) {
 	}
 
 	resp := srv.handleRequest(req)
-	// Will fail due to network, but tests the path
 	if resp.Error != nil {
-		t.Log("handleOBEXDownload with target_dir returned error (expected if no network):", resp.Error.Message)
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	if !mock.Called("DownloadAndExtract(2811, custom/output/path)") {
+		t.Errorf("calls = %v, want target_dir passed through", mock.Calls)
 	}
 }
 
 func TestHandleOBEXDownloadWithPathTraversal(t *testing.T) {
 	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	mock.DownloadErr = errors.New("invalid target directory: path traversal not allowed")
+	srv.obexManager = mock
 	params, _ := json.Marshal(map[string]interface{}{
 		"name": "p2kb_obex_download",
 		"arguments": map[string]interface{}{
@@ -742,14 +1272,20 @@ func TestHandleOBEXDownloadWithPathTraversal(t *testing.T) {
 	}
 
 	resp := srv.handleRequest(req)
-	// Should fail due to path traversal attempt (or network, but the path check comes later)
-	if resp.Error != nil {
-		t.Log("handleOBEXDownload with path traversal returned error:", resp.Error.Message)
+	// The manager rejects the path; the handler reports it as a failed download
+	if resp.Error == nil {
+		t.Fatal("expected error for path traversal")
+	}
+	if resp.Error.Code != -32000 {
+		t.Errorf("Error.Code = %d, want -32000", resp.Error.Code)
 	}
 }
 
 func TestHandleOBEXDownloadWithOBPrefix(t *testing.T) {
 	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	mock.DownloadResult = &obex.DownloadResult{ObjectID: "2811"}
+	srv.obexManager = mock
 	params, _ := json.Marshal(map[string]interface{}{
 		"name": "p2kb_obex_download",
 		"arguments": map[string]interface{}{
@@ -765,29 +1301,28 @@ func TestHandleOBEXDownloadWithOBPrefix(t *testing.T) {
 	}
 
 	resp := srv.handleRequest(req)
-	// Will fail due to network, but tests that OB prefix is handled
 	if resp.Error != nil {
-		t.Log("handleOBEXDownload with OB prefix returned error (expected if no network):", resp.Error.Message)
-		// Should NOT be a "missing parameter" error
-		if resp.Error.Code == -32602 {
-			t.Error("OB prefix should be accepted")
-		}
+		t.Fatalf("OB prefix should be accepted: %s", resp.Error.Message)
+	}
+	if !mock.Called("DownloadAndExtract(OB2811, )") {
+		t.Errorf("calls = %v, want the object ID passed through", mock.Calls)
 	}
 }
 
-// makeMinimalGzippedIndex builds a minimal valid p2kb index gzip payload for tests.
+// makeMinimalGzippedIndex builds a minimal valid p2kb index gzip payload for
+// tests: one entry in one category, the least the index validation accepts.
 func makeMinimalGzippedIndex(t *testing.T) []byte {
 	t.Helper()
 	idx := map[string]interface{}{
 		"system": map[string]interface{}{
 			"version":           "test-1.0",
 			"generated":         "2024-01-01T00:00:00Z",
-			"total_entries":     0,
-			"total_categories":  0,
+			"total_entries":     1,
+			"total_categories":  1,
 			"total_aliases":     0,
 		},
-		"categories": map[string]interface{}{},
-		"files":      map[string]interface{}{},
+		"categories": map[string]interface{}{"pasm2_misc": []string{"p2kbPasm2Nop"}},
+		"files":      map[string]interface{}{"p2kbPasm2Nop": map[string]interface{}{"path": "nop.yaml", "mtime": 1700000000}},
 		"aliases":    map[string]interface{}{},
 	}
 	raw, err := json.Marshal(idx)
@@ -950,10 +1485,26 @@ func sha256HexT(s string) string {
 // is isolated in a temp dir. No live network is touched.
 func newServerWithFilesAndContent(t *testing.T, files map[string]interface{}, contentHandler http.HandlerFunc) (*Server, func()) {
 	t.Helper()
+	return newServerWithIndex(t, files, map[string]interface{}{}, contentHandler)
+}
+
+// newServerWithIndex is newServerWithFilesAndContent with categories as well.
+// Without categories every file goes in one, "uncategorized", so the index
+// passes validation.
+func newServerWithIndex(t *testing.T, files, categories map[string]interface{}, contentHandler http.HandlerFunc) (*Server, func()) {
+	t.Helper()
 
+	if len(categories) == 0 {
+		keys := make([]string, 0, len(files))
+		for key := range files {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		categories = map[string]interface{}{"uncategorized": keys}
+	}
 	idx := map[string]interface{}{
-		"system":     map[string]interface{}{"version": "test-1.0", "generated": "2024-01-01T00:00:00Z"},
-		"categories": map[string]interface{}{},
+		"system":     map[string]interface{}{"version": "test-1.0", "generated": "2024-01-01T00:00:00Z", "total_entries": len(files)},
+		"categories": categories,
 		"files":      files,
 		"aliases":    map[string]interface{}{},
 	}
@@ -1017,7 +1568,7 @@ func TestGetContentVerificationFailureMapsTo32001(t *testing.T) {
 	})
 	defer cleanup()
 
-	resp := srv.getContentWithRelated(1, "p2kbVerifyMe", "")
+	resp := srv.getContentWithRelated(context.Background(), 1, "p2kbVerifyMe", "", contentPage{})
 	if resp.Error == nil {
 		t.Fatal("expected an error for sha256 mismatch, got success")
 	}
@@ -1053,7 +1604,7 @@ func TestGetContentNetworkErrorMapsTo32000(t *testing.T) {
 	})
 	defer cleanup()
 
-	resp := srv.getContentWithRelated(1, "p2kbPlainFail", "")
+	resp := srv.getContentWithRelated(context.Background(), 1, "p2kbPlainFail", "", contentPage{})
 	if resp.Error == nil {
 		t.Fatal("expected an error for HTTP 500, got success")
 	}
@@ -1120,3 +1671,1913 @@ func extractResultMap(t *testing.T, resp *MCPResponse) map[string]interface{} {
 	}
 	return resultMap
 }
+
+// TestHandlePinResolvesAndWarms verifies p2kb_pin stores canonical keys,
+// reports unknown ones, and pre-warms pinned content into memory.
+func TestHandlePinResolvesAndWarms(t *testing.T) {
+	files := map[string]interface{}{
+		"p2kbPasm2Mov": map[string]interface{}{"path": "pasm2/mov.yaml", "mtime": 1700000000},
+	}
+	srv, cleanup := newServerWithFilesAndContent(t, files, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "mnemonic: MOV\n")
+	})
+	defer cleanup()
+
+	args, _ := json.Marshal(map[string]interface{}{"keys": []string{"p2kbpasm2mov", "p2kbNoSuchKey"}})
+	resp := srv.handlePin(context.Background(), 1, args)
+	if resp.Error != nil {
+		t.Fatalf("handlePin returned error: %v", resp.Error)
+	}
+
+	result := extractResultMap(t, resp)
+	if result["warmed"] != float64(1) {
+		t.Errorf("warmed = %v, want 1", result["warmed"])
+	}
+	unknown, _ := result["unknown_keys"].([]interface{})
+	if len(unknown) != 1 || unknown[0] != "p2kbNoSuchKey" {
+		t.Errorf("unknown_keys = %v, want [p2kbNoSuchKey]", result["unknown_keys"])
+	}
+	if !srv.cacheManager.IsPinned("p2kbPasm2Mov") {
+		t.Error("canonical key p2kbPasm2Mov should be pinned")
+	}
+
+	// Unpinning the canonical key empties the set again.
+	args, _ = json.Marshal(map[string]interface{}{"keys": []string{"p2kbPasm2Mov"}})
+	resp = srv.handleUnpin(1, args)
+	if resp.Error != nil {
+		t.Fatalf("handleUnpin returned error: %v", resp.Error)
+	}
+	if got := extractResultMap(t, resp)["removed"]; got != float64(1) {
+		t.Errorf("removed = %v, want 1", got)
+	}
+}
+
+func TestHandlePinMissingKeys(t *testing.T) {
+	srv := New("1.0.0")
+	resp := srv.handlePin(context.Background(), 1, json.RawMessage(`{}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("expected -32602 for missing keys, got %v", resp.Error)
+	}
+}
+
+func TestHandleSuggestUnionsRelatedInstructions(t *testing.T) {
+	files := map[string]interface{}{
+		"p2kbPasm2Mov":    map[string]interface{}{"path": "pasm2/mov.yaml", "mtime": 1700000000},
+		"p2kbPasm2Add":    map[string]interface{}{"path": "pasm2/add.yaml", "mtime": 1700000000},
+		"p2kbPasm2Sub":    map[string]interface{}{"path": "pasm2/sub.yaml", "mtime": 1700000000},
+		"p2kbPasm2Loc":    map[string]interface{}{"path": "pasm2/loc.yaml", "mtime": 1700000000},
+		"p2kbPasm2Rdlong": map[string]interface{}{"path": "pasm2/rdlong.yaml", "mtime": 1700000000},
+	}
+	srv, cleanup := newServerWithFilesAndContent(t, files, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pasm2/mov.yaml":
+			_, _ = w.Write(testdata.MustGetFixture("p2kbPasm2Mov.yaml"))
+		case "/pasm2/add.yaml":
+			_, _ = w.Write(testdata.MustGetFixture("p2kbPasm2Add.yaml"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer cleanup()
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"context_keys": []string{"p2kbPasm2Mov", "p2kbPasm2Add", "p2kbNoSuchKey"},
+	})
+	resp := srv.handleSuggest(context.Background(), 1, args)
+	if resp.Error != nil {
+		t.Fatalf("handleSuggest returned error: %v", resp.Error)
+	}
+
+	result := extractResultMap(t, resp)
+	if result["type"] != "related_suggestions" {
+		t.Errorf("type = %v, want related_suggestions", result["type"])
+	}
+
+	raw, _ := result["suggestions"].([]interface{})
+	var keys []string
+	reasons := make(map[string]string)
+	for _, item := range raw {
+		sug := item.(map[string]interface{})
+		key := sug["key"].(string)
+		keys = append(keys, key)
+		reasons[key] = sug["reason"].(string)
+	}
+
+	// Mov and Add are already seen; Loc is referenced by both so ranks first;
+	// the unknown related key is dropped.
+	want := []string{"p2kbPasm2Loc", "p2kbPasm2Rdlong", "p2kbPasm2Sub"}
+	if len(keys) != len(want) {
+		t.Fatalf("suggestions = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("suggestions[%d] = %q, want %q", i, keys[i], want[i])
+		}
+	}
+	if reasons["p2kbPasm2Loc"] != "referenced by p2kbPasm2Mov, p2kbPasm2Add" {
+		t.Errorf("Loc reason = %q", reasons["p2kbPasm2Loc"])
+	}
+	if reasons["p2kbPasm2Sub"] != "referenced by p2kbPasm2Add" {
+		t.Errorf("Sub reason = %q", reasons["p2kbPasm2Sub"])
+	}
+
+	unknown, _ := result["unknown_keys"].([]interface{})
+	if len(unknown) != 1 || unknown[0] != "p2kbNoSuchKey" {
+		t.Errorf("unknown_keys = %v, want [p2kbNoSuchKey]", result["unknown_keys"])
+	}
+}
+
+func TestHandleSuggestMissingContextKeys(t *testing.T) {
+	srv := New("1.0.0")
+	resp := srv.handleSuggest(context.Background(), 1, json.RawMessage(`{}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("expected -32602 for missing context_keys, got %v", resp.Error)
+	}
+}
+
+func TestHandleMemoryPressure(t *testing.T) {
+	files := map[string]interface{}{
+		"p2kbPasm2Mov": map[string]interface{}{"path": "pasm2/mov.yaml", "mtime": 1700000000},
+		"p2kbPasm2Add": map[string]interface{}{"path": "pasm2/add.yaml", "mtime": 1700000000},
+	}
+	srv, cleanup := newServerWithFilesAndContent(t, files, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "mnemonic: X\n")
+	})
+	defer cleanup()
+
+	for _, key := range []string{"p2kbPasm2Mov", "p2kbPasm2Add"} {
+		if _, err := srv.getContent(key); err != nil {
+			t.Fatalf("getContent(%s) failed: %v", key, err)
+		}
+	}
+
+	resp := srv.handleMemoryPressure(1, json.RawMessage(`{}`))
+	if resp.Error != nil {
+		t.Fatalf("handleMemoryPressure returned error: %v", resp.Error)
+	}
+	result := extractResultMap(t, resp)
+	if result["evicted_count"] != float64(2) {
+		t.Errorf("evicted_count = %v, want 2", result["evicted_count"])
+	}
+	if result["remaining_memory_entries"] != float64(0) {
+		t.Errorf("remaining_memory_entries = %v, want 0", result["remaining_memory_entries"])
+	}
+	if result["remaining_disk_entries"] != float64(2) {
+		t.Errorf("remaining_disk_entries = %v, want 2", result["remaining_disk_entries"])
+	}
+	if freed, _ := result["memory_freed_estimate_bytes"].(float64); freed <= 0 {
+		t.Errorf("memory_freed_estimate_bytes = %v, want > 0", result["memory_freed_estimate_bytes"])
+	}
+
+	resp = srv.handleMemoryPressure(1, json.RawMessage(`{"target_entries": -1}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("expected -32602 for negative target_entries, got %v", resp.Error)
+	}
+}
+
+func TestSortCategoryCounts(t *testing.T) {
+	got := sortCategoryCounts(map[string]int{
+		"pasm2_math":   45,
+		"spin2_pin":    12,
+		"pasm2_branch": 45,
+		"arch":         3,
+	})
+
+	want := []categoryCount{
+		{"pasm2_branch", 45},
+		{"pasm2_math", 45},
+		{"spin2_pin", 12},
+		{"arch", 3},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if empty := sortCategoryCounts(nil); empty == nil || len(empty) != 0 {
+		t.Errorf("sortCategoryCounts(nil) = %v, want empty non-nil slice", empty)
+	}
+}
+
+// seedOBEXObject writes a one-object OBEX index and the object's YAML into
+// the disk cache so GetObject resolves without network access.
+func seedOBEXObject(t *testing.T, objectID, fixture string) {
+	t.Helper()
+	seedOBEXObjects(t, map[string][]byte{objectID: testdata.MustGetFixture(fixture)})
+}
+
+// seedOBEXObjects is seedOBEXObject for several objects, given as ID -> YAML.
+func seedOBEXObjects(t *testing.T, objects map[string][]byte) {
+	t.Helper()
+	obexDir := filepath.Join(os.Getenv("P2KB_CACHE_DIR"), "obex")
+	if err := os.MkdirAll(filepath.Join(obexDir, "objects"), 0755); err != nil {
+		t.Fatalf("mkdir obex objects: %v", err)
+	}
+
+	ids := make([]string, 0, len(objects))
+	for id, data := range objects {
+		ids = append(ids, id)
+		if err := os.WriteFile(filepath.Join(obexDir, "objects", id+".yaml"), data, 0644); err != nil {
+			t.Fatalf("write obex object: %v", err)
+		}
+	}
+	sort.Strings(ids)
+	indexData, _ := json.Marshal(ids)
+	if err := os.WriteFile(filepath.Join(obexDir, "index.json"), indexData, 0644); err != nil {
+		t.Fatalf("write obex index: %v", err)
+	}
+}
+
+func TestGetOBEXObjectValidationWarnings(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObject(t, "2811", "obexObjectLanguagesScalar.yaml")
+
+	// Lenient by default: the malformed object is served without warnings
+	result := extractResultMap(t, srv.getOBEXObject(1, "2811", true, false))
+	if result["type"] != "obex_object" {
+		t.Fatalf("type = %v, want obex_object", result["type"])
+	}
+	if _, ok := result["validation_warnings"]; ok {
+		t.Error("validation_warnings present without P2KB_STRICT_VALIDATION")
+	}
+
+	t.Setenv("P2KB_STRICT_VALIDATION", "true")
+	result = extractResultMap(t, srv.getOBEXObject(1, "2811", true, false))
+	warnings, ok := result["validation_warnings"].([]interface{})
+	if !ok || len(warnings) == 0 {
+		t.Fatalf("validation_warnings = %v, want a non-empty list in strict mode", result["validation_warnings"])
+	}
+
+	// p2kb_settings turns it off again without a restart
+	if resp := callSettings(srv, map[string]interface{}{"key": "P2KB_STRICT_VALIDATION", "value": "false"}); resp.Error != nil {
+		t.Fatalf("p2kb_settings failed: %+v", resp.Error)
+	}
+	result = extractResultMap(t, srv.getOBEXObject(1, "2811", true, false))
+	if _, ok := result["validation_warnings"]; ok {
+		t.Error("validation_warnings present after p2kb_settings set P2KB_STRICT_VALIDATION=false")
+	}
+}
+
+// spin2Balanced reports whether the code outside comments and strings of a
+// Spin2 snippet has balanced parentheses, brackets and braces, and no
+// unterminated string.
+func spin2Balanced(code string) bool {
+	for _, line := range strings.Split(code, "\n") {
+		depth := map[rune]int{}
+		inString := false
+		for _, r := range line {
+			if inString {
+				inString = r != '"'
+				continue
+			}
+			if r == '\'' {
+				break
+			}
+			switch r {
+			case '"':
+				inString = true
+			case '(', '[', '{':
+				depth[r]++
+			case ')':
+				depth['(']--
+			case ']':
+				depth['[']--
+			case '}':
+				depth['{']--
+			}
+			for _, d := range depth {
+				if d < 0 {
+					return false
+				}
+			}
+		}
+		if inString || depth['('] != 0 || depth['['] != 0 || depth['{'] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSpin2Snippet(t *testing.T) {
+	var obj obex.OBEXObject
+	if err := yaml.Unmarshal(testdata.MustGetFixture("obexObjectComplete.yaml"), &obj); err != nil {
+		t.Fatal(err)
+	}
+	snippet := spin2Snippet(obj.ObjectMetadata)
+
+	for _, want := range []string{
+		"' VL53L1X Time-of-Flight Sensor by Test Author, version 1.2\n",
+		"' OBEX: https://obex.parallax.com/obex/vl53l1x/\n",
+		"\nOBJ\n  lib : \"OBEX/vl53l1x-time-of-flight-sensor/VL53L1X_Time_of_Flight_Sensor\"\n",
+		"\nPUB main()\n  ' Driver for the VL53L1X time-of-flight distance sensor\n  lib.start()",
+		"\nCON\n",
+	} {
+		if !strings.Contains(snippet, want) {
+			t.Errorf("snippet missing %q:\n%s", want, snippet)
+		}
+	}
+	if !spin2Balanced(snippet) {
+		t.Errorf("snippet is not balanced:\n%s", snippet)
+	}
+
+	// Metadata text cannot escape its comment or the OBJ string
+	obj.ObjectMetadata.Title = `Bad "Title" (v2`
+	obj.ObjectMetadata.Functionality.DescriptionShort = "line one\nlib.stop()"
+	snippet = spin2Snippet(obj.ObjectMetadata)
+	if !spin2Balanced(snippet) || strings.Contains(snippet, "\nlib.stop()") {
+		t.Errorf("snippet from awkward metadata is not valid:\n%s", snippet)
+	}
+	if !strings.Contains(snippet, `lib : "OBEX/bad-title-v2/Bad_Title_v2"`) {
+		t.Errorf("OBJ declaration not cleaned:\n%s", snippet)
+	}
+}
+
+func TestOBEXGetFullDescription(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+
+	var notModified atomic.Int32
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"page-v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"page-v1"`)
+		_, _ = w.Write(testdata.MustGetFixture("obexPage.html"))
+	}))
+	defer page.Close()
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	withPage := func(objectID, url string) []byte {
+		yaml := bytes.Replace(testdata.MustGetFixture("obexObjectPageURL.yaml"),
+			[]byte("http://obex.parallax.com/obex/park-transformation"), []byte(url), 1)
+		return bytes.Replace(yaml, []byte(`"2905"`), []byte(`"`+objectID+`"`), 1)
+	}
+	seedOBEXObjects(t, map[string][]byte{"2905": withPage("2905", page.URL), "2906": withPage("2906", gone.URL)})
+
+	result := extractResultMap(t, srv.handleOBEXGet(context.Background(), 1, json.RawMessage(`{"query": "2905", "fetch_full_description": true}`)))
+	if result["full_description_available"] != true {
+		t.Fatalf("full_description_available = %v (%v), want true", result["full_description_available"], result["full_description_error"])
+	}
+	if text, _ := result["full_description_text"].(string); !strings.HasPrefix(text, "Smart-pin driver for WS2812 RGB LED strips.\n") {
+		t.Errorf("full_description_text = %q", text)
+	}
+	if html, _ := result["full_description_html"].(string); !strings.Contains(html, "<strong>WS2812</strong>") {
+		t.Errorf("full_description_html = %q", html)
+	}
+
+	// The page is kept by ETag, so fetching it again is answered with a 304
+	result = extractResultMap(t, srv.handleOBEXGet(context.Background(), 1, json.RawMessage(`{"query": "2905", "fetch_full_description": true}`)))
+	if text, _ := result["full_description_text"].(string); notModified.Load() != 1 || !strings.HasPrefix(text, "Smart-pin driver") {
+		t.Errorf("second fetch: %d 304s, full_description_text = %q; want 1 and the cached page", notModified.Load(), text)
+	}
+
+	// Off by default
+	result = extractResultMap(t, srv.handleOBEXGet(context.Background(), 1, json.RawMessage(`{"query": "2905"}`)))
+	if _, ok := result["full_description_available"]; ok {
+		t.Error("page fetched without fetch_full_description")
+	}
+
+	// An unreachable page still serves the object
+	result = extractResultMap(t, srv.handleOBEXGet(context.Background(), 1, json.RawMessage(`{"query": "2906", "fetch_full_description": true}`)))
+	if result["type"] != "obex_object" || result["full_description_available"] != false {
+		t.Errorf("unreachable page: type = %v, full_description_available = %v", result["type"], result["full_description_available"])
+	}
+	if _, ok := result["full_description_text"]; ok {
+		t.Error("full_description_text set for an unreachable page")
+	}
+}
+
+func TestOBEXGetCodeSnippet(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObjects(t, map[string][]byte{
+		"2811": testdata.MustGetFixture("obexObjectValid.yaml"),
+		"2812": testdata.MustGetFixture("obexObjectP1.yaml"),
+	})
+
+	result := extractResultMap(t, srv.handleOBEXGet(context.Background(), 1, json.RawMessage(`{"query": "2811"}`)))
+	snippet, _ := result["code_snippet"].(string)
+	if !strings.Contains(snippet, `lib : "OBEX/ws2812-led-driver/WS2812_LED_Driver"`) {
+		t.Errorf("code_snippet = %q, want the OBJ declaration for 2811", snippet)
+	}
+
+	result = extractResultMap(t, srv.handleOBEXGet(context.Background(), 1, json.RawMessage(`{"query": "2811", "include_snippet": false}`)))
+	if _, ok := result["code_snippet"]; ok {
+		t.Error("code_snippet present with include_snippet false")
+	}
+
+	// A Spin (P1) object gets no Spin2 snippet
+	result = extractResultMap(t, srv.handleOBEXGet(context.Background(), 1, json.RawMessage(`{"query": "2812"}`)))
+	if _, ok := result["code_snippet"]; ok {
+		t.Error("code_snippet present for an object without SPIN2")
+	}
+}
+
+func TestOBEXLocalObjectsMarkedLocal(t *testing.T) {
+	localDir := t.TempDir()
+	t.Setenv("P2KB_OBEX_LOCAL_DIR", localDir)
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObjects(t, map[string][]byte{
+		"2811": testdata.MustGetFixture("obexObjectValid.yaml"),
+		"2812": testdata.MustGetFixture("obexObjectP1.yaml"),
+	})
+	private := strings.Replace(string(testdata.MustGetFixture("obexObjectValid.yaml")), `"2811"`, `"9001"`, 1)
+	if err := os.WriteFile(filepath.Join(localDir, "9001.yaml"), []byte(private), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := extractResultMap(t, srv.getOBEXObject(1, "9001", true, false))
+	if result["source"] != "local" {
+		t.Errorf("local object source = %v, want local", result["source"])
+	}
+	if result := extractResultMap(t, srv.getOBEXObject(1, "2811", true, false)); result["source"] != nil {
+		t.Errorf("public object source = %v, want none", result["source"])
+	}
+
+	overview := extractResultMap(t, srv.handleOBEXFind(context.Background(), 1, json.RawMessage(`{}`)))
+	if overview["local_objects"] != float64(1) || overview["total_objects"] != float64(3) {
+		t.Errorf("local_objects = %v, total_objects = %v; want 1 and 3", overview["local_objects"], overview["total_objects"])
+	}
+
+	found := extractResultMap(t, srv.handleOBEXFind(context.Background(), 1, json.RawMessage(`{"term": "ws2812"}`)))
+	sources := map[string]interface{}{}
+	objects, _ := found["objects"].([]interface{})
+	for _, o := range objects {
+		obj := o.(map[string]interface{})
+		sources[obj["object_id"].(string)] = obj["source"]
+	}
+	if want := map[string]interface{}{"2811": nil, "2812": nil, "9001": "local"}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("search sources = %v, want %v", sources, want)
+	}
+}
+
+// newServerWithMathCategories serves an index with two "math" categories and
+// one "memory" category.
+func newServerWithMathCategories(t *testing.T) (*Server, func()) {
+	t.Helper()
+	entry := map[string]interface{}{"path": "x.yaml", "mtime": 1700000000}
+	files := map[string]interface{}{
+		"p2kbPasm2Add":      entry,
+		"p2kbSpin2Abs":      entry,
+		"p2kbArchHubMemory": entry,
+	}
+	categories := map[string]interface{}{
+		"pasm2_math":  []string{"p2kbPasm2Add"},
+		"spin2_math":  []string{"p2kbSpin2Abs"},
+		"arch_memory": []string{"p2kbArchHubMemory"},
+	}
+	return newServerWithIndex(t, files, categories, http.NotFoundHandler().ServeHTTP)
+}
+
+func TestHandleFindSortByMtime(t *testing.T) {
+	files := map[string]interface{}{
+		"p2kbPasm2Add": map[string]interface{}{"path": "pasm2/add.yaml", "mtime": 1700000000},
+		"p2kbPasm2Sub": map[string]interface{}{"path": "pasm2/sub.yaml", "mtime": 1700000300},
+		"p2kbSpin2Abs": map[string]interface{}{"path": "spin2/abs.yaml", "mtime": 1700000600},
+	}
+	categories := map[string]interface{}{
+		"pasm2_math": []string{"p2kbPasm2Add", "p2kbPasm2Sub"},
+		"spin2_math": []string{"p2kbSpin2Abs"},
+	}
+	srv, cleanup := newServerWithIndex(t, files, categories, http.NotFoundHandler().ServeHTTP)
+	defer cleanup()
+
+	find := func(args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		raw, _ := json.Marshal(args)
+		return extractResultMap(t, srv.handleFind(1, raw))
+	}
+	keyOrder := func(result map[string]interface{}) []string {
+		keys, _ := result["keys"].([]interface{})
+		order := make([]string, 0, len(keys))
+		for _, k := range keys {
+			order = append(order, k.(map[string]interface{})["key"].(string))
+		}
+		return order
+	}
+
+	overview := find(map[string]interface{}{})
+	if got := keyOrder(map[string]interface{}{"keys": overview["most_recently_updated"]}); !reflect.DeepEqual(got, []string{"p2kbSpin2Abs", "p2kbPasm2Sub", "p2kbPasm2Add"}) {
+		t.Errorf("most_recently_updated = %v, want newest first", got)
+	}
+
+	result := find(map[string]interface{}{"category": "pasm2_math", "sort_by": "mtime"})
+	if got := keyOrder(result); !reflect.DeepEqual(got, []string{"p2kbPasm2Sub", "p2kbPasm2Add"}) {
+		t.Errorf("pasm2_math by mtime = %v, want [p2kbPasm2Sub p2kbPasm2Add]", got)
+	}
+	first, _ := result["keys"].([]interface{})[0].(map[string]interface{})
+	if first["updated"] != "2023-11-14T22:18:20Z" || first["mtime"] != float64(1700000300) {
+		t.Errorf("first key = %v, want mtime 1700000300 as 2023-11-14T22:18:20Z", first)
+	}
+
+	all := find(map[string]interface{}{"sort_by": "mtime", "limit": 2})
+	if all["type"] != "keys" {
+		t.Fatalf("type = %v, want keys for sort_by alone", all["type"])
+	}
+	if got := keyOrder(all); !reflect.DeepEqual(got, []string{"p2kbSpin2Abs", "p2kbPasm2Sub"}) {
+		t.Errorf("sort_by alone with limit 2 = %v, want the 2 newest keys", got)
+	}
+
+	resp := srv.handleFind(1, json.RawMessage(`{"sort_by": "size"}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("expected -32602 for sort_by size, got %+v", resp.Error)
+	}
+}
+
+func TestHandleFindCategoryAlias(t *testing.T) {
+	srv, cleanup := newServerWithMathCategories(t)
+	defer cleanup()
+
+	// Single match resolves to the full category name
+	args, _ := json.Marshal(map[string]interface{}{"category": "Memory"})
+	result := extractResultMap(t, srv.handleFind(1, args))
+	if result["type"] != "keys" || result["category"] != "arch_memory" || result["resolved_from"] != "Memory" {
+		t.Errorf("single alias result = %v, want keys for arch_memory resolved from Memory", result)
+	}
+
+	// Several matches ask the caller to pick one
+	args, _ = json.Marshal(map[string]interface{}{"category": "math"})
+	result = extractResultMap(t, srv.handleFind(1, args))
+	if result["type"] != "category_ambiguous" {
+		t.Fatalf("type = %v, want category_ambiguous", result["type"])
+	}
+	matches, _ := result["matching_categories"].([]interface{})
+	if len(matches) != 2 || matches[0] != "pasm2_math" || matches[1] != "spin2_math" {
+		t.Errorf("matching_categories = %v, want [pasm2_math spin2_math]", result["matching_categories"])
+	}
+
+	// No match keeps the existing not-found response
+	args, _ = json.Marshal(map[string]interface{}{"category": "motors"})
+	result = extractResultMap(t, srv.handleFind(1, args))
+	if result["type"] != "category_not_found" {
+		t.Errorf("type = %v, want category_not_found", result["type"])
+	}
+}
+
+func TestHandleFindTermFiltersByAliasUnion(t *testing.T) {
+	srv, cleanup := newServerWithMathCategories(t)
+	defer cleanup()
+
+	args, _ := json.Marshal(map[string]interface{}{"term": "p2kb", "category": "MATH"})
+	result := extractResultMap(t, srv.handleFind(1, args))
+	keys, _ := result["keys"].([]interface{})
+	got := make(map[interface{}]bool)
+	for _, k := range keys {
+		got[k] = true
+	}
+	if len(keys) != 2 || !got["p2kbPasm2Add"] || !got["p2kbSpin2Abs"] {
+		t.Errorf("keys = %v, want both math keys", keys)
+	}
+}
+
+func TestHandleFindCountRange(t *testing.T) {
+	entry := map[string]interface{}{"path": "x.yaml", "mtime": 1700000000}
+	files := map[string]interface{}{
+		"p2kbPasm2Add": entry, "p2kbPasm2Sub": entry, "p2kbPasm2Mul": entry, "p2kbPasm2Div": entry,
+		"p2kbSpin2Abs": entry, "p2kbSpin2Sqrt": entry,
+		"p2kbArchHubMemory": entry,
+	}
+	categories := map[string]interface{}{
+		"pasm2_math":  []string{"p2kbPasm2Add", "p2kbPasm2Sub", "p2kbPasm2Mul", "p2kbPasm2Div"},
+		"spin2_math":  []string{"p2kbSpin2Abs", "p2kbSpin2Sqrt"},
+		"arch_memory": []string{"p2kbArchHubMemory"},
+	}
+	srv, cleanup := newServerWithIndex(t, files, categories, http.NotFoundHandler().ServeHTTP)
+	defer cleanup()
+
+	names := func(list interface{}) []string {
+		entries, _ := list.([]interface{})
+		got := make([]string, 0, len(entries))
+		for _, e := range entries {
+			got = append(got, e.(map[string]interface{})["name"].(string))
+		}
+		return got
+	}
+
+	tests := []struct {
+		name string
+		args string
+		want []string
+	}{
+		{"min only", `{"min_count": 2}`, []string{"pasm2_math", "spin2_math"}},
+		{"max only", `{"max_count": 2}`, []string{"spin2_math", "arch_memory"}},
+		{"both bounds", `{"min_count": 2, "max_count": 3}`, []string{"spin2_math"}},
+		{"nothing in range", `{"min_count": 5}`, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := extractResultMap(t, srv.handleFind(1, json.RawMessage(tt.args)))
+			if result["type"] != "categories" {
+				t.Fatalf("type = %v, want categories", result["type"])
+			}
+			if got := names(result["categories"]); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("categories = %v, want %v", got, tt.want)
+			}
+			if result["filtered_category_count"] != float64(len(tt.want)) {
+				t.Errorf("filtered_category_count = %v, want %d", result["filtered_category_count"], len(tt.want))
+			}
+			if _, ok := result["total_categories"]; !ok {
+				t.Error("total_categories should still be reported")
+			}
+		})
+	}
+
+	overview := extractResultMap(t, srv.handleFind(1, json.RawMessage(`{}`)))
+	if _, ok := overview["filtered_category_count"]; ok {
+		t.Error("filtered_category_count should be absent without count filters")
+	}
+
+	search := extractResultMap(t, srv.handleFind(1, json.RawMessage(`{"term": "pasm2", "max_count": 1}`)))
+	if keys, _ := search["keys"].([]interface{}); len(keys) != 4 {
+		t.Errorf("term keys = %v, want the 4 pasm2 keys unfiltered", search["keys"])
+	}
+	if got := names(search["categories_in_range"]); !reflect.DeepEqual(got, []string{"arch_memory"}) {
+		t.Errorf("categories_in_range = %v, want [arch_memory]", got)
+	}
+
+	for _, args := range []string{`{"min_count": -1}`, `{"min_count": 3, "max_count": 2}`} {
+		resp := srv.handleFind(1, json.RawMessage(args))
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("%s: expected -32602, got %+v", args, resp.Error)
+		}
+	}
+}
+
+func TestHandleFindDetailed(t *testing.T) {
+	files := make(map[string]interface{})
+	var keys []string
+	for i := 1; i <= 23; i++ {
+		key := fmt.Sprintf("p2kbPasm2Op%02d", i)
+		files[key] = map[string]interface{}{"path": "ops/" + key + ".yaml", "mtime": 1700000000 + i}
+		keys = append(keys, key)
+	}
+	categories := map[string]interface{}{
+		"pasm2_ops":  keys,
+		"pasm2_math": []string{"p2kbPasm2Op01"},
+	}
+	srv, cleanup := newServerWithIndex(t, files, categories, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("name: op\n"))
+	})
+	defer cleanup()
+
+	if _, err := srv.cacheManager.GetOrFetch("p2kbPasm2Op01", "ops/p2kbPasm2Op01.yaml", "", 1700000001); err != nil {
+		t.Fatalf("GetOrFetch: %v", err)
+	}
+
+	result := extractResultMap(t, srv.handleFind(1, json.RawMessage(`{"term": "op", "detailed": true, "sort_by": "mtime"}`)))
+	details, _ := result["keys"].([]interface{})
+	if len(details) != keyDetailPageSize {
+		t.Fatalf("first page has %d keys, want %d", len(details), keyDetailPageSize)
+	}
+	if result["total_matches"] != float64(23) || result["has_more"] != true {
+		t.Errorf("total_matches = %v, has_more = %v, want 23 and true", result["total_matches"], result["has_more"])
+	}
+	if _, ok := result["next_offset"]; ok {
+		t.Errorf("next_offset = %v, want only addPageInfo's paging fields", result["next_offset"])
+	}
+
+	// Oldest last, so the cached key opens the second page
+	result = extractResultMap(t, srv.handleFind(2, json.RawMessage(`{"term": "op", "detailed": true, "sort_by": "mtime", "offset": 20}`)))
+	details, _ = result["keys"].([]interface{})
+	if len(details) != 3 {
+		t.Fatalf("second page has %d keys, want 3", len(details))
+	}
+	if result["has_more"] != false {
+		t.Errorf("last page has has_more %v", result["has_more"])
+	}
+	last := details[2].(map[string]interface{})
+	want := map[string]interface{}{
+		"key":            "p2kbPasm2Op01",
+		"path":           "ops/p2kbPasm2Op01.yaml",
+		"mtime_rfc3339":  "2023-11-14T22:13:21Z",
+		"categories":     []interface{}{"pasm2_math", "pasm2_ops"},
+		"content_cached": true,
+	}
+	if !reflect.DeepEqual(last, want) {
+		t.Errorf("detail = %v, want %v", last, want)
+	}
+	if cached := details[0].(map[string]interface{})["content_cached"]; cached != false {
+		t.Errorf("uncached key has content_cached %v", cached)
+	}
+
+	// total_matches counts every match, not just those up to limit, for
+	// term and regex searches alike
+	for _, args := range []string{
+		`{"term": "op", "detailed": true, "limit": 5}`,
+		`{"term": "^p2kbPasm2Op", "regex": true, "detailed": true, "limit": 5}`,
+	} {
+		result = extractResultMap(t, srv.handleFind(3, json.RawMessage(args)))
+		if result["count"] != float64(5) || result["total_matches"] != float64(23) || result["has_more"] != true {
+			t.Errorf("%s: count = %v, total_matches = %v, has_more = %v; want 5, 23 and true",
+				args, result["count"], result["total_matches"], result["has_more"])
+		}
+	}
+
+	resp := srv.handleFind(3, json.RawMessage(`{"term": "op", "detailed": true, "offset": -1}`))
+	if resp.Error == nil || resp.Error.Message != "Invalid offset" {
+		t.Errorf("negative offset error = %v, want Invalid offset", resp.Error)
+	}
+}
+
+func TestHandleFindPagination(t *testing.T) {
+	files := make(map[string]interface{})
+	var keys []string
+	for i := 1; i <= 23; i++ {
+		key := fmt.Sprintf("p2kbPasm2Op%02d", i)
+		files[key] = map[string]interface{}{"path": "ops/" + key + ".yaml", "mtime": 1700000000 + i}
+		keys = append(keys, key)
+	}
+	srv, cleanup := newServerWithIndex(t, files, map[string]interface{}{"pasm2_ops": keys}, nil)
+	defer cleanup()
+
+	for _, args := range []map[string]interface{}{
+		{"category": "pasm2_ops"},
+		{"term": "op"},
+	} {
+		seen := make(map[string]bool)
+		var got []string
+		for offset := 0; ; offset += 5 {
+			args["offset"], args["limit"] = offset, 5
+			raw, _ := json.Marshal(args)
+			result := extractResultMap(t, srv.handleFind(1, raw))
+			if result["total_count"] != float64(23) || result["offset"] != float64(offset) || result["limit"] != float64(5) {
+				t.Fatalf("%v: total_count = %v, offset = %v, limit = %v", args, result["total_count"], result["offset"], result["limit"])
+			}
+			page, _ := result["keys"].([]interface{})
+			for _, k := range page {
+				if seen[k.(string)] {
+					t.Errorf("%v: key %v on more than one page", args, k)
+				}
+				seen[k.(string)] = true
+				got = append(got, k.(string))
+			}
+			if more, _ := result["has_more"].(bool); !more {
+				if offset != 20 || len(page) != 3 {
+					t.Errorf("%v: last page at offset %d has %d keys, want offset 20 with 3", args, offset, len(page))
+				}
+				break
+			}
+			if len(page) != 5 {
+				t.Fatalf("%v: page at offset %d has %d keys, want 5", args, offset, len(page))
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, keys) {
+			t.Errorf("%v: pages together = %v, want %v", args, got, keys)
+		}
+	}
+
+	result := extractResultMap(t, srv.handleFind(2, json.RawMessage(`{"category": "pasm2_ops", "offset": 100}`)))
+	if page, _ := result["keys"].([]interface{}); len(page) != 0 || result["has_more"] != false {
+		t.Errorf("offset past the end: keys = %v, has_more = %v", result["keys"], result["has_more"])
+	}
+}
+
+func TestHandleFindRegex(t *testing.T) {
+	entry := map[string]interface{}{"path": "x.yaml", "mtime": 1700000000}
+	files := map[string]interface{}{
+		"p2kbPasm2Mov": entry, "p2kbPasm2Movbyts": entry, "p2kbPasm2Add": entry,
+		"p2kbSpin2Move": entry, "p2kbSpin2Abs": entry,
+		"p2kbArchMovement": entry,
+	}
+	categories := map[string]interface{}{
+		"pasm2_data": []string{"p2kbPasm2Mov", "p2kbPasm2Movbyts"},
+	}
+	srv, cleanup := newServerWithIndex(t, files, categories, http.NotFoundHandler().ServeHTTP)
+	defer cleanup()
+
+	tests := []struct {
+		name string
+		args string
+		want []string
+	}{
+		{"alternation across instruction sets", `{"term": "^p2kb(Pasm2|Spin2).*Mov", "regex": true}`,
+			[]string{"p2kbPasm2Mov", "p2kbPasm2Movbyts", "p2kbSpin2Move"}},
+		{"anchored to the whole key", `{"term": "Mov$", "regex": true}`, []string{"p2kbPasm2Mov"}},
+		{"limit", `{"term": "Mov", "regex": true, "limit": 2}`, []string{"p2kbArchMovement", "p2kbPasm2Mov"}},
+		{"with category", `{"term": "s$", "regex": true, "category": "pasm2_data"}`, []string{"p2kbPasm2Movbyts"}},
+		{"complex alternation", `{"term": "^p2kb(Pasm2|Spin2)(Mov|Add|Abs|Sub|Mul|Div)(e|byts)?$", "regex": true}`,
+			[]string{"p2kbPasm2Add", "p2kbPasm2Mov", "p2kbPasm2Movbyts", "p2kbSpin2Abs", "p2kbSpin2Move"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := extractResultMap(t, srv.handleFind(1, json.RawMessage(tt.args)))
+			if result["regex"] != true {
+				t.Errorf("regex = %v, want true", result["regex"])
+			}
+			got := make([]string, 0)
+			for _, k := range result["keys"].([]interface{}) {
+				got = append(got, k.(string))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name, term, want string
+	}{
+		{"does not compile", `p2kb(Pasm2`, "missing closing )"},
+		{"too many alternations", `^(a|b|c|d|e|f|g|h|i|j|k|l)x(y|z)$`, "alternations"},
+		{"too many quantifiers", `a+b*c?d+e*f+`, "quantifiers"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, _ := json.Marshal(map[string]interface{}{"term": tt.term, "regex": true})
+			resp := srv.handleFind(1, args)
+			if resp.Error == nil || resp.Error.Code != -32602 {
+				t.Fatalf("error = %v, want -32602", resp.Error)
+			}
+			if data, _ := resp.Error.Data.(string); !strings.Contains(data, tt.want) {
+				t.Errorf("error data = %q, want it to mention %q", data, tt.want)
+			}
+		})
+	}
+
+	// P2KB_MAX_REGEX_COMPLEXITY raises the limits
+	srv.settings.Store("P2KB_MAX_REGEX_COMPLEXITY", "12,5")
+	args, _ := json.Marshal(map[string]interface{}{"term": errorTests[1].term, "regex": true})
+	if resp := srv.handleFind(1, args); resp.Error != nil {
+		t.Errorf("12 alternations with a limit of 12: %v", resp.Error)
+	}
+}
+
+func TestParseRegexLimits(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    regexLimits
+		wantErr bool
+	}{
+		{"", defaultRegexLimits, false},
+		{"20,8", regexLimits{alternations: 20, quantifiers: 8}, false},
+		{" 0 , 0 ", regexLimits{}, false},
+		{"20", regexLimits{}, true},
+		{"a,5", regexLimits{}, true},
+		{"10,-1", regexLimits{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseRegexLimits(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseRegexLimits(%q) = %+v, %v; want %+v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if got := regexLimitsOrDefault("soon"); got != defaultRegexLimits {
+		t.Errorf("regexLimitsOrDefault(invalid) = %+v, want the defaults", got)
+	}
+}
+
+func TestCountAlternations(t *testing.T) {
+	tests := []struct {
+		term string
+		want int
+	}{
+		{"Mov", 0},
+		{"a|b|c", 2},
+		{`(Pasm2|Spin2)Mov`, 1},
+		{`a\|b`, 0},
+		{"[|]x|y", 1},
+		{"[]|]|y", 1},
+		{"[^]|]|y", 1},
+	}
+	for _, tt := range tests {
+		if got := countAlternations(tt.term); got != tt.want {
+			t.Errorf("countAlternations(%q) = %d, want %d", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestHandleFindMultipleTerms(t *testing.T) {
+	entry := map[string]interface{}{"path": "x.yaml", "mtime": 1700000000}
+	files := map[string]interface{}{
+		"p2kbPasm2Mov":     entry,
+		"p2kbPasm2Movbyts": entry,
+		"p2kbPasm2Add":     entry,
+		"p2kbSpin2Movepin": entry,
+		"p2kbArchCogRam":   entry,
+	}
+	srv, cleanup := newServerWithIndex(t, files, map[string]interface{}{}, http.NotFoundHandler().ServeHTTP)
+	defer cleanup()
+
+	find := func(args map[string]interface{}) (map[string]interface{}, map[string]bool) {
+		t.Helper()
+		raw, _ := json.Marshal(args)
+		result := extractResultMap(t, srv.handleFind(1, raw))
+		keys, _ := result["keys"].([]interface{})
+		got := make(map[string]bool)
+		for _, k := range keys {
+			got[k.(string)] = true
+		}
+		return result, got
+	}
+
+	// AND by default: only keys containing both tokens
+	result, got := find(map[string]interface{}{"term": "  pasm2 mov "})
+	if len(got) != 2 || !got["p2kbPasm2Mov"] || !got["p2kbPasm2Movbyts"] {
+		t.Errorf("AND keys = %v, want the two PASM2 mov keys", result["keys"])
+	}
+	if !reflect.DeepEqual(result["tokens_used"], []interface{}{"pasm2", "mov"}) {
+		t.Errorf("tokens_used = %v, want [pasm2 mov]", result["tokens_used"])
+	}
+	if result["operator"] != "AND" {
+		t.Errorf("operator = %v, want AND", result["operator"])
+	}
+
+	// OR: the union of both tokens' matches
+	result, got = find(map[string]interface{}{"term": "pasm2 mov", "operator": "or"})
+	want := []string{"p2kbPasm2Mov", "p2kbPasm2Movbyts", "p2kbPasm2Add", "p2kbSpin2Movepin"}
+	for _, k := range want {
+		if !got[k] {
+			t.Errorf("OR keys = %v, missing %s", result["keys"], k)
+		}
+	}
+	if len(got) != len(want) || result["operator"] != "OR" {
+		t.Errorf("OR result = %v, want %d keys with operator OR", result, len(want))
+	}
+
+	// A single token searches as before
+	result, got = find(map[string]interface{}{"term": "cog"})
+	if len(got) != 1 || !got["p2kbArchCogRam"] {
+		t.Errorf("single token keys = %v, want p2kbArchCogRam", result["keys"])
+	}
+	if _, ok := result["operator"]; ok {
+		t.Error("operator should only be reported for multiple tokens")
+	}
+
+	raw, _ := json.Marshal(map[string]interface{}{"term": "pasm2 mov", "operator": "XOR"})
+	if resp := srv.handleFind(1, raw); resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("operator XOR: got %+v, want -32602", resp.Error)
+	}
+}
+
+func TestHandleOBEXTagSearch(t *testing.T) {
+	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	srv.obexManager = mock
+
+	search := func(args map[string]interface{}) *MCPResponse {
+		raw, _ := json.Marshal(args)
+		return srv.handleOBEXTagSearch(1, raw)
+	}
+	objectIDs := func(result map[string]interface{}) []string {
+		objects, _ := result["objects"].([]interface{})
+		ids := make([]string, 0, len(objects))
+		for _, o := range objects {
+			ids = append(ids, o.(map[string]interface{})["object_id"].(string))
+		}
+		return ids
+	}
+
+	result := extractResultMap(t, search(map[string]interface{}{"tag": "LED"}))
+	if result["type"] != "tag_results" || !reflect.DeepEqual(objectIDs(result), []string{"2811", "2812"}) {
+		t.Errorf("tag LED: result = %v, want 2811 and 2812", result)
+	}
+	if !mock.Called("SearchByTag(LED)") {
+		t.Error("tag search did not use SearchByTag")
+	}
+
+	result = extractResultMap(t, search(map[string]interface{}{"tag": "led", "limit": 1}))
+	if result["count"] != float64(1) {
+		t.Errorf("limit 1: count = %v, want 1", result["count"])
+	}
+
+	result = extractResultMap(t, search(map[string]interface{}{"prefix_tag": "s"}))
+	if !reflect.DeepEqual(objectIDs(result), []string{"2813"}) {
+		t.Errorf("prefix_tag s: objects = %v, want [2813]", objectIDs(result))
+	}
+	if !reflect.DeepEqual(result["matched_tags"], []interface{}{"sensor"}) {
+		t.Errorf("prefix_tag s: matched_tags = %v, want [sensor]", result["matched_tags"])
+	}
+
+	result = extractResultMap(t, search(map[string]interface{}{"prefix_tag": "zz"}))
+	if result["count"] != float64(0) || !reflect.DeepEqual(result["matched_tags"], []interface{}{}) {
+		t.Errorf("prefix_tag zz: result = %v, want no tags or objects", result)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{},
+		{"tag": "  "},
+		{"tag": "led", "prefix_tag": "l"},
+	} {
+		if resp := search(args); resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("args %v: error = %+v, want -32602", args, resp.Error)
+		}
+	}
+
+	mock.IndexErr = errors.New("no network")
+	if resp := search(map[string]interface{}{"tag": "led"}); resp.Error == nil || resp.Error.Code != -32000 {
+		t.Errorf("index failure: error = %+v, want -32000", resp.Error)
+	}
+}
+
+func TestHandleOBEXAuthorDetail(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+
+	jon := testdata.MustGetFixture("obexObjectValid.yaml")
+	jane := bytes.Replace(jon, []byte("Jon McPhalen"), []byte("Jane McPhalen"), 1)
+	jane = bytes.Replace(jane, []byte(`"2811"`), []byte(`"2900"`), 1)
+	seedOBEXObjects(t, map[string][]byte{"2811": jon, "2900": jane})
+
+	args, _ := json.Marshal(map[string]interface{}{"author": "jon"})
+	result := extractResultMap(t, srv.handleOBEXAuthorDetail(1, args))
+	if result["type"] != "author_detail" || result["author"] != "Jon McPhalen" {
+		t.Fatalf("result = %v, want author_detail for Jon McPhalen", result)
+	}
+	if result["object_count"] != float64(1) {
+		t.Errorf("object_count = %v, want 1", result["object_count"])
+	}
+	objects, _ := result["objects"].([]interface{})
+	if len(objects) != 1 || objects[0].(map[string]interface{})["object_id"] != "2811" {
+		t.Errorf("objects = %v, want only 2811", result["objects"])
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"author": "mcphalen"})
+	result = extractResultMap(t, srv.handleOBEXAuthorDetail(1, args))
+	if result["type"] != "author_ambiguous" {
+		t.Fatalf("type = %v, want author_ambiguous", result["type"])
+	}
+	if matches, _ := result["matching_authors"].([]interface{}); len(matches) != 2 {
+		t.Errorf("matching_authors = %v, want 2 names", result["matching_authors"])
+	}
+
+	args, _ = json.Marshal(map[string]interface{}{"author": "nobody"})
+	result = extractResultMap(t, srv.handleOBEXAuthorDetail(1, args))
+	if result["type"] != "author_not_found" {
+		t.Errorf("type = %v, want author_not_found", result["type"])
+	}
+}
+
+func TestHandleOBEXFindMicrocontroller(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObjects(t, map[string][]byte{
+		"2811": testdata.MustGetFixture("obexObjectValid.yaml"),
+		"2812": testdata.MustGetFixture("obexObjectP1.yaml"),
+		"2813": testdata.MustGetFixture("obexObjectNoMicrocontroller.yaml"),
+	})
+
+	find := func(args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		raw, _ := json.Marshal(args)
+		return extractResultMap(t, srv.handleOBEXFind(context.Background(), 1, raw))
+	}
+	objectIDs := func(result map[string]interface{}) []string {
+		objects, _ := result["objects"].([]interface{})
+		ids := make([]string, 0, len(objects))
+		for _, o := range objects {
+			ids = append(ids, o.(map[string]interface{})["object_id"].(string))
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	overview := find(map[string]interface{}{"microcontroller": "any"})
+	if overview["type"] != "overview" {
+		t.Fatalf("type = %v, want overview for microcontroller any", overview["type"])
+	}
+	distribution, _ := overview["microcontroller_distribution"].(map[string]interface{})
+	if distribution["P2"] != float64(1) || distribution["Propeller 1"] != float64(1) || distribution["unspecified"] != float64(1) {
+		t.Errorf("microcontroller_distribution = %v, want one each of P2, Propeller 1, unspecified", distribution)
+	}
+
+	if got := objectIDs(find(map[string]interface{}{"term": "ws2812", "microcontroller": "P2"})); !reflect.DeepEqual(got, []string{"2811"}) {
+		t.Errorf("term search for P2 = %v, want [2811]", got)
+	}
+	if got := objectIDs(find(map[string]interface{}{"category": "drivers", "microcontroller": "P1"})); !reflect.DeepEqual(got, []string{"2812"}) {
+		t.Errorf("drivers browse for P1 = %v, want [2812]", got)
+	}
+	if got := objectIDs(find(map[string]interface{}{"author": "jon", "microcontroller": "P2"})); !reflect.DeepEqual(got, []string{"2811"}) {
+		t.Errorf("author listing for P2 = %v, want [2811]", got)
+	}
+
+	// A filter on its own browses every category
+	if got := objectIDs(find(map[string]interface{}{"microcontroller": "P2"})); !reflect.DeepEqual(got, []string{"2811"}) {
+		t.Errorf("microcontroller-only browse = %v, want [2811]", got)
+	}
+}
+
+func TestHandleOBEXFindFileSize(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	withSize := func(fixture, size string) []byte {
+		return bytes.Replace(testdata.MustGetFixture(fixture), []byte("  technical_details:\n"),
+			[]byte("  technical_details:\n    file_size: \""+size+"\"\n"), 1)
+	}
+	seedOBEXObjects(t, map[string][]byte{
+		"2811": withSize("obexObjectValid.yaml", "45.2 KB"),
+		"2812": withSize("obexObjectP1.yaml", "1.2 MB"),
+		"2813": testdata.MustGetFixture("obexObjectNoMicrocontroller.yaml"),
+	})
+
+	find := func(args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		raw, _ := json.Marshal(args)
+		return extractResultMap(t, srv.handleOBEXFind(context.Background(), 1, raw))
+	}
+	objectIDs := func(result map[string]interface{}) []string {
+		objects, _ := result["objects"].([]interface{})
+		ids := make([]string, 0, len(objects))
+		for _, o := range objects {
+			ids = append(ids, o.(map[string]interface{})["object_id"].(string))
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	stats, _ := find(map[string]interface{}{})["file_size_stats"].(map[string]interface{})
+	want := map[string]interface{}{"min_kb": 45.2, "max_kb": 1228.8, "avg_kb": 637.0, "known": 2.0, "unknown": 1.0}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("file_size_stats = %v, want %v", stats, want)
+	}
+
+	// A size filter alone browses every category; unknown sizes are kept
+	result := find(map[string]interface{}{"max_file_size_kb": 100})
+	if got := objectIDs(result); !reflect.DeepEqual(got, []string{"2811", "2813"}) {
+		t.Fatalf("max 100 KB = %v, want [2811 2813]", got)
+	}
+	for _, o := range result["objects"].([]interface{}) {
+		object := o.(map[string]interface{})
+		switch object["object_id"] {
+		case "2811":
+			if object["file_size_kb"] != 45.2 || object["size_unknown"] != nil {
+				t.Errorf("2811 = %v, want file_size_kb 45.2", object)
+			}
+		case "2813":
+			if object["file_size_kb"] != -1.0 || object["size_unknown"] != true {
+				t.Errorf("2813 = %v, want file_size_kb -1 and size_unknown", object)
+			}
+		}
+	}
+
+	tests := []struct {
+		args map[string]interface{}
+		want []string
+	}{
+		{map[string]interface{}{"term": "ws2812", "min_file_size_kb": 1000}, []string{"2812", "2813"}},
+		{map[string]interface{}{"category": "drivers", "max_file_size_kb": 1228}, []string{"2811"}},
+		{map[string]interface{}{"category": "drivers", "max_file_size_kb": 1229}, []string{"2811", "2812"}},
+		{map[string]interface{}{"author": "jon", "min_file_size_kb": 46}, []string{"2812"}},
+	}
+	for _, tt := range tests {
+		if got := objectIDs(find(tt.args)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+
+	for _, args := range []string{`{"max_file_size_kb": -1}`, `{"min_file_size_kb": 10, "max_file_size_kb": 5}`} {
+		if resp := srv.handleOBEXFind(context.Background(), 1, json.RawMessage(args)); resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("%s: error = %v, want -32602", args, resp.Error)
+		}
+	}
+}
+
+func TestHandleOBEXFindSearchPhases(t *testing.T) {
+	srv := New("1.0.0")
+	srv.obexManager = newMockOBEXManager()
+
+	for _, tt := range []struct {
+		limit      int
+		wantPhases float64
+	}{
+		{1, 1}, // limit reached from memory
+		{20, 2},
+	} {
+		raw, _ := json.Marshal(map[string]interface{}{"term": "led", "limit": tt.limit})
+		result := extractResultMap(t, srv.handleOBEXFind(context.Background(), 1, raw))
+		if result["search_phases"] != tt.wantPhases {
+			t.Errorf("limit %d: search_phases = %v, want %v", tt.limit, result["search_phases"], tt.wantPhases)
+		}
+	}
+}
+
+func TestHandleOBEXFindSubcategory(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObjects(t, map[string][]byte{
+		"2811": testdata.MustGetFixture("obexObjectValid.yaml"),
+		"2812": testdata.MustGetFixture("obexObjectP1.yaml"),
+		"2813": testdata.MustGetFixture("obexObjectNoMicrocontroller.yaml"),
+		"2814": testdata.MustGetFixture("obexObjectI2CDriver.yaml"),
+	})
+
+	find := func(args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		raw, _ := json.Marshal(args)
+		return extractResultMap(t, srv.handleOBEXFind(context.Background(), 1, raw))
+	}
+
+	overview := find(map[string]interface{}{})
+	categories, _ := overview["categories"].([]interface{})
+	if len(categories) != 2 {
+		t.Fatalf("categories = %v, want drivers and demos", overview["categories"])
+	}
+	drivers, _ := categories[0].(map[string]interface{})
+	if drivers["name"] != "drivers" || drivers["count"] != float64(3) {
+		t.Fatalf("first category = %v, want drivers with 3 objects", drivers)
+	}
+	if subs, _ := drivers["subcategories"].(map[string]interface{}); subs["led"] != float64(2) || subs["i2c"] != float64(1) {
+		t.Errorf("drivers subcategories = %v, want led: 2, i2c: 1", drivers["subcategories"])
+	}
+	if demos, _ := categories[1].(map[string]interface{}); demos["subcategories"] != nil {
+		t.Errorf("demos = %v, want no subcategories", demos)
+	}
+
+	result := find(map[string]interface{}{"category": "drivers", "subcategory": "i2c"})
+	objects, _ := result["objects"].([]interface{})
+	if result["subcategory"] != "i2c" || len(objects) != 1 || objects[0].(map[string]interface{})["object_id"] != "2814" {
+		t.Errorf("drivers/i2c = %v, want only 2814", result)
+	}
+
+	result = find(map[string]interface{}{"term": "ws2812", "category": "drivers", "subcategory": "led"})
+	if result["count"] != float64(2) {
+		t.Errorf("term search in drivers/led = %v, want 2 objects", result)
+	}
+
+	resp := srv.handleOBEXFind(context.Background(), 1, json.RawMessage(`{"subcategory": "i2c"}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("expected -32602 for subcategory without category, got %+v", resp.Error)
+	}
+}
+
+func TestHandleOBEXGetMicrocontroller(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObjects(t, map[string][]byte{
+		"2811": testdata.MustGetFixture("obexObjectValid.yaml"),
+		"2812": testdata.MustGetFixture("obexObjectP1.yaml"),
+	})
+
+	// Unfiltered, both drivers match and come back as suggestions
+	raw, _ := json.Marshal(map[string]interface{}{"query": "ws2812 led driver"})
+	if result := extractResultMap(t, srv.handleOBEXGet(context.Background(), 1, raw)); result["type"] != "suggestions" {
+		t.Fatalf("type = %v, want suggestions without a filter", result["type"])
+	}
+
+	raw, _ = json.Marshal(map[string]interface{}{"query": "ws2812 led driver", "microcontroller": "P1"})
+	result := extractResultMap(t, srv.handleOBEXGet(context.Background(), 1, raw))
+	if result["type"] != "obex_object" || result["object_id"] != "2812" {
+		t.Fatalf("result = %v, want the P1 object 2812", result)
+	}
+	if mcs, _ := result["microcontroller"].([]interface{}); len(mcs) != 1 || mcs[0] != "Propeller 1" {
+		t.Errorf("microcontroller = %v, want [Propeller 1]", result["microcontroller"])
+	}
+}
+
+func TestHandleOBEXStats(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObjects(t, map[string][]byte{
+		"2811": testdata.MustGetFixture("obexObjectValid.yaml"),
+		"2815": testdata.MustGetFixture("obexObjectComplete.yaml"),
+	})
+
+	result := extractResultMap(t, srv.handleOBEXStats(1, nil))
+	for _, field := range []string{
+		"total_objects", "by_language", "by_category", "quality_stats", "with_github_repo_pct",
+		"with_forum_link_pct", "completeness_avg_pct", "extraction_status_breakdown", "computed_at",
+	} {
+		if _, ok := result[field]; !ok {
+			t.Errorf("missing field %s in %v", field, result)
+		}
+	}
+	if result["total_objects"] != float64(2) || result["with_github_repo_pct"] != float64(50) {
+		t.Errorf("total_objects = %v, with_github_repo_pct = %v, want 2 and 50", result["total_objects"], result["with_github_repo_pct"])
+	}
+	quality, _ := result["quality_stats"].(map[string]interface{})
+	if quality["scored_objects"] != float64(1) || quality["p90"] != float64(8) {
+		t.Errorf("quality_stats = %v, want one score of 8", quality)
+	}
+}
+
+func TestHandleOBEXDependencyGraph(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObjects(t, map[string][]byte{
+		"2901": testdata.MustGetFixture("obexGraphServo.yaml"),
+		"2902": testdata.MustGetFixture("obexGraphMotor.yaml"),
+		"2904": testdata.MustGetFixture("obexGraphBlinky.yaml"),
+	})
+
+	result := extractResultMap(t, srv.handleOBEXDependencyGraph(1))
+	if result["type"] != "obex_dependency_graph" {
+		t.Fatalf("type = %v, want obex_dependency_graph", result["type"])
+	}
+	if nodes, _ := result["nodes"].([]interface{}); len(nodes) != 3 {
+		t.Errorf("nodes = %v, want 3", result["nodes"])
+	}
+	edges, _ := result["edges"].([]interface{})
+	if len(edges) != 2 {
+		t.Fatalf("edges = %v, want the two between 2901 and 2902", result["edges"])
+	}
+	if edge, _ := edges[0].(map[string]interface{}); edge["source_id"] != "2901" || edge["target_id"] != "2902" || edge["match_type"] != "object_id" {
+		t.Errorf("first edge = %v, want 2901 -> 2902 by object_id", edge)
+	}
+	if components, _ := result["strongly_connected_components"].([]interface{}); len(components) != 1 {
+		t.Errorf("strongly_connected_components = %v, want one", result["strongly_connected_components"])
+	}
+	if isolated, _ := result["isolated_objects"].([]interface{}); len(isolated) != 1 || isolated[0] != "2904" {
+		t.Errorf("isolated_objects = %v, want [2904]", result["isolated_objects"])
+	}
+}
+
+func TestHandleOBEXAuthorDetailMissingAuthor(t *testing.T) {
+	srv := New("1.0.0")
+	resp := srv.handleOBEXAuthorDetail(1, json.RawMessage(`{"author": "  "}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Fatalf("expected -32602 for a blank author, got %+v", resp.Error)
+	}
+}
+
+func TestHandleListKeys(t *testing.T) {
+	srv, cleanup := newServerWithMathCategories(t)
+	defer cleanup()
+
+	listKeys := func(args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		raw, _ := json.Marshal(args)
+		return extractResultMap(t, srv.handleListKeys(1, raw))
+	}
+
+	// Default list format, prefix filter
+	result := listKeys(map[string]interface{}{"prefix": "p2kbPasm2"})
+	keys, _ := result["keys"].([]interface{})
+	if len(keys) != 1 || keys[0] != "p2kbPasm2Add" {
+		t.Errorf("keys = %v, want [p2kbPasm2Add]", result["keys"])
+	}
+	if result["total_count"] != float64(3) || result["matched_count"] != float64(1) || result["returned_count"] != float64(1) {
+		t.Errorf("counts = %v/%v/%v, want 3/1/1", result["total_count"], result["matched_count"], result["returned_count"])
+	}
+
+	// Map format joins file entries and categories
+	result = listKeys(map[string]interface{}{"format": "map", "category": "spin2_math"})
+	entries, _ := result["keys"].(map[string]interface{})
+	entry, _ := entries["p2kbSpin2Abs"].(map[string]interface{})
+	if len(entries) != 1 || entry["path"] != "x.yaml" || entry["mtime"] != float64(1700000000) {
+		t.Fatalf("map keys = %v, want p2kbSpin2Abs with path and mtime", result["keys"])
+	}
+	if cats, _ := entry["categories"].([]interface{}); len(cats) != 1 || cats[0] != "spin2_math" {
+		t.Errorf("categories = %v, want [spin2_math]", entry["categories"])
+	}
+}
+
+func TestHandleListKeysPagination(t *testing.T) {
+	srv, cleanup := newServerWithMathCategories(t)
+	defer cleanup()
+
+	tests := []struct {
+		offset, limit int
+		want          []string
+	}{
+		{0, 2, []string{"p2kbArchHubMemory", "p2kbPasm2Add"}},
+		{2, 2, []string{"p2kbSpin2Abs"}},
+		{3, 2, []string{}},
+		{10, 2, []string{}},
+		{0, 5000, []string{"p2kbArchHubMemory", "p2kbPasm2Add", "p2kbSpin2Abs"}},
+	}
+
+	for _, tt := range tests {
+		raw, _ := json.Marshal(map[string]interface{}{"offset": tt.offset, "limit": tt.limit})
+		result := extractResultMap(t, srv.handleListKeys(1, raw))
+		keys, _ := result["keys"].([]interface{})
+		got := make([]string, 0, len(keys))
+		for _, k := range keys {
+			got = append(got, k.(string))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("offset %d limit %d: keys = %v, want %v", tt.offset, tt.limit, got, tt.want)
+		}
+		if tt.limit > maxListKeysLimit && result["limit"] != float64(maxListKeysLimit) {
+			t.Errorf("limit = %v, want clamped to %d", result["limit"], maxListKeysLimit)
+		}
+	}
+
+	for _, args := range []string{`{"format": "csv"}`, `{"offset": -1}`, `{"limit": 0}`} {
+		resp := srv.handleListKeys(1, json.RawMessage(args))
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("%s: expected -32602, got %+v", args, resp.Error)
+		}
+	}
+}
+
+// serveOBEXDownloads points obex.OBEXDownloadBase at handler for the test.
+func serveOBEXDownloads(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	orig := obex.OBEXDownloadBase
+	obex.OBEXDownloadBase = srv.URL + "/download?obuid=OB"
+	t.Cleanup(func() {
+		obex.OBEXDownloadBase = orig
+		srv.Close()
+	})
+}
+
+func TestHandleOBEXPreview(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObject(t, "2811", "obexObjectValid.yaml")
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	w, _ := zw.Create("jm_ws2812.spin2")
+	_, _ = w.Write([]byte("'' WS2812 driver\npub null()\n"))
+	w, _ = zw.Create("logo.bin")
+	_, _ = w.Write([]byte{0xff, 0xfe, 0x00})
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	serveOBEXDownloads(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("obuid") != "OB2811" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(zipBuf.Bytes())
+	})
+
+	// Off by default
+	resp := srv.handleOBEXPreview(context.Background(), 1, json.RawMessage(`{"query": "2811"}`))
+	if resp.Error == nil || resp.Error.Code != -32000 {
+		t.Fatalf("preview without P2KB_ENABLE_DOWNLOADS = %+v, want -32000", resp)
+	}
+
+	t.Setenv("P2KB_ENABLE_DOWNLOADS", "true")
+	result := extractResultMap(t, srv.handleOBEXPreview(context.Background(), 1, json.RawMessage(`{"query": "2811", "max_bytes": 16}`)))
+	if result["type"] != "obex_preview" || result["preview_filename"] != "jm_ws2812.spin2" {
+		t.Fatalf("result = %v, want a preview of jm_ws2812.spin2", result)
+	}
+	if result["preview_content"] != "'' WS2812 driver" || result["preview_encoding"] != "utf-8" || result["truncated"] != true {
+		t.Errorf("preview = %q (%v, truncated=%v), want the first 16 bytes as text", result["preview_content"], result["preview_encoding"], result["truncated"])
+	}
+	listing, _ := result["file_listing"].([]interface{})
+	if len(listing) != 2 {
+		t.Errorf("file_listing = %v, want 2 files", result["file_listing"])
+	}
+
+	tests := []struct {
+		name string
+		args string
+		code int
+	}{
+		{"missing query", `{}`, -32602},
+		{"negative max_bytes", `{"query": "2811", "max_bytes": -1}`, -32602},
+		{"oversized max_bytes", `{"query": "2811", "max_bytes": 2000000}`, -32602},
+	}
+	for _, tt := range tests {
+		resp := srv.handleOBEXPreview(context.Background(), 1, json.RawMessage(tt.args))
+		if resp.Error == nil || resp.Error.Code != tt.code {
+			t.Errorf("%s: got %+v, want error %d", tt.name, resp.Error, tt.code)
+		}
+	}
+}
+
+func TestHandleOBEXPreviewDownloadFailures(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObject(t, "2811", "obexObjectValid.yaml")
+	t.Setenv("P2KB_ENABLE_DOWNLOADS", "true")
+
+	status := http.StatusNotFound
+	serveOBEXDownloads(t, func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("<html>OBEX maintenance</html>"))
+	})
+
+	resp := srv.handleOBEXPreview(context.Background(), 1, json.RawMessage(`{"query": "2811"}`))
+	if resp.Error == nil || resp.Error.Code != -32000 {
+		t.Fatalf("404 download = %+v, want -32000", resp)
+	}
+	if data, _ := resp.Error.Data.(map[string]interface{}); data["hint"] == nil || !strings.Contains(data["error"].(string), "404") {
+		t.Errorf("error data = %v, want the HTTP status and a hint", resp.Error.Data)
+	}
+
+	// A 200 that is not a ZIP is previewed as-is
+	status = http.StatusOK
+	result := extractResultMap(t, srv.handleOBEXPreview(context.Background(), 1, json.RawMessage(`{"query": "2811"}`)))
+	if result["preview_content"] != "<html>OBEX maintenance</html>" || result["note"] == nil {
+		t.Errorf("non-zip preview = %v, want raw body with a note", result)
+	}
+}
+
+func TestUTF8Prefix(t *testing.T) {
+	euro := []byte("€") // 3 bytes
+	tests := []struct {
+		in     []byte
+		want   string
+		wantOK bool
+	}{
+		{[]byte("plain"), "plain", true},
+		{append([]byte("ab"), euro[:2]...), "ab", true},
+		{[]byte{'a', 0xff, 'b'}, "", false},
+		{[]byte{}, "", true},
+	}
+	for _, tt := range tests {
+		got, ok := utf8Prefix(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("utf8Prefix(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	counts := map[string]int{
+		"pasm2_math":   42,
+		"pasm2_branch": 18,
+		"pasm2_data":   15,
+		"spin2_pin":    23,
+		"guides":       4,
+	}
+	want := []categoryTreeNode{
+		{Name: "pasm2", Count: 75, Subcategories: []categoryCount{{"math", 42}, {"branch", 18}, {"data", 15}}},
+		{Name: "spin2", Count: 23, Subcategories: []categoryCount{{"pin", 23}}},
+		{Name: "guides", Count: 4},
+	}
+	if got := buildCategoryTree(counts); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildCategoryTree() = %+v, want %+v", got, want)
+	}
+
+	wantASCII := "pasm2 (75)\n" +
+		"  ├── math (42)\n" +
+		"  ├── branch (18)\n" +
+		"  └── data (15)\n" +
+		"spin2 (23)\n" +
+		"  └── pin (23)\n" +
+		"guides (4)"
+	got := renderCategoryTree(want)
+	if got != wantASCII {
+		t.Errorf("renderCategoryTree() =\n%s\nwant\n%s", got, wantASCII)
+	}
+	for i, line := range strings.Split(got, "\n") {
+		if strings.TrimRight(line, " \t") != line {
+			t.Errorf("line %d has trailing whitespace: %q", i+1, line)
+		}
+	}
+}
+
+func TestHandleCategoryTree(t *testing.T) {
+	srv, cleanup := newServerWithMathCategories(t)
+	defer cleanup()
+
+	result := extractResultMap(t, srv.handleCategoryTree(1, nil))
+	if result["format"] != "ascii" || result["total_categories"] != float64(3) {
+		t.Errorf("result = %v, want ascii tree of 3 categories", result)
+	}
+	if tree, _ := result["tree"].(string); !strings.Contains(tree, "pasm2 (1)\n  └── math (1)") {
+		t.Errorf("tree = %q, want pasm2 with math under it", tree)
+	}
+
+	result = extractResultMap(t, srv.handleCategoryTree(1, json.RawMessage(`{"format": "json"}`)))
+	nodes, _ := result["tree"].([]interface{})
+	if len(nodes) != 3 {
+		t.Fatalf("tree = %v, want 3 groups", result["tree"])
+	}
+	for _, n := range nodes {
+		node := n.(map[string]interface{})
+		subs, _ := node["subcategories"].([]interface{})
+		sum := 0.0
+		for _, sub := range subs {
+			sum += sub.(map[string]interface{})["count"].(float64)
+		}
+		if len(subs) != 1 || node["count"] != sum {
+			t.Errorf("group %v: count %v, subcategories %v; want one subcategory summing to the count", node["name"], node["count"], subs)
+		}
+	}
+
+	resp := srv.handleCategoryTree(1, json.RawMessage(`{"format": "xml"}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("format xml = %+v, want -32602", resp)
+	}
+}
+
+func TestHandleFindDuplicates(t *testing.T) {
+	files := map[string]interface{}{
+		"p2kbPasm2Add":  map[string]interface{}{"path": "pasm2/add.yaml", "mtime": 1700000000},
+		"p2kbSpin2Add":  map[string]interface{}{"path": "spin2/add.yaml", "mtime": 1700000000},
+		"p2kbPasm2Mov":  map[string]interface{}{"path": "pasm2/mov.yaml", "mtime": 1700000000},
+		"p2kbPasm2Push": map[string]interface{}{"path": "pasm2/push.yaml", "mtime": 1700000000},
+	}
+	srv, cleanup := newServerWithFilesAndContent(t, files, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/add.yaml") {
+			_, _ = w.Write([]byte("mnemonic: ADD\n"))
+			return
+		}
+		_, _ = w.Write([]byte("mnemonic: " + r.URL.Path + "\n"))
+	})
+	defer cleanup()
+
+	// Only memory-cached entries count: p2kbPasm2Push is never read
+	for _, key := range []string{"p2kbPasm2Add", "p2kbSpin2Add", "p2kbPasm2Mov"} {
+		if _, err := srv.getContent(key); err != nil {
+			t.Fatalf("getContent(%s): %v", key, err)
+		}
+	}
+
+	params, _ := json.Marshal(map[string]interface{}{"name": "p2kb_find_duplicates", "arguments": map[string]interface{}{}})
+	resp := srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: params})
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if result["group_count"] != float64(1) || result["memory_entries_scanned"] != float64(3) {
+		t.Fatalf("result = %v, want 1 group from 3 entries", result)
+	}
+	group := result["groups"].([]interface{})[0].(map[string]interface{})
+	keys, _ := group["keys"].([]interface{})
+	if len(keys) != 2 || keys[0] != "p2kbPasm2Add" || keys[1] != "p2kbSpin2Add" {
+		t.Errorf("keys = %v, want p2kbPasm2Add and p2kbSpin2Add", group["keys"])
+	}
+	if group["content_length"] == float64(0) || len(group["content_hash"].(string)) != 64 {
+		t.Errorf("group = %v, want a content length and SHA-256", group)
+	}
+
+	version := extractResultMap(t, srv.handleVersion(1))
+	if version["duplicate_content_groups"] != float64(1) {
+		t.Errorf("duplicate_content_groups = %v, want 1", version["duplicate_content_groups"])
+	}
+}
+
+func TestHandleOBEXFindTagCloud(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObjects(t, map[string][]byte{
+		"2811": testdata.MustGetFixture("obexObjectValid.yaml"),
+		"2813": testdata.MustGetFixture("obexObjectI2CDriver.yaml"),
+	})
+
+	resp := srv.handleOBEXFind(context.Background(), 1, json.RawMessage(`{"mode": "tags", "category": "ignored"}`))
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if result["type"] != "tag_cloud" || result["total_objects"] != float64(2) {
+		t.Fatalf("result = %v, want a tag cloud over 2 objects", result)
+	}
+	tags, _ := result["tags"].([]interface{})
+	if len(tags) != 5 {
+		t.Errorf("tags = %v, want i2c, led, neopixel, sensor and ws2812", tags)
+	}
+	pairs, _ := result["tag_pairs"].([]interface{})
+	if first, _ := pairs[0].(map[string]interface{}); first["pair"] != "i2c+sensor" || first["count"] != float64(1) {
+		t.Errorf("first pair = %v, want i2c+sensor once", pairs[0])
+	}
+
+	resp = srv.handleOBEXFind(context.Background(), 1, json.RawMessage(`{"mode": "cloud"}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("unknown mode: error = %v, want -32602", resp.Error)
+	}
+}
+
+func TestHandleOBEXGetErrorCodes(t *testing.T) {
+	call := func(srv *Server, query string) *MCPResponse {
+		params, _ := json.Marshal(map[string]interface{}{
+			"name":      "p2kb_obex_get",
+			"arguments": map[string]interface{}{"query": query},
+		})
+		return srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: params})
+	}
+
+	// An unknown object is a result, not an error
+	srv := New("1.0.0")
+	srv.obexManager = newMockOBEXManager()
+	resp := call(srv, "9999")
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	if result := extractResultMap(t, resp); result["type"] != "object_not_found" {
+		t.Errorf("type = %v, want object_not_found", result["type"])
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  int
+		wantRetry bool
+		wantReset int64 // rate_limit_reset; 0 when absent
+	}{
+		{"offline", errs.Transport(errors.New("dial tcp: no route to host")), -32000, false, 0},
+		{"rate limited", fmt.Errorf("fetching: %w", errs.HTTPStatus(&http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": {"60"}},
+		}, "https://obex.example/2811.json")), -32000, true, 0},
+		{"GitHub rate limited", fmt.Errorf("fetching: %w", errs.HTTPStatus(&http.Response{
+			StatusCode: http.StatusForbidden,
+			Header:     http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"1700000000"}},
+		}, "https://api.github.com/repos/x/contents")), -32000, false, 1700000000},
+		{"disk", &fs.PathError{Op: "open", Path: "/cache/2811.json", Err: fs.ErrPermission}, -32603, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockOBEXManager()
+			mock.IndexErr = tt.err
+			srv := New("1.0.0")
+			srv.obexManager = mock
+
+			resp := call(srv, "2811")
+			if resp.Error == nil {
+				t.Fatal("expected an error response")
+			}
+			if resp.Error.Code != tt.wantCode {
+				t.Errorf("Error.Code = %d, want %d", resp.Error.Code, tt.wantCode)
+			}
+			data, _ := resp.Error.Data.(map[string]interface{})
+			if _, ok := data["retry_after_secs"]; ok != tt.wantRetry {
+				t.Errorf("retry_after_secs present = %v, want %v (data %v)", ok, tt.wantRetry, data)
+			}
+			if reset, _ := data["rate_limit_reset"].(int64); reset != tt.wantReset {
+				t.Errorf("rate_limit_reset = %v, want %d", data["rate_limit_reset"], tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestManagerErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{&errs.ErrKeyNotFound{Key: "p2kbNope"}, -32602},
+		{fmt.Errorf("listing: %w", &errs.ErrCategoryNotFound{Category: "maths"}), -32602},
+		{&errs.ErrHTTPStatus{Code: 500, URL: "u"}, -32000},
+		{errs.Transport(errors.New("timeout")), -32000},
+		{&fs.PathError{Op: "write", Path: "p", Err: fs.ErrPermission}, -32603},
+		{errors.New("something else"), -32000},
+	}
+	for _, tt := range tests {
+		if got := managerErrorCode(tt.err); got != tt.want {
+			t.Errorf("managerErrorCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHandleGetByPosition(t *testing.T) {
+	files := map[string]interface{}{
+		"p2kbPasm2Sub": map[string]interface{}{"path": "pasm2/sub.yaml", "mtime": 1700000000},
+		"p2kbPasm2Add": map[string]interface{}{"path": "pasm2/add.yaml", "mtime": 1700000000},
+		"p2kbPasm2Mul": map[string]interface{}{"path": "pasm2/mul.yaml", "mtime": 1700000000},
+	}
+	categories := map[string]interface{}{
+		"pasm2_math": []string{"p2kbPasm2Sub", "p2kbPasm2Add", "p2kbPasm2Mul"},
+	}
+	srv, cleanup := newServerWithIndex(t, files, categories, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "path: %s\n", r.URL.Path)
+	})
+	defer cleanup()
+
+	get := func(args string) *MCPResponse {
+		return srv.handleGet(context.Background(), 1, json.RawMessage(args))
+	}
+
+	// Position 0 is the alphabetically first key
+	result := extractResultMap(t, get(`{"category": "pasm2_math", "position": 0}`))
+	if result["key"] != "p2kbPasm2Add" || result["position"] != float64(0) || result["total_in_category"] != float64(3) {
+		t.Fatalf("position 0 = key %v, position %v, total %v; want p2kbPasm2Add, 0, 3", result["key"], result["position"], result["total_in_category"])
+	}
+	if _, ok := result["prev_key"]; ok || result["next_key"] != "p2kbPasm2Mul" {
+		t.Errorf("position 0 prev_key = %v, next_key = %v; want none and p2kbPasm2Mul", result["prev_key"], result["next_key"])
+	}
+	if !strings.Contains(result["content"].(string), "pasm2/add.yaml") {
+		t.Errorf("content = %q, want pasm2/add.yaml", result["content"])
+	}
+
+	result = extractResultMap(t, get(`{"category": "PASM2_MATH", "position": 2}`))
+	if result["key"] != "p2kbPasm2Sub" || result["prev_key"] != "p2kbPasm2Mul" {
+		t.Errorf("position 2 = key %v, prev_key %v; want p2kbPasm2Sub, p2kbPasm2Mul", result["key"], result["prev_key"])
+	}
+	if _, ok := result["next_key"]; ok {
+		t.Errorf("last position has next_key %v", result["next_key"])
+	}
+
+	for _, position := range []int{-1, 3} {
+		resp := get(fmt.Sprintf(`{"category": "pasm2_math", "position": %d}`, position))
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Fatalf("position %d = %+v, want -32602", position, resp)
+		}
+		data, _ := resp.Error.Data.(map[string]interface{})
+		if data["valid_range"] != "0-2" || !strings.Contains(resp.Error.Message, "0-2") {
+			t.Errorf("position %d error = %q, data %v; want the range 0-2", position, resp.Error.Message, data)
+		}
+	}
+
+	for _, args := range []string{
+		`{"category": "nosuch", "position": 0}`,
+		`{"category": "pasm2_math"}`,
+		`{"position": 1}`,
+		`{"category": "pasm2_math", "position": 0, "query": "add"}`,
+	} {
+		if resp := get(args); resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("p2kb_get %s = %+v, want -32602", args, resp)
+		}
+	}
+}
+
+// newBatchGetServer serves p2kbPasm2Mov and p2kbPasm2Add, with the content
+// body returns for each key, but answers 404 for p2kbPasm2Sub.
+func newBatchGetServer(t *testing.T, body func(key string) []byte) (*Server, func()) {
+	t.Helper()
+	files := map[string]interface{}{
+		"p2kbPasm2Mov": map[string]interface{}{"path": "pasm2/mov.yaml", "mtime": 1700000000},
+		"p2kbPasm2Add": map[string]interface{}{"path": "pasm2/add.yaml", "mtime": 1700000000},
+		"p2kbPasm2Sub": map[string]interface{}{"path": "pasm2/sub.yaml", "mtime": 1700000000},
+	}
+	return newServerWithFilesAndContent(t, files, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pasm2/mov.yaml":
+			_, _ = w.Write(body("p2kbPasm2Mov"))
+		case "/pasm2/add.yaml":
+			_, _ = w.Write(body("p2kbPasm2Add"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// mnemonicBody is a newBatchGetServer body holding just the key's mnemonic.
+func mnemonicBody(key string) []byte {
+	return []byte("mnemonic: " + strings.ToUpper(strings.TrimPrefix(key, "p2kbPasm2")) + "\n")
+}
+
+// batchGetResults runs p2kb_batch_get for keys and returns its results.
+func batchGetResults(t *testing.T, srv *Server, keys []string) []map[string]interface{} {
+	t.Helper()
+	args, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	resp := srv.handleBatchGet(context.Background(), 1, args)
+	if resp.Error != nil {
+		t.Fatalf("handleBatchGet returned error: %v", resp.Error)
+	}
+	raw, _ := extractResultMap(t, resp)["results"].([]interface{})
+	results := make([]map[string]interface{}, len(raw))
+	for i, r := range raw {
+		results[i], _ = r.(map[string]interface{})
+	}
+	return results
+}
+
+func TestHandleBatchGet(t *testing.T) {
+	srv, cleanup := newBatchGetServer(t, mnemonicBody)
+	defer cleanup()
+
+	keys := []string{"p2kbPasm2Add", "p2kbpasm2mov", "p2kbPasm2Add"}
+	results := batchGetResults(t, srv, keys)
+	if len(results) != len(keys) {
+		t.Fatalf("got %d results, want %d", len(results), len(keys))
+	}
+	want := []string{"mnemonic: ADD\n", "mnemonic: MOV\n", "mnemonic: ADD\n"}
+	for i, r := range results {
+		if r["key"] != keys[i] || r["content"] != want[i] {
+			t.Errorf("results[%d] = %v, want key %s with content %q", i, r, keys[i], want[i])
+		}
+		if _, ok := r["error"]; ok {
+			t.Errorf("results[%d] has error %v", i, r["error"])
+		}
+	}
+}
+
+func TestHandleBatchGetPartialFailure(t *testing.T) {
+	srv, cleanup := newBatchGetServer(t, mnemonicBody)
+	defer cleanup()
+
+	results := batchGetResults(t, srv, []string{"p2kbPasm2Mov", "p2kbPasm2Sub", "p2kbNoSuchKey", "p2kbPasm2Add"})
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	for _, i := range []int{0, 3} {
+		if results[i]["content"] == nil || results[i]["error"] != nil {
+			t.Errorf("results[%d] = %v, want content", i, results[i])
+		}
+	}
+	for _, i := range []int{1, 2} {
+		if msg, _ := results[i]["error"].(string); msg == "" || results[i]["content"] != nil {
+			t.Errorf("results[%d] = %v, want an error", i, results[i])
+		}
+	}
+}
+
+func TestHandleBatchGetInvalidKeys(t *testing.T) {
+	srv := New("1.0.0")
+
+	tooMany := make([]string, maxBatchGetKeys+1)
+	for i := range tooMany {
+		tooMany[i] = "p2kbPasm2Mov"
+	}
+	args, _ := json.Marshal(map[string]interface{}{"keys": tooMany})
+	for _, args := range []json.RawMessage{
+		json.RawMessage(`{}`),
+		json.RawMessage(`{"keys": []}`),
+		args,
+	} {
+		if resp := srv.handleBatchGet(context.Background(), 1, args); resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("%s: error = %+v, want -32602", args, resp.Error)
+		}
+	}
+}
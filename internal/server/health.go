@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/paths"
+)
+
+// Health statuses, from best to worst.
+const (
+	healthHealthy   = "healthy"
+	healthDegraded  = "degraded"
+	healthUnhealthy = "unhealthy"
+)
+
+// healthRank orders health statuses so the overall status is the worst check.
+var healthRank = map[string]int{
+	healthHealthy:   0,
+	healthDegraded:  1,
+	healthUnhealthy: 2,
+}
+
+// HealthCheck is the outcome of one p2kb_healthcheck probe.
+type HealthCheck struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Message   string  `json:"message"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// runHealthCheck runs probe and records how long it took.
+func runHealthCheck(name string, probe func() (status, message string)) HealthCheck {
+	start := time.Now()
+	status, message := probe()
+	return HealthCheck{
+		Name:      name,
+		Status:    status,
+		Message:   message,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+}
+
+// handleHealthcheck implements p2kb_healthcheck - a structured health report
+// for liveness probes. Nothing is fetched or loaded: the index and OBEX list
+// load lazily, so a fresh server reports them as degraded until first use.
+func (s *Server) handleHealthcheck(id interface{}, _ json.RawMessage) *MCPResponse {
+	cacheDir := s.cacheManager.CacheDir()
+	indexLoaded := s.indexManager.IsLoaded()
+
+	checks := []HealthCheck{
+		runHealthCheck("index_loaded", func() (string, string) {
+			if !indexLoaded {
+				return healthDegraded, "index not loaded yet; it loads on first lookup"
+			}
+			return healthHealthy, "index loaded"
+		}),
+		runHealthCheck("index_age", s.checkIndexAge),
+		runHealthCheck("cache_dir_writable", func() (string, string) {
+			return checkDirWritable(cacheDir)
+		}),
+		runHealthCheck("memory_cache", func() (string, string) {
+			if !indexLoaded {
+				return healthHealthy, "skipped: index not loaded"
+			}
+			entries := s.cacheManager.GetStats().MemoryEntries
+			if entries == 0 {
+				return healthDegraded, "index loaded but no content cached in memory"
+			}
+			return healthHealthy, fmt.Sprintf("%d entries in memory", entries)
+		}),
+		runHealthCheck("obex_index_loaded", func() (string, string) {
+			if !s.obexManager.IsIndexLoaded() {
+				return healthDegraded, "OBEX index not loaded yet; it loads on first OBEX lookup"
+			}
+			return healthHealthy, "OBEX index loaded"
+		}),
+	}
+
+	// Container-tools installs keep the cache behind a symlink into the container volume
+	if paths.IsContainerInstall() {
+		checks = append(checks, runHealthCheck("cache_symlink", func() (string, string) {
+			return checkSymlinkTarget(cacheDir)
+		}))
+	}
+
+	status := healthHealthy
+	for _, check := range checks {
+		if healthRank[check.Status] > healthRank[status] {
+			status = check.Status
+		}
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// checkIndexAge reports the cached index as degraded once it is more than
+// twice its TTL old, i.e. refreshes have been failing for a full TTL.
+func (s *Server) checkIndexAge() (string, string) {
+	status := s.indexManager.GetIndexStatus()
+	if !status.IsCached {
+		return healthDegraded, "no cached index file"
+	}
+
+	age := time.Duration(status.AgeSeconds) * time.Second
+	limit := 2 * time.Duration(status.TTLSeconds) * time.Second
+	if age >= limit {
+		return healthDegraded, fmt.Sprintf("index is %s old, limit %s (2x TTL)", age, limit)
+	}
+	return healthHealthy, fmt.Sprintf("index is %s old", age)
+}
+
+// checkDirWritable reports dir as unhealthy unless a file can be created in it.
+func checkDirWritable(dir string) (string, string) {
+	testFile := filepath.Join(dir, fmt.Sprintf(".healthcheck-%d", os.Getpid()))
+	f, err := os.Create(testFile)
+	if err != nil {
+		return healthUnhealthy, fmt.Sprintf("cache directory %s not writable: %v", dir, err)
+	}
+	f.Close()
+	os.Remove(testFile)
+	return healthHealthy, fmt.Sprintf("cache directory %s writable", dir)
+}
+
+// checkSymlinkTarget reports path as unhealthy if it is a symlink whose
+// target does not exist. A plain directory passes.
+func checkSymlinkTarget(path string) (string, string) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return healthUnhealthy, fmt.Sprintf("cache directory %s missing: %v", path, err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return healthHealthy, fmt.Sprintf("%s is not a symlink", path)
+	}
+
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return healthUnhealthy, fmt.Sprintf("symlink %s target missing: %v", path, err)
+	}
+	return healthHealthy, fmt.Sprintf("symlink %s -> %s", path, target)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// healthChecksByName indexes a p2kb_healthcheck result's checks by name.
+func healthChecksByName(t *testing.T, result map[string]interface{}) map[string]map[string]interface{} {
+	t.Helper()
+	checks, _ := result["checks"].([]interface{})
+	byName := make(map[string]map[string]interface{}, len(checks))
+	for _, c := range checks {
+		check, _ := c.(map[string]interface{})
+		byName[check["name"].(string)] = check
+	}
+	return byName
+}
+
+func TestHandleHealthcheckFreshServer(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+
+	result := extractResultMap(t, srv.handleHealthcheck(1, nil))
+	if result["status"] != healthDegraded {
+		t.Errorf("status = %v, want degraded before anything is loaded", result["status"])
+	}
+
+	checks := healthChecksByName(t, result)
+	want := map[string]string{
+		"index_loaded":       healthDegraded,
+		"index_age":          healthDegraded,
+		"cache_dir_writable": healthHealthy,
+		"memory_cache":       healthHealthy,
+		"obex_index_loaded":  healthDegraded,
+	}
+	for name, status := range want {
+		check, ok := checks[name]
+		if !ok {
+			t.Errorf("missing check %s", name)
+			continue
+		}
+		if check["status"] != status {
+			t.Errorf("%s status = %v (%v), want %s", name, check["status"], check["message"], status)
+		}
+		if _, ok := check["latency_ms"].(float64); !ok {
+			t.Errorf("%s latency_ms = %v, want a number", name, check["latency_ms"])
+		}
+	}
+}
+
+func TestHandleHealthcheckAfterIndexLoad(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+
+	if err := srv.indexManager.EnsureIndex(); err != nil {
+		t.Fatalf("EnsureIndex: %v", err)
+	}
+
+	checks := healthChecksByName(t, extractResultMap(t, srv.handleHealthcheck(1, nil)))
+	if checks["index_loaded"]["status"] != healthHealthy {
+		t.Errorf("index_loaded = %v, want healthy", checks["index_loaded"])
+	}
+	if checks["index_age"]["status"] != healthHealthy {
+		t.Errorf("index_age = %v, want healthy for a just-cached index", checks["index_age"])
+	}
+	if checks["memory_cache"]["status"] != healthDegraded {
+		t.Errorf("memory_cache = %v, want degraded with nothing cached", checks["memory_cache"])
+	}
+}
+
+func TestCheckDirWritable(t *testing.T) {
+	dir := t.TempDir()
+	if status, msg := checkDirWritable(dir); status != healthHealthy {
+		t.Errorf("checkDirWritable(temp dir) = %s (%s), want healthy", status, msg)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("test file left behind: %v", entries)
+	}
+
+	if status, _ := checkDirWritable(filepath.Join(dir, "missing")); status != healthUnhealthy {
+		t.Errorf("checkDirWritable(missing dir) = %s, want unhealthy", status)
+	}
+}
+
+func TestCheckSymlinkTarget(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "volume")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "cache")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	dangling := filepath.Join(dir, "dangling")
+	if err := os.Symlink(filepath.Join(dir, "gone"), dangling); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{target, healthHealthy},
+		{link, healthHealthy},
+		{dangling, healthUnhealthy},
+		{filepath.Join(dir, "missing"), healthUnhealthy},
+	}
+	for _, tt := range tests {
+		if status, msg := checkSymlinkTarget(tt.path); status != tt.want {
+			t.Errorf("checkSymlinkTarget(%s) = %s (%s), want %s", filepath.Base(tt.path), status, msg, tt.want)
+		}
+	}
+}
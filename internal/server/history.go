@@ -0,0 +1,37 @@
+package server
+
+import "sync"
+
+// recentKeysLimit is how many recently served keys the server remembers.
+const recentKeysLimit = 20
+
+// recentKeys remembers the keys p2kb_get served most recently, oldest first,
+// so auto_select can favour the area a session is working in. The zero value
+// is ready to use.
+type recentKeys struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+// add records key as the most recent, moving it to the end if already present.
+func (r *recentKeys) add(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, k := range r.keys {
+		if k == key {
+			r.keys = append(r.keys[:i], r.keys[i+1:]...)
+			break
+		}
+	}
+	r.keys = append(r.keys, key)
+	if len(r.keys) > recentKeysLimit {
+		r.keys = r.keys[len(r.keys)-recentKeysLimit:]
+	}
+}
+
+// list returns the remembered keys, oldest first.
+func (r *recentKeys) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.keys...)
+}
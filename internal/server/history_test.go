@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/index"
+)
+
+func TestRecentKeys(t *testing.T) {
+	var r recentKeys
+	r.add("a")
+	r.add("b")
+	r.add("a")
+	if got, want := r.list(), []string{"b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("list() = %v, want %v (re-adding moves a key to the end)", got, want)
+	}
+
+	for i := 0; i < recentKeysLimit+5; i++ {
+		r.add(fmt.Sprintf("k%d", i))
+	}
+	got := r.list()
+	if len(got) != recentKeysLimit || got[len(got)-1] != fmt.Sprintf("k%d", recentKeysLimit+4) {
+		t.Errorf("list() has %d keys ending %s, want the newest %d", len(got), got[len(got)-1], recentKeysLimit)
+	}
+}
+
+// newAutoSelectServer serves two competing "add" keys from different
+// categories: p2kbPasm2Add lists two related instructions, p2kbSpin2Add one,
+// and p2kbPasm2Sub one.
+func newAutoSelectServer(t *testing.T) (*Server, func()) {
+	t.Helper()
+	files := map[string]interface{}{
+		"p2kbPasm2Add": map[string]interface{}{"path": "pasm2/add.yaml", "mtime": 1700000000},
+		"p2kbPasm2Sub": map[string]interface{}{"path": "pasm2/sub.yaml", "mtime": 1700000000},
+		"p2kbSpin2Add": map[string]interface{}{"path": "spin2/add.yaml", "mtime": 1700000000},
+		"p2kbSpin2Abs": map[string]interface{}{"path": "spin2/abs.yaml", "mtime": 1700000000},
+	}
+	categories := map[string]interface{}{
+		"pasm2_math": []string{"p2kbPasm2Add", "p2kbPasm2Sub"},
+		"spin2_math": []string{"p2kbSpin2Add", "p2kbSpin2Abs"},
+	}
+	related := map[string][]string{
+		"/pasm2/add.yaml": {"SUB", "ADDX"},
+		"/pasm2/sub.yaml": {"ADD"},
+		"/spin2/add.yaml": {"ABS"},
+		"/spin2/abs.yaml": {},
+	}
+	return newServerWithIndex(t, files, categories, func(w http.ResponseWriter, r *http.Request) {
+		keys, ok := related[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		body := "description: test\nrelated_instructions:\n"
+		for _, k := range keys {
+			body += "  - " + k + "\n"
+		}
+		_, _ = w.Write([]byte(body))
+	})
+}
+
+func TestAutoSelectMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		history    []string
+		top        index.MatchResult
+		runnerUp   index.MatchResult
+		wantKey    string
+		wantReason string
+	}{
+		{
+			name:       "clear lead",
+			top:        index.MatchResult{Key: "p2kbSpin2Add", Score: 0.8},
+			runnerUp:   index.MatchResult{Key: "p2kbPasm2Add", Score: 0.62},
+			wantKey:    "p2kbSpin2Add",
+			wantReason: autoSelectHighestScore,
+		},
+		{
+			name:       "richer docs",
+			top:        index.MatchResult{Key: "p2kbSpin2Add", Score: 0.8},
+			runnerUp:   index.MatchResult{Key: "p2kbPasm2Add", Score: 0.7},
+			wantKey:    "p2kbPasm2Add",
+			wantReason: autoSelectRicherDocs,
+		},
+		{
+			name:       "history beats richer docs",
+			history:    []string{"p2kbSpin2Abs"},
+			top:        index.MatchResult{Key: "p2kbPasm2Add", Score: 0.8},
+			runnerUp:   index.MatchResult{Key: "p2kbSpin2Add", Score: 0.7},
+			wantKey:    "p2kbSpin2Add",
+			wantReason: autoSelectHistory,
+		},
+		{
+			name:       "tie falls back to score",
+			top:        index.MatchResult{Key: "p2kbSpin2Add", Score: 0.8},
+			runnerUp:   index.MatchResult{Key: "p2kbPasm2Sub", Score: 0.75},
+			wantKey:    "p2kbSpin2Add",
+			wantReason: autoSelectHighestScore,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, cleanup := newAutoSelectServer(t)
+			defer cleanup()
+			for _, key := range tt.history {
+				if resp := srv.getContentWithRelated(context.Background(), 1, key, "", contentPage{}); resp.Error != nil {
+					t.Fatalf("seeding history with %s: %+v", key, resp.Error)
+				}
+			}
+
+			result := extractResultMap(t, srv.autoSelectMatch(context.Background(), 1, tt.top, tt.runnerUp, contentPage{}))
+			if result["key"] != tt.wantKey || result["auto_select_reason"] != tt.wantReason {
+				t.Errorf("picked %v (%v), want %s (%s)", result["key"], result["auto_select_reason"], tt.wantKey, tt.wantReason)
+			}
+			if result["auto_selected"] != true || !strings.Contains(result["content"].(string), "related_instructions") {
+				t.Errorf("result = %v, want auto_selected content", result)
+			}
+		})
+	}
+}
+
+func TestGetByQueries(t *testing.T) {
+	srv, cleanup := newAutoSelectServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		name      string
+		queries   string
+		wantKey   string
+		wantQuery string
+	}{
+		{"one query matches", `["zzz qqq", "pasm2 sub"]`, "p2kbPasm2Sub", "pasm2 sub"},
+		{"tie ranks by key", `["spin2 add", "pasm2 add"]`, "p2kbPasm2Add", "pasm2 add"},
+		{"exact key", `["add numbers", "p2kbSpin2Abs"]`, "p2kbSpin2Abs", "p2kbSpin2Abs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := srv.handleGet(context.Background(), 1, []byte(`{"queries": `+tt.queries+`}`))
+			if resp.Error != nil {
+				t.Fatalf("unexpected error: %s", resp.Error.Message)
+			}
+			result := extractResultMap(t, resp)
+			if result["type"] != "content" || result["key"] != tt.wantKey || result["matched_by_query"] != tt.wantQuery {
+				t.Errorf("type = %v, key = %v, matched_by_query = %v; want content, %s, %s",
+					result["type"], result["key"], result["matched_by_query"], tt.wantKey, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestMatchQueriesMerge(t *testing.T) {
+	srv, cleanup := newAutoSelectServer(t)
+	defer cleanup()
+
+	matches, matchedBy, err := srv.matchQueries([]string{"pasm2 sub", "pasm2 add"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// p2kbPasm2Add scores 0.65 for "pasm2 sub" but 1.15 for "pasm2 add"
+	want := []index.MatchResult{
+		{Key: "p2kbPasm2Add", Score: 1.15, Category: "pasm2_math"},
+		{Key: "p2kbPasm2Sub", Score: 1.15, Category: "pasm2_math"},
+		{Key: "p2kbSpin2Add", Score: 0.5, Category: "spin2_math"},
+	}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("matches = %v, want %v", matches, want)
+	}
+	wantBy := map[string]string{"p2kbPasm2Add": "pasm2 add", "p2kbPasm2Sub": "pasm2 sub", "p2kbSpin2Add": "pasm2 add"}
+	if !reflect.DeepEqual(matchedBy, wantBy) {
+		t.Errorf("matchedBy = %v, want %v", matchedBy, wantBy)
+	}
+}
+
+// barrierIndex is an IndexManager whose MatchQuery calls wait for each other:
+// none returns until n are running, or a timeout marks the calls serial.
+type barrierIndex struct {
+	IndexManager
+	n       int
+	mu      sync.Mutex
+	started int
+	all     chan struct{}
+	serial  bool
+}
+
+func (b *barrierIndex) MatchQuery(query string) ([]index.MatchResult, error) {
+	b.mu.Lock()
+	b.started++
+	if b.started == b.n {
+		close(b.all)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.all:
+	case <-time.After(2 * time.Second):
+		b.mu.Lock()
+		b.serial = true
+		b.mu.Unlock()
+	}
+	return b.IndexManager.MatchQuery(query)
+}
+
+func TestGetByQueriesRunsInParallel(t *testing.T) {
+	srv, cleanup := newAutoSelectServer(t)
+	defer cleanup()
+	barrier := &barrierIndex{IndexManager: srv.indexManager, n: 3, all: make(chan struct{})}
+	srv.indexManager = barrier
+
+	resp := srv.handleGet(context.Background(), 1, []byte(`{"queries": ["pasm2 sub", "spin2 add", "zzz qqq"]}`))
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	if barrier.serial {
+		t.Error("queries were matched one after another, not in parallel")
+	}
+}
+
+func TestGetByQueriesNoMatches(t *testing.T) {
+	srv, cleanup := newAutoSelectServer(t)
+	defer cleanup()
+
+	result := extractResultMap(t, srv.handleGet(context.Background(), 1, []byte(`{"queries": ["zzz qqq", "yyy www"]}`)))
+	if result["type"] != "no_matches" {
+		t.Fatalf("type = %v, want no_matches", result["type"])
+	}
+	if got := fmt.Sprint(result["queries"]); got != "[zzz qqq yyy www]" {
+		t.Errorf("queries = %s, want both queries", got)
+	}
+	if msg, _ := result["message"].(string); !strings.Contains(msg, `"zzz qqq", "yyy www"`) {
+		t.Errorf("message = %q, want both queries listed", msg)
+	}
+}
+
+func TestGetByQueriesInvalid(t *testing.T) {
+	srv, cleanup := newAutoSelectServer(t)
+	defer cleanup()
+
+	for _, args := range []string{
+		`{"query": "add", "queries": ["sub"]}`,
+		`{"queries": ["a", "b", "c", "d", "e", "f"]}`,
+		`{"queries": ["add", " "]}`,
+	} {
+		resp := srv.handleGet(context.Background(), 1, []byte(args))
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("%s: resp.Error = %+v, want -32602", args, resp.Error)
+		}
+	}
+}
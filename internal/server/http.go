@@ -0,0 +1,456 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transports the server can speak MCP over.
+const (
+	TransportStdio = "stdio" // One JSON-RPC message per line on stdin/stdout
+	TransportHTTP  = "http"  // POST /mcp for requests, Server-Sent Events on GET /mcp/events
+)
+
+// DefaultHTTPAddr is where the HTTP transport listens when P2KB_HTTP_ADDR is
+// unset: loopback only, so other hosts cannot reach the admin tools.
+const DefaultHTTPAddr = "127.0.0.1:8080"
+
+// maxHTTPRequestBytes caps a POST /mcp body, matching the stdio line limit.
+const maxHTTPRequestBytes = 1024 * 1024
+
+// sessionHeader names the HTTP transport session a request belongs to.
+const sessionHeader = "Mcp-Session-Id"
+
+// eventBuffer is how many notifications an SSE client may fall behind by
+// before further ones are dropped for it.
+const eventBuffer = 64
+
+// DefaultHTTPSessionTTL is how long an HTTP transport session may go without
+// a request or an open event stream before it is ended, when
+// P2KB_HTTP_SESSION_TTL_SECS is unset.
+const DefaultHTTPSessionTTL = 30 * time.Minute
+
+// SetTransport selects the transport Run serves on: TransportStdio (the
+// default) or TransportHTTP.
+func (s *Server) SetTransport(transport string) error {
+	switch transport {
+	case TransportStdio, TransportHTTP:
+		s.transport = transport
+		return nil
+	}
+	return fmt.Errorf("unknown transport %q (want %s or %s)", transport, TransportStdio, TransportHTTP)
+}
+
+// httpAddr returns the HTTP transport's listen address from settings or default.
+func (s *Server) httpAddr() string {
+	if addr := strings.TrimSpace(s.getenv("P2KB_HTTP_ADDR")); addr != "" {
+		return addr
+	}
+	return DefaultHTTPAddr
+}
+
+// parseHTTPSessionTTL returns a P2KB_HTTP_SESSION_TTL_SECS value, or
+// DefaultHTTPSessionTTL if it is unset or invalid.
+func parseHTTPSessionTTL(v string) time.Duration {
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return DefaultHTTPSessionTTL
+}
+
+// RunHTTP serves MCP over HTTP on addr until SIGTERM or SIGINT: JSON-RPC
+// requests and batches are POSTed to /mcp and answered in the response body,
+// and server-initiated notifications are streamed to the GET /mcp/events
+// clients of the session that caused them. When P2KB_HTTP_TOKEN is set, each request must carry it
+// as "Authorization: Bearer <token>"; without it, RunHTTP only listens on a
+// loopback address. Sessions idle for P2KB_HTTP_SESSION_TTL_SECS are ended.
+func (s *Server) RunHTTP(addr string) error {
+	if s.getenv("P2KB_HTTP_TOKEN") == "" && !isLoopbackAddr(addr) {
+		return fmt.Errorf("refusing to serve on %s without P2KB_HTTP_TOKEN; set a token or listen on 127.0.0.1", addr)
+	}
+
+	ctx, stop := s.start()
+	defer stop()
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	slog.Info("serving MCP over HTTP", "addr", listener.Addr().String())
+
+	events := newEventHub()
+	httpServer := &http.Server{Handler: s.httpHandler(events)}
+	httpServer.RegisterOnShutdown(events.close)
+	go events.expireIdle(ctx, parseHTTPSessionTTL(s.getenv("P2KB_HTTP_SESSION_TTL_SECS")))
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := getShutdownTimeout()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown timed out after %s with requests still in flight", timeout)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("shutting down gracefully")
+	return nil
+}
+
+// httpHandler returns the HTTP transport's routes, sending each client's
+// notifications to the event streams of its session in events.
+func (s *Server) httpHandler(events *eventHub) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /mcp", func(w http.ResponseWriter, r *http.Request) {
+		s.handleHTTPRequest(events, w, r)
+	})
+	mux.HandleFunc("DELETE /mcp", events.end)
+	mux.HandleFunc("GET /mcp/events", events.serve)
+	return rejectForeignOrigins(requireHTTPToken(s.getenv("P2KB_HTTP_TOKEN"), mux))
+}
+
+// isLoopbackAddr reports whether addr ("127.0.0.1:8080", "localhost:8080")
+// listens on loopback only. An address without a host (":8080") listens on
+// every interface.
+func isLoopbackAddr(addr string) bool {
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	return err == nil && tcpAddr.IP != nil && tcpAddr.IP.IsLoopback()
+}
+
+// isLoopbackOrigin reports whether origin, an Origin header, names a page
+// served from this machine.
+func isLoopbackOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// rejectForeignOrigins refuses requests a browser sends on behalf of a page
+// from another host - cross-site requests and DNS rebinding - with 403, and
+// POSTs whose body is not declared as JSON, which a page can send without a
+// CORS preflight, with 415. Clients that are not browsers send no Origin.
+func rejectForeignOrigins(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !isLoopbackOrigin(origin) {
+			http.Error(w, "forbidden origin", http.StatusForbidden)
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+				http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireHTTPToken rejects requests without "Authorization: Bearer <token>",
+// or passes every request through when token is empty.
+func requireHTTPToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleHTTPRequest answers one POSTed JSON-RPC request or batch through the
+// same dispatch as stdio. A notification, or a batch of only notifications,
+// gets 202 Accepted with no body. An initialize request without a session
+// starts one, named in the Mcp-Session-Id response header; requests sending
+// that header have their notifications streamed to the session's
+// GET /mcp/events clients, and to no one else.
+func (s *Server) handleHTTPRequest(events *eventHub, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPRequestBytes))
+	if err != nil {
+		writeHTTPResponse(w, http.StatusRequestEntityTooLarge, parseErrorResponse(err))
+		return
+	}
+
+	ctx := r.Context()
+	session := r.Header.Get(sessionHeader)
+	if session != "" {
+		if !events.touch(session) {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		ctx = withNotifier(ctx, func(v interface{}) { events.publish(session, v) })
+	}
+
+	var resp interface{}
+	if isBatch(body) {
+		var reqs []MCPRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			writeHTTPResponse(w, http.StatusBadRequest, parseErrorResponse(err))
+			return
+		}
+		resps := s.handleBatch(ctx, reqs)
+		switch {
+		case len(reqs) == 0:
+			// An empty batch is answered with one error, not an array
+			resp = resps[0]
+		case len(resps) > 0:
+			resp = resps
+		}
+	} else {
+		var req MCPRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeHTTPResponse(w, http.StatusBadRequest, parseErrorResponse(err))
+			return
+		}
+		if r := s.handleRequestContext(ctx, &req); r != nil {
+			resp = r
+			if req.Method == "initialize" && session == "" && r.Error == nil {
+				w.Header().Set(sessionHeader, events.newSession())
+			}
+		}
+	}
+
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeHTTPResponse(w, http.StatusOK, resp)
+}
+
+// parseErrorResponse is the JSON-RPC -32700 answer to a body that is not JSON.
+func parseErrorResponse(err error) *MCPResponse {
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		Error:   &MCPError{Code: -32700, Message: "Parse error", Data: err.Error()},
+	}
+}
+
+// writeHTTPResponse writes v as a JSON body with the given status.
+func writeHTTPResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to write HTTP response", "error", err)
+	}
+}
+
+// eventHub keeps the client sessions of the HTTP transport and streams each
+// session's notifications to its clients of GET /mcp/events.
+type eventHub struct {
+	mu       sync.Mutex
+	sessions map[string]*hubSession // Session ID -> its state
+	done     chan struct{}          // Closed on shutdown, ending every stream
+
+	closeOnce sync.Once
+}
+
+// hubSession is one HTTP transport session.
+type hubSession struct {
+	streams  map[chan []byte]struct{} // Its open event streams
+	lastSeen time.Time                // Its last request, or when its last stream closed
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		sessions: make(map[string]*hubSession),
+		done:     make(chan struct{}),
+	}
+}
+
+// newSession starts a session and returns its ID, a random 128-bit hex
+// string that other clients cannot guess.
+func (h *eventHub) newSession() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	id := hex.EncodeToString(b[:])
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions[id] = &hubSession{streams: make(map[chan []byte]struct{}), lastSeen: time.Now()}
+	return id
+}
+
+// touch reports whether session was started and has not ended, and if so
+// marks it as seen now.
+func (h *eventHub) touch(session string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sess, ok := h.sessions[session]
+	if ok {
+		sess.lastSeen = time.Now()
+	}
+	return ok
+}
+
+// expire ends the sessions with no open event stream that were last seen
+// before cutoff, so a client that went away without DELETE /mcp does not keep
+// its session forever. It returns how many it ended.
+func (h *eventHub) expire(cutoff time.Time) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for id, sess := range h.sessions {
+		if len(sess.streams) == 0 && sess.lastSeen.Before(cutoff) {
+			delete(h.sessions, id)
+			n++
+		}
+	}
+	return n
+}
+
+// expireIdle ends sessions idle for longer than ttl until ctx is done,
+// checking every ttl or every minute, whichever is sooner.
+func (h *eventHub) expireIdle(ctx context.Context, ttl time.Duration) {
+	ticker := time.NewTicker(min(ttl, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if n := h.expire(now.Add(-ttl)); n > 0 {
+				slog.Debug("expired idle HTTP sessions", "count", n, "ttl", ttl)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// end ends the session named by a DELETE /mcp request's Mcp-Session-Id
+// header, closing its event streams.
+func (h *eventHub) end(w http.ResponseWriter, r *http.Request) {
+	session := r.Header.Get(sessionHeader)
+	h.mu.Lock()
+	sess, ok := h.sessions[session]
+	delete(h.sessions, session)
+	h.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	for ch := range sess.streams {
+		close(ch)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publish sends v to the event streams of session. A stream too far behind
+// misses it rather than holding up the request that sent it.
+func (h *eventHub) publish(session string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("failed to encode notification", "error", err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sess, ok := h.sessions[session]
+	if !ok {
+		return
+	}
+	for ch := range sess.streams {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+// close ends every event stream, so HTTP shutdown does not wait on them.
+func (h *eventHub) close() {
+	h.closeOnce.Do(func() { close(h.done) })
+}
+
+// serve streams the notifications of the session named by the request's
+// Mcp-Session-Id header to one client as Server-Sent Events, until the client
+// disconnects, the session ends or the server shuts down.
+func (h *eventHub) serve(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	session := r.Header.Get(sessionHeader)
+	if session == "" {
+		http.Error(w, sessionHeader+" header required; initialize starts a session", http.StatusBadRequest)
+		return
+	}
+	ch := make(chan []byte, eventBuffer)
+	h.mu.Lock()
+	sess, ok := h.sessions[session]
+	if ok {
+		sess.streams[ch] = struct{}{}
+	}
+	h.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	defer func() {
+		h.mu.Lock()
+		delete(sess.streams, ch)
+		sess.lastSeen = time.Now()
+		h.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case data, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-h.done:
+			return
+		}
+	}
+}
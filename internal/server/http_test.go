@@ -0,0 +1,403 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newHTTPTestServer serves srv's HTTP transport on a random local port.
+func newHTTPTestServer(t *testing.T, srv *Server) *httptest.Server {
+	t.Helper()
+	events := newEventHub()
+	ts := httptest.NewServer(srv.httpHandler(events))
+	t.Cleanup(func() {
+		events.close()
+		ts.Close()
+	})
+	return ts
+}
+
+// postMCP POSTs body to /mcp and returns the response status and body.
+func postMCP(t *testing.T, ts *httptest.Server, body, token string) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest("POST", ts.URL+"/mcp", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("POST /mcp: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp.StatusCode, data
+}
+
+func TestHTTPToolsList(t *testing.T) {
+	ts := newHTTPTestServer(t, New("1.0.0"))
+
+	status, body := postMCP(t, ts, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", status, body)
+	}
+	var resp struct {
+		ID     float64 `json:"id"`
+		Result struct {
+			Tools []Tool `json:"tools"`
+		} `json:"result"`
+		Error *MCPError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	if resp.Error != nil || resp.ID != 1 {
+		t.Fatalf("response = %s, want id 1 with no error", body)
+	}
+	if got, want := len(resp.Result.Tools), len(GetToolDefinitions()); got != want || got == 0 {
+		t.Errorf("got %d tools, want %d", got, want)
+	}
+}
+
+func TestHTTPBatchAndNotification(t *testing.T) {
+	ts := newHTTPTestServer(t, New("1.0.0"))
+
+	status, body := postMCP(t, ts, `[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":2,"method":"ping"}]`, "")
+	var resps []MCPResponse
+	if err := json.Unmarshal(body, &resps); status != http.StatusOK || err != nil {
+		t.Fatalf("batch: status %d, body %s", status, body)
+	}
+	if len(resps) != 2 || resps[0].ID != float64(1) || resps[1].ID != float64(2) {
+		t.Errorf("batch responses = %s, want ids 1 and 2", body)
+	}
+
+	// A notification gets no body
+	if status, body := postMCP(t, ts, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, ""); status != http.StatusAccepted || len(body) != 0 {
+		t.Errorf("notification: status %d, body %q; want 202 and no body", status, body)
+	}
+
+	// Malformed JSON is a parse error
+	status, body = postMCP(t, ts, `{"jsonrpc":`, "")
+	var resp MCPResponse
+	if err := json.Unmarshal(body, &resp); status != http.StatusBadRequest || err != nil || resp.Error == nil || resp.Error.Code != -32700 {
+		t.Errorf("parse error: status %d, body %s; want 400 with -32700", status, body)
+	}
+}
+
+func TestHTTPToken(t *testing.T) {
+	t.Setenv("P2KB_HTTP_TOKEN", "s3cret")
+	ts := newHTTPTestServer(t, New("1.0.0"))
+
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	for _, token := range []string{"", "wrong"} {
+		if status, _ := postMCP(t, ts, ping, token); status != http.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, want 401", token, status)
+		}
+	}
+	if status, body := postMCP(t, ts, ping, "s3cret"); status != http.StatusOK {
+		t.Errorf("right token: status = %d, want 200 (body %s)", status, body)
+	}
+
+	resp, err := ts.Client().Get(ts.URL + "/mcp/events")
+	if err != nil {
+		t.Fatalf("GET /mcp/events: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("events without token: status = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestHTTPRejectsForeignOrigins(t *testing.T) {
+	ts := newHTTPTestServer(t, New("1.0.0"))
+
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	for origin, want := range map[string]int{
+		"https://evil.example":  http.StatusForbidden,
+		"http://192.168.1.20":   http.StatusForbidden,
+		"http://localhost:3000": http.StatusOK,
+		"http://127.0.0.1:8080": http.StatusOK,
+		"http://[::1]:8080":     http.StatusOK,
+	} {
+		req, _ := http.NewRequest("POST", ts.URL+"/mcp", strings.NewReader(ping))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", origin)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("POST /mcp: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("Origin %s: status = %d, want %d", origin, resp.StatusCode, want)
+		}
+	}
+
+	// A form or text/plain POST needs no CORS preflight, so it is refused
+	resp, err := ts.Client().Post(ts.URL+"/mcp", "text/plain", strings.NewReader(ping))
+	if err != nil {
+		t.Fatalf("POST /mcp: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain POST: status = %d, want 415", resp.StatusCode)
+	}
+}
+
+func TestRunHTTPNeedsTokenOffLoopback(t *testing.T) {
+	t.Setenv("P2KB_HTTP_TOKEN", "")
+	for addr, want := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"[::1]:8080":     true,
+		"localhost:8080": true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"192.0.2.1:8080": false,
+	} {
+		if got := isLoopbackAddr(addr); got != want {
+			t.Errorf("isLoopbackAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+
+	err := New("1.0.0").RunHTTP("0.0.0.0:0")
+	if err == nil || !strings.Contains(err.Error(), "P2KB_HTTP_TOKEN") {
+		t.Errorf("RunHTTP on every interface without a token = %v, want a refusal", err)
+	}
+}
+
+// startSession sends initialize and returns the session ID the response names.
+func startSession(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+	req, _ := http.NewRequest("POST", ts.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	resp.Body.Close()
+	session := resp.Header.Get(sessionHeader)
+	if session == "" {
+		t.Fatalf("initialize response has no %s header", sessionHeader)
+	}
+	return session
+}
+
+// openEvents opens session's event stream and returns the notifications it
+// delivers, one per data line.
+func openEvents(t *testing.T, ts *httptest.Server, session string) <-chan MCPNotification {
+	t.Helper()
+	req, _ := http.NewRequest("GET", ts.URL+"/mcp/events", nil)
+	req.Header.Set(sessionHeader, session)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET /mcp/events: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET /mcp/events: status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	notes := make(chan MCPNotification, 16)
+	go func() {
+		defer resp.Body.Close()
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), "data: "); ok {
+				var note MCPNotification
+				if json.Unmarshal([]byte(data), &note) == nil {
+					notes <- note
+				}
+			}
+		}
+	}()
+	return notes
+}
+
+func TestHTTPEventsGoToTheirSession(t *testing.T) {
+	t.Setenv("P2KB_KEEPALIVE_INTERVAL_SECS", "1")
+	files := map[string]interface{}{
+		"p2kbSlow": map[string]interface{}{"path": "slow.yaml"},
+	}
+	srv, cleanup := newServerWithIndex(t, files, map[string]interface{}{}, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1300 * time.Millisecond)
+		_, _ = w.Write([]byte("slow: content\n"))
+	})
+	defer cleanup()
+	ts := newHTTPTestServer(t, srv)
+
+	mine, other := startSession(t, ts), startSession(t, ts)
+	if mine == other {
+		t.Fatal("two initialize calls got the same session")
+	}
+	myEvents, otherEvents := openEvents(t, ts, mine), openEvents(t, ts, other)
+
+	req, _ := http.NewRequest("POST", ts.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"p2kb_get","arguments":{"query":"p2kbSlow"}}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(sessionHeader, mine)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("tools/call: %v", err)
+	}
+	resp.Body.Close()
+
+	select {
+	case note := <-myEvents:
+		if note.Method != "$/keepalive" {
+			t.Errorf("notification = %+v, want a keepalive", note)
+		}
+	case <-time.After(time.Second):
+		t.Error("no keepalive reached the calling session")
+	}
+	select {
+	case note := <-otherEvents:
+		t.Errorf("another session received %+v", note)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestHTTPSessions(t *testing.T) {
+	ts := newHTTPTestServer(t, New("1.0.0"))
+
+	get := func(session string) int {
+		req, _ := http.NewRequest("GET", ts.URL+"/mcp/events", nil)
+		if session != "" {
+			req.Header.Set(sessionHeader, session)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("GET /mcp/events: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := get(""); status != http.StatusBadRequest {
+		t.Errorf("events without a session: status = %d, want 400", status)
+	}
+	if status := get("made-up"); status != http.StatusNotFound {
+		t.Errorf("events for an unknown session: status = %d, want 404", status)
+	}
+
+	session := startSession(t, ts)
+	req, _ := http.NewRequest("DELETE", ts.URL+"/mcp", nil)
+	req.Header.Set(sessionHeader, session)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("DELETE /mcp: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE /mcp: status = %d, want 204", resp.StatusCode)
+	}
+	if status := get(session); status != http.StatusNotFound {
+		t.Errorf("events for an ended session: status = %d, want 404", status)
+	}
+
+	req, _ = http.NewRequest("POST", ts.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(sessionHeader, session)
+	resp, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("POST /mcp: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request in an ended session: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestHTTPIdleSessionsExpire(t *testing.T) {
+	events := newEventHub()
+	ts := httptest.NewServer(New("1.0.0").httpHandler(events))
+	t.Cleanup(func() {
+		events.close()
+		ts.Close()
+	})
+
+	idle, streaming := startSession(t, ts), startSession(t, ts)
+	openEvents(t, ts, streaming)
+
+	if n := events.expire(time.Now().Add(-time.Minute)); n != 0 {
+		t.Errorf("expire before the TTL ended %d sessions, want 0", n)
+	}
+	if n := events.expire(time.Now().Add(time.Minute)); n != 1 {
+		t.Errorf("expire after the TTL ended %d sessions, want 1", n)
+	}
+
+	ping := func(session string) int {
+		req, _ := http.NewRequest("POST", ts.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(sessionHeader, session)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("POST /mcp: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := ping(idle); status != http.StatusNotFound {
+		t.Errorf("request in an expired session: status = %d, want 404", status)
+	}
+	if status := ping(streaming); status != http.StatusOK {
+		t.Errorf("request in a session with an open stream: status = %d, want 200", status)
+	}
+
+	for v, want := range map[string]time.Duration{
+		"":    DefaultHTTPSessionTTL,
+		"0":   DefaultHTTPSessionTTL,
+		"abc": DefaultHTTPSessionTTL,
+		"90":  90 * time.Second,
+	} {
+		if got := parseHTTPSessionTTL(v); got != want {
+			t.Errorf("parseHTTPSessionTTL(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestHTTPSettingsOverrideEnvironment(t *testing.T) {
+	t.Setenv("P2KB_HTTP_ADDR", "")
+	t.Setenv("P2KB_HTTP_TOKEN", "")
+	srv := New("1.0.0")
+	if addr := srv.httpAddr(); addr != DefaultHTTPAddr {
+		t.Errorf("httpAddr() = %q, want %q", addr, DefaultHTTPAddr)
+	}
+
+	srv.settings.Store("P2KB_HTTP_ADDR", "127.0.0.1:9090")
+	srv.settings.Store("P2KB_HTTP_TOKEN", "s3cret")
+	if addr := srv.httpAddr(); addr != "127.0.0.1:9090" {
+		t.Errorf("httpAddr() = %q, want the overridden address", addr)
+	}
+	ts := newHTTPTestServer(t, srv)
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	if status, _ := postMCP(t, ts, ping, ""); status != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d, want 401", status)
+	}
+	if status, body := postMCP(t, ts, ping, "s3cret"); status != http.StatusOK {
+		t.Errorf("overridden token: status = %d, want 200 (body %s)", status, body)
+	}
+}
+
+func TestSetTransport(t *testing.T) {
+	srv := New("1.0.0")
+	for _, transport := range []string{TransportStdio, TransportHTTP} {
+		if err := srv.SetTransport(transport); err != nil {
+			t.Errorf("SetTransport(%q) = %v", transport, err)
+		}
+	}
+	if err := srv.SetTransport("websocket"); err == nil {
+		t.Error("SetTransport(websocket) accepted an unknown transport")
+	}
+}
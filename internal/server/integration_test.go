@@ -0,0 +1,245 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/index"
+	"github.com/ironsheep/p2kb-mcp/internal/testdata"
+)
+
+// Integration tests: drive a complete server over a stdio-style pipe with
+// newline-delimited JSON-RPC, the way an MCP client does.
+
+// mcpSession is a client connected to a running server through io.Pipe.
+type mcpSession struct {
+	t      *testing.T
+	stdin  *io.PipeWriter
+	stdout *bufio.Reader
+	nextID int
+}
+
+// startMCPSession starts srv's request loop on a pair of pipes. The index is
+// served from the p2kb-index.json fixture and the OBEX index is pre-seeded on
+// disk, so no request leaves the test process.
+func startMCPSession(t *testing.T) *mcpSession {
+	t.Helper()
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	if _, err := w.Write(testdata.MustGetFixture("p2kb-index.json")); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	idxSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(gz.Bytes())
+	}))
+	origIdx := index.IndexURL
+	index.IndexURL = idxSrv.URL
+
+	t.Setenv("P2KB_CACHE_DIR", t.TempDir())
+	seedOBEXObject(t, "2811", "obexObjectValid.yaml")
+
+	srv := New("test")
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.serve(ctx, inReader, outWriter) }()
+
+	t.Cleanup(func() {
+		cancel()
+		_ = inWriter.Close()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("serve() = %v, want nil on shutdown", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Error("serve() did not return after shutdown")
+		}
+		idxSrv.Close()
+		index.IndexURL = origIdx
+	})
+
+	return &mcpSession{t: t, stdin: inWriter, stdout: bufio.NewReader(outReader)}
+}
+
+// call sends one request and returns its response, failing the test if the
+// response does not arrive or carries a different id.
+func (s *mcpSession) call(method string, params interface{}) *MCPResponse {
+	s.t.Helper()
+	s.nextID++
+
+	req := map[string]interface{}{"jsonrpc": "2.0", "id": s.nextID, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	line, err := json.Marshal(req)
+	if err != nil {
+		s.t.Fatalf("marshal request: %v", err)
+	}
+	go func() { _, _ = s.stdin.Write(append(line, '\n')) }()
+
+	type readResult struct {
+		line string
+		err  error
+	}
+	read := make(chan readResult, 1)
+	go func() {
+		line, err := s.stdout.ReadString('\n')
+		read <- readResult{line, err}
+	}()
+
+	var got readResult
+	select {
+	case got = <-read:
+	case <-time.After(5 * time.Second):
+		s.t.Fatalf("%s: no response within 5s", method)
+	}
+	if got.err != nil {
+		s.t.Fatalf("%s: reading response: %v", method, got.err)
+	}
+
+	var resp MCPResponse
+	if err := json.Unmarshal([]byte(got.line), &resp); err != nil {
+		s.t.Fatalf("%s: response %q is not JSON-RPC: %v", method, got.line, err)
+	}
+	if resp.JSONRPC != "2.0" {
+		s.t.Errorf("%s: jsonrpc = %q, want 2.0", method, resp.JSONRPC)
+	}
+	if id, ok := resp.ID.(float64); !ok || int(id) != s.nextID {
+		s.t.Fatalf("%s: response id = %v, want %d", method, resp.ID, s.nextID)
+	}
+	return &resp
+}
+
+// callTool sends tools/call for name with arguments.
+func (s *mcpSession) callTool(name string, arguments map[string]interface{}) *MCPResponse {
+	s.t.Helper()
+	return s.call("tools/call", map[string]interface{}{"name": name, "arguments": arguments})
+}
+
+// toolResult decodes the JSON document in a tools/call response's first text
+// content block, as received over the wire.
+func toolResult(t *testing.T, resp *MCPResponse) map[string]interface{} {
+	t.Helper()
+	if resp.Error != nil {
+		t.Fatalf("tools/call returned error: %+v", resp.Error)
+	}
+
+	raw, err := json.Marshal(resp.Result)
+	if err != nil {
+		t.Fatalf("re-marshal result: %v", err)
+	}
+	var wire struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(raw, &wire); err != nil || len(wire.Content) == 0 {
+		t.Fatalf("result %s has no content blocks (err: %v)", raw, err)
+	}
+	if wire.Content[0].Type != "text" {
+		t.Errorf("content[0].type = %q, want text", wire.Content[0].Type)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(wire.Content[0].Text), &result); err != nil {
+		t.Fatalf("content text is not JSON: %v", err)
+	}
+	return result
+}
+
+func TestIntegrationInitialize(t *testing.T) {
+	session := startMCPSession(t)
+
+	resp := session.call("initialize", map[string]interface{}{
+		"protocolVersion": "2024-11-05",
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "integration-test", "version": "1.0"},
+	})
+	if resp.Error != nil {
+		t.Fatalf("initialize returned error: %+v", resp.Error)
+	}
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("result = %T, want object", resp.Result)
+	}
+	if result["protocolVersion"] != "2024-11-05" {
+		t.Errorf("protocolVersion = %v, want the client's 2024-11-05", result["protocolVersion"])
+	}
+}
+
+func TestIntegrationToolsList(t *testing.T) {
+	session := startMCPSession(t)
+
+	resp := session.call("tools/list", nil)
+	if resp.Error != nil {
+		t.Fatalf("tools/list returned error: %+v", resp.Error)
+	}
+	result, _ := resp.Result.(map[string]interface{})
+	tools, _ := result["tools"].([]interface{})
+	if want := len(GetToolDefinitions()); len(tools) != want {
+		t.Fatalf("got %d tools, want %d", len(tools), want)
+	}
+	for _, tool := range tools {
+		def, _ := tool.(map[string]interface{})
+		if def["name"] == "" || def["inputSchema"] == nil {
+			t.Errorf("tool %v lacks a name or inputSchema", def)
+		}
+	}
+}
+
+func TestIntegrationVersion(t *testing.T) {
+	session := startMCPSession(t)
+
+	result := toolResult(t, session.callTool("p2kb_version", map[string]interface{}{}))
+	if result["mcp_version"] != "test" {
+		t.Errorf("mcp_version = %v, want test", result["mcp_version"])
+	}
+}
+
+func TestIntegrationFindCategories(t *testing.T) {
+	session := startMCPSession(t)
+
+	result := toolResult(t, session.callTool("p2kb_find", map[string]interface{}{}))
+	if result["type"] != "categories" {
+		t.Fatalf("type = %v, want categories", result["type"])
+	}
+
+	categories, _ := result["categories"].([]interface{})
+	if len(categories) != 2 {
+		t.Fatalf("categories = %v, want the fixture's 2", result["categories"])
+	}
+	first, _ := categories[0].(map[string]interface{})
+	if first["name"] != "pasm2_math" || first["count"] != float64(2) {
+		t.Errorf("first category = %v, want pasm2_math with 2 keys", first)
+	}
+	if result["total_entries"] != float64(5) {
+		t.Errorf("total_entries = %v, want 5", result["total_entries"])
+	}
+}
+
+func TestIntegrationUnknownTool(t *testing.T) {
+	session := startMCPSession(t)
+
+	resp := session.callTool("p2kb_does_not_exist", map[string]interface{}{})
+	if resp.Error == nil {
+		t.Fatalf("expected an error for an unknown tool, got %v", resp.Result)
+	}
+	if resp.Error.Code != -32601 {
+		t.Errorf("error code = %d, want -32601", resp.Error.Code)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// DefaultKeepaliveInterval is how often a running tool call sends a
+// $/keepalive notification, so clients with request timeouts don't give up
+// during long OBEX downloads or index fetches.
+const DefaultKeepaliveInterval = 5 * time.Second
+
+// fastTool lists tools that never touch the network and finish well under a
+// second; they get no keepalive goroutine.
+var fastTool = map[string]bool{
+	"p2kb_version":         true,
+	"p2kb_settings":        true,
+	"p2kb_pin":             true,
+	"p2kb_unpin":           true,
+	"p2kb_memory_pressure": true,
+	"p2kb_healthcheck":     true,
+	"p2kb_cache_dump":      true,
+	"p2kb_find_duplicates": true,
+}
+
+// startKeepalive sends a $/keepalive notification for request id, handled
+// under ctx, every interval until the returned stop function is called. It
+// does nothing when the client has no notification stream.
+func (s *Server) startKeepalive(ctx context.Context, id interface{}, interval time.Duration) (stop func()) {
+	notify := s.notifier(ctx)
+	if notify == nil {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				notify(&MCPNotification{
+					JSONRPC: "2.0",
+					Method:  "$/keepalive",
+					Params:  map[string]interface{}{"id": id},
+				})
+			}
+		}
+	}()
+
+	// Wait for the goroutine so no keepalive is written after the response
+	return func() {
+		cancel()
+		<-finished
+	}
+}
+
+// parseKeepaliveInterval returns v, in seconds, as the keepalive interval, or
+// the default if it is not a positive number.
+func parseKeepaliveInterval(v string) time.Duration {
+	if v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return DefaultKeepaliveInterval
+}
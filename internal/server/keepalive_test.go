@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartKeepalive(t *testing.T) {
+	srv := New("1.0.0")
+
+	var mu sync.Mutex
+	var sent []*MCPNotification
+	srv.notify = func(v interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, v.(*MCPNotification))
+	}
+
+	stop := srv.startKeepalive(context.Background(), 42, 10*time.Millisecond)
+	time.Sleep(55 * time.Millisecond)
+	stop()
+
+	mu.Lock()
+	count := len(sent)
+	mu.Unlock()
+	if count == 0 {
+		t.Fatal("no keepalives sent while running")
+	}
+	if sent[0].Method != "$/keepalive" {
+		t.Errorf("method = %q, want $/keepalive", sent[0].Method)
+	}
+	if params, _ := sent[0].Params.(map[string]interface{}); params["id"] != 42 {
+		t.Errorf("params = %v, want id 42", sent[0].Params)
+	}
+
+	// stop waits for the goroutine, so nothing is sent afterwards
+	time.Sleep(30 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != count {
+		t.Errorf("%d keepalives sent after stop", len(sent)-count)
+	}
+}
+
+func TestStartKeepaliveWithoutClient(t *testing.T) {
+	srv := New("1.0.0")
+	stop := srv.startKeepalive(context.Background(), 1, time.Millisecond)
+	stop()
+}
+
+func TestServeSendsKeepaliveForSlowTool(t *testing.T) {
+	t.Setenv("P2KB_KEEPALIVE_INTERVAL_SECS", "1")
+
+	files := map[string]interface{}{
+		"p2kbSlow": map[string]interface{}{"path": "slow.yaml"},
+	}
+	srv, cleanup := newServerWithIndex(t, files, map[string]interface{}{}, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1300 * time.Millisecond)
+		_, _ = w.Write([]byte("slow: content\n"))
+	})
+	defer cleanup()
+
+	in := strings.NewReader(
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"p2kb_get","arguments":{"query":"p2kbSlow"}}}` + "\n" +
+			`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"p2kb_version","arguments":{}}}` + "\n")
+	var out bytes.Buffer
+	if err := srv.serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("serve() = %v", err)
+	}
+
+	var keepalives, responses int
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("line %q is not valid JSON: %v", line, err)
+		}
+		if msg["method"] == "$/keepalive" {
+			keepalives++
+			if params, _ := msg["params"].(map[string]interface{}); params["id"] != float64(1) {
+				t.Errorf("keepalive params = %v, want id 1 (the slow call only)", msg["params"])
+			}
+			continue
+		}
+		responses++
+	}
+	if responses != 2 {
+		t.Errorf("got %d responses, want 2", responses)
+	}
+	if keepalives == 0 {
+		t.Error("no keepalive sent during a slow tool call")
+	}
+}
+
+func TestKeepaliveIntervalSetting(t *testing.T) {
+	srv := New("1.0.0")
+	interval := func() time.Duration {
+		return parseKeepaliveInterval(srv.getenv("P2KB_KEEPALIVE_INTERVAL_SECS"))
+	}
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", DefaultKeepaliveInterval},
+		{"2", 2 * time.Second},
+		{"0", DefaultKeepaliveInterval},
+		{"often", DefaultKeepaliveInterval},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("P2KB_KEEPALIVE_INTERVAL_SECS", tt.value)
+		if got := interval(); got != tt.want {
+			t.Errorf("P2KB_KEEPALIVE_INTERVAL_SECS=%q: interval = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	// A value set through p2kb_settings wins over the environment
+	t.Setenv("P2KB_KEEPALIVE_INTERVAL_SECS", "2")
+	if resp := callSettings(srv, map[string]interface{}{"key": "P2KB_KEEPALIVE_INTERVAL_SECS", "value": "7"}); resp.Error != nil {
+		t.Fatalf("p2kb_settings failed: %+v", resp.Error)
+	}
+	if got := interval(); got != 7*time.Second {
+		t.Errorf("interval after p2kb_settings = %v, want 7s", got)
+	}
+}
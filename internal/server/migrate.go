@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/paths"
+)
+
+// maxConflictsReported caps the files listed when p2kb_migrate_cache refuses
+// to overwrite newer ones.
+const maxConflictsReported = 20
+
+// handleMigrateCache implements p2kb_migrate_cache - copy an orphaned cache
+// into this server's cache directory, or a directory beneath it. The memory
+// cache is dropped afterwards, so entries are read from the copied files.
+func (s *Server) handleMigrateCache(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		SourceDir      string `json:"source_dir"`
+		DestinationDir string `json:"destination_dir"`
+		Force          bool   `json:"force"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+	if params.SourceDir == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "source_dir")
+	}
+	cacheRoot, err := filepath.Abs(s.cacheManager.CacheDir())
+	if err != nil {
+		return s.errorResponse(id, -32603, "Cache directory unavailable", err.Error())
+	}
+	if params.DestinationDir == "" {
+		params.DestinationDir = cacheRoot
+	}
+	if dest, err := filepath.Abs(paths.ExpandCacheDir(params.DestinationDir)); err != nil || !paths.Within(cacheRoot, dest) {
+		return s.errorResponse(id, -32602, "Invalid destination_dir", map[string]interface{}{
+			"destination_dir": params.DestinationDir,
+			"hint":            "destination_dir must be the server's cache directory " + cacheRoot + " or beneath it",
+		})
+	}
+
+	start := time.Now()
+	result, err := paths.MigrateCache(params.SourceDir, params.DestinationDir, params.Force)
+	if result.Files > 0 {
+		// Even a migration that failed part way may have replaced cached files
+		s.cacheManager.DropMemory()
+	}
+	if err != nil {
+		var newer *paths.ErrDestinationNewer
+		switch {
+		case errors.As(err, &newer):
+			files := newer.Files
+			if len(files) > maxConflictsReported {
+				files = files[:maxConflictsReported]
+			}
+			return s.errorResponse(id, -32000, "Destination has newer files", map[string]interface{}{
+				"error":       err.Error(),
+				"newer_files": files,
+				"count":       len(newer.Files),
+				"hint":        "Nothing was copied. Pass force: true to overwrite the newer files with the source's",
+			})
+		case errors.Is(err, os.ErrNotExist):
+			return s.errorResponse(id, -32602, "Source directory not found", map[string]interface{}{
+				"error":      err.Error(),
+				"source_dir": params.SourceDir,
+			})
+		}
+		return s.errorResponse(id, managerErrorCode(err), "Cache migration failed", err.Error())
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":                 "cache_migrated",
+		"source_dir":           paths.ExpandCacheDir(params.SourceDir),
+		"destination_dir":      paths.ExpandCacheDir(params.DestinationDir),
+		"migrated_files_count": result.Files,
+		"migrated_size_bytes":  result.Bytes,
+		"skipped_files_count":  result.Skipped,
+		"duration_ms":          time.Since(start).Milliseconds(),
+	})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHandleMigrateCache(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+
+	source := t.TempDir()
+	old := time.Unix(1700000000, 0)
+	for rel, content := range map[string]string{
+		"index/p2kb-index.json":   `{"files":{}}`,
+		"cache/p2kbPasm2Mov.yaml": "mnemonic: MOV\n",
+	} {
+		path := filepath.Join(source, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The destination defaults to the server's cache directory
+	dest := os.Getenv("P2KB_CACHE_DIR")
+	args, _ := json.Marshal(map[string]interface{}{"source_dir": source})
+	result := extractResultMap(t, srv.handleMigrateCache(1, args))
+	if result["type"] != "cache_migrated" || result["destination_dir"] != dest {
+		t.Fatalf("result = %v, want cache_migrated into %s", result, dest)
+	}
+	if result["migrated_files_count"] != float64(2) || result["migrated_size_bytes"] != float64(26) {
+		t.Errorf("migrated %v files, %v bytes; want 2 and 26", result["migrated_files_count"], result["migrated_size_bytes"])
+	}
+	if _, err := os.Stat(filepath.Join(dest, "cache", "p2kbPasm2Mov.yaml")); err != nil {
+		t.Errorf("migrated entry missing: %v", err)
+	}
+
+	// The destination's index is now newer than the source's
+	newer := old.Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dest, "index", "p2kb-index.json"), newer, newer); err != nil {
+		t.Fatal(err)
+	}
+	resp := srv.handleMigrateCache(2, args)
+	if resp.Error == nil || resp.Error.Code != -32000 {
+		t.Fatalf("error = %v, want -32000 for newer destination files", resp.Error)
+	}
+	args, _ = json.Marshal(map[string]interface{}{"source_dir": source, "force": true})
+	if result := extractResultMap(t, srv.handleMigrateCache(3, args)); result["migrated_files_count"] != float64(1) {
+		t.Errorf("forced migration copied %v files, want 1", result["migrated_files_count"])
+	}
+
+	// A destination outside the cache directory is refused
+	args, _ = json.Marshal(map[string]interface{}{"source_dir": source, "destination_dir": t.TempDir()})
+	if resp := srv.handleMigrateCache(4, args); resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("outside destination: error = %v, want -32602", resp.Error)
+	}
+
+	for _, args := range []string{`{}`, `{"source_dir": "/no/such/p2kb-cache"}`} {
+		if resp := srv.handleMigrateCache(4, json.RawMessage(args)); resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("%s: error = %v, want -32602", args, resp.Error)
+		}
+	}
+}
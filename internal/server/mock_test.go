@@ -0,0 +1,549 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+)
+
+// MockOBEXManager is an in-memory OBEXManager for handler tests. Objects is
+// the corpus that search, browse and the category and author listings are
+// derived from; Details backs GetObject. The error fields make the matching
+// calls fail. Calls records every method invoked, in order.
+type MockOBEXManager struct {
+	mu sync.Mutex
+
+	Objects        []obex.SearchResult
+	Details        map[string]*obex.OBEXObject
+	DownloadResult *obex.DownloadResult
+
+	IndexErr    error // EnsureIndex, Search, browse and listings
+	RefreshErr  error
+	DownloadErr error
+	LoadErr     error
+
+	// LoadGate, if non-nil, holds LoadAllObjects before each batch until it
+	// receives a value or the context is cancelled.
+	LoadGate chan struct{}
+	Loaded   int // Objects LoadAllObjects has loaded, reported by IndexCoverage
+
+	// SearchGate, if non-nil, holds Search until it receives a value.
+	SearchGate chan struct{}
+
+	Tags map[string][]string // Object ID -> normalized tags, for the tag searches
+
+	// PanicOn, if set, makes GetObject panic for that object ID.
+	PanicOn string
+
+	// DownloadBase, if set, replaces the download URL prefix GetDownloadURL
+	// puts before an object ID; DownloadURLs holds SetDownloadURL overrides.
+	DownloadBase string
+	DownloadURLs map[string]string
+
+	MirrorUsed string // Returned by LastMirrorUsed
+	Authed     bool   // Returned by AuthConfigured
+
+	Calls []string
+}
+
+var _ OBEXManager = (*MockOBEXManager)(nil)
+
+// record notes a call and returns the error configured for index-backed calls.
+func (m *MockOBEXManager) record(call string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls = append(m.Calls, call)
+	return m.IndexErr
+}
+
+// Called reports whether call (e.g. "Refresh" or "DownloadAndExtract(2811, out)")
+// was made.
+func (m *MockOBEXManager) Called(call string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.Calls {
+		if c == call {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockOBEXManager) EnsureIndex() error { return m.record("EnsureIndex") }
+
+func (m *MockOBEXManager) Refresh() (obex.RefreshStats, error) {
+	m.record("Refresh")
+	if m.RefreshErr != nil {
+		return obex.RefreshStats{}, m.RefreshErr
+	}
+	return obex.RefreshStats{Retained: len(m.Details)}, nil
+}
+
+func (m *MockOBEXManager) IsIndexLoaded() bool {
+	m.record("IsIndexLoaded")
+	return true
+}
+
+func (m *MockOBEXManager) GetTotalObjects() int {
+	m.record("GetTotalObjects")
+	return len(m.Objects)
+}
+
+func (m *MockOBEXManager) LocalObjectCount() int {
+	m.record("LocalObjectCount")
+	count := 0
+	for _, obj := range m.Objects {
+		if obj.Source == "local" {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *MockOBEXManager) GetObject(objectID string) (*obex.OBEXObject, error) {
+	if err := m.record("GetObject(" + objectID + ")"); err != nil {
+		return nil, err
+	}
+	if m.PanicOn != "" && objectID == m.PanicOn {
+		var obj *obex.OBEXObject
+		_ = obj.ObjectMetadata.Title // nil pointer dereference, as a bad decode would hit
+	}
+	obj, ok := m.Details[strings.TrimPrefix(strings.ToUpper(objectID), "OB")]
+	if !ok {
+		return nil, &errs.ErrKeyNotFound{Key: objectID}
+	}
+	return obj, nil
+}
+
+func (m *MockOBEXManager) GetDownloadURL(objectID string) string {
+	objectID = strings.TrimPrefix(strings.ToUpper(objectID), "OB")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if url, ok := m.DownloadURLs[objectID]; ok {
+		return url
+	}
+	base := m.DownloadBase
+	if base == "" {
+		base = "https://obex.example/download?obuid=OB"
+	}
+	return base + objectID
+}
+
+func (m *MockOBEXManager) SetDownloadURL(objectID, downloadURL string) error {
+	if err := m.record("SetDownloadURL(" + objectID + ", " + downloadURL + ")"); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.DownloadURLs == nil {
+		m.DownloadURLs = make(map[string]string)
+	}
+	m.DownloadURLs[strings.TrimPrefix(strings.ToUpper(objectID), "OB")] = downloadURL
+	return nil
+}
+
+func (m *MockOBEXManager) SearchByOBEXPageURL(pageURL string) (*obex.OBEXObject, error) {
+	if err := m.record("SearchByOBEXPageURL(" + pageURL + ")"); err != nil {
+		return nil, err
+	}
+	slug, _ := obex.OBEXPageSlug(pageURL)
+	for _, obj := range m.Details {
+		if objSlug, ok := obex.OBEXPageSlug(obj.ObjectMetadata.URLs.OBEXPage); ok && objSlug == slug {
+			return obj, nil
+		}
+	}
+	return nil, fmt.Errorf("no OBEX object has page URL: %s", pageURL)
+}
+
+func (m *MockOBEXManager) LoadAllObjects(ctx context.Context, batchSize int, pause time.Duration, progress func(loaded, failed int)) error {
+	if err := m.record("LoadAllObjects"); err != nil {
+		return err
+	}
+	for start := 0; start < len(m.Objects); start += batchSize {
+		if m.LoadGate != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-m.LoadGate:
+			}
+		}
+		if m.LoadErr != nil {
+			return m.LoadErr
+		}
+		m.mu.Lock()
+		m.Loaded = min(start+batchSize, len(m.Objects))
+		loaded := m.Loaded
+		m.mu.Unlock()
+		if progress != nil {
+			progress(loaded, 0)
+		}
+	}
+	return nil
+}
+
+func (m *MockOBEXManager) IndexCoverage() (loaded, total int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Loaded, len(m.Objects)
+}
+
+func (m *MockOBEXManager) DownloadAndExtract(objectID, targetDir string) (*obex.DownloadResult, error) {
+	m.record(fmt.Sprintf("DownloadAndExtract(%s, %s)", objectID, targetDir))
+	if m.DownloadErr != nil {
+		return nil, m.DownloadErr
+	}
+	return m.DownloadResult, nil
+}
+
+func (m *MockOBEXManager) Search(term, category, language, microcontroller string, limit int) ([]obex.SearchResult, error) {
+	return m.search(term, obex.SearchFilters{Category: category, Language: language, Microcontroller: microcontroller}, limit)
+}
+
+// search matches term against the titles and short descriptions of the
+// objects passing filters; the language filter is ignored.
+func (m *MockOBEXManager) search(term string, filters obex.SearchFilters, limit int) ([]obex.SearchResult, error) {
+	if err := m.record("Search(" + term + ")"); err != nil {
+		return nil, err
+	}
+	if m.SearchGate != nil {
+		<-m.SearchGate
+	}
+	var results []obex.SearchResult
+	for _, obj := range m.Objects {
+		if filters.Category != "" && !strings.EqualFold(obj.Category, filters.Category) {
+			continue
+		}
+		if filters.Subcategory != "" && !strings.EqualFold(obj.Subcategory, filters.Subcategory) {
+			continue
+		}
+		if !obex.MatchesMicrocontroller(obj.Microcontroller, filters.Microcontroller) {
+			continue
+		}
+		if !obex.MatchesFileSize(obj.FileSizeBytes, filters.MinFileSizeKB, filters.MaxFileSizeKB) {
+			continue
+		}
+		if strings.Contains(strings.ToLower(obj.Title+" "+obj.DescriptionShort), strings.ToLower(term)) {
+			obj.MatchType = "title"
+			results = append(results, obj)
+		}
+		if len(results) >= limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// SearchWithPhases is Search, reporting the second phase whenever the mock
+// finds fewer than limit objects, as the real search would have gone on to
+// fetch the rest.
+func (m *MockOBEXManager) SearchWithPhases(term string, filters obex.SearchFilters, limit int) ([]obex.SearchResult, int, error) {
+	results, err := m.search(term, filters, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(results) < limit {
+		return results, 2, nil
+	}
+	return results, 1, nil
+}
+
+func (m *MockOBEXManager) FindByTags(tokens []string, limit int) []obex.SearchResult {
+	m.record("FindByTags")
+	return nil
+}
+
+func (m *MockOBEXManager) SearchByTag(tag string, limit int) ([]obex.SearchResult, error) {
+	if err := m.record("SearchByTag(" + tag + ")"); err != nil {
+		return nil, err
+	}
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	return m.tagged(func(t string) bool { return t == tag }, limit), nil
+}
+
+func (m *MockOBEXManager) SearchByTagPrefix(prefix string, limit int) ([]obex.SearchResult, []string, error) {
+	if err := m.record("SearchByTagPrefix(" + prefix + ")"); err != nil {
+		return nil, nil, err
+	}
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	matched := make(map[string]bool)
+	results := m.tagged(func(t string) bool {
+		if strings.HasPrefix(t, prefix) {
+			matched[t] = true
+			return true
+		}
+		return false
+	}, limit)
+	var tags []string
+	for t := range matched {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	return results, tags, nil
+}
+
+// tagged returns the objects with a tag satisfying match, up to limit.
+func (m *MockOBEXManager) tagged(match func(tag string) bool, limit int) []obex.SearchResult {
+	results := []obex.SearchResult{}
+	for _, obj := range m.Objects {
+		hit := false
+		for _, t := range m.Tags[obj.ObjectID] {
+			hit = match(t) || hit
+		}
+		if hit && (limit <= 0 || len(results) < limit) {
+			obj.MatchType = "tag"
+			results = append(results, obj)
+		}
+	}
+	return results
+}
+
+func (m *MockOBEXManager) BrowseCategory(category string) ([]obex.SearchResult, error) {
+	if err := m.record("BrowseCategory(" + category + ")"); err != nil {
+		return nil, err
+	}
+	var results []obex.SearchResult
+	for _, obj := range m.Objects {
+		if category == "" || strings.EqualFold(obj.Category, category) {
+			results = append(results, obj)
+		}
+	}
+	return results, nil
+}
+
+func (m *MockOBEXManager) BrowseByAuthor(authorQuery string, limit int) ([]obex.SearchResult, error) {
+	if err := m.record("BrowseByAuthor(" + authorQuery + ")"); err != nil {
+		return nil, err
+	}
+	return m.browseByAuthors([]string{authorQuery}, limit), nil
+}
+
+func (m *MockOBEXManager) BrowseByAuthors(authorQueries []string, limit int) ([]obex.SearchResult, error) {
+	if err := m.record("BrowseByAuthors(" + strings.Join(authorQueries, ",") + ")"); err != nil {
+		return nil, err
+	}
+	return m.browseByAuthors(authorQueries, limit), nil
+}
+
+func (m *MockOBEXManager) browseByAuthors(authorQueries []string, limit int) []obex.SearchResult {
+	var results []obex.SearchResult
+	for _, obj := range m.Objects {
+		for _, query := range authorQueries {
+			if score := obex.AuthorMatchScore(query, obj.Author); score > 0.5 {
+				obj.MatchedQueries = append(obj.MatchedQueries, query)
+				obj.MatchScore = math.Max(obj.MatchScore, score)
+			}
+		}
+		if len(obj.MatchedQueries) > 0 {
+			obj.MatchType = "author"
+			obj.MatchedAuthorName = obj.Author
+			results = append(results, obj)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].MatchScore > results[j].MatchScore })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func (m *MockOBEXManager) BrowseSubcategory(category, subcategory string) ([]obex.SearchResult, error) {
+	objects, err := m.BrowseCategory(category)
+	if err != nil {
+		return nil, err
+	}
+	var results []obex.SearchResult
+	for _, obj := range objects {
+		if strings.EqualFold(obj.Subcategory, subcategory) {
+			results = append(results, obj)
+		}
+	}
+	return results, nil
+}
+
+func (m *MockOBEXManager) GetCategories() (map[string]int, error) {
+	if err := m.record("GetCategories"); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, obj := range m.Objects {
+		counts[strings.ToLower(obj.Category)]++
+	}
+	return counts, nil
+}
+
+func (m *MockOBEXManager) GetSubcategories(category string) map[string]int {
+	m.record("GetSubcategories(" + category + ")")
+	counts := make(map[string]int)
+	for _, obj := range m.Objects {
+		if strings.EqualFold(obj.Category, category) && obj.Subcategory != "" {
+			counts[strings.ToLower(obj.Subcategory)]++
+		}
+	}
+	return counts
+}
+
+func (m *MockOBEXManager) GetOverviewStats() (*obex.OverviewStats, error) {
+	if err := m.record("GetOverviewStats"); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	var sizes []int64
+	unknown := 0
+	for _, obj := range m.Objects {
+		if len(obj.Microcontroller) == 0 {
+			counts["unspecified"]++
+		}
+		for _, mc := range obj.Microcontroller {
+			counts[mc]++
+		}
+		if obj.FileSizeBytes >= 0 {
+			sizes = append(sizes, obj.FileSizeBytes)
+		} else {
+			unknown++
+		}
+	}
+	return &obex.OverviewStats{
+		MicrocontrollerDistribution: counts,
+		FileSizes:                   obex.SummarizeFileSizes(sizes, unknown),
+	}, nil
+}
+
+func (m *MockOBEXManager) GetAuthors() ([]obex.AuthorStats, error) {
+	if err := m.record("GetAuthors"); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, obj := range m.Objects {
+		counts[obj.Author]++
+	}
+	authors := make([]obex.AuthorStats, 0, len(counts))
+	for name, count := range counts {
+		authors = append(authors, obex.AuthorStats{Name: name, ObjectCount: count})
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].ObjectCount != authors[j].ObjectCount {
+			return authors[i].ObjectCount > authors[j].ObjectCount
+		}
+		return authors[i].Name < authors[j].Name
+	})
+	return authors, nil
+}
+
+func (m *MockOBEXManager) MatchAuthors(name string) ([]obex.AuthorStats, error) {
+	authors, err := m.GetAuthors()
+	if err != nil {
+		return nil, err
+	}
+	var matches []obex.AuthorStats
+	for _, a := range authors {
+		if strings.Contains(strings.ToLower(a.Name), strings.ToLower(name)) {
+			matches = append(matches, a)
+		}
+	}
+	return matches, nil
+}
+
+func (m *MockOBEXManager) GetAuthorDetail(author string) (*obex.AuthorDetail, error) {
+	if err := m.record("GetAuthorDetail(" + author + ")"); err != nil {
+		return nil, err
+	}
+	return &obex.AuthorDetail{Name: author}, nil
+}
+
+func (m *MockOBEXManager) GetCorpusStats() (*obex.CorpusStats, error) {
+	if err := m.record("GetCorpusStats"); err != nil {
+		return nil, err
+	}
+	return &obex.CorpusStats{TotalObjects: len(m.Objects)}, nil
+}
+
+func (m *MockOBEXManager) GetDependencyGraph() (*obex.DependencyGraph, error) {
+	if err := m.record("GetDependencyGraph"); err != nil {
+		return nil, err
+	}
+	return &obex.DependencyGraph{Nodes: []obex.GraphNode{}, Edges: []obex.GraphEdge{}, TotalObjects: len(m.Objects)}, nil
+}
+
+func (m *MockOBEXManager) GetTagCloud() (*obex.TagCloud, error) {
+	if err := m.record("GetTagCloud"); err != nil {
+		return nil, err
+	}
+	return &obex.TagCloud{Tags: []obex.TagCount{}, TagPairs: []obex.TagPairCount{}, TotalObjects: len(m.Objects)}, nil
+}
+
+func (m *MockOBEXManager) GetInvalidObjects() ([]obex.InvalidObject, error) {
+	if err := m.record("GetInvalidObjects"); err != nil {
+		return nil, err
+	}
+	return []obex.InvalidObject{}, nil
+}
+
+func (m *MockOBEXManager) GetCacheStats() (memoryCount, diskCount int, staleCount int) {
+	m.record("GetCacheStats")
+	return len(m.Details), 0, 0
+}
+
+func (m *MockOBEXManager) MemoryObjects() []obex.MemoryObject {
+	m.record("MemoryObjects")
+	objects := make([]obex.MemoryObject, 0, len(m.Objects))
+	for _, obj := range m.Objects {
+		objects = append(objects, obex.MemoryObject{ObjectID: obj.ObjectID, Title: obj.Title, Author: obj.Author})
+	}
+	return objects
+}
+
+func (m *MockOBEXManager) ClearCache() int {
+	m.record("ClearCache")
+	return len(m.Details)
+}
+
+func (m *MockOBEXManager) EvictMemoryObjects(target int) int {
+	m.record("EvictMemoryObjects")
+	return 0
+}
+
+func (m *MockOBEXManager) ConcurrencyLimit() int { return obex.DefaultOBEXConcurrency }
+
+func (m *MockOBEXManager) SetConcurrencyLimit(n int) {
+	m.record(fmt.Sprintf("SetConcurrencyLimit(%d)", n))
+}
+
+func (m *MockOBEXManager) PendingFetches() int64 { return 0 }
+
+func (m *MockOBEXManager) CorruptedCacheEvictions() int64 { return 0 }
+
+func (m *MockOBEXManager) LastMirrorUsed() string { return m.MirrorUsed }
+
+func (m *MockOBEXManager) AuthConfigured() bool { return m.Authed }
+
+// newMockOBEXManager returns a mock with three objects: 2811 (P2 LED driver,
+// with details), 2812 (P1 LED driver) and 2813 (P2 I2C driver), all drivers.
+func newMockOBEXManager() *MockOBEXManager {
+	var led obex.OBEXObject
+	led.ObjectMetadata.ObjectID = "2811"
+	led.ObjectMetadata.Title = "WS2812 LED Driver"
+	led.ObjectMetadata.Author = "Jon McPhalen"
+	led.ObjectMetadata.URLs.OBEXPage = "https://obex.parallax.com/obex/ws2812-led-driver/"
+	led.ObjectMetadata.Functionality.Category = "drivers"
+
+	return &MockOBEXManager{
+		Objects: []obex.SearchResult{
+			{ObjectID: "2811", Title: "WS2812 LED Driver", Author: "Jon McPhalen", Category: "drivers", Subcategory: "led", Microcontroller: []string{"P2"}},
+			{ObjectID: "2812", Title: "P1 LED Driver", Author: "Jon McPhalen", Category: "drivers", Subcategory: "led", Microcontroller: []string{"P1"}},
+			{ObjectID: "2813", Title: "I2C Driver", Author: "Chip Gracey", Category: "drivers", Subcategory: "i2c", Microcontroller: []string{"P2"}},
+		},
+		Details: map[string]*obex.OBEXObject{"2811": &led},
+		Tags: map[string][]string{
+			"2811": {"led", "ws2812", "neopixel"},
+			"2812": {"led"},
+			"2813": {"i2c", "sensor"},
+		},
+	}
+}
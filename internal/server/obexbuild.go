@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+)
+
+// OBEX index build states reported by p2kb_obex_build_index.
+const (
+	buildRunning   = "running"
+	buildCompleted = "completed"
+	buildStopped   = "stopped"
+	buildFailed    = "failed"
+)
+
+// obexBuild is one p2kb_obex_build_index run. The fields below mu in
+// obexBuilds change while the build runs and are guarded by it.
+type obexBuild struct {
+	id        string
+	total     int
+	startedAt time.Time
+	cancel    context.CancelFunc
+	done      chan struct{} // Closed when the build goroutine exits
+
+	status     string
+	loaded     int
+	failed     int
+	err        string
+	finishedAt time.Time
+}
+
+// obexBuilds holds the current or most recent OBEX index build; only one runs
+// at a time. The zero value is ready to use.
+type obexBuilds struct {
+	mu      sync.Mutex
+	current *obexBuild
+}
+
+// handleOBEXBuildIndex implements p2kb_obex_build_index - load every OBEX
+// object into memory in the background. With no arguments it starts a build,
+// or reports on the one already running; build_id asks about a specific build
+// and stop aborts the running one.
+func (s *Server) handleOBEXBuildIndex(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		BuildID string `json:"build_id"`
+		Stop    bool   `json:"stop"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+	}
+
+	builds := &s.obexBuilds
+	builds.mu.Lock()
+	current := builds.current
+
+	if params.BuildID != "" {
+		defer builds.mu.Unlock()
+		if current == nil || current.id != params.BuildID {
+			return s.errorResponse(id, -32602, "Unknown build_id", params.BuildID)
+		}
+		return s.successResponse(id, current.statusLocked())
+	}
+
+	if params.Stop {
+		if current == nil || current.status != buildRunning {
+			builds.mu.Unlock()
+			return s.successResponse(id, map[string]interface{}{
+				"status":  "idle",
+				"message": "No OBEX index build is running",
+			})
+		}
+		current.cancel()
+		builds.mu.Unlock()
+
+		<-current.done
+		builds.mu.Lock()
+		defer builds.mu.Unlock()
+		return s.successResponse(id, current.statusLocked())
+	}
+
+	defer builds.mu.Unlock()
+	if current != nil && current.status == buildRunning {
+		return s.successResponse(id, current.statusLocked())
+	}
+
+	if err := s.obexManager.EnsureIndex(); err != nil {
+		return s.errorResponse(id, -32000, "Failed to load OBEX index", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	build := &obexBuild{
+		id:        newBuildID(),
+		total:     s.obexManager.GetTotalObjects(),
+		startedAt: time.Now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		status:    buildRunning,
+	}
+	builds.current = build
+	go s.runOBEXBuild(ctx, build)
+
+	status := build.statusLocked()
+	status["status"] = "started"
+	return s.successResponse(id, status)
+}
+
+// runOBEXBuild loads every OBEX object, recording progress in build.
+func (s *Server) runOBEXBuild(ctx context.Context, build *obexBuild) {
+	defer close(build.done)
+	defer build.cancel()
+
+	builds := &s.obexBuilds
+	err := s.obexManager.LoadAllObjects(ctx, obex.LoadAllBatchSize, obex.LoadAllBatchDelay, func(loaded, failed int) {
+		builds.mu.Lock()
+		build.loaded, build.failed = loaded, failed
+		builds.mu.Unlock()
+	})
+
+	builds.mu.Lock()
+	defer builds.mu.Unlock()
+	build.finishedAt = time.Now()
+	switch {
+	case errors.Is(err, context.Canceled):
+		build.status = buildStopped
+	case err != nil:
+		build.status = buildFailed
+		build.err = err.Error()
+	default:
+		build.status = buildCompleted
+	}
+}
+
+// statusLocked describes the build for p2kb_obex_build_index. Caller holds
+// obexBuilds.mu.
+func (b *obexBuild) statusLocked() map[string]interface{} {
+	status := map[string]interface{}{
+		"build_id":      b.id,
+		"status":        b.status,
+		"total_objects": b.total,
+		"fetched":       b.loaded,
+		"failed":        b.failed,
+		"progress_pct":  coveragePct(b.loaded+b.failed, b.total),
+		"started_at":    b.startedAt.UTC().Format(time.RFC3339),
+	}
+	if !b.finishedAt.IsZero() {
+		status["finished_at"] = b.finishedAt.UTC().Format(time.RFC3339)
+		status["elapsed_ms"] = b.finishedAt.Sub(b.startedAt).Milliseconds()
+	}
+	if b.err != "" {
+		status["error"] = b.err
+	}
+	return status
+}
+
+// coveragePct returns part as a percentage of total, to one decimal place.
+func coveragePct(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
+
+// newBuildID returns a random (version 4) UUID.
+func newBuildID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// callOBEXBuildIndex calls p2kb_obex_build_index with args and returns the result map.
+func callOBEXBuildIndex(t *testing.T, srv *Server, args map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	params, _ := json.Marshal(map[string]interface{}{
+		"name":      "p2kb_obex_build_index",
+		"arguments": args,
+	})
+	resp := srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: params})
+	if resp.Error != nil {
+		t.Fatalf("p2kb_obex_build_index(%v) failed: %s %v", args, resp.Error.Message, resp.Error.Data)
+	}
+	return extractResultMap(t, resp)
+}
+
+// waitForBuild waits for the current build to finish.
+func waitForBuild(t *testing.T, srv *Server) {
+	t.Helper()
+	srv.obexBuilds.mu.Lock()
+	build := srv.obexBuilds.current
+	srv.obexBuilds.mu.Unlock()
+	select {
+	case <-build.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("build did not finish")
+	}
+}
+
+func TestHandleOBEXBuildIndex(t *testing.T) {
+	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	mock.LoadGate = make(chan struct{})
+	srv.obexManager = mock
+
+	started := callOBEXBuildIndex(t, srv, map[string]interface{}{})
+	if started["status"] != "started" || started["total_objects"] != float64(3) {
+		t.Fatalf("start = %v, want status started with 3 objects", started)
+	}
+	buildID, _ := started["build_id"].(string)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(buildID) {
+		t.Errorf("build_id = %q, want a version 4 UUID", buildID)
+	}
+
+	// A second call while running reports on the same build
+	running := callOBEXBuildIndex(t, srv, map[string]interface{}{})
+	if running["build_id"] != buildID || running["status"] != buildRunning {
+		t.Errorf("second call = %v, want build %s running", running, buildID)
+	}
+
+	close(mock.LoadGate)
+	waitForBuild(t, srv)
+
+	done := callOBEXBuildIndex(t, srv, map[string]interface{}{"build_id": buildID})
+	if done["status"] != buildCompleted || done["fetched"] != float64(3) || done["progress_pct"] != float64(100) {
+		t.Errorf("finished build = %v, want completed with 3 fetched", done)
+	}
+	if _, ok := done["finished_at"]; !ok {
+		t.Error("finished build has no finished_at")
+	}
+
+	version := extractResultMap(t, srv.handleVersion(1))
+	if version["obex_index_coverage_pct"] != float64(100) {
+		t.Errorf("obex_index_coverage_pct = %v, want 100", version["obex_index_coverage_pct"])
+	}
+
+	// With the last build finished, a call with no arguments starts another
+	restarted := callOBEXBuildIndex(t, srv, map[string]interface{}{})
+	if restarted["status"] != "started" || restarted["build_id"] == buildID {
+		t.Errorf("restart = %v, want a new build", restarted)
+	}
+	waitForBuild(t, srv)
+}
+
+func TestHandleOBEXBuildIndexStop(t *testing.T) {
+	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	mock.LoadGate = make(chan struct{})
+	srv.obexManager = mock
+
+	idle := callOBEXBuildIndex(t, srv, map[string]interface{}{"stop": true})
+	if idle["status"] != "idle" {
+		t.Errorf("stop with no build = %v, want idle", idle)
+	}
+
+	callOBEXBuildIndex(t, srv, map[string]interface{}{})
+	stopped := callOBEXBuildIndex(t, srv, map[string]interface{}{"stop": true})
+	if stopped["status"] != buildStopped || stopped["fetched"] != float64(0) {
+		t.Errorf("stopped build = %v, want stopped with nothing fetched", stopped)
+	}
+}
+
+func TestHandleOBEXBuildIndexErrors(t *testing.T) {
+	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	srv.obexManager = mock
+
+	params, _ := json.Marshal(map[string]interface{}{
+		"name":      "p2kb_obex_build_index",
+		"arguments": map[string]interface{}{"build_id": "no-such-build"},
+	})
+	resp := srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: params})
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("unknown build_id: error = %v, want -32602", resp.Error)
+	}
+
+	// A load failure ends the build as failed, with the error
+	mock.LoadErr = errors.New("rate limited")
+	started := callOBEXBuildIndex(t, srv, map[string]interface{}{})
+	waitForBuild(t, srv)
+	failed := callOBEXBuildIndex(t, srv, map[string]interface{}{"build_id": started["build_id"]})
+	if failed["status"] != buildFailed || failed["error"] != "rate limited" {
+		t.Errorf("failed build = %v, want failed with the load error", failed)
+	}
+
+	// Without an OBEX index nothing starts
+	mock.IndexErr = errors.New("offline")
+	params, _ = json.Marshal(map[string]interface{}{"name": "p2kb_obex_build_index", "arguments": map[string]interface{}{}})
+	resp = srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: params})
+	if resp.Error == nil || resp.Error.Code != -32000 {
+		t.Errorf("no index: error = %v, want -32000", resp.Error)
+	}
+}
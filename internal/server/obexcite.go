@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+)
+
+// citationUnknown stands in for a field an OBEX object does not record.
+const citationUnknown = "Unknown"
+
+// handleOBEXCite implements p2kb_obex_cite - a citation for an OBEX object in
+// APA, BibTeX or Markdown form.
+func (s *Server) handleOBEXCite(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Query  string `json:"query"`
+		Format string `json:"format"`
+	}
+	params.Format = "markdown"
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if params.Query == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "query")
+	}
+	format := strings.ToLower(params.Format)
+	if format != "apa" && format != "bibtex" && format != "markdown" {
+		return s.errorResponse(id, -32602, "Invalid format", `format must be "apa", "bibtex" or "markdown"`)
+	}
+
+	objectID, resp := s.resolveOBEXQuery(ctx, id, params.Query, "")
+	if resp != nil {
+		return resp
+	}
+
+	obj, err := s.obexManager.GetObject(objectID)
+	var notFound *errs.ErrKeyNotFound
+	if errors.As(err, &notFound) {
+		return s.successResponse(id, map[string]interface{}{
+			"type":      "object_not_found",
+			"object_id": objectID,
+			"message":   fmt.Sprintf("OBEX object '%s' not found", objectID),
+			"hint":      "Use p2kb_obex_find to search for objects",
+		})
+	}
+	if err != nil {
+		data := map[string]interface{}{"error": err.Error(), "object_id": objectID}
+		addRetryAfter(data, err)
+		return s.errorResponse(id, managerErrorCode(err), fmt.Sprintf("Failed to fetch OBEX object '%s'", objectID), data)
+	}
+
+	c := newCitation(&obj.ObjectMetadata)
+	return s.successResponse(id, map[string]interface{}{
+		"type":           "obex_citation",
+		"object_id":      objectID,
+		"format":         format,
+		"citation":       c.format(format),
+		"missing_fields": c.missing,
+	})
+}
+
+// citation holds the fields of an OBEX object's citation, with
+// citationUnknown in place of any the object lacks.
+type citation struct {
+	objectID string
+	author   string
+	title    string
+	year     string
+	url      string
+	version  string
+	missing  []string // Names of the fields that are citationUnknown
+}
+
+// newCitation gathers meta's citation fields. The year is the one the object
+// was created in.
+func newCitation(meta *obex.ObjectMetadata) citation {
+	c := citation{objectID: meta.ObjectID, missing: []string{}}
+	field := func(name, value string) string {
+		if value = strings.TrimSpace(value); value == "" {
+			c.missing = append(c.missing, name)
+			return citationUnknown
+		}
+		return value
+	}
+	c.author = field("author", meta.Author)
+	c.title = field("title", meta.Title)
+	c.year = field("year", createdYear(meta.Metadata.CreatedDate))
+	c.url = field("url", meta.URLs.OBEXPage)
+	c.version = field("version", meta.TechnicalDetails.Version)
+	return c
+}
+
+// createdYear returns the year a created_date ("2023-11-20 08:00:00") starts
+// with, or "" if it does not start with one.
+func createdYear(date string) string {
+	if len(date) < 4 {
+		return ""
+	}
+	for _, r := range date[:4] {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return date[:4]
+}
+
+// format renders the citation as "apa", "bibtex" or "markdown".
+func (c citation) format(format string) string {
+	switch format {
+	case "apa":
+		// The author element ends with a period, which initials may supply
+		author := c.author
+		if !strings.HasSuffix(author, ".") {
+			author += "."
+		}
+		return fmt.Sprintf("%s (%s). *%s* (Version %s). Parallax OBEX. %s", author, c.year, c.title, c.version, c.url)
+	case "bibtex":
+		year := c.year
+		if year == citationUnknown {
+			year = "{" + year + "}"
+		}
+		return fmt.Sprintf("@software{ob%s, author={%s}, title={%s}, year=%s, url={%s}, version={%s}}",
+			c.objectID, bibtexEscape(c.author), bibtexEscape(c.title), year, c.url, bibtexEscape(c.version))
+	default:
+		version := c.version
+		if version != citationUnknown {
+			version = "v" + version
+		}
+		return fmt.Sprintf("[%s](%s) by %s (%s, %s)", c.title, c.url, c.author, c.year, version)
+	}
+}
+
+// bibtexEscaper escapes the characters LaTeX treats specially in a BibTeX
+// field.
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`, "}", `\}`,
+	"&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`,
+)
+
+// bibtexEscape escapes s for use in a BibTeX field.
+func bibtexEscape(s string) string {
+	return bibtexEscaper.Replace(s)
+}
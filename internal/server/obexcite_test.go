@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ironsheep/p2kb-mcp/internal/errs"
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+)
+
+func TestHandleOBEXCite(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObject(t, "2815", "obexObjectComplete.yaml")
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"apa", "Test Author. (2023). *VL53L1X Time-of-Flight Sensor* (Version 1.2). Parallax OBEX. https://obex.parallax.com/obex/vl53l1x/"},
+		{"bibtex", "@software{ob2815, author={Test Author}, title={VL53L1X Time-of-Flight Sensor}, year=2023, url={https://obex.parallax.com/obex/vl53l1x/}, version={1.2}}"},
+		{"markdown", "[VL53L1X Time-of-Flight Sensor](https://obex.parallax.com/obex/vl53l1x/) by Test Author (2023, v1.2)"},
+		{"", "[VL53L1X Time-of-Flight Sensor](https://obex.parallax.com/obex/vl53l1x/) by Test Author (2023, v1.2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			args := map[string]interface{}{"query": "2815"}
+			if tt.format != "" {
+				args["format"] = tt.format
+			}
+			raw, _ := json.Marshal(args)
+			result := extractResultMap(t, srv.handleOBEXCite(context.Background(), 1, raw))
+			if result["type"] != "obex_citation" || result["object_id"] != "2815" {
+				t.Fatalf("result = %v, want obex_citation for 2815", result)
+			}
+			if result["citation"] != tt.want {
+				t.Errorf("citation =\n%v\nwant\n%s", result["citation"], tt.want)
+			}
+			if missing, _ := result["missing_fields"].([]interface{}); len(missing) != 0 {
+				t.Errorf("missing_fields = %v, want none", missing)
+			}
+		})
+	}
+
+	for _, args := range []string{`{}`, `{"query": "2815", "format": "mla"}`} {
+		resp := srv.handleOBEXCite(context.Background(), 1, json.RawMessage(args))
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("%s: error = %v, want -32602", args, resp.Error)
+		}
+	}
+}
+
+func TestHandleOBEXCiteFetchErrors(t *testing.T) {
+	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	srv.obexManager = mock
+
+	result := extractResultMap(t, srv.handleOBEXCite(context.Background(), 1, json.RawMessage(`{"query": "9999"}`)))
+	if result["type"] != "object_not_found" {
+		t.Errorf("unknown object: type = %v, want object_not_found", result["type"])
+	}
+
+	mock.IndexErr = &errs.ErrOffline{}
+	resp := srv.handleOBEXCite(context.Background(), 1, json.RawMessage(`{"query": "9999"}`))
+	if resp.Error == nil || resp.Error.Code != -32000 {
+		t.Errorf("offline: error = %+v, want -32000", resp.Error)
+	}
+}
+
+func TestCitationUnknownFields(t *testing.T) {
+	var meta obex.ObjectMetadata
+	meta.ObjectID = "9000"
+	meta.Title = "Odd_Name & Co"
+	meta.Metadata.CreatedDate = "sometime"
+
+	c := newCitation(&meta)
+	if want := []string{"author", "year", "url", "version"}; !reflect.DeepEqual(c.missing, want) {
+		t.Errorf("missing = %v, want %v", c.missing, want)
+	}
+
+	tests := map[string]string{
+		"apa":      "Unknown. (Unknown). *Odd_Name & Co* (Version Unknown). Parallax OBEX. Unknown",
+		"bibtex":   `@software{ob9000, author={Unknown}, title={Odd\_Name \& Co}, year={Unknown}, url={Unknown}, version={Unknown}}`,
+		"markdown": "[Odd_Name & Co](Unknown) by Unknown (Unknown, Unknown)",
+	}
+	for format, want := range tests {
+		if got := c.format(format); got != want {
+			t.Errorf("%s citation =\n%s\nwant\n%s", format, got, want)
+		}
+	}
+}
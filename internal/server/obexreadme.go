@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+)
+
+// Limits that keep a p2kb_obex_readme skeleton under about 2000 characters:
+// free text is clipped and list sections name at most readmeListItems entries.
+const (
+	readmeTitleChars       = 100
+	readmeDescriptionChars = 300
+	readmeItemChars        = 60
+	readmeListItems        = 5
+)
+
+// handleOBEXReadme implements p2kb_obex_readme - a Markdown README skeleton
+// for a downloaded OBEX object, built from its metadata.
+func (s *Server) handleOBEXReadme(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if params.Query == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "query")
+	}
+
+	objectID, resp := s.resolveOBEXQuery(ctx, id, params.Query, "")
+	if resp != nil {
+		return resp
+	}
+
+	obj, err := s.obexManager.GetObject(objectID)
+	if err != nil {
+		return s.successResponse(id, map[string]interface{}{
+			"type":      "object_not_found",
+			"object_id": objectID,
+			"message":   fmt.Sprintf("OBEX object '%s' not found", objectID),
+			"hint":      "Use p2kb_obex_find to search for objects",
+		})
+	}
+
+	meta := obj.ObjectMetadata
+	content := renderOBEXReadme(&meta, s.obexManager.GetDownloadURL(meta.ObjectID))
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":      "obex_readme",
+		"object_id": meta.ObjectID,
+		"title":     meta.Title,
+		"content":   content,
+		"length":    len(content),
+	})
+}
+
+// renderOBEXReadme builds the README skeleton for meta. Sections with nothing
+// to show get a TODO comment for the developer to fill in.
+func renderOBEXReadme(meta *obex.ObjectMetadata, downloadURL string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", clipText(meta.Title, readmeTitleChars))
+
+	var badges []string
+	if langs := upperAll(meta.TechnicalDetails.Languages); len(langs) > 0 {
+		badges = append(badges, shieldsBadge("Language", "language", strings.Join(langs, " | "), "blue"))
+	}
+	if category := meta.Functionality.Category; category != "" {
+		badges = append(badges, shieldsBadge("Category", "category", strings.ToLower(category), "green"))
+	}
+	if score := meta.Metadata.QualityScore; score > 0 {
+		badges = append(badges, shieldsBadge("Quality", "quality", fmt.Sprint(score), "brightgreen"))
+	}
+	if len(badges) > 0 {
+		b.WriteString(strings.Join(badges, " ") + "\n\n")
+	}
+
+	if description := meta.Functionality.DescriptionShort; description != "" {
+		b.WriteString(clipText(description, readmeDescriptionChars) + "\n\n")
+	} else {
+		b.WriteString("<!-- TODO: describe what this object does -->\n\n")
+	}
+
+	archive := fmt.Sprintf("OB%s.zip", meta.ObjectID)
+	b.WriteString("## Installation\n\n")
+	fmt.Fprintf(&b, "Download object %s from the Parallax OBEX and extract it into your project:\n\n", meta.ObjectID)
+	fmt.Fprintf(&b, "```sh\ncurl -L -o %s '%s'\nunzip %s -d OBEX/%s\n```\n\n", archive, downloadURL, archive, generateSlug(meta.Title))
+
+	b.WriteString("## Usage\n\n<!-- TODO: show how to include and call this object -->\n\n")
+
+	b.WriteString("## Hardware Requirements\n\n")
+	hardware := []struct {
+		label string
+		items []string
+	}{
+		{"Microcontroller", meta.TechnicalDetails.Microcontroller},
+		{"Hardware", meta.Functionality.HardwareSupport},
+		{"Peripherals", meta.Functionality.Peripherals},
+	}
+	listed := false
+	for _, h := range hardware {
+		if items := clipList(h.items); len(items) > 0 {
+			fmt.Fprintf(&b, "- %s: %s\n", h.label, strings.Join(items, ", "))
+			listed = true
+		}
+	}
+	if !listed {
+		b.WriteString("<!-- TODO: list the hardware this object needs -->\n")
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Links\n\n")
+	links := []struct {
+		label, url string
+	}{
+		{"OBEX page", meta.URLs.OBEXPage},
+		{"GitHub repository", meta.URLs.GithubRepo},
+		{"Forum discussion", meta.URLs.ForumDiscussion},
+		{"Documentation", meta.URLs.Documentation},
+	}
+	for _, l := range links {
+		if l.url != "" {
+			fmt.Fprintf(&b, "- [%s](%s)\n", l.label, l.url)
+		}
+	}
+	fmt.Fprintf(&b, "- [Download](%s)\n\n", downloadURL)
+
+	b.WriteString("## Credits\n\n")
+	author := meta.Author
+	if author == "" {
+		author = "unknown author"
+	}
+	fmt.Fprintf(&b, "Written by %s", clipText(author, readmeItemChars))
+	// created_date is "YYYY-MM-DD hh:mm:ss"; the date is enough here
+	if created, _, _ := strings.Cut(meta.Metadata.CreatedDate, " "); created != "" {
+		fmt.Fprintf(&b, ", created %s", created)
+	}
+	fmt.Fprintf(&b, ". Published on the Parallax OBEX as object %s.\n", meta.ObjectID)
+
+	return b.String()
+}
+
+// shieldsBadge returns Markdown for a shields.io static badge.
+func shieldsBadge(alt, label, message, color string) string {
+	escape := func(s string) string {
+		s = strings.NewReplacer("-", "--", "_", "__").Replace(s)
+		return url.PathEscape(s)
+	}
+	return fmt.Sprintf("![%s](https://img.shields.io/badge/%s-%s-%s)", alt, escape(label), escape(message), color)
+}
+
+// upperAll returns the non-empty values uppercased and trimmed.
+func upperAll(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.ToUpper(strings.TrimSpace(v)); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// clipList returns the first readmeListItems non-empty items, each clipped.
+func clipList(items []string) []string {
+	out := make([]string, 0, readmeListItems)
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		if len(out) == readmeListItems {
+			break
+		}
+		out = append(out, clipText(item, readmeItemChars))
+	}
+	return out
+}
+
+// clipText shortens s to at most n runes, marking the cut with an ellipsis.
+func clipText(s string, n int) string {
+	s = strings.TrimSpace(s)
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+)
+
+func TestHandleOBEXReadme(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	seedOBEXObject(t, "2815", "obexObjectComplete.yaml")
+
+	resp := srv.handleOBEXReadme(context.Background(), 1, json.RawMessage(`{"query": "2815"}`))
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	result := extractResultMap(t, resp)
+	if result["type"] != "obex_readme" || result["object_id"] != "2815" {
+		t.Fatalf("result = %v, want obex_readme for 2815", result)
+	}
+	content, _ := result["content"].(string)
+	if len(content) >= 2000 {
+		t.Errorf("README is %d characters, want under 2000", len(content))
+	}
+
+	wantCurl := "curl -L -o OB2815.zip '" + srv.obexManager.GetDownloadURL("2815") + "'"
+	for _, want := range []string{
+		"# VL53L1X Time-of-Flight Sensor\n",
+		"https://img.shields.io/badge/language-SPIN2%20%7C%20PASM2-blue",
+		"https://img.shields.io/badge/category-sensors-green",
+		"https://img.shields.io/badge/quality-8-brightgreen",
+		"Driver for the VL53L1X time-of-flight distance sensor",
+		wantCurl,
+		"unzip OB2815.zip -d OBEX/vl53l1x-time-of-flight-sensor",
+		"## Usage",
+		"- Microcontroller: P2\n- Hardware: VL53L1X\n- Peripherals: I2C\n",
+		"- [OBEX page](https://obex.parallax.com/obex/vl53l1x/)",
+		"- [GitHub repository](https://github.com/example/vl53l1x)",
+		"- [Forum discussion](https://forums.parallax.com/discussion/2815)",
+		"- [Documentation](https://example.com/vl53l1x/docs)",
+		"Written by Test Author, created 2023-11-20.",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("README missing %q:\n%s", want, content)
+		}
+	}
+
+	resp = srv.handleOBEXReadme(context.Background(), 1, json.RawMessage(`{}`))
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("missing query: error = %v, want -32602", resp.Error)
+	}
+}
+
+func TestRenderOBEXReadmeSparseAndLong(t *testing.T) {
+	var meta obex.ObjectMetadata
+	meta.ObjectID = "9000"
+	meta.Title = strings.Repeat("Long Title ", 30)
+	for i := 0; i < 20; i++ {
+		meta.Functionality.HardwareSupport = append(meta.Functionality.HardwareSupport, strings.Repeat("board-", 20))
+	}
+
+	content := renderOBEXReadme(&meta, "https://obex.example/download?obuid=OB9000")
+	if len(content) >= 2000 {
+		t.Errorf("README is %d characters, want under 2000", len(content))
+	}
+	if strings.Contains(content, "img.shields.io") {
+		t.Error("badges rendered with no language, category or quality")
+	}
+	for _, want := range []string{"TODO: describe what this object does", "Written by unknown author. Published"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("README missing %q:\n%s", want, content)
+		}
+	}
+	_, hardware, _ := strings.Cut(content, "- Hardware: ")
+	hardware, _, _ = strings.Cut(hardware, "\n")
+	if items := strings.Split(hardware, ", "); len(items) != readmeListItems || !strings.HasSuffix(items[0], "…") {
+		t.Errorf("hardware = %q, want %d clipped items", hardware, readmeListItems)
+	}
+}
+
+func TestShieldsBadge(t *testing.T) {
+	got := shieldsBadge("Language", "language", "spin_2 - pasm/2", "blue")
+	want := "![Language](https://img.shields.io/badge/language-spin__2%20--%20pasm%2F2-blue)"
+	if got != want {
+		t.Errorf("shieldsBadge = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"html"
+	"net/url"
+	"regexp"
+	"sync"
+
+	"github.com/ironsheep/p2kb-mcp/internal/fetch"
+)
+
+const (
+	// defaultVerifyObjects is how many objects p2kb_obex_verify checks when
+	// max_objects is not given.
+	defaultVerifyObjects = 20
+
+	// verifyConcurrency caps the download checks p2kb_obex_verify runs at once.
+	verifyConcurrency = 5
+)
+
+// obexDownloadLink finds candidate download links in an OBEX page: the
+// download_obex_zip action the OBEX site uses, or any link to a .zip.
+var obexDownloadLink = regexp.MustCompile(`href=["']([^"']*(?:download_obex_zip|\.zip)[^"']*)["']`)
+
+// verifiedDownload is the outcome of checking one object's download URL.
+type verifiedDownload struct {
+	objectID string
+	title    string
+	status   int    // HTTP status of the HEAD request; 0 if it failed
+	err      error  // Why the HEAD request failed
+	fixedURL string // Working download link found on the OBEX page, if fixed
+}
+
+// ok reports whether the download URL answered with a 2xx status.
+func (v *verifiedDownload) ok() bool {
+	return v.err == nil && v.status >= 200 && v.status < 300
+}
+
+// handleOBEXVerify implements p2kb_obex_verify - HEAD-checks the download
+// URLs of in-memory OBEX objects, or of one category, and with fix looks for
+// a working link on the object's OBEX page.
+func (s *Server) handleOBEXVerify(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Category   string `json:"category"`
+		MaxObjects int    `json:"max_objects"`
+		Fix        bool   `json:"fix"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+	}
+	if params.MaxObjects <= 0 {
+		params.MaxObjects = defaultVerifyObjects
+	}
+
+	var checks []verifiedDownload
+	if params.Category != "" {
+		objects, err := s.obexManager.BrowseCategory(params.Category)
+		if err != nil {
+			return s.errorResponse(id, -32000, "Failed to browse category", err.Error())
+		}
+		for _, obj := range objects {
+			checks = append(checks, verifiedDownload{objectID: obj.ObjectID, title: obj.Title})
+		}
+	} else {
+		for _, obj := range s.obexManager.MemoryObjects() {
+			checks = append(checks, verifiedDownload{objectID: obj.ObjectID, title: obj.Title})
+		}
+	}
+	if len(checks) > params.MaxObjects {
+		checks = checks[:params.MaxObjects]
+	}
+
+	client := fetch.NewClient()
+	sem := make(chan struct{}, verifyConcurrency)
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(check *verifiedDownload) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			check.status, check.err = client.HeadStatus(s.obexManager.GetDownloadURL(check.objectID))
+			if params.Fix && !check.ok() {
+				check.fixedURL = s.fixOBEXDownload(client, check.objectID)
+			}
+		}(&checks[i])
+	}
+	wg.Wait()
+
+	broken := make([]map[string]interface{}, 0)
+	fixed := 0
+	for _, check := range checks {
+		if check.ok() {
+			continue
+		}
+		entry := map[string]interface{}{
+			"object_id":   check.objectID,
+			"title":       check.title,
+			"http_status": check.status,
+		}
+		if check.err != nil {
+			entry["error"] = check.err.Error()
+		}
+		if check.fixedURL != "" {
+			entry["fixed_url"] = check.fixedURL
+			fixed++
+		}
+		broken = append(broken, entry)
+	}
+
+	result := map[string]interface{}{
+		"type":           "obex_verify",
+		"verified_count": len(checks),
+		"ok_count":       len(checks) - len(broken),
+		"broken_count":   len(broken),
+		"broken":         broken,
+	}
+	if params.Category != "" {
+		result["category"] = params.Category
+	}
+	if params.Fix {
+		result["fixed_count"] = fixed
+	}
+	return s.successResponse(id, result)
+}
+
+// fixOBEXDownload looks for a working download link on an object's OBEX page
+// and, if one answers a HEAD request, makes it the object's download URL. It
+// returns the new URL, or "" if none was found. Finding the link is
+// heuristic: the first download_obex_zip or .zip link that works wins.
+func (s *Server) fixOBEXDownload(client *fetch.Client, objectID string) string {
+	obj, err := s.obexManager.GetObject(objectID)
+	if err != nil || obj.ObjectMetadata.URLs.OBEXPage == "" {
+		return ""
+	}
+	page, err := url.Parse(obj.ObjectMetadata.URLs.OBEXPage)
+	if err != nil {
+		return ""
+	}
+	body, err := client.FetchURL(page.String())
+	if err != nil {
+		return ""
+	}
+
+	current := s.obexManager.GetDownloadURL(objectID)
+	for _, match := range obexDownloadLink.FindAllSubmatch(body, -1) {
+		link, err := page.Parse(html.UnescapeString(string(match[1])))
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.String() == current {
+			continue
+		}
+		status, err := client.HeadStatus(link.String())
+		if err != nil || status < 200 || status >= 300 {
+			continue
+		}
+		if err := s.obexManager.SetDownloadURL(objectID, link.String()); err != nil {
+			return ""
+		}
+		return link.String()
+	}
+	return ""
+}
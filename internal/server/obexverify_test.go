@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ironsheep/p2kb-mcp/internal/obex"
+)
+
+// newVerifyTestServer serves OBEX downloads where object 2812's link is
+// broken, and an OBEX page for 2812 linking to where its ZIP moved.
+func newVerifyTestServer(t *testing.T) (*Server, *MockOBEXManager) {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/2811", "/download/2813":
+		case "/moved/OB2812.zip":
+			if r.URL.Query().Get("v") != "2" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case "/obex/p1-led-driver/":
+			_, _ = w.Write([]byte(`<a href="/download/2812">Old</a> <a class="btn" href="/moved/OB2812.zip?v=2&amp;src=obex">Download</a>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	mock := newMockOBEXManager()
+	mock.DownloadBase = ts.URL + "/download/"
+	var p1 obex.OBEXObject
+	p1.ObjectMetadata.ObjectID = "2812"
+	p1.ObjectMetadata.Title = "P1 LED Driver"
+	p1.ObjectMetadata.URLs.OBEXPage = ts.URL + "/obex/p1-led-driver/"
+	mock.Details["2812"] = &p1
+
+	srv := New("1.0.0")
+	srv.obexManager = mock
+	return srv, mock
+}
+
+func TestHandleOBEXVerify(t *testing.T) {
+	srv, mock := newVerifyTestServer(t)
+
+	result := extractResultMap(t, srv.handleOBEXVerify(1, json.RawMessage(`{}`)))
+	if result["verified_count"] != float64(3) || result["ok_count"] != float64(2) || result["broken_count"] != float64(1) {
+		t.Errorf("counts = %v verified, %v ok, %v broken; want 3, 2, 1", result["verified_count"], result["ok_count"], result["broken_count"])
+	}
+	broken, _ := result["broken"].([]interface{})
+	if len(broken) != 1 {
+		t.Fatalf("broken = %v, want one entry", result["broken"])
+	}
+	entry := broken[0].(map[string]interface{})
+	if entry["object_id"] != "2812" || entry["title"] != "P1 LED Driver" || entry["http_status"] != float64(http.StatusNotFound) {
+		t.Errorf("broken entry = %v, want 2812 with status 404", entry)
+	}
+	if _, ok := result["fixed_count"]; ok || mock.DownloadURLs != nil {
+		t.Error("links were fixed without fix: true")
+	}
+
+	// max_objects caps the check; category limits it to that category
+	result = extractResultMap(t, srv.handleOBEXVerify(1, json.RawMessage(`{"max_objects": 1}`)))
+	if result["verified_count"] != float64(1) || result["broken_count"] != float64(0) {
+		t.Errorf("max_objects 1: %v verified, %v broken; want 1 and 0", result["verified_count"], result["broken_count"])
+	}
+	result = extractResultMap(t, srv.handleOBEXVerify(1, json.RawMessage(`{"category": "sensors"}`)))
+	if result["verified_count"] != float64(0) || !mock.Called("BrowseCategory(sensors)") {
+		t.Errorf("category sensors: %v verified, want 0 from a category browse", result["verified_count"])
+	}
+}
+
+func TestHandleOBEXVerifyFix(t *testing.T) {
+	srv, mock := newVerifyTestServer(t)
+
+	result := extractResultMap(t, srv.handleOBEXVerify(1, json.RawMessage(`{"fix": true}`)))
+	broken, _ := result["broken"].([]interface{})
+	if len(broken) != 1 || result["fixed_count"] != float64(1) {
+		t.Fatalf("broken = %v, fixed_count = %v; want 2812 fixed", result["broken"], result["fixed_count"])
+	}
+	want := mock.DownloadBase[:len(mock.DownloadBase)-len("/download/")] + "/moved/OB2812.zip?v=2&src=obex"
+	if got := broken[0].(map[string]interface{})["fixed_url"]; got != want {
+		t.Errorf("fixed_url = %v, want %s", got, want)
+	}
+	if got := mock.GetDownloadURL("2812"); got != want {
+		t.Errorf("download URL after fix = %s, want %s", got, want)
+	}
+
+	// The fixed link now verifies
+	result = extractResultMap(t, srv.handleOBEXVerify(1, json.RawMessage(`{}`)))
+	if result["broken_count"] != float64(0) {
+		t.Errorf("broken_count after fix = %v, want 0", result["broken_count"])
+	}
+}
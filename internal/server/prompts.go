@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Prompt represents an MCP prompt: a starter template the client can offer
+// the user, rendered into messages by prompts/get.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Arguments   []PromptArgument `json:"arguments"`
+
+	// render builds the prompt text from the supplied arguments; required
+	// arguments are present and non-empty by the time it is called.
+	render func(args map[string]string) string
+}
+
+// PromptArgument describes one parameterized slot of a prompt.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// basicPASM2Instructions is the lesson order used by the learn_pasm2 prompt.
+var basicPASM2Instructions = []string{"MOV", "ADD", "SUB", "CMP", "JMP", "DJNZ", "RDLONG", "WRLONG"}
+
+// GetPromptDefinitions returns the P2 starter prompts.
+func GetPromptDefinitions() []Prompt {
+	return []Prompt{
+		// Single instruction deep dive
+		{
+			Name:        "explain_instruction",
+			Description: "Explain a PASM2 instruction or Spin2 method using the P2 Knowledge Base, then point to related entries",
+			Arguments: []PromptArgument{
+				{Name: "instruction", Description: "Instruction or method name (e.g., 'MOV', 'WAITX', 'PINWRITE')", Required: true},
+			},
+			render: func(args map[string]string) string {
+				name := args["instruction"]
+				return fmt.Sprintf(`Explain the Propeller 2 instruction or method %[1]s.
+
+1. Call p2kb_get with query "%[1]s" to fetch its documentation.
+2. Call p2kb_suggest with context_keys set to the key p2kb_get returned, to find related entries.
+3. Explain what %[1]s does, its syntax and operands, flag effects and timing where documented, and give a short example.
+4. Finish with the related entries worth reading next and one sentence on why each is relevant.
+
+Use only what the knowledge base returns; say so if something is not documented.`, name)
+			},
+		},
+
+		// OBEX driver discovery
+		{
+			Name:        "find_drivers",
+			Description: "Find community OBEX drivers for the P2, optionally for a specific device",
+			Arguments: []PromptArgument{
+				{Name: "device", Description: "Device or peripheral to drive (e.g., 'WS2812', 'SD card'); omit to browse all drivers", Required: false},
+			},
+			render: func(args map[string]string) string {
+				device := args["device"]
+				if device == "" {
+					return `Find Propeller 2 drivers in the Parallax Object Exchange (OBEX).
+
+1. Call p2kb_obex_find with category "drivers".
+2. Group the results by the kind of hardware they drive and summarize each group.
+3. For any object worth a closer look, call p2kb_obex_get with its object_id for details and download instructions.`
+				}
+				return fmt.Sprintf(`Find Propeller 2 drivers for %[1]s in the Parallax Object Exchange (OBEX).
+
+1. Call p2kb_obex_find with category "drivers" and term "%[1]s".
+2. If nothing matches, call p2kb_obex_find with term "%[1]s" alone to search every category.
+3. For the best candidates, call p2kb_obex_get with their object_id and compare author, languages and quality.
+4. Recommend one, and offer to fetch it with p2kb_obex_download.`, device)
+			},
+		},
+
+		// Guided PASM2 introduction
+		{
+			Name:        "learn_pasm2",
+			Description: "A guided introduction to PASM2 through its most basic instructions",
+			Arguments: []PromptArgument{
+				{Name: "background", Description: "What the learner already knows (e.g., 'Propeller 1 PASM', 'C only'); tailors the explanations", Required: false},
+			},
+			render: func(args map[string]string) string {
+				var b strings.Builder
+				b.WriteString("Teach me PASM2, the Propeller 2 assembly language, one basic instruction at a time.\n")
+				if background := args["background"]; background != "" {
+					fmt.Fprintf(&b, "My background: %s. Relate each instruction to what I already know.\n", background)
+				}
+				b.WriteString("\nFor each instruction below, in order:\n")
+				for i, name := range basicPASM2Instructions {
+					fmt.Fprintf(&b, "%d. Call p2kb_get with query %q and explain it in a few sentences with a one-line example.\n", i+1, name)
+				}
+				b.WriteString("\nThen write a short PASM2 routine that uses all of them together, and explain how it runs in a cog.")
+				return b.String()
+			},
+		},
+	}
+}
+
+// handlePromptsList returns the list of available prompts in MCP format.
+func (s *Server) handlePromptsList(req *MCPRequest) *MCPResponse {
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"prompts": GetPromptDefinitions(),
+		},
+	}
+}
+
+// handlePromptsGet renders the named prompt with the supplied arguments.
+func (s *Server) handlePromptsGet(req *MCPRequest) *MCPResponse {
+	var params struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return s.errorResponse(req.ID, -32602, "Invalid params", err.Error())
+	}
+
+	for _, prompt := range GetPromptDefinitions() {
+		if prompt.Name != params.Name {
+			continue
+		}
+
+		args := make(map[string]string, len(params.Arguments))
+		for k, v := range params.Arguments {
+			args[k] = strings.TrimSpace(v)
+		}
+		for _, arg := range prompt.Arguments {
+			if arg.Required && args[arg.Name] == "" {
+				return s.errorResponse(req.ID, -32602, "Missing required argument", arg.Name)
+			}
+		}
+
+		return &MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result: map[string]interface{}{
+				"description": prompt.Description,
+				"messages": []map[string]interface{}{
+					{
+						"role": "user",
+						"content": map[string]interface{}{
+							"type": "text",
+							"text": prompt.render(args),
+						},
+					},
+				},
+			},
+		}
+	}
+
+	return s.errorResponse(req.ID, -32602, "Unknown prompt", params.Name)
+}
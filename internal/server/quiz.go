@@ -0,0 +1,382 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// quizAttempts bounds how many entries p2kb_quiz reads looking for one with
+// the fields a question needs, and how many siblings it reads for wrong
+// answers.
+const quizAttempts = 8
+
+// quizOptionCount is how many choices each question offers.
+const quizOptionCount = 4
+
+// quizExplanationChars caps the description quoted in a quiz explanation.
+const quizExplanationChars = 600
+
+// flagChoices are the medium question's options, by which of C and Z an
+// instruction writes.
+var flagChoices = []string{"C only", "Z only", "C and Z", "Neither C nor Z"}
+
+// quizEntry is the part of a knowledge base entry a quiz question draws on.
+type quizEntry struct {
+	Mnemonic    string                 `yaml:"mnemonic"`
+	Syntax      interface{}            `yaml:"syntax"` // A string or a list of them
+	Description string                 `yaml:"description"`
+	Flags       map[string]interface{} `yaml:"flags"`
+	Examples    []struct {
+		Code        string `yaml:"code"`
+		Description string `yaml:"description"`
+	} `yaml:"examples"`
+}
+
+// quizQuestion is one p2kb_quiz question.
+type quizQuestion struct {
+	Question    string   `json:"question"`
+	Options     []string `json:"options"`
+	AnswerIndex int      `json:"answer_index"`
+	Explanation string   `json:"explanation"`
+}
+
+// handleQuiz implements p2kb_quiz - a multiple-choice question about a
+// random instruction, built from its knowledge base entry.
+func (s *Server) handleQuiz(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Category   string `json:"category"`
+		Difficulty string `json:"difficulty"`
+	}
+	params.Difficulty = "medium"
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+	}
+
+	difficulty := strings.ToLower(params.Difficulty)
+	if difficulty != "easy" && difficulty != "medium" && difficulty != "hard" {
+		return s.errorResponse(id, -32602, "Invalid difficulty", `difficulty must be "easy", "medium" or "hard"`)
+	}
+
+	var keys []string
+	if params.Category != "" {
+		var err error
+		keys, err = s.indexManager.GetCategoryKeys(params.Category)
+		if err != nil {
+			// An alias draws from every category it names
+			keys, err = s.aliasCategoryKeys(params.Category)
+		}
+		if err != nil {
+			return s.errorResponse(id, managerErrorCode(err), "Category lookup failed", map[string]interface{}{
+				"error":    err.Error(),
+				"category": params.Category,
+				"hint":     "Use p2kb_find with no parameters to list categories",
+			})
+		}
+	} else {
+		all, _, err := s.indexManager.ListKeys("", "")
+		if err != nil {
+			return s.errorResponse(id, -32000, "Failed to load index", err.Error())
+		}
+		for _, k := range all {
+			keys = append(keys, k.Key)
+		}
+	}
+
+	// Instructions only: other entries have no mnemonic to ask about
+	var candidates []string
+	for _, key := range keys {
+		if keyMnemonic(key) != "" {
+			candidates = append(candidates, key)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+
+	tried := candidates
+	if len(tried) > quizAttempts {
+		tried = tried[:quizAttempts]
+	}
+	for _, key := range tried {
+		entry, ok := s.quizEntry(key)
+		if !ok {
+			continue
+		}
+		var q *quizQuestion
+		switch difficulty {
+		case "easy":
+			q = easyQuestion(entry, s.quizSiblings(key, candidates))
+		case "medium":
+			q = mediumQuestion(entry)
+		case "hard":
+			q = s.hardQuestion(entry, s.quizSiblings(key, candidates))
+		}
+		if q == nil {
+			continue
+		}
+		return s.successResponse(id, map[string]interface{}{
+			"type":         "quiz",
+			"key":          key,
+			"difficulty":   difficulty,
+			"question":     q.Question,
+			"options":      q.Options,
+			"answer_index": q.AnswerIndex,
+			"explanation":  q.Explanation,
+		})
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":       "quiz_unavailable",
+		"category":   params.Category,
+		"difficulty": difficulty,
+		"message":    fmt.Sprintf("No %s question could be made from the %d entries tried", difficulty, len(tried)),
+		"hint":       "Try another difficulty, or a category of PASM2 instructions such as pasm2_math",
+	})
+}
+
+// quizKeyPrefix matches the language prefix of an instruction key.
+var quizKeyPrefix = regexp.MustCompile(`^p2kb(Pasm2|Spin2)`)
+
+// keyMnemonic derives an instruction's mnemonic from its key
+// (p2kbPasm2Mov -> MOV), or returns "" for a key that is not an instruction.
+func keyMnemonic(key string) string {
+	loc := quizKeyPrefix.FindStringIndex(key)
+	if loc == nil || loc[1] == len(key) {
+		return ""
+	}
+	return strings.ToUpper(key[loc[1]:])
+}
+
+// quizEntry fetches and parses key's entry, reporting false when it cannot be
+// read or has no mnemonic.
+func (s *Server) quizEntry(key string) (*quizEntry, bool) {
+	content, err := s.getContent(key)
+	if err != nil {
+		return nil, false
+	}
+	var entry quizEntry
+	if err := yaml.Unmarshal([]byte(content), &entry); err != nil || entry.Mnemonic == "" {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// quizSiblings returns the other instructions that wrong answers are drawn
+// from: those sharing a category with key first, then the rest of
+// candidates, shuffled within each group.
+func (s *Server) quizSiblings(key string, candidates []string) []string {
+	seen := map[string]bool{key: true}
+	var near, far []string
+	for _, category := range s.indexManager.GetKeyCategories(key) {
+		categoryKeys, _ := s.indexManager.GetCategoryKeys(category)
+		for _, k := range categoryKeys {
+			if !seen[k] && keyMnemonic(k) != "" {
+				seen[k] = true
+				near = append(near, k)
+			}
+		}
+	}
+	for _, k := range candidates {
+		if !seen[k] {
+			seen[k] = true
+			far = append(far, k)
+		}
+	}
+	rand.Shuffle(len(near), func(i, j int) { near[i], near[j] = near[j], near[i] })
+	rand.Shuffle(len(far), func(i, j int) { far[i], far[j] = far[j], far[i] })
+	return append(near, far...)
+}
+
+// easyQuestion asks which instruction a description sentence belongs to,
+// with sibling mnemonics as the wrong answers. Nil when the entry has no
+// description, or its first sentence gives the mnemonic away.
+func easyQuestion(entry *quizEntry, siblings []string) *quizQuestion {
+	sentence := firstSentence(entry.Description)
+	if sentence == "" || mentionsWord(sentence, entry.Mnemonic) {
+		return nil
+	}
+	answer := strings.ToUpper(entry.Mnemonic)
+
+	var wrong []string
+	for _, k := range siblings {
+		wrong = appendDistinct(wrong, answer, keyMnemonic(k))
+		if len(wrong) == quizOptionCount-1 {
+			break
+		}
+	}
+	q := newQuizQuestion(fmt.Sprintf("Which instruction is described as: %q?", sentence), answer, wrong)
+	if q != nil {
+		q.Explanation = quizExplanation(fmt.Sprintf("%s: %s", answer, sentence), entry.Description)
+	}
+	return q
+}
+
+// mediumQuestion asks which of the C and Z flags the instruction writes. Nil
+// when the entry does not document its flags.
+func mediumQuestion(entry *quizEntry) *quizQuestion {
+	if len(entry.Flags) == 0 {
+		return nil
+	}
+	c, cNote := flagAffected(entry.Flags, "C")
+	z, zNote := flagAffected(entry.Flags, "Z")
+	answer := flagChoices[3]
+	switch {
+	case c && z:
+		answer = flagChoices[2]
+	case c:
+		answer = flagChoices[0]
+	case z:
+		answer = flagChoices[1]
+	}
+
+	var wrong []string
+	for _, choice := range flagChoices {
+		wrong = appendDistinct(wrong, answer, choice)
+	}
+	q := newQuizQuestion(fmt.Sprintf("Which flags does %s affect?", strings.ToUpper(entry.Mnemonic)), answer, wrong)
+	if q != nil {
+		q.Explanation = quizExplanation(fmt.Sprintf("C: %s. Z: %s.", cNote, zNote), entry.Description)
+	}
+	return q
+}
+
+// hardQuestion asks what one of the entry's examples does, given its syntax,
+// with the descriptions of other examples as the wrong answers. Nil when the
+// entry has no syntax or described example, or too few other examples exist.
+func (s *Server) hardQuestion(entry *quizEntry, siblings []string) *quizQuestion {
+	syntax := firstString(entry.Syntax)
+	var usable []int
+	for i, ex := range entry.Examples {
+		if ex.Code != "" && ex.Description != "" {
+			usable = append(usable, i)
+		}
+	}
+	if syntax == "" || len(usable) == 0 {
+		return nil
+	}
+	pick := entry.Examples[usable[rand.IntN(len(usable))]]
+	answer := strings.TrimSpace(pick.Description)
+
+	var wrong []string
+	for _, ex := range entry.Examples {
+		wrong = appendDistinct(wrong, answer, strings.TrimSpace(ex.Description))
+	}
+	for i := 0; i < len(siblings) && i < quizAttempts && len(wrong) < quizOptionCount-1; i++ {
+		sibling, ok := s.quizEntry(siblings[i])
+		if !ok {
+			continue
+		}
+		for _, ex := range sibling.Examples {
+			wrong = appendDistinct(wrong, answer, strings.TrimSpace(ex.Description))
+		}
+	}
+	if len(wrong) > quizOptionCount-1 {
+		rand.Shuffle(len(wrong), func(i, j int) { wrong[i], wrong[j] = wrong[j], wrong[i] })
+	}
+
+	q := newQuizQuestion(fmt.Sprintf("Given %q, what is the result of %q?", syntax, strings.TrimSpace(pick.Code)), answer, wrong)
+	if q != nil {
+		q.Explanation = quizExplanation(fmt.Sprintf("%s: %s", strings.TrimSpace(pick.Code), answer), entry.Description)
+	}
+	return q
+}
+
+// newQuizQuestion places answer among the first three wrong answers at a
+// random position. Nil when there are fewer than three.
+func newQuizQuestion(question, answer string, wrong []string) *quizQuestion {
+	if len(wrong) < quizOptionCount-1 {
+		return nil
+	}
+	options := append([]string{}, wrong[:quizOptionCount-1]...)
+	at := rand.IntN(quizOptionCount)
+	options = append(options[:at], append([]string{answer}, options[at:]...)...)
+	return &quizQuestion{Question: question, Options: options, AnswerIndex: at}
+}
+
+// appendDistinct appends option to options unless it is empty, the answer, or
+// already there.
+func appendDistinct(options []string, answer, option string) []string {
+	if option == "" || option == answer {
+		return options
+	}
+	for _, o := range options {
+		if o == option {
+			return options
+		}
+	}
+	return append(options, option)
+}
+
+// unaffectedFlag matches flag notes saying the flag is left alone.
+var unaffectedFlag = regexp.MustCompile(`(?i)^(-+|n/?a|none|unchanged|not (affected|modified|changed)|unaffected|no change)\.?$`)
+
+// flagAffected reports whether flags says the instruction writes flag, with
+// the note to quote for it.
+func flagAffected(flags map[string]interface{}, flag string) (bool, string) {
+	// Keys may be written in either case
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.EqualFold(name, flag) {
+			note := strings.TrimSpace(fmt.Sprint(flags[name]))
+			if note == "" || note == "<nil>" || unaffectedFlag.MatchString(note) {
+				return false, "not affected"
+			}
+			return true, note
+		}
+	}
+	return false, "not affected"
+}
+
+// firstString returns v when it is a string, or the first string of a list.
+func firstString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				return strings.TrimSpace(s)
+			}
+		}
+	}
+	return ""
+}
+
+// firstSentence returns text's first sentence: up to the first full stop
+// followed by a space or the end, or its first line.
+func firstSentence(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.Index(text, "\n"); i >= 0 {
+		text = text[:i]
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '.' && (i+1 == len(text) || text[i+1] == ' ') {
+			return text[:i+1]
+		}
+	}
+	return text
+}
+
+// mentionsWord reports whether text contains word on its own, ignoring case.
+func mentionsWord(text, word string) bool {
+	return regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(word) + `\b`).MatchString(text)
+}
+
+// quizExplanation is the answer's reason followed by the entry's description.
+func quizExplanation(reason, description string) string {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return reason
+	}
+	return reason + "\n\n" + clipText(description, quizExplanationChars)
+}
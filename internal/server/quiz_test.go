@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+)
+
+// quizEntries are the instructions served to the quiz tests, keyed by mnemonic.
+var quizEntries = map[string]string{
+	"Mov": `mnemonic: MOV
+syntax:
+  - "MOV D,{#}S {WC/WZ/WCZ}"
+description: Copy S into D.
+flags:
+  C: "Set to S[31]"
+  Z: "Set if result is zero"
+examples:
+  - code: "MOV x, #5"
+    description: Loads 5 into x
+`,
+	"Add": `mnemonic: ADD
+syntax:
+  - "ADD D,{#}S {WC/WZ/WCZ}"
+description: Add S into D. Flags are optional.
+flags:
+  C: "Set on carry"
+  Z: unchanged
+examples:
+  - code: "ADD x, #1"
+    description: Increments x
+  - code: "ADD x, y WC"
+    description: Adds y to x, carry into C
+`,
+	"Nop": `mnemonic: NOP
+syntax: NOP
+description: NOP does nothing.
+flags:
+  C: not affected
+  Z: not affected
+examples:
+  - code: NOP
+    description: Waits two clocks
+`,
+	"Neg": `mnemonic: NEG
+syntax:
+  - "NEG D,{#}S {WC/WZ/WCZ}"
+description: Negate S into D.
+flags:
+  Z: "Set if result is zero"
+examples:
+  - code: "NEG x"
+    description: Negates x in place
+`,
+}
+
+func newQuizServer(t *testing.T) (*Server, func()) {
+	t.Helper()
+	files := make(map[string]interface{})
+	var keys []string
+	for name := range quizEntries {
+		key := "p2kbPasm2" + name
+		files[key] = map[string]interface{}{"path": "pasm2/" + key + ".yaml", "mtime": 1700000000}
+		keys = append(keys, key)
+	}
+	files["p2kbArchCog"] = map[string]interface{}{"path": "arch/p2kbArchCog.yaml", "mtime": 1700000000}
+	categories := map[string]interface{}{
+		"pasm2_math":   keys,
+		"architecture": []string{"p2kbArchCog"},
+	}
+	return newServerWithIndex(t, files, categories, func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(strings.TrimSuffix(path.Base(r.URL.Path), ".yaml"), "p2kbPasm2")
+		if content, ok := quizEntries[name]; ok {
+			_, _ = w.Write([]byte(content))
+			return
+		}
+		_, _ = w.Write([]byte("description: The cog.\n"))
+	})
+}
+
+func TestHandleQuiz(t *testing.T) {
+	srv, cleanup := newQuizServer(t)
+	defer cleanup()
+
+	// The medium answer for each key
+	wantFlags := map[string]string{
+		"p2kbPasm2Mov": "C and Z",
+		"p2kbPasm2Add": "C only",
+		"p2kbPasm2Nop": "Neither C nor Z",
+		"p2kbPasm2Neg": "Z only",
+	}
+	for _, difficulty := range []string{"easy", "medium", "hard"} {
+		for i := 0; i < 20; i++ {
+			args := fmt.Sprintf(`{"category": "pasm2_math", "difficulty": %q}`, difficulty)
+			result := extractResultMap(t, srv.handleQuiz(i, json.RawMessage(args)))
+			if result["type"] != "quiz" {
+				t.Fatalf("%s: result = %v, want a quiz", difficulty, result)
+			}
+			key, _ := result["key"].(string)
+			options, _ := result["options"].([]interface{})
+			if len(options) != quizOptionCount {
+				t.Fatalf("%s: options = %v, want %d", difficulty, options, quizOptionCount)
+			}
+			seen := make(map[interface{}]bool)
+			for _, o := range options {
+				if seen[o] {
+					t.Errorf("%s: option %v repeated in %v", difficulty, o, options)
+				}
+				seen[o] = true
+			}
+			at, _ := result["answer_index"].(float64)
+			if at < 0 || int(at) >= quizOptionCount {
+				t.Fatalf("%s: answer_index = %v", difficulty, result["answer_index"])
+			}
+			answer := options[int(at)]
+
+			switch difficulty {
+			case "easy":
+				if answer != keyMnemonic(key) {
+					t.Errorf("easy answer for %s = %v", key, answer)
+				}
+				if key == "p2kbPasm2Nop" {
+					t.Errorf("easy question from %s, whose description names it", key)
+				}
+			case "medium":
+				if answer != wantFlags[key] {
+					t.Errorf("medium answer for %s = %v, want %s", key, answer, wantFlags[key])
+				}
+			case "hard":
+				if !strings.Contains(quizEntries[strings.TrimPrefix(key, "p2kbPasm2")], answer.(string)) {
+					t.Errorf("hard answer %v is not one of %s's examples", answer, key)
+				}
+			}
+			if explanation, _ := result["explanation"].(string); explanation == "" {
+				t.Errorf("%s: no explanation", difficulty)
+			}
+		}
+	}
+}
+
+func TestHandleQuizErrors(t *testing.T) {
+	srv, cleanup := newQuizServer(t)
+	defer cleanup()
+
+	for _, args := range []string{`{"difficulty": "expert"}`, `{"category": "no_such"}`} {
+		resp := srv.handleQuiz(1, json.RawMessage(args))
+		if resp.Error == nil {
+			t.Errorf("%s: no error", args)
+		}
+	}
+	if resp := srv.handleQuiz(1, json.RawMessage(`{"difficulty": "expert"}`)); resp.Error.Code != -32602 {
+		t.Errorf("bad difficulty code = %d, want -32602", resp.Error.Code)
+	}
+
+	// No instructions to ask about
+	result := extractResultMap(t, srv.handleQuiz(1, json.RawMessage(`{"category": "architecture"}`)))
+	if result["type"] != "quiz_unavailable" {
+		t.Errorf("result = %v, want quiz_unavailable", result)
+	}
+}
+
+func TestFlagAffected(t *testing.T) {
+	flags := map[string]interface{}{"c": "Set on carry", "Z": "Unchanged."}
+	if ok, note := flagAffected(flags, "C"); !ok || note != "Set on carry" {
+		t.Errorf("C = %v %q, want affected", ok, note)
+	}
+	if ok, _ := flagAffected(flags, "Z"); ok {
+		t.Error("Z reported affected")
+	}
+	if ok, _ := flagAffected(map[string]interface{}{"C": nil}, "C"); ok {
+		t.Error("empty C reported affected")
+	}
+}
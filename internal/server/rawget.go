@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ironsheep/p2kb-mcp/internal/cache"
+	"github.com/ironsheep/p2kb-mcp/internal/filter"
+	"github.com/ironsheep/p2kb-mcp/internal/index"
+)
+
+// rawGetTool is the p2kb_raw_get definition, listed by GetToolDefinitions only
+// when debug tools are enabled.
+var rawGetTool = Tool{
+	Name: "p2kb_raw_get",
+	Description: `For P2 Knowledge Base contributors and validators: fetch a KB entry's YAML exactly as published, including the metadata fields (last_updated, enhancement_source, ...) that p2kb_get strips.
+Always downloads a fresh copy; filtered_line_count says how many lines p2kb_get would remove.
+Takes an exact key or alias, not a natural-language query. Only available with P2KB_ENABLE_DEBUG_TOOLS=true.`,
+	InputSchema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Exact key (e.g., \"p2kbPasm2Mov\") or alias (e.g., \"MOV\")",
+			},
+		},
+		"required": []string{"key"},
+	},
+}
+
+// handleRawGet implements p2kb_raw_get - a key's content without metadata
+// filtering. Like the tool definition, it only exists with debug tools enabled.
+func (s *Server) handleRawGet(id interface{}, args json.RawMessage) *MCPResponse {
+	if !debugToolsEnabled() {
+		return s.errorResponse(id, -32601, "Unknown tool", "p2kb_raw_get")
+	}
+
+	var params struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+	if params.Key == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "key")
+	}
+
+	// The filtered copy is cached under the canonical key, as p2kb_get would
+	key := params.Key
+	if resolution := s.indexManager.ResolveKey(params.Key); resolution.Found {
+		key = resolution.CanonicalKey
+	}
+	raw, err := s.getRawContent(key)
+	if err != nil {
+		data := map[string]interface{}{"error": err.Error(), "key": params.Key}
+		var verr *cache.VerificationError
+		if errors.As(err, &verr) {
+			return s.errorResponse(id, -32001, fmt.Sprintf("Content for '%s' is temporarily unavailable — verification failed", key), data)
+		}
+		addRetryAfter(data, err)
+		return s.errorResponse(id, managerErrorCode(err), fmt.Sprintf("Failed to fetch raw content for '%s'", params.Key), data)
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":                "raw_content",
+		"key":                 key,
+		"content":             raw,
+		"filtered_line_count": filter.CountFilteredLines(raw),
+	})
+}
+
+// getRawContent is getContent without the cache tiers: it always downloads
+// key's content and returns it unfiltered.
+func (s *Server) getRawContent(key string) (string, error) {
+	path, mtime, sha256, err := s.indexManager.GetKeyPath(key)
+	if err != nil {
+		return "", err
+	}
+
+	baseURL := cache.BaseContentURL
+	if source := s.indexManager.GetKeySource(key); source != "" && source != index.IndexURL {
+		baseURL = index.ContentBaseURL(source)
+	}
+	return s.cacheManager.FetchRaw(baseURL, key, path, sha256, mtime)
+}
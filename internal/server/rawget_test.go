@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const rawGetYAML = "mnemonic: ADD\nlast_updated: 2025-01-01\nenhancement_source: |\n  manual pass\n  by hand\ndescription: Add S to D\n"
+
+func newServerWithRawContent(t *testing.T) (srv *Server, fetches *int32, cleanup func()) {
+	t.Helper()
+	files := map[string]interface{}{
+		"p2kbPasm2Add": map[string]interface{}{"path": "pasm2/add.yaml", "mtime": 1700000000},
+	}
+	fetches = new(int32)
+	srv, cleanup = newServerWithFilesAndContent(t, files, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(fetches, 1)
+		_, _ = w.Write([]byte(rawGetYAML))
+	})
+	return srv, fetches, cleanup
+}
+
+func callRawGet(srv *Server, args string) *MCPResponse {
+	params, _ := json.Marshal(map[string]interface{}{
+		"name":      "p2kb_raw_get",
+		"arguments": json.RawMessage(args),
+	})
+	return srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: params})
+}
+
+func TestRawGetRequiresDebugTools(t *testing.T) {
+	t.Setenv("P2KB_ENABLE_DEBUG_TOOLS", "")
+	srv, _, cleanup := newServerWithRawContent(t)
+	defer cleanup()
+
+	for _, tool := range GetToolDefinitions() {
+		if tool.Name == "p2kb_raw_get" {
+			t.Fatal("p2kb_raw_get listed without P2KB_ENABLE_DEBUG_TOOLS")
+		}
+	}
+	resp := callRawGet(srv, `{"key": "p2kbPasm2Add"}`)
+	if resp.Error == nil || resp.Error.Code != -32601 {
+		t.Errorf("p2kb_raw_get without P2KB_ENABLE_DEBUG_TOOLS = %+v, want -32601", resp)
+	}
+
+	t.Setenv("P2KB_ENABLE_DEBUG_TOOLS", "true")
+	tools := GetToolDefinitions()
+	if tools[len(tools)-1].Name != "p2kb_raw_get" {
+		t.Error("p2kb_raw_get not listed with P2KB_ENABLE_DEBUG_TOOLS=true")
+	}
+}
+
+func TestRawGetReturnsUnfilteredContent(t *testing.T) {
+	t.Setenv("P2KB_ENABLE_DEBUG_TOOLS", "true")
+	srv, fetches, cleanup := newServerWithRawContent(t)
+	defer cleanup()
+
+	result := extractResultMap(t, callRawGet(srv, `{"key": "p2kbPasm2Add"}`))
+	if result["type"] != "raw_content" || result["content"] != rawGetYAML {
+		t.Fatalf("result = %v, want the YAML as served", result)
+	}
+	if result["filtered_line_count"] != float64(4) {
+		t.Errorf("filtered_line_count = %v, want 4", result["filtered_line_count"])
+	}
+
+	// Only the filtered form is cached, and p2kb_get serves it without a fetch
+	content, err := srv.getContent("p2kbPasm2Add")
+	if err != nil {
+		t.Fatalf("getContent: %v", err)
+	}
+	if strings.Contains(content, "last_updated") || strings.Contains(content, "manual pass") {
+		t.Errorf("cached content is unfiltered: %q", content)
+	}
+	if !strings.Contains(content, "description: Add S to D") {
+		t.Errorf("cached content = %q, want the description kept", content)
+	}
+	if *fetches != 1 {
+		t.Errorf("fetches = %d, want 1", *fetches)
+	}
+
+	// Raw always fetches, since the cache holds no raw copy
+	callRawGet(srv, `{"key": "p2kbPasm2Add"}`)
+	if *fetches != 2 {
+		t.Errorf("fetches = %d after a second raw get, want 2", *fetches)
+	}
+}
+
+func TestRawGetErrors(t *testing.T) {
+	t.Setenv("P2KB_ENABLE_DEBUG_TOOLS", "true")
+	srv, fetches, cleanup := newServerWithRawContent(t)
+	defer cleanup()
+
+	for _, args := range []string{`{}`, `{"key": "p2kbPasm2Nope"}`} {
+		resp := callRawGet(srv, args)
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("p2kb_raw_get %s = %+v, want -32602", args, resp)
+		}
+	}
+	if *fetches != 0 {
+		t.Errorf("fetches = %d, want none for bad keys", *fetches)
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+)
+
+// panicDetailLimit is how many bytes of the stack trace a recovered panic's
+// error response carries; the full trace goes to the log.
+const panicDetailLimit = 200
+
+// recoverPanic, deferred by a handler call, turns a panic into a -32603
+// response stored in *resp, so one bad request cannot bring down the server.
+// The full stack trace is logged to stderr. what names the call for the log.
+func (s *Server) recoverPanic(id interface{}, what string, resp **MCPResponse) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	slog.Error("recovered panic", "call", what, "panic", fmt.Sprint(r), "stack", string(stack))
+
+	detail := stack
+	if len(detail) > panicDetailLimit {
+		detail = detail[:panicDetailLimit]
+	}
+	*resp = s.errorResponse(id, -32603, "Internal error", map[string]interface{}{
+		"panic":  "recovered",
+		"detail": strings.ToValidUTF8(string(detail), ""),
+	})
+}
+
+// safeCallTool runs the named tool's handler, recovering from a panic in it.
+func (s *Server) safeCallTool(ctx context.Context, id interface{}, name string, args json.RawMessage) (resp *MCPResponse) {
+	defer s.recoverPanic(id, name, &resp)
+	return s.callTool(ctx, id, name, args)
+}
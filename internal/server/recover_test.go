@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestToolPanicRecovered(t *testing.T) {
+	srv := New("1.0.0")
+	srv.obexManager = &MockOBEXManager{Details: newMockOBEXManager().Details, PanicOn: "9999"}
+
+	call := func(id int, query string) *MCPResponse {
+		params, _ := json.Marshal(map[string]interface{}{
+			"name":      "p2kb_obex_get",
+			"arguments": map[string]interface{}{"query": query},
+		})
+		return srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: id, Method: "tools/call", Params: params})
+	}
+
+	// Panicking and healthy calls interleave; run with -race
+	var wg sync.WaitGroup
+	responses := make([]*MCPResponse, 20)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			query := "2811"
+			if i%2 == 0 {
+				query = "9999"
+			}
+			responses[i] = call(i, query)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, resp := range responses {
+		if i%2 == 1 {
+			if resp.Error != nil {
+				t.Errorf("response %d: %s, want object 2811", i, resp.Error.Message)
+			}
+			continue
+		}
+		if resp == nil || resp.Error == nil || resp.Error.Code != -32603 {
+			t.Fatalf("response %d = %+v, want a -32603 error", i, resp)
+		}
+		if resp.ID != i {
+			t.Errorf("response %d has ID %v", i, resp.ID)
+		}
+		data, _ := resp.Error.Data.(map[string]interface{})
+		detail, _ := data["detail"].(string)
+		if data["panic"] != "recovered" || detail == "" || len(detail) > panicDetailLimit {
+			t.Errorf("error data = %v, want recovered with a stack excerpt of at most %d bytes", data, panicDetailLimit)
+		}
+	}
+
+	// The server keeps answering afterwards
+	if resp := call(100, "2811"); resp.Error != nil {
+		t.Fatalf("call after panic: %s", resp.Error.Message)
+	}
+	resp := srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 101, Method: "tools/list"})
+	if resp.Error != nil || !strings.Contains(string(mustMarshal(t, resp.Result)), "p2kb_obex_get") {
+		t.Errorf("tools/list after panic = %+v", resp)
+	}
+}
+
+func TestRequestPanicRecovered(t *testing.T) {
+	// A server with no managers panics in any handler that reaches one
+	srv := &Server{}
+	resp := srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: json.RawMessage(`{"name": "p2kb_version"}`)})
+	if resp == nil || resp.Error == nil || resp.Error.Code != -32603 {
+		t.Fatalf("response = %+v, want a -32603 error", resp)
+	}
+
+	var nilServer *Server
+	resp = nilServer.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 2, Method: "initialize"})
+	if resp == nil || resp.Error == nil || resp.Error.Code != -32603 {
+		t.Errorf("initialize response = %+v, want a -32603 error", resp)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
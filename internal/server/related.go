@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Bounds and default of p2kb_related's depth.
+	minRelatedDepth     = 1
+	maxRelatedDepth     = 3
+	defaultRelatedDepth = 1
+
+	// maxRelatedNodes caps the entries p2kb_related fetches, root included,
+	// however deep it is asked to go.
+	maxRelatedNodes = 20
+)
+
+// relatedNode is one entry of the p2kb_related graph: its content and the
+// keys its related_instructions name, or why it could not be fetched.
+type relatedNode struct {
+	Content string   `json:"content,omitempty"`
+	Related []string `json:"related"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// handleRelated implements p2kb_related - an entry and the entries its
+// related_instructions lead to, depth steps out, each fetched once. A cycle
+// (ADD -> SUB -> ADD) ends where it meets a key already in the graph, and no
+// more than maxRelatedNodes entries are fetched in all.
+func (s *Server) handleRelated(ctx context.Context, id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Key   string `json:"key"`
+		Depth int    `json:"depth"`
+	}
+	params.Depth = defaultRelatedDepth
+	if err := json.Unmarshal(args, &params); err != nil {
+		return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	if strings.TrimSpace(params.Key) == "" {
+		return s.errorResponse(id, -32602, "Missing required parameter", "key")
+	}
+	if params.Depth < minRelatedDepth || params.Depth > maxRelatedDepth {
+		return s.errorResponse(id, -32602, "Invalid depth", fmt.Sprintf("depth must be %d to %d, got %d", minRelatedDepth, maxRelatedDepth, params.Depth))
+	}
+
+	root := params.Key
+	if resolution := s.indexManager.ResolveKey(root); resolution.Found {
+		root = resolution.CanonicalKey
+	}
+	content, err := s.getContentFor(ctx, root)
+	if err != nil {
+		return s.contentErrorResponse(id, root, err)
+	}
+
+	nodes := map[string]*relatedNode{root: s.newRelatedNode(content)}
+	level := []string{root}
+	truncated := false
+	for depth := 0; depth < params.Depth && len(level) > 0; depth++ {
+		var next []string
+		for _, key := range level {
+			for _, related := range nodes[key].Related {
+				if _, visited := nodes[related]; visited {
+					continue
+				}
+				if len(nodes) == maxRelatedNodes {
+					truncated = true
+					break
+				}
+				node := &relatedNode{Related: []string{}}
+				if content, err := s.getContentFor(ctx, related); err != nil {
+					node.Error = err.Error()
+				} else {
+					node = s.newRelatedNode(content)
+				}
+				nodes[related] = node
+				next = append(next, related)
+			}
+		}
+		level = next
+	}
+
+	result := map[string]interface{}{
+		"root":       root,
+		"depth":      params.Depth,
+		"nodes":      nodes,
+		"node_count": len(nodes),
+	}
+	if root != params.Key {
+		result["resolved_from"] = params.Key
+	}
+	if truncated {
+		result["truncated"] = true
+	}
+	return s.successResponse(id, result)
+}
+
+// newRelatedNode returns the graph node for content, its related keys
+// resolved to canonical keys where the index knows them.
+func (s *Server) newRelatedNode(content string) *relatedNode {
+	node := &relatedNode{Content: content, Related: []string{}}
+	seen := make(map[string]bool)
+	for _, related := range extractRelatedInstructions(content) {
+		if resolution := s.indexManager.ResolveKey(related); resolution.Found {
+			related = resolution.CanonicalKey
+		}
+		if !seen[related] {
+			seen[related] = true
+			node.Related = append(node.Related, related)
+		}
+	}
+	return node
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newRelatedServer serves one entry per key of links, each naming its
+// values under related_instructions.
+func newRelatedServer(t *testing.T, links map[string][]string) (*Server, func()) {
+	t.Helper()
+	files := make(map[string]interface{}, len(links))
+	for key := range links {
+		files[key] = map[string]interface{}{"path": "pasm2/" + key + ".yaml", "mtime": 1700000000}
+	}
+	return newServerWithFilesAndContent(t, files, func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/pasm2/"), ".yaml")
+		related, ok := links[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		content := "mnemonic: " + key + "\n"
+		if len(related) > 0 {
+			content += "related_instructions:\n  - " + strings.Join(related, "\n  - ") + "\n"
+		}
+		_, _ = w.Write([]byte(content))
+	})
+}
+
+// relatedGraph calls p2kb_related and returns the result and the keys of its
+// nodes, sorted.
+func relatedGraph(t *testing.T, srv *Server, key string, depth int) (map[string]interface{}, []string) {
+	t.Helper()
+	args, _ := json.Marshal(map[string]interface{}{"key": key, "depth": depth})
+	result := extractResultMap(t, srv.handleRelated(context.Background(), 1, args))
+	nodes, _ := result["nodes"].(map[string]interface{})
+	keys := make([]string, 0, len(nodes))
+	for k := range nodes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return result, keys
+}
+
+func TestHandleRelatedTwoLevels(t *testing.T) {
+	srv, cleanup := newRelatedServer(t, map[string][]string{
+		"p2kbPasm2Add":   {"p2kbPasm2Addx", "p2kbPasm2Sub"},
+		"p2kbPasm2Addx":  {"p2kbPasm2Addsx"},
+		"p2kbPasm2Sub":   {"p2kbPasm2Add"},
+		"p2kbPasm2Addsx": {"p2kbPasm2Cmp"},
+		"p2kbPasm2Cmp":   nil,
+	})
+	defer cleanup()
+
+	result, keys := relatedGraph(t, srv, "p2kbPasm2Add", 2)
+	if want := []string{"p2kbPasm2Add", "p2kbPasm2Addsx", "p2kbPasm2Addx", "p2kbPasm2Sub"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("nodes = %v, want %v", keys, want)
+	}
+	if result["root"] != "p2kbPasm2Add" || result["node_count"] != float64(4) {
+		t.Errorf("root = %v, node_count = %v", result["root"], result["node_count"])
+	}
+	if _, ok := result["truncated"]; ok {
+		t.Error("small graph reported truncated")
+	}
+
+	nodes := result["nodes"].(map[string]interface{})
+	root := nodes["p2kbPasm2Add"].(map[string]interface{})
+	if !reflect.DeepEqual(root["related"], []interface{}{"p2kbPasm2Addx", "p2kbPasm2Sub"}) {
+		t.Errorf("root related = %v", root["related"])
+	}
+	if content, _ := root["content"].(string); !strings.Contains(content, "mnemonic: p2kbPasm2Add") {
+		t.Errorf("root content = %q", content)
+	}
+	// The last level names its related keys without fetching them
+	addsx := nodes["p2kbPasm2Addsx"].(map[string]interface{})
+	if !reflect.DeepEqual(addsx["related"], []interface{}{"p2kbPasm2Cmp"}) {
+		t.Errorf("p2kbPasm2Addsx related = %v, want [p2kbPasm2Cmp]", addsx["related"])
+	}
+}
+
+func TestHandleRelatedCycle(t *testing.T) {
+	srv, cleanup := newRelatedServer(t, map[string][]string{
+		"p2kbPasm2Add": {"p2kbPasm2Sub"},
+		"p2kbPasm2Sub": {"p2kbPasm2Add"},
+	})
+	defer cleanup()
+
+	result, keys := relatedGraph(t, srv, "p2kbPasm2Add", maxRelatedDepth)
+	if want := []string{"p2kbPasm2Add", "p2kbPasm2Sub"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("nodes = %v, want %v", keys, want)
+	}
+	sub := result["nodes"].(map[string]interface{})["p2kbPasm2Sub"].(map[string]interface{})
+	if !reflect.DeepEqual(sub["related"], []interface{}{"p2kbPasm2Add"}) {
+		t.Errorf("p2kbPasm2Sub related = %v, want the edge back to the root", sub["related"])
+	}
+}
+
+func TestHandleRelatedDepthLimit(t *testing.T) {
+	srv, cleanup := newRelatedServer(t, map[string][]string{
+		"p2kbPasm2Add":   {"p2kbPasm2Addx"},
+		"p2kbPasm2Addx":  {"p2kbPasm2Addsx"},
+		"p2kbPasm2Addsx": nil,
+	})
+	defer cleanup()
+
+	if _, keys := relatedGraph(t, srv, "p2kbPasm2Add", 1); !reflect.DeepEqual(keys, []string{"p2kbPasm2Add", "p2kbPasm2Addx"}) {
+		t.Errorf("depth 1 nodes = %v, want the root and its direct related key", keys)
+	}
+
+	for _, depth := range []int{0, maxRelatedDepth + 1} {
+		args, _ := json.Marshal(map[string]interface{}{"key": "p2kbPasm2Add", "depth": depth})
+		if resp := srv.handleRelated(context.Background(), 1, args); resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("depth %d: error = %v, want -32602", depth, resp.Error)
+		}
+	}
+}
+
+func TestHandleRelatedNodeLimit(t *testing.T) {
+	links := map[string][]string{"p2kbPasm2Add": nil}
+	for i := 1; i <= 25; i++ {
+		key := fmt.Sprintf("p2kbPasm2Op%02d", i)
+		links["p2kbPasm2Add"] = append(links["p2kbPasm2Add"], key)
+		links[key] = nil
+	}
+	srv, cleanup := newRelatedServer(t, links)
+	defer cleanup()
+
+	result, keys := relatedGraph(t, srv, "p2kbPasm2Add", 1)
+	if len(keys) != maxRelatedNodes || result["truncated"] != true {
+		t.Errorf("got %d nodes, truncated = %v; want %d and true", len(keys), result["truncated"], maxRelatedNodes)
+	}
+}
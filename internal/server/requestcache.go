@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRequestCacheTTL is how long a p2kb_get or p2kb_obex_get response is
+// reused for an identical call. Override with P2KB_REQUEST_CACHE_TTL_SECS.
+const DefaultRequestCacheTTL = 5 * time.Minute
+
+// maxRequestCacheEntries caps the responses requestCache holds; storing
+// another drops the one that expires soonest.
+const maxRequestCacheEntries = 256
+
+// cachedTools are the tools whose successful responses requestCache keeps.
+var cachedTools = map[string]bool{
+	"p2kb_get":      true,
+	"p2kb_obex_get": true,
+}
+
+// wantsFreshContent reports whether a call's arguments set bypass_cache, which
+// no stored response can satisfy.
+func wantsFreshContent(args json.RawMessage) bool {
+	var params struct {
+		BypassCache bool `json:"bypass_cache"`
+	}
+	return json.Unmarshal(args, &params) == nil && params.BypassCache
+}
+
+// cachedResponse is a tool response, the keys its call served (see
+// withServedKeys) and when it stops being reused.
+type cachedResponse struct {
+	resp    *MCPResponse
+	served  []string
+	expires time.Time
+}
+
+// requestCache remembers recent tool responses by toolCallKey, so an agent
+// asking for the same entry again is answered without touching the content
+// or OBEX caches. Unlike callGroup it serves calls that arrive after the
+// first has returned. The zero value is ready to use.
+type requestCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+// get returns the response stored for key and the keys its call served, or
+// a nil response if there is none or it expired before now.
+func (c *requestCache) get(key string, now time.Time) (resp *MCPResponse, served []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, nil
+	}
+	return entry.resp, entry.served
+}
+
+// put stores resp and the keys its call served for key until expires. It
+// drops entries that have expired by then, so the map does not grow with
+// one-off calls, and the soonest to expire while it holds
+// maxRequestCacheEntries.
+func (c *requestCache) put(key string, resp *MCPResponse, served []string, now, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]cachedResponse)
+	}
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	for len(c.entries) >= maxRequestCacheEntries {
+		soonest := ""
+		for k, entry := range c.entries {
+			if soonest == "" || entry.expires.Before(c.entries[soonest].expires) {
+				soonest = k
+			}
+		}
+		delete(c.entries, soonest)
+	}
+	c.entries[key] = cachedResponse{resp: resp, served: served, expires: expires}
+}
+
+// clear drops every stored response.
+func (c *requestCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// servedKeysKey is the context key under which withServedKeys stores its
+// collector.
+type servedKeysKey struct{}
+
+// withServedKeys returns ctx collecting the keys recordServed is given while
+// a call runs, so a cached response can add them to the history again when
+// it is reused.
+func withServedKeys(ctx context.Context) (context.Context, *recentKeys) {
+	served := &recentKeys{}
+	return context.WithValue(ctx, servedKeysKey{}, served), served
+}
+
+// recordServed adds key to the keys p2kb_get served recently, and to those
+// ctx collects, if any.
+func (s *Server) recordServed(ctx context.Context, key string) {
+	s.history.add(key)
+	if served, ok := ctx.Value(servedKeysKey{}).(*recentKeys); ok {
+		served.add(key)
+	}
+}
+
+// parseRequestCacheTTL returns v, the P2KB_REQUEST_CACHE_TTL_SECS setting, as
+// a duration, or DefaultRequestCacheTTL if it is unset or invalid. 0 disables
+// the cache.
+func parseRequestCacheTTL(v string) time.Duration {
+	if v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return DefaultRequestCacheTTL
+}
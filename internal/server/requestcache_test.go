@@ -0,0 +1,171 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRepeatedOBEXGetServedFromRequestCache(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	mock := newMockOBEXManager()
+	srv.obexManager = mock
+
+	getCalls := func() int {
+		n := 0
+		for _, call := range mock.Calls {
+			if call == "GetObject(2811)" {
+				n++
+			}
+		}
+		return n
+	}
+	call := func(id int, name string, args map[string]interface{}) *MCPResponse {
+		t.Helper()
+		params, _ := json.Marshal(map[string]interface{}{"name": name, "arguments": args})
+		resp := srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: id, Method: "tools/call", Params: params})
+		if resp.Error != nil {
+			t.Fatalf("%s: %s", name, resp.Error.Message)
+		}
+		return resp
+	}
+	get := map[string]interface{}{"query": "2811"}
+
+	first := call(1, "p2kb_obex_get", get)
+	second := call(2, "p2kb_obex_get", get)
+	if n := getCalls(); n != 1 {
+		t.Errorf("GetObject called %d times for two identical calls, want 1", n)
+	}
+	if second.ID != 2 {
+		t.Errorf("cached response ID = %v, want 2", second.ID)
+	}
+	if a, b := extractResultMap(t, first), extractResultMap(t, second); a["object_id"] != "2811" || b["object_id"] != "2811" {
+		t.Errorf("responses = %v and %v, want object 2811 twice", a, b)
+	}
+
+	// Different arguments are a different request
+	call(3, "p2kb_obex_get", map[string]interface{}{"query": "2811", "microcontroller": "P2"})
+	if n := getCalls(); n != 2 {
+		t.Errorf("GetObject called %d times after a call with new arguments, want 2", n)
+	}
+
+	// p2kb_refresh drops every cached response
+	call(4, "p2kb_refresh", map[string]interface{}{})
+	call(5, "p2kb_obex_get", get)
+	if n := getCalls(); n != 3 {
+		t.Errorf("GetObject called %d times after p2kb_refresh, want 3", n)
+	}
+}
+
+func TestRequestCacheHitRecordsHistory(t *testing.T) {
+	srv, cleanup := newAutoSelectServer(t)
+	defer cleanup()
+
+	for i, key := range []string{"p2kbPasm2Add", "p2kbSpin2Abs", "p2kbPasm2Add"} {
+		params, _ := json.Marshal(map[string]interface{}{"name": "p2kb_get", "arguments": map[string]interface{}{"query": key}})
+		if resp := srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: i, Method: "tools/call", Params: params}); resp.Error != nil {
+			t.Fatalf("p2kb_get %s: %s", key, resp.Error.Message)
+		}
+	}
+
+	// The second p2kbPasm2Add is served from the request cache, and still
+	// makes it the most recent key
+	if got, want := srv.history.list(), []string{"p2kbSpin2Abs", "p2kbPasm2Add"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
+
+func TestRequestCacheTTLDisabled(t *testing.T) {
+	t.Setenv("P2KB_REQUEST_CACHE_TTL_SECS", "0")
+	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	srv.obexManager = mock
+
+	params, _ := json.Marshal(map[string]interface{}{"name": "p2kb_obex_get", "arguments": map[string]interface{}{"query": "2811"}})
+	for i := 0; i < 2; i++ {
+		srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: i, Method: "tools/call", Params: params})
+	}
+	n := 0
+	for _, call := range mock.Calls {
+		if call == "GetObject(2811)" {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Errorf("with a TTL of 0, GetObject called %d times for two calls, want 2", n)
+	}
+}
+
+func TestRequestCacheExpiry(t *testing.T) {
+	var c requestCache
+	now := time.Now()
+	resp := &MCPResponse{JSONRPC: "2.0", ID: 1}
+	c.put("a", resp, nil, now, now.Add(time.Minute))
+
+	if got, _ := c.get("a", now.Add(30*time.Second)); got != resp {
+		t.Errorf("get before expiry = %v, want the stored response", got)
+	}
+	if got, _ := c.get("a", now.Add(time.Minute)); got != nil {
+		t.Errorf("get at expiry = %v, want nil", got)
+	}
+
+	// Storing a response sweeps out expired ones
+	c.put("b", resp, nil, now, now.Add(-time.Second))
+	c.put("c", resp, nil, now, now.Add(time.Minute))
+	if _, ok := c.entries["b"]; ok {
+		t.Error("expired entry b kept after put")
+	}
+}
+
+func TestRequestCacheEntryLimit(t *testing.T) {
+	var c requestCache
+	now := time.Now()
+	resp := &MCPResponse{JSONRPC: "2.0", ID: 1}
+	for i := 0; i <= maxRequestCacheEntries; i++ {
+		c.put(fmt.Sprint(i), resp, nil, now, now.Add(time.Minute+time.Duration(i)*time.Second))
+	}
+
+	if len(c.entries) != maxRequestCacheEntries {
+		t.Errorf("%d entries after %d puts, want %d", len(c.entries), maxRequestCacheEntries+1, maxRequestCacheEntries)
+	}
+	if got, _ := c.get("0", now); got != nil {
+		t.Error("entry expiring soonest kept past the limit")
+	}
+	if got, _ := c.get(fmt.Sprint(maxRequestCacheEntries), now); got != resp {
+		t.Error("newest entry dropped")
+	}
+}
+
+func TestRequestCacheTTLSetting(t *testing.T) {
+	srv := New("1.0.0")
+	ttl := func() time.Duration {
+		return parseRequestCacheTTL(srv.getenv("P2KB_REQUEST_CACHE_TTL_SECS"))
+	}
+	for _, tt := range []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", DefaultRequestCacheTTL},
+		{"60", time.Minute},
+		{"0", 0},
+		{"-1", DefaultRequestCacheTTL},
+		{"soon", DefaultRequestCacheTTL},
+	} {
+		t.Setenv("P2KB_REQUEST_CACHE_TTL_SECS", tt.env)
+		if got := ttl(); got != tt.want {
+			t.Errorf("P2KB_REQUEST_CACHE_TTL_SECS=%q: TTL = %v, want %v", tt.env, got, tt.want)
+		}
+	}
+
+	// A value set through p2kb_settings wins over the environment
+	t.Setenv("P2KB_REQUEST_CACHE_TTL_SECS", "60")
+	if resp := callSettings(srv, map[string]interface{}{"key": "P2KB_REQUEST_CACHE_TTL_SECS", "value": "0"}); resp.Error != nil {
+		t.Fatalf("p2kb_settings failed: %+v", resp.Error)
+	}
+	if got := ttl(); got != 0 {
+		t.Errorf("TTL after p2kb_settings = %v, want 0 (off)", got)
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxResponseBytes caps the text of a tool result. Override with
+// P2KB_MAX_RESPONSE_BYTES.
+const DefaultMaxResponseBytes = 512 * 1024
+
+// truncationMarker ends a result text cut down by limitResponseSize.
+const truncationMarker = "\n... [TRUNCATED: response exceeded max size]"
+
+// limitResponseSize cuts a successful tool result whose text exceeds
+// maxBytes down to fit, ending it with truncationMarker and adding
+// "response_truncated": true beside the content. Some MCP clients fail on
+// multi-megabyte messages. The text is no longer valid JSON once cut, but it
+// stays valid UTF-8 and never ends inside an escape sequence.
+func limitResponseSize(tool string, resp *MCPResponse, maxBytes int) *MCPResponse {
+	if resp == nil || resp.Error != nil {
+		return resp
+	}
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		return resp
+	}
+	content, ok := result["content"].([]map[string]interface{})
+	if !ok || len(content) == 0 {
+		return resp
+	}
+	text, ok := content[0]["text"].(string)
+	if !ok || len(text) <= maxBytes {
+		return resp
+	}
+
+	keep := maxBytes - len(truncationMarker)
+	if keep < 0 {
+		keep = 0
+	}
+	slog.Warn("tool response exceeded max size, truncating", "tool", tool, "size", len(text), "max", maxBytes)
+
+	// Copy rather than modify: the response may be shared by coalesced calls
+	truncated := make([]map[string]interface{}, len(content))
+	copy(truncated, content)
+	first := make(map[string]interface{}, len(content[0]))
+	for k, v := range content[0] {
+		first[k] = v
+	}
+	first["text"] = truncateJSONText(text, keep) + truncationMarker
+	truncated[0] = first
+
+	limited := make(map[string]interface{}, len(result)+1)
+	for k, v := range result {
+		limited[k] = v
+	}
+	limited["content"] = truncated
+	limited["response_truncated"] = true
+
+	out := *resp
+	out.Result = limited
+	return &out
+}
+
+// truncateJSONText returns the longest prefix of the JSON text s, at most n
+// bytes, that ends on a whole UTF-8 character and not inside a backslash
+// escape such as \n or \u00e9.
+func truncateJSONText(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	s = s[:n]
+
+	// An unfinished \uXXXX escape: drop it whole
+	if i := strings.LastIndex(s, `\u`); i >= 0 && len(s)-i < 6 && trailingBackslashes(s[:i+1])%2 == 1 {
+		s = s[:i]
+	}
+	// A lone backslash starts an escape whose character was cut off
+	if trailingBackslashes(s)%2 == 1 {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// trailingBackslashes counts the backslashes at the end of s.
+func trailingBackslashes(s string) int {
+	n := 0
+	for n < len(s) && s[len(s)-1-n] == '\\' {
+		n++
+	}
+	return n
+}
+
+// getMaxResponseBytes returns the tool result size cap from environment or default.
+func getMaxResponseBytes() int {
+	if v := os.Getenv("P2KB_MAX_RESPONSE_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultMaxResponseBytes
+}
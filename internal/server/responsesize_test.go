@@ -0,0 +1,89 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLimitResponseSize(t *testing.T) {
+	srv := New("1.0.0")
+	big := map[string]interface{}{
+		"type":    "content",
+		"content": strings.Repeat("Größe \"quoted\"\n", 200),
+	}
+	resp := srv.successResponse(1, big)
+	text := resp.Result.(map[string]interface{})["content"].([]map[string]interface{})[0]["text"].(string)
+
+	// Under the limit the response is returned as is
+	if got := limitResponseSize("p2kb_get", resp, len(text)); got != resp {
+		t.Error("response within the limit was changed")
+	}
+
+	for max := 500; max < 540; max++ {
+		got := limitResponseSize("p2kb_get", resp, max)
+		result := got.Result.(map[string]interface{})
+		if result["response_truncated"] != true {
+			t.Fatalf("max %d: response_truncated = %v, want true", max, result["response_truncated"])
+		}
+		cut := result["content"].([]map[string]interface{})[0]["text"].(string)
+		if len(cut) > max {
+			t.Errorf("max %d: text is %d bytes", max, len(cut))
+		}
+		if !utf8.ValidString(cut) {
+			t.Errorf("max %d: text is not valid UTF-8", max)
+		}
+		body := strings.TrimSuffix(cut, truncationMarker)
+		if body == cut {
+			t.Fatalf("max %d: text does not end with the truncation marker", max)
+		}
+		if !strings.HasPrefix(text, body) || trailingBackslashes(body)%2 == 1 {
+			t.Errorf("max %d: text ends mid-escape: %q", max, body[len(body)-10:])
+		}
+	}
+
+	// The original response, which coalesced callers may share, is untouched
+	if _, ok := resp.Result.(map[string]interface{})["response_truncated"]; ok {
+		t.Error("limitResponseSize modified the original response")
+	}
+}
+
+func TestTruncateJSONText(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{`"abc"`, 3, `"ab`},
+		{`"a\nb"`, 3, `"a`},
+		{`"a\\b"`, 4, `"a\\`},
+		{`"a\u00e9b"`, 6, `"a`},
+		{`"a\u00e9b"`, 8, `"a\u00e9`},
+		{`"aéb"`, 3, `"a`},
+		{`"aéb"`, 4, `"aé`},
+		{`"abc"`, 10, `"abc"`},
+	}
+	for _, tt := range tests {
+		if got := truncateJSONText(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncateJSONText(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestGetMaxResponseBytes(t *testing.T) {
+	tests := []struct {
+		env  string
+		want int
+	}{
+		{"", DefaultMaxResponseBytes},
+		{"65536", 65536},
+		{"0", DefaultMaxResponseBytes},
+		{"lots", DefaultMaxResponseBytes},
+	}
+	for _, tt := range tests {
+		t.Setenv("P2KB_MAX_RESPONSE_BYTES", tt.env)
+		if got := getMaxResponseBytes(); got != tt.want {
+			t.Errorf("P2KB_MAX_RESPONSE_BYTES=%q: got %d, want %d", tt.env, got, tt.want)
+		}
+	}
+}
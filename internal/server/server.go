@@ -3,24 +3,176 @@ package server
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/ironsheep/p2kb-mcp/internal/cache"
 	"github.com/ironsheep/p2kb-mcp/internal/index"
 	"github.com/ironsheep/p2kb-mcp/internal/obex"
+	"github.com/ironsheep/p2kb-mcp/internal/paths"
 )
 
-// Server handles MCP protocol communication over stdio.
+// Server handles MCP protocol communication over stdio or HTTP.
 type Server struct {
 	version      string
-	indexManager *index.Manager
-	cacheManager *cache.Manager
-	obexManager  *obex.Manager
+	indexManager IndexManager
+	cacheManager CacheManager
+	obexManager  OBEXManager
+	history      recentKeys    // Keys p2kb_get served recently, for auto_select
+	obexBuilds   obexBuilds    // Current or last p2kb_obex_build_index run
+	calls        callGroup     // Coalesces identical concurrent tool calls
+	requestCache requestCache  // Recent p2kb_get and p2kb_obex_get responses
+	settings     sync.Map      // Setting name -> value applied by p2kb_settings, ahead of the environment
+	bypass       bypassLimiter // p2kb_get bypass_cache calls this minute
+	telemetry    *telemetry    // Trace spans; nil unless P2KB_OTEL_ENDPOINT is set
+	transport    string        // TransportStdio or TransportHTTP; "" means stdio
+
+	prefetchMu     sync.Mutex
+	indexPreloaded bool  // The startup prefetch loaded the index, guarded by prefetchMu
+	prefetchErr    error // Why the startup prefetch failed, guarded by prefetchMu
+
+	// notify writes a server-initiated message to the stdio client; nil
+	// outside serve. HTTP clients are reached through their request context
+	// instead (see withNotifier).
+	notify func(v interface{})
+}
+
+// notifierKey is the context key under which withNotifier stores a notifier.
+type notifierKey struct{}
+
+// withNotifier returns ctx carrying notify, which sends notifications to the
+// client that made the request handled under ctx.
+func withNotifier(ctx context.Context, notify func(v interface{})) context.Context {
+	return context.WithValue(ctx, notifierKey{}, notify)
+}
+
+// notifier returns the function that sends notifications about the request
+// handled under ctx: its client's own, or else the stdio stream. It is nil
+// when there is neither.
+func (s *Server) notifier(ctx context.Context) func(v interface{}) {
+	if notify, ok := ctx.Value(notifierKey{}).(func(v interface{})); ok {
+		return notify
+	}
+	return s.notify
+}
+
+// IndexManager is the P2KB index as the server uses it. *index.Manager is the
+// production implementation; tests may substitute their own.
+type IndexManager interface {
+	EnsureIndex() error
+	Refresh() error
+	WatchForChanges(ctx context.Context, interval time.Duration, onChange func(newVersion string)) error
+	Close()
+	IsLoaded() bool
+	GetStats() index.Stats
+	GetIndexStatus() index.IndexStatus
+	ResolveKey(key string) index.KeyResolution
+	MatchQuery(query string) ([]index.MatchResult, error)
+	Search(term string, limit int) []string
+	SearchRanked(term string, limit int) []index.RankedResult
+	GetKeyPath(key string) (path string, mtime int64, sha256 string, err error)
+	GetKeySource(key string) string
+	GetKeyCategories(key string) []string
+	GetCategories() []string
+	GetCategoriesWithCounts() map[string]int
+	GetCategoriesByAlias(alias string) []string
+	GetCategoryKeys(category string) ([]string, error)
+	ListKeys(prefix, category string) (keys []index.KeyInfo, total int, err error)
+	GetKeysByMtime(limit int) []index.KeyMtimeResult
+	SortKeysByMtime(keys []string, limit int) []index.KeyMtimeResult
+	GetStaleKeys(cachedKeys []string, getCacheMtime func(key string) int64) []string
 }
 
+// CacheManager is the content cache as the server uses it. *cache.Manager is
+// the production implementation.
+type CacheManager interface {
+	GetOrFetch(key, path, expectedSHA256 string, indexMtime int64) (string, error)
+	GetOrFetchFrom(baseURL, key, path, expectedSHA256 string, indexMtime int64) (string, error)
+	FetchRaw(baseURL, key, path, expectedSHA256 string, indexMtime int64) (string, error)
+	Refetch(baseURL, key, path, expectedSHA256 string, indexMtime int64) (string, error)
+	GetMtime(key string) int64
+	GetCachedKeys() []string
+	GetStats() cache.CacheStats
+	MemoryEntries() []cache.MemoryEntry
+	FindDuplicates() map[string][]string
+	CacheDir() string
+	Clear()
+	Close()
+	InvalidateKeys(keys []string) int
+	EvictMemory(target int) (evicted int, freedBytes int64)
+	DropMemory()
+	SetMaxEntries(n int)
+	MaxEntries() int
+	Pin(keys []string) error
+	Unpin(keys []string) (int, error)
+	IsPinned(key string) bool
+	PinnedKeys() []string
+	Prewarm(fetch func(key string) (string, error)) int
+}
+
+// OBEXManager is the OBEX object store as the server uses it. *obex.Manager is
+// the production implementation.
+type OBEXManager interface {
+	EnsureIndex() error
+	Refresh() (obex.RefreshStats, error)
+	IsIndexLoaded() bool
+	GetTotalObjects() int
+	LocalObjectCount() int
+	GetObject(objectID string) (*obex.OBEXObject, error)
+	GetDownloadURL(objectID string) string
+	SetDownloadURL(objectID, downloadURL string) error
+	LoadAllObjects(ctx context.Context, batchSize int, pause time.Duration, progress func(loaded, failed int)) error
+	IndexCoverage() (loaded, total int)
+	SearchByOBEXPageURL(pageURL string) (*obex.OBEXObject, error)
+	DownloadAndExtract(objectID, targetDir string) (*obex.DownloadResult, error)
+	Search(term, category, language, microcontroller string, limit int) ([]obex.SearchResult, error)
+	SearchWithPhases(term string, filters obex.SearchFilters, limit int) ([]obex.SearchResult, int, error)
+	FindByTags(tokens []string, limit int) []obex.SearchResult
+	SearchByTag(tag string, limit int) ([]obex.SearchResult, error)
+	SearchByTagPrefix(prefix string, limit int) ([]obex.SearchResult, []string, error)
+	BrowseCategory(category string) ([]obex.SearchResult, error)
+	BrowseByAuthor(authorQuery string, limit int) ([]obex.SearchResult, error)
+	BrowseByAuthors(authorQueries []string, limit int) ([]obex.SearchResult, error)
+	BrowseSubcategory(category, subcategory string) ([]obex.SearchResult, error)
+	GetCategories() (map[string]int, error)
+	GetSubcategories(category string) map[string]int
+	GetOverviewStats() (*obex.OverviewStats, error)
+	GetAuthors() ([]obex.AuthorStats, error)
+	MatchAuthors(name string) ([]obex.AuthorStats, error)
+	GetAuthorDetail(author string) (*obex.AuthorDetail, error)
+	GetCorpusStats() (*obex.CorpusStats, error)
+	GetDependencyGraph() (*obex.DependencyGraph, error)
+	GetTagCloud() (*obex.TagCloud, error)
+	GetInvalidObjects() ([]obex.InvalidObject, error)
+	GetCacheStats() (memoryCount, diskCount int, staleCount int)
+	CorruptedCacheEvictions() int64
+	LastMirrorUsed() string
+	AuthConfigured() bool
+	MemoryObjects() []obex.MemoryObject
+	ClearCache() int
+	EvictMemoryObjects(target int) int
+	ConcurrencyLimit() int
+	SetConcurrencyLimit(n int)
+	PendingFetches() int64
+}
+
+// The production managers must satisfy the server's interfaces.
+var (
+	_ IndexManager = (*index.Manager)(nil)
+	_ CacheManager = (*cache.Manager)(nil)
+	_ OBEXManager  = (*obex.Manager)(nil)
+)
+
 // MCPRequest represents an incoming JSON-RPC 2.0 request.
 type MCPRequest struct {
 	JSONRPC string          `json:"jsonrpc"`
@@ -37,6 +189,13 @@ type MCPResponse struct {
 	Error   *MCPError   `json:"error,omitempty"`
 }
 
+// MCPNotification represents an outgoing JSON-RPC 2.0 notification (no id).
+type MCPNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
 // MCPError represents a JSON-RPC 2.0 error object.
 type MCPError struct {
 	Code    int         `json:"code"`
@@ -46,65 +205,325 @@ type MCPError struct {
 
 // New creates and initializes a new MCP server instance.
 func New(version string) *Server {
+	cacheManager := cache.NewManager()
+	seedCache(cacheManager)
+
 	return &Server{
 		version:      version,
 		indexManager: index.NewManager(),
-		cacheManager: cache.NewManager(),
+		cacheManager: cacheManager,
 		obexManager:  obex.NewManager(),
+		telemetry:    newTelemetryFromEnv(version),
 	}
 }
 
-// Run starts the MCP server's main loop, processing requests from stdin.
+// seedCache loads the offline seed archive named by P2KB_SEED_ARCHIVE, or
+// p2kb-cache.zip in the cache directory if present. Failures are logged only:
+// a bad seed must not keep the server from starting.
+func seedCache(cacheManager *cache.Manager) {
+	archivePath := os.Getenv("P2KB_SEED_ARCHIVE")
+	if archivePath == "" {
+		archivePath = filepath.Join(paths.GetCacheDirOrDefault(), cache.SeedArchiveName)
+		if _, err := os.Stat(archivePath); err != nil {
+			return
+		}
+	}
+
+	loaded, err := cacheManager.LoadFromSeedArchive(archivePath)
+	if err != nil {
+		slog.Error("failed to load seed archive", "path", archivePath, "error", err)
+		return
+	}
+	slog.Info("seeded cache entries", "entries", loaded, "path", archivePath)
+}
+
+// DefaultShutdownTimeout is how long Run waits for in-flight requests after
+// SIGTERM or SIGINT before giving up on them.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Run starts the MCP server's main loop, processing requests from stdin, or
+// delegates to RunHTTP on P2KB_HTTP_ADDR when the HTTP transport is selected.
+// It returns nil when stdin closes, or after a graceful shutdown on SIGTERM or
+// SIGINT; it returns an error if in-flight requests outlast the grace period.
 func (s *Server) Run() error {
-	scanner := bufio.NewScanner(os.Stdin)
-	// Increase buffer size for large requests
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	if s.transport == TransportHTTP {
+		return s.RunHTTP(s.httpAddr())
+	}
+
+	ctx, stop := s.start()
+	defer stop()
+	return s.serve(ctx, os.Stdin, os.Stdout)
+}
 
-	encoder := json.NewEncoder(os.Stdout)
+// start does the work common to every transport: loading the index,
+// pre-warming pinned keys and watching the upstream index in the background.
+// The context is cancelled on SIGTERM or SIGINT; stop releases it and closes
+// the managers.
+func (s *Server) start() (ctx context.Context, stop func()) {
+	// Load the index and pre-warm pinned keys in the background so startup is
+	// not blocked on network I/O
+	go s.prefetchIndex()
+	go s.cacheManager.Prewarm(s.getContent)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	if interval := getIndexWatchInterval(); interval > 0 {
+		go s.watchIndex(ctx, interval)
+	}
+
+	return ctx, func() {
+		cancel()
+		s.telemetry.shutdown() // Exports any spans not yet sent
+		s.cacheManager.Close() // Writes any queued cache files
+		s.indexManager.Close()
+	}
+}
+
+// prefetchIndex loads the index so the first tool call does not wait for the
+// download. A failure is kept in prefetchErr for p2kb_version rather than
+// reported: tools load the index again when they need it and report their own
+// error then.
+func (s *Server) prefetchIndex() {
+	err := s.indexManager.EnsureIndex()
+
+	s.prefetchMu.Lock()
+	s.indexPreloaded = err == nil
+	s.prefetchErr = err
+	s.prefetchMu.Unlock()
+
+	if err != nil {
+		slog.Warn("index prefetch failed, will load on first use", "error", err)
+		return
+	}
+	slog.Debug("index preloaded", "entries", s.indexManager.GetStats().TotalEntries)
+}
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
-			continue
+// prefetchStatus returns whether the startup prefetch loaded the index, and
+// why it failed if it did not. The failure is forgotten once the index has
+// loaded since, as it no longer explains anything.
+func (s *Server) prefetchStatus() (preloaded bool, err error) {
+	s.prefetchMu.Lock()
+	defer s.prefetchMu.Unlock()
+	if s.prefetchErr != nil && s.indexManager.IsLoaded() {
+		s.prefetchErr = nil
+	}
+	return s.indexPreloaded, s.prefetchErr
+}
+
+// getIndexWatchInterval returns P2KB_INDEX_WATCH_INTERVAL_SECS as a duration,
+// or 0 (no watching) if it is unset or invalid.
+func getIndexWatchInterval() time.Duration {
+	if v := os.Getenv("P2KB_INDEX_WATCH_INTERVAL_SECS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
 		}
+	}
+	return 0
+}
 
-		var req MCPRequest
-		if err := json.Unmarshal(line, &req); err != nil {
-			log.Printf("Failed to parse request: %v", err)
-			continue
+// watchIndex refreshes the index whenever the upstream index file gets a new
+// commit, until ctx is done. Responses kept for repeat lookups are dropped
+// with the old index.
+func (s *Server) watchIndex(ctx context.Context, interval time.Duration) {
+	_ = s.indexManager.WatchForChanges(ctx, interval, func(newVersion string) {
+		slog.Info("upstream index changed, refreshing", "commit", newVersion)
+		if err := s.indexManager.Refresh(); err != nil {
+			slog.Error("index refresh after upstream change failed", "error", err)
+			return
 		}
+		s.requestCache.clear()
+	})
+}
 
-		resp := s.handleRequest(&req)
-		if resp != nil {
-			if err := encoder.Encode(resp); err != nil {
-				log.Printf("Failed to encode response: %v", err)
+// serve reads one JSON-RPC request or batch per line from in and writes
+// responses to out. Each request is handled in its own goroutine; writes, including
+// keepalive notifications, are serialized so they never interleave. When ctx
+// is cancelled, serve stops reading, waits for in-flight requests and flushes
+// out before returning.
+func (s *Server) serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	// Read stdin in its own goroutine so a signal is noticed while blocked on
+	// input. It always reports on scanErr before closing lines, even when
+	// cancelled, so the main loop never waits on scanErr for nothing.
+	lines := make(chan []byte)
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		defer func() { scanErr <- scanner.Err() }()
+		// Increase buffer size for large requests
+		buf := make([]byte, 0, 64*1024)
+		scanner.Buffer(buf, 1024*1024)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
 			}
 		}
+	}()
+
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+	var writeMu sync.Mutex
+	var inFlight sync.WaitGroup
+
+	// respond writes a response, or the array of responses to a batch
+	respond := func(resp interface{}) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := encoder.Encode(resp); err != nil {
+			slog.Error("failed to encode response", "error", err)
+		}
+		if err := writer.Flush(); err != nil {
+			slog.Error("failed to write response", "error", err)
+		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("scanner error: %w", err)
+	// Notifications share the write lock so they never interleave with responses
+	s.notify = func(v interface{}) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := encoder.Encode(v); err != nil {
+			slog.Error("failed to encode notification", "error", err)
+		}
+		if err := writer.Flush(); err != nil {
+			slog.Error("failed to write notification", "error", err)
+		}
 	}
 
+	for {
+		select {
+		case <-ctx.Done():
+			return shutdown(&inFlight, func() {
+				writeMu.Lock()
+				defer writeMu.Unlock()
+				_ = writer.Flush()
+			})
+
+		case line, ok := <-lines:
+			if !ok {
+				inFlight.Wait()
+				if err := <-scanErr; err != nil {
+					return fmt.Errorf("scanner error: %w", err)
+				}
+				return nil
+			}
+			if len(line) == 0 {
+				continue
+			}
+
+			if isBatch(line) {
+				var reqs []MCPRequest
+				if err := json.Unmarshal(line, &reqs); err != nil {
+					slog.Warn("failed to parse batch request", "error", err)
+					continue
+				}
+
+				inFlight.Add(1)
+				go func() {
+					defer inFlight.Done()
+					resps := s.handleBatch(context.Background(), reqs)
+					switch {
+					case len(reqs) == 0:
+						// An empty batch is answered with one error, not an array
+						respond(resps[0])
+					case len(resps) > 0:
+						// A batch of only notifications gets no response at all
+						respond(resps)
+					}
+				}()
+				continue
+			}
+
+			var req MCPRequest
+			if err := json.Unmarshal(line, &req); err != nil {
+				slog.Warn("failed to parse request", "error", err)
+				continue
+			}
+
+			inFlight.Add(1)
+			go func() {
+				defer inFlight.Done()
+				if resp := s.handleRequestContext(context.Background(), &req); resp != nil {
+					respond(resp)
+				}
+			}()
+		}
+	}
+}
+
+// shutdown waits up to the shutdown timeout for in-flight requests, then
+// flushes any buffered output.
+func shutdown(inFlight *sync.WaitGroup, flush func()) error {
+	done := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(done)
+	}()
+
+	timeout := getShutdownTimeout()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		return fmt.Errorf("shutdown timed out after %s with requests still in flight", timeout)
+	}
+
+	flush()
+	slog.Info("shutting down gracefully")
 	return nil
 }
 
-// Supported MCP protocol versions, with serverMaxVersion as the default response.
-const serverMaxVersion = "2025-06-18"
+// getShutdownTimeout returns the shutdown grace period from environment or default.
+func getShutdownTimeout() time.Duration {
+	if v := os.Getenv("P2KB_SHUTDOWN_TIMEOUT_SECS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return DefaultShutdownTimeout
+}
+
+// supportedProtocolVersions lists the MCP protocol versions the server
+// speaks, newest first. MCP versions are dates, so they order as strings.
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// serverMaxVersion is the newest supported version, the response to a client
+// whose version the server does not support.
+var serverMaxVersion = supportedProtocolVersions[0]
 
-var supportedProtocolVersions = map[string]bool{
-	"2024-11-05": true,
-	"2025-03-26": true,
-	"2025-06-18": true,
+// negotiateProtocolVersion returns the version to answer a client requesting
+// clientVersion with: the client's own when supported, otherwise the newest
+// the server supports. A client older than every supported version is logged
+// as deprecated; it may not understand the response.
+func negotiateProtocolVersion(clientVersion string) string {
+	for _, v := range supportedProtocolVersions {
+		if v == clientVersion {
+			return v
+		}
+	}
+	oldest := supportedProtocolVersions[len(supportedProtocolVersions)-1]
+	if clientVersion != "" && clientVersion < oldest {
+		slog.Warn("client requested deprecated MCP protocol version",
+			"version", clientVersion, "oldest_supported", oldest, "answering_with", serverMaxVersion)
+	}
+	return serverMaxVersion
 }
 
-// handleRequest routes JSON-RPC requests to the appropriate handler method.
+// handleRequest is handleRequestContext for a request from no particular
+// client.
 func (s *Server) handleRequest(req *MCPRequest) *MCPResponse {
+	return s.handleRequestContext(context.Background(), req)
+}
+
+// handleRequestContext routes JSON-RPC requests to the appropriate handler
+// method; ctx carries the client's notifier, if it has its own. A panic in a
+// handler is recovered and answered with a -32603 error.
+func (s *Server) handleRequestContext(ctx context.Context, req *MCPRequest) (resp *MCPResponse) {
 	// Notifications (no id) MUST NOT receive a response per JSON-RPC 2.0.
 	if req.ID == nil {
 		return nil
 	}
+	defer s.recoverPanic(req.ID, req.Method, &resp)
 
 	switch req.Method {
 	case "initialize":
@@ -112,7 +531,11 @@ func (s *Server) handleRequest(req *MCPRequest) *MCPResponse {
 	case "tools/list":
 		return s.handleToolsList(req)
 	case "tools/call":
-		return s.handleToolsCall(req)
+		return s.handleToolsCall(ctx, req)
+	case "prompts/list":
+		return s.handlePromptsList(req)
+	case "prompts/get":
+		return s.handlePromptsGet(req)
 	case "ping":
 		return &MCPResponse{
 			JSONRPC: "2.0",
@@ -139,9 +562,7 @@ func (s *Server) handleInitialize(req *MCPRequest) *MCPResponse {
 			ProtocolVersion string `json:"protocolVersion"`
 		}
 		if err := json.Unmarshal(req.Params, &params); err == nil {
-			if supportedProtocolVersions[params.ProtocolVersion] {
-				negotiatedVersion = params.ProtocolVersion
-			}
+			negotiatedVersion = negotiateProtocolVersion(params.ProtocolVersion)
 		}
 	}
 
@@ -151,11 +572,13 @@ func (s *Server) handleInitialize(req *MCPRequest) *MCPResponse {
 		Result: map[string]interface{}{
 			"protocolVersion": negotiatedVersion,
 			"capabilities": map[string]interface{}{
-				"tools": map[string]interface{}{},
+				"tools":   map[string]interface{}{},
+				"prompts": map[string]interface{}{},
 			},
 			"serverInfo": map[string]interface{}{
-				"name":    "p2kb-mcp",
-				"version": s.version,
+				"name":                        "p2kb-mcp",
+				"version":                     s.version,
+				"supported_protocol_versions": supportedProtocolVersions,
 			},
 			"instructions": serverInstructions,
 		},
@@ -174,9 +597,34 @@ For ANY question about P2 architecture, PASM2 instructions, or Spin2 syntax/meth
 
 Tool selection:
 - p2kb_get        — fetch a specific instruction, method, or concept by name or natural-language query
+- p2kb_batch_get  — fetch up to 20 known keys at once, e.g. a family of related instructions
+- p2kb_compare    — set 2-4 instructions side by side (syntax, flags, description)
+- p2kb_related    — an instruction with its related instructions, up to 3 steps out
 - p2kb_find       — discover what's documented; list categories or search keys
+- p2kb_category_tree — categories grouped by prefix (pasm2 → math, branch, ...)
+- p2kb_discover   — not sure if it is documentation or community code? search the KB and OBEX at once
 - p2kb_obex_get   — look up a specific community OBEX object by ID or description
 - p2kb_obex_find  — browse OBEX objects by category, author, or keyword
+- p2kb_obex_author_detail — an OBEX author's portfolio: categories, tags, languages, objects
+- p2kb_obex_tag_search — OBEX objects carrying a tag, or any tag starting with a prefix
+- p2kb_obex_stats — aggregate OBEX statistics: languages, categories, quality, link coverage
+- p2kb_obex_dependency_graph — which OBEX objects mention each other in their descriptions
 - p2kb_obex_download — download and extract an OBEX object's source
+- p2kb_obex_preview — list an OBEX object's ZIP and peek at its first file (needs P2KB_ENABLE_DOWNLOADS=true)
+- p2kb_obex_readme — Markdown README skeleton for an OBEX object: badges, install command, hardware, links, credits
+- p2kb_obex_cite — citation for an OBEX object in APA, BibTeX or Markdown, for papers and project credits
+- p2kb_obex_build_index — load every OBEX object into memory in the background so OBEX search never waits on GitHub
+- p2kb_obex_verify — check that OBEX download links still work, optionally finding moved links on the OBEX page
 - p2kb_refresh    — force-refresh the index when the KB has been updated
-- p2kb_version    — diagnostic: server + index version info`
+- p2kb_migrate_cache — copy the cache of a moved or upgraded install into this server's cache directory
+- p2kb_pin / p2kb_unpin — keep frequently used entries resident in memory
+- p2kb_suggest    — related entries you have not read yet, given the keys you have
+- p2kb_quiz       — a multiple-choice self-test question about a random PASM2 instruction
+- p2kb_memory_pressure — shrink the in-memory caches in a long-running session
+- p2kb_list_keys  — raw, paginated key listing for scripts (prefer p2kb_find)
+- p2kb_version    — diagnostic: server + index version info
+- p2kb_settings   — view the server's settings, or change log level, cache size, OBEX concurrency, request cache TTL or keepalive interval without a restart
+- p2kb_healthcheck — structured health report for liveness probes
+- p2kb_cache_dump — debugging: dump the memory caches to JSON (needs P2KB_ENABLE_DEBUG_TOOLS=true)
+- p2kb_find_duplicates — for KB maintainers: cached entries whose content is identical
+- p2kb_raw_get    — for KB contributors: an entry's YAML with its metadata fields unfiltered (listed only with P2KB_ENABLE_DEBUG_TOOLS=true)`
@@ -1,8 +1,20 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -65,6 +77,13 @@ func TestHandleInitialize(t *testing.T) {
 	if serverInfo["name"] != "p2kb-mcp" {
 		t.Errorf("serverInfo.name = %v, want p2kb-mcp", serverInfo["name"])
 	}
+
+	capabilities, _ := result["capabilities"].(map[string]interface{})
+	for _, capability := range []string{"tools", "prompts"} {
+		if _, ok := capabilities[capability]; !ok {
+			t.Errorf("capabilities missing %q: %v", capability, capabilities)
+		}
+	}
 }
 
 func TestHandleInitializeProtocolNegotiation(t *testing.T) {
@@ -79,6 +98,8 @@ func TestHandleInitializeProtocolNegotiation(t *testing.T) {
 		{"supported 2025-03-26 echoed", "2025-03-26", "2025-03-26"},
 		{"supported 2025-06-18 echoed", "2025-06-18", "2025-06-18"},
 		{"future version falls back to server max", "2099-01-01", serverMaxVersion},
+		{"older than oldest falls back to server max", "2024-10-07", serverMaxVersion},
+		{"unlisted version between supported falls back to server max", "2025-01-01", serverMaxVersion},
 		{"unknown version falls back to server max", "garbage", serverMaxVersion},
 	}
 
@@ -94,10 +115,31 @@ func TestHandleInitializeProtocolNegotiation(t *testing.T) {
 			if result["protocolVersion"] != tc.wantVersion {
 				t.Errorf("protocolVersion = %v, want %v", result["protocolVersion"], tc.wantVersion)
 			}
+			serverInfo := result["serverInfo"].(map[string]interface{})
+			if got := serverInfo["supported_protocol_versions"]; !reflect.DeepEqual(got, supportedProtocolVersions) {
+				t.Errorf("supported_protocol_versions = %v, want %v", got, supportedProtocolVersions)
+			}
 		})
 	}
 }
 
+func TestNegotiateProtocolVersionLogsDeprecatedClients(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	negotiateProtocolVersion("2025-03-26")
+	negotiateProtocolVersion("2099-01-01")
+	if buf.Len() != 0 {
+		t.Errorf("supported and newer clients logged %q", buf.String())
+	}
+
+	negotiateProtocolVersion("2024-10-07")
+	if out := buf.String(); !strings.Contains(out, "deprecated MCP protocol version") || !strings.Contains(out, "2024-10-07") {
+		t.Errorf("older client log = %q, want a deprecation warning", buf.String())
+	}
+}
+
 func TestHandleNotificationsAreFiltered(t *testing.T) {
 	srv := New("1.0.0")
 
@@ -160,6 +202,7 @@ func TestHandleUnknownMethod(t *testing.T) {
 }
 
 func TestHandleToolsList(t *testing.T) {
+	t.Setenv("P2KB_ENABLE_DEBUG_TOOLS", "")
 	srv := New("1.0.0")
 	req := &MCPRequest{
 		JSONRPC: "2.0",
@@ -185,9 +228,9 @@ func TestHandleToolsList(t *testing.T) {
 		t.Fatal("tools is not a []Tool")
 	}
 
-	// Check we have all 7 tools
-	if len(tools) != 7 {
-		t.Errorf("got %d tools, want 7", len(tools))
+	// Check we have all 32 tools
+	if len(tools) != 32 {
+		t.Errorf("got %d tools, want 32", len(tools))
 	}
 
 	// Check for specific tools
@@ -199,6 +242,14 @@ func TestHandleToolsList(t *testing.T) {
 	expectedTools := []string{
 		"p2kb_get", "p2kb_find", "p2kb_obex_get", "p2kb_obex_find",
 		"p2kb_obex_download", "p2kb_version", "p2kb_refresh",
+		"p2kb_pin", "p2kb_unpin", "p2kb_suggest", "p2kb_memory_pressure",
+		"p2kb_obex_author_detail", "p2kb_list_keys", "p2kb_healthcheck",
+		"p2kb_obex_stats", "p2kb_obex_preview", "p2kb_cache_dump",
+		"p2kb_category_tree", "p2kb_obex_build_index", "p2kb_find_duplicates",
+		"p2kb_obex_readme", "p2kb_discover", "p2kb_obex_tag_search",
+		"p2kb_obex_verify", "p2kb_settings", "p2kb_obex_cite", "p2kb_quiz",
+		"p2kb_migrate_cache", "p2kb_obex_dependency_graph", "p2kb_batch_get",
+		"p2kb_compare", "p2kb_related",
 	}
 
 	for _, name := range expectedTools {
@@ -287,3 +338,360 @@ func TestMCPErrorResponse(t *testing.T) {
 		t.Errorf("Error.Code = %d, want -32600", decoded.Error.Code)
 	}
 }
+
+func TestServeReturnsOnEOF(t *testing.T) {
+	srv := New("1.0.0")
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n\n" +
+		"not json\n" +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n")
+	var out bytes.Buffer
+
+	if err := srv.serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("serve() = %v, want nil at EOF", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d responses, want 2 (blank, malformed and notification lines get none): %q", len(lines), out.String())
+	}
+	for _, line := range lines {
+		var resp MCPResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Errorf("response %q is not valid JSON: %v", line, err)
+		}
+	}
+}
+
+func TestServeStopsOnCancel(t *testing.T) {
+	srv := New("1.0.0")
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	defer inWriter.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- srv.serve(ctx, inReader, outWriter) }()
+
+	go func() { _, _ = io.WriteString(inWriter, `{"jsonrpc":"2.0","id":7,"method":"ping"}`+"\n") }()
+	line, err := bufio.NewReader(outReader).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading response: %v", err)
+	}
+	if !strings.Contains(line, `"id":7`) {
+		t.Errorf("response = %q, want id 7", line)
+	}
+
+	// stdin is still open; cancelling must end serve without waiting for EOF
+	cancel()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("serve() = %v, want nil after graceful shutdown", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("serve() did not return after cancel")
+	}
+}
+
+// cancelOnRead is a reader of blank lines that cancels its context on the
+// second read, while serve is still taking lines.
+type cancelOnRead struct {
+	cancel context.CancelFunc
+	reads  int
+}
+
+func (r *cancelOnRead) Read(p []byte) (int, error) {
+	r.reads++
+	if r.reads == 2 {
+		r.cancel()
+	}
+	return copy(p, strings.Repeat("\n", 4096)), nil
+}
+
+func TestServeCancelledWhileReadingNeverHangs(t *testing.T) {
+	srv := New("1.0.0")
+	// The reader may stop on cancellation while serve sees both the cancelled
+	// context and the closed lines channel; whichever it picks, it must return
+	for i := 0; i < 200; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		result := make(chan error, 1)
+		go func() { result <- srv.serve(ctx, &cancelOnRead{cancel: cancel}, io.Discard) }()
+		select {
+		case <-result:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("run %d: serve() hung after cancel", i)
+		}
+	}
+}
+
+func TestShutdownWaitsForInFlight(t *testing.T) {
+	var inFlight sync.WaitGroup
+	var finished atomic.Bool
+	inFlight.Add(1)
+	go func() {
+		defer inFlight.Done()
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+	}()
+
+	flushedAfterFinish := false
+	err := shutdown(&inFlight, func() { flushedAfterFinish = finished.Load() })
+	if err != nil {
+		t.Fatalf("shutdown() = %v, want nil", err)
+	}
+	if !flushedAfterFinish {
+		t.Error("output flushed before the in-flight request finished")
+	}
+}
+
+func TestShutdownTimesOut(t *testing.T) {
+	t.Setenv("P2KB_SHUTDOWN_TIMEOUT_SECS", "1")
+
+	var inFlight sync.WaitGroup
+	inFlight.Add(1)
+	defer inFlight.Done()
+
+	flushed := false
+	if err := shutdown(&inFlight, func() { flushed = true }); err == nil {
+		t.Error("shutdown() = nil, want a timeout error while a request is stuck")
+	}
+	if flushed {
+		t.Error("output should not be flushed while a handler may still be writing")
+	}
+}
+
+func TestGetShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", DefaultShutdownTimeout},
+		{"3", 3 * time.Second},
+		{"0", DefaultShutdownTimeout},
+		{"soon", DefaultShutdownTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("P2KB_SHUTDOWN_TIMEOUT_SECS", tt.value)
+		if got := getShutdownTimeout(); got != tt.want {
+			t.Errorf("getShutdownTimeout() with %q = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestGetIndexWatchInterval(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"600", 10 * time.Minute},
+		{"0", 0},
+		{"often", 0},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("P2KB_INDEX_WATCH_INTERVAL_SECS", tt.value)
+		if got := getIndexWatchInterval(); got != tt.want {
+			t.Errorf("getIndexWatchInterval() with %q = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHandlePromptsList(t *testing.T) {
+	srv := New("1.0.0")
+	resp := srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "prompts/list"})
+	if resp == nil || resp.Error != nil {
+		t.Fatalf("prompts/list failed: %+v", resp)
+	}
+
+	result := resp.Result.(map[string]interface{})
+	prompts, ok := result["prompts"].([]Prompt)
+	if !ok || len(prompts) < 3 {
+		t.Fatalf("prompts = %v, want at least 3", result["prompts"])
+	}
+
+	names := make(map[string]bool)
+	for _, p := range prompts {
+		names[p.Name] = true
+		if p.Description == "" || p.Arguments == nil {
+			t.Errorf("prompt %q lacks a description or arguments", p.Name)
+		}
+	}
+	for _, name := range []string{"explain_instruction", "find_drivers", "learn_pasm2"} {
+		if !names[name] {
+			t.Errorf("missing prompt: %s", name)
+		}
+	}
+
+	// Arguments serialize with the MCP field names
+	raw, _ := json.Marshal(prompts[0])
+	if !strings.Contains(string(raw), `"required":true`) {
+		t.Errorf("prompt JSON %s lacks a required argument", raw)
+	}
+}
+
+func TestHandlePromptsGet(t *testing.T) {
+	srv := New("1.0.0")
+	getPrompt := func(params string) *MCPResponse {
+		return srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "prompts/get", Params: json.RawMessage(params)})
+	}
+
+	resp := getPrompt(`{"name": "explain_instruction", "arguments": {"instruction": "WAITX"}}`)
+	if resp.Error != nil {
+		t.Fatalf("prompts/get failed: %+v", resp.Error)
+	}
+	raw, _ := json.Marshal(resp.Result)
+	var result struct {
+		Description string `json:"description"`
+		Messages    []struct {
+			Role    string `json:"role"`
+			Content struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if result.Description == "" || len(result.Messages) != 1 {
+		t.Fatalf("result = %s, want a description and one message", raw)
+	}
+	msg := result.Messages[0]
+	if msg.Role != "user" || msg.Content.Type != "text" {
+		t.Errorf("message role/type = %s/%s, want user/text", msg.Role, msg.Content.Type)
+	}
+	for _, want := range []string{`p2kb_get with query "WAITX"`, "p2kb_suggest"} {
+		if !strings.Contains(msg.Content.Text, want) {
+			t.Errorf("prompt text missing %q:\n%s", want, msg.Content.Text)
+		}
+	}
+
+	// Optional arguments may be omitted
+	if resp := getPrompt(`{"name": "learn_pasm2"}`); resp.Error != nil {
+		t.Errorf("learn_pasm2 without arguments failed: %+v", resp.Error)
+	}
+
+	for _, params := range []string{
+		`{"name": "no_such_prompt"}`,
+		`{"name": "explain_instruction", "arguments": {"instruction": " "}}`,
+	} {
+		resp := getPrompt(params)
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("%s: expected -32602, got %+v", params, resp.Error)
+		}
+	}
+}
+
+func TestPromptTemplates(t *testing.T) {
+	for _, p := range GetPromptDefinitions() {
+		t.Run(p.Name, func(t *testing.T) {
+			args := map[string]string{}
+			for _, arg := range p.Arguments {
+				args[arg.Name] = "SAMPLE"
+			}
+			withArgs := p.render(args)
+			if !strings.Contains(withArgs, "SAMPLE") {
+				t.Errorf("rendered text ignores its arguments:\n%s", withArgs)
+			}
+			if strings.Contains(withArgs, "%!") {
+				t.Errorf("rendered text has a formatting error:\n%s", withArgs)
+			}
+		})
+	}
+
+	var learn string
+	for _, p := range GetPromptDefinitions() {
+		if p.Name == "learn_pasm2" {
+			learn = p.render(map[string]string{})
+		}
+	}
+	for _, name := range basicPASM2Instructions {
+		if !strings.Contains(learn, `"`+name+`"`) {
+			t.Errorf("learn_pasm2 text missing p2kb_get for %s", name)
+		}
+	}
+}
+
+// slowIndex is an IndexManager whose EnsureIndex takes a while and can fail,
+// like a first index download.
+type slowIndex struct {
+	IndexManager
+	delay time.Duration
+	err   error
+}
+
+func (s slowIndex) EnsureIndex() error {
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return s.err
+	}
+	return s.IndexManager.EnsureIndex()
+}
+
+// IsLoaded reports no index while err is set, whatever the wrapped manager
+// loaded on its own.
+func (s slowIndex) IsLoaded() bool {
+	return s.err == nil && s.IndexManager.IsLoaded()
+}
+
+func TestPrefetchIndexDoesNotBlockTools(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	srv.indexManager = slowIndex{IndexManager: srv.indexManager, delay: 200 * time.Millisecond}
+
+	prefetched := make(chan struct{})
+	go func() {
+		srv.prefetchIndex()
+		close(prefetched)
+	}()
+
+	// A tool call while the prefetch is still running completes on its own
+	found := make(chan *MCPResponse, 1)
+	go func() { found <- srv.handleFind(1, json.RawMessage(`{}`)) }()
+	select {
+	case resp := <-found:
+		if resp.Error != nil {
+			t.Fatalf("handleFind during prefetch: %+v", resp.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handleFind blocked on the running prefetch")
+	}
+
+	<-prefetched
+	version := extractResultMap(t, srv.handleVersion(1))
+	if version["index_preloaded"] != true {
+		t.Errorf("index_preloaded = %v, want true", version["index_preloaded"])
+	}
+	if _, ok := version["index_prefetch_error"]; ok {
+		t.Errorf("index_prefetch_error = %v after a successful prefetch", version["index_prefetch_error"])
+	}
+}
+
+func TestPrefetchIndexFailure(t *testing.T) {
+	srv, cleanup := newServerWithLocalIndex(t)
+	defer cleanup()
+	working := srv.indexManager
+	srv.indexManager = slowIndex{IndexManager: working, err: errors.New("network unavailable")}
+
+	srv.prefetchIndex()
+	version := extractResultMap(t, srv.handleVersion(1))
+	if version["index_preloaded"] != false || version["index_prefetch_error"] != "network unavailable" {
+		t.Errorf("index_preloaded = %v, index_prefetch_error = %v; want false and the error",
+			version["index_preloaded"], version["index_prefetch_error"])
+	}
+
+	// Tools still load the index when they need it, after which the
+	// prefetch failure is no longer reported
+	srv.indexManager = working
+	if resp := srv.handleFind(1, json.RawMessage(`{}`)); resp.Error != nil {
+		t.Errorf("handleFind after a failed prefetch: %+v", resp.Error)
+	}
+	version = extractResultMap(t, srv.handleVersion(1))
+	if _, ok := version["index_prefetch_error"]; ok || version["index_preloaded"] != false {
+		t.Errorf("after a later load: index_preloaded = %v, index_prefetch_error = %v; want false and none",
+			version["index_preloaded"], version["index_prefetch_error"])
+	}
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ironsheep/p2kb-mcp/internal/logging"
+)
+
+// settingSpec describes one P2KB_ environment setting for p2kb_settings.
+type settingSpec struct {
+	name         string
+	defaultValue string // As documented; "" when unset means off or none
+	secret       bool   // Value is never shown
+
+	// apply validates a new value and puts it into effect. Settings read on
+	// each use through Server.getenv need only validate. Nil for settings
+	// that are read once at startup and so need a restart to change.
+	apply func(s *Server, value string) error
+}
+
+// settingSpecs lists the settings p2kb_settings reports, in display order:
+// those adjustable at runtime first.
+var settingSpecs = []settingSpec{
+	{name: "P2KB_LOG_LEVEL", defaultValue: "info", apply: func(s *Server, v string) error {
+		return logging.SetLevel(v)
+	}},
+	{name: "P2KB_CACHE_MAX_ENTRIES", defaultValue: "0", apply: func(s *Server, v string) error {
+		n, err := settingInt(v, 0)
+		if err != nil {
+			return err
+		}
+		s.cacheManager.SetMaxEntries(n)
+		return nil
+	}},
+	{name: "P2KB_OBEX_CONCURRENCY", defaultValue: "3", apply: func(s *Server, v string) error {
+		n, err := settingInt(v, 1)
+		if err != nil {
+			return err
+		}
+		s.obexManager.SetConcurrencyLimit(n)
+		return nil
+	}},
+	{name: "P2KB_REQUEST_CACHE_TTL_SECS", defaultValue: "300", apply: func(s *Server, v string) error {
+		_, err := settingInt(v, 0)
+		return err
+	}},
+	{name: "P2KB_KEEPALIVE_INTERVAL_SECS", defaultValue: "5", apply: func(s *Server, v string) error {
+		_, err := settingInt(v, 1)
+		return err
+	}},
+	{name: "P2KB_BYPASS_CACHE_MAX_PER_MIN", defaultValue: "5", apply: func(s *Server, v string) error {
+		_, err := settingInt(v, 0)
+		return err
+	}},
+	{name: "P2KB_MAX_REGEX_COMPLEXITY", defaultValue: "10,5", apply: func(s *Server, v string) error {
+		_, err := parseRegexLimits(v)
+		return err
+	}},
+	{name: "P2KB_STRICT_VALIDATION", apply: func(s *Server, v string) error {
+		return settingBool(v)
+	}},
+	{name: "P2KB_LOG_REDIRECTS", apply: func(s *Server, v string) error {
+		return settingBool(v)
+	}},
+
+	{name: "P2KB_LOG_FORMAT", defaultValue: logging.FormatText},
+	{name: "P2KB_CACHE_DIR", defaultValue: "~/.p2kb-mcp"},
+	{name: "P2KB_INDEX_TTL", defaultValue: "86400"},
+	{name: "P2KB_BACKGROUND_REFRESH", defaultValue: "true"},
+	{name: "P2KB_INDEX_WATCH_INTERVAL_SECS"},
+	{name: "P2KB_EXTRA_INDEX_URLS"},
+	{name: "P2KB_SEED_ARCHIVE"},
+	{name: "P2KB_OBEX_LOCAL_DIR"},
+	{name: "P2KB_OBEX_MIRROR_URLS"},
+	{name: "P2KB_OBEX_WORKERS", defaultValue: "8"},
+	{name: "P2KB_GITHUB_TOKENS", secret: true},
+	{name: "P2KB_GITHUB_TOKEN", secret: true},
+	{name: "P2KB_HTTP_CACHE_TTL_SECS", defaultValue: "3600"},
+	{name: "P2KB_MAX_RESPONSE_BYTES", defaultValue: "524288"},
+	{name: "P2KB_SHUTDOWN_TIMEOUT_SECS", defaultValue: "10"},
+	{name: "P2KB_ENABLE_DOWNLOADS", defaultValue: "false"},
+	{name: "P2KB_ENABLE_DEBUG_TOOLS", defaultValue: "false"},
+	{name: "P2KB_OTEL_ENDPOINT"},
+	{name: "P2KB_HTTP_ADDR", defaultValue: DefaultHTTPAddr},
+	{name: "P2KB_HTTP_TOKEN", secret: true},
+	{name: "P2KB_HTTP_SESSION_TTL_SECS", defaultValue: "1800"},
+}
+
+// settingInt parses a whole-number setting no smaller than min.
+func settingInt(v string, min int) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min {
+		return 0, fmt.Errorf("value must be a whole number of at least %d, not %q", min, v)
+	}
+	return n, nil
+}
+
+// settingBool checks a setting that is on when "true" and off otherwise.
+func settingBool(v string) error {
+	if v != "true" && v != "false" {
+		return fmt.Errorf("value must be true or false, not %q", v)
+	}
+	return nil
+}
+
+// getenv returns a setting's value: the one p2kb_settings applied, if any,
+// otherwise the environment's. A nil server, as in recovering a panic from a
+// nil receiver, reads the environment.
+func (s *Server) getenv(name string) string {
+	if s == nil {
+		return os.Getenv(name)
+	}
+	if v, ok := s.settings.Load(name); ok {
+		return v.(string)
+	}
+	return os.Getenv(name)
+}
+
+// settingsOverridden counts the settings p2kb_settings has applied.
+func (s *Server) settingsOverridden() int {
+	n := 0
+	s.settings.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// handleSettings implements p2kb_settings - with no arguments it lists the
+// effective settings; with key and value it changes a runtime-adjustable one.
+func (s *Server) handleSettings(id interface{}, args json.RawMessage) *MCPResponse {
+	var params struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+	}
+
+	if params.Key == "" {
+		if len(params.Value) > 0 {
+			return s.errorResponse(id, -32602, "Missing required parameter", "key")
+		}
+		return s.listSettings(id)
+	}
+	if len(params.Value) == 0 {
+		return s.errorResponse(id, -32602, "Missing required parameter", "value")
+	}
+
+	// Numbers are as welcome as strings
+	value := strings.TrimSpace(string(params.Value))
+	if strings.HasPrefix(value, `"`) {
+		if err := json.Unmarshal(params.Value, &value); err != nil {
+			return s.errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+	}
+
+	name := strings.ToUpper(params.Key)
+	var spec *settingSpec
+	for i := range settingSpecs {
+		if settingSpecs[i].name == name {
+			spec = &settingSpecs[i]
+			break
+		}
+	}
+	if spec == nil {
+		return s.errorResponse(id, -32602, "Unknown setting", map[string]interface{}{
+			"key":  params.Key,
+			"hint": "Call p2kb_settings with no arguments to list the settings",
+		})
+	}
+	if spec.apply == nil {
+		return s.errorResponse(id, -32602, "Setting requires a restart", map[string]interface{}{
+			"key":     name,
+			"message": fmt.Sprintf("%s is read at startup; set it in the server's environment and restart", name),
+		})
+	}
+
+	previous := s.getenv(name)
+	if err := spec.apply(s, value); err != nil {
+		return s.errorResponse(id, -32602, "Invalid setting value", map[string]interface{}{"key": name, "error": err.Error()})
+	}
+	s.settings.Store(name, value)
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":     "setting_applied",
+		"key":      name,
+		"value":    value,
+		"previous": previous,
+		"source":   "runtime",
+	})
+}
+
+// listSettings returns every setting with its effective value and where the
+// value came from: "runtime" (p2kb_settings), "env" or "default".
+func (s *Server) listSettings(id interface{}) *MCPResponse {
+	settings := make([]map[string]interface{}, 0, len(settingSpecs))
+	for _, spec := range settingSpecs {
+		value, source := spec.defaultValue, "default"
+		if v, ok := s.settings.Load(spec.name); ok {
+			value, source = v.(string), "runtime"
+		} else if v, ok := os.LookupEnv(spec.name); ok && v != "" {
+			value, source = v, "env"
+		}
+		if spec.secret && source != "default" {
+			value = "(set)"
+		}
+		settings = append(settings, map[string]interface{}{
+			"key":                spec.name,
+			"value":              value,
+			"default":            spec.defaultValue,
+			"source":             source,
+			"runtime_adjustable": spec.apply != nil,
+		})
+	}
+
+	return s.successResponse(id, map[string]interface{}{
+		"type":                "settings",
+		"settings":            settings,
+		"settings_overridden": s.settingsOverridden(),
+	})
+}
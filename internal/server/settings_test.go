@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// callSettings calls p2kb_settings with args.
+func callSettings(srv *Server, args map[string]interface{}) *MCPResponse {
+	params, _ := json.Marshal(map[string]interface{}{"name": "p2kb_settings", "arguments": args})
+	return srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: params})
+}
+
+func TestSettingsList(t *testing.T) {
+	t.Setenv("P2KB_INDEX_TTL", "600")
+	t.Setenv("P2KB_GITHUB_TOKENS", "ghp_secret")
+	srv := New("1.0.0")
+
+	result := extractResultMap(t, callSettings(srv, nil))
+	settings, _ := result["settings"].([]interface{})
+	if len(settings) != len(settingSpecs) {
+		t.Fatalf("listed %d settings, want %d", len(settings), len(settingSpecs))
+	}
+	byKey := make(map[string]map[string]interface{})
+	for _, raw := range settings {
+		setting := raw.(map[string]interface{})
+		byKey[setting["key"].(string)] = setting
+	}
+
+	if got := byKey["P2KB_INDEX_TTL"]; got["value"] != "600" || got["source"] != "env" || got["runtime_adjustable"] != false {
+		t.Errorf("P2KB_INDEX_TTL = %v, want value 600 from env, restart-only", got)
+	}
+	if got := byKey["P2KB_LOG_LEVEL"]; got["source"] != "default" || got["runtime_adjustable"] != true {
+		t.Errorf("P2KB_LOG_LEVEL = %v, want default, runtime adjustable", got)
+	}
+	if got := byKey["P2KB_GITHUB_TOKENS"]["value"]; got != "(set)" {
+		t.Errorf("P2KB_GITHUB_TOKENS value = %v, want it redacted", got)
+	}
+}
+
+func TestSettingsApply(t *testing.T) {
+	srv := New("1.0.0")
+	mock := newMockOBEXManager()
+	srv.obexManager = mock
+
+	// A TTL of 0 stops repeat calls being served from the request cache
+	resp := callSettings(srv, map[string]interface{}{"key": "P2KB_REQUEST_CACHE_TTL_SECS", "value": 0})
+	if result := extractResultMap(t, resp); result["type"] != "setting_applied" || result["value"] != "0" {
+		t.Fatalf("apply TTL = %v", result)
+	}
+	params, _ := json.Marshal(map[string]interface{}{"name": "p2kb_obex_get", "arguments": map[string]interface{}{"query": "2811"}})
+	for i := 0; i < 2; i++ {
+		srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: i, Method: "tools/call", Params: params})
+	}
+	gets := 0
+	for _, call := range mock.Calls {
+		if call == "GetObject(2811)" {
+			gets++
+		}
+	}
+	if gets != 2 {
+		t.Errorf("after setting the TTL to 0, GetObject called %d times for two calls, want 2", gets)
+	}
+
+	callSettings(srv, map[string]interface{}{"key": "P2KB_CACHE_MAX_ENTRIES", "value": "25"})
+	if got := srv.cacheManager.MaxEntries(); got != 25 {
+		t.Errorf("MaxEntries() = %d after setting 25", got)
+	}
+
+	callSettings(srv, map[string]interface{}{"key": "p2kb_obex_concurrency", "value": 6})
+	if got := mock.Calls[len(mock.Calls)-1]; got != "SetConcurrencyLimit(6)" {
+		t.Errorf("last OBEX call = %q, want SetConcurrencyLimit(6)", got)
+	}
+
+	result := extractResultMap(t, callSettings(srv, nil))
+	if got := result["settings_overridden"]; got != float64(3) {
+		t.Errorf("settings_overridden = %v, want 3", got)
+	}
+}
+
+func TestSettingsErrors(t *testing.T) {
+	srv := New("1.0.0")
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"unknown key", map[string]interface{}{"key": "P2KB_NOPE", "value": "1"}, "Unknown setting"},
+		{"restart only", map[string]interface{}{"key": "P2KB_CACHE_DIR", "value": "/tmp"}, "Setting requires a restart"},
+		{"missing value", map[string]interface{}{"key": "P2KB_LOG_LEVEL"}, "Missing required parameter"},
+		{"bad log level", map[string]interface{}{"key": "P2KB_LOG_LEVEL", "value": "loud"}, "Invalid setting value"},
+		{"zero concurrency", map[string]interface{}{"key": "P2KB_OBEX_CONCURRENCY", "value": 0}, "Invalid setting value"},
+		{"negative TTL", map[string]interface{}{"key": "P2KB_REQUEST_CACHE_TTL_SECS", "value": -1}, "Invalid setting value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := callSettings(srv, tt.args)
+			if resp.Error == nil || resp.Error.Message != tt.want {
+				t.Errorf("error = %v, want %q", resp.Error, tt.want)
+			}
+		})
+	}
+	if n := srv.settingsOverridden(); n != 0 {
+		t.Errorf("rejected settings left %d overrides", n)
+	}
+}
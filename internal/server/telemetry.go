@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span names. Each tool call is a p2kb.tool.call span; the others are its
+// children.
+const (
+	spanToolCall     = "p2kb.tool.call"
+	spanIndexEnsure  = "p2kb.index.ensure"  // Index lookup, refreshing an expired index
+	spanCacheGet     = "p2kb.cache.get"     // Content served from the memory or disk cache
+	spanFetchContent = "p2kb.fetch.content" // Content fetched from GitHub
+	spanOBEXSearch   = "p2kb.obex.search"
+)
+
+// maxSpanQueryLen caps the query attribute of a p2kb.tool.call span, in
+// characters.
+const maxSpanQueryLen = 100
+
+// telemetryShutdownTimeout bounds how long Run waits to export the last spans.
+const telemetryShutdownTimeout = 5 * time.Second
+
+// telemetry emits OpenTelemetry trace spans for tool calls. A nil *telemetry,
+// the state unless P2KB_OTEL_ENDPOINT is set, does nothing, so the hooks cost
+// no more than a nil check.
+type telemetry struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// newTelemetryFromEnv starts exporting spans over OTLP gRPC to
+// P2KB_OTEL_ENDPOINT ("localhost:4317", or a URL), or returns nil when it is
+// unset. The SDK is not touched otherwise. An endpoint given without an
+// https:// scheme is reached without TLS, as a local collector usually is.
+func newTelemetryFromEnv(version string) *telemetry {
+	endpoint := strings.TrimSpace(os.Getenv("P2KB_OTEL_ENDPOINT"))
+	if endpoint == "" {
+		return nil
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure()}
+	if strings.Contains(endpoint, "://") {
+		opts = []otlptracegrpc.Option{otlptracegrpc.WithEndpointURL(endpoint)}
+	}
+	// The connection is made in the background; a collector that is down
+	// costs dropped spans, not a failed start
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		slog.Warn("tracing disabled", "P2KB_OTEL_ENDPOINT", endpoint, "error", err)
+		return nil
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", "p2kb-mcp"),
+		attribute.String("service.version", version),
+	)
+	return newTelemetry(sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res)))
+}
+
+// newTelemetry traces through provider.
+func newTelemetry(provider *sdktrace.TracerProvider) *telemetry {
+	return &telemetry{
+		provider: provider,
+		tracer:   provider.Tracer("github.com/ironsheep/p2kb-mcp/internal/server"),
+	}
+}
+
+// shutdown exports any spans not yet sent and stops the exporter.
+func (t *telemetry) shutdown() {
+	if t == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), telemetryShutdownTimeout)
+	defer cancel()
+	if err := t.provider.Shutdown(ctx); err != nil {
+		slog.Error("failed to flush trace spans", "error", err)
+	}
+}
+
+// startCall starts the p2kb.tool.call span of request id's call to tool. It
+// returns ctx carrying the span, for the handler to pass to span, and a
+// function that ends it, marking it failed when the response is an error.
+func (t *telemetry) startCall(ctx context.Context, id interface{}, tool string, args json.RawMessage) (context.Context, func(resp *MCPResponse)) {
+	if t == nil {
+		return ctx, func(*MCPResponse) {}
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("tool.name", tool),
+		attribute.String("request.id", fmt.Sprint(id)),
+	}
+	if query := spanQuery(args); query != "" {
+		attrs = append(attrs, attribute.String("query", query))
+	}
+	ctx, span := t.tracer.Start(ctx, spanToolCall, trace.WithAttributes(attrs...))
+	return ctx, func(resp *MCPResponse) {
+		if resp != nil && resp.Error != nil {
+			span.SetStatus(codes.Error, resp.Error.Message)
+		}
+		span.End()
+	}
+}
+
+// span starts a child span of the tool call whose span ctx carries, or a
+// span without a parent when ctx carries none. The returned function ends
+// it, recording err if there is one.
+func (t *telemetry) span(ctx context.Context, name string, attrs ...attribute.KeyValue) func(err error) {
+	if t == nil {
+		return func(error) {}
+	}
+
+	_, span := t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// spanQuery returns the query a tool call's arguments name - query, or else
+// term - cut to maxSpanQueryLen characters.
+func spanQuery(args json.RawMessage) string {
+	var params struct {
+		Query string `json:"query"`
+		Term  string `json:"term"`
+	}
+	if len(args) == 0 || json.Unmarshal(args, &params) != nil {
+		return ""
+	}
+	query := params.Query
+	if query == "" {
+		query = params.Term
+	}
+	if utf8.RuneCountInString(query) > maxSpanQueryLen {
+		query = string([]rune(query)[:maxSpanQueryLen])
+	}
+	return query
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestToolCallSpans(t *testing.T) {
+	t.Setenv("P2KB_REQUEST_CACHE_TTL_SECS", "0") // Every call runs its handler
+	files := map[string]interface{}{"p2kbPasm2Nop": map[string]interface{}{"path": "nop.yaml", "mtime": 1700000000}}
+	srv, cleanup := newServerWithIndex(t, files, map[string]interface{}{}, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("name: NOP\n"))
+	})
+	defer cleanup()
+
+	exporter := tracetest.NewInMemoryExporter()
+	srv.telemetry = newTelemetry(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	call := func(id interface{}, args map[string]interface{}) {
+		params, _ := json.Marshal(map[string]interface{}{"name": "p2kb_get", "arguments": args})
+		srv.handleRequest(&MCPRequest{JSONRPC: "2.0", ID: id, Method: "tools/call", Params: params})
+	}
+	// Spans by name, checking each child's parent is its call
+	spans := func() map[string]tracetest.SpanStub {
+		t.Helper()
+		byName := make(map[string]tracetest.SpanStub)
+		for _, span := range exporter.GetSpans() {
+			byName[span.Name] = span
+		}
+		root, ok := byName[spanToolCall]
+		if !ok {
+			t.Fatalf("no %s span in %v", spanToolCall, byName)
+		}
+		for name, span := range byName {
+			if name != spanToolCall && span.Parent.SpanID() != root.SpanContext.SpanID() {
+				t.Errorf("%s span is not a child of the call", name)
+			}
+		}
+		exporter.Reset()
+		return byName
+	}
+
+	call(7, map[string]interface{}{"query": "p2kbPasm2Nop"})
+	got := spans()
+	want := map[attribute.Key]string{"tool.name": "p2kb_get", "request.id": "7", "query": "p2kbPasm2Nop"}
+	for _, attr := range got[spanToolCall].Attributes {
+		if v, ok := want[attr.Key]; ok && attr.Value.AsString() != v {
+			t.Errorf("%s = %q, want %q", attr.Key, attr.Value.AsString(), v)
+		}
+		delete(want, attr.Key)
+	}
+	if len(want) != 0 {
+		t.Errorf("call span lacks %v", want)
+	}
+	for _, name := range []string{spanIndexEnsure, spanFetchContent} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("first lookup has no %s span", name)
+		}
+	}
+
+	// The second lookup is a cache read
+	call("second", map[string]interface{}{"query": "p2kbPasm2Nop"})
+	got = spans()
+	if _, ok := got[spanCacheGet]; !ok {
+		t.Errorf("second lookup has no %s span: %v", spanCacheGet, got)
+	}
+	if _, ok := got[spanFetchContent]; ok {
+		t.Errorf("second lookup fetched")
+	}
+
+	call(9, map[string]interface{}{}) // No query
+	if got = spans(); got[spanToolCall].Status.Code != codes.Error {
+		t.Errorf("failed call status = %v, want Error", got[spanToolCall].Status)
+	}
+}
+
+func TestSpansFollowTheirOwnCall(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tel := newTelemetry(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	// Two HTTP sessions may each have a call with ID 1 in flight
+	ctxA, endA := tel.startCall(context.Background(), 1, "p2kb_get", nil)
+	ctxB, endB := tel.startCall(context.Background(), 1, "p2kb_obex_get", nil)
+	tel.span(ctxA, spanCacheGet)(nil)
+	tel.span(ctxB, spanOBEXSearch)(nil)
+	endB(nil)
+	endA(nil)
+
+	callSpans := make(map[string]trace.SpanID)
+	parents := make(map[string]trace.SpanID)
+	for _, span := range exporter.GetSpans() {
+		if span.Name == spanToolCall {
+			for _, attr := range span.Attributes {
+				if attr.Key == "tool.name" {
+					callSpans[attr.Value.AsString()] = span.SpanContext.SpanID()
+				}
+			}
+		} else {
+			parents[span.Name] = span.Parent.SpanID()
+		}
+	}
+	if parents[spanCacheGet] != callSpans["p2kb_get"] {
+		t.Errorf("%s span is not a child of the p2kb_get call", spanCacheGet)
+	}
+	if parents[spanOBEXSearch] != callSpans["p2kb_obex_get"] {
+		t.Errorf("%s span is not a child of the p2kb_obex_get call", spanOBEXSearch)
+	}
+}
+
+func TestTelemetryDisabled(t *testing.T) {
+	var tel *telemetry
+	ctx, endCall := tel.startCall(context.Background(), 1, "p2kb_get", nil)
+	tel.span(ctx, spanCacheGet)(nil)
+	endCall(nil)
+	tel.shutdown()
+
+	t.Setenv("P2KB_OTEL_ENDPOINT", "")
+	if tel := newTelemetryFromEnv("1.0.0"); tel != nil {
+		t.Error("telemetry enabled without P2KB_OTEL_ENDPOINT")
+	}
+}
+
+func TestSpanQuery(t *testing.T) {
+	long := strings.Repeat("é", 150)
+	tests := map[string]string{
+		`{"query": "mov"}`:            "mov",
+		`{"term": "uart"}`:            "uart",
+		`{"query": "a", "term": "b"}`: "a",
+		`{"query": "` + long + `"}`:   strings.Repeat("é", maxSpanQueryLen),
+		`{"keys": ["p2kbPasm2Mov"]}`:  "",
+		`not json`:                    "",
+	}
+	for args, want := range tests {
+		if got := spanQuery(json.RawMessage(args)); got != want {
+			t.Errorf("spanQuery(%.30s) = %q, want %q", args, got, want)
+		}
+	}
+}
@@ -9,8 +9,9 @@ type Tool struct {
 
 // GetToolDefinitions returns the complete list of available P2KB tools.
 // The API is intentionally minimal to reduce cognitive load for Claude.
+// p2kb_raw_get is listed only when debug tools are enabled.
 func GetToolDefinitions() []Tool {
-	return []Tool{
+	tools := []Tool{
 		// Primary content access - natural language query
 		{
 			Name: "p2kb_get",
@@ -20,7 +21,11 @@ Fetch P2 Knowledge Base content using natural language or exact key.
 Accepts natural language queries like "mov instruction", "cog architecture", "spin2 pinwrite".
 Also accepts exact keys like "p2kbPasm2Mov" for direct lookup.
 Returns the content along with related items for exploration.
-If query is ambiguous, returns matching suggestions.`,
+If query is ambiguous, returns matching suggestions, or with auto_select picks one for you.
+Unsure how to phrase it? Pass up to 5 alternatives as queries instead of query; the best match across all of them wins, and matched_by_query says which query found it.
+For very large files, max_bytes returns the content a page at a time; pass the continuation_token from a truncated result as continuation to read the next page.
+To walk a whole category without knowing its keys, pass category and position (0, 1, 2, ...) instead of query.
+bypass_cache: true fetches the entry straight from GitHub and replaces the cached copy, for content changed since the last refresh; limited to P2KB_BYPASS_CACHE_MAX_PER_MIN calls a minute (default 5), with bypass_cache_remaining in the result.`,
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -29,8 +34,106 @@ If query is ambiguous, returns matching suggestions.`,
 						"description": `Natural language query or exact key.
 Examples: "mov instruction", "pasm2 add", "spin2 pinwrite", "cog memory", "smart pin", "p2kbPasm2Mov"`,
 					},
+					"queries": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"maxItems":    5,
+						"description": "Alternative queries to try together instead of query (e.g., [\"mov instruction\", \"move data pasm2\"]); matched in parallel and ranked as one result set",
+					},
+					"auto_select": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Instead of returning suggestions for an ambiguous query, return the best match's content, marked auto_selected with an auto_select_reason (default: false)",
+						"default":     false,
+					},
+					"max_bytes": map[string]interface{}{
+						"type":        "integer",
+						"description": "Return at most this many bytes of content, with truncated, total_bytes, returned_bytes and a continuation_token when more follows (default: 0 = unlimited, max: 32768)",
+						"default":     0,
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Byte offset into the content to start from (default: 0)",
+						"default":     0,
+					},
+					"continuation": map[string]interface{}{
+						"type":        "string",
+						"description": "continuation_token from a truncated p2kb_get result; reads the next page of the same key, overriding query and offset",
+					},
+					"category": map[string]interface{}{
+						"type":        "string",
+						"description": "With position, instead of query: the category to read from (e.g., \"pasm2_math\")",
+					},
+					"position": map[string]interface{}{
+						"type":        "integer",
+						"description": "With category: 0-based position in the category's alphabetical key list. The result's next_key and prev_key name the neighbouring entries",
+						"minimum":     0,
+					},
+					"bypass_cache": map[string]interface{}{
+						"type":        "boolean",
+						"description": "With query: skip the memory and disk caches and fetch the entry from GitHub (default: false; a few calls a minute)",
+					},
+					"field": map[string]interface{}{
+						"type":        "string",
+						"description": "Return only this YAML field, by dot path (e.g. \"syntax\", \"operands.D\", \"flags.Z\"), as value instead of the whole content. Not combinable with paging",
+					},
 				},
-				"required": []string{"query"},
+			},
+		},
+		{
+			Name: "p2kb_batch_get",
+			Description: `Fetch several P2 Knowledge Base entries in one call, e.g. a family of related instructions (MOV, MOVBYTS, GETBYTE).
+Accepts canonical keys or aliases. Results come back in request order as {key, content}; a key that cannot be fetched gets {key, error} instead of failing the whole call.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"keys": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"maxItems":    20,
+						"description": "Keys to fetch (e.g., [\"p2kbPasm2Mov\", \"MOVBYTS\"])",
+					},
+				},
+				"required": []string{"keys"},
+			},
+		},
+		{
+			Name: "p2kb_compare",
+			Description: `Compare two to four P2 Knowledge Base entries side by side, e.g. ADD vs ADDX vs ADDSX.
+Returns each entry's mnemonic, syntax, description, flags and related_instructions as fields.{field}.{key}. A field an entry lacks is null; a key that cannot be fetched has every field null and its reason in errors.{key}.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"keys": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"minItems":    2,
+						"maxItems":    4,
+						"description": "Keys or aliases to compare (e.g., [\"p2kbPasm2Add\", \"ADDX\"])",
+					},
+				},
+				"required": []string{"keys"},
+			},
+		},
+		{
+			Name: "p2kb_related",
+			Description: `Follow an entry's related_instructions out to depth steps and fetch every entry reached, e.g. the family around ADD.
+Returns {root, nodes: {key: {content, related}}}; each key is fetched once, so cycles end where they meet the graph. At most 20 entries are fetched; truncated is set when more were linked. An entry that cannot be fetched has error instead of content.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"key": map[string]interface{}{
+						"type":        "string",
+						"description": "Key or alias to start from (e.g., 'p2kbPasm2Add', 'ADD')",
+					},
+					"depth": map[string]interface{}{
+						"type":        "integer",
+						"description": "Steps of related_instructions to follow, 1 to 3 (default: 1)",
+						"minimum":     1,
+						"maximum":     3,
+						"default":     1,
+					},
+				},
+				"required": []string{"key"},
 			},
 		},
 
@@ -40,9 +143,14 @@ Examples: "mov instruction", "pasm2 add", "spin2 pinwrite", "cog memory", "smart
 			Description: `AUTHORITATIVE SOURCE for Propeller 2 (P2) documentation discovery — use this instead of web search to find what's documented about P2 architecture, PASM2, or Spin2.
 
 Explore and discover P2KB documentation. Use to find what's available.
-With no parameters: lists all categories with counts.
-With term: searches for matching keys.
-With category: lists keys in that category.`,
+With no parameters: lists all categories as [{name, count}], most populated first.
+With term: searches for matching keys. Several words match keys containing all of them ("cog memory"); set operator "OR" to match any.
+With category: lists keys in that category.
+With sort_by "mtime": lists keys most recently updated first, as [{key, mtime, updated}]; combine with category or term to narrow.
+With min_count and/or max_count: lists only categories holding that many keys (filtered_category_count); with a term or category, those categories come back as categories_in_range.
+With regex true: term is a Go regular expression matched against whole key names, e.g. "^p2kb(Pasm2|Spin2).*Mov"; at most 10 alternations and 5 quantifiers unless P2KB_MAX_REGEX_COMPLEXITY says otherwise.
+With term and detailed true: keys come back as [{key, path, mtime_rfc3339, categories, content_cached}], 20 per page; while has_more, pass offset + count as offset for the next page.
+Key lists are paged by offset and limit: total_count counts every match and has_more says whether another page follows.`,
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -59,10 +167,85 @@ With category: lists keys in that category.`,
 						"description": "Maximum results (default: 50)",
 						"default":     50,
 					},
+					"sort_by": map[string]interface{}{
+						"type":        "string",
+						"description": "Set to 'mtime' to list keys most recently updated upstream first (optional)",
+						"enum":        []string{"mtime"},
+					},
+					"operator": map[string]interface{}{
+						"type":        "string",
+						"description": "How a term of several words matches: 'AND' (default) requires every word, 'OR' any of them",
+						"enum":        []string{"AND", "OR"},
+					},
+					"min_count": map[string]interface{}{
+						"type":        "integer",
+						"description": "Only list categories with at least this many keys (optional)",
+					},
+					"max_count": map[string]interface{}{
+						"type":        "integer",
+						"description": "Only list categories with at most this many keys (optional)",
+					},
+					"regex": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Treat term as a regular expression matched against key names (optional)",
+					},
+					"detailed": map[string]interface{}{
+						"type":        "boolean",
+						"description": "With term: describe each key (path, mtime, categories, whether cached), 20 per page (optional)",
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Index of the first key to return, for paging through total_count matches (default: 0)",
+						"default":     0,
+					},
 				},
 			},
 		},
 
+		// Category hierarchy
+		{
+			Name: "p2kb_category_tree",
+			Description: `Show how P2 Knowledge Base categories relate: categories grouped by their underscore prefix (pasm2_math under pasm2) with entry counts.
+Returns an ASCII tree by default, or nested JSON [{name, count, subcategories: [{name, count}]}] with format "json".
+Use p2kb_find with a category to list its keys.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"format": map[string]interface{}{
+						"type":        "string",
+						"description": "Output format: 'ascii' (default) or 'json'",
+						"enum":        []string{"ascii", "json"},
+						"default":     "ascii",
+					},
+				},
+			},
+		},
+
+		// Discovery across both the KB and the OBEX
+		{
+			Name: "p2kb_discover",
+			Description: `Search the P2 Knowledge Base and the OBEX community code library at once.
+Use when unsure whether a question is about P2 documentation (instructions, architecture, Spin2) or community code (drivers, objects).
+Returns KB category and key matches under kb, OBEX category and object matches under obex,
+and primary_source ("kb", "obex", "both" or "none") naming the system with more matches.
+Follow up with p2kb_get for KB keys or p2kb_obex_get for OBEX objects.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Search term, e.g. \"ws2812\", \"pasm2\", \"smart pin\"",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum keys and objects from each source (default: 10)",
+						"default":     10,
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+
 		// OBEX code retrieval - natural language query with download
 		{
 			Name: "p2kb_obex_get",
@@ -71,14 +254,26 @@ With category: lists keys in that category.`,
 Get OBEX code object by search or ID.
 Searches OBEX objects using natural language (e.g., "i2c sensor", "led driver") or retrieves by numeric ID.
 Search terms are automatically expanded (i2c matches twi, iic; led matches ws2812, neopixel).
-Returns object metadata with download URL and instructions.`,
+Returns object metadata with download URL and instructions, and for Spin2 objects a code_snippet showing how to use the object from Spin2.`,
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"query": map[string]interface{}{
 						"type": "string",
-						"description": `Natural language search or numeric object ID.
-Examples: "i2c sensor", "led driver", "servo motor", "2811", "4047"`,
+						"description": `Natural language search, numeric object ID, or OBEX page URL.
+Examples: "i2c sensor", "led driver", "servo motor", "2811", "4047", "https://obex.parallax.com/obex/park-transformation/"`,
+					},
+					"microcontroller": map[string]interface{}{
+						"type":        "string",
+						"description": "Only match objects built for this chip: P2, P1, or any (default: any)",
+					},
+					"include_snippet": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include code_snippet for Spin2 objects (default: true)",
+					},
+					"fetch_full_description": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also fetch the object's OBEX page and return its full description as full_description_html and full_description_text (default: false)",
 					},
 				},
 				"required": []string{"query"},
@@ -91,10 +286,14 @@ Examples: "i2c sensor", "led driver", "servo motor", "2811", "4047"`,
 			Description: `AUTHORITATIVE SOURCE for browsing P2 community code objects — use this instead of web search to discover what's available in OBEX.
 
 Explore OBEX objects. Lists categories, searches, or browses by category/author.
-With no parameters: lists all categories with counts.
+With no parameters: lists all categories as [{name, count}], most populated first.
 With term: searches across all objects.
-With category: lists objects in that category.
-With author: lists objects by that author.`,
+With category: lists objects in that category; add subcategory (e.g. "i2c") to narrow to one of its subcategories.
+With author: lists objects by that author. With authors (several names): lists objects by any of them once each, with per-author counts in authors_matched.
+With microcontroller: narrows any of the above to P2 or P1 objects.
+With min_file_size_kb / max_file_size_kb: narrows any of the above to lightweight (or substantial) libraries; objects of unknown size are kept, marked size_unknown. The overview reports file_size_stats {min_kb, max_kb, avg_kb}.
+With mode "tags": returns a tag cloud instead, the 50 most common tags [{tag, count}] and 20 most common tag pairs [{pair, count}] across all objects.
+Object lists are paged by offset and limit, with total_count and has_more.`,
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -106,19 +305,124 @@ With author: lists objects by that author.`,
 						"type":        "string",
 						"description": "Category filter: drivers, misc, display, demos, audio, motors, communication, sensors, tools",
 					},
+					"subcategory": map[string]interface{}{
+						"type":        "string",
+						"description": "Subcategory within category (e.g., 'i2c' under 'drivers'); requires category. The overview lists each category's subcategories",
+					},
 					"author": map[string]interface{}{
 						"type":        "string",
-						"description": "Filter by author name",
+						"description": "Filter by author name; matched word by word, so 'McPhalen' finds 'Jon McPhalen' and 'Jon_McPhalen'",
+					},
+					"authors": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Filter by several author names at once, matched like author; cannot be combined with author",
+					},
+					"microcontroller": map[string]interface{}{
+						"type":        "string",
+						"description": "Only list objects built for this chip: P2, P1, or any (default: any)",
+					},
+					"min_file_size_kb": map[string]interface{}{
+						"type":        "integer",
+						"description": "Only list objects of at least this many KB (inclusive)",
+					},
+					"max_file_size_kb": map[string]interface{}{
+						"type":        "integer",
+						"description": "Only list objects of at most this many KB (inclusive), e.g. to fit limited Flash",
 					},
 					"limit": map[string]interface{}{
 						"type":        "integer",
 						"description": "Maximum results (default: 20)",
 						"default":     20,
 					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Index of the first object to return, for paging; has_more says whether another page follows (default: 0)",
+						"default":     0,
+					},
+					"show_invalid": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Admin/debug: list objects whose YAML fails schema validation (these are hidden from search and browse) (default: false)",
+						"default":     false,
+					},
+					"mode": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"tags"},
+						"description": "\"tags\" returns the corpus tag cloud; other parameters are then ignored",
+					},
+				},
+			},
+		},
+
+		// OBEX author portfolio
+		{
+			Name: "p2kb_obex_author_detail",
+			Description: `Summarize one OBEX author's complete portfolio of P2 community code objects.
+
+Accepts an exact or partial author name. Returns object count, objects per category, tags by frequency, average quality score, created-date range, languages used, GitHub usernames, and every object (object_id, title, category, quality_score).
+If a partial name matches several authors, returns the matching names with object counts instead.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"author": map[string]interface{}{
+						"type":        "string",
+						"description": "Author name, exact or partial (case-insensitive)",
+					},
+				},
+				"required": []string{"author"},
+			},
+		},
+
+		// OBEX tag lookup
+		{
+			Name: "p2kb_obex_tag_search",
+			Description: `Find P2 OBEX (Parallax Object Exchange) community objects by tag, using an inverted tag index.
+
+tag matches one tag exactly, case-insensitive and singular ("Sensors" finds "sensor"); abbreviations count too ("neopixel" finds WS2812 objects).
+prefix_tag matches every tag starting with it and also returns matched_tags.
+Returns objects [{object_id, title, author, category, description_short}]. The first search loads every object; run p2kb_obex_build_index first to make it instant.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"tag": map[string]interface{}{
+						"type":        "string",
+						"description": "Exact tag to look up (e.g., 'i2c', 'sensor')",
+					},
+					"prefix_tag": map[string]interface{}{
+						"type":        "string",
+						"description": "Tag prefix; matches every tag starting with it (e.g., 'mot' for 'motor' and 'motion')",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum objects (default: 20)",
+						"default":     20,
+					},
 				},
 			},
 		},
 
+		// OBEX corpus analytics
+		{
+			Name: "p2kb_obex_stats",
+			Description: `Aggregate statistics across every valid object in the P2 OBEX (Parallax Object Exchange).
+Returns total_objects, by_language, by_category, quality_stats {scored_objects, min, max, avg, median, p90}, with_github_repo_pct, with_forum_link_pct, completeness_avg_pct (share of optional metadata fields populated) and extraction_status_breakdown.
+Results are cached for 5 minutes; computed_at tells when they were computed.`,
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name: "p2kb_obex_dependency_graph",
+			Description: `Graph of P2 OBEX objects whose descriptions mention one another, by object ID (OB2811), OBEX page URL or title.
+An edge source_id -> target_id means the source's description mentions the target. Returns nodes (object_id, title, category, in_degree, out_degree), edges (source_id, target_id, match_type), strongly_connected_components (groups of objects that reference each other) and isolated_objects.
+Covers the first 200 valid objects; truncated is true when more were left out.`,
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+
 		// OBEX download and extract
 		{
 			Name: "p2kb_obex_download",
@@ -163,6 +467,319 @@ Example: Download object 2811 "WS2812 LED Driver" -> extracts to ./OBEX/2811-ws2
 			},
 		},
 
+		// Runtime settings
+		{
+			Name: "p2kb_settings",
+			Description: `View or change the P2 Knowledge Base MCP server's settings (its P2KB_ environment variables) without a restart.
+With no arguments: lists every setting as {key, value, default, source, runtime_adjustable}, where source is runtime, env or default. P2KB_GITHUB_TOKENS, P2KB_GITHUB_TOKEN and P2KB_HTTP_TOKEN are shown only as "(set)".
+With key and value: applies the value until the server exits. Runtime adjustable: P2KB_LOG_LEVEL (debug, info, warn, error), P2KB_CACHE_MAX_ENTRIES (0 = unbounded), P2KB_OBEX_CONCURRENCY, P2KB_REQUEST_CACHE_TTL_SECS (0 = off), P2KB_KEEPALIVE_INTERVAL_SECS, P2KB_BYPASS_CACHE_MAX_PER_MIN, P2KB_MAX_REGEX_COMPLEXITY ("alternations,quantifiers"), P2KB_STRICT_VALIDATION and P2KB_LOG_REDIRECTS (true or false). Other settings are read at startup and return an error.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"key": map[string]interface{}{
+						"type":        "string",
+						"description": "Setting to change, e.g. 'P2KB_LOG_LEVEL'. Omit to list the settings.",
+					},
+					"value": map[string]interface{}{
+						"type":        []string{"string", "integer"},
+						"description": "New value for key",
+					},
+				},
+			},
+		},
+
+		// Health report for liveness probes
+		{
+			Name: "p2kb_healthcheck",
+			Description: `Health report for the P2 Knowledge Base MCP, for container liveness probes and diagnostics.
+Returns status (healthy, degraded or unhealthy: the worst of its checks) and checks [{name, status, message, latency_ms}].
+Checks index loaded, index age under 2x TTL, cache directory writable, memory cache populated and OBEX index loaded; container-tools installs also check the cache symlink target.
+Loads nothing: the index and OBEX list load on first use, so a fresh server reports degraded until then.`,
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+
+		// Debug snapshot of the memory caches
+		{
+			Name: "p2kb_cache_dump",
+			Description: `Debugging aid: dump the P2 Knowledge Base memory caches to a JSON file.
+For each cached entry: key, mtime, content_length, content_hash (SHA-256) and first_100_chars; for each in-memory OBEX object: object_id, title, author and loaded_at; plus index_summary (version, total_entries).
+Requires the server to run with P2KB_ENABLE_DEBUG_TOOLS=true.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"output_path": map[string]interface{}{
+						"type":        "string",
+						"description": "File to write (default: debug-dump.json in the cache directory); \"-\" returns the dump in the response instead",
+					},
+				},
+			},
+		},
+
+		// Identical content under different keys
+		{
+			Name: "p2kb_find_duplicates",
+			Description: `For P2 Knowledge Base maintainers: find cached entries whose content is identical, e.g. an instruction documented under both PASM2 and Spin2 keys.
+Returns groups [{content_hash, content_length, keys}], largest first, with group_count and memory_entries_scanned.
+Informational only: examines entries already in the memory cache and fetches nothing, so it only sees entries read this session.`,
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+
+		// Pinned (always-resident) content
+		{
+			Name: "p2kb_pin",
+			Description: `Pin frequently used P2 Knowledge Base entries so they are pre-loaded at startup and always kept in the memory cache.
+Accepts canonical keys or aliases; unknown keys are reported and skipped.
+Pins persist across restarts. At most 50 keys may be pinned.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"keys": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Keys to pin (e.g., [\"p2kbPasm2Mov\", \"ADD\"])",
+					},
+				},
+				"required": []string{"keys"},
+			},
+		},
+		{
+			Name:        "p2kb_unpin",
+			Description: "Remove P2 Knowledge Base entries from the pinned set created by p2kb_pin.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"keys": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Keys to unpin",
+					},
+				},
+				"required": []string{"keys"},
+			},
+		},
+
+		// Related-entry suggestions
+		{
+			Name: "p2kb_suggest",
+			Description: `Suggest P2 Knowledge Base entries related to the ones you have already read.
+Pass the keys accessed so far in this session; returns up to 10 related keys you have not seen yet, each with the reason it was suggested (e.g., "referenced by p2kbPasm2Mov"), plus loaded OBEX objects whose tags overlap those keys.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"context_keys": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Keys already accessed (e.g., [\"p2kbPasm2Mov\", \"p2kbPasm2Add\"])",
+					},
+				},
+				"required": []string{"context_keys"},
+			},
+		},
+
+		// Self-test questions
+		{
+			Name: "p2kb_quiz",
+			Description: `Generate a multiple-choice P2 self-test question from a random instruction in the P2 Knowledge Base.
+Difficulty easy asks which instruction a description belongs to; medium (default) asks which of the C and Z flags an instruction affects; hard asks what an example does, given the instruction's syntax.
+Returns question, four options, answer_index (0-3) and an explanation quoting the entry's description. Entries lacking what a difficulty needs are skipped.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"category": map[string]interface{}{
+						"type":        "string",
+						"description": "Draw the instruction from this category or alias (e.g., 'pasm2_math'); default: any",
+					},
+					"difficulty": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"easy", "medium", "hard"},
+						"description": "Question difficulty (default: medium)",
+					},
+				},
+			},
+		},
+
+		// On-demand memory reclamation
+		{
+			Name: "p2kb_memory_pressure",
+			Description: `Reclaim memory held by the P2 Knowledge Base caches without restarting the server.
+Evicts least recently used entries from the in-memory content and OBEX caches until at most target_entries remain in each; pinned keys are kept and disk caches are untouched, so evicted entries reload from disk on next use.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"target_entries": map[string]interface{}{
+						"type":        "integer",
+						"description": "Memory entries to keep (default: 0, clear everything except pinned keys)",
+						"default":     0,
+					},
+				},
+			},
+		},
+
+		// Raw key listing for tooling scripts
+		{
+			Name: "p2kb_list_keys",
+			Description: `Power-user tool for scripts: list P2 Knowledge Base index keys, paginated.
+Prefer p2kb_find for exploring the knowledge base; this returns raw index data.
+format "list" returns key names; format "map" returns {key: {path, categories, mtime}}.
+Response includes total_count (all keys), matched_count (after filters) and returned_count (this page).`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"prefix": map[string]interface{}{
+						"type":        "string",
+						"description": "Only keys starting with this prefix (case-sensitive, e.g., 'p2kbPasm2')",
+					},
+					"category": map[string]interface{}{
+						"type":        "string",
+						"description": "Only keys in this exact category (e.g., 'pasm2_math')",
+					},
+					"format": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"list", "map"},
+						"description": "Output format (default: list)",
+						"default":     "list",
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of matching keys to skip (default: 0)",
+						"default":     0,
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum keys to return (default: 200, max: 1000)",
+						"default":     200,
+					},
+				},
+			},
+		},
+
+		// OBEX ZIP preview
+		{
+			Name: "p2kb_obex_preview",
+			Description: `Peek inside an OBEX object's ZIP without extracting it.
+
+Lists every file in the object's ZIP and returns the start of the first file, so you can check an object is what you want before p2kb_obex_download.
+Requires the server to run with P2KB_ENABLE_DOWNLOADS=true.
+
+Returns:
+- file_listing: Every file in the ZIP with its size in bytes
+- preview_filename: The file previewed (the first in the ZIP)
+- preview_content: Its first max_bytes bytes, as UTF-8 text or base64 (see preview_encoding)
+- truncated: Whether the file is longer than the preview`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "OBEX object ID (e.g., '2811') or description, as for p2kb_obex_get",
+					},
+					"max_bytes": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum bytes of the first file to return (default: 4096, max: 1048576)",
+						"default":     4096,
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+
+		// README skeleton for a downloaded object
+		{
+			Name: "p2kb_obex_readme",
+			Description: `Generate a Markdown README skeleton for a P2 community OBEX object, for documenting a downloaded package.
+Includes a title, language/category/quality badges, the short description, an install section with the curl download command, a usage placeholder, hardware requirements, links (OBEX page, GitHub, forum, docs) and credits.
+Returns: content (the Markdown, under about 2000 characters), object_id, title. Queries that match several objects return suggestions, as for p2kb_obex_get.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "OBEX object ID (e.g., '2811'), page URL or description, as for p2kb_obex_get",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+
+		// Citation for an OBEX object
+		{
+			Name: "p2kb_obex_cite",
+			Description: `Cite a P2 community OBEX object in a paper, report or project README.
+Builds the citation from the object's author, title, year created, OBEX page URL and version; fields the object lacks read "Unknown" and are listed in missing_fields.
+Formats: markdown (default) "[Title](URL) by Author (Year, vVersion)"; apa "Author. (Year). *Title* (Version X). Parallax OBEX. URL"; bibtex "@software{obNNNN, author={...}, title={...}, year=YYYY, url={...}, version={...}}".
+Queries that match several objects return suggestions, as for p2kb_obex_get.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "OBEX object ID (e.g., '2811'), page URL or description, as for p2kb_obex_get",
+					},
+					"format": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"markdown", "apa", "bibtex"},
+						"description": "Citation style (default: markdown)",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+
+		// Background bulk load of OBEX objects
+		{
+			Name: "p2kb_obex_build_index",
+			Description: `Admin: load every OBEX object into memory in the background, so OBEX search and browse never wait on GitHub.
+Returns immediately with status "started", total_objects and a build_id. Call again with no arguments (or with build_id) for progress: status (running, completed, stopped, failed), fetched, failed and progress_pct.
+Objects load 10 at a time with a short pause between batches, within P2KB_OBEX_CONCURRENCY. stop: true aborts a running build.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"build_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Report on this build instead of starting one",
+					},
+					"stop": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Abort the running build",
+						"default":     false,
+					},
+				},
+			},
+		},
+
+		// Download link health check
+		{
+			Name: "p2kb_obex_verify",
+			Description: `Admin: check that OBEX download links still work, with an HTTP HEAD request per object (5 at a time).
+Checks the objects already in memory, or every object in category. Returns verified_count, ok_count, broken_count and broken: object_id, title and http_status of each failing link.
+fix: true looks on each broken object's OBEX page for a working download link and, if one answers, uses it for that object from then on (fixed_url, fixed_count).`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"category": map[string]interface{}{
+						"type":        "string",
+						"description": "Check this category's objects instead of those in memory",
+					},
+					"max_objects": map[string]interface{}{
+						"type":        "integer",
+						"description": "Most objects to check",
+						"default":     defaultVerifyObjects,
+					},
+					"fix": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Look for a working link on the OBEX page of each broken object",
+						"default":     false,
+					},
+				},
+			},
+		},
+
 		// User-triggered refresh
 		{
 			Name: "p2kb_refresh",
@@ -185,7 +802,40 @@ Automatically detects and removes stale cached items based on index timestamps.`
 				},
 			},
 		},
+
+		// Cache relocation after an install moves
+		{
+			Name: "p2kb_migrate_cache",
+			Description: `Admin: copy a P2 Knowledge Base cache left behind by a moved or upgraded install (e.g., standalone to container-tools layout) into this server's cache directory.
+Files keep their modification times; a file the destination already has is replaced when the source copy is newer, such as a newer index/p2kb-index.json, and skipped when both match.
+If the destination has newer copies of any file, nothing is copied and the error lists them; pass force: true to overwrite them. The source is left in place, and the in-memory content cache is dropped so entries are read from the copied files.
+Returns migrated_files_count, migrated_size_bytes, source_dir, destination_dir and duration_ms.`,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"source_dir": map[string]interface{}{
+						"type":        "string",
+						"description": "The old cache directory (~ and environment variables are expanded)",
+					},
+					"destination_dir": map[string]interface{}{
+						"type":        "string",
+						"description": "Where to copy it: this server's cache directory (the default) or a directory beneath it",
+					},
+					"force": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Overwrite destination files newer than the source's (default: false)",
+						"default":     false,
+					},
+				},
+				"required": []string{"source_dir"},
+			},
+		},
+	}
+
+	if debugToolsEnabled() {
+		tools = append(tools, rawGetTool)
 	}
+	return tools
 }
 
 // handleToolsList returns the list of available tools in MCP format.
@@ -0,0 +1,94 @@
+// Package yaml extracts top-level fields from P2KB YAML files as plain text.
+package yaml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ExtractField returns the value of the top-level field in content as text,
+// or "" if content is not a YAML mapping or has no such field. See
+// ExtractFields for how values are rendered.
+func ExtractField(content, field string) string {
+	values, err := ExtractFields(content, field)
+	if err != nil {
+		return ""
+	}
+	return values[field]
+}
+
+// ExtractFields returns the text of each named top-level field in content,
+// omitting fields it does not have. Scalars are trimmed; a sequence of
+// scalars has one item per line, and a mapping of scalars one "key: value"
+// per line, in key order. Anything more deeply nested is rendered as YAML.
+// It fails only when content is not a YAML mapping.
+func ExtractFields(content string, fields ...string) (map[string]string, error) {
+	var doc map[string]interface{}
+	if err := yamlv3.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, fmt.Errorf("content is not a YAML mapping: %w", err)
+	}
+
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		if value, ok := doc[field]; ok && value != nil {
+			values[field] = render(value)
+		}
+	}
+	return values, nil
+}
+
+// render converts a decoded YAML value to text for ExtractFields.
+func render(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		lines := make([]string, 0, len(v))
+		for _, item := range v {
+			if !isScalar(item) {
+				return marshal(value)
+			}
+			lines = append(lines, render(item))
+		}
+		return strings.Join(lines, "\n")
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name, item := range v {
+			if !isScalar(item) {
+				return marshal(value)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lines := make([]string, len(names))
+		for i, name := range names {
+			lines[i] = name + ": " + render(v[name])
+		}
+		return strings.Join(lines, "\n")
+	case map[interface{}]interface{}:
+		return marshal(value)
+	case nil:
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// isScalar reports whether a decoded YAML value is neither a sequence nor a
+// mapping.
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}, map[interface{}]interface{}:
+		return false
+	}
+	return true
+}
+
+// marshal renders a nested value back to YAML, trimmed.
+func marshal(value interface{}) string {
+	data, err := yamlv3.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,51 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ironsheep/p2kb-mcp/internal/testdata"
+)
+
+func TestExtractField(t *testing.T) {
+	mov := string(testdata.MustGetFixture("p2kbPasm2Mov.yaml"))
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"mnemonic", "MOV"},
+		{"syntax", "MOV D,S\nMOV D,#N"},
+		{"description", "Copy source to destination.\n\nThe MOV instruction copies the value from the source operand\nto the destination register."},
+		{"flags", "C: Set if S[31] = 1\nZ: Set if D = 0"},
+		{"related_instructions", "p2kbPasm2Add\np2kbPasm2Loc\np2kbPasm2Rdlong"},
+		{"opcodes", "- description: MOV D,S/# - Copy S to D\n  encoding: EEEE 0110000 CZI DDDDDDDDD SSSSSSSSS"},
+		{"no_such_field", ""},
+	}
+	for _, tt := range tests {
+		if got := ExtractField(mov, tt.field); got != tt.want {
+			t.Errorf("ExtractField(%q) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+
+	if got := ExtractField("- not\n- a mapping\n", "mnemonic"); got != "" {
+		t.Errorf("ExtractField on a sequence = %q, want \"\"", got)
+	}
+}
+
+func TestExtractFields(t *testing.T) {
+	add := string(testdata.MustGetFixture("p2kbPasm2Add.yaml"))
+
+	got, err := ExtractFields(add, "mnemonic", "flags", "category")
+	if err != nil {
+		t.Fatalf("ExtractFields failed: %v", err)
+	}
+	want := map[string]string{"mnemonic": "ADD", "category": "Math and Logic"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractFields = %v, want %v (no flags entry)", got, want)
+	}
+
+	if _, err := ExtractFields("mnemonic: [unclosed", "mnemonic"); err == nil {
+		t.Error("ExtractFields accepted invalid YAML")
+	}
+}